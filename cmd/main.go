@@ -1,8 +1,16 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	runtimedebug "runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -19,8 +27,11 @@ import (
 	"github.com/oneforall-go/internal/dnsquery"
 	"github.com/oneforall-go/internal/enrich"
 	"github.com/oneforall-go/internal/intelligence"
+	"github.com/oneforall-go/internal/metrics"
 	"github.com/oneforall-go/internal/search"
+	"github.com/oneforall-go/internal/server"
 	"github.com/oneforall-go/internal/validator"
+	"github.com/oneforall-go/internal/webhook"
 	"github.com/oneforall-go/pkg/logger"
 	"github.com/oneforall-go/pkg/utils"
 )
@@ -56,6 +67,101 @@ var (
 	enableBruteForce bool
 	libConcurrency   int
 	libTimeout       int
+
+	// DNS参数
+	dnsServers string
+	ipVersion  string
+
+	// 过滤参数
+	includePattern string
+	excludePattern string
+
+	// 基线文件
+	baselineFile string
+
+	// 复查输入和基线文件的格式（auto/csv/json）
+	inputFormat string
+
+	// Prometheus 指标监听地址
+	metricsAddr string
+
+	// 配置文件路径
+	configFile string
+
+	// 数据文件目录
+	dataDir string
+
+	// 服务模式监听地址
+	serveAddr string
+
+	// 并发处理的域名数
+	domainConcurrency int
+
+	// 模块筛选（逗号分隔的模块名称）
+	onlyModules string
+	skipModules string
+
+	// 预览模式
+	dryRun bool
+
+	// 爆破字典文件（可重复指定或逗号分隔）
+	wordlists []string
+
+	// 被动模式
+	passive bool
+
+	// 单个域名的最大结果数
+	maxResults int
+
+	// PTR 扫描的 /24 网段数
+	ptrSweep int
+
+	// 导出结果的置信度下限
+	minConfidence int
+
+	// 整次运行的时间预算
+	maxRuntime time.Duration
+
+	// 自定义 User-Agent
+	userAgent string
+
+	// 深度查询（额外消耗 API 额度）
+	deep bool
+
+	// 跳过运行前的联网检查
+	noPreflight bool
+
+	// 不验证，只导出发现的候选
+	noValidation bool
+
+	// 只导出未存活域名 / 显示未存活原因统计
+	deadOnly        bool
+	showDeadReasons bool
+
+	// 导出全部结果，覆盖 export_alive_only/export_dead_only 配置
+	exportAll bool
+
+	// JSON 结果使用带版本的信封格式
+	jsonEnvelope bool
+
+	// 每个域名的结果写入单独目录并维护索引
+	perDomain bool
+
+	// txt 格式每行加 https:// 前缀
+	txtWithScheme bool
+
+	// 导出结果时另外写入统计文件
+	statsFile bool
+
+	// CSV 结果的列及顺序（可重复指定或逗号分隔）
+	csvColumns []string
+
+	// HTTP 探测时识别 Web 技术
+	fingerprint bool
+
+	// 复查模式的输入文件和验证并发数
+	recheckInput       string
+	recheckConcurrency int
 )
 
 // OneForAll OneForAll 主程序
@@ -64,6 +170,21 @@ type OneForAll struct {
 	dispatcher *core.Dispatcher
 	output     *core.OutputManager
 	domains    []string
+
+	// ASN/CIDR 目标反查得到的种子结果，按域名存放
+	seeds map[string][]core.SubdomainResult
+
+	// 运行上下文，设置 --max-runtime 时带有全局截止时间
+	ctx context.Context
+
+	// 并发处理多个域名时保护 output
+	outputMutex sync.Mutex
+
+	// 正在处理的域名中模块已发现、尚未写入 output 的结果，运行中断时一并导出（受 outputMutex 保护）
+	pending map[string][]core.SubdomainResult
+
+	// 正在处理的域名中已完成的验证结果，运行中断时应用到 pending 中的结果上（受 outputMutex 保护）
+	validated map[string][]validator.ValidationResult
 }
 
 // NewOneForAll 创建 OneForAll 实例
@@ -75,15 +196,24 @@ func NewOneForAll() *OneForAll {
 		dispatcher: core.NewDispatcher(cfg),
 		output:     core.NewOutputManager(cfg),
 		domains:    make([]string, 0),
+		ctx:        context.Background(),
 	}
 }
 
 // run 运行主程序
 func (o *OneForAll) run() error {
 	logger.Info("Starting OneForAll...")
+	defer o.exportPartialOnPanic()
 
 	// 配置参数
-	o.configParam()
+	if err := o.configParam(); err != nil {
+		return err
+	}
+	o.output.SetDiscoveryOnly(o.config.DiscoveryOnly())
+
+	// 启动全局运行预算
+	cancel := o.startBudget()
+	defer cancel()
 
 	// 加载域名
 	if err := o.loadDomains(); err != nil {
@@ -96,26 +226,45 @@ func (o *OneForAll) run() error {
 	// 列出模块
 	o.dispatcher.ListModules()
 
+	// 运行前检查网络连接，离线时直接终止
+	if err := o.dispatcher.Preflight(); err != nil {
+		return err
+	}
+
+	// 收到 SIGINT/SIGTERM 时导出已收集的结果再退出
+	stopSignals := o.exportPartialOnSignal()
+	defer stopSignals()
+
 	// 处理每个域名
-	for _, domain := range o.domains {
+	o.processDomains(func(dispatcher *core.Dispatcher, domain string) {
 		logger.Infof("Processing domain: %s", domain)
+		start := time.Now()
+		dispatcher.AddSeeds(domain, o.seeds[domain])
 
 		// 运行所有模块
-		results, validationResults, err := o.dispatcher.RunAllModules(domain)
+		results, validationResults, err := dispatcher.RunAllModules(domain)
 		if err != nil {
 			logger.Errorf("Failed to run modules for %s: %v", domain, err)
-			continue
+			return
 		}
 
 		// 处理结果
-		o.processResults(domain, results, validationResults)
-	}
+		output := o.domainOutput(domain, start)
+		o.outputMutex.Lock()
+		o.processResults(output, domain, results, validationResults)
+		output.AddRelatedDomains(dispatcher.RelatedDomains(domain))
+		o.outputMutex.Unlock()
+		o.finishDomainOutput(domain, output, dispatcher.RelatedDomains(domain))
+	})
 
 	// 导出结果
-	if err := o.output.Export(); err != nil {
-		return fmt.Errorf("failed to export results: %v", err)
+	if err := o.exportResults(); err != nil {
+		return err
 	}
 
+	// 发送Webhook通知
+	o.notifyWebhook()
+
 	// 显示统计信息
 	o.showStats()
 
@@ -125,9 +274,17 @@ func (o *OneForAll) run() error {
 // runLib 运行库调用
 func (o *OneForAll) runLib() error {
 	logger.Info("Starting OneForAll Library Call...")
+	defer o.exportPartialOnPanic()
 
 	// 配置参数
-	o.configParam()
+	if err := o.configParam(); err != nil {
+		return err
+	}
+	o.output.SetDiscoveryOnly(o.config.DiscoveryOnly())
+
+	// 启动全局运行预算
+	cancel := o.startBudget()
+	defer cancel()
 
 	// 加载域名
 	if err := o.loadDomains(); err != nil {
@@ -137,28 +294,92 @@ func (o *OneForAll) runLib() error {
 	// 注册模块
 	o.registerModules()
 
+	// 运行前检查网络连接，离线时直接终止
+	if err := o.dispatcher.Preflight(); err != nil {
+		return err
+	}
+
+	// 收到 SIGINT/SIGTERM 时导出已收集的结果再退出
+	stopSignals := o.exportPartialOnSignal()
+	defer stopSignals()
+
 	// 处理每个域名
-	for _, domain := range o.domains {
+	o.processDomains(func(dispatcher *core.Dispatcher, domain string) {
 		logger.Infof("Processing domain: %s", domain)
+		start := time.Now()
+		dispatcher.AddSeeds(domain, o.seeds[domain])
 
 		// 准备库调用选项
 		options := map[string]interface{}{
-			"enable_validation":  enableValidation,
+			"enable_validation":  o.config.EnableDomainValidation,
 			"enable_brute_force": enableBruteForce,
 			"concurrency":        libConcurrency,
 			"timeout":            time.Duration(libTimeout) * time.Second,
 		}
 
 		// 运行库调用
-		results, err := o.dispatcher.RunLib(domain, options)
+		results, err := dispatcher.RunLib(domain, options)
 		if err != nil {
 			logger.Errorf("Failed to run library call for %s: %v", domain, err)
-			continue
+			return
 		}
 
 		// 处理库调用结果
-		o.processLibResults(domain, results)
+		output := o.domainOutput(domain, start)
+		o.outputMutex.Lock()
+		o.processLibResults(output, domain, results)
+		output.AddRelatedDomains(dispatcher.RelatedDomains(domain))
+		o.outputMutex.Unlock()
+		o.finishDomainOutput(domain, output, dispatcher.RelatedDomains(domain))
+	})
+
+	// 导出结果
+	if err := o.exportResults(); err != nil {
+		return err
+	}
+
+	// 发送Webhook通知
+	o.notifyWebhook()
+
+	// 显示统计信息
+	o.showStats()
+
+	return nil
+}
+
+// recheck 重新验证已有结果文件中的子域名，不重新枚举
+func (o *OneForAll) recheck() error {
+	logger.Infof("Rechecking results from %s...", recheckInput)
+
+	// 配置参数
+	if err := o.configParam(); err != nil {
+		return err
 	}
+	o.output.SetFormat(o.config.ResultSaveFormat)
+
+	// 加载已有结果，输入格式由 --input-format 指定或自动识别，与 --format 无关
+	results, err := core.LoadResultsAs(recheckInput, inputFormat)
+	if err != nil {
+		return fmt.Errorf("failed to load results: %v", err)
+	}
+	if len(results) == 0 {
+		logger.Warnf("No results found in %s", recheckInput)
+		return nil
+	}
+
+	subdomains := make([]string, 0, len(results))
+	for _, result := range results {
+		subdomains = append(subdomains, result.Subdomain)
+	}
+
+	concurrency := recheckConcurrency
+	if concurrency <= 0 {
+		concurrency = o.config.ValidationConcurrency
+	}
+	validationResults := validator.NewDomainValidator(o.config).ValidateDomains(subdomains, concurrency)
+
+	// 保留原有来源等信息，只更新存活状态
+	o.output.AddResults(applyValidation(results, validationResults))
 
 	// 导出结果
 	if err := o.output.Export(); err != nil {
@@ -171,8 +392,32 @@ func (o *OneForAll) runLib() error {
 	return nil
 }
 
+// applyValidation 用新的验证结果更新已有结果的 IP、存活状态、状态码、服务商和证书信息
+func applyValidation(results []core.SubdomainResult, validationResults []validator.ValidationResult) []core.SubdomainResult {
+	fresh := make(map[string]validator.ValidationResult, len(validationResults))
+	for _, result := range validationResults {
+		fresh[result.Subdomain] = result
+	}
+
+	updated := make([]core.SubdomainResult, 0, len(results))
+	for _, result := range results {
+		if v, ok := fresh[result.Subdomain]; ok {
+			core.ApplyValidation(&result, v)
+		}
+		updated = append(updated, result)
+	}
+	return updated
+}
+
 // configParam 配置参数
-func (o *OneForAll) configParam() {
+func (o *OneForAll) configParam() error {
+	// 记录命令行参数覆盖的配置项
+	before := o.config.Clone()
+	defer config.LogChanges(before, o.config, "cli")
+
+	// 设置日志格式
+	logger.SetFormat(o.config.LogFormat)
+
 	// 设置输出格式
 	if outputFmt != "" {
 		o.config.ResultSaveFormat = outputFmt
@@ -180,7 +425,50 @@ func (o *OneForAll) configParam() {
 	if path != "" {
 		o.config.ResultSavePath = path
 	}
-	o.config.ResultExportAlive = alive
+	// --all 导出全部结果（默认配置 export_alive_only 为 true），不能与 --alive/--dead-only 同时使用
+	if exportAll {
+		if alive || deadOnly {
+			return fmt.Errorf("--all cannot be combined with --alive or --dead-only")
+		}
+		o.config.ExportAliveOnly = false
+		o.config.ExportDeadOnly = false
+	}
+	// --alive 强制只导出存活域名，未指定时使用 EXPORT_ALIVE_ONLY 配置
+	if alive {
+		o.config.ExportAliveOnly = true
+	}
+	// --dead-only 只导出未存活域名，优先于 --alive
+	if deadOnly {
+		o.config.ExportDeadOnly = true
+	}
+	// --json-envelope 使用带版本的 JSON 信封
+	if jsonEnvelope {
+		o.config.JSONEnvelope = true
+	}
+	// --per-domain 每个域名的结果写入 <path>/<domain>/ 并更新 index.json
+	if perDomain {
+		o.config.PerDomainOutput = true
+	}
+	// --txt-with-scheme txt 格式每行加 https:// 前缀
+	if txtWithScheme {
+		o.config.TxtWithScheme = true
+	}
+	// --stats-file 另外写入 <结果文件名>.stats.json
+	if statsFile {
+		o.config.StatsFile = true
+	}
+	// --csv-columns 选择 CSV 结果的列及顺序，列名在 Validate 中校验
+	if len(csvColumns) > 0 {
+		o.config.CSVColumns = csvColumns
+	}
+	// --no-preflight 跳过运行前的联网检查
+	if noPreflight {
+		o.config.Preflight = false
+	}
+	// --no-validation（run-lib 的 --enable-validation=false）跳过验证，只导出发现的候选
+	if noValidation || !enableValidation {
+		o.config.EnableDomainValidation = false
+	}
 
 	// 设置模块开关
 	if !brute {
@@ -196,6 +484,14 @@ func (o *OneForAll) configParam() {
 		o.config.EnableTakeoverCheck = false
 	}
 
+	// --fingerprint 在 HTTP 探测时识别 Web 技术
+	if fingerprint {
+		o.config.Fingerprint = true
+	}
+	if o.config.Fingerprint && !o.config.EnableHTTPRequest {
+		logger.Warn("Web fingerprinting needs HTTP requests, which are disabled; no fingerprints will be recorded")
+	}
+
 	// 新架构模块开关
 	if !searchModules {
 		o.config.EnableSearchModules = false
@@ -215,6 +511,98 @@ func (o *OneForAll) configParam() {
 	if !enrichModules {
 		o.config.EnableEnrichModules = false
 	}
+
+	// 自定义DNS服务器
+	if dnsServers != "" {
+		resolvers, err := config.ParseResolvers(dnsServers)
+		if err != nil {
+			return fmt.Errorf("invalid --dns-servers: %v", err)
+		}
+		o.config.Resolvers = resolvers
+		logger.Infof("Using custom DNS servers: %v", resolvers)
+	}
+
+	// 爆破字典，多个文件合并去重
+	if len(wordlists) > 0 {
+		o.config.BruteWordlists = wordlists
+		logger.Infof("Using brute force wordlists: %v", wordlists)
+	}
+
+	// 解析和验证使用的 IP 版本
+	if ipVersion != "" {
+		version, err := config.ParseIPVersion(ipVersion)
+		if err != nil {
+			return fmt.Errorf("invalid --ip-version: %v", err)
+		}
+		o.config.IPVersion = version
+	}
+
+	// 预览模式
+	if dryRun {
+		o.config.DryRun = true
+		logger.Info("Dry-run mode: modules will be listed and brute force candidates generated without sending traffic")
+	}
+
+	// 被动模式
+	if passive {
+		o.config.Passive = true
+	}
+	if o.config.Passive {
+		logger.Info("Passive mode: only search, dataset, certificate and intelligence modules run; results are not validated")
+	}
+
+	// 结果上限
+	if maxResults > 0 {
+		o.config.MaxResults = maxResults
+	}
+
+	// /24 网段 PTR 扫描
+	if ptrSweep > 0 {
+		o.config.PTRSweepLimit = ptrSweep
+	}
+
+	// 置信度下限
+	if minConfidence < 0 || minConfidence > 100 {
+		return fmt.Errorf("--min-confidence must be between 0 and 100, got %d", minConfidence)
+	}
+	if minConfidence > 0 {
+		o.config.MinConfidence = minConfidence
+	}
+
+	// 运行时间预算
+	if maxRuntime > 0 {
+		o.config.MaxRuntime = maxRuntime
+	}
+
+	// 自定义 User-Agent，替代内置的随机 User-Agent
+	if userAgent != "" {
+		o.config.UserAgent = userAgent
+	}
+
+	// 深度查询，额外消耗 API 额度
+	if deep {
+		o.config.Deep = true
+	}
+
+	// 结果过滤
+	filter, err := core.NewResultFilter(includePattern, excludePattern)
+	if err != nil {
+		return err
+	}
+	o.output.SetFilter(filter)
+
+	// 加载基线结果
+	if baselineFile != "" {
+		baseline, err := core.LoadResultsAs(baselineFile, inputFormat)
+		if err != nil {
+			return fmt.Errorf("failed to load baseline: %v", err)
+		}
+		o.output.SetBaseline(baselineFile, baseline)
+		logger.Infof("Loaded %d baseline results from %s", len(baseline), baselineFile)
+	}
+
+	// 校验合并命令行参数后的最终配置
+	return o.config.Validate()
 }
 
 // loadDomains 加载域名
@@ -230,6 +618,11 @@ func (o *OneForAll) loadDomains() error {
 		o.domains = append(o.domains, domains...)
 	}
 
+	// 展开 ASN/CIDR 目标
+	if err := o.expandNetworkTargets(); err != nil {
+		return err
+	}
+
 	if len(o.domains) == 0 {
 		return fmt.Errorf("no valid domains provided")
 	}
@@ -237,6 +630,256 @@ func (o *OneForAll) loadDomains() error {
 	return nil
 }
 
+// expandNetworkTargets 将 ASN/CIDR 目标替换为反查 PTR 得到的注册域名，反查到的主机名作为种子结果；
+// 只有显式指定的目标和 NetworkTargetDomains 中列出的注册域名继续枚举，
+// 其他注册域名（多为云服务商等第三方域名）下的主机名只作为结果输出
+func (o *OneForAll) expandNetworkTargets() error {
+	var domains []string
+	seen := make(map[string]bool)
+	addDomain := func(domain string) {
+		if !seen[domain] {
+			seen[domain] = true
+			domains = append(domains, domain)
+		}
+	}
+
+	var allSkipped []string
+	explicit := make(map[string]bool)
+	for _, target := range o.domains {
+		if !enrich.IsNetworkTarget(target) {
+			explicit[core.NormalizeHost(target)] = true
+		}
+	}
+
+	for _, target := range o.domains {
+		if !enrich.IsNetworkTarget(target) {
+			addDomain(target)
+			continue
+		}
+
+		seeds, err := enrich.ExpandNetworkTarget(o.config, target)
+		if err != nil {
+			return fmt.Errorf("failed to expand %s: %v", target, err)
+		}
+		if len(seeds) == 0 {
+			logger.Warnf("No domains found by reverse DNS for %s", target)
+		}
+
+		var expanded, skipped []string
+		for domain := range seeds {
+			if explicit[domain] || o.config.NetworkTargetDomain(domain) {
+				expanded = append(expanded, domain)
+			} else {
+				skipped = append(skipped, domain)
+			}
+		}
+		sort.Strings(expanded)
+		sort.Strings(skipped)
+
+		if o.seeds == nil {
+			o.seeds = make(map[string][]core.SubdomainResult)
+		}
+		for _, domain := range expanded {
+			addDomain(domain)
+			o.seeds[domain] = append(o.seeds[domain], seeds[domain]...)
+		}
+		for _, domain := range skipped {
+			o.output.AddResults(seeds[domain])
+		}
+		if len(expanded) > 0 {
+			logger.Infof("Expanded %s to domains: %v", target, expanded)
+		}
+		if len(skipped) > 0 {
+			logger.Infof("Reverse DNS for %s found hosts under %d other domains, kept as results only "+
+				"(list them in NETWORK_TARGET_DOMAINS to enumerate): %v", target, len(skipped), skipped)
+		}
+		allSkipped = append(allSkipped, skipped...)
+	}
+
+	if len(domains) == 0 && len(allSkipped) > 0 {
+		return fmt.Errorf("reverse DNS found no domains allowed for enumeration, list the ones to enumerate in NETWORK_TARGET_DOMAINS: %v", allSkipped)
+	}
+
+	o.domains = domains
+	return nil
+}
+
+// startBudget 按 MaxRuntime 启动全局截止时间，预算耗尽后调度器取消剩余工作并直接导出
+func (o *OneForAll) startBudget() context.CancelFunc {
+	if o.ctx == nil {
+		o.ctx = context.Background()
+	}
+	if o.config.MaxRuntime <= 0 {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithTimeout(o.ctx, o.config.MaxRuntime)
+	o.ctx = ctx
+	o.dispatcher.SetContext(ctx)
+	logger.Infof("Run budget: %v (deadline %s)", o.config.MaxRuntime, time.Now().Add(o.config.MaxRuntime).Format("15:04:05"))
+	return cancel
+}
+
+// processDomains 处理所有域名，--domain-concurrency 大于 1 时使用工作池并发处理，
+// 每个工作协程使用独立的调度器和模块实例，避免共享状态
+func (o *OneForAll) processDomains(process func(dispatcher *core.Dispatcher, domain string)) {
+	workers := domainConcurrency
+	if workers > len(o.domains) {
+		workers = len(o.domains)
+	}
+	if workers <= 1 {
+		for i, domain := range o.domains {
+			if o.ctx.Err() != nil {
+				logger.Warnf("Run budget expired after processing %d/%d domains, skipping the rest", i, len(o.domains))
+				return
+			}
+			o.processDomain(process, o.dispatcher, domain)
+		}
+		return
+	}
+
+	logger.Infof("Processing %d domains with %d workers", len(o.domains), workers)
+
+	// 工作协程中的 panic 转交给调用方，由 run/runLib 导出部分结果
+	var workerPanic interface{}
+	var panicOnce sync.Once
+
+	domainChan := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		dispatcher := o.newWorkerDispatcher()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					logger.Errorf("Panic while processing domains: %v\n%s", r, runtimedebug.Stack())
+					panicOnce.Do(func() { workerPanic = r })
+					// 继续消费剩余域名，避免分发协程阻塞
+					for range domainChan {
+					}
+				}
+			}()
+			for domain := range domainChan {
+				o.processDomain(process, dispatcher, domain)
+			}
+		}()
+	}
+
+	for i, domain := range o.domains {
+		if o.ctx.Err() != nil {
+			logger.Warnf("Run budget expired after dispatching %d/%d domains, skipping the rest", i, len(o.domains))
+			break
+		}
+		domainChan <- domain
+	}
+	close(domainChan)
+	wg.Wait()
+
+	if workerPanic != nil {
+		panic(workerPanic)
+	}
+}
+
+// processDomain 处理单个域名，处理期间模块发现的结果记入 pending、已完成的验证记入 validated，运行中断时这些结果不会丢失
+func (o *OneForAll) processDomain(process func(dispatcher *core.Dispatcher, domain string), dispatcher *core.Dispatcher, domain string) {
+	// 同一调度器依次处理多个域名，先清空模块中上一个域名的结果
+	dispatcher.Reset()
+	dispatcher.SetResultHandler(func(result core.SubdomainResult) {
+		o.outputMutex.Lock()
+		defer o.outputMutex.Unlock()
+		if o.pending == nil {
+			o.pending = make(map[string][]core.SubdomainResult)
+		}
+		o.pending[domain] = append(o.pending[domain], result)
+	})
+	defer dispatcher.SetResultHandler(nil)
+	dispatcher.SetValidationHandler(func(result validator.ValidationResult) {
+		o.outputMutex.Lock()
+		defer o.outputMutex.Unlock()
+		if o.validated == nil {
+			o.validated = make(map[string][]validator.ValidationResult)
+		}
+		o.validated[domain] = append(o.validated[domain], result)
+	})
+	defer dispatcher.SetValidationHandler(nil)
+
+	process(dispatcher, domain)
+
+	o.outputMutex.Lock()
+	delete(o.pending, domain)
+	delete(o.validated, domain)
+	o.outputMutex.Unlock()
+}
+
+// exportPartial 导出 output 中已有的结果和正在处理的域名中已发现的结果（带上已完成的验证），文件名带 _partial 后缀
+func (o *OneForAll) exportPartial(reason string) {
+	logger.Errorf("Run aborted (%s), exporting collected results", reason)
+
+	// 中断可能发生在持有锁的代码中，拿不到锁时只导出 output 中已有的结果
+	if o.outputMutex.TryLock() {
+		for domain, results := range o.pending {
+			o.output.AddResults(applyValidation(results, o.validated[domain]))
+		}
+		o.pending = nil
+		o.validated = nil
+		o.outputMutex.Unlock()
+	}
+
+	path, err := o.output.ExportPartial()
+	if err != nil {
+		logger.Errorf("Failed to export partial results: %v", err)
+		return
+	}
+	if path != "" {
+		fmt.Fprintf(os.Stderr, "Incomplete results saved to %s\n", path)
+	}
+}
+
+// exportPartialOnPanic 运行中 panic 时先导出部分结果，再继续向上抛出，需直接 defer 调用
+func (o *OneForAll) exportPartialOnPanic() {
+	if r := recover(); r != nil {
+		o.exportPartial(fmt.Sprintf("panic: %v", r))
+		panic(r)
+	}
+}
+
+// exportPartialOnSignal 收到 SIGINT/SIGTERM 时导出部分结果并退出，返回的函数用于取消监听
+func (o *OneForAll) exportPartialOnSignal() func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case sig := <-signals:
+			o.exportPartial(fmt.Sprintf("received %v", sig))
+			os.Exit(130)
+		case <-done:
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// newWorkerDispatcher 创建已注册全部模块的独立调度器
+func (o *OneForAll) newWorkerDispatcher() *core.Dispatcher {
+	worker := &OneForAll{
+		config:     o.config,
+		dispatcher: core.NewDispatcher(o.config),
+	}
+	worker.registerModules()
+	worker.dispatcher.SetContext(o.ctx)
+	// 各工作调度器的模块耗时汇总到主调度器
+	worker.dispatcher.SetTimingCollector(o.dispatcher.TimingCollector())
+	// 主调度器已完成运行前检查
+	worker.dispatcher.SetPreflight(nil)
+	return worker.dispatcher
+}
+
 // registerModules 注册模块
 func (o *OneForAll) registerModules() {
 	logger.Info("Registering modules...")
@@ -267,6 +910,20 @@ func (o *OneForAll) registerModules() {
 
 	// 注册丰富模块
 	o.registerEnrichModules()
+
+	// 按 --only-modules/--skip-modules 筛选模块
+	o.dispatcher.FilterModules(splitList(onlyModules), splitList(skipModules))
+}
+
+// splitList 解析逗号分隔的列表，忽略空项
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
 
 // registerSearchModules 注册搜索引擎模块
@@ -367,6 +1024,7 @@ func (o *OneForAll) registerIntelligenceModules() {
 	o.dispatcher.RegisterModule(intelligence.NewThreatMiner(o.config))
 	o.dispatcher.RegisterModule(intelligence.NewVirusTotal(o.config))
 	o.dispatcher.RegisterModule(intelligence.NewVirusTotalAPI(o.config))
+	o.dispatcher.RegisterModule(intelligence.NewWhois(o.config))
 }
 
 // registerBruteModules 注册爆破模块
@@ -394,7 +1052,7 @@ func (o *OneForAll) registerEnrichModules() {
 }
 
 // processResults 处理结果
-func (o *OneForAll) processResults(domain string, results map[core.ModuleType][]core.SubdomainResult, validationResults []validator.ValidationResult) {
+func (o *OneForAll) processResults(output *core.OutputManager, domain string, results map[core.ModuleType][]core.SubdomainResult, validationResults []validator.ValidationResult) {
 	// 汇总主机列表
 	var hosts []string
 	for _, subdomainResults := range results {
@@ -405,30 +1063,111 @@ func (o *OneForAll) processResults(domain string, results map[core.ModuleType][]
 	// 后处理：当数量超过阈值时，按标题去重并对403限流
 	if processed := core.PostProcessHosts(hosts, o.config); processed != nil {
 		logger.Infof("Post-processed %d hosts for %s", len(hosts), domain)
-		o.output.AddResults(processed)
+		output.AddResults(processed)
 		return
 	}
 
 	// 否则走原流程：先添加验证结果，再添加其他模块
 	if len(validationResults) > 0 {
 		logger.Infof("Adding %d validation results for %s", len(validationResults), domain)
-		o.output.AddValidationResults(validationResults)
+		output.AddValidationResults(validationResults)
 	}
 	for moduleType, subdomainResults := range results {
 		logger.Infof("Module type %s found %d subdomains for %s", moduleType, len(subdomainResults), domain)
 		for _, result := range subdomainResults {
-			o.output.AddResult(result)
+			output.AddResult(result)
 		}
 	}
 }
 
+// domainOutput 返回域名结果写入的输出管理器：按域名分目录输出时每个域名单独一个（运行耗时从 start 开始计算），
+// 否则为全局输出
+func (o *OneForAll) domainOutput(domain string, start time.Time) *core.OutputManager {
+	if !o.config.PerDomainOutput {
+		return o.output
+	}
+	output := o.output.ForDomain(domain)
+	output.SetStartTime(start)
+	return output
+}
+
+// finishDomainOutput 按域名分目录输出时立即导出该域名的结果并更新索引，再汇总到全局输出用于统计和通知
+func (o *OneForAll) finishDomainOutput(domain string, output *core.OutputManager, related []string) {
+	if output == o.output {
+		return
+	}
+	if err := output.Export(); err != nil {
+		logger.Errorf("Failed to export results for %s: %v", domain, err)
+	}
+
+	o.outputMutex.Lock()
+	defer o.outputMutex.Unlock()
+	o.output.AddResults(output.GetResults())
+	o.output.AddRelatedDomains(related)
+}
+
+// exportResults 导出全局结果，按域名分目录输出时各域名已单独导出，不再写汇总文件
+func (o *OneForAll) exportResults() error {
+	if o.config.PerDomainOutput {
+		logger.Infof("Per-domain results indexed in %s", o.resultsLocation())
+		return nil
+	}
+	if err := o.output.Export(); err != nil {
+		return fmt.Errorf("failed to export results: %v", err)
+	}
+	return nil
+}
+
+// resultsLocation 返回结果保存位置，按域名分目录输出时为索引文件
+func (o *OneForAll) resultsLocation() string {
+	if o.config.PerDomainOutput {
+		return core.IndexPath(o.config.ResultSavePath)
+	}
+	return o.output.GetOutputPath()
+}
+
+// notifyWebhook 发送扫描完成通知，失败时只记录日志
+func (o *OneForAll) notifyWebhook() {
+	notifier := webhook.NewNotifier(o.config)
+	if notifier == nil || o.config.DryRun {
+		return
+	}
+
+	// 持续监控只通知与基线相比新出现的子域名
+	if o.config.WebhookDeltaOnly {
+		if added, ok := o.output.AddedSinceBaseline(); ok {
+			delta := webhook.NewDelta(strings.Join(o.domains, ","), o.output.BaselinePath(), len(o.output.GetResults()), added)
+			if err := notifier.SendDelta(delta); err != nil {
+				logger.Errorf("Failed to send webhook notification: %v", err)
+			}
+			return
+		}
+		logger.Warn("webhook_delta_only needs a --baseline file, sending the full summary instead")
+	}
+
+	summary := webhook.Summary{
+		Domain:     strings.Join(o.domains, ","),
+		OutputPath: o.resultsLocation(),
+		Stats:      o.output.GetStats(),
+		Time:       time.Now().Format("2006-01-02 15:04:05"),
+	}
+	if err := notifier.Send(summary); err != nil {
+		logger.Errorf("Failed to send webhook notification: %v", err)
+	}
+}
+
 // showStats 显示统计信息
 func (o *OneForAll) showStats() {
 	stats := o.output.GetStats()
 	logger.Info("=== Collection Statistics ===")
 	logger.Infof("Total subdomains: %d", stats["total"])
-	logger.Infof("Alive subdomains: %d", stats["alive"])
-	logger.Infof("Dead subdomains: %d", stats["dead"])
+	if o.output.DiscoveryOnly() {
+		logger.Infof("Unvalidated subdomains: %d", stats["unvalidated"])
+	} else {
+		logger.Infof("Alive subdomains: %d", stats["alive"])
+		logger.Infof("Dead subdomains: %d", stats["dead"])
+	}
+	logger.Infof("Related domains: %d", stats["related"])
 
 	if sources, ok := stats["sources"].(map[string]int); ok {
 		logger.Info("Sources breakdown:")
@@ -444,7 +1183,35 @@ func (o *OneForAll) showStats() {
 		}
 	}
 
-	logger.Infof("Results saved to: %s", o.output.GetOutputPath())
+	if reasons, ok := stats["dead_reasons"].(map[string]int); ok && showDeadReasons {
+		logger.Info("Dead reasons breakdown:")
+		names := make([]string, 0, len(reasons))
+		for reason := range reasons {
+			names = append(names, reason)
+		}
+		sort.Slice(names, func(i, j int) bool { return reasons[names[i]] > reasons[names[j]] })
+		for _, reason := range names {
+			logger.Infof("  %s: %d", reason, reasons[reason])
+		}
+	}
+
+	logger.Infof("Results saved to: %s", o.resultsLocation())
+
+	if verbose {
+		o.showModuleTimings()
+	}
+}
+
+// showModuleTimings 按耗时从长到短显示各模块的耗时和结果数，便于找出拖慢运行的数据源
+func (o *OneForAll) showModuleTimings() {
+	timings := o.dispatcher.ModuleTimings()
+	if len(timings) == 0 {
+		return
+	}
+	logger.Info("=== Module Timings ===")
+	for _, line := range core.FormatModuleTimings(timings) {
+		logger.Info(line)
+	}
 }
 
 // version 显示版本信息
@@ -454,6 +1221,36 @@ func (o *OneForAll) version() {
 	fmt.Println("GitHub: https://github.com/oneforall-go")
 }
 
+// sources 注册所有模块并按分类列出模块名称，名称可用于 --only-modules/--skip-modules
+func (o *OneForAll) sources(w io.Writer) {
+	o.registerModules()
+	writeSources(w, o.dispatcher.Sources())
+}
+
+// writeSources 按分类输出模块名称，需要 API 密钥的模块带有 [API key] 标记
+func writeSources(w io.Writer, sources []core.SourceInfo) {
+	groups := make(map[string][]core.SourceInfo)
+	for _, source := range sources {
+		groups[source.Category] = append(groups[source.Category], source)
+	}
+
+	for _, category := range core.SourceCategories {
+		group := groups[category]
+		if len(group) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s (%d):\n", category, len(group))
+		for _, source := range group {
+			if source.RequiresAPIKey {
+				fmt.Fprintf(w, "  %-26s [API key]\n", source.Name)
+			} else {
+				fmt.Fprintf(w, "  %s\n", source.Name)
+			}
+		}
+	}
+	fmt.Fprintf(w, "%d modules\n", len(sources))
+}
+
 // check 检查环境
 func (o *OneForAll) check() error {
 	logger.Info("Checking environment...")
@@ -484,6 +1281,30 @@ var rootCmd = &cobra.Command{
 	Long: `OneForAll-Go is a powerful subdomain enumeration tool written in Go.
 It supports various modules for subdomain discovery including search, datasets, 
 certificates, crawl, check, intelligence, brute force, and validation.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// 加载指定的配置文件，文件不存在或格式错误时直接退出
+		if configFile != "" {
+			cfg, err := config.LoadFile(configFile)
+			if err != nil {
+				return err
+			}
+			if cfg.DebugEnabled {
+				logger.SetLevel("debug")
+			} else if cfg.LogLevel != "" {
+				logger.SetLevel(cfg.LogLevel)
+			}
+			logger.Infof("Loaded config file: %s", configFile)
+		}
+
+		// 数据文件目录，优先于 DATA_DIR 和配置文件
+		if dataDir != "" {
+			config.GetConfig().DataDir = dataDir
+		}
+
+		// 启动 Prometheus 指标服务（未设置地址时不启动）
+		metrics.Serve(metricsAddr)
+		return nil
+	},
 }
 
 // 创建运行命令
@@ -516,6 +1337,19 @@ var checkCmd = &cobra.Command{
 	},
 }
 
+// 创建模块列表命令
+var sourcesCmd = &cobra.Command{
+	Use:   "sources",
+	Short: "List all registered module names",
+	Long:  `List the names of all registered modules grouped by category, marking modules that need an API key. Names are accepted by --only-modules and --skip-modules.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// 只输出模块列表，不输出注册过程的日志
+		logger.SetLevel("warn")
+		oneforall := NewOneForAll()
+		oneforall.sources(os.Stdout)
+	},
+}
+
 // 创建runlib命令
 var runLibCmd = &cobra.Command{
 	Use:   "runlib",
@@ -526,6 +1360,26 @@ var runLibCmd = &cobra.Command{
 	},
 }
 
+// 创建服务命令
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run as an HTTP service",
+	Long:  `Start an HTTP server exposing subdomain enumeration via POST /enumerate.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runServe()
+	},
+}
+
+// 创建复查命令
+var recheckCmd = &cobra.Command{
+	Use:   "recheck",
+	Short: "Re-validate an existing result file",
+	Long:  `Re-run alive validation for subdomains in an existing CSV/JSON result file and write an updated file, without re-enumerating.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runRecheck()
+	},
+}
+
 // runLib 运行库调用
 func runOneForAll() error {
 	oneforall := NewOneForAll()
@@ -537,13 +1391,27 @@ func runLibCall() error {
 	return oneforall.runLib()
 }
 
+// runRecheck 运行复查模式
+func runRecheck() error {
+	oneforall := NewOneForAll()
+	return oneforall.recheck()
+}
+
+// runServe 运行服务模式，收到 SIGINT/SIGTERM 后优雅关闭
+func runServe() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return server.NewServer(serveAddr).ListenAndServe(ctx)
+}
+
 // processLibResults 处理库调用结果
-func (o *OneForAll) processLibResults(domain string, results []core.SubdomainResult) {
+func (o *OneForAll) processLibResults(output *core.OutputManager, domain string, results []core.SubdomainResult) {
 	logger.Infof("Processing %d results for domain %s", len(results), domain)
 
 	// 直接添加所有结果
 	for _, result := range results {
-		o.output.AddResult(result)
+		output.AddResult(result)
 	}
 
 	// 显示结果统计
@@ -574,20 +1442,55 @@ func init() {
 	logger.Init(logLevel, "")
 
 	// 设置根命令
-	rootCmd.AddCommand(runCmd, versionCmd, checkCmd, runLibCmd)
+	rootCmd.AddCommand(runCmd, versionCmd, checkCmd, runLibCmd, serveCmd, recheckCmd, sourcesCmd)
+
+	// 全局参数
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "配置文件路径 (YAML/JSON)，环境变量优先于文件，命令行参数优先于两者")
+	rootCmd.PersistentFlags().StringVar(&dataDir, "data-dir", "", "数据文件目录 (字典、DNS 服务器列表、CDN 列表等)，缺少的文件使用内置默认数据 (默认使用 DATA_DIR 配置或 ./data)")
+	rootCmd.PersistentFlags().StringVar(&metricsAddr, "metrics-addr", "", "Prometheus指标监听地址 (如 :9090)，为空时不启用")
 
 	// 设置run命令的参数
-	runCmd.Flags().StringVarP(&target, "target", "t", "", "Target domain (required)")
+	runCmd.Flags().StringVarP(&target, "target", "t", "", "目标域名，也支持 ASN (如 AS13335) 或 CIDR (如 192.0.2.0/24)")
 	runCmd.Flags().StringVarP(&targets, "targets", "f", "", "目标域名文件")
 	runCmd.Flags().BoolVarP(&brute, "brute", "b", false, "启用爆破模块")
 	runCmd.Flags().BoolVarP(&dns, "dns", "d", false, "启用DNS解析")
 	runCmd.Flags().BoolVarP(&req, "req", "r", false, "启用HTTP请求")
 	runCmd.Flags().StringVarP(&port, "port", "p", "80,443", "HTTP请求端口")
-	runCmd.Flags().BoolVarP(&alive, "alive", "a", false, "只导出存活域名")
-	runCmd.Flags().StringVarP(&outputFmt, "format", "o", "csv", "输出格式 (csv/json)")
+	runCmd.Flags().BoolVarP(&alive, "alive", "a", false, "只导出存活域名到结果文件 (默认使用 EXPORT_ALIVE_ONLY 配置)")
+	runCmd.Flags().BoolVar(&deadOnly, "dead-only", false, "只导出未存活域名及失败原因，用于排查 NXDOMAIN 接管候选 (优先于 --alive)")
+	runCmd.Flags().BoolVar(&exportAll, "all", false, "导出全部结果，包括未存活域名 (覆盖 EXPORT_ALIVE_ONLY/EXPORT_DEAD_ONLY 配置)")
+	runCmd.Flags().BoolVar(&showDeadReasons, "show-dead-reasons", false, "在统计信息中按失败原因分类显示未存活域名数")
+	runCmd.Flags().BoolVar(&jsonEnvelope, "json-envelope", false, "JSON 结果使用带版本的信封 {version, domain, generated_at, stats, results} (默认使用 JSON_ENVELOPE 配置)")
+	runCmd.Flags().BoolVar(&perDomain, "per-domain", false, "每个域名的结果写入 <path>/<domain>/ 目录，并在 <path>/index.json 中维护索引 (默认使用 PER_DOMAIN_OUTPUT 配置)")
+	runCmd.Flags().BoolVar(&txtWithScheme, "txt-with-scheme", false, "txt 格式每行加 https:// 前缀 (默认使用 TXT_WITH_SCHEME 配置)")
+	runCmd.Flags().BoolVar(&fingerprint, "fingerprint", false, "HTTP 探测时记录 Server/X-Powered-By 响应头，并按 data/web_fingerprints.json 识别 WordPress、Jenkins、GitLab 等 Web 技术 (默认使用 FINGERPRINT 配置)")
+	runCmd.Flags().BoolVar(&statsFile, "stats-file", false, "另外写入 <结果文件名>.stats.json，包含统计信息、目标域名和运行耗时 (默认使用 STATS_FILE 配置)")
+	runCmd.Flags().StringVarP(&outputFmt, "format", "o", "csv", "输出格式 (csv/json/md/txt/ipjson/elasticsearch)，txt 为每行一个子域名，ipjson 按 IP 分组")
+	runCmd.Flags().StringSliceVar(&csvColumns, "csv-columns", nil, "CSV 结果的列及顺序，逗号分隔，必须包含 subdomain，如 subdomain,ip,status_code,cname (默认使用 CSV_COLUMNS 配置或全部列)")
 	runCmd.Flags().StringVarP(&path, "path", "", "results", "结果保存路径")
 	runCmd.Flags().BoolVarP(&takeover, "takeover", "", false, "启用接管检查")
 	runCmd.Flags().BoolVarP(&show, "show", "s", false, "显示帮助信息")
+	runCmd.Flags().BoolVar(&verbose, "verbose", false, "运行结束后按耗时从长到短显示各模块的耗时和结果数")
+	runCmd.Flags().StringVar(&dnsServers, "dns-servers", "", "自定义DNS服务器，逗号分隔或 @文件路径 (默认端口53，tls:// 前缀使用 DNS over TLS)")
+	runCmd.Flags().StringVar(&ipVersion, "ip-version", "", "爆破、解析和验证查询的 IP 版本：4 (A 记录)、6 (AAAA 记录) 或 both (默认使用 IP_VERSION 配置)")
+	runCmd.Flags().StringVar(&includePattern, "include", "", "只保留匹配该正则的子域名")
+	runCmd.Flags().StringVar(&excludePattern, "exclude", "", "排除匹配该正则的子域名")
+	runCmd.Flags().StringVar(&baselineFile, "baseline", "", "基线结果文件 (csv/json)，导出时生成新增/消失子域名的差异报告")
+	runCmd.Flags().StringVar(&inputFormat, "input-format", "auto", "基线文件格式 (auto/csv/json)，auto 按扩展名和内容识别")
+	runCmd.Flags().IntVar(&domainConcurrency, "domain-concurrency", 1, "并发处理的域名数，每个域名使用独立的调度器")
+	runCmd.Flags().StringVar(&onlyModules, "only-modules", "", "只运行指定模块，逗号分隔的模块名称 (如 CrtshQuery,ShodanAPISearch)")
+	runCmd.Flags().StringVar(&skipModules, "skip-modules", "", "跳过指定模块，逗号分隔的模块名称")
+	runCmd.Flags().BoolVar(&dryRun, "dry-run", false, "预览模式：列出将运行的模块并生成爆破字典，不发送网络请求")
+	runCmd.Flags().StringSliceVar(&wordlists, "wordlist", nil, "爆破字典文件，可重复指定或逗号分隔，多个字典合并去重 (默认使用 BRUTE_WORDLISTS 配置或 data/subnames.txt)")
+	runCmd.Flags().BoolVar(&passive, "passive", false, "被动模式：只运行搜索、数据集、证书透明度和情报模块，不向目标发送流量，结果不做验证 (默认使用 PASSIVE 配置)")
+	runCmd.Flags().DurationVar(&maxRuntime, "max-runtime", 0, "整次运行的时间预算 (如 30m)，超出后取消剩余模块（包括爆破）并直接导出已有结果")
+	runCmd.Flags().IntVar(&maxResults, "max-results", 0, "单个域名收集的最大子域名数，达到后停止剩余模块并直接验证导出 (默认使用 MAX_RESULTS 配置)")
+	runCmd.Flags().IntVar(&ptrSweep, "ptr-sweep", 0, "丰富步骤对最多 N 个非 CDN IP 所在的 /24 网段扫描全部 256 个地址的 PTR 记录 (默认使用 PTR_SWEEP_LIMIT 配置，0 不扫描)")
+	runCmd.Flags().IntVar(&minConfidence, "min-confidence", 0, "只导出置信度 (0-100) 不低于该值的结果 (默认使用 MIN_CONFIDENCE 配置)")
+	runCmd.Flags().StringVar(&userAgent, "user-agent", "", "自定义 User-Agent，替代内置的随机 User-Agent (默认使用 USER_AGENT 配置)")
+	runCmd.Flags().BoolVar(&deep, "deep", false, "启用深度查询：SecurityTrails 已不活跃的子域名、A 记录历史和关联域名 (额外消耗 API 额度)")
+	runCmd.Flags().BoolVar(&noPreflight, "no-preflight", false, "跳过运行前的网络连接和 DNS 服务器检查 (默认使用 PREFLIGHT 配置)")
+	runCmd.Flags().BoolVar(&noValidation, "no-validation", false, "快速模式：不做 DNS/HTTP 验证，只导出去重后的子域名及来源，统计中计为未验证 (默认使用 ENABLE_DOMAIN_VALIDATION 配置)")
 
 	// 新架构参数
 	runCmd.Flags().BoolVarP(&searchModules, "search", "", false, "启用搜索模块")
@@ -598,13 +1501,66 @@ func init() {
 	runCmd.Flags().BoolVarP(&enrichModules, "enrich", "", false, "启用丰富模块")
 
 	// 库调用参数
-	runLibCmd.Flags().StringVarP(&target, "target", "t", "", "Target domain (required)")
+	runLibCmd.Flags().StringVarP(&target, "target", "t", "", "目标域名，也支持 ASN (如 AS13335) 或 CIDR (如 192.0.2.0/24)")
 	runLibCmd.Flags().BoolVar(&enableValidation, "enable-validation", true, "Enable domain validation")
+	runLibCmd.Flags().BoolVar(&noValidation, "no-validation", false, "Skip validation and export only the deduplicated subdomains with their sources (same as --enable-validation=false)")
 	runLibCmd.Flags().BoolVar(&enableBruteForce, "enable-brute-force", false, "Enable brute force attack")
 	runLibCmd.Flags().IntVar(&libConcurrency, "concurrency", 10, "Concurrency level")
 	runLibCmd.Flags().IntVar(&libTimeout, "timeout", 60, "Timeout in seconds")
 	runLibCmd.Flags().BoolVar(&debug, "debug", false, "Enable debug mode")
-	runLibCmd.Flags().BoolVar(&verbose, "verbose", false, "Enable verbose logging")
+	runLibCmd.Flags().BoolVar(&verbose, "verbose", false, "Print a per-module timing report (slowest first) after the run")
+	runLibCmd.Flags().StringVar(&dnsServers, "dns-servers", "", "Custom DNS servers, comma-separated or @file (default port 53; tls:// prefix for DNS over TLS)")
+	runLibCmd.Flags().StringVar(&ipVersion, "ip-version", "", "IP version queried by brute force, resolution and validation: 4 (A), 6 (AAAA) or both (default from IP_VERSION)")
+	runLibCmd.Flags().StringVar(&includePattern, "include", "", "Only keep subdomains matching this regex")
+	runLibCmd.Flags().StringVar(&excludePattern, "exclude", "", "Drop subdomains matching this regex")
+	runLibCmd.Flags().StringVar(&baselineFile, "baseline", "", "Baseline result file (csv/json) to diff against")
+	runLibCmd.Flags().StringVar(&inputFormat, "input-format", "auto", "Baseline file format (auto/csv/json); auto detects from extension and content")
+	runLibCmd.Flags().IntVar(&domainConcurrency, "domain-concurrency", 1, "Number of domains processed in parallel")
+	runLibCmd.Flags().StringVar(&onlyModules, "only-modules", "", "Only run these modules (comma-separated module names)")
+	runLibCmd.Flags().StringVar(&skipModules, "skip-modules", "", "Skip these modules (comma-separated module names)")
+	runLibCmd.Flags().BoolVar(&dryRun, "dry-run", false, "List modules and generate brute force candidates without sending traffic")
+	runLibCmd.Flags().StringSliceVar(&wordlists, "wordlist", nil, "Brute force wordlist file; repeatable or comma-separated, merged and deduplicated (default from BRUTE_WORDLISTS or data/subnames.txt)")
+	runLibCmd.Flags().BoolVar(&passive, "passive", false, "Only run search, dataset, certificate and intelligence modules; no traffic to the target and no validation (default from PASSIVE)")
+	runLibCmd.Flags().DurationVar(&maxRuntime, "max-runtime", 0, "Wall-clock budget for the whole run (e.g. 30m); remaining work is cancelled and results exported")
+	runLibCmd.Flags().IntVar(&maxResults, "max-results", 0, "Stop collecting once this many subdomains are found per domain (0 uses MAX_RESULTS)")
+	runLibCmd.Flags().IntVar(&ptrSweep, "ptr-sweep", 0, "Sweep PTR records of all 256 addresses in up to N non-CDN /24 networks during enrichment (0 uses PTR_SWEEP_LIMIT)")
+	runLibCmd.Flags().IntVar(&minConfidence, "min-confidence", 0, "Only export results with confidence (0-100) at or above this value (0 uses MIN_CONFIDENCE)")
+	runLibCmd.Flags().BoolVar(&deadOnly, "dead-only", false, "Only export non-alive subdomains with their failure reason (overrides alive-only)")
+	runLibCmd.Flags().BoolVar(&exportAll, "all", false, "Export all results including non-alive subdomains (overrides EXPORT_ALIVE_ONLY/EXPORT_DEAD_ONLY)")
+	runLibCmd.Flags().BoolVar(&showDeadReasons, "show-dead-reasons", false, "Show non-alive subdomain counts by failure reason in stats")
+	runLibCmd.Flags().BoolVar(&jsonEnvelope, "json-envelope", false, "Wrap JSON output in a versioned envelope {version, domain, generated_at, stats, results}")
+	runLibCmd.Flags().BoolVar(&perDomain, "per-domain", false, "Write each domain's results under <path>/<domain>/ and keep an index.json manifest")
+	runLibCmd.Flags().BoolVar(&txtWithScheme, "txt-with-scheme", false, "Prefix each host with https:// in txt output")
+	runLibCmd.Flags().BoolVar(&fingerprint, "fingerprint", false, "Record Server/X-Powered-By headers and detect web technologies from data/web_fingerprints.json during HTTP probing (default from FINGERPRINT)")
+	runLibCmd.Flags().BoolVar(&statsFile, "stats-file", false, "Also write <output>.stats.json with stats, domain and run duration (default from STATS_FILE)")
+	runLibCmd.Flags().StringSliceVar(&csvColumns, "csv-columns", nil, "CSV columns and their order, comma-separated, must include subdomain, e.g. subdomain,ip,status_code,cname (default from CSV_COLUMNS or all columns)")
+	runLibCmd.Flags().StringVar(&userAgent, "user-agent", "", "Custom User-Agent replacing the built-in rotation (empty uses USER_AGENT)")
+	runLibCmd.Flags().BoolVar(&deep, "deep", false, "Run quota-heavy deep queries (SecurityTrails inactive subdomains, DNS history, associated domains)")
+	runLibCmd.Flags().BoolVar(&noPreflight, "no-preflight", false, "Skip the connectivity and resolver check before enumeration")
+
+	// 复查模式参数
+	recheckCmd.Flags().StringVarP(&recheckInput, "input", "i", "", "已有结果文件 (csv/json)")
+	recheckCmd.Flags().StringVar(&inputFormat, "input-format", "auto", "输入文件格式 (auto/csv/json)，auto 按扩展名和内容识别")
+	recheckCmd.Flags().StringVarP(&outputFmt, "format", "o", "csv", "输出格式 (csv/json/md/txt/ipjson/elasticsearch)，与输入格式无关")
+	recheckCmd.Flags().StringSliceVar(&csvColumns, "csv-columns", nil, "CSV 结果的列及顺序，逗号分隔，必须包含 subdomain (默认使用 CSV_COLUMNS 配置或全部列)")
+	recheckCmd.Flags().StringVarP(&path, "path", "", "results", "结果保存路径")
+	recheckCmd.Flags().BoolVarP(&alive, "alive", "a", false, "只导出存活域名到结果文件 (默认使用 EXPORT_ALIVE_ONLY 配置)")
+	recheckCmd.Flags().BoolVar(&deadOnly, "dead-only", false, "只导出未存活域名及失败原因 (优先于 --alive)")
+	recheckCmd.Flags().BoolVar(&exportAll, "all", false, "导出全部结果，包括未存活域名 (覆盖 EXPORT_ALIVE_ONLY/EXPORT_DEAD_ONLY 配置)")
+	recheckCmd.Flags().BoolVar(&showDeadReasons, "show-dead-reasons", false, "在统计信息中按失败原因分类显示未存活域名数")
+	recheckCmd.Flags().BoolVar(&jsonEnvelope, "json-envelope", false, "JSON 结果使用带版本的信封 (默认使用 JSON_ENVELOPE 配置)")
+	recheckCmd.Flags().BoolVar(&txtWithScheme, "txt-with-scheme", false, "txt 格式每行加 https:// 前缀 (默认使用 TXT_WITH_SCHEME 配置)")
+	recheckCmd.Flags().BoolVar(&fingerprint, "fingerprint", false, "HTTP 探测时记录 Server/X-Powered-By 响应头，并按 data/web_fingerprints.json 识别 Web 技术 (默认使用 FINGERPRINT 配置)")
+	recheckCmd.Flags().BoolVar(&statsFile, "stats-file", false, "另外写入 <结果文件名>.stats.json，包含统计信息和复查耗时 (默认使用 STATS_FILE 配置)")
+	recheckCmd.Flags().IntVar(&recheckConcurrency, "concurrency", 0, "验证并发数 (默认使用 VALIDATION_CONCURRENCY 配置)")
+	recheckCmd.Flags().StringVar(&dnsServers, "dns-servers", "", "自定义DNS服务器，逗号分隔或 @文件路径 (默认端口53，tls:// 前缀使用 DNS over TLS)")
+	recheckCmd.Flags().StringVar(&ipVersion, "ip-version", "", "验证解析的 IP 版本：4 (A 记录)、6 (AAAA 记录) 或 both (默认使用 IP_VERSION 配置)")
+	recheckCmd.Flags().StringVar(&includePattern, "include", "", "只保留匹配该正则的子域名")
+	recheckCmd.Flags().StringVar(&excludePattern, "exclude", "", "排除匹配该正则的子域名")
+	recheckCmd.MarkFlagRequired("input")
+
+	// 服务模式参数
+	serveCmd.Flags().StringVar(&serveAddr, "listen", ":8080", "HTTP listen address")
 
 	// 设置必需参数
 	runCmd.MarkFlagRequired("target")
@@ -628,6 +1584,14 @@ func loadEnvironment() {
 }
 
 func main() {
+	// 兜底：run/runLib 已导出部分结果，这里只输出错误和调用栈并以非零状态退出
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(os.Stderr, "Fatal: %v\n%s", r, runtimedebug.Stack())
+			os.Exit(2)
+		}
+	}()
+
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
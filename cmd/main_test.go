@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	mdns "github.com/miekg/dns"
+	"github.com/oneforall-go/internal/config"
+	"github.com/oneforall-go/internal/core"
+)
+
+func TestSourcesListsRegisteredModules(t *testing.T) {
+	cfg := &config.Config{}
+	o := &OneForAll{config: cfg, dispatcher: core.NewDispatcher(cfg)}
+	o.registerModules()
+
+	sources := make(map[string]core.SourceInfo)
+	for _, source := range o.dispatcher.Sources() {
+		sources[source.Name] = source
+	}
+
+	tests := []struct {
+		name     string
+		category string
+		apiKey   bool
+	}{
+		{"GoogleSearch", "search", false},
+		{"ShodanAPISearch", "search", true},
+		{"RapidDNSQuery", "dataset", false},
+		{"SecurityTrailsAPIQuery", "dataset", true},
+		{"CrtshQuery", "certificate", false},
+		{"ArchiveCrawl", "crawl", false},
+		{"QueryTXT", "dns", false},
+		{"AXFRCheck", "check", false},
+		{"VirusTotalAPIQuery", "intelligence", true},
+		{"Brute", "brute", false},
+		{"Alt", "brute", false},
+		{"enrich", "enrich", false},
+	}
+	for _, tt := range tests {
+		source, ok := sources[tt.name]
+		if !ok {
+			t.Errorf("Expected module %s to be listed", tt.name)
+			continue
+		}
+		if source.Category != tt.category || source.RequiresAPIKey != tt.apiKey {
+			t.Errorf("%s: expected category %s and API key %v, got %+v", tt.name, tt.category, tt.apiKey, source)
+		}
+	}
+
+	var out bytes.Buffer
+	writeSources(&out, o.dispatcher.Sources())
+	listing := out.String()
+	if !strings.HasPrefix(listing, "search (") || !strings.Contains(listing, "\nenrich (1):\n  enrich\n") {
+		t.Errorf("Unexpected grouping:\n%s", listing)
+	}
+	if !strings.Contains(listing, "ShodanAPISearch") || !strings.Contains(listing, "[API key]") {
+		t.Errorf("Expected API key modules to be marked:\n%s", listing)
+	}
+}
+
+func TestProcessDomainsWithoutBudget(t *testing.T) {
+	cfg := &config.Config{}
+	o := &OneForAll{config: cfg, dispatcher: core.NewDispatcher(cfg), output: core.NewOutputManager(cfg)}
+	o.domains = []string{"a.example.com", "b.example.com", "c.example.com"}
+	defer func(saved int) { domainConcurrency = saved }(domainConcurrency)
+
+	for _, workers := range []int{1, 2} {
+		domainConcurrency = workers
+		cancel := o.startBudget()
+
+		var mutex sync.Mutex
+		var processed []string
+		o.processDomains(func(dispatcher *core.Dispatcher, domain string) {
+			mutex.Lock()
+			processed = append(processed, domain)
+			mutex.Unlock()
+		})
+		cancel()
+
+		if len(processed) != len(o.domains) {
+			t.Errorf("workers=%d: expected %d processed domains, got %v", workers, len(o.domains), processed)
+		}
+	}
+}
+
+func TestExpandNetworkTargetsAllowList(t *testing.T) {
+	ptrs := map[string]string{
+		"1.2.0.192.in-addr.arpa.": "www.example.com.",
+		"2.2.0.192.in-addr.arpa.": "ec2-192-0-2-2.example.net.",
+	}
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	server := &mdns.Server{PacketConn: pc, NotifyStartedFunc: func() { close(started) }, Handler: mdns.HandlerFunc(func(w mdns.ResponseWriter, req *mdns.Msg) {
+		resp := new(mdns.Msg)
+		resp.SetReply(req)
+		if target, ok := ptrs[req.Question[0].Name]; ok {
+			resp.Answer = append(resp.Answer, &mdns.PTR{
+				Hdr: mdns.RR_Header{Name: req.Question[0].Name, Rrtype: mdns.TypePTR, Class: mdns.ClassINET, Ttl: 60},
+				Ptr: target,
+			})
+		}
+		w.WriteMsg(resp)
+	})}
+	go server.ActivateAndServe()
+	<-started
+	defer server.Shutdown()
+
+	cfg := &config.Config{MaxCIDRHosts: 256, Resolvers: []string{pc.LocalAddr().String()}}
+	cfg.MultiThreading.EnrichConcurrency = 4
+	cfg.MultiThreading.EnrichTimeout = 2
+
+	// 未列入 NETWORK_TARGET_DOMAINS 的注册域名不作为目标
+	o := &OneForAll{config: cfg, output: core.NewOutputManager(cfg), domains: []string{"192.0.2.0/30"}}
+	if err := o.expandNetworkTargets(); err == nil {
+		t.Fatalf("Expected an error without allowed domains, got targets %v", o.domains)
+	}
+
+	cfg.NetworkTargetDomains = []string{"Example.com"}
+	o = &OneForAll{config: cfg, output: core.NewOutputManager(cfg), domains: []string{"192.0.2.0/30"}}
+	if err := o.expandNetworkTargets(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"example.com"}; !reflect.DeepEqual(o.domains, want) {
+		t.Errorf("Expected targets %v, got %v", want, o.domains)
+	}
+	if seeds := o.seeds["example.com"]; len(seeds) != 1 || seeds[0].Subdomain != "www.example.com" {
+		t.Errorf("Unexpected seeds for example.com: %v", seeds)
+	}
+	results := o.output.GetResults()
+	if len(results) != 1 || results[0].Subdomain != "ec2-192-0-2-2.example.net" {
+		t.Errorf("Expected the third-party PTR name as a plain result, got %v", results)
+	}
+}
+
+func TestExportAllFlag(t *testing.T) {
+	defer func(a, d, all bool) { alive, deadOnly, exportAll = a, d, all }(alive, deadOnly, exportAll)
+
+	cfg := config.GetConfig().Clone()
+	cfg.ExportAliveOnly = true
+	cfg.ExportDeadOnly = true
+	o := &OneForAll{config: cfg, dispatcher: core.NewDispatcher(cfg), output: core.NewOutputManager(cfg)}
+
+	alive, deadOnly, exportAll = false, false, true
+	if err := o.configParam(); err != nil {
+		t.Fatalf("configParam failed: %v", err)
+	}
+	if cfg.ExportAliveOnly || cfg.ExportDeadOnly {
+		t.Errorf("Expected --all to export every result, got alive-only=%v dead-only=%v", cfg.ExportAliveOnly, cfg.ExportDeadOnly)
+	}
+
+	alive = true
+	if err := o.configParam(); err == nil {
+		t.Error("Expected --all with --alive to be rejected")
+	}
+}
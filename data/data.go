@@ -0,0 +1,23 @@
+// Package data 数据目录，内置其中的默认数据文件，运行目录中缺少数据文件时作为回退
+package data
+
+import "embed"
+
+// Defaults 内置的默认数据文件；完整的 subnames.txt 体积较大，只内置 wordlists/subnames.txt 中的常用子域名
+//
+//go:embed altdns_wordlist.txt asn_prefixes.json cdn_cname.json cdn_ip_cidr.json common_subnames.txt nameservers.txt nameservers_cn.txt
+//go:embed srv_prefixes.txt subnames_next.txt web_fingerprints.json wordlists/subnames.txt
+var Defaults embed.FS
+
+// builtinNames 数据文件名到内置文件的映射，未列出的文件按同名查找
+var builtinNames = map[string]string{
+	"subnames.txt": "wordlists/subnames.txt",
+}
+
+// BuiltinName 返回数据文件对应的内置文件名
+func BuiltinName(name string) string {
+	if builtin, ok := builtinNames[name]; ok {
+		return builtin
+	}
+	return name
+}
@@ -44,6 +44,16 @@ func main() {
 	fmt.Printf("Alive Percentage: %.2f%%\n", result.AlivePercentage)
 	fmt.Printf("Execution Time: %v\n", result.ExecutionTime)
 
+	// 打印来源与IP提供商统计
+	fmt.Printf("\n=== Source Breakdown ===\n")
+	for source, count := range result.SourceBreakdown {
+		fmt.Printf("  %s: %d\n", source, count)
+	}
+	fmt.Printf("\n=== Provider Breakdown ===\n")
+	for provider, count := range result.ProviderBreakdown {
+		fmt.Printf("  %s: %d\n", provider, count)
+	}
+
 	// 打印详细结果
 	fmt.Printf("\n=== Detailed Results ===\n")
 	for i, subdomain := range result.Results {
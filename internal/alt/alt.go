@@ -3,8 +3,6 @@ package alt
 import (
 	"bufio"
 	"fmt"
-	"os"
-	"path/filepath"
 	"regexp"
 	"strings"
 
@@ -35,6 +33,7 @@ type Alt struct {
 	enableReplaceWord bool
 	enableInsertWord  bool
 	enableAddWord     bool
+	seed              []string // 前面步骤已收集的子域名，由调度器通过 SetSeed 传入
 }
 
 // NewAlt 创建 Alt 模块
@@ -54,9 +53,27 @@ func NewAlt(cfg *config.Config) *Alt {
 	}
 }
 
+// SetSeed 设置用于生成变体的已知子域名（实现 core.SeedConsumer）
+func (a *Alt) SetSeed(subdomains []string) {
+	a.seed = subdomains
+}
+
+// Reset 清空上一个域名的单词、子域名和种子（实现 core.Resetter）
+func (a *Alt) Reset() {
+	a.BaseModule.Reset()
+	a.domain = ""
+	a.words = make(map[string]bool)
+	a.nowSubdomains = make(map[string]bool)
+	a.newSubdomains = make(map[string]bool)
+	a.seed = nil
+}
+
 // Run 执行 Alt 模块
 func (a *Alt) Run(domain string) ([]string, error) {
 	logger.Infof("=== Starting Alt module for domain: %s ===", domain)
+	a.domain = domain
+	a.nowSubdomains = make(map[string]bool)
+	a.newSubdomains = make(map[string]bool)
 
 	// 获取字典
 	logger.Debugf("Loading altdns wordlist...")
@@ -66,13 +83,18 @@ func (a *Alt) Run(domain string) ([]string, error) {
 	}
 	logger.Debugf("Loaded %d words from altdns wordlist", len(a.words))
 
-	// 加载现有子域名（这里需要从其他模块获取）
-	// 暂时使用空列表，实际使用时需要从其他模块的结果中获取
-	existingSubdomains := []string{}
-	for _, subdomain := range existingSubdomains {
-		a.nowSubdomains[subdomain] = true
+	// 加载前面步骤发现的子域名，没有种子时不生成变体
+	for _, subdomain := range a.seed {
+		subdomain = core.NormalizeHost(subdomain)
+		if subdomain != domain && core.InScope(subdomain, domain) {
+			a.nowSubdomains[subdomain] = true
+		}
+	}
+	logger.Debugf("Loaded %d existing subdomains", len(a.nowSubdomains))
+	if len(a.nowSubdomains) == 0 {
+		logger.Infof("Alt module has no seed subdomains for %s, skipping", domain)
+		return []string{}, nil
 	}
-	logger.Debugf("Loaded %d existing subdomains", len(existingSubdomains))
 
 	// 提取单词
 	logger.Debugf("Extracting words from existing subdomains...")
@@ -102,8 +124,7 @@ func (a *Alt) Run(domain string) ([]string, error) {
 
 // getWords 获取字典
 func (a *Alt) getWords() error {
-	path := filepath.Join("data", "altdns_wordlist.txt")
-	file, err := os.Open(path)
+	file, err := a.GetConfig().OpenDataFile("altdns_wordlist.txt")
 	if err != nil {
 		return fmt.Errorf("failed to open altdns wordlist: %v", err)
 	}
@@ -0,0 +1,114 @@
+package brute
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/oneforall-go/pkg/logger"
+)
+
+// errResolverFailure 所有 DNS 服务器都没有给出明确应答（超时、SERVFAIL、REFUSED 等），区别于 NXDOMAIN
+var errResolverFailure = errors.New("no definitive answer from resolvers")
+
+// adaptiveWindow 每收集多少个查询结果评估一次错误率
+const adaptiveWindow = 200
+
+// adaptiveLimiter 按解析器错误率自动调整并发数的信号量：错误率超过目标时减半，低于目标一半时逐步增加
+type adaptiveLimiter struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	limit  int
+	inUse  int
+	min    int
+	max    int
+	target float64 // 目标错误率（百分比）
+	window int
+
+	samples  int
+	failures int
+}
+
+// newAdaptiveLimiter 创建并发控制器，初始并发数限制在 [min, max] 内
+func newAdaptiveLimiter(initial, min, max int, target float64) *adaptiveLimiter {
+	if min <= 0 {
+		min = 1
+	}
+	if max < min {
+		max = min
+	}
+	if initial < min {
+		initial = min
+	}
+	if initial > max {
+		initial = max
+	}
+
+	l := &adaptiveLimiter{
+		limit:  initial,
+		min:    min,
+		max:    max,
+		target: target,
+		window: adaptiveWindow,
+	}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// Acquire 获取一个并发槽位，当前并发数达到上限时阻塞
+func (l *adaptiveLimiter) Acquire() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.inUse >= l.limit {
+		l.cond.Wait()
+	}
+	l.inUse++
+}
+
+// Release 释放槽位并记录本次查询是否因解析器错误失败
+func (l *adaptiveLimiter) Release(failed bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inUse--
+	l.samples++
+	if failed {
+		l.failures++
+	}
+	if l.samples >= l.window {
+		l.adjust()
+	}
+	l.cond.Broadcast()
+}
+
+// adjust 根据当前窗口的错误率调整并发数，调用方需持有锁
+func (l *adaptiveLimiter) adjust() {
+	rate := float64(l.failures) / float64(l.samples) * 100
+	l.samples, l.failures = 0, 0
+
+	previous := l.limit
+	switch {
+	case rate > l.target:
+		l.limit = previous / 2
+	case rate < l.target/2:
+		l.limit = previous + previous/4 + 1
+	}
+	if l.limit < l.min {
+		l.limit = l.min
+	}
+	if l.limit > l.max {
+		l.limit = l.max
+	}
+
+	if l.limit < previous {
+		logger.Infof("Resolver error rate %.1f%% above target %.1f%%, reducing brute concurrency %d -> %d", rate, l.target, previous, l.limit)
+	} else if l.limit > previous {
+		logger.Debugf("Resolver error rate %.1f%% below target %.1f%%, raising brute concurrency %d -> %d", rate, l.target, previous, l.limit)
+	}
+}
+
+// Limit 返回当前并发上限
+func (l *adaptiveLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
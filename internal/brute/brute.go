@@ -3,11 +3,15 @@ package brute
 import (
 	"bufio"
 	"crypto/rand"
+	"errors"
 	"fmt"
+	"io"
 	"math/big"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -15,25 +19,55 @@ import (
 	"github.com/miekg/dns"
 	"github.com/oneforall-go/internal/config"
 	"github.com/oneforall-go/internal/core"
+	dnsutil "github.com/oneforall-go/internal/dns"
 	"github.com/oneforall-go/pkg/logger"
 )
 
+// dnsQueryAttempts 每个 DNS 服务器的 UDP 查询次数（含一次重试）
+const dnsQueryAttempts = 2
+
 // Brute 爆破模块
 type Brute struct {
 	*core.BaseModule
 	domain         string
 	wordlist       string
 	nextlist       string
+	wordlists      []string // 用户指定的字典文件，合并去重后生成候选，为空时使用 wordlist
 	concurrent     int
+	minConcurrent  int     // 自动调整并发数的下限
+	maxConcurrent  int     // 自动调整并发数的上限
+	targetErrRate  float64 // 解析器错误率目标（百分比），超过时降低并发数
 	recursive      bool
 	depth          int
 	enableWildcard bool
 	wildcardIPs    []string
 	wildcardTTL    int
 	nameservers    []string
+	resolvers      []string   // 用户指定的DNS服务器，设置后替代权威/公共DNS
+	clientSubnet   *net.IPNet // EDNS Client Subnet，地址记录查询时附加
+	recordTypes    []uint16   // 按 IP 版本查询的地址记录类型（A、AAAA）
+	insecureTLS    bool       // DoT 服务器不校验证书
 	results        map[string]*BruteResult
+	onFound        func(BruteResult) // 发现有效子域名时立即回调，为 nil 时不回调
+	wildcardCache  *WildcardCache
 	mu             sync.RWMutex
 
+	// 递归爆破子区域时使用 nextlist 字典；泛解析子区域的泛解析 IP，只解析到这些 IP 的候选视为不存在
+	subZone        bool
+	wildcardFilter map[string]bool
+
+	// DNS 服务器对不存在的域名返回的 NXDOMAIN 劫持 IP，解析结果中忽略
+	hijackIPs map[string]bool
+
+	// 字典去重：预计候选数超过阈值时使用布隆过滤器
+	dedupThreshold int
+	dedupFPRate    float64
+
+	// 泛解析检测参数
+	wildcardTestCount        int
+	wildcardSuccessThreshold float64 // 成功率阈值（百分比）
+	wildcardRepeatThreshold  float64 // IP重复率阈值（百分比）
+
 	// 进度跟踪
 	totalCount     int
 	processedCount int
@@ -66,14 +100,43 @@ type WildcardDetectionResult struct {
 // NewBrute 创建爆破模块
 func NewBrute(cfg *config.Config) *Brute {
 	brute := &Brute{
-		BaseModule: core.NewBaseModule("Brute", core.ModuleTypeBrute, cfg),
-		domain:     "",
-		wordlist:   "",
-		nextlist:   "",
-		concurrent: 20, // 默认设置为20个线程
-		recursive:  false,
-		depth:      1,
-		results:    make(map[string]*BruteResult),
+		BaseModule:  core.NewBaseModule("Brute", core.ModuleTypeBrute, cfg),
+		domain:      "",
+		wordlist:    "",
+		nextlist:    "",
+		concurrent:  20, // 默认设置为20个线程
+		recursive:   false,
+		depth:       1,
+		wordlists:   append([]string(nil), cfg.BruteWordlists...),
+		resolvers:   cfg.Resolvers,
+		insecureTLS: cfg.DoTInsecureSkipVerify,
+		results:     make(map[string]*BruteResult),
+
+		dedupThreshold: cfg.DedupBloomThreshold,
+		dedupFPRate:    cfg.DedupFalsePositiveRate,
+	}
+	brute.wildcardCache = getSharedWildcardCache(cfg)
+	brute.recordTypes = dnsutil.RecordTypes(cfg.IPVersion)
+
+	// EDNS Client Subnet，配置无效时不附加
+	if subnet, err := dnsutil.ParseClientSubnet(cfg.EDNSClientSubnet); err != nil {
+		logger.Warnf("Ignoring EDNS client subnet: %v", err)
+	} else {
+		brute.clientSubnet = subnet
+	}
+
+	// 泛解析检测参数，未配置时使用默认值
+	brute.wildcardTestCount = 20
+	brute.wildcardSuccessThreshold = 90.0
+	brute.wildcardRepeatThreshold = 50.0
+	if cfg.WildcardTestCount > 0 {
+		brute.wildcardTestCount = cfg.WildcardTestCount
+	}
+	if cfg.WildcardSuccessRateThreshold > 0 {
+		brute.wildcardSuccessThreshold = cfg.WildcardSuccessRateThreshold
+	}
+	if cfg.WildcardIPRepeatRateThreshold > 0 {
+		brute.wildcardRepeatThreshold = cfg.WildcardIPRepeatRateThreshold
 	}
 
 	// 如果配置中有设置，则使用配置值
@@ -81,6 +144,20 @@ func NewBrute(cfg *config.Config) *Brute {
 		brute.concurrent = cfg.MultiThreading.BruteForceConcurrency
 	}
 
+	// 并发数自动调整范围，未配置时固定为初始并发数
+	brute.minConcurrent = brute.concurrent
+	brute.maxConcurrent = brute.concurrent
+	brute.targetErrRate = 5.0
+	if cfg.BruteMinConcurrency > 0 {
+		brute.minConcurrent = cfg.BruteMinConcurrency
+	}
+	if cfg.BruteMaxConcurrency > 0 {
+		brute.maxConcurrent = cfg.BruteMaxConcurrency
+	}
+	if cfg.BruteTargetErrorRate > 0 {
+		brute.targetErrRate = cfg.BruteTargetErrorRate
+	}
+
 	// 初始化字典路径
 	brute.initDictPaths()
 
@@ -95,6 +172,7 @@ func (b *Brute) Run(domain string) ([]string, error) {
 	logger.Debugf("  - Domain: %s", domain)
 	logger.Debugf("  - Concurrent: %d", b.concurrent)
 	logger.Debugf("  - Wordlist: %s", b.wordlist)
+	logger.Debugf("  - Wordlists: %v", b.wordlists)
 	logger.Debugf("  - Nextlist: %s", b.nextlist)
 	logger.Debugf("  - Recursive: %t", b.recursive)
 	logger.Debugf("  - Depth: %d", b.depth)
@@ -112,9 +190,12 @@ func (b *Brute) Run(domain string) ([]string, error) {
 	}
 	logger.Debugf("Nameservers loaded: %v", b.nameservers)
 
+	// 校准 NXDOMAIN 劫持，须在泛解析检测之前，否则劫持 IP 会被误判为泛解析
+	b.hijackIPs = b.detectHijack()
+
 	// 高级泛解析检测
 	logger.Debugf("Starting advanced wildcard detection for domain: %s", domain)
-	wildcardResult, err := b.detectWildcardAdvanced(domain)
+	wildcardResult, err := b.wildcardCache.Detect(domain, b.detectWildcardAdvanced)
 	if err != nil {
 		logger.Errorf("Failed to detect wildcard: %v", err)
 		return nil, err
@@ -124,8 +205,8 @@ func (b *Brute) Run(domain string) ([]string, error) {
 	if wildcardResult.IsWildcard {
 		logger.Warnf("Wildcard DNS detected for domain %s. Skipping brute force attack.", domain)
 		logger.Infof("Wildcard detection details:")
-		logger.Infof("  - Success rate: %.2f%% (threshold: 90%%)", wildcardResult.SuccessRate)
-		logger.Infof("  - IP repeat rate: %.2f%% (threshold: 50%%)", wildcardResult.IPRepeatRate)
+		logger.Infof("  - Success rate: %.2f%% (threshold: %.0f%%)", wildcardResult.SuccessRate, b.wildcardSuccessThreshold)
+		logger.Infof("  - IP repeat rate: %.2f%% (threshold: %.0f%%)", wildcardResult.IPRepeatRate, b.wildcardRepeatThreshold)
 		logger.Infof("  - Test subdomains: %v", wildcardResult.TestSubdomains)
 		logger.Infof("  - Reason: Domain has wildcard DNS enabled, brute force would be ineffective")
 		return []string{}, nil
@@ -134,8 +215,8 @@ func (b *Brute) Run(domain string) ([]string, error) {
 	// 没有检测到泛解析，继续爆破
 	logger.Infof("No wildcard DNS detected for domain %s. Proceeding with brute force attack.", domain)
 	logger.Infof("Wildcard detection details:")
-	logger.Infof("  - Success rate: %.2f%% (threshold: 90%%)", wildcardResult.SuccessRate)
-	logger.Infof("  - IP repeat rate: %.2f%% (threshold: 50%%)", wildcardResult.IPRepeatRate)
+	logger.Infof("  - Success rate: %.2f%% (threshold: %.0f%%)", wildcardResult.SuccessRate, b.wildcardSuccessThreshold)
+	logger.Infof("  - IP repeat rate: %.2f%% (threshold: %.0f%%)", wildcardResult.IPRepeatRate, b.wildcardRepeatThreshold)
 	logger.Infof("  - Test subdomains: %v", wildcardResult.TestSubdomains)
 	logger.Infof("  - Reason: Domain does not have wildcard DNS, brute force will be effective")
 
@@ -147,17 +228,17 @@ func (b *Brute) Run(domain string) ([]string, error) {
 	}
 	logger.Debugf("Fallback wildcard detection completed: enableWildcard=%t, wildcardIPs=%v", b.enableWildcard, b.wildcardIPs)
 
-	// 生成爆破字典
+	// 流式生成爆破字典，字典不整体加载到内存
 	logger.Debugf("Generating dictionary for domain: %s", domain)
-	subdomains, err := b.generateDict(domain)
+	dict, err := b.openDict(domain)
 	if err != nil {
 		logger.Errorf("Failed to generate dictionary: %v", err)
 		return nil, err
 	}
-	logger.Infof("Dictionary generated: %d subdomains", len(subdomains))
+	logger.Infof("Dictionary opened: up to %d subdomains", dict.Lines)
 
-	// 初始化统计信息
-	b.totalCount = len(subdomains)
+	// 初始化统计信息，总数先按字典行数计，读完字典后修正为实际候选数
+	b.totalCount = dict.Lines
 	b.processedCount = 0
 	b.successCount = 0
 	b.startTime = time.Now()
@@ -168,10 +249,14 @@ func (b *Brute) Run(domain string) ([]string, error) {
 
 	// 执行爆破
 	logger.Debugf("Starting brute force subdomain testing...")
-	if err := b.bruteSubdomains(domain, subdomains); err != nil {
+	if err := b.bruteSubdomains(domain, dict.Candidates); err != nil {
 		logger.Errorf("Brute force subdomain testing failed: %v", err)
 		return []string{}, err
 	}
+	if _, err := dict.Wait(); err != nil {
+		logger.Errorf("Failed to generate dictionary: %v", err)
+		return []string{}, err
+	}
 
 	// 计算最终统计信息
 	elapsed := time.Since(b.startTime)
@@ -185,6 +270,13 @@ func (b *Brute) Run(domain string) ([]string, error) {
 	logger.Infof("  - Total time elapsed: %v", elapsed)
 	logger.Infof("  - Average speed: %.2f subdomains/second", float64(b.totalCount)/elapsed.Seconds())
 
+	// 递归爆破下一层子域名
+	if b.recursive && b.depth > 0 {
+		if err := b.recursiveBrute(domain); err != nil {
+			logger.Warnf("Recursive brute force for %s stopped: %v", domain, err)
+		}
+	}
+
 	// 返回结果
 	var results []string
 	for subdomain, result := range b.results {
@@ -200,6 +292,47 @@ func (b *Brute) Run(domain string) ([]string, error) {
 	return results, nil
 }
 
+// Reset 清空上一个域名的爆破结果、泛解析状态和进度统计（实现 core.Resetter），
+// 字典、并发和解析器配置以及泛解析检测缓存保持不变
+func (b *Brute) Reset() {
+	b.BaseModule.Reset()
+	b.domain = ""
+	b.results = make(map[string]*BruteResult)
+	b.nameservers = nil
+	b.hijackIPs = nil
+	b.enableWildcard = false
+	b.wildcardIPs = nil
+	b.wildcardTTL = 0
+	b.totalCount = 0
+	b.processedCount = 0
+	b.successCount = 0
+}
+
+// DryRun 只生成爆破字典并写入文件，不发送 DNS 查询
+func (b *Brute) DryRun(domain string) ([]string, error) {
+	b.initDictPaths()
+
+	subdomains, err := b.generateDict(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	outputPath := filepath.Join(b.GetConfig().ResultSavePath, fmt.Sprintf("%s_brute_candidates.txt", domain))
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %v", err)
+	}
+	content := strings.Join(subdomains, "\n")
+	if len(subdomains) > 0 {
+		content += "\n"
+	}
+	if err := os.WriteFile(outputPath, []byte(content), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write candidates: %v", err)
+	}
+
+	logger.Infof("[dry-run] Wrote %d brute force candidates to %s", len(subdomains), outputPath)
+	return subdomains, nil
+}
+
 // initDictPaths 初始化字典文件路径
 func (b *Brute) initDictPaths() {
 	logger.Debugf("Initializing dictionary paths...")
@@ -210,9 +343,8 @@ func (b *Brute) initDictPaths() {
 		logger.Infof("Using custom dictionary URL: %s", cfg.BruteDictionaryURL)
 		b.wordlist = cfg.BruteDictionaryURL
 	} else {
-		// 使用本地字典文件
-		dataDir := "data"
-		b.wordlist = filepath.Join(dataDir, "subnames.txt")
+		// 使用数据目录下的字典文件
+		b.wordlist = b.GetConfig().DataPath("subnames.txt")
 		logger.Debugf("Using local wordlist: %s", b.wordlist)
 	}
 
@@ -225,33 +357,82 @@ func (b *Brute) initDictPaths() {
 		logger.Debugf("Using local DNS server configuration")
 	}
 
-	// 设置nextlist（目前仍使用本地文件）
-	dataDir := "data"
-	b.nextlist = filepath.Join(dataDir, "subnames_next.txt")
+	// 设置nextlist（目前仍使用数据目录下的文件）
+	b.nextlist = b.GetConfig().DataPath("subnames_next.txt")
 
 	logger.Debugf("Dictionary paths set:")
 	logger.Debugf("  - Wordlist: %s", b.wordlist)
 	logger.Debugf("  - Nextlist: %s", b.nextlist)
 
-	// 检查本地文件是否存在（仅当使用本地文件时）
-	if !strings.HasPrefix(b.wordlist, "http") && !strings.HasPrefix(b.wordlist, "https") {
-		if _, err := os.Stat(b.wordlist); os.IsNotExist(err) {
-			logger.Errorf("Wordlist file does not exist: %s", b.wordlist)
+	// 默认字典和 nextlist 缺失时由 openWordlist 回退到内置字典并只提示一次，这里只检查用户指定的字典
+	for _, wordlist := range b.wordlists {
+		if _, err := os.Stat(wordlist); os.IsNotExist(err) {
+			logger.Errorf("Wordlist file does not exist: %s", wordlist)
 		} else {
-			logger.Debugf("Wordlist file exists: %s", b.wordlist)
+			logger.Debugf("Using wordlist: %s", wordlist)
 		}
 	}
+}
 
-	if _, err := os.Stat(b.nextlist); os.IsNotExist(err) {
-		logger.Errorf("Nextlist file does not exist: %s", b.nextlist)
-	} else {
-		logger.Debugf("Nextlist file exists: %s", b.nextlist)
+// SetWordlists 设置爆破字典文件，多个文件的词合并去重后生成候选，传空列表时恢复默认字典
+func (b *Brute) SetWordlists(paths []string) {
+	b.wordlists = nil
+	for _, path := range paths {
+		if path = strings.TrimSpace(path); path != "" {
+			b.wordlists = append(b.wordlists, path)
+		}
+	}
+}
+
+// openWordlist 打开字典文件：默认字典和 nextlist 从数据目录读取，缺失时使用内置字典；用户指定的字典必须存在
+func (b *Brute) openWordlist(path string) (io.ReadCloser, error) {
+	if path == b.wordlist || path == b.nextlist {
+		return b.GetConfig().OpenDataFile(filepath.Base(path))
+	}
+	return os.Open(path)
+}
+
+// dictFiles 返回生成候选使用的字典文件：递归爆破子区域使用 nextlist，否则优先使用用户指定的字典
+func (b *Brute) dictFiles() []string {
+	if b.subZone {
+		return []string{b.nextlist}
+	}
+	if len(b.wordlists) > 0 {
+		return b.wordlists
 	}
+	return []string{b.wordlist}
+}
+
+// SetOnFound 设置发现回调，每个有效子域名在发现时回调一次，便于长时间爆破中实时输出（传 nil 取消）
+// 回调在爆破 goroutine 中并发调用，需自行保证并发安全且不应长时间阻塞
+func (b *Brute) SetOnFound(fn func(BruteResult)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.onFound = fn
+}
+
+// SetRecursive 设置是否以发现的子域名为子区域递归爆破下一层，depth 为递归层数
+func (b *Brute) SetRecursive(recursive bool, depth int) {
+	b.recursive = recursive
+	b.depth = depth
+}
+
+// SetResolvers 设置自定义 DNS 服务器（host:port，tls:// 前缀表示 DNS over TLS）
+func (b *Brute) SetResolvers(resolvers []string) {
+	b.resolvers = resolvers
 }
 
 // getNameservers 获取权威 DNS 服务器
 func (b *Brute) getNameservers(domain string) error {
 	logger.Debugf("Getting nameservers for domain: %s", domain)
+	b.nameservers = nil
+
+	// 用户指定了DNS服务器时直接使用（例如内网域名侦察）
+	if len(b.resolvers) > 0 {
+		b.nameservers = append(b.nameservers, b.resolvers...)
+		logger.Infof("Using %d custom DNS servers", len(b.nameservers))
+		return nil
+	}
 
 	// 查询 NS 记录
 	logger.Debugf("Querying NS records for domain: %s", domain)
@@ -272,7 +453,9 @@ func (b *Brute) getNameservers(domain string) error {
 			continue
 		}
 		logger.Debugf("A records for NS server %s: %v", ns, ips)
-		b.nameservers = append(b.nameservers, ips...)
+		for _, ip := range ips {
+			b.nameservers = append(b.nameservers, net.JoinHostPort(ip, "53"))
+		}
 	}
 
 	// 如果没有获取到权威服务器，使用公共 DNS
@@ -286,17 +469,66 @@ func (b *Brute) getNameservers(domain string) error {
 	return nil
 }
 
+// exchange 向指定 DNS 服务器发送查询：UDP 失败时重试一次，响应被截断（TC 位）时改用 TCP 重新查询
+// tls:// 前缀的服务器使用 DNS over TLS
+func (b *Brute) exchange(msg *dns.Msg, nameserver string) (*dns.Msg, error) {
+	client, addr := dnsutil.NewExchangeClient(nameserver, 0, b.insecureTLS)
+
+	var resp *dns.Msg
+	var err error
+	for attempt := 1; attempt <= dnsQueryAttempts; attempt++ {
+		resp, _, err = client.Exchange(msg, addr)
+		if err == nil {
+			break
+		}
+		logger.Debugf("DNS query %s to %s failed (attempt %d/%d): %v",
+			msg.Question[0].Name, nameserver, attempt, dnsQueryAttempts, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Truncated && !dnsutil.IsDoT(nameserver) {
+		logger.Debugf("Truncated response for %s from %s, retrying over TCP", msg.Question[0].Name, nameserver)
+		tcpClient := &dns.Client{Net: "tcp"}
+		tcpResp, _, err := tcpClient.Exchange(msg, nameserver)
+		if err != nil {
+			// TCP 不可用时退回截断的 UDP 响应，至少保留部分记录
+			logger.Debugf("TCP query %s to %s failed: %v", msg.Question[0].Name, nameserver, err)
+			return resp, nil
+		}
+		resp = tcpResp
+	}
+
+	return resp, nil
+}
+
 // queryNS 查询 NS 记录
 func (b *Brute) queryNS(domain string) ([]string, error) {
 	logger.Debugf("Querying NS records for domain: %s", domain)
 
-	client := new(dns.Client)
 	msg := new(dns.Msg)
 	msg.SetQuestion(dns.Fqdn(domain), dns.TypeNS)
 	msg.RecursionDesired = true
 
-	logger.Debugf("Sending NS query to 8.8.8.8:53")
-	resp, _, err := client.Exchange(msg, "8.8.8.8:53")
+	// 依次尝试配置的 DNS 服务器，未配置时使用默认公共 DNS
+	servers := b.resolvers
+	if len(servers) == 0 {
+		servers = dnsutil.ConfigResolvers(b.GetConfig())
+	}
+
+	var resp *dns.Msg
+	var err error
+	for _, server := range servers {
+		logger.Debugf("Sending NS query to %s", server)
+		resp, err = b.exchange(msg, server)
+		if err == nil && (resp.Rcode == dns.RcodeServerFailure || resp.Rcode == dns.RcodeRefused) {
+			err = fmt.Errorf("resolver %s returned %s", server, dns.RcodeToString[resp.Rcode])
+		}
+		if err == nil {
+			break
+		}
+	}
 	if err != nil {
 		logger.Errorf("NS query failed: %v", err)
 		return nil, err
@@ -317,38 +549,71 @@ func (b *Brute) queryNS(domain string) ([]string, error) {
 
 // queryA 查询 A 记录
 func (b *Brute) queryA(domain string) ([]string, error) {
+	return b.queryAddresses(domain, []uint16{dns.TypeA})
+}
+
+// queryIPs 按 IP 版本查询 A 和/或 AAAA 记录
+func (b *Brute) queryIPs(domain string) ([]string, error) {
+	return b.queryAddresses(domain, b.recordTypes)
+}
+
+// queryAddresses 依次查询各地址记录类型并合并结果，每种类型使用第一个返回记录的 DNS 服务器
+func (b *Brute) queryAddresses(domain string, qtypes []uint16) ([]string, error) {
 	// 添加异常处理
 	defer func() {
 		if r := recover(); r != nil {
-			logger.Errorf("Panic in queryA for %s: %v", domain, r)
+			logger.Errorf("Panic in queryAddresses for %s: %v", domain, r)
 		}
 	}()
 
-	client := new(dns.Client)
-	msg := new(dns.Msg)
-	msg.SetQuestion(dns.Fqdn(domain), dns.TypeA)
-	msg.RecursionDesired = true
-
-	// 遍历多个DNS服务器
-	for _, nameserver := range b.nameservers {
-		resp, _, err := client.Exchange(msg, nameserver+":53")
-		if err != nil {
-			continue
-		}
+	var ips []string
+	answered := false
+	for _, qtype := range qtypes {
+		msg := new(dns.Msg)
+		msg.SetQuestion(dns.Fqdn(domain), qtype)
+		msg.RecursionDesired = true
+		dnsutil.AddClientSubnet(msg, b.clientSubnet)
+
+		// 遍历多个DNS服务器
+		for _, nameserver := range b.nameservers {
+			resp, err := b.exchange(msg, nameserver)
+			if err != nil {
+				continue
+			}
+			if resp.Rcode == dns.RcodeSuccess || resp.Rcode == dns.RcodeNameError {
+				answered = true
+			}
 
-		var ips []string
-		for _, answer := range resp.Answer {
-			if a, ok := answer.(*dns.A); ok {
-				ips = append(ips, a.A.String())
+			found := 0
+			for _, answer := range resp.Answer {
+				if ip := dnsutil.AddressFromRR(answer); ip != "" && answer.Header().Rrtype == qtype {
+					ips = append(ips, ip)
+					found++
+				}
+			}
+			if found > 0 {
+				break
 			}
 		}
+	}
 
-		if len(ips) > 0 {
-			return ips, nil
-		}
+	if len(ips) > 0 {
+		return ips, nil
+	}
+	// 没有任何服务器给出明确应答时返回解析器错误，用于并发数自动调整
+	if !answered {
+		return nil, errResolverFailure
 	}
+	return nil, fmt.Errorf("no %s record found for %s", recordTypeNames(qtypes), domain)
+}
 
-	return nil, fmt.Errorf("no A record found for %s", domain)
+// recordTypeNames 返回记录类型名称，如 A/AAAA
+func recordTypeNames(qtypes []uint16) string {
+	names := make([]string, 0, len(qtypes))
+	for _, qtype := range qtypes {
+		names = append(names, dns.TypeToString[qtype])
+	}
+	return strings.Join(names, "/")
 }
 
 // getPublicNameservers 获取公共 DNS 服务器
@@ -363,30 +628,36 @@ func (b *Brute) getPublicNameservers() []string {
 	// 读取nameservers.txt文件
 	nameservers := []string{}
 
-	// 尝试读取nameservers.txt
-	data, err := os.ReadFile("data/nameservers.txt")
+	// 尝试读取数据目录下的nameservers.txt
+	data, err := b.GetConfig().ReadDataFile("nameservers.txt")
 	if err == nil {
 		lines := strings.Split(string(data), "\n")
 		for _, line := range lines {
 			line = strings.TrimSpace(line)
-			if line != "" && !strings.HasPrefix(line, "#") {
-				nameservers = append(nameservers, line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			nameserver, err := config.NormalizeResolver(line)
+			if err != nil {
+				logger.Debugf("Skipping invalid nameserver %s: %v", line, err)
+				continue
 			}
+			nameservers = append(nameservers, nameserver)
 		}
-		logger.Infof("Loaded %d nameservers from data/nameservers.txt", len(nameservers))
+		logger.Infof("Loaded %d nameservers from %s", len(nameservers), b.GetConfig().DataPath("nameservers.txt"))
 	} else {
-		logger.Warnf("Failed to load nameservers.txt: %v", err)
+		logger.Debugf("Failed to load nameservers.txt: %v", err)
 	}
 
 	// 如果文件读取失败，使用默认的公共DNS服务器
 	if len(nameservers) == 0 {
 		nameservers = []string{
-			"8.8.8.8",
-			"8.8.4.4",
-			"1.1.1.1",
-			"1.0.0.1",
-			"114.114.114.114",
-			"114.114.115.115",
+			"8.8.8.8:53",
+			"8.8.4.4:53",
+			"1.1.1.1:53",
+			"1.0.0.1:53",
+			"114.114.114.114:53",
+			"114.114.115.115:53",
 		}
 		logger.Infof("Using default nameservers: %v", nameservers)
 	}
@@ -404,7 +675,7 @@ func (b *Brute) detectWildcard(domain string) error {
 
 	var testIPs []string
 	for _, subdomain := range randomSubdomains {
-		ips, err := b.queryA(subdomain)
+		ips, err := b.queryIPs(subdomain)
 		if err != nil {
 			continue
 		}
@@ -428,7 +699,7 @@ func (b *Brute) detectWildcardAdvanced(domain string) (*WildcardDetectionResult,
 		IsWildcard:     false,
 		SuccessRate:    0.0,
 		IPRepeatRate:   0.0,
-		TestCount:      20,
+		TestCount:      b.wildcardTestCount,
 		SuccessCount:   0,
 		UniqueIPs:      0,
 		TotalIPs:       0,
@@ -444,6 +715,7 @@ func (b *Brute) detectWildcardAdvanced(domain string) (*WildcardDetectionResult,
 	}
 
 	result.TestSubdomains = testSubdomains
+	result.TestCount = len(testSubdomains)
 	logger.Infof("Generated %d random test subdomains for wildcard detection", len(testSubdomains))
 
 	// 并发测试子域名
@@ -498,7 +770,7 @@ func (b *Brute) detectWildcardAdvanced(domain string) (*WildcardDetectionResult,
 	result.calculateStatistics(allIPs)
 
 	// 判断是否为泛解析
-	result.IsWildcard = result.SuccessRate > 90.0 && result.IPRepeatRate > 50.0
+	result.IsWildcard = b.isWildcardResult(result)
 
 	logger.Infof("=== Wildcard detection completed ===")
 	logger.Infof("Test results:")
@@ -519,54 +791,9 @@ func (b *Brute) detectWildcardAdvanced(domain string) (*WildcardDetectionResult,
 	return result, nil
 }
 
-// generateDict 生成爆破字典
-func (b *Brute) generateDict(domain string) ([]string, error) {
-	var subdomains []string
-
-	// 读取字典文件
-	wordlist := b.wordlist
-	if b.recursive {
-		wordlist = b.nextlist
-	}
-
-	logger.Infof("Loading wordlist from: %s", wordlist)
-
-	file, err := os.Open(wordlist)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open wordlist %s: %v", wordlist, err)
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	lineCount := 0
-	for scanner.Scan() {
-		lineCount++
-		word := strings.TrimSpace(scanner.Text())
-		if word == "" || strings.HasPrefix(word, "#") {
-			continue
-		}
-
-		// 生成子域名
-		subdomain := fmt.Sprintf("%s.%s", word, domain)
-		subdomains = append(subdomains, subdomain)
-	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading wordlist: %v", err)
-	}
-
-	logger.Infof("Generated %d subdomains from wordlist (read %d lines)", len(subdomains), lineCount)
-
-	// 显示前几个子域名作为示例
-	if len(subdomains) > 0 {
-		sampleCount := 5
-		if len(subdomains) < sampleCount {
-			sampleCount = len(subdomains)
-		}
-		logger.Debugf("Sample subdomains: %v", subdomains[:sampleCount])
-	}
-
-	return subdomains, nil
+// isWildcardResult 根据成功率和IP重复率阈值判断是否为泛解析
+func (b *Brute) isWildcardResult(result *WildcardDetectionResult) bool {
+	return result.SuccessRate > b.wildcardSuccessThreshold && result.IPRepeatRate > b.wildcardRepeatThreshold
 }
 
 // generateRandomTestSubdomains 生成随机测试子域名
@@ -609,7 +836,7 @@ func (b *Brute) loadWordlist() ([]string, error) {
 			}
 		} else {
 			// 从本地文件加载字典
-			file, err := os.Open(b.wordlist)
+			file, err := b.openWordlist(b.wordlist)
 			if err == nil {
 				defer file.Close()
 				scanner := bufio.NewScanner(file)
@@ -726,17 +953,17 @@ func (r *WildcardDetectionResult) calculateStatistics(allIPs map[string]int) {
 	}
 }
 
-// bruteSubdomains 爆破子域名
-func (b *Brute) bruteSubdomains(domain string, subdomains []string) error {
-	logger.Infof("Starting brute force with %d subdomains, concurrency: %d", len(subdomains), b.concurrent)
+// bruteSubdomains 爆破子域名，从 subdomains 读取候选直到通道关闭
+func (b *Brute) bruteSubdomains(domain string, subdomains <-chan string) error {
+	logger.Infof("Starting brute force with up to %d subdomains, concurrency: %d", b.totalCount, b.concurrent)
 	logger.Debugf("Brute force parameters:")
 	logger.Debugf("  - Domain: %s", domain)
-	logger.Debugf("  - Subdomains count: %d", len(subdomains))
+	logger.Debugf("  - Subdomains count: up to %d", b.totalCount)
 	logger.Debugf("  - Concurrency: %d", b.concurrent)
 	logger.Debugf("  - Nameservers: %v", b.nameservers)
 
-	// 创建并发控制
-	semaphore := make(chan struct{}, b.concurrent)
+	// 创建并发控制，并发数按解析器错误率在上下限内自动调整
+	limiter := newAdaptiveLimiter(b.concurrent, b.minConcurrent, b.maxConcurrent, b.targetErrRate)
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 
@@ -758,13 +985,28 @@ func (b *Brute) bruteSubdomains(domain string, subdomains []string) error {
 	}()
 
 	logger.Debugf("Starting concurrent subdomain testing...")
-	for i, subdomain := range subdomains {
+	ctx := b.Context()
+	count := 0
+	for subdomain := range subdomains {
+		// 运行被取消（结果上限、运行预算）时不再发出新的查询，剩余候选在后台读完，字典读取协程随之结束
+		if ctx.Err() != nil {
+			logger.Warnf("Brute force for %s cancelled after %d candidates", domain, count)
+			go func() {
+				for range subdomains {
+				}
+			}()
+			break
+		}
+
+		index := count
+		count++
 		wg.Add(1)
-		semaphore <- struct{}{}
+		limiter.Acquire()
 
-		go func(subdomain string, index int) {
+		go func(subdomain string, index int, total int) {
 			defer wg.Done()
-			defer func() { <-semaphore }()
+			failed := false
+			defer func() { limiter.Release(failed) }()
 
 			// 添加异常处理
 			defer func() {
@@ -778,15 +1020,17 @@ func (b *Brute) bruteSubdomains(domain string, subdomains []string) error {
 
 			// 每1000个显示一次进度
 			if index%1000 == 0 {
-				logger.Debugf("Processing subdomain %d/%d: %s", index+1, len(subdomains), subdomain)
+				logger.Debugf("Processing subdomain %d/%d: %s", index+1, total, subdomain)
 			}
 
 			// 查询子域名
-			result := b.querySubdomain(subdomain)
+			result, err := b.resolveSubdomain(subdomain)
+			failed = errors.Is(err, errResolverFailure)
 
 			// 检查是否为有效子域名
 			if b.isValidSubdomain(result) {
 				mu.Lock()
+				_, seen := b.results[subdomain]
 				b.results[subdomain] = result
 				b.processedCount++
 				if result.Valid {
@@ -795,17 +1039,33 @@ func (b *Brute) bruteSubdomains(domain string, subdomains []string) error {
 						subdomain, result.IPs, result.CNAMEs)
 				}
 				mu.Unlock()
+
+				// 字典中重复的子域名只回调一次
+				if !seen {
+					b.notifyFound(*result)
+				}
 			} else {
 				mu.Lock()
 				b.processedCount++
 				mu.Unlock()
 			}
-		}(subdomain, i)
+		}(subdomain, index, b.totalCount)
 	}
 
+	// 字典已读完，按实际候选数修正总数（字典行数包含空行、注释和重复词）
+	mu.Lock()
+	b.totalCount = count
+	mu.Unlock()
+
 	logger.Debugf("Waiting for all goroutines to complete...")
 	wg.Wait()
 
+	// 递归爆破的下一层从调整后的并发数开始
+	if limit := limiter.Limit(); limit != b.concurrent {
+		logger.Infof("Brute concurrency tuned from %d to %d", b.concurrent, limit)
+		b.concurrent = limit
+	}
+
 	// 最终进度报告
 	b.reportProgress()
 
@@ -813,6 +1073,16 @@ func (b *Brute) bruteSubdomains(domain string, subdomains []string) error {
 	return nil
 }
 
+// notifyFound 调用发现回调
+func (b *Brute) notifyFound(result BruteResult) {
+	b.mu.RLock()
+	onFound := b.onFound
+	b.mu.RUnlock()
+	if onFound != nil {
+		onFound(result)
+	}
+}
+
 // reportProgress 报告进度
 func (b *Brute) reportProgress() {
 	if b.totalCount == 0 {
@@ -842,6 +1112,12 @@ func (b *Brute) reportProgress() {
 
 // querySubdomain 查询子域名（优化版本，只进行DNS查询）
 func (b *Brute) querySubdomain(subdomain string) *BruteResult {
+	result, _ := b.resolveSubdomain(subdomain)
+	return result
+}
+
+// resolveSubdomain 按 IP 版本查询子域名的地址记录，同时返回查询错误，解析器错误为 errResolverFailure
+func (b *Brute) resolveSubdomain(subdomain string) (result *BruteResult, err error) {
 	// 添加异常处理
 	defer func() {
 		if r := recover(); r != nil {
@@ -849,61 +1125,40 @@ func (b *Brute) querySubdomain(subdomain string) *BruteResult {
 		}
 	}()
 
-	result := &BruteResult{
+	result = &BruteResult{
 		Subdomain: subdomain,
 		Valid:     false,
 	}
 
-	// 只查询 A 记录，有解析IP就成功
-	ips, err := b.queryA(subdomain)
+	// 只查询地址记录，有解析IP就成功
+	ips, err := b.queryIPs(subdomain)
 	if err != nil {
-		logger.Debugf("No A record for %s: %v", subdomain, err)
-		return result
+		logger.Debugf("No address record for %s: %v", subdomain, err)
+		return result, err
+	}
+
+	// 只解析到 NXDOMAIN 劫持 IP 的子域名实际不存在
+	if valid := b.withoutHijackIPs(ips); len(valid) < len(ips) {
+		logger.Debugf("Ignoring NXDOMAIN hijack IPs for %s: %v", subdomain, ips)
+		ips = valid
 	}
 
 	if len(ips) > 0 {
 		result.IPs = ips
 		result.Valid = true
-		logger.Debugf("Found A record for %s: %v", subdomain, ips)
+		logger.Debugf("Found address records for %s: %v", subdomain, ips)
 	}
 
-	return result
+	return result, nil
 }
 
-// queryCNAME 查询 CNAME 记录
+// queryCNAME 查询域名的完整 CNAME 指向链
 func (b *Brute) queryCNAME(domain string) ([]string, error) {
-	// 添加异常处理
-	defer func() {
-		if r := recover(); r != nil {
-			logger.Errorf("Panic in queryCNAME for %s: %v", domain, r)
-		}
-	}()
-
-	client := new(dns.Client)
-	msg := new(dns.Msg)
-	msg.SetQuestion(dns.Fqdn(domain), dns.TypeCNAME)
-	msg.RecursionDesired = true
-
-	// 遍历多个DNS服务器
-	for _, nameserver := range b.nameservers {
-		resp, _, err := client.Exchange(msg, nameserver+":53")
-		if err != nil {
-			continue
-		}
-
-		var cnames []string
-		for _, answer := range resp.Answer {
-			if cname, ok := answer.(*dns.CNAME); ok {
-				cnames = append(cnames, strings.TrimSuffix(cname.Target, "."))
-			}
-		}
-
-		if len(cnames) > 0 {
-			return cnames, nil
-		}
+	chain := dnsutil.ResolveCNAMEChain(domain, b.nameservers, dnsutil.ConfigTimeout(b.GetConfig()), b.insecureTLS)
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("no CNAME record found for %s", domain)
 	}
-
-	return nil, fmt.Errorf("no CNAME record found for %s", domain)
+	return chain, nil
 }
 
 // isValidSubdomain 检查是否为有效子域名
@@ -913,6 +1168,12 @@ func (b *Brute) isValidSubdomain(result *BruteResult) bool {
 	logger.Debugf("  - IPs: %v", result.IPs)
 	logger.Debugf("  - CNAMEs: %v", result.CNAMEs)
 
+	// 泛解析子区域中只解析到泛解析 IP 的候选实际不存在
+	if b.wildcardOnly(result.IPs) {
+		logger.Debugf("Subdomain %s only resolves to wildcard IPs", result.Subdomain)
+		return false
+	}
+
 	// 检查是否有有效的IP或CNAME记录
 	if len(result.IPs) > 0 || len(result.CNAMEs) > 0 {
 		logger.Debugf("Subdomain %s is valid (has IPs or CNAMEs)", result.Subdomain)
@@ -923,31 +1184,111 @@ func (b *Brute) isValidSubdomain(result *BruteResult) bool {
 	return false
 }
 
-// recursiveBrute 递归爆破
+// recursiveBrute 递归爆破：以上一层发现的子域名为子区域，用 nextlist 字典爆破下一层，最多 depth 层；
+// 泛解析的子区域同样爆破，只解析到随机子域名泛解析 IP 的候选视为不存在
 func (b *Brute) recursiveBrute(domain string) error {
-	// 获取当前有效的子域名
-	var validSubdomains []string
-	for subdomain, result := range b.results {
-		if result.Valid {
-			validSubdomains = append(validSubdomains, subdomain)
+	b.subZone = true
+	defer func() {
+		b.subZone = false
+		b.wildcardFilter = nil
+	}()
+
+	zones := b.validSubdomains(nil)
+	for level := 1; level <= b.depth && len(zones) > 0; level++ {
+		logger.Infof("Recursive brute force level %d for %s: %d sub-zones", level, domain, len(zones))
+		known := make(map[string]bool, len(b.results))
+		for subdomain := range b.results {
+			known[subdomain] = true
 		}
-	}
 
-	// 对每个有效子域名进行递归爆破
-	for _, subdomain := range validSubdomains {
-		if b.depth > 0 {
-			// 生成下一层子域名的字典
-			nextSubdomains, err := b.generateDict(subdomain)
+		for _, zone := range zones {
+			if err := b.Context().Err(); err != nil {
+				return err
+			}
+
+			// 子区域可能有独立的泛解析，单独检测（结果按子域名缓存）
+			wildcardResult, err := b.wildcardCache.Detect(zone, b.detectWildcardAdvanced)
 			if err != nil {
+				logger.Debugf("Skipping recursive brute for %s: %v", zone, err)
 				continue
 			}
+			b.wildcardFilter = nil
+			if wildcardResult.IsWildcard {
+				b.wildcardFilter = b.probeWildcardIPs(zone)
+				if len(b.wildcardFilter) == 0 {
+					logger.Debugf("Skipping recursive brute for wildcard sub-zone %s: no wildcard IPs recorded", zone)
+					continue
+				}
+				logger.Infof("Wildcard DNS detected for sub-zone %s, ignoring candidates resolving only to %d wildcard IPs", zone, len(b.wildcardFilter))
+			}
 
-			// 爆破下一层子域名
-			if err := b.bruteSubdomains(subdomain, nextSubdomains); err != nil {
+			// 流式生成下一层子域名的字典
+			dict, err := b.openDict(zone)
+			if err != nil {
+				logger.Debugf("Skipping recursive brute for %s: %v", zone, err)
 				continue
 			}
+
+			// 爆破下一层子域名
+			b.totalCount = dict.Lines
+			if err := b.bruteSubdomains(zone, dict.Candidates); err != nil {
+				logger.Debugf("Recursive brute for %s failed: %v", zone, err)
+			}
+			dict.Wait()
 		}
+
+		// 下一层以本层新发现的子域名为子区域
+		zones = b.validSubdomains(known)
 	}
 
 	return nil
 }
+
+// validSubdomains 返回有效的爆破结果，跳过 exclude 中的子域名
+func (b *Brute) validSubdomains(exclude map[string]bool) []string {
+	var subdomains []string
+	for subdomain, result := range b.results {
+		if result.Valid && !exclude[subdomain] {
+			subdomains = append(subdomains, subdomain)
+		}
+	}
+	sort.Strings(subdomains)
+	return subdomains
+}
+
+// wildcardOnly 判断 IP 是否全部为当前子区域的泛解析 IP
+func (b *Brute) wildcardOnly(ips []string) bool {
+	if len(b.wildcardFilter) == 0 || len(ips) == 0 {
+		return false
+	}
+	for _, ip := range ips {
+		if !b.wildcardFilter[ip] {
+			return false
+		}
+	}
+	return true
+}
+
+// wildcardProbes 构建子区域泛解析过滤时解析的随机子域名数
+const wildcardProbes = 3
+
+// probeWildcardIPs 解析子区域下几个随机标签，返回泛解析应答的 IP。泛解析检测的测试子域名取自字典，
+// 可能是真实存在的主机，其 IP 不能作为过滤依据
+func (b *Brute) probeWildcardIPs(zone string) map[string]bool {
+	ips := make(map[string]bool)
+	for i := 0; i < wildcardProbes; i++ {
+		label, err := randomLabel()
+		if err != nil {
+			logger.Debugf("Failed to generate wildcard probe name: %v", err)
+			break
+		}
+		resolved, err := b.queryIPs(label + "." + zone)
+		if err != nil {
+			continue
+		}
+		for _, ip := range resolved {
+			ips[ip] = true
+		}
+	}
+	return ips
+}
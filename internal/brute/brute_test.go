@@ -0,0 +1,656 @@
+package brute
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/oneforall-go/internal/config"
+)
+
+func TestWildcardCacheSkipsSecondDetection(t *testing.T) {
+	cache := NewWildcardCache("", time.Hour)
+
+	calls := 0
+	detect := func(domain string) (*WildcardDetectionResult, error) {
+		calls++
+		return &WildcardDetectionResult{IsWildcard: domain == "wild.example.com"}, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := cache.Detect("example.com", detect); err != nil {
+			t.Fatalf("Detect failed: %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 detection pass for repeated domain, got %d", calls)
+	}
+
+	// 子区域单独检测
+	result, err := cache.Detect("wild.example.com", detect)
+	if err != nil {
+		t.Fatalf("Detect failed: %v", err)
+	}
+	if calls != 2 || !result.IsWildcard {
+		t.Errorf("Expected separate detection for sub-zone, calls=%d wildcard=%t", calls, result.IsWildcard)
+	}
+}
+
+func TestWildcardCachePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wildcard.json")
+
+	NewWildcardCache(path, time.Hour).Set("example.com", &WildcardDetectionResult{IsWildcard: true})
+
+	result, ok := NewWildcardCache(path, time.Hour).Get("example.com")
+	if !ok || !result.IsWildcard {
+		t.Errorf("Expected persisted wildcard result, got %v (ok=%t)", result, ok)
+	}
+
+	if _, ok := NewWildcardCache(path, time.Nanosecond).Get("example.com"); ok {
+		t.Error("Expected expired entry to be ignored")
+	}
+}
+
+func TestWildcardThresholdsFromConfig(t *testing.T) {
+	result := &WildcardDetectionResult{SuccessRate: 60, IPRepeatRate: 60}
+
+	low := NewBrute(&config.Config{WildcardSuccessRateThreshold: 50, WildcardIPRepeatRateThreshold: 50})
+	if !low.isWildcardResult(result) {
+		t.Error("Expected low thresholds to classify domain as wildcard")
+	}
+
+	high := NewBrute(&config.Config{WildcardSuccessRateThreshold: 95, WildcardIPRepeatRateThreshold: 50})
+	if high.isWildcardResult(result) {
+		t.Error("Expected high thresholds not to classify domain as wildcard")
+	}
+
+	defaults := NewBrute(&config.Config{})
+	if defaults.wildcardTestCount != 20 || defaults.wildcardSuccessThreshold != 90 || defaults.wildcardRepeatThreshold != 50 {
+		t.Errorf("Unexpected defaults: %d/%.0f/%.0f", defaults.wildcardTestCount, defaults.wildcardSuccessThreshold, defaults.wildcardRepeatThreshold)
+	}
+}
+
+func TestSharedWildcardCacheKeyedByConfig(t *testing.T) {
+	dir := t.TempDir()
+	first := getSharedWildcardCache(&config.Config{WildcardCacheFile: filepath.Join(dir, "a.json"), WildcardCacheTTL: 60})
+	same := getSharedWildcardCache(&config.Config{WildcardCacheFile: filepath.Join(dir, "a.json"), WildcardCacheTTL: 60})
+	other := getSharedWildcardCache(&config.Config{WildcardCacheFile: filepath.Join(dir, "b.json"), WildcardCacheTTL: 60})
+
+	if first != same {
+		t.Error("Expected brute modules with the same cache settings to share a cache")
+	}
+	if first == other || other.path != filepath.Join(dir, "b.json") {
+		t.Errorf("Expected a separate cache for a different cache file, got path %q", other.path)
+	}
+}
+
+func TestRecursiveBruteFiltersWildcardSubZone(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen udp: %v", err)
+	}
+
+	// wild.example.com 是泛解析子区域，只有 real.wild.example.com 解析到独立的 IP
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		name := r.Question[0].Name
+		switch {
+		case r.Question[0].Qtype != dns.TypeA:
+		case name == "real.wild.example.com.":
+			rr, _ := dns.NewRR(name + " 60 IN A 192.0.2.10")
+			m.Answer = append(m.Answer, rr)
+		case strings.HasSuffix(name, ".wild.example.com."):
+			rr, _ := dns.NewRR(name + " 60 IN A 192.0.2.99")
+			m.Answer = append(m.Answer, rr)
+		default:
+			m.Rcode = dns.RcodeNameError
+		}
+		w.WriteMsg(m)
+	})
+
+	started := make(chan struct{})
+	server := &dns.Server{PacketConn: pc, Handler: handler, NotifyStartedFunc: func() { close(started) }}
+	go server.ActivateAndServe()
+	<-started
+	defer server.Shutdown()
+
+	dataDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dataDir, "subnames_next.txt"), []byte("real\nfake\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	b := NewBrute(&config.Config{DataDir: dataDir})
+	b.nameservers = []string{pc.LocalAddr().String()}
+	b.wildcardCache = NewWildcardCache("", time.Hour)
+	b.nextlist = filepath.Join(dataDir, "subnames_next.txt")
+	b.SetRecursive(true, 1)
+	b.results["wild.example.com"] = &BruteResult{Subdomain: "wild.example.com", IPs: []string{"192.0.2.1"}, Valid: true}
+
+	// 泛解析检测的测试子域名取自字典，可能碰到真实主机；过滤只使用随机子域名的应答
+	detected := &WildcardDetectionResult{IsWildcard: true, TestResults: map[string]*BruteResult{
+		"real.wild.example.com": {Subdomain: "real.wild.example.com", IPs: []string{"192.0.2.10"}, Valid: true},
+		"www.wild.example.com":  {Subdomain: "www.wild.example.com", IPs: []string{"192.0.2.99"}, Valid: true},
+	}}
+	b.wildcardCache.Detect("wild.example.com", func(string) (*WildcardDetectionResult, error) { return detected, nil })
+
+	if err := b.recursiveBrute("example.com"); err != nil {
+		t.Fatalf("recursiveBrute failed: %v", err)
+	}
+
+	if result, ok := b.results["real.wild.example.com"]; !ok || !result.Valid {
+		t.Error("Expected real.wild.example.com to be found in the wildcard sub-zone")
+	}
+	if _, ok := b.results["fake.wild.example.com"]; ok {
+		t.Error("Expected fake.wild.example.com resolving to the wildcard IP to be filtered")
+	}
+	if b.subZone || b.wildcardFilter != nil {
+		t.Error("Expected sub-zone state to be cleared after recursion")
+	}
+}
+
+// startTruncatingResolver 启动同一端口上的 UDP/TCP DNS 服务：UDP 只返回一条记录并设置 TC 位，TCP 返回完整应答
+func startTruncatingResolver(t *testing.T, records int) (string, *int32, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen tcp: %v", err)
+	}
+	pc, err := net.ListenPacket("udp", listener.Addr().String())
+	if err != nil {
+		listener.Close()
+		t.Fatalf("listen udp: %v", err)
+	}
+
+	var tcpQueries int32
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		answers := records
+		if _, ok := w.RemoteAddr().(*net.UDPAddr); ok {
+			m.Truncated = true
+			answers = 1
+		} else {
+			atomic.AddInt32(&tcpQueries, 1)
+		}
+		for i := 0; i < answers; i++ {
+			rr, _ := dns.NewRR(fmt.Sprintf("%s 60 IN A 192.0.2.%d", r.Question[0].Name, i+1))
+			m.Answer = append(m.Answer, rr)
+		}
+		w.WriteMsg(m)
+	})
+
+	udpStarted, tcpStarted := make(chan struct{}), make(chan struct{})
+	udpServer := &dns.Server{PacketConn: pc, Handler: handler, NotifyStartedFunc: func() { close(udpStarted) }}
+	tcpServer := &dns.Server{Listener: listener, Handler: handler, NotifyStartedFunc: func() { close(tcpStarted) }}
+	go udpServer.ActivateAndServe()
+	go tcpServer.ActivateAndServe()
+	<-udpStarted
+	<-tcpStarted
+
+	return listener.Addr().String(), &tcpQueries, func() {
+		udpServer.Shutdown()
+		tcpServer.Shutdown()
+	}
+}
+
+func TestQueryATruncatedFallsBackToTCP(t *testing.T) {
+	addr, tcpQueries, stop := startTruncatingResolver(t, 40)
+	defer stop()
+
+	b := NewBrute(&config.Config{})
+	b.nameservers = []string{addr}
+
+	ips, err := b.queryA("www.example.com")
+	if err != nil {
+		t.Fatalf("queryA failed: %v", err)
+	}
+	if len(ips) != 40 {
+		t.Errorf("Expected 40 A records from TCP retry, got %d", len(ips))
+	}
+	if atomic.LoadInt32(tcpQueries) != 1 {
+		t.Errorf("Expected 1 TCP query, got %d", atomic.LoadInt32(tcpQueries))
+	}
+}
+
+func TestQueryAAttachesClientSubnet(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen udp: %v", err)
+	}
+
+	subnets := make(chan string, 1)
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		received := ""
+		if opt := r.IsEdns0(); opt != nil {
+			for _, option := range opt.Option {
+				if ecs, ok := option.(*dns.EDNS0_SUBNET); ok {
+					received = fmt.Sprintf("%s/%d", ecs.Address, ecs.SourceNetmask)
+				}
+			}
+		}
+		subnets <- received
+
+		m := new(dns.Msg)
+		m.SetReply(r)
+		rr, _ := dns.NewRR(r.Question[0].Name + " 60 IN A 192.0.2.1")
+		m.Answer = append(m.Answer, rr)
+		w.WriteMsg(m)
+	})
+
+	started := make(chan struct{})
+	server := &dns.Server{PacketConn: pc, Handler: handler, NotifyStartedFunc: func() { close(started) }}
+	go server.ActivateAndServe()
+	<-started
+	defer server.Shutdown()
+
+	b := NewBrute(&config.Config{EDNSClientSubnet: "198.51.100.0/24"})
+	b.nameservers = []string{pc.LocalAddr().String()}
+
+	if _, err := b.queryA("www.example.com"); err != nil {
+		t.Fatalf("queryA failed: %v", err)
+	}
+	if got := <-subnets; got != "198.51.100.0/24" {
+		t.Errorf("Expected ECS 198.51.100.0/24 on the query, got %q", got)
+	}
+}
+
+func TestOnFoundCalledOncePerValidHost(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen udp: %v", err)
+	}
+
+	valid := map[string]bool{"www.example.com.": true, "mail.example.com.": true, "api.example.com.": true}
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		if valid[r.Question[0].Name] {
+			rr, _ := dns.NewRR(r.Question[0].Name + " 60 IN A 192.0.2.1")
+			m.Answer = append(m.Answer, rr)
+		} else {
+			m.Rcode = dns.RcodeNameError
+		}
+		w.WriteMsg(m)
+	})
+
+	started := make(chan struct{})
+	server := &dns.Server{PacketConn: pc, Handler: handler, NotifyStartedFunc: func() { close(started) }}
+	go server.ActivateAndServe()
+	<-started
+	defer server.Shutdown()
+
+	b := NewBrute(&config.Config{})
+	b.nameservers = []string{pc.LocalAddr().String()}
+
+	var mu sync.Mutex
+	counts := make(map[string]int)
+	b.SetOnFound(func(result BruteResult) {
+		mu.Lock()
+		counts[result.Subdomain]++
+		mu.Unlock()
+	})
+
+	// 字典中的重复项和无效子域名都不应产生额外回调
+	subdomains := []string{"www.example.com", "mail.example.com", "nope.example.com", "api.example.com", "www.example.com", "missing.example.com"}
+	if err := b.bruteSubdomains("example.com", candidates(subdomains)); err != nil {
+		t.Fatalf("bruteSubdomains failed: %v", err)
+	}
+
+	var found []string
+	for subdomain, count := range counts {
+		if count != 1 {
+			t.Errorf("Expected 1 callback for %s, got %d", subdomain, count)
+		}
+		found = append(found, subdomain)
+	}
+	sort.Strings(found)
+	if strings.Join(found, ",") != "api.example.com,mail.example.com,www.example.com" {
+		t.Errorf("Expected callbacks for the 3 valid hosts, got %v", found)
+	}
+}
+
+func TestOnFoundNil(t *testing.T) {
+	b := NewBrute(&config.Config{})
+	b.SetOnFound(nil)
+	// 未设置回调时不应 panic
+	b.notifyFound(BruteResult{Subdomain: "www.example.com", Valid: true})
+}
+
+func TestAdaptiveLimiterBacksOff(t *testing.T) {
+	l := newAdaptiveLimiter(400, 20, 1000, 5)
+
+	// 持续高错误率时每个窗口减半，直到下限
+	expected := []int{200, 100, 50, 25, 20, 20}
+	for _, want := range expected {
+		for i := 0; i < adaptiveWindow; i++ {
+			l.Acquire()
+			l.Release(i%2 == 0)
+		}
+		if got := l.Limit(); got != want {
+			t.Fatalf("Expected concurrency %d after a high-error window, got %d", want, got)
+		}
+	}
+
+	// 错误率恢复后逐步回升
+	for i := 0; i < adaptiveWindow; i++ {
+		l.Acquire()
+		l.Release(false)
+	}
+	if got := l.Limit(); got <= 20 {
+		t.Errorf("Expected concurrency to grow after a clean window, got %d", got)
+	}
+}
+
+func TestBruteConcurrencyBacksOffOnServfail(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen udp: %v", err)
+	}
+
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeServerFailure)
+		w.WriteMsg(m)
+	})
+	started := make(chan struct{})
+	server := &dns.Server{PacketConn: pc, Handler: handler, NotifyStartedFunc: func() { close(started) }}
+	go server.ActivateAndServe()
+	<-started
+	defer server.Shutdown()
+
+	cfg := &config.Config{BruteMinConcurrency: 4, BruteMaxConcurrency: 64}
+	cfg.MultiThreading.BruteForceConcurrency = 64
+	b := NewBrute(cfg)
+	b.nameservers = []string{pc.LocalAddr().String()}
+
+	subdomains := make([]string, 3*adaptiveWindow)
+	for i := range subdomains {
+		subdomains[i] = fmt.Sprintf("host%d.example.com", i)
+	}
+	if err := b.bruteSubdomains("example.com", candidates(subdomains)); err != nil {
+		t.Fatalf("bruteSubdomains failed: %v", err)
+	}
+
+	if b.concurrent >= 64 {
+		t.Errorf("Expected concurrency to back off from 64 under SERVFAIL, got %d", b.concurrent)
+	}
+	if b.concurrent < 4 {
+		t.Errorf("Expected concurrency to stay at or above the minimum 4, got %d", b.concurrent)
+	}
+}
+
+func TestQueryANXDomainIsNotResolverFailure(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen udp: %v", err)
+	}
+
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeNameError)
+		w.WriteMsg(m)
+	})
+	started := make(chan struct{})
+	server := &dns.Server{PacketConn: pc, Handler: handler, NotifyStartedFunc: func() { close(started) }}
+	go server.ActivateAndServe()
+	<-started
+	defer server.Shutdown()
+
+	b := NewBrute(&config.Config{})
+	b.nameservers = []string{pc.LocalAddr().String()}
+
+	// NXDOMAIN 是正常的否定应答，不应计入解析器错误
+	if _, err := b.queryA("missing.example.com"); err == nil || errors.Is(err, errResolverFailure) {
+		t.Errorf("Expected a plain not-found error for NXDOMAIN, got %v", err)
+	}
+}
+
+func TestResolveSubdomainIPVersion(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen udp: %v", err)
+	}
+
+	var mu sync.Mutex
+	var queried []string
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		question := r.Question[0]
+		mu.Lock()
+		queried = append(queried, dns.TypeToString[question.Qtype])
+		mu.Unlock()
+
+		m := new(dns.Msg)
+		m.SetReply(r)
+		switch question.Qtype {
+		case dns.TypeA:
+			rr, _ := dns.NewRR(question.Name + " 60 IN A 192.0.2.1")
+			m.Answer = append(m.Answer, rr)
+		case dns.TypeAAAA:
+			rr, _ := dns.NewRR(question.Name + " 60 IN AAAA 2001:db8::1")
+			m.Answer = append(m.Answer, rr)
+		}
+		w.WriteMsg(m)
+	})
+
+	started := make(chan struct{})
+	server := &dns.Server{PacketConn: pc, Handler: handler, NotifyStartedFunc: func() { close(started) }}
+	go server.ActivateAndServe()
+	<-started
+	defer server.Shutdown()
+
+	tests := []struct {
+		version string
+		queried string
+		ips     string
+	}{
+		{"", "A", "192.0.2.1"},
+		{"4", "A", "192.0.2.1"},
+		{"6", "AAAA", "2001:db8::1"},
+		{"both", "A,AAAA", "192.0.2.1,2001:db8::1"},
+	}
+	for _, tt := range tests {
+		mu.Lock()
+		queried = nil
+		mu.Unlock()
+
+		b := NewBrute(&config.Config{IPVersion: tt.version})
+		b.nameservers = []string{pc.LocalAddr().String()}
+		result, err := b.resolveSubdomain("www.example.com")
+		if err != nil {
+			t.Fatalf("resolveSubdomain with IP version %q failed: %v", tt.version, err)
+		}
+
+		mu.Lock()
+		got := strings.Join(queried, ",")
+		mu.Unlock()
+		if got != tt.queried {
+			t.Errorf("IP version %q: expected %s queries, got %s", tt.version, tt.queried, got)
+		}
+		if !result.Valid || strings.Join(result.IPs, ",") != tt.ips {
+			t.Errorf("IP version %q: expected valid result with %s, got %+v", tt.version, tt.ips, result)
+		}
+	}
+}
+
+func TestGenerateDictMergesWordlists(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.txt")
+	second := filepath.Join(dir, "second.txt")
+	if err := os.WriteFile(first, []byte("www\nmail\n# comment\napi\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// 与第一个字典重复的词（大小写不同）只生成一次
+	if err := os.WriteFile(second, []byte("API\nvpn\n\nmail\ndev\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	b := NewBrute(&config.Config{})
+	b.SetWordlists([]string{first, " ", second})
+
+	subdomains, err := b.generateDict("example.com")
+	if err != nil {
+		t.Fatalf("generateDict failed: %v", err)
+	}
+	want := []string{"www.example.com", "mail.example.com", "api.example.com", "vpn.example.com", "dev.example.com"}
+	if strings.Join(subdomains, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected %v, got %v", want, subdomains)
+	}
+
+	// 指定的字典不存在时报错，而不是静默回退到默认字典
+	b.SetWordlists([]string{first, filepath.Join(dir, "missing.txt")})
+	if _, err := b.generateDict("example.com"); err == nil {
+		t.Error("Expected an error for a missing wordlist")
+	}
+
+	// 清空后恢复默认字典
+	b.SetWordlists(nil)
+	if files := b.dictFiles(); len(files) != 1 || files[0] != b.wordlist {
+		t.Errorf("Expected the default wordlist after clearing, got %v", files)
+	}
+}
+
+// candidates 把候选列表转换为 bruteSubdomains 读取的通道
+func candidates(subdomains []string) <-chan string {
+	ch := make(chan string, len(subdomains))
+	for _, subdomain := range subdomains {
+		ch <- subdomain
+	}
+	close(ch)
+	return ch
+}
+
+func TestOpenDictStreamsCandidates(t *testing.T) {
+	wordlist := filepath.Join(t.TempDir(), "words.txt")
+	// 最后一行没有换行符
+	if err := os.WriteFile(wordlist, []byte("www\n# comment\n\nmail\nWWW\napi"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	b := NewBrute(&config.Config{})
+	b.SetWordlists([]string{wordlist})
+	dict, err := b.openDict("example.com")
+	if err != nil {
+		t.Fatalf("openDict failed: %v", err)
+	}
+	// 行数包含注释、空行和重复词，作为进度统计的上限
+	if dict.Lines != 6 {
+		t.Errorf("Expected 6 lines, got %d", dict.Lines)
+	}
+
+	var got []string
+	for subdomain := range dict.Candidates {
+		got = append(got, subdomain)
+	}
+	generated, err := dict.Wait()
+	if err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	want := []string{"www.example.com", "mail.example.com", "api.example.com"}
+	if generated != len(want) || strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected %v, got %v (generated %d)", want, got, generated)
+	}
+}
+
+// BenchmarkOpenDict 流式读取不同大小的字典，peak-heap-B 为读取过程中堆内存的峰值增量：
+// 候选字符串不再整体保存，峰值只随去重布隆过滤器（每行约 2.4 字节）增长，而不是每行数十字节。
+// 使用 go test -run '^$' -bench OpenDict -benchmem ./internal/brute/ 查看
+func BenchmarkOpenDict(b *testing.B) {
+	for _, lines := range []int{100000, 1000000} {
+		wordlist := filepath.Join(b.TempDir(), "words.txt")
+		var sb strings.Builder
+		for i := 0; i < lines; i++ {
+			fmt.Fprintf(&sb, "word%d\n", i)
+		}
+		if err := os.WriteFile(wordlist, []byte(sb.String()), 0644); err != nil {
+			b.Fatal(err)
+		}
+		sb.Reset()
+
+		b.Run(fmt.Sprintf("lines=%d", lines), func(b *testing.B) {
+			brute := NewBrute(&config.Config{DedupBloomThreshold: 1})
+			brute.SetWordlists([]string{wordlist})
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				runtime.GC()
+				var stats runtime.MemStats
+				runtime.ReadMemStats(&stats)
+				base := stats.HeapAlloc
+				peak := base
+
+				dict, err := brute.openDict("example.com")
+				if err != nil {
+					b.Fatal(err)
+				}
+				count := 0
+				for range dict.Candidates {
+					count++
+					if count%50000 == 0 {
+						runtime.ReadMemStats(&stats)
+						if stats.HeapAlloc > peak {
+							peak = stats.HeapAlloc
+						}
+					}
+				}
+				if _, err := dict.Wait(); err != nil {
+					b.Fatal(err)
+				}
+				b.ReportMetric(float64(peak-base), "peak-heap-B")
+			}
+		})
+	}
+}
+
+func TestNXDomainHijackingResolverIgnored(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen udp: %v", err)
+	}
+
+	// 劫持 NXDOMAIN 的解析器：除 www.example.com 外的任何名称都返回同一个广告页 IP
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		ip := "198.51.100.77"
+		if r.Question[0].Name == "www.example.com." {
+			ip = "192.0.2.10"
+		}
+		rr, _ := dns.NewRR(r.Question[0].Name + " 60 IN A " + ip)
+		m.Answer = append(m.Answer, rr)
+		w.WriteMsg(m)
+	})
+
+	started := make(chan struct{})
+	server := &dns.Server{PacketConn: pc, Handler: handler, NotifyStartedFunc: func() { close(started) }}
+	go server.ActivateAndServe()
+	<-started
+	defer server.Shutdown()
+
+	b := NewBrute(&config.Config{})
+	b.nameservers = []string{pc.LocalAddr().String()}
+	b.hijackIPs = b.detectHijack()
+	if len(b.hijackIPs) != 1 || !b.hijackIPs["198.51.100.77"] {
+		t.Fatalf("Expected hijack IP 198.51.100.77, got %v", b.hijackIPs)
+	}
+
+	// 只解析到劫持 IP 的子域名无效
+	result, err := b.resolveSubdomain("nonexistent.example.com")
+	if err != nil || result.Valid || len(result.IPs) != 0 || b.isValidSubdomain(result) {
+		t.Errorf("Expected hijacked answer to be invalid, got %+v (%v)", result, err)
+	}
+
+	// 真实存在的子域名不受影响
+	result, err = b.resolveSubdomain("www.example.com")
+	if err != nil || !result.Valid || len(result.IPs) != 1 || result.IPs[0] != "192.0.2.10" {
+		t.Errorf("Expected www.example.com to resolve to 192.0.2.10, got %+v (%v)", result, err)
+	}
+}
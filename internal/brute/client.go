@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
@@ -47,6 +48,26 @@ func NewClient(concurrency int, timeout int) *Client {
 	}
 }
 
+// SetResolvers 设置 DNS 服务器
+func (c *Client) SetResolvers(resolvers []string) {
+	c.dnsClient.SetResolvers(resolvers)
+}
+
+// SetInsecureTLS 设置是否跳过 DoT 服务器的证书校验
+func (c *Client) SetInsecureTLS(insecure bool) {
+	c.dnsClient.SetInsecureTLS(insecure)
+}
+
+// SetIPVersion 设置解析的 IP 版本（4、6、both）
+func (c *Client) SetIPVersion(version string) {
+	c.dnsClient.SetIPVersion(version)
+}
+
+// SetClientSubnet 设置 EDNS Client Subnet
+func (c *Client) SetClientSubnet(subnet *net.IPNet) {
+	c.dnsClient.SetClientSubnet(subnet)
+}
+
 // Brute 执行暴力破解
 func (c *Client) Brute(domain string) ([]Subdomain, error) {
 	logger.Infof("Starting brute force for domain: %s", domain)
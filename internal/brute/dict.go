@@ -0,0 +1,158 @@
+package brute
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/oneforall-go/internal/dedup"
+	"github.com/oneforall-go/pkg/logger"
+)
+
+// dictBufferSize 字典读取协程与爆破协程之间的候选缓冲数，决定了字典在内存中的最大积压量
+const dictBufferSize = 1024
+
+// dictStream 流式生成的爆破字典：后台协程逐行读取字典文件，去重后写入 Candidates，
+// 内存占用只与缓冲大小和去重集合有关，不随字典行数增长
+type dictStream struct {
+	// Candidates 候选子域名，字典读完或读取出错时关闭，调用方需读到关闭为止
+	Candidates <-chan string
+	// Lines 字典文件总行数（含空行、注释和重复词），是候选数的上限，用于进度统计
+	Lines int
+
+	done      chan struct{}
+	generated int
+	err       error
+}
+
+// Wait 等待字典读取结束，返回生成的候选数和读取错误
+func (s *dictStream) Wait() (int, error) {
+	<-s.done
+	return s.generated, s.err
+}
+
+// openDict 统计字典行数后开始流式生成候选子域名，多个字典文件中的词合并去重；
+// 字典文件不存在时在开始读取前报错
+func (b *Brute) openDict(domain string) (*dictStream, error) {
+	files := b.dictFiles()
+	logger.Infof("Loading wordlists from: %s", strings.Join(files, ", "))
+
+	lines, err := b.countLines(files)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make(chan string, dictBufferSize)
+	stream := &dictStream{
+		Candidates: candidates,
+		Lines:      lines,
+		done:       make(chan struct{}),
+	}
+	go func() {
+		defer close(stream.done)
+		defer close(candidates)
+		stream.generated, stream.err = b.readDict(domain, files, lines, candidates)
+	}()
+	return stream, nil
+}
+
+// readDict 逐行读取字典文件，把去重后的候选子域名写入 out，返回写入的候选数
+func (b *Brute) readDict(domain string, files []string, lines int, out chan<- string) (int, error) {
+	// 按总行数创建去重集合，大字典使用布隆过滤器去重以节省内存
+	seen := dedup.NewSet(lines, b.dedupThreshold, b.dedupFPRate)
+
+	generated := 0
+	lineCount := 0
+	duplicates := 0
+	var samples []string
+	for _, wordlist := range files {
+		file, err := b.openWordlist(wordlist)
+		if err != nil {
+			return generated, fmt.Errorf("failed to open wordlist %s: %v", wordlist, err)
+		}
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			lineCount++
+			word := strings.ToLower(strings.TrimSpace(scanner.Text()))
+			if word == "" || strings.HasPrefix(word, "#") {
+				continue
+			}
+			if !seen.Add(word) {
+				duplicates++
+				continue
+			}
+
+			// 生成子域名
+			subdomain := fmt.Sprintf("%s.%s", word, domain)
+			if len(samples) < 5 {
+				samples = append(samples, subdomain)
+			}
+			out <- subdomain
+			generated++
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return generated, fmt.Errorf("error reading wordlist %s: %v", wordlist, err)
+		}
+	}
+
+	logger.Infof("Generated %d subdomains from wordlist (read %d lines, skipped %d duplicates)", generated, lineCount, duplicates)
+	if len(samples) > 0 {
+		logger.Debugf("Sample subdomains: %v", samples)
+	}
+	return generated, nil
+}
+
+// generateDict 生成完整的爆破字典，只用于 dry-run 等需要全部候选的场景，爆破时使用 openDict 流式读取
+func (b *Brute) generateDict(domain string) ([]string, error) {
+	stream, err := b.openDict(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	var subdomains []string
+	for subdomain := range stream.Candidates {
+		subdomains = append(subdomains, subdomain)
+	}
+	if _, err := stream.Wait(); err != nil {
+		return nil, err
+	}
+	return subdomains, nil
+}
+
+// countLines 统计字典文件的总行数（最后一行没有换行符时也计入），只按块扫描换行符，不逐行解析
+func (b *Brute) countLines(files []string) (int, error) {
+	buf := make([]byte, 64*1024)
+	total := 0
+	for _, wordlist := range files {
+		file, err := b.openWordlist(wordlist)
+		if err != nil {
+			return 0, fmt.Errorf("failed to open wordlist %s: %v", wordlist, err)
+		}
+
+		var last byte = '\n'
+		for {
+			n, err := file.Read(buf)
+			if n > 0 {
+				total += bytes.Count(buf[:n], []byte{'\n'})
+				last = buf[n-1]
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				file.Close()
+				return 0, fmt.Errorf("error reading wordlist %s: %v", wordlist, err)
+			}
+		}
+		file.Close()
+		if last != '\n' {
+			total++
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,100 @@
+package brute
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/miekg/dns"
+	dnsutil "github.com/oneforall-go/internal/dns"
+	"github.com/oneforall-go/pkg/logger"
+)
+
+// hijackProbeSuffixes NXDOMAIN 劫持校准查询的后缀，每个后缀查询一个随机标签：
+// .invalid 保证不存在（RFC 6761），常见顶级域名下 20 位随机标签实际上也不存在，且运营商解析器往往只劫持这些顶级域名
+var hijackProbeSuffixes = []string{"com", "net", "invalid"}
+
+// detectHijack 向每个 DNS 服务器查询几个随机的不存在域名，返回应答了地址记录的 IP（NXDOMAIN 劫持 IP）。
+// 泛解析检测针对目标区域，这里检测的是解析器本身：劫持的解析器对任何不存在的主机都返回同一个广告/搜索页 IP
+func (b *Brute) detectHijack() map[string]bool {
+	hijackIPs := make(map[string]bool)
+	for _, nameserver := range b.nameservers {
+		var ips []string
+		for _, suffix := range hijackProbeSuffixes {
+			label, err := randomLabel()
+			if err != nil {
+				logger.Debugf("Failed to generate NXDOMAIN probe name: %v", err)
+				return hijackIPs
+			}
+			ips = append(ips, b.probeNameserver(nameserver, fmt.Sprintf("%s.%s", label, suffix))...)
+		}
+		if len(ips) == 0 {
+			continue
+		}
+
+		for _, ip := range ips {
+			hijackIPs[ip] = true
+		}
+		logger.Warnf("DNS server %s answers nonexistent names with %v (NXDOMAIN hijacking), ignoring these IPs", nameserver, uniqueSorted(ips))
+	}
+	return hijackIPs
+}
+
+// probeNameserver 只向指定 DNS 服务器查询地址记录，返回应答中的 IP
+func (b *Brute) probeNameserver(nameserver, name string) []string {
+	var ips []string
+	for _, qtype := range b.recordTypes {
+		msg := new(dns.Msg)
+		msg.SetQuestion(dns.Fqdn(name), qtype)
+		msg.RecursionDesired = true
+
+		resp, err := b.exchange(msg, nameserver)
+		if err != nil {
+			continue
+		}
+		for _, answer := range resp.Answer {
+			if ip := dnsutil.AddressFromRR(answer); ip != "" && answer.Header().Rrtype == qtype {
+				ips = append(ips, ip)
+			}
+		}
+	}
+	return ips
+}
+
+// withoutHijackIPs 去掉解析结果中的 NXDOMAIN 劫持 IP
+func (b *Brute) withoutHijackIPs(ips []string) []string {
+	if len(b.hijackIPs) == 0 {
+		return ips
+	}
+	kept := ips[:0:0]
+	for _, ip := range ips {
+		if !b.hijackIPs[ip] {
+			kept = append(kept, ip)
+		}
+	}
+	return kept
+}
+
+// randomLabel 生成 20 位十六进制随机标签
+func randomLabel() (string, error) {
+	buf := make([]byte, 10)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// uniqueSorted 返回去重排序后的列表
+func uniqueSorted(items []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, item := range items {
+		if !seen[item] {
+			seen[item] = true
+			unique = append(unique, item)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
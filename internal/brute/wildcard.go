@@ -0,0 +1,153 @@
+package brute
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oneforall-go/internal/config"
+	"github.com/oneforall-go/pkg/logger"
+)
+
+var (
+	sharedWildcardCaches     = make(map[wildcardCacheKey]*WildcardCache)
+	sharedWildcardCacheMutex sync.Mutex
+)
+
+// wildcardCacheKey 共享缓存按缓存文件和有效期区分
+type wildcardCacheKey struct {
+	path string
+	ttl  time.Duration
+}
+
+// wildcardCacheEntry 泛解析检测缓存项
+type wildcardCacheEntry struct {
+	Result     *WildcardDetectionResult `json:"result"`
+	DetectedAt time.Time                `json:"detected_at"`
+}
+
+// WildcardCache 按域名缓存泛解析检测结果，子域名（子区域）单独缓存
+type WildcardCache struct {
+	entries map[string]wildcardCacheEntry
+	ttl     time.Duration
+	path    string
+	mutex   sync.Mutex
+}
+
+// NewWildcardCache 创建泛解析检测缓存，path 非空时从文件加载并在更新时写回
+func NewWildcardCache(path string, ttl time.Duration) *WildcardCache {
+	c := &WildcardCache{
+		entries: make(map[string]wildcardCacheEntry),
+		ttl:     ttl,
+		path:    path,
+	}
+	if path != "" {
+		if err := c.load(); err != nil {
+			logger.Debugf("Failed to load wildcard cache %s: %v", path, err)
+		}
+	}
+	return c
+}
+
+// getSharedWildcardCache 获取进程内共享的泛解析检测缓存，缓存文件和有效期相同的配置共用一个缓存
+func getSharedWildcardCache(cfg *config.Config) *WildcardCache {
+	key := wildcardCacheKey{path: cfg.WildcardCacheFile, ttl: time.Duration(cfg.WildcardCacheTTL) * time.Second}
+
+	sharedWildcardCacheMutex.Lock()
+	defer sharedWildcardCacheMutex.Unlock()
+
+	cache, ok := sharedWildcardCaches[key]
+	if !ok {
+		cache = NewWildcardCache(key.path, key.ttl)
+		sharedWildcardCaches[key] = cache
+	}
+	return cache
+}
+
+// Get 获取未过期的检测结果
+func (c *WildcardCache) Get(domain string) (*WildcardDetectionResult, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[cacheKey(domain)]
+	if !ok || c.expired(entry) {
+		return nil, false
+	}
+	return entry.Result, true
+}
+
+// Set 缓存检测结果
+func (c *WildcardCache) Set(domain string, result *WildcardDetectionResult) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries[cacheKey(domain)] = wildcardCacheEntry{Result: result, DetectedAt: time.Now()}
+	if c.path != "" {
+		if err := c.save(); err != nil {
+			logger.Warnf("Failed to persist wildcard cache %s: %v", c.path, err)
+		}
+	}
+}
+
+// Detect 优先返回缓存结果，未命中时调用 detect 并缓存
+func (c *WildcardCache) Detect(domain string, detect func(string) (*WildcardDetectionResult, error)) (*WildcardDetectionResult, error) {
+	if result, ok := c.Get(domain); ok {
+		logger.Infof("Using cached wildcard detection result for %s (wildcard: %t)", domain, result.IsWildcard)
+		return result, nil
+	}
+
+	result, err := detect(domain)
+	if err != nil {
+		return result, err
+	}
+	c.Set(domain, result)
+	return result, nil
+}
+
+// expired 判断缓存项是否过期，ttl 不大于 0 时不过期
+func (c *WildcardCache) expired(entry wildcardCacheEntry) bool {
+	return c.ttl > 0 && time.Since(entry.DetectedAt) > c.ttl
+}
+
+// load 从文件加载未过期的缓存项
+func (c *WildcardCache) load() error {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var entries map[string]wildcardCacheEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("failed to parse cache: %v", err)
+	}
+	for domain, entry := range entries {
+		if entry.Result != nil && !c.expired(entry) {
+			c.entries[domain] = entry
+		}
+	}
+	return nil
+}
+
+// save 将缓存写入文件
+func (c *WildcardCache) save() error {
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, data, 0644)
+}
+
+// cacheKey 规范化缓存键
+func cacheKey(domain string) string {
+	return strings.ToLower(strings.TrimSuffix(domain, "."))
+}
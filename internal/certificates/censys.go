@@ -1,15 +1,21 @@
 package certificates
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/url"
+	"strings"
 
 	"github.com/oneforall-go/internal/config"
 	"github.com/oneforall-go/internal/core"
 )
 
-// Censys Censys 证书模块
+// censysMaxPages 每个索引最多翻页数，每页消耗一次查询额度
+const censysMaxPages = 10
+
+// Censys Censys 证书模块，查询 v2 证书和主机索引
 type Censys struct {
 	*core.Query
 	baseURL string
@@ -17,29 +23,56 @@ type Censys struct {
 	secret  string
 }
 
-// CensysResponse Censys API 响应结构
+// CensysResponse Censys v2 搜索 API 响应结构
 type CensysResponse struct {
+	Code   int    `json:"code"`
 	Status string `json:"status"`
+	Error  string `json:"error"`
 	Result struct {
+		Total int `json:"total"`
 		Links struct {
 			Next string `json:"next"`
 		} `json:"links"`
-		Hits []struct {
-			Names []string `json:"names"`
-		} `json:"hits"`
+		Hits []CensysHit `json:"hits"`
 	} `json:"result"`
 }
 
+// CensysHit 搜索结果条目，证书索引返回 names，主机索引返回 name、dns 和服务证书
+type CensysHit struct {
+	Names []string `json:"names"`
+	Name  string   `json:"name"`
+	DNS   struct {
+		Names      []string `json:"names"`
+		ReverseDNS struct {
+			Names []string `json:"names"`
+		} `json:"reverse_dns"`
+	} `json:"dns"`
+	Services []struct {
+		TLS struct {
+			Certificates struct {
+				LeafData struct {
+					Names []string `json:"names"`
+				} `json:"leaf_data"`
+			} `json:"certificates"`
+		} `json:"tls"`
+	} `json:"services"`
+}
+
 // NewCensys 创建 Censys 模块
 func NewCensys(cfg *config.Config) *Censys {
 	return &Censys{
 		Query:   core.NewQuery("CensysAPIQuery", cfg),
-		baseURL: "https://search.censys.io/api/v2/certificates/search",
+		baseURL: "https://search.censys.io/api/v2",
 		apiID:   cfg.APIKeys["censys_api_id"],
 		secret:  cfg.APIKeys["censys_api_secret"],
 	}
 }
 
+// RequiresAPIKey 未配置 censys_api_id、censys_api_secret 时不发送请求
+func (c *Censys) RequiresAPIKey() bool {
+	return true
+}
+
 // Run 执行查询
 func (c *Censys) Run(domain string) ([]string, error) {
 	c.SetDomain(domain)
@@ -59,95 +92,118 @@ func (c *Censys) Run(domain string) ([]string, error) {
 	return c.GetSubdomains(), nil
 }
 
-// query 执行查询
+// query 依次查询证书索引和主机索引，一个索引失败时继续查询另一个
 func (c *Censys) query(domain string) error {
-	// 设置请求头
+	// 设置请求头，API ID 和 Secret 使用 HTTP Basic 认证
 	c.SetHeader("User-Agent", c.GetRandomUserAgent())
+	c.SetHeader("Accept", "application/json")
+	auth := base64.StdEncoding.EncodeToString([]byte(c.apiID + ":" + c.secret))
+	c.SetHeader("Authorization", "Basic "+auth)
 
-	// 构建查询参数
-	params := url.Values{}
-	params.Set("q", fmt.Sprintf("names: %s", domain))
-	params.Set("per_page", "100")
-
-	// 发送 GET 请求
-	queryURL := fmt.Sprintf("%s?%s", c.baseURL, params.Encode())
-	resp, err := c.HTTPGet(queryURL, c.GetHeader())
-	if err != nil {
-		return fmt.Errorf("failed to query Censys API: %v", err)
+	certErr := c.search("certificates", fmt.Sprintf("names: %s", domain), domain)
+	if certErr != nil {
+		c.LogError("Certificate search failed: %v", certErr)
 	}
 
-	// 读取响应
-	body, err := c.ReadResponseBody(resp)
-	if err != nil {
-		return fmt.Errorf("failed to read response: %v", err)
+	hostErr := c.search("hosts", fmt.Sprintf("dns.names: %s or services.tls.certificates.leaf_data.names: %s", domain, domain), domain)
+	if hostErr != nil {
+		c.LogError("Host search failed: %v", hostErr)
 	}
 
-	// 解析 JSON 响应
-	var response CensysResponse
-	if err := json.Unmarshal([]byte(body), &response); err != nil {
-		return fmt.Errorf("failed to parse JSON response: %v", err)
+	if certErr != nil && hostErr != nil {
+		return fmt.Errorf("failed to query Censys: %v", certErr)
 	}
+	return nil
+}
 
-	// 检查状态
-	if response.Status != "OK" {
-		return fmt.Errorf("Censys API returned status: %s", response.Status)
-	}
+// search 按 links.next 游标分页查询指定索引，达到页数上限或游标为空时停止
+func (c *Censys) search(index, q, domain string) error {
+	cursor := ""
+	for page := 1; page <= censysMaxPages; page++ {
+		params := url.Values{}
+		params.Set("q", q)
+		params.Set("per_page", "100")
+		if cursor != "" {
+			params.Set("cursor", cursor)
+		}
 
-	// 提取子域名
-	c.extractSubdomains(response, domain)
+		response, err := c.fetchPage(fmt.Sprintf("%s/%s/search?%s", c.baseURL, index, params.Encode()))
+		if err != nil {
+			// 已获取的页面结果保留
+			if page > 1 {
+				c.LogError("Failed to query %s page %d: %v", index, page, err)
+				return nil
+			}
+			return err
+		}
 
-	// 处理分页
-	nextCursor := response.Result.Links.Next
-	for nextCursor != "" {
-		if err := c.queryNextPage(domain, nextCursor); err != nil {
-			c.LogError("Failed to query next page: %v", err)
-			break
+		c.extractSubdomains(response, domain)
+		c.LogDebug("Censys %s page %d: %d hits (total %d)", index, page, len(response.Result.Hits), response.Result.Total)
+
+		next := response.Result.Links.Next
+		if next == "" || next == cursor {
+			return nil
 		}
+		cursor = next
 	}
 
+	c.LogInfo("Reached the %d page limit for Censys %s search", censysMaxPages, index)
 	return nil
 }
 
-// queryNextPage 查询下一页
-func (c *Censys) queryNextPage(domain, cursor string) error {
-	// 构建查询参数
-	params := url.Values{}
-	params.Set("q", fmt.Sprintf("names: %s", domain))
-	params.Set("per_page", "100")
-	params.Set("cursor", cursor)
+// fetchPage 请求一页结果
+func (c *Censys) fetchPage(queryURL string) (CensysResponse, error) {
+	var response CensysResponse
 
-	// 发送 GET 请求
-	queryURL := fmt.Sprintf("%s?%s", c.baseURL, params.Encode())
 	resp, err := c.HTTPGet(queryURL, c.GetHeader())
 	if err != nil {
-		return fmt.Errorf("failed to query next page: %v", err)
+		return response, err
 	}
 
 	// 读取响应
 	body, err := c.ReadResponseBody(resp)
 	if err != nil {
-		return fmt.Errorf("failed to read response: %v", err)
+		return response, fmt.Errorf("failed to read response: %v", err)
 	}
 
-	// 解析 JSON 响应
-	var response CensysResponse
+	// 解析 JSON 响应，错误响应同样是 JSON
 	if err := json.Unmarshal([]byte(body), &response); err != nil {
-		return fmt.Errorf("failed to parse JSON response: %v", err)
+		return response, fmt.Errorf("failed to parse JSON response (status %d): %v", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return response, fmt.Errorf("Censys API returned status %d: %s", resp.StatusCode, response.Error)
+	}
+	if response.Status != "OK" {
+		return response, fmt.Errorf("Censys API returned status: %s", response.Status)
 	}
 
-	// 提取子域名
-	c.extractSubdomains(response, domain)
-
-	return nil
+	return response, nil
 }
 
-// extractSubdomains 提取子域名
+// extractSubdomains 提取子域名，范围外的名称记为关联域名
 func (c *Censys) extractSubdomains(response CensysResponse, domain string) {
 	for _, hit := range response.Result.Hits {
-		for _, name := range hit.Names {
+		for _, name := range hit.names() {
+			name = strings.TrimPrefix(core.NormalizeHost(name), "*.")
 			if c.IsValidSubdomain(name, domain) {
 				c.AddSubdomain(name)
+			} else if !core.InScope(name, domain) {
+				c.AddRelatedDomain(name)
 			}
 		}
 	}
 }
+
+// names 汇总条目中的所有主机名
+func (h CensysHit) names() []string {
+	names := append([]string{}, h.Names...)
+	if h.Name != "" {
+		names = append(names, h.Name)
+	}
+	names = append(names, h.DNS.Names...)
+	names = append(names, h.DNS.ReverseDNS.Names...)
+	for _, service := range h.Services {
+		names = append(names, service.TLS.Certificates.LeafData.Names...)
+	}
+	return names
+}
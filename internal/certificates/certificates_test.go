@@ -0,0 +1,302 @@
+package certificates
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oneforall-go/internal/config"
+	"github.com/oneforall-go/internal/core"
+)
+
+// crtshErrorPage crt.sh 过载时返回的错误页面
+const crtshErrorPage = `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01//EN" "http://www.w3.org/TR/html4/strict.dtd">
+<HTML>
+<HEAD>
+  <TITLE>crt.sh | ERROR!</TITLE>
+</HEAD>
+<BODY>
+  <P>Sorry, something went wrong... :-(</P>
+  <P>ERROR: canceling statement due to statement timeout</P>
+</BODY>
+</HTML>`
+
+// crtshResultPage crt.sh 的 HTML 查询结果页面（节选）
+const crtshResultPage = `<TABLE>
+  <TR>
+    <TD style="text-align:center"><A href="?id=1">1</A></TD>
+    <TD>*.api.example.com<BR>www.example.com</TD>
+  </TR>
+</TABLE>`
+
+func newTestCRTSh(baseURL string) *CRTSh {
+	c := NewCRTSh(&config.Config{})
+	c.baseURL = baseURL
+	c.certSpotterURL = baseURL + "certspotter"
+	c.bufferOverURL = baseURL + "bufferover"
+	c.SetDelay(0)
+	c.SetRetryPolicy(core.RetryPolicy{MaxAttempts: 1})
+	return c
+}
+
+func TestSplitNameValue(t *testing.T) {
+	got := splitNameValue("*.example.com\nWWW.example.com\n\n mail.example.com \nfoo.*.example.com")
+	want := []string{"example.com", "www.example.com", "mail.example.com"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("splitNameValue = %v, want %v", got, want)
+	}
+}
+
+func TestParseCrtShJSON(t *testing.T) {
+	if _, err := parseCrtShJSON(crtshErrorPage); err == nil {
+		t.Errorf("expected error for HTML error page")
+	}
+	if _, err := parseCrtShJSON(`[{"name_value":"a.example.com"`); err == nil {
+		t.Errorf("expected error for truncated JSON")
+	}
+
+	records, err := parseCrtShJSON(` [{"name_value":"a.example.com\nb.example.com"}]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(records) != 1 || records[0].NameValue != "a.example.com\nb.example.com" {
+		t.Errorf("unexpected records: %+v", records)
+	}
+}
+
+func TestCRTShFallbackToHTML(t *testing.T) {
+	oldDelay := crtshRetryDelay
+	crtshRetryDelay = time.Millisecond
+	defer func() { crtshRetryDelay = oldDelay }()
+
+	var jsonRequests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("output") == "json" {
+			// 交替返回 502 与 200 的 HTML 错误页
+			if atomic.AddInt32(&jsonRequests, 1)%2 == 1 {
+				w.WriteHeader(http.StatusBadGateway)
+			}
+			w.Write([]byte(crtshErrorPage))
+			return
+		}
+		w.Write([]byte(crtshResultPage))
+	}))
+	defer server.Close()
+
+	c := newTestCRTSh(server.URL + "/")
+	if err := c.query("example.com"); err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+
+	if n := atomic.LoadInt32(&jsonRequests); n != crtshMaxAttempts {
+		t.Errorf("expected %d JSON attempts, got %d", crtshMaxAttempts, n)
+	}
+
+	got := c.GetSubdomains()
+	sort.Strings(got)
+	want := []string{"api.example.com", "www.example.com"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("subdomains = %v, want %v", got, want)
+	}
+}
+
+func TestCRTShRetryJSON(t *testing.T) {
+	oldDelay := crtshRetryDelay
+	crtshRetryDelay = time.Millisecond
+	defer func() { crtshRetryDelay = oldDelay }()
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte(crtshErrorPage))
+			return
+		}
+		w.Write([]byte(`[{"name_value":"*.dev.example.com\nmail.example.com"},{"name_value":"other.org"}]`))
+	}))
+	defer server.Close()
+
+	c := newTestCRTSh(server.URL + "/")
+	if err := c.query("example.com"); err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+
+	found := make(map[string]bool)
+	for _, subdomain := range c.GetSubdomains() {
+		found[subdomain] = true
+	}
+	for _, want := range []string{"dev.example.com", "mail.example.com"} {
+		if !found[want] {
+			t.Errorf("expected %s in results, got %v", want, c.GetSubdomains())
+		}
+	}
+	if found["other.org"] {
+		t.Errorf("out-of-scope name should be filtered")
+	}
+}
+
+func TestCRTShUnavailable(t *testing.T) {
+	oldDelay := crtshRetryDelay
+	crtshRetryDelay = time.Millisecond
+	defer func() { crtshRetryDelay = oldDelay }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(crtshErrorPage))
+	}))
+	defer server.Close()
+
+	c := newTestCRTSh(server.URL + "/")
+	if err := c.query("example.com"); err == nil {
+		t.Errorf("expected error when crt.sh is unavailable")
+	}
+	if len(c.GetSubdomains()) != 0 {
+		t.Errorf("expected no subdomains, got %v", c.GetSubdomains())
+	}
+}
+
+func TestCRTShFallbackToOtherCTSources(t *testing.T) {
+	oldDelay := crtshRetryDelay
+	crtshRetryDelay = time.Millisecond
+	defer func() { crtshRetryDelay = oldDelay }()
+
+	var crtshRequests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/certspotter":
+			if r.URL.Query().Get("domain") != "example.com" || r.URL.Query().Get("expand") != "dns_names" {
+				t.Errorf("unexpected CertSpotter query %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`[{"dns_names": ["*.example.com", "www.example.com", "api.example.com", "other.org"]}]`))
+		case "/bufferover":
+			if r.Header.Get("x-api-key") != "test-key" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"Meta": {"Errors": []}, "Results": ["192.0.2.1,abc123,,www.example.com,vpn.example.com"]}`))
+		default:
+			atomic.AddInt32(&crtshRequests, 1)
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte(crtshErrorPage))
+		}
+	}))
+	defer server.Close()
+
+	// crt.sh 返回 502 时使用 CertSpotter 的数据
+	c := newTestCRTSh(server.URL + "/")
+	if err := c.query("example.com"); err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if atomic.LoadInt32(&crtshRequests) == 0 {
+		t.Errorf("expected crt.sh to be queried first")
+	}
+	got := c.GetSubdomains()
+	sort.Strings(got)
+	if strings.Join(got, ",") != "api.example.com,www.example.com" {
+		t.Errorf("subdomains = %v, want CertSpotter results", got)
+	}
+	if sources := c.Sources(); len(sources) != 1 || sources[0] != CertSpotterSource {
+		t.Errorf("expected CertSpotter as the succeeded source, got %v", sources)
+	}
+	if tags := c.TakeSourceTags(); tags["www.example.com"] != CertSpotterSource {
+		t.Errorf("expected results tagged with %s, got %v", CertSpotterSource, tags)
+	}
+
+	// 配置 bufferover API 密钥后合并两个备用数据源的结果
+	c = newTestCRTSh(server.URL + "/")
+	c.GetConfig().APIKeys = map[string]string{"bufferover_api_key": "test-key"}
+	if err := c.query("example.com"); err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	got = c.GetSubdomains()
+	sort.Strings(got)
+	if strings.Join(got, ",") != "api.example.com,vpn.example.com,www.example.com" {
+		t.Errorf("subdomains = %v, want merged fallback results", got)
+	}
+	if sources := c.Sources(); strings.Join(sources, ",") != CertSpotterSource+","+BufferOverSource {
+		t.Errorf("expected both fallback sources, got %v", sources)
+	}
+}
+
+// censysPages Censys v2 搜索响应（节选），按索引和游标区分
+var censysPages = map[string]string{
+	"certificates:": `{"code": 200, "status": "OK", "result": {"query": "names: example.com", "total": 3,
+		"hits": [{"names": ["*.example.com", "www.example.com"], "fingerprint_sha256": "aa"},
+		         {"names": ["api.example.com", "example-cdn.net"], "fingerprint_sha256": "bb"}],
+		"links": {"prev": "", "next": "eyJwYWdlIjoyfQ=="}}}`,
+	"certificates:eyJwYWdlIjoyfQ==": `{"code": 200, "status": "OK", "result": {"query": "names: example.com", "total": 3,
+		"hits": [{"names": ["mail.example.com"], "fingerprint_sha256": "cc"}],
+		"links": {"prev": "eyJwYWdlIjoxfQ==", "next": ""}}}`,
+	"hosts:": `{"code": 200, "status": "OK", "result": {"query": "dns.names: example.com", "total": 1,
+		"hits": [{"ip": "192.0.2.10", "name": "vpn.example.com",
+		          "dns": {"names": ["portal.example.com"], "reverse_dns": {"names": ["host-10.example.com"]}},
+		          "services": [{"port": 443, "service_name": "HTTP",
+		                        "tls": {"certificates": {"leaf_data": {"names": ["sso.example.com"]}}}}]}],
+		"links": {"prev": "", "next": ""}}}`,
+}
+
+func TestCensysPagination(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if id, secret, ok := r.BasicAuth(); !ok || id != "test-id" || secret != "test-secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"code": 401, "status": "Unauthorized", "error": "You must authenticate with a valid API ID and secret."}`))
+			return
+		}
+
+		index := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/"), "/search")
+		page, ok := censysPages[index+":"+r.URL.Query().Get("cursor")]
+		if !ok {
+			t.Errorf("Unexpected request %s", r.URL)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(page))
+	}))
+	defer server.Close()
+
+	c := NewCensys(&config.Config{APIKeys: map[string]string{"censys_api_id": "test-id", "censys_api_secret": "test-secret"}})
+	c.baseURL = server.URL
+	c.SetDelay(0)
+	c.SetRetryPolicy(core.RetryPolicy{MaxAttempts: 1})
+
+	subdomains, err := c.Run("example.com")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	sort.Strings(subdomains)
+	want := []string{"api.example.com", "host-10.example.com", "mail.example.com", "portal.example.com",
+		"sso.example.com", "vpn.example.com", "www.example.com"}
+	if strings.Join(subdomains, ",") != strings.Join(want, ",") {
+		t.Errorf("subdomains = %v, want %v", subdomains, want)
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Errorf("expected 3 requests (2 certificate pages, 1 host page), got %d", n)
+	}
+	if related := c.TakeRelatedDomains(); len(related) != 1 || related[0] != "example-cdn.net" {
+		t.Errorf("expected example-cdn.net as related domain, got %v", related)
+	}
+}
+
+func TestCensysUnauthorized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"code": 401, "status": "Unauthorized", "error": "You must authenticate with a valid API ID and secret."}`))
+	}))
+	defer server.Close()
+
+	c := NewCensys(&config.Config{APIKeys: map[string]string{"censys_api_id": "bad", "censys_api_secret": "bad"}})
+	c.baseURL = server.URL
+	c.SetDelay(0)
+	c.SetRetryPolicy(core.RetryPolicy{MaxAttempts: 1})
+
+	if _, err := c.Run("example.com"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected 401 error, got %v", err)
+	}
+}
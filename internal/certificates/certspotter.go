@@ -77,6 +77,8 @@ func (c *CertSpotter) query(domain string) error {
 		for _, dnsName := range cert.DNSNames {
 			if c.IsValidSubdomain(dnsName, domain) {
 				c.AddSubdomain(dnsName)
+			} else if !core.InScope(dnsName, domain) {
+				c.AddRelatedDomain(dnsName)
 			}
 		}
 	}
@@ -3,11 +3,12 @@ package certificates
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/oneforall-go/internal/config"
+	"github.com/oneforall-go/internal/core"
 	"github.com/oneforall-go/pkg/logger"
 )
 
@@ -125,7 +126,7 @@ func (c *CertificateClient) queryCensys(domain string) ([]string, error) {
 	var subdomains []string
 	for _, hit := range result.Result.Hits {
 		for _, name := range hit.Names {
-			if strings.Contains(name, domain) {
+			if core.InScope(name, domain) {
 				subdomains = append(subdomains, name)
 			}
 		}
@@ -160,7 +161,7 @@ func (c *CertificateClient) queryCertSpotter(domain string) ([]string, error) {
 
 	var subdomains []string
 	for _, cert := range certs {
-		if strings.Contains(cert.NameValue, domain) {
+		if core.InScope(cert.NameValue, domain) {
 			subdomains = append(subdomains, cert.NameValue)
 		}
 	}
@@ -187,20 +188,21 @@ func (c *CertificateClient) queryCrtSh(domain string) ([]string, error) {
 		return nil, fmt.Errorf("crt.sh API returned status: %d", resp.StatusCode)
 	}
 
-	var certs []struct {
-		NameValue string `json:"name_value"`
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&certs); err != nil {
+	// crt.sh 出错时可能返回 200 的 HTML 页面，需识别为错误而不是空结果
+	certs, err := parseCrtShJSON(string(body))
+	if err != nil {
 		return nil, err
 	}
 
 	var subdomains []string
 	for _, cert := range certs {
-		names := strings.Split(cert.NameValue, "\n")
-		for _, name := range names {
-			name = strings.TrimSpace(name)
-			if strings.Contains(name, domain) && name != domain {
+		for _, name := range splitNameValue(cert.NameValue) {
+			if core.InScope(name, domain) && core.NormalizeHost(name) != core.NormalizeHost(domain) {
 				subdomains = append(subdomains, name)
 			}
 		}
@@ -3,18 +3,26 @@ package certificates
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/url"
-	"os"
 	"strings"
+	"time"
 
 	"github.com/oneforall-go/internal/config"
 	"github.com/oneforall-go/internal/core"
 )
 
-// CRTSh CRTSh 证书模块
+// CRTSh CRTSh 证书模块，crt.sh 不可用时回退到其他 CT 数据源
 type CRTSh struct {
 	*core.Query
 	baseURL string
+
+	// 备用 CT 数据源
+	certSpotterURL string
+	bufferOverURL  string
+
+	// 最近一次查询实际返回数据的 CT 数据源
+	sources []string
 }
 
 // CRTShRecord CRTSh 记录结构
@@ -25,8 +33,10 @@ type CRTShRecord struct {
 // NewCRTSh 创建 CRTSh 证书模块
 func NewCRTSh(cfg *config.Config) *CRTSh {
 	return &CRTSh{
-		Query:   core.NewQuery("CrtshQuery", cfg),
-		baseURL: "https://crt.sh/",
+		Query:          core.NewQuery("CrtshQuery", cfg),
+		baseURL:        "https://crt.sh/",
+		certSpotterURL: "https://api.certspotter.com/v1/issuances",
+		bufferOverURL:  "https://tls.bufferover.run/dns",
 	}
 }
 
@@ -44,75 +54,168 @@ func (c *CRTSh) Run(domain string) ([]string, error) {
 	return c.GetSubdomains(), nil
 }
 
+// crt.sh 在高负载时经常返回 HTML 错误页或 502，非 JSON 响应时按退避重试
+const crtshMaxAttempts = 3
+
+// crtshRetryDelay 非 JSON 响应的首次重试间隔，之后指数增长
+var crtshRetryDelay = 2 * time.Second
+
 // query 执行查询
 func (c *CRTSh) query(domain string) error {
 	// 设置请求头
 	c.SetHeader("User-Agent", c.GetRandomUserAgent())
+	c.sources = nil
+
+	// 优先使用 JSON 接口，多次失败后回退到 HTML 页面解析，仍失败时查询备用 CT 数据源
+	names, wildcards, err := c.queryJSON(domain)
+	if err != nil {
+		c.LogInfo("crt.sh JSON output unavailable (%v), falling back to HTML", err)
+		names, err = c.queryHTML(domain)
+		if err != nil {
+			c.LogInfo("crt.sh unavailable (%v), querying fallback CT sources", err)
+			if err := c.queryFallbacks(domain, err); err != nil {
+				return fmt.Errorf("failed to query CRTSh: %v", err)
+			}
+			return nil
+		}
+	}
+	c.sources = []string{"crt.sh"}
+
+	// 收集范围内的域名
+	subDomains := make(map[string]bool)
+	for _, name := range names {
+		if c.IsValidSubdomain(name, domain) {
+			subDomains[name] = true
+		}
+	}
+	// 使用 altdns 字典展开通配符域名
+	if altdnsWords, err := c.readAltDNSWords(); err == nil {
+		for _, nameValue := range wildcards {
+			for _, word := range altdnsWords {
+				result := strings.Replace(nameValue, "*", word, 1)
+				if c.IsValidSubdomain(result, domain) {
+					subDomains[result] = true
+				}
+			}
+		}
+	}
+
+	for subdomain := range subDomains {
+		c.AddSubdomain(subdomain)
+	}
 
-	// 构建查询参数
+	return nil
+}
+
+// queryJSON 通过 output=json 查询，返回域名和原始通配符名称，非 200 或非 JSON 响应时退避重试
+func (c *CRTSh) queryJSON(domain string) ([]string, []string, error) {
 	params := url.Values{}
 	params.Set("q", fmt.Sprintf("%%.%s", domain))
 	params.Set("output", "json")
+	queryURL := fmt.Sprintf("%s?%s", c.baseURL, params.Encode())
+
+	var lastErr error
+	for attempt := 0; attempt < crtshMaxAttempts; attempt++ {
+		if attempt > 0 {
+			delay := crtshRetryDelay * time.Duration(1<<uint(attempt-1))
+			c.LogDebug("Retrying crt.sh in %v after: %v", delay, lastErr)
+			time.Sleep(delay)
+		}
 
-	// 发送 GET 请求
+		body, err := c.fetch(queryURL)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		records, err := parseCrtShJSON(body)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		var names, wildcards []string
+		for _, record := range records {
+			names = append(names, splitNameValue(record.NameValue)...)
+			for _, raw := range strings.Split(record.NameValue, "\n") {
+				if raw = strings.TrimSpace(raw); strings.Contains(raw, "*") {
+					wildcards = append(wildcards, raw)
+				}
+			}
+		}
+		return names, wildcards, nil
+	}
+
+	return nil, nil, lastErr
+}
+
+// queryHTML 查询 HTML 页面并从表格中提取域名
+func (c *CRTSh) queryHTML(domain string) ([]string, error) {
+	params := url.Values{}
+	params.Set("q", fmt.Sprintf("%%.%s", domain))
 	queryURL := fmt.Sprintf("%s?%s", c.baseURL, params.Encode())
+
+	body, err := c.fetch(queryURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// 证书名称在单元格中以 <BR> 分隔
+	text := strings.NewReplacer("<BR>", "\n", "<br>", "\n", "*.", "").Replace(body)
+	return c.ExtractSubdomains(text, domain), nil
+}
+
+// fetch 发送请求，非 200 响应返回错误
+func (c *CRTSh) fetch(queryURL string) (string, error) {
 	resp, err := c.HTTPGet(queryURL, c.GetHeader())
 	if err != nil {
-		return fmt.Errorf("failed to query CRTSh: %v", err)
+		return "", err
 	}
 
-	// 读取响应
 	body, err := c.ReadResponseBody(resp)
 	if err != nil {
-		return fmt.Errorf("failed to read response: %v", err)
+		return "", fmt.Errorf("failed to read response: %v", err)
 	}
-
-	// 处理响应文本
-	text := strings.ReplaceAll(body, "\\n", " ")
-
-	// 解析 JSON 数据
-	var jsonData []CRTShRecord
-	if err := json.Unmarshal([]byte(text), &jsonData); err != nil {
-		return fmt.Errorf("failed to parse JSON: %v", err)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("crt.sh returned status %d", resp.StatusCode)
 	}
+	return body, nil
+}
 
-	// 处理通配符域名
-	subDomains := make(map[string]bool)
-	for _, record := range jsonData {
-		nameValue := record.NameValue
-		if strings.Contains(nameValue, "*") {
-			// 读取 altdns 字典文件
-			altdnsWords, err := c.readAltDNSWords()
-			if err == nil {
-				for _, word := range altdnsWords {
-					result := strings.Replace(nameValue, "*", word, 1)
-					if strings.Contains(result, domain) {
-						subDomains[result] = true
-					}
-				}
-			}
+// parseCrtShJSON 解析 JSON 响应，crt.sh 出错时返回的 HTML 页面会被识别为错误而不是空结果
+func parseCrtShJSON(body string) ([]CRTShRecord, error) {
+	trimmed := strings.TrimSpace(body)
+	if !strings.HasPrefix(trimmed, "[") {
+		snippet := trimmed
+		if len(snippet) > 80 {
+			snippet = snippet[:80]
 		}
+		return nil, fmt.Errorf("non-JSON response: %q", snippet)
 	}
 
-	// 将处理后的通配符域名添加到文本中
-	for subdomain := range subDomains {
-		text += "," + subdomain + ","
+	var records []CRTShRecord
+	if err := json.Unmarshal([]byte(trimmed), &records); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %v", err)
 	}
+	return records, nil
+}
 
-	// 提取子域名
-	subdomains := c.ExtractSubdomains(text, domain)
-	for _, subdomain := range subdomains {
-		c.AddSubdomain(subdomain)
+// splitNameValue 拆分多行 name_value，通配符名称（*.example.com）还原为基础域名
+func splitNameValue(nameValue string) []string {
+	var names []string
+	for _, name := range strings.Split(nameValue, "\n") {
+		name = strings.TrimPrefix(core.NormalizeHost(name), "*.")
+		if name != "" && !strings.Contains(name, "*") {
+			names = append(names, name)
+		}
 	}
-
-	return nil
+	return names
 }
 
 // readAltDNSWords 读取 altdns 字典文件
 func (c *CRTSh) readAltDNSWords() ([]string, error) {
-	// 尝试读取字典文件
-	dictPath := "data/altdns_wordlist.txt"
-	content, err := os.ReadFile(dictPath)
+	// 尝试读取数据目录下的字典文件
+	content, err := c.GetConfig().ReadDataFile("altdns_wordlist.txt")
 	if err != nil {
 		return nil, err
 	}
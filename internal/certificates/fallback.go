@@ -0,0 +1,144 @@
+package certificates
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// crt.sh 不可用时备用 CT 数据源的来源标记，结果的来源字段据此区分实际返回数据的数据源
+const (
+	CertSpotterSource = "certspotter"
+	BufferOverSource  = "bufferover"
+)
+
+// BufferOverResponse tls.bufferover.run 响应结构，Results 每项形如 "IP,证书指纹,,名称1,名称2"
+type BufferOverResponse struct {
+	Meta struct {
+		Errors []string `json:"Errors"`
+	} `json:"Meta"`
+	Results []string `json:"Results"`
+}
+
+// ctFallback crt.sh 不可用时依次查询的备用 CT 数据源
+type ctFallback struct {
+	name  string
+	query func(domain string) ([]string, error)
+}
+
+// fallbacks 返回备用 CT 数据源：SSLMate 的 CertSpotter（免 API 密钥）和 tls.bufferover.run（需要 bufferover_api_key）
+func (c *CRTSh) fallbacks() []ctFallback {
+	return []ctFallback{
+		{name: CertSpotterSource, query: c.queryCertSpotter},
+		{name: BufferOverSource, query: c.queryBufferOver},
+	}
+}
+
+// queryFallbacks crt.sh 失败后查询全部备用数据源并合并结果，只要有一个数据源成功就不返回错误
+func (c *CRTSh) queryFallbacks(domain string, crtshErr error) error {
+	errs := []string{fmt.Sprintf("crt.sh: %v", crtshErr)}
+	var succeeded []string
+	for _, fallback := range c.fallbacks() {
+		names, err := fallback.query(domain)
+		if err != nil {
+			c.LogInfo("Fallback CT source %s failed: %v", fallback.name, err)
+			errs = append(errs, fmt.Sprintf("%s: %v", fallback.name, err))
+			continue
+		}
+
+		for _, name := range names {
+			name = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(name)), "*.")
+			if c.IsValidSubdomain(name, domain) {
+				c.AddTaggedSubdomain(name, fallback.name)
+			}
+		}
+		succeeded = append(succeeded, fallback.name)
+	}
+
+	if len(succeeded) == 0 {
+		return fmt.Errorf("all CT sources failed: %s", strings.Join(errs, "; "))
+	}
+	c.sources = succeeded
+	c.LogInfo("crt.sh unavailable, CT data returned by %s", strings.Join(succeeded, ", "))
+	return nil
+}
+
+// queryCertSpotter 查询 CertSpotter 签发记录中的 DNS 名称
+func (c *CRTSh) queryCertSpotter(domain string) ([]string, error) {
+	params := url.Values{}
+	params.Set("domain", domain)
+	params.Set("include_subdomains", "true")
+	params.Set("expand", "dns_names")
+
+	body, err := c.fetchFallback(fmt.Sprintf("%s?%s", c.certSpotterURL, params.Encode()), c.GetHeader())
+	if err != nil {
+		return nil, err
+	}
+
+	var response CertSpotterResponse
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response: %v", err)
+	}
+
+	var names []string
+	for _, cert := range response {
+		names = append(names, cert.DNSNames...)
+	}
+	return names, nil
+}
+
+// queryBufferOver 查询 tls.bufferover.run 的证书名称，未配置 API 密钥时跳过
+func (c *CRTSh) queryBufferOver(domain string) ([]string, error) {
+	apiKey := c.GetAPIKey("bufferover_api_key")
+	if apiKey == "" {
+		return nil, fmt.Errorf("bufferover_api_key not configured")
+	}
+
+	params := url.Values{}
+	params.Set("q", "."+domain)
+	headers := c.GetHeader()
+	headers["x-api-key"] = apiKey
+
+	body, err := c.fetchFallback(fmt.Sprintf("%s?%s", c.bufferOverURL, params.Encode()), headers)
+	if err != nil {
+		return nil, err
+	}
+
+	var response BufferOverResponse
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response: %v", err)
+	}
+	if len(response.Meta.Errors) > 0 {
+		return nil, fmt.Errorf("bufferover returned errors: %s", strings.Join(response.Meta.Errors, "; "))
+	}
+
+	var names []string
+	for _, result := range response.Results {
+		names = append(names, strings.Split(result, ",")...)
+	}
+	return names, nil
+}
+
+// fetchFallback 请求备用数据源，非 200 响应返回错误
+func (c *CRTSh) fetchFallback(queryURL string, headers map[string]string) (string, error) {
+	resp, err := c.HTTPGet(queryURL, headers)
+	if err != nil {
+		return "", err
+	}
+
+	body, err := c.ReadResponseBody(resp)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("returned status %d", resp.StatusCode)
+	}
+	return body, nil
+}
+
+// Sources 返回最近一次查询实际返回数据的 CT 数据源，crt.sh 可用时为 [crt.sh]
+func (c *CRTSh) Sources() []string {
+	return append([]string(nil), c.sources...)
+}
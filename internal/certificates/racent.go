@@ -24,6 +24,11 @@ func NewRacent(cfg *config.Config) *Racent {
 	}
 }
 
+// RequiresAPIKey 未配置 racent_api_token 时不发送请求
+func (r *Racent) RequiresAPIKey() bool {
+	return true
+}
+
 // Run 执行查询
 func (r *Racent) Run(domain string) ([]string, error) {
 	r.SetDomain(domain)
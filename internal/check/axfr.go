@@ -2,22 +2,41 @@ package check
 
 import (
 	"fmt"
+	"net"
 	"strings"
+	"time"
 
 	"github.com/miekg/dns"
 	"github.com/oneforall-go/internal/config"
 	"github.com/oneforall-go/internal/core"
+	dnsutil "github.com/oneforall-go/internal/dns"
 )
 
 // AXFR AXFR 检查模块
 type AXFR struct {
 	*core.Check
+	resolvers []string
+	port      string
+	timeout   time.Duration
 }
 
 // NewAXFR 创建 AXFR 模块
 func NewAXFR(cfg *config.Config) *AXFR {
+	timeout := time.Duration(cfg.DNSResolveTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	resolvers := []string{"8.8.8.8:53", "1.1.1.1:53", "223.5.5.5:53"}
+	if len(cfg.Resolvers) > 0 {
+		resolvers = cfg.Resolvers
+	}
+
 	return &AXFR{
-		Check: core.NewCheck("AXFRCheck", cfg),
+		Check:     core.NewCheck("AXFRCheck", cfg),
+		resolvers: resolvers,
+		port:      "53",
+		timeout:   timeout,
 	}
 }
 
@@ -33,14 +52,16 @@ func (a *AXFR) Run(domain string) ([]string, error) {
 		return nil, fmt.Errorf("failed to get nameservers: %v", err)
 	}
 
-	// 对每个域名服务器执行域传送
+	// 对每个域名服务器执行域传送，大多数服务器会拒绝，失败时只记录调试日志
 	for _, nameserver := range nameservers {
 		subdomains, err := a.performZoneTransfer(domain, nameserver)
 		if err != nil {
-			a.LogError("Failed to perform zone transfer on %s: %v", nameserver, err)
+			a.LogDebug("Zone transfer refused by %s: %v", nameserver, err)
 			continue
 		}
 
+		a.LogInfo("Zone transfer succeeded on %s, found %d subdomains", nameserver, len(subdomains))
+
 		// 添加子域名
 		for _, subdomain := range subdomains {
 			a.AddSubdomain(subdomain)
@@ -52,26 +73,23 @@ func (a *AXFR) Run(domain string) ([]string, error) {
 
 // getNameservers 获取域名服务器
 func (a *AXFR) getNameservers(domain string) ([]string, error) {
-	var nameservers []string
-
-	// 查询 NS 记录
+	// 查询 NS 记录，出错或返回 SERVFAIL/REFUSED 的服务器换下一个
 	msg := new(dns.Msg)
 	msg.SetQuestion(dns.Fqdn(domain), dns.TypeNS)
 	msg.RecursionDesired = true
 
-	resp, err := dns.Exchange(msg, "8.8.8.8:53")
+	resp, err := dnsutil.Exchange(msg, a.resolvers, a.timeout, a.GetConfig().DoTInsecureSkipVerify)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query NS records: %v", err)
 	}
 
 	// 提取域名服务器
+	var nameservers []string
 	for _, answer := range resp.Answer {
 		if ns, ok := answer.(*dns.NS); ok {
-			nameserver := strings.TrimSuffix(ns.Ns, ".")
-			nameservers = append(nameservers, nameserver)
+			nameservers = append(nameservers, strings.TrimSuffix(ns.Ns, "."))
 		}
 	}
-
 	return nameservers, nil
 }
 
@@ -80,26 +98,44 @@ func (a *AXFR) performZoneTransfer(domain, nameserver string) ([]string, error)
 	var subdomains []string
 
 	// 创建传输对象
-	transfer := new(dns.Transfer)
+	transfer := &dns.Transfer{
+		DialTimeout:  a.timeout,
+		ReadTimeout:  a.timeout,
+		WriteTimeout: a.timeout,
+	}
 	msg := new(dns.Msg)
 	msg.SetAxfr(dns.Fqdn(domain))
 
 	// 执行域传送
-	envelope, err := transfer.In(msg, nameserver+":53")
+	envelope, err := transfer.In(msg, net.JoinHostPort(nameserver, a.port))
 	if err != nil {
 		return nil, fmt.Errorf("failed to initiate zone transfer: %v", err)
 	}
 
-	// 处理响应
+	// 处理响应，传输中途出错时保留已收到的记录
+	var transferErr error
 	for env := range envelope {
 		if env.Error != nil {
+			transferErr = env.Error
 			continue
 		}
 
 		for _, rr := range env.RR {
-			switch rr.Header().Rrtype {
-			case dns.TypeA, dns.TypeCNAME, dns.TypeNS, dns.TypeMX, dns.TypeTXT:
-				name := strings.TrimSuffix(rr.Header().Name, ".")
+			// 所有记录类型的所有者名称都是区域内的主机名，指向其他主机的记录再加上目标名称
+			names := []string{rr.Header().Name}
+			switch record := rr.(type) {
+			case *dns.CNAME:
+				names = append(names, record.Target)
+			case *dns.MX:
+				names = append(names, record.Mx)
+			case *dns.NS:
+				names = append(names, record.Ns)
+			case *dns.SRV:
+				names = append(names, record.Target)
+			}
+
+			for _, name := range names {
+				name = strings.ToLower(strings.TrimSuffix(name, "."))
 				if a.IsValidSubdomain(name, domain) {
 					subdomains = append(subdomains, name)
 				}
@@ -107,5 +143,12 @@ func (a *AXFR) performZoneTransfer(domain, nameserver string) ([]string, error)
 		}
 	}
 
-	return subdomains, nil
+	if transferErr != nil {
+		if len(subdomains) == 0 {
+			return nil, transferErr
+		}
+		a.LogDebug("Zone transfer from %s interrupted after %d names: %v", nameserver, len(subdomains), transferErr)
+	}
+
+	return a.Deduplicate(subdomains), nil
 }
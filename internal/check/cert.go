@@ -57,6 +57,9 @@ func (c *Cert) check(domain string) error {
 	for _, name := range cert.DNSNames {
 		if c.IsValidSubdomain(name, domain) {
 			c.AddSubdomain(name)
+		} else if !core.InScope(name, domain) {
+			// 同一证书中的其他主域名通常属于同一组织
+			c.AddRelatedDomain(name)
 		}
 	}
 
@@ -0,0 +1,163 @@
+package check
+
+import (
+	"net"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/oneforall-go/internal/config"
+)
+
+// startZoneServer 启动允许域传送的本地 DNS 服务器（UDP 与 TCP 共用端口），
+// truncate 为 true 时发送记录后不发送结尾的 SOA 直接断开，模拟中途中断的传送
+func startZoneServer(t *testing.T, zone string, records []dns.RR, truncate bool) (string, func()) {
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen tcp: %v", err)
+	}
+	_, port, _ := net.SplitHostPort(tcp.Addr().String())
+	udp, err := net.ListenPacket("udp", "127.0.0.1:"+port)
+	if err != nil {
+		tcp.Close()
+		t.Fatalf("listen udp: %v", err)
+	}
+
+	soa, _ := dns.NewRR(zone + " 60 IN SOA ns1." + zone + " admin." + zone + " 1 60 60 60 60")
+
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		q := req.Question[0]
+		if q.Qtype == dns.TypeAXFR {
+			ch := make(chan *dns.Envelope)
+			tr := new(dns.Transfer)
+			rrs := append([]dns.RR{soa}, records...)
+			if !truncate {
+				rrs = append(rrs, soa)
+			}
+			go func() {
+				ch <- &dns.Envelope{RR: rrs}
+				close(ch)
+			}()
+			tr.Out(w, req, ch)
+			if truncate {
+				w.Close()
+				return
+			}
+			w.Hijack()
+			return
+		}
+
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		if q.Qtype == dns.TypeNS {
+			resp.Answer = append(resp.Answer, &dns.NS{
+				Hdr: dns.RR_Header{Name: q.Name, Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: 60},
+				Ns:  "127.0.0.1.",
+			})
+		}
+		w.WriteMsg(resp)
+	})
+
+	tcpServer := &dns.Server{Listener: tcp, Handler: handler}
+	udpServer := &dns.Server{PacketConn: udp, Handler: handler}
+	go tcpServer.ActivateAndServe()
+	go udpServer.ActivateAndServe()
+	time.Sleep(50 * time.Millisecond)
+
+	return port, func() {
+		tcpServer.Shutdown()
+		udpServer.Shutdown()
+	}
+}
+
+func TestAXFRTransfer(t *testing.T) {
+	mustRR := func(s string) dns.RR {
+		rr, err := dns.NewRR(s)
+		if err != nil {
+			t.Fatalf("NewRR: %v", err)
+		}
+		return rr
+	}
+
+	port, shutdown := startZoneServer(t, "example.com.", []dns.RR{
+		mustRR("www.example.com. 60 IN A 192.0.2.1"),
+		mustRR("cdn.example.com. 60 IN CNAME edge.example.com."),
+		mustRR("example.com. 60 IN MX 10 mail.example.com."),
+		mustRR("txt.example.com. 60 IN TXT \"v=spf1 -all\""),
+		mustRR("out.example.com. 60 IN CNAME other.net."),
+		mustRR("_sip._tcp.example.com. 60 IN SRV 10 5 5060 sip.example.com."),
+	}, false)
+	defer shutdown()
+
+	axfr := NewAXFR(&config.Config{DNSResolveTimeout: 2})
+	axfr.resolvers = []string{"127.0.0.1:" + port}
+	axfr.port = port
+
+	subdomains, err := axfr.Run("example.com")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	sort.Strings(subdomains)
+
+	expected := []string{"_sip._tcp.example.com", "cdn.example.com", "edge.example.com", "mail.example.com", "out.example.com",
+		"sip.example.com", "txt.example.com", "www.example.com"}
+	if len(subdomains) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, subdomains)
+	}
+	for i := range expected {
+		if subdomains[i] != expected[i] {
+			t.Errorf("Expected %s, got %s", expected[i], subdomains[i])
+		}
+	}
+}
+
+func TestAXFRKeepsPartialTransfer(t *testing.T) {
+	rr, err := dns.NewRR("www.example.com. 60 IN A 192.0.2.1")
+	if err != nil {
+		t.Fatalf("NewRR: %v", err)
+	}
+	port, shutdown := startZoneServer(t, "example.com.", []dns.RR{rr}, true)
+	defer shutdown()
+
+	axfr := NewAXFR(&config.Config{DNSResolveTimeout: 2})
+	axfr.resolvers = []string{"127.0.0.1:" + port}
+	axfr.port = port
+
+	subdomains, err := axfr.Run("example.com")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(subdomains) != 1 || subdomains[0] != "www.example.com" {
+		t.Errorf("Expected records received before the interruption, got %v", subdomains)
+	}
+}
+
+func TestAXFRNameserversSkipFailingResolver(t *testing.T) {
+	// 第一个服务器返回 REFUSED，应换下一个服务器
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen udp: %v", err)
+	}
+	refused := &dns.Server{PacketConn: pc, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetRcode(req, dns.RcodeRefused)
+		w.WriteMsg(resp)
+	})}
+	go refused.ActivateAndServe()
+	defer refused.Shutdown()
+
+	port, shutdown := startZoneServer(t, "example.com.", nil, false)
+	defer shutdown()
+
+	axfr := NewAXFR(&config.Config{DNSResolveTimeout: 2})
+	axfr.resolvers = []string{pc.LocalAddr().String(), "127.0.0.1:" + port}
+
+	nameservers, err := axfr.getNameservers("example.com")
+	if err != nil {
+		t.Fatalf("getNameservers failed: %v", err)
+	}
+	if len(nameservers) != 1 || nameservers[0] != "127.0.0.1" {
+		t.Errorf("Expected the nameserver from the working resolver, got %v", nameservers)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/miekg/dns"
 	"github.com/oneforall-go/internal/config"
 	"github.com/oneforall-go/internal/core"
+	dnsutil "github.com/oneforall-go/internal/dns"
 )
 
 // NSEC NSEC 检查模块
@@ -87,16 +88,13 @@ func (n *NSEC) walk(domain string) error {
 
 // dnsQuery 执行 DNS 查询
 func (n *NSEC) dnsQuery(domain, recordType string) ([]dns.RR, error) {
-	// 创建 DNS 客户端
-	client := new(dns.Client)
-
 	// 创建查询消息
 	msg := new(dns.Msg)
 	msg.SetQuestion(dns.Fqdn(domain), dns.TypeNSEC)
 	msg.RecursionDesired = true
 
-	// 使用默认 DNS 服务器
-	resp, _, err := client.Exchange(msg, "8.8.8.8:53")
+	// 使用配置的 DNS 服务器
+	resp, err := dnsutil.ExchangeWithConfig(msg, n.GetConfig())
 	if err != nil {
 		return nil, err
 	}
@@ -48,7 +48,7 @@ func NewCollector(domain string) *Collector {
 	// 创建模块管理器
 	moduleManager := modules.NewManager(cfg)
 
-	return &Collector{
+	collector := &Collector{
 		domain:        domain,
 		dnsClient:     dns.NewClient(cfg.DNSResolveTimeout, cfg.DNSResolveConcurrency),
 		reflectClient: dns.NewReflectClient(cfg.DNSResolveTimeout, cfg.DNSResolveConcurrency),
@@ -60,6 +60,31 @@ func NewCollector(domain string) *Collector {
 		moduleManager: moduleManager,
 		results:       make([]Subdomain, 0),
 	}
+
+	// 使用自定义 DNS 服务器
+	if len(cfg.Resolvers) > 0 {
+		collector.dnsClient.SetResolvers(cfg.Resolvers)
+		collector.reflectClient.SetResolvers(cfg.Resolvers)
+		collector.bruteClient.SetResolvers(cfg.Resolvers)
+	}
+	collector.reflectClient.SetConfig(cfg)
+	collector.dnsClient.SetIPVersion(cfg.IPVersion)
+	collector.bruteClient.SetIPVersion(cfg.IPVersion)
+	if cfg.DoTInsecureSkipVerify {
+		collector.dnsClient.SetInsecureTLS(true)
+		collector.reflectClient.SetInsecureTLS(true)
+		collector.bruteClient.SetInsecureTLS(true)
+	}
+
+	// EDNS Client Subnet
+	if subnet, err := dns.ParseClientSubnet(cfg.EDNSClientSubnet); err != nil {
+		logger.Warnf("Ignoring EDNS client subnet: %v", err)
+	} else if subnet != nil {
+		collector.dnsClient.SetClientSubnet(subnet)
+		collector.bruteClient.SetClientSubnet(subnet)
+	}
+
+	return collector
 }
 
 // Collect 执行子域收集
@@ -1,11 +1,16 @@
 package config
 
 import (
+	"crypto/tls"
+	"fmt"
+	"net"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/oneforall-go/pkg/logger"
 	"github.com/spf13/viper"
 )
 
@@ -15,6 +20,7 @@ type Config struct {
 	DebugEnabled   bool   `mapstructure:"debug_enabled"`
 	LogLevel       string `mapstructure:"log_level"`
 	LogFile        string `mapstructure:"log_file"`
+	LogFormat      string `mapstructure:"log_format"`
 	VerboseLogging bool   `mapstructure:"verbose_logging"`
 
 	// 模块开关
@@ -31,31 +37,92 @@ type Config struct {
 	EnableCheckModules  bool `mapstructure:"enable_check_modules"`
 	EnableCrawlModules  bool `mapstructure:"enable_crawl_modules"`
 	EnableEnrichModules bool `mapstructure:"enable_enrich_modules"`
+	// 单独禁用的模块名称（Module.Name()，不区分大小写），所在步骤仍正常执行
+	DisabledModules []string `mapstructure:"disabled_modules"`
 
-	// 搜索配置
+	// 搜索配置，开启递归搜索时搜索引擎模块再以已发现的子域名为目标搜索下一层，最多 SearchRecursiveTimes 层
 	EnableRecursiveSearch bool `mapstructure:"enable_recursive_search"`
 	SearchRecursiveTimes  int  `mapstructure:"search_recursive_times"`
 	EnableFullSearch      bool `mapstructure:"enable_full_search"`
 
+	// CommonCrawl 查询的最近索引数（索引按月轮换）
+	CommonCrawlIndexes int `mapstructure:"commoncrawl_indexes"`
+
 	// 结果配置
-	ResultSaveFormat  string `mapstructure:"result_save_format"`
-	ResultSavePath    string `mapstructure:"result_save_path"`
-	ResultExportAlive bool   `mapstructure:"result_export_alive"`
+	ResultSaveFormat string `mapstructure:"result_save_format"`
+	ResultSavePath   string `mapstructure:"result_save_path"`
+	// 结果文件名模板（相对于 ResultSavePath），支持 {domain}、{date}、{time}、{format}/{ext}
+	OutputTemplate string `mapstructure:"output_template"`
+	// CSV 结果的列及顺序（列名见 CSVColumns），为空时输出全部默认列
+	CSVColumns []string `mapstructure:"csv_columns"`
+	// 每个域名的结果写入 ResultSavePath/<domain>/ 目录，并在 ResultSavePath/index.json 中维护索引
+	PerDomainOutput bool `mapstructure:"per_domain_output"`
+	// JSON 导出使用带版本的信封 {version, domain, generated_at, stats, results}，默认输出结果数组
+	JSONEnvelope bool `mapstructure:"json_envelope"`
+	// txt 格式每行加 https:// 前缀
+	TxtWithScheme bool `mapstructure:"txt_with_scheme"`
+	// 导出结果时另外写入 <结果文件名>.stats.json，包含统计信息、目标域名和运行耗时
+	StatsFile bool `mapstructure:"stats_file"`
 	// 结果后处理阈值
 	ResultCheckLimit int `mapstructure:"result_check_limit"`
+	// 单个域名收集的最大子域名数，达到后停止运行剩余模块，0 表示不限制
+	MaxResults int `mapstructure:"max_results"`
+	// 整次运行的时间预算，超出后取消剩余工作并直接导出，0 表示不限制
+	MaxRuntime time.Duration `mapstructure:"max_runtime"`
+	// 模块每次 HTTP 请求前在 [RequestDelayMin, RequestDelayMax] 内随机等待，都为 0 时不等待
+	RequestDelayMin time.Duration `mapstructure:"request_delay_min"`
+	RequestDelayMax time.Duration `mapstructure:"request_delay_max"`
 
 	// HTTP配置
 	HTTPRequestPort string `mapstructure:"http_request_port"`
+	// 模块读取 HTTP 响应体的最大字节数（解压后），超出时返回错误
+	MaxResponseBody int64 `mapstructure:"max_response_body"`
+	// 自定义 User-Agent，设置后不再使用内置的随机 User-Agent
+	UserAgent string `mapstructure:"user_agent"`
+	// 附加到所有模块请求的请求头（如代理认证），模块自身设置的同名请求头优先
+	ExtraHeaders map[string]string `mapstructure:"extra_headers"`
+	// 共享连接池中每个主机保留的空闲连接数，所有模块复用同一连接池
+	HTTPMaxIdleConnsPerHost int `mapstructure:"http_max_idle_conns_per_host"`
+	// 不校验 HTTPS 证书的模块名称（Module.Name()，不区分大小写）；未列出的模块中只有直接访问目标的检查模块不校验证书
+	InsecureModules []string `mapstructure:"insecure_modules"`
 
 	// DNS配置
-	DNSResolveTimeout     int `mapstructure:"dns_resolve_timeout"`
-	DNSResolveConcurrency int `mapstructure:"dns_resolve_concurrency"`
+	DNSResolveTimeout     int      `mapstructure:"dns_resolve_timeout"`
+	DNSResolveConcurrency int      `mapstructure:"dns_resolve_concurrency"`
+	Resolvers             []string `mapstructure:"resolvers"` // 自定义DNS服务器（host:port），为空时使用内置服务器
+	// EDNS Client Subnet（如 203.0.113.0/24），查询时附加以获取该地区的 CDN 解析结果，为空时不附加
+	EDNSClientSubnet string `mapstructure:"edns_client_subnet"`
+	// 跳过 DNS over TLS（tls:// 前缀）服务器的证书校验，用于使用自签名证书的内网解析器
+	DoTInsecureSkipVerify bool `mapstructure:"dot_insecure_skip_verify"`
+	// 解析和验证使用的 IP 版本：4 只查询 A 记录，6 只查询 AAAA 记录，both 两者都查询
+	IPVersion string `mapstructure:"ip_version"`
+
+	// ASN/CIDR 目标展开的最大 IP 数
+	MaxCIDRHosts int `mapstructure:"max_cidr_hosts"`
+	// ASN/CIDR 目标反查 PTR 得到的注册域名中允许作为新目标继续枚举的域名，
+	// 其余注册域名（如 amazonaws.com 等第三方域名）下的主机名只作为结果输出，不做枚举
+	NetworkTargetDomains []string `mapstructure:"network_target_domains"`
+	// 反查丰富时最多对多少个非 CDN IP 查询 HackerTarget 反向 IP 数据集以发现同 IP 上的子域名，0 表示不查询
+	ReverseIPLimit int `mapstructure:"reverse_ip_limit"`
+	// 反查丰富时最多对多少个非 CDN IP 所在的 /24 网段逐个查询 256 个地址的 PTR 记录，0 表示不扫描
+	PTRSweepLimit int `mapstructure:"ptr_sweep_limit"`
+	// /24 网段 PTR 扫描每秒最多发出的查询数，0 表示不限速
+	PTRSweepRate int `mapstructure:"ptr_sweep_rate"`
 
 	// 爆破配置
 	BruteConcurrency   int    `mapstructure:"brute_concurrency"`
 	BruteTimeout       int    `mapstructure:"brute_timeout"`
 	BruteDictionaryURL string `mapstructure:"brute_dictionary_url"`
 	BruteDNSServerURL  string `mapstructure:"brute_dns_server_url"`
+	// 爆破字典文件列表，合并去重后生成候选，为空时使用 data/subnames.txt
+	BruteWordlists []string `mapstructure:"brute_wordlists"`
+	// 爆破并发数按解析器错误率（超时、SERVFAIL 等）在 [min, max] 内自动调整，错误率超过目标（百分比）时减半
+	BruteMinConcurrency  int     `mapstructure:"brute_min_concurrency"`
+	BruteMaxConcurrency  int     `mapstructure:"brute_max_concurrency"`
+	BruteTargetErrorRate float64 `mapstructure:"brute_target_error_rate"`
+	// 去重的候选数超过该值时使用布隆过滤器代替 map（0 表示始终使用 map），误判率为 DedupFalsePositiveRate
+	DedupBloomThreshold    int     `mapstructure:"dedup_bloom_threshold"`
+	DedupFalsePositiveRate float64 `mapstructure:"dedup_false_positive_rate"`
 
 	// 域名验证配置
 	EnableDomainValidation bool  `mapstructure:"enable_domain_validation"`
@@ -63,8 +130,29 @@ type Config struct {
 	ValidationTimeout      int   `mapstructure:"validation_timeout"`
 	ExcludePrivateIP       bool  `mapstructure:"exclude_private_ip"`
 	ExportAliveOnly        bool  `mapstructure:"export_alive_only"`
+	ExportDeadOnly         bool  `mapstructure:"export_dead_only"` // 只导出未存活的域名及失败原因，优先于 ExportAliveOnly
 	EnableTCPValidation    bool  `mapstructure:"enable_tcp_validation"`
 	TCPValidationPorts     []int `mapstructure:"tcp_validation_ports"`
+	ValidationUseICMP      bool  `mapstructure:"validation_use_icmp"`
+
+	// 只导出置信度（0-100）不低于该值的结果，0 表示不过滤
+	MinConfidence int `mapstructure:"min_confidence"`
+
+	// 验证时 DNS 阶段（CNAME 和 A 记录解析）与探测阶段（Ping、HTTP、多端口、证书）各自的并发数，0 表示使用 ValidationConcurrency
+	ValidationDNSConcurrency  int `mapstructure:"validation_dns_concurrency"`
+	ValidationHTTPConcurrency int `mapstructure:"validation_http_concurrency"`
+
+	// 验证存活域名时获取 HTTPS 证书，记录签发者和过期时间，并将证书中范围内的新名称再验证一轮
+	HarvestCertSANs bool `mapstructure:"harvest_cert_sans"`
+	// 验证时查询并记录完整的 CNAME 指向链，用于子域名接管检测和 CDN 识别
+	ResolveCNAME bool `mapstructure:"resolve_cname"`
+	// HTTP 探测时记录 Server、X-Powered-By 响应头，并按 data/web_fingerprints.json 识别 Web 技术（需开启 HTTP 请求）
+	Fingerprint bool `mapstructure:"fingerprint"`
+
+	// HTTPS 探测的 TLS 设置：最低版本（1.0-1.3）、握手失败时是否降到 TLS 1.0 重试、是否在握手中发送子域名作为 SNI
+	TLSMinVersion string `mapstructure:"tls_min_version"`
+	TLSFallback   bool   `mapstructure:"tls_fallback"`
+	TLSSendSNI    bool   `mapstructure:"tls_send_sni"`
 
 	// 多线程配置
 	MultiThreading MultiThreadingConfig `mapstructure:"multi_threading"`
@@ -76,9 +164,33 @@ type Config struct {
 	WildcardTestCount             int     `mapstructure:"wildcard_test_count"`
 	WildcardSuccessRateThreshold  float64 `mapstructure:"wildcard_success_rate_threshold"`
 	WildcardIPRepeatRateThreshold float64 `mapstructure:"wildcard_ip_repeat_rate_threshold"`
+	WildcardCacheFile             string  `mapstructure:"wildcard_cache_file"`
+	WildcardCacheTTL              int     `mapstructure:"wildcard_cache_ttl"`
+
+	// Webhook配置
+	WebhookURL    string `mapstructure:"webhook_url"`
+	WebhookSecret string `mapstructure:"webhook_secret"`
+	// 指定基线时只发送新出现的子域名（webhook.Delta）而不是统计摘要，没有新增时不发送
+	WebhookDeltaOnly bool `mapstructure:"webhook_delta_only"`
+	// 增量通知没有新增时也发送 event 为 heartbeat 的通知，用于确认监控仍在运行
+	WebhookHeartbeat bool `mapstructure:"webhook_heartbeat"`
+
+	// Elasticsearch输出配置
+	ESURL   string `mapstructure:"es_url"`
+	ESIndex string `mapstructure:"es_index"`
+
+	// 运行模式
+	DryRun bool `mapstructure:"dry_run"` // 只生成候选列表并列出将运行的模块，不发送网络请求
+	Deep   bool `mapstructure:"deep"`    // 启用额外消耗 API 额度的深度查询（如 SecurityTrails 历史记录和关联域名）
+	// 枚举开始前检查网络连接和自定义 DNS 服务器，不可用时直接终止
+	Preflight bool `mapstructure:"preflight"`
+	// 被动模式：只运行不向目标发送流量的模块（搜索、数据集、证书透明度、情报），跳过验证
+	Passive bool `mapstructure:"passive"`
 
 	// 其他配置
 	CommonSubnames string `mapstructure:"common_subnames"`
+	// 数据文件目录（字典、DNS 服务器列表、CDN 列表等），相对路径基于当前工作目录，缺少的文件回退到内置默认数据
+	DataDir string `mapstructure:"data_dir"`
 }
 
 // MultiThreadingConfig 多线程配置
@@ -125,6 +237,10 @@ var config *Config
 func GetConfig() *Config {
 	if config == nil {
 		config = loadConfig()
+		// 配置错误不阻断加载，由启动流程和 API 在使用前校验
+		if err := config.Validate(); err != nil {
+			logger.Warnf("%v", err)
+		}
 	}
 	return config
 }
@@ -159,6 +275,7 @@ func setDefaults(cfg *Config) {
 	cfg.DebugEnabled = false
 	cfg.LogLevel = "info"
 	cfg.LogFile = "logs/oneforall.log"
+	cfg.LogFormat = "text"
 	cfg.VerboseLogging = false
 
 	// 模块开关
@@ -180,34 +297,64 @@ func setDefaults(cfg *Config) {
 	cfg.EnableRecursiveSearch = false
 	cfg.SearchRecursiveTimes = 1
 	cfg.EnableFullSearch = true
+	cfg.CommonCrawlIndexes = 3
 
 	// 结果配置
 	cfg.ResultSaveFormat = "csv"
 	cfg.ResultSavePath = "results"
-	cfg.ResultExportAlive = true
+	cfg.OutputTemplate = DefaultOutputTemplate
+	cfg.JSONEnvelope = false
+	cfg.TxtWithScheme = false
+	cfg.StatsFile = false
 	cfg.ResultCheckLimit = 30
+	cfg.MaxResults = 0
+	cfg.RequestDelayMin = time.Second
+	cfg.RequestDelayMax = 3 * time.Second
+	cfg.Preflight = true
 
 	// HTTP配置
 	cfg.HTTPRequestPort = "80,443"
+	cfg.MaxResponseBody = 10 << 20
+	cfg.HTTPMaxIdleConnsPerHost = 16
 
 	// DNS配置
 	cfg.DNSResolveTimeout = 10
 	cfg.DNSResolveConcurrency = 100
+	cfg.DoTInsecureSkipVerify = false
+	cfg.IPVersion = IPVersion4
+	cfg.MaxCIDRHosts = 65536
+	cfg.ReverseIPLimit = 10
+	cfg.PTRSweepLimit = 0
+	cfg.PTRSweepRate = 100
 
 	// 爆破配置
 	cfg.BruteConcurrency = 2000
 	cfg.BruteTimeout = 300
 	cfg.BruteDictionaryURL = "" // 默认使用本地字典
 	cfg.BruteDNSServerURL = ""  // 默认使用本地DNS服务器
+	cfg.BruteMinConcurrency = 50
+	cfg.BruteMaxConcurrency = 5000
+	cfg.BruteTargetErrorRate = 5.0
+	cfg.DedupBloomThreshold = 1000000
+	cfg.DedupFalsePositiveRate = 0.0001
 
 	// 域名验证配置
 	cfg.EnableDomainValidation = true
 	cfg.ValidationConcurrency = 50
+	cfg.ValidationDNSConcurrency = 0
+	cfg.ValidationHTTPConcurrency = 0
 	cfg.ValidationTimeout = 30
 	cfg.ExcludePrivateIP = true
 	cfg.ExportAliveOnly = true
+	cfg.ExportDeadOnly = false
 	cfg.EnableTCPValidation = true
 	cfg.TCPValidationPorts = []int{80, 443, 8080, 8443}
+	cfg.ValidationUseICMP = false
+	cfg.HarvestCertSANs = true
+	cfg.ResolveCNAME = true
+	cfg.TLSMinVersion = "1.2"
+	cfg.TLSFallback = true
+	cfg.TLSSendSNI = true
 
 	// 多线程配置
 	cfg.MultiThreading = MultiThreadingConfig{
@@ -254,9 +401,14 @@ func setDefaults(cfg *Config) {
 	cfg.WildcardTestCount = 20
 	cfg.WildcardSuccessRateThreshold = 90.0
 	cfg.WildcardIPRepeatRateThreshold = 50.0
+	cfg.WildcardCacheTTL = 86400
+
+	// Elasticsearch输出配置
+	cfg.ESIndex = "oneforall"
 
 	// 其他配置
 	cfg.CommonSubnames = "www,mail,ftp,admin,blog,api,dev,stage,prod,app,web,cdn,static,img,css,js,docs,help,support"
+	cfg.DataDir = DefaultDataDir
 }
 
 // loadFromEnv 从环境变量加载配置
@@ -271,6 +423,9 @@ func loadFromEnv(cfg *Config) {
 	if val := getEnvString("LOG_FILE"); val != "" {
 		cfg.LogFile = val
 	}
+	if val := getEnvString("LOG_FORMAT"); val != "" {
+		cfg.LogFormat = val
+	}
 	if val := getEnvBool("VERBOSE_LOGGING"); val != nil {
 		cfg.VerboseLogging = *val
 	}
@@ -315,6 +470,9 @@ func loadFromEnv(cfg *Config) {
 	if val := getEnvBool("ENABLE_ENRICH_MODULES"); val != nil {
 		cfg.EnableEnrichModules = *val
 	}
+	if val := getEnvString("DISABLED_MODULES"); val != "" {
+		cfg.DisabledModules = parseList(val)
+	}
 
 	// 搜索配置
 	if val := getEnvBool("ENABLE_RECURSIVE_SEARCH"); val != nil {
@@ -326,6 +484,9 @@ func loadFromEnv(cfg *Config) {
 	if val := getEnvBool("ENABLE_FULL_SEARCH"); val != nil {
 		cfg.EnableFullSearch = *val
 	}
+	if val := getEnvInt("COMMONCRAWL_INDEXES"); val != nil {
+		cfg.CommonCrawlIndexes = *val
+	}
 
 	// 结果配置
 	if val := getEnvString("RESULT_SAVE_FORMAT"); val != "" {
@@ -334,17 +495,72 @@ func loadFromEnv(cfg *Config) {
 	if val := getEnvString("RESULT_SAVE_PATH"); val != "" {
 		cfg.ResultSavePath = val
 	}
+	if val := getEnvString("OUTPUT_TEMPLATE"); val != "" {
+		cfg.OutputTemplate = val
+	}
+	if val := getEnvString("CSV_COLUMNS"); val != "" {
+		cfg.CSVColumns = parseList(val)
+	}
+	if val := getEnvBool("PER_DOMAIN_OUTPUT"); val != nil {
+		cfg.PerDomainOutput = *val
+	}
+	if val := getEnvBool("JSON_ENVELOPE"); val != nil {
+		cfg.JSONEnvelope = *val
+	}
+	if val := getEnvBool("TXT_WITH_SCHEME"); val != nil {
+		cfg.TxtWithScheme = *val
+	}
+	if val := getEnvBool("STATS_FILE"); val != nil {
+		cfg.StatsFile = *val
+	}
+	// RESULT_EXPORT_ALIVE 为旧名称，EXPORT_ALIVE_ONLY 优先
 	if val := getEnvBool("RESULT_EXPORT_ALIVE"); val != nil {
-		cfg.ResultExportAlive = *val
+		cfg.ExportAliveOnly = *val
 	}
 	if val := getEnvInt("RESULT_CHECK_LIMIT"); val != nil {
 		cfg.ResultCheckLimit = *val
 	}
+	if val := getEnvInt("MAX_RESULTS"); val != nil {
+		cfg.MaxResults = *val
+	}
+	if val := getEnvDuration("MAX_RUNTIME"); val != nil {
+		cfg.MaxRuntime = *val
+	}
+	if val := getEnvDuration("REQUEST_DELAY_MIN"); val != nil {
+		cfg.RequestDelayMin = *val
+	}
+	if val := getEnvDuration("REQUEST_DELAY_MAX"); val != nil {
+		cfg.RequestDelayMax = *val
+	}
+	if val := getEnvBool("DEEP"); val != nil {
+		cfg.Deep = *val
+	}
+	if val := getEnvBool("PREFLIGHT"); val != nil {
+		cfg.Preflight = *val
+	}
+	if val := getEnvBool("PASSIVE"); val != nil {
+		cfg.Passive = *val
+	}
 
 	// HTTP配置
 	if val := getEnvString("HTTP_REQUEST_PORT"); val != "" {
 		cfg.HTTPRequestPort = val
 	}
+	if val := getEnvInt("MAX_RESPONSE_BODY"); val != nil {
+		cfg.MaxResponseBody = int64(*val)
+	}
+	if val := getEnvString("USER_AGENT"); val != "" {
+		cfg.UserAgent = val
+	}
+	if val := getEnvString("EXTRA_HEADERS"); val != "" {
+		cfg.ExtraHeaders = parseHeaders(val)
+	}
+	if val := getEnvInt("HTTP_MAX_IDLE_CONNS_PER_HOST"); val != nil {
+		cfg.HTTPMaxIdleConnsPerHost = *val
+	}
+	if val := getEnvString("INSECURE_MODULES"); val != "" {
+		cfg.InsecureModules = parseList(val)
+	}
 
 	// DNS配置
 	if val := getEnvInt("DNS_RESOLVE_TIMEOUT"); val != nil {
@@ -353,6 +569,37 @@ func loadFromEnv(cfg *Config) {
 	if val := getEnvInt("DNS_RESOLVE_CONCURRENCY"); val != nil {
 		cfg.DNSResolveConcurrency = *val
 	}
+	if val := getEnvString("DNS_SERVERS"); val != "" {
+		if resolvers, err := ParseResolvers(val); err != nil {
+			logger.Warnf("Ignoring DNS_SERVERS: %v", err)
+		} else {
+			cfg.Resolvers = resolvers
+		}
+	}
+	if val := getEnvString("EDNS_CLIENT_SUBNET"); val != "" {
+		cfg.EDNSClientSubnet = val
+	}
+	if val := getEnvString("IP_VERSION"); val != "" {
+		cfg.IPVersion = val
+	}
+	if val := getEnvBool("DOT_INSECURE_SKIP_VERIFY"); val != nil {
+		cfg.DoTInsecureSkipVerify = *val
+	}
+	if val := getEnvInt("MAX_CIDR_HOSTS"); val != nil {
+		cfg.MaxCIDRHosts = *val
+	}
+	if val := getEnvString("NETWORK_TARGET_DOMAINS"); val != "" {
+		cfg.NetworkTargetDomains = parseList(val)
+	}
+	if val := getEnvInt("REVERSE_IP_LIMIT"); val != nil {
+		cfg.ReverseIPLimit = *val
+	}
+	if val := getEnvInt("PTR_SWEEP_LIMIT"); val != nil {
+		cfg.PTRSweepLimit = *val
+	}
+	if val := getEnvInt("PTR_SWEEP_RATE"); val != nil {
+		cfg.PTRSweepRate = *val
+	}
 
 	// 爆破配置
 	if val := getEnvInt("BRUTE_CONCURRENCY"); val != nil {
@@ -367,6 +614,24 @@ func loadFromEnv(cfg *Config) {
 	if val := getEnvString("BRUTE_DNS_SERVER_URL"); val != "" {
 		cfg.BruteDNSServerURL = val
 	}
+	if val := getEnvString("BRUTE_WORDLISTS"); val != "" {
+		cfg.BruteWordlists = parseList(val)
+	}
+	if val := getEnvInt("BRUTE_MIN_CONCURRENCY"); val != nil {
+		cfg.BruteMinConcurrency = *val
+	}
+	if val := getEnvInt("BRUTE_MAX_CONCURRENCY"); val != nil {
+		cfg.BruteMaxConcurrency = *val
+	}
+	if val := getEnvFloat("BRUTE_TARGET_ERROR_RATE"); val != nil {
+		cfg.BruteTargetErrorRate = *val
+	}
+	if val := getEnvInt("DEDUP_BLOOM_THRESHOLD"); val != nil {
+		cfg.DedupBloomThreshold = *val
+	}
+	if val := getEnvFloat("DEDUP_FALSE_POSITIVE_RATE"); val != nil {
+		cfg.DedupFalsePositiveRate = *val
+	}
 
 	// 域名验证配置
 	if val := getEnvBool("ENABLE_DOMAIN_VALIDATION"); val != nil {
@@ -375,6 +640,12 @@ func loadFromEnv(cfg *Config) {
 	if val := getEnvInt("VALIDATION_CONCURRENCY"); val != nil {
 		cfg.ValidationConcurrency = *val
 	}
+	if val := getEnvInt("VALIDATION_DNS_CONCURRENCY"); val != nil {
+		cfg.ValidationDNSConcurrency = *val
+	}
+	if val := getEnvInt("VALIDATION_HTTP_CONCURRENCY"); val != nil {
+		cfg.ValidationHTTPConcurrency = *val
+	}
 	if val := getEnvInt("VALIDATION_TIMEOUT"); val != nil {
 		cfg.ValidationTimeout = *val
 	}
@@ -384,12 +655,39 @@ func loadFromEnv(cfg *Config) {
 	if val := getEnvBool("EXPORT_ALIVE_ONLY"); val != nil {
 		cfg.ExportAliveOnly = *val
 	}
+	if val := getEnvBool("EXPORT_DEAD_ONLY"); val != nil {
+		cfg.ExportDeadOnly = *val
+	}
+	if val := getEnvInt("MIN_CONFIDENCE"); val != nil {
+		cfg.MinConfidence = *val
+	}
 	if val := getEnvBool("ENABLE_TCP_VALIDATION"); val != nil {
 		cfg.EnableTCPValidation = *val
 	}
 	if val := getEnvString("TCP_VALIDATION_PORTS"); val != "" {
 		cfg.TCPValidationPorts = parsePorts(val)
 	}
+	if val := getEnvBool("VALIDATION_USE_ICMP"); val != nil {
+		cfg.ValidationUseICMP = *val
+	}
+	if val := getEnvBool("HARVEST_CERT_SANS"); val != nil {
+		cfg.HarvestCertSANs = *val
+	}
+	if val := getEnvBool("RESOLVE_CNAME"); val != nil {
+		cfg.ResolveCNAME = *val
+	}
+	if val := getEnvBool("FINGERPRINT"); val != nil {
+		cfg.Fingerprint = *val
+	}
+	if val := getEnvString("TLS_MIN_VERSION"); val != "" {
+		cfg.TLSMinVersion = val
+	}
+	if val := getEnvBool("TLS_FALLBACK"); val != nil {
+		cfg.TLSFallback = *val
+	}
+	if val := getEnvBool("TLS_SEND_SNI"); val != nil {
+		cfg.TLSSendSNI = *val
+	}
 
 	// 多线程配置
 	if val := getEnvBool("ENABLE_STEP_EXECUTION"); val != nil {
@@ -487,9 +785,9 @@ func loadFromEnv(cfg *Config) {
 	apiKeys := []string{
 		"GITHUB_API_TOKEN", "SHODAN_API_KEY", "FOFA_API_EMAIL", "FOFA_API_KEY",
 		"HUNTER_API_KEY", "QUAKE_API_KEY", "ZOOMEYE_API_KEY", "VIRUSTOTAL_API_KEY",
-		"SECURITYTRAILS_API_KEY", "CENSYS_API_KEY", "BINARYEDGE_API_KEY",
+		"SECURITYTRAILS_API_KEY", "CENSYS_API_KEY", "CENSYS_API_ID", "CENSYS_API_SECRET", "BINARYEDGE_API_KEY",
 		"SPYSE_API_KEY", "RISKIQ_API_KEY", "THREATBOOK_API_KEY", "ANUBIS_API_KEY",
-		"BEVIGIL_API_KEY",
+		"BEVIGIL_API_KEY", "WHOISXML_API_KEY", "BUFFEROVER_API_KEY",
 	}
 
 	for _, key := range apiKeys {
@@ -508,22 +806,55 @@ func loadFromEnv(cfg *Config) {
 	if val := getEnvFloat("WILDCARD_IP_REPEAT_RATE_THRESHOLD"); val != nil {
 		cfg.WildcardIPRepeatRateThreshold = *val
 	}
+	if val := getEnvString("WILDCARD_CACHE_FILE"); val != "" {
+		cfg.WildcardCacheFile = val
+	}
+	if val := getEnvInt("WILDCARD_CACHE_TTL"); val != nil {
+		cfg.WildcardCacheTTL = *val
+	}
+
+	// Webhook配置
+	if val := getEnvString("WEBHOOK_URL"); val != "" {
+		cfg.WebhookURL = val
+	}
+	if val := getEnvString("WEBHOOK_SECRET"); val != "" {
+		cfg.WebhookSecret = val
+	}
+	if val := getEnvBool("WEBHOOK_DELTA_ONLY"); val != nil {
+		cfg.WebhookDeltaOnly = *val
+	}
+	if val := getEnvBool("WEBHOOK_HEARTBEAT"); val != nil {
+		cfg.WebhookHeartbeat = *val
+	}
+
+	// Elasticsearch输出配置
+	if val := getEnvString("ES_URL"); val != "" {
+		cfg.ESURL = val
+	}
+	if val := getEnvString("ES_INDEX"); val != "" {
+		cfg.ESIndex = val
+	}
 
 	// 其他配置
 	if val := getEnvString("COMMON_SUBNAMES"); val != "" {
 		cfg.CommonSubnames = val
 	}
+	if val := getEnvString("DATA_DIR"); val != "" {
+		cfg.DataDir = val
+	}
 }
 
 // loadFromYAML 从YAML文件加载配置（保留兼容性）
 func loadFromYAML(cfg *Config) {
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
-	viper.AddConfigPath("data/config")
+	viper.AddConfigPath(cfg.DataPath("config"))
 
 	if err := viper.ReadInConfig(); err == nil {
 		// 如果YAML文件存在，使用YAML配置覆盖环境变量
-		viper.Unmarshal(cfg)
+		viper.Unmarshal(cfg, viper.DecodeHook(decodeHook()))
+		applyDeprecatedKeys(viper.GetViper(), cfg)
+		normalizeConfigResolvers(cfg)
 	}
 }
 
@@ -568,6 +899,226 @@ func getEnvFloat(key string) *float64 {
 	return &f
 }
 
+func getEnvDuration(key string) *time.Duration {
+	val := os.Getenv(key)
+	if val == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		return nil
+	}
+	return &d
+}
+
+// ParseResolvers 解析DNS服务器列表，支持逗号分隔或 @file 形式，端口缺省为53（tls:// 前缀的 DoT 服务器缺省为853）
+func ParseResolvers(value string) ([]string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	var entries []string
+	if strings.HasPrefix(value, "@") {
+		data, err := os.ReadFile(strings.TrimPrefix(value, "@"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read DNS servers file: %v", err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line != "" && !strings.HasPrefix(line, "#") {
+				entries = append(entries, line)
+			}
+		}
+	} else {
+		entries = strings.Split(value, ",")
+	}
+
+	return normalizeResolvers(entries)
+}
+
+// normalizeResolvers 规范化DNS服务器列表，跳过空项，任一地址无效时返回错误
+func normalizeResolvers(entries []string) ([]string, error) {
+	var resolvers []string
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		resolver, err := NormalizeResolver(entry)
+		if err != nil {
+			return nil, err
+		}
+		resolvers = append(resolvers, resolver)
+	}
+
+	return resolvers, nil
+}
+
+// applyDeprecatedKeys 将配置文件中的旧配置项映射到新字段，新旧名称同时设置时以新名称为准
+func applyDeprecatedKeys(v *viper.Viper, cfg *Config) {
+	// result_export_alive 为 export_alive_only 的旧名称
+	if v.IsSet("result_export_alive") {
+		logger.Warnf("Config key result_export_alive is deprecated, use export_alive_only instead")
+		if !v.IsSet("export_alive_only") {
+			cfg.ExportAliveOnly = v.GetBool("result_export_alive")
+		}
+	}
+}
+
+// normalizeConfigResolvers 将配置文件中的DNS服务器规范化为 host:port 形式，无效地址保留原样由 Validate 报告
+func normalizeConfigResolvers(cfg *Config) {
+	if resolvers, err := normalizeResolvers(cfg.Resolvers); err == nil {
+		cfg.Resolvers = resolvers
+	}
+}
+
+// NormalizeResolver 校验DNS服务器地址并规范化为 host:port 形式，tls:// 前缀表示 DNS over TLS（规范化为 tls://host:port）
+func NormalizeResolver(entry string) (string, error) {
+	entry = strings.TrimSpace(entry)
+
+	// DNS over TLS，默认端口853
+	if len(entry) > len(dotScheme) && strings.EqualFold(entry[:len(dotScheme)], dotScheme) {
+		resolver, err := normalizeResolver(entry[len(dotScheme):], "853")
+		if err != nil {
+			return "", err
+		}
+		return dotScheme + resolver, nil
+	}
+
+	return normalizeResolver(entry, "53")
+}
+
+// IP 版本取值，见 Config.IPVersion
+const (
+	IPVersion4    = "4"
+	IPVersion6    = "6"
+	IPVersionBoth = "both"
+)
+
+// ParseIPVersion 校验 IP 版本（4、6、both，也接受 ipv4、ipv6），空值为 4
+func ParseIPVersion(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "4", "ipv4":
+		return IPVersion4, nil
+	case "6", "ipv6":
+		return IPVersion6, nil
+	case "both":
+		return IPVersionBoth, nil
+	}
+	return "", fmt.Errorf("invalid IP version %q: must be 4, 6 or both", value)
+}
+
+// TLS 版本名称与 crypto/tls 常量的对应关系
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ParseTLSVersion 解析 TLS 版本（1.0、1.1、1.2、1.3，也接受 tls1.2 形式），空值为 1.2
+func ParseTLSVersion(value string) (uint16, error) {
+	name := strings.ToLower(strings.TrimSpace(value))
+	if name == "" {
+		return tls.VersionTLS12, nil
+	}
+	if version, ok := tlsVersions[strings.TrimSpace(strings.TrimPrefix(name, "tls"))]; ok {
+		return version, nil
+	}
+	return 0, fmt.Errorf("invalid TLS version %q: must be 1.0, 1.1, 1.2 or 1.3", value)
+}
+
+// WantsIPv4 是否查询 A 记录，IP 版本无效时按 4 处理
+func (c *Config) WantsIPv4() bool {
+	version, err := ParseIPVersion(c.IPVersion)
+	return err != nil || version != IPVersion6
+}
+
+// WantsIPv6 是否查询 AAAA 记录
+func (c *Config) WantsIPv6() bool {
+	version, _ := ParseIPVersion(c.IPVersion)
+	return version == IPVersion6 || version == IPVersionBoth
+}
+
+// DiscoveryOnly 本次运行是否只做发现、不验证结果（关闭域名验证或被动模式）
+func (c *Config) DiscoveryOnly() bool {
+	return !c.EnableDomainValidation || c.Passive
+}
+
+// InsecureModule 模块是否列在 InsecureModules 中（按模块名称，不区分大小写）
+func (c *Config) InsecureModule(name string) bool {
+	for _, module := range c.InsecureModules {
+		if strings.EqualFold(strings.TrimSpace(module), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// NetworkTargetDomain 注册域名是否列在 NetworkTargetDomains 中（不区分大小写）
+func (c *Config) NetworkTargetDomain(domain string) bool {
+	for _, allowed := range c.NetworkTargetDomains {
+		if strings.EqualFold(strings.TrimSuffix(strings.TrimSpace(allowed), "."), domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// dotScheme DNS over TLS 服务器地址前缀
+const dotScheme = "tls://"
+
+// normalizeResolver 校验 host[:port] 形式的地址，端口缺省为 defaultPort
+func normalizeResolver(entry, defaultPort string) (string, error) {
+	// 纯IP（含IPv6）直接补全默认端口
+	if ip := net.ParseIP(strings.Trim(entry, "[]")); ip != nil {
+		return net.JoinHostPort(ip.String(), defaultPort), nil
+	}
+
+	host, port, err := net.SplitHostPort(entry)
+	if err != nil {
+		if strings.Contains(entry, ":") {
+			return "", fmt.Errorf("invalid DNS server: %s", entry)
+		}
+		// 没有端口的主机名
+		host, port = entry, defaultPort
+	}
+
+	if host == "" || (net.ParseIP(host) == nil && strings.ContainsAny(host, " /:")) {
+		return "", fmt.Errorf("invalid DNS server: %s", entry)
+	}
+	if p, err := strconv.Atoi(port); err != nil || p <= 0 || p > 65535 {
+		return "", fmt.Errorf("invalid DNS server port: %s", entry)
+	}
+
+	return net.JoinHostPort(host, port), nil
+}
+
+// parseList 解析逗号分隔的列表，忽略空项
+func parseList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseHeaders 解析分号分隔的 "Name: value" 列表，请求头的值中可能包含逗号
+func parseHeaders(value string) map[string]string {
+	headers := make(map[string]string)
+	for _, item := range strings.Split(value, ";") {
+		name, val, ok := strings.Cut(item, ":")
+		if name = strings.TrimSpace(name); !ok || name == "" {
+			continue
+		}
+		headers[name] = strings.TrimSpace(val)
+	}
+	return headers
+}
+
 func parsePorts(portsStr string) []int {
 	var ports []int
 	for _, portStr := range strings.Split(portsStr, ",") {
@@ -0,0 +1,253 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseResolvers(t *testing.T) {
+	resolvers, err := ParseResolvers("8.8.8.8, 10.0.0.1:5353,[2001:db8::1]:53,2001:db8::2,ns.internal")
+	if err != nil {
+		t.Fatalf("ParseResolvers failed: %v", err)
+	}
+
+	expected := []string{"8.8.8.8:53", "10.0.0.1:5353", "[2001:db8::1]:53", "[2001:db8::2]:53", "ns.internal:53"}
+	if len(resolvers) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, resolvers)
+	}
+	for i := range expected {
+		if resolvers[i] != expected[i] {
+			t.Errorf("Expected %s, got %s", expected[i], resolvers[i])
+		}
+	}
+
+	// DNS over TLS 缺省端口为853，保留 tls:// 前缀
+	dot, err := ParseResolvers("tls://1.1.1.1,TLS://dns.google:8853,tls://[2001:db8::1]")
+	if err != nil {
+		t.Fatalf("ParseResolvers failed for DoT: %v", err)
+	}
+	if strings.Join(dot, ",") != "tls://1.1.1.1:853,tls://dns.google:8853,tls://[2001:db8::1]:853" {
+		t.Errorf("Unexpected DoT resolvers: %v", dot)
+	}
+
+	for _, invalid := range []string{"1.2.3.4:0", "1.2.3.4:abc", "bad host", "a:b:c", "tls://", "tls://1.2.3.4:0"} {
+		if _, err := ParseResolvers(invalid); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+func TestParseResolversFromFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "resolvers.txt")
+	if err := os.WriteFile(file, []byte("# internal\n10.0.0.53\n\n10.0.0.54:53\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	resolvers, err := ParseResolvers("@" + file)
+	if err != nil {
+		t.Fatalf("ParseResolvers failed: %v", err)
+	}
+	if len(resolvers) != 2 || resolvers[0] != "10.0.0.53:53" || resolvers[1] != "10.0.0.54:53" {
+		t.Errorf("Unexpected resolvers: %v", resolvers)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	saved := config
+	t.Cleanup(func() { config = saved })
+
+	dir := t.TempDir()
+	yamlFile := filepath.Join(dir, "config.yaml")
+	content := "result_save_path: from-file\nvalidation_concurrency: 7\ncommon_subnames:\n  - www\n  - mail\nresolvers:\n  - 10.0.0.53\n  - tls://dns.example\n"
+	if err := os.WriteFile(yamlFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("RESULT_SAVE_PATH", "from-env")
+	cfg, err := LoadFile(yamlFile)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if cfg.ResultSavePath != "from-env" {
+		t.Errorf("Expected env to override file, got %s", cfg.ResultSavePath)
+	}
+	if cfg.ValidationConcurrency != 7 {
+		t.Errorf("Expected validation_concurrency 7 from file, got %d", cfg.ValidationConcurrency)
+	}
+	if cfg.CommonSubnames != "www,mail" {
+		t.Errorf("Expected common_subnames www,mail, got %q", cfg.CommonSubnames)
+	}
+	// 配置文件中的 DNS 服务器规范化为 host:port
+	if len(cfg.Resolvers) != 2 || cfg.Resolvers[0] != "10.0.0.53:53" || cfg.Resolvers[1] != "tls://dns.example:853" {
+		t.Errorf("Expected normalized resolvers, got %v", cfg.Resolvers)
+	}
+	if GetConfig() != cfg {
+		t.Error("Expected LoadFile to replace the global config")
+	}
+
+	jsonFile := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(jsonFile, []byte(`{"es_index": "subs"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = LoadFile(jsonFile)
+	if err != nil {
+		t.Fatalf("LoadFile json failed: %v", err)
+	}
+	if cfg.ESIndex != "subs" {
+		t.Errorf("Expected es_index subs, got %s", cfg.ESIndex)
+	}
+}
+
+func TestLoadFileDeprecatedExportAlive(t *testing.T) {
+	saved := config
+	t.Cleanup(func() { config = saved })
+
+	dir := t.TempDir()
+	legacy := filepath.Join(dir, "legacy.yaml")
+	if err := os.WriteFile(legacy, []byte("result_export_alive: false\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadFile(legacy)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if cfg.ExportAliveOnly {
+		t.Error("Expected result_export_alive: false to disable export_alive_only")
+	}
+
+	// 新旧名称同时设置时以 export_alive_only 为准
+	both := filepath.Join(dir, "both.yaml")
+	if err := os.WriteFile(both, []byte("result_export_alive: false\nexport_alive_only: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = LoadFile(both)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if !cfg.ExportAliveOnly {
+		t.Error("Expected export_alive_only to take precedence over result_export_alive")
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	saved := config
+	t.Cleanup(func() { config = saved })
+
+	dir := t.TempDir()
+	malformed := filepath.Join(dir, "bad.yaml")
+	if err := os.WriteFile(malformed, []byte("result_save_path: [unclosed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wrongType := filepath.Join(dir, "type.yaml")
+	if err := os.WriteFile(wrongType, []byte("validation_concurrency: many\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range []string{filepath.Join(dir, "missing.yaml"), malformed, wrongType} {
+		if _, err := LoadFile(file); err == nil {
+			t.Errorf("Expected error loading %s", file)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := &Config{}
+	setDefaults(valid)
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Expected defaults to be valid, got %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		modify func(cfg *Config)
+		want   string
+	}{
+		{"validation concurrency", func(cfg *Config) { cfg.ValidationConcurrency = 0 }, "validation_concurrency"},
+		{"dns concurrency", func(cfg *Config) { cfg.DNSResolveConcurrency = -1 }, "dns_resolve_concurrency"},
+		{"brute concurrency", func(cfg *Config) { cfg.BruteConcurrency = 0 }, "brute_concurrency"},
+		{"step concurrency", func(cfg *Config) { cfg.MultiThreading.CrawlConcurrency = 0 }, "multi_threading.crawl_concurrency"},
+		{"validation timeout", func(cfg *Config) { cfg.ValidationTimeout = 0 }, "validation_timeout"},
+		{"dns timeout", func(cfg *Config) { cfg.DNSResolveTimeout = 0 }, "dns_resolve_timeout"},
+		{"step timeout", func(cfg *Config) { cfg.MultiThreading.EnrichTimeout = -5 }, "multi_threading.enrich_timeout"},
+		{"empty format", func(cfg *Config) { cfg.ResultSaveFormat = "" }, "result_save_format"},
+		{"unknown format", func(cfg *Config) { cfg.ResultSaveFormat = "xml" }, "result_save_format"},
+		{"port zero", func(cfg *Config) { cfg.TCPValidationPorts = []int{80, 0} }, "tcp_validation_ports"},
+		{"port too large", func(cfg *Config) { cfg.TCPValidationPorts = []int{70000} }, "tcp_validation_ports"},
+		{"template traversal", func(cfg *Config) { cfg.OutputTemplate = "{domain}/../../etc/{date}.{ext}" }, "output_template"},
+		{"absolute template", func(cfg *Config) { cfg.OutputTemplate = "/tmp/{domain}.{ext}" }, "output_template"},
+		{"template directory", func(cfg *Config) { cfg.OutputTemplate = "{domain}/{date}/" }, "output_template"},
+		{"unknown csv column", func(cfg *Config) { cfg.CSVColumns = []string{"subdomain", "asn"} }, "csv_columns"},
+		{"duplicate csv column", func(cfg *Config) { cfg.CSVColumns = []string{"subdomain", "ip", "IP"} }, "csv_columns"},
+		{"invalid resolver", func(cfg *Config) { cfg.Resolvers = []string{"10.0.0.53:dns"} }, "resolvers"},
+		{"csv without subdomain", func(cfg *Config) { cfg.CSVColumns = []string{"ip", "title"} }, "csv_columns"},
+	}
+
+	for _, c := range cases {
+		cfg := valid.Clone()
+		c.modify(cfg)
+		err := cfg.Validate()
+		if err == nil {
+			t.Errorf("%s: expected validation error", c.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: expected error mentioning %s, got %v", c.name, c.want, err)
+		}
+	}
+
+	// 多个错误应汇总返回
+	cfg := valid.Clone()
+	cfg.ValidationConcurrency = 0
+	cfg.ResultSaveFormat = "xml"
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "validation_concurrency") || !strings.Contains(err.Error(), "result_save_format") {
+		t.Errorf("Expected aggregated errors, got %v", err)
+	}
+}
+
+func TestDataFilesFromDataDir(t *testing.T) {
+	// 在没有 data 目录的工作目录中运行，数据目录指向其他位置
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	dataDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dataDir, "nameservers.txt"), []byte("10.0.0.53\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{DataDir: dataDir}
+	if path := cfg.DataPath("nameservers.txt"); path != filepath.Join(dataDir, "nameservers.txt") {
+		t.Errorf("Unexpected data path %s", path)
+	}
+	data, err := cfg.ReadDataFile("nameservers.txt")
+	if err != nil || string(data) != "10.0.0.53\n" {
+		t.Errorf("Expected nameservers from the data directory, got %q (%v)", data, err)
+	}
+
+	// 数据目录中缺少的文件回退到内置默认数据
+	for _, name := range []string{"subnames.txt", "subnames_next.txt", "cdn_ip_cidr.json", "altdns_wordlist.txt",
+		"common_subnames.txt", "srv_prefixes.txt", "web_fingerprints.json", "asn_prefixes.json"} {
+		data, err := cfg.ReadDataFile(name)
+		if err != nil || len(data) == 0 {
+			t.Errorf("Expected built-in default for %s, got %d bytes (%v)", name, len(data), err)
+		}
+	}
+
+	// 没有内置默认数据的文件返回错误
+	if _, err := cfg.ReadDataFile("missing.txt"); err == nil {
+		t.Error("Expected an error for a missing data file without a built-in default")
+	}
+
+	// 未配置数据目录时使用当前目录下的 data
+	if path := (&Config{}).DataPath("subnames.txt"); path != filepath.Join("data", "subnames.txt") {
+		t.Errorf("Unexpected default data path %s", path)
+	}
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/oneforall-go/data"
+	"github.com/oneforall-go/pkg/logger"
+)
+
+// DefaultDataDir 默认的数据目录，相对于当前工作目录
+const DefaultDataDir = "data"
+
+// reportedDataFiles 已记录过缺失日志的数据文件路径，同一文件只提示一次
+var reportedDataFiles sync.Map
+
+// DataPath 返回数据目录下文件的路径，未配置数据目录时使用 DefaultDataDir
+func (c *Config) DataPath(name string) string {
+	dir := DefaultDataDir
+	if c != nil && c.DataDir != "" {
+		dir = c.DataDir
+	}
+	return filepath.Join(dir, name)
+}
+
+// OpenDataFile 打开数据目录下的文件，文件不存在时回退到内置的默认数据；
+// 两者都没有时返回错误，同一文件的缺失只记录一次日志，调用方无需再逐次报错
+func (c *Config) OpenDataFile(name string) (io.ReadCloser, error) {
+	filePath := c.DataPath(name)
+	file, err := os.Open(filePath)
+	if err == nil {
+		return file, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if builtin, embedErr := data.Defaults.Open(data.BuiltinName(name)); embedErr == nil {
+		if _, reported := reportedDataFiles.LoadOrStore(filePath, true); !reported {
+			logger.Warnf("Data file %s not found, using built-in default (set DATA_DIR or --data-dir to the data directory)", filePath)
+		}
+		return builtin, nil
+	}
+
+	if _, reported := reportedDataFiles.LoadOrStore(filePath, true); !reported {
+		logger.Errorf("Data file %s not found and has no built-in default (set DATA_DIR or --data-dir to the data directory)", filePath)
+	}
+	return nil, fmt.Errorf("data file %s not found", filePath)
+}
+
+// ReadDataFile 读取数据目录下的文件，缺失时的处理同 OpenDataFile
+func (c *Config) ReadDataFile(name string) ([]byte, error) {
+	file, err := c.OpenDataFile(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
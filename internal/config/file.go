@@ -0,0 +1,177 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/oneforall-go/pkg/logger"
+	"github.com/spf13/viper"
+)
+
+// LoadFile 从指定的 YAML/JSON 配置文件加载配置，并替换全局配置
+// 优先级：默认值 < 配置文件 < 环境变量（命令行参数由调用方在之后覆盖）
+// 文件不存在或解析失败时直接返回错误，不会静默忽略
+func LoadFile(path string) (*Config, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %v", path, err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("config file %s is a directory", path)
+	}
+
+	loadEnvFile()
+
+	cfg := &Config{}
+	setDefaults(cfg)
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %v", path, err)
+	}
+
+	before := cfg.Clone()
+	if err := v.Unmarshal(cfg, viper.DecodeHook(decodeHook())); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+	applyDeprecatedKeys(v, cfg)
+	normalizeConfigResolvers(cfg)
+	LogChanges(before, cfg, "file "+path)
+
+	before = cfg.Clone()
+	loadFromEnv(cfg)
+	LogChanges(before, cfg, "env")
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("config file %s: %v", path, err)
+	}
+
+	config = cfg
+	return cfg, nil
+}
+
+// decodeHook 配置文件解码钩子，在 viper 默认钩子基础上支持将列表解码为逗号分隔字符串
+func decodeHook() mapstructure.DecodeHookFunc {
+	return mapstructure.ComposeDecodeHookFunc(
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+		sliceToStringHook,
+	)
+}
+
+// sliceToStringHook 将列表解码为逗号分隔字符串（如 common_subnames）
+func sliceToStringHook(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() != reflect.Slice || to.Kind() != reflect.String {
+		return data, nil
+	}
+
+	items := reflect.ValueOf(data)
+	parts := make([]string, 0, items.Len())
+	for i := 0; i < items.Len(); i++ {
+		parts = append(parts, fmt.Sprint(items.Index(i).Interface()))
+	}
+	return strings.Join(parts, ","), nil
+}
+
+// Clone 复制配置，切片和 map 字段深拷贝
+func (c *Config) Clone() *Config {
+	clone := *c
+	if c.Resolvers != nil {
+		clone.Resolvers = append([]string(nil), c.Resolvers...)
+	}
+	if c.DisabledModules != nil {
+		clone.DisabledModules = append([]string(nil), c.DisabledModules...)
+	}
+	if c.InsecureModules != nil {
+		clone.InsecureModules = append([]string(nil), c.InsecureModules...)
+	}
+	if c.NetworkTargetDomains != nil {
+		clone.NetworkTargetDomains = append([]string(nil), c.NetworkTargetDomains...)
+	}
+	if c.BruteWordlists != nil {
+		clone.BruteWordlists = append([]string(nil), c.BruteWordlists...)
+	}
+	if c.CSVColumns != nil {
+		clone.CSVColumns = append([]string(nil), c.CSVColumns...)
+	}
+	if c.TCPValidationPorts != nil {
+		clone.TCPValidationPorts = append([]int(nil), c.TCPValidationPorts...)
+	}
+	if c.APIKeys != nil {
+		clone.APIKeys = make(map[string]string, len(c.APIKeys))
+		for k, v := range c.APIKeys {
+			clone.APIKeys[k] = v
+		}
+	}
+	if c.ExtraHeaders != nil {
+		clone.ExtraHeaders = make(map[string]string, len(c.ExtraHeaders))
+		for k, v := range c.ExtraHeaders {
+			clone.ExtraHeaders[k] = v
+		}
+	}
+	return &clone
+}
+
+// LogChanges 以调试日志输出 before 到 after 之间变化的配置项及其来源
+func LogChanges(before, after *Config, source string) {
+	if before == nil || after == nil {
+		return
+	}
+	diffValues("", reflect.ValueOf(before).Elem(), reflect.ValueOf(after).Elem(), func(key string, value interface{}) {
+		logger.Debugf("Config %s = %v (from %s)", key, value, source)
+	})
+}
+
+// diffValues 递归比较结构体字段，键名使用 mapstructure 标签
+func diffValues(prefix string, before, after reflect.Value, report func(key string, value interface{})) {
+	t := after.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		key := field.Tag.Get("mapstructure")
+		if key == "" {
+			key = strings.ToLower(field.Name)
+		}
+		key = prefix + key
+
+		b, a := before.Field(i), after.Field(i)
+		switch a.Kind() {
+		case reflect.Struct:
+			diffValues(key+".", b, a, report)
+		case reflect.Map:
+			// API 密钥和附加请求头只输出名称，不输出值
+			names := make([]string, 0, a.Len())
+			for _, k := range a.MapKeys() {
+				if v := b.MapIndex(k); !v.IsValid() || v.Interface() != a.MapIndex(k).Interface() {
+					names = append(names, fmt.Sprint(k.Interface()))
+				}
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				report(key+"."+name, "******")
+			}
+		default:
+			if reflect.DeepEqual(b.Interface(), a.Interface()) {
+				continue
+			}
+			if isSecretKey(key) {
+				report(key, "******")
+			} else {
+				report(key, a.Interface())
+			}
+		}
+	}
+}
+
+// isSecretKey 判断配置项是否为敏感信息
+func isSecretKey(key string) bool {
+	return strings.Contains(key, "secret") || strings.Contains(key, "password") || strings.Contains(key, "token")
+}
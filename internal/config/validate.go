@@ -0,0 +1,257 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+)
+
+// SupportedFormats 支持的结果输出格式
+var SupportedFormats = []string{"csv", "json", "md", "txt", "ipjson", "elasticsearch"}
+
+// CSVColumns CSV 结果支持的列，也是未配置 csv_columns 时的列和顺序；新增列只追加到末尾，保持下游解析稳定
+var CSVColumns = []string{
+	"subdomain", "ip", "status", "title", "port", "alive", "source", "time", "provider", "dns_resolved", "ping_alive",
+	"status_code", "status_text", "cert_issuer", "cert_expiry", "cname", "url", "ports", "confidence",
+	"server", "powered_by", "technologies", "tls_version",
+}
+
+// DefaultOutputTemplate 默认的结果文件名模板，如 example.com_20240101_120000.csv
+const DefaultOutputTemplate = "{domain}_{date}_{time}.{ext}"
+
+// Validate 校验配置取值，返回汇总后的全部错误
+func (c *Config) Validate() error {
+	var problems []string
+
+	positive := func(key string, value int) {
+		if value <= 0 {
+			problems = append(problems, fmt.Sprintf("%s must be greater than 0, got %d", key, value))
+		}
+	}
+
+	// 并发数
+	positive("dns_resolve_concurrency", c.DNSResolveConcurrency)
+	positive("brute_concurrency", c.BruteConcurrency)
+	positive("validation_concurrency", c.ValidationConcurrency)
+	if c.ValidationDNSConcurrency < 0 {
+		problems = append(problems, fmt.Sprintf("validation_dns_concurrency must not be negative, got %d", c.ValidationDNSConcurrency))
+	}
+	if c.ValidationHTTPConcurrency < 0 {
+		problems = append(problems, fmt.Sprintf("validation_http_concurrency must not be negative, got %d", c.ValidationHTTPConcurrency))
+	}
+
+	mt := c.MultiThreading
+	positive("multi_threading.fast_search_concurrency", mt.FastSearchConcurrency)
+	positive("multi_threading.dataset_concurrency", mt.DatasetConcurrency)
+	positive("multi_threading.certificate_concurrency", mt.CertificateConcurrency)
+	positive("multi_threading.crawl_concurrency", mt.CrawlConcurrency)
+	positive("multi_threading.dns_lookup_concurrency", mt.DNSLookupConcurrency)
+	positive("multi_threading.file_check_concurrency", mt.FileCheckConcurrency)
+	positive("multi_threading.intelligence_concurrency", mt.IntelligenceConcurrency)
+	positive("multi_threading.brute_force_concurrency", mt.BruteForceConcurrency)
+	positive("multi_threading.enrich_concurrency", mt.EnrichConcurrency)
+
+	// 爆破并发数自动调整范围
+	positive("brute_min_concurrency", c.BruteMinConcurrency)
+	positive("brute_max_concurrency", c.BruteMaxConcurrency)
+	if c.BruteMaxConcurrency < c.BruteMinConcurrency {
+		problems = append(problems, fmt.Sprintf("brute_max_concurrency (%d) must not be less than brute_min_concurrency (%d)",
+			c.BruteMaxConcurrency, c.BruteMinConcurrency))
+	}
+	if c.BruteTargetErrorRate <= 0 || c.BruteTargetErrorRate >= 100 {
+		problems = append(problems, fmt.Sprintf("brute_target_error_rate must be between 0 and 100, got %g", c.BruteTargetErrorRate))
+	}
+
+	// 大规模去重的布隆过滤器
+	if c.DedupBloomThreshold < 0 {
+		problems = append(problems, fmt.Sprintf("dedup_bloom_threshold must not be negative, got %d", c.DedupBloomThreshold))
+	}
+	if c.DedupFalsePositiveRate <= 0 || c.DedupFalsePositiveRate >= 1 {
+		problems = append(problems, fmt.Sprintf("dedup_false_positive_rate must be between 0 and 1, got %g", c.DedupFalsePositiveRate))
+	}
+
+	// 超时
+	positive("dns_resolve_timeout", c.DNSResolveTimeout)
+	positive("brute_timeout", c.BruteTimeout)
+	positive("validation_timeout", c.ValidationTimeout)
+
+	positive("multi_threading.fast_search_timeout", mt.FastSearchTimeout)
+	positive("multi_threading.dataset_timeout", mt.DatasetTimeout)
+	positive("multi_threading.certificate_timeout", mt.CertificateTimeout)
+	positive("multi_threading.crawl_timeout", mt.CrawlTimeout)
+	positive("multi_threading.dns_lookup_timeout", mt.DNSLookupTimeout)
+	positive("multi_threading.file_check_timeout", mt.FileCheckTimeout)
+	positive("multi_threading.intelligence_timeout", mt.IntelligenceTimeout)
+	positive("multi_threading.brute_force_timeout", mt.BruteForceTimeout)
+	positive("multi_threading.enrich_timeout", mt.EnrichTimeout)
+
+	// ASN/CIDR 展开上限
+	positive("max_cidr_hosts", c.MaxCIDRHosts)
+	if c.ReverseIPLimit < 0 {
+		problems = append(problems, fmt.Sprintf("reverse_ip_limit must not be negative, got %d", c.ReverseIPLimit))
+	}
+	if c.PTRSweepLimit < 0 {
+		problems = append(problems, fmt.Sprintf("ptr_sweep_limit must not be negative, got %d", c.PTRSweepLimit))
+	}
+	if c.PTRSweepRate < 0 {
+		problems = append(problems, fmt.Sprintf("ptr_sweep_rate must not be negative, got %d", c.PTRSweepRate))
+	}
+
+	// 递归搜索层数
+	if c.SearchRecursiveTimes < 0 {
+		problems = append(problems, fmt.Sprintf("search_recursive_times must not be negative, got %d", c.SearchRecursiveTimes))
+	}
+
+	// CommonCrawl 索引数
+	positive("commoncrawl_indexes", c.CommonCrawlIndexes)
+
+	// 结果上限，0 表示不限制
+	if c.MaxResults < 0 {
+		problems = append(problems, fmt.Sprintf("max_results must not be negative, got %d", c.MaxResults))
+	}
+
+	// IP 版本
+	if _, err := ParseIPVersion(c.IPVersion); err != nil {
+		problems = append(problems, fmt.Sprintf("ip_version: %v", err))
+	}
+
+	// HTTPS 探测的最低 TLS 版本
+	if _, err := ParseTLSVersion(c.TLSMinVersion); err != nil {
+		problems = append(problems, fmt.Sprintf("tls_min_version: %v", err))
+	}
+
+	// 置信度下限
+	if c.MinConfidence < 0 || c.MinConfidence > 100 {
+		problems = append(problems, fmt.Sprintf("min_confidence must be between 0 and 100, got %d", c.MinConfidence))
+	}
+
+	// HTTP 响应体上限
+	positive("http_max_idle_conns_per_host", c.HTTPMaxIdleConnsPerHost)
+	if c.MaxResponseBody <= 0 {
+		problems = append(problems, fmt.Sprintf("max_response_body must be greater than 0, got %d", c.MaxResponseBody))
+	}
+
+	// 附加请求头名称
+	for name := range c.ExtraHeaders {
+		if name == "" || strings.ContainsAny(name, " \t:\r\n") {
+			problems = append(problems, fmt.Sprintf("extra_headers contains invalid header name %q", name))
+		}
+	}
+
+	// DNS 服务器
+	if _, err := normalizeResolvers(c.Resolvers); err != nil {
+		problems = append(problems, fmt.Sprintf("resolvers: %v", err))
+	}
+
+	// EDNS Client Subnet，单个 IP 或 CIDR
+	if subnet := strings.TrimSpace(c.EDNSClientSubnet); subnet != "" {
+		if _, _, err := net.ParseCIDR(subnet); err != nil && net.ParseIP(subnet) == nil {
+			problems = append(problems, fmt.Sprintf("edns_client_subnet %q is not an IP address or CIDR", c.EDNSClientSubnet))
+		}
+	}
+
+	// 运行时间预算，0 表示不限制
+	if c.MaxRuntime < 0 {
+		problems = append(problems, fmt.Sprintf("max_runtime must not be negative, got %v", c.MaxRuntime))
+	}
+
+	// 请求间隔的随机范围
+	if c.RequestDelayMin < 0 || c.RequestDelayMax < 0 {
+		problems = append(problems, fmt.Sprintf("request_delay_min and request_delay_max must not be negative, got %v and %v", c.RequestDelayMin, c.RequestDelayMax))
+	} else if c.RequestDelayMax < c.RequestDelayMin {
+		problems = append(problems, fmt.Sprintf("request_delay_max (%v) must not be less than request_delay_min (%v)", c.RequestDelayMax, c.RequestDelayMin))
+	}
+
+	// 结果文件名模板
+	if err := ValidateOutputTemplate(c.OutputTemplate); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	// CSV 列
+	if err := ValidateCSVColumns(c.CSVColumns); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	// 输出格式
+	if !isSupportedFormat(c.ResultSaveFormat) {
+		problems = append(problems, fmt.Sprintf("result_save_format %q is not supported (supported: %s)",
+			c.ResultSaveFormat, strings.Join(SupportedFormats, "/")))
+	}
+
+	// TCP 验证端口
+	for _, port := range c.TCPValidationPorts {
+		if port < 1 || port > 65535 {
+			problems = append(problems, fmt.Sprintf("tcp_validation_ports contains out-of-range port %d", port))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// ValidateOutputTemplate 校验结果文件名模板：必须是相对路径，不能包含 ".." 跳出结果目录，且不能以目录结尾
+func ValidateOutputTemplate(template string) error {
+	if template == "" {
+		return nil
+	}
+	if filepath.IsAbs(template) || strings.HasPrefix(template, "/") || strings.HasPrefix(template, "\\") {
+		return fmt.Errorf("output_template %q must be a relative path", template)
+	}
+	segments := strings.FieldsFunc(template, func(r rune) bool { return r == '/' || r == '\\' })
+	for _, segment := range segments {
+		if segment == ".." {
+			return fmt.Errorf("output_template %q must not contain '..'", template)
+		}
+	}
+	if len(segments) == 0 || strings.HasSuffix(template, "/") || strings.HasSuffix(template, "\\") {
+		return fmt.Errorf("output_template %q must end with a file name", template)
+	}
+	return nil
+}
+
+// ValidateCSVColumns 校验 csv_columns：列名（不区分大小写）必须在 CSVColumns 中且不能重复，
+// 并且要包含 subdomain 列，使结果文件可以作为基线再次加载；为空表示使用全部默认列
+func ValidateCSVColumns(columns []string) error {
+	if len(columns) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	for _, column := range columns {
+		name := strings.ToLower(strings.TrimSpace(column))
+		if !isCSVColumn(name) {
+			return fmt.Errorf("csv_columns contains unknown column %q (supported: %s)", column, strings.Join(CSVColumns, ","))
+		}
+		if seen[name] {
+			return fmt.Errorf("csv_columns contains duplicate column %q", column)
+		}
+		seen[name] = true
+	}
+	if !seen["subdomain"] {
+		return fmt.Errorf("csv_columns must include the subdomain column")
+	}
+	return nil
+}
+
+// isCSVColumn 判断是否为支持的 CSV 列名
+func isCSVColumn(name string) bool {
+	for _, column := range CSVColumns {
+		if name == column {
+			return true
+		}
+	}
+	return false
+}
+
+// isSupportedFormat 判断输出格式是否受支持
+func isSupportedFormat(format string) bool {
+	for _, f := range SupportedFormats {
+		if format == f {
+			return true
+		}
+	}
+	return false
+}
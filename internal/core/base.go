@@ -1,8 +1,12 @@
 package core
 
 import (
+	"bufio"
 	"bytes"
-	"crypto/tls"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -15,6 +19,7 @@ import (
 	"time"
 
 	"github.com/oneforall-go/internal/config"
+	"github.com/oneforall-go/internal/transport"
 	"github.com/oneforall-go/pkg/logger"
 )
 
@@ -40,6 +45,11 @@ type Module interface {
 	SetEnabled(enabled bool)
 }
 
+// DryRunner 支持 dry-run 的模块，在不发送网络请求的情况下生成候选子域名
+type DryRunner interface {
+	DryRun(domain string) ([]string, error)
+}
+
 // BaseModule 基础模块类（对应 Python 的 Module 基类）
 type BaseModule struct {
 	name       string
@@ -48,6 +58,8 @@ type BaseModule struct {
 	config     *config.Config
 	domain     string
 	subdomains map[string]bool
+	related    map[string]bool   // 关联域名（注册域名）
+	sourceTags map[string]string // 子域名的来源标记
 	infos      map[string]interface{}
 	results    []interface{}
 	startTime  time.Time
@@ -56,13 +68,24 @@ type BaseModule struct {
 
 	// HTTP 相关
 	httpClient *http.Client
-	userAgents []string
-	cookie     *http.Cookie
-	header     map[string]string
-	proxy      *url.URL
-	delay      time.Duration
-	timeout    time.Duration
-	retryCount int
+	transport  *http.Transport // 共享连接池，设置代理时复制后使用
+	// 不校验 HTTPS 证书：只用于直接访问目标（可能使用自签名证书）的检查模块和 insecure_modules 中列出的模块
+	insecureTLS bool
+	userAgents  []string
+	cookie      *http.Cookie
+	header      map[string]string
+	proxy       *url.URL
+	delayMin    time.Duration // 每次请求前随机等待 [delayMin, delayMax]
+	delayMax    time.Duration
+	timeout     time.Duration
+	retry       RetryPolicy
+
+	// 运行上下文，由调度器在模块运行前注入，取消后进行中的请求和重试等待立即结束
+	ctx context.Context
+
+	// API 额度，耗尽后本次运行不再发送请求
+	quotaReason    string // 额度耗尽原因，为空表示未耗尽
+	quotaRemaining string // 响应头报告的剩余额度
 
 	// 线程安全
 	mutex sync.RWMutex
@@ -70,32 +93,37 @@ type BaseModule struct {
 
 // NewBaseModule 创建基础模块
 func NewBaseModule(name string, moduleType ModuleType, cfg *config.Config) *BaseModule {
+	insecureTLS := insecureModule(name, moduleType, cfg)
+	shared := transport.Default().Secure
+	if insecureTLS {
+		shared = transport.Default().Insecure
+	}
+
 	return &BaseModule{
-		name:       name,
-		moduleType: moduleType,
-		enabled:    true,
-		config:     cfg,
-		subdomains: make(map[string]bool),
-		infos:      make(map[string]interface{}),
-		results:    make([]interface{}, 0),
+		name:        name,
+		moduleType:  moduleType,
+		enabled:     true,
+		config:      cfg,
+		subdomains:  make(map[string]bool),
+		infos:       make(map[string]interface{}),
+		results:     make([]interface{}, 0),
+		insecureTLS: insecureTLS,
 		httpClient: &http.Client{
-			Timeout: time.Duration(cfg.DNSResolveTimeout) * time.Second,
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{
-					InsecureSkipVerify: true,
-				},
-			},
+			Timeout:   time.Duration(cfg.DNSResolveTimeout) * time.Second,
+			Transport: shared,
 		},
+		transport: shared,
 		userAgents: []string{
 			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36",
 			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36",
 			"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36",
 			"Googlebot/2.1 (+http://www.google.com/bot.html)",
 		},
-		header:     make(map[string]string),
-		delay:      time.Duration(rand.Intn(3)+1) * time.Second,
-		timeout:    time.Duration(cfg.DNSResolveTimeout) * time.Second,
-		retryCount: 3,
+		header:   make(map[string]string),
+		delayMin: cfg.RequestDelayMin,
+		delayMax: cfg.RequestDelayMax,
+		timeout:  time.Duration(cfg.DNSResolveTimeout) * time.Second,
+		retry:    DefaultRetryPolicy(),
 	}
 }
 
@@ -129,6 +157,11 @@ func (b *BaseModule) GetDomain() string {
 	return b.domain
 }
 
+// GetConfig 获取配置
+func (b *BaseModule) GetConfig() *config.Config {
+	return b.config
+}
+
 // Begin 开始执行
 func (b *BaseModule) Begin() {
 	b.startTime = time.Now()
@@ -147,7 +180,7 @@ func (b *BaseModule) Finish() {
 func (b *BaseModule) AddSubdomain(subdomain string) {
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
-	b.subdomains[subdomain] = true
+	b.subdomains[NormalizeHost(subdomain)] = true
 }
 
 // GetSubdomains 获取所有子域名
@@ -195,8 +228,11 @@ func (b *BaseModule) GetResults() []interface{} {
 
 // HTTP 请求相关方法
 
-// GetRandomUserAgent 获取随机 User-Agent
+// GetRandomUserAgent 获取随机 User-Agent，配置了 user_agent 时始终返回该值
 func (b *BaseModule) GetRandomUserAgent() string {
+	if b.config.UserAgent != "" {
+		return b.config.UserAgent
+	}
 	return b.userAgents[rand.Intn(len(b.userAgents))]
 }
 
@@ -221,34 +257,135 @@ func (b *BaseModule) SetProxy(proxyURL string) error {
 		return err
 	}
 	b.proxy = proxy
+	b.applyTransport()
 	return nil
 }
 
-// SetDelay 设置延迟
+// TransportSetter 可注入共享 Transport 的模块，嵌入 BaseModule 的模块都实现该接口
+type TransportSetter interface {
+	SetTransport(t *http.Transport)
+}
+
+// ContextSetter 可注入运行上下文的模块，嵌入 BaseModule 的模块都实现该接口；
+// 调度器在达到结果上限、步骤超时或运行预算耗尽时取消上下文，让运行中的模块提前结束
+type ContextSetter interface {
+	SetContext(ctx context.Context)
+}
+
+// SetContext 设置运行上下文（由调度器注入）
+func (b *BaseModule) SetContext(ctx context.Context) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.ctx = ctx
+}
+
+// Context 返回运行上下文，未注入时返回 context.Background()
+func (b *BaseModule) Context() context.Context {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	if b.ctx == nil {
+		return context.Background()
+	}
+	return b.ctx
+}
+
+// TLSVerifier 可按模块决定是否校验 HTTPS 证书的模块，嵌入 BaseModule 的模块都实现该接口
+type TLSVerifier interface {
+	InsecureTLS() bool
+}
+
+// InsecureTLS 模块是否跳过 HTTPS 证书校验，调度器据此注入校验或不校验证书的共享 Transport
+func (b *BaseModule) InsecureTLS() bool {
+	return b.insecureTLS
+}
+
+// insecureModule 判断模块是否不校验证书：检查模块直接访问目标，目标常使用自签名证书；
+// 其他模块访问的是搜索引擎和 API，默认校验证书，可通过 insecure_modules 单独关闭
+func insecureModule(name string, moduleType ModuleType, cfg *config.Config) bool {
+	return moduleType == ModuleTypeCheck || cfg.InsecureModule(name)
+}
+
+// SetTransport 设置共享的 Transport（由调度器注入），已设置的代理继续生效
+func (b *BaseModule) SetTransport(t *http.Transport) {
+	b.transport = t
+	b.applyTransport()
+}
+
+// applyTransport 更新 HTTP 客户端的 Transport，设置了代理时使用共享 Transport 的副本
+func (b *BaseModule) applyTransport() {
+	if b.proxy != nil {
+		b.httpClient.Transport = transport.WithProxy(b.transport, b.proxy)
+		return
+	}
+	b.httpClient.Transport = b.transport
+}
+
+// SetDelay 设置固定的请求间隔，0 表示不等待
 func (b *BaseModule) SetDelay(delay time.Duration) {
-	b.delay = delay
+	b.SetDelayRange(delay, delay)
+}
+
+// SetDelayRange 设置请求间隔的随机范围，每次请求前重新取值；max 小于 min 时使用 min
+func (b *BaseModule) SetDelayRange(min, max time.Duration) {
+	if min < 0 {
+		min = 0
+	}
+	if max < min {
+		max = min
+	}
+	b.delayMin, b.delayMax = min, max
 }
 
-// Sleep 随机延迟
+// Sleep 随机延迟，每次调用在 [delayMin, delayMax] 内重新取值
 func (b *BaseModule) Sleep() {
-	time.Sleep(b.delay)
+	if delay := b.nextDelay(); delay > 0 {
+		time.Sleep(delay)
+	}
 }
 
-// HTTPGet 执行 HTTP GET 请求
-func (b *BaseModule) HTTPGet(urlStr string, headers map[string]string) (*http.Response, error) {
-	req, err := http.NewRequest("GET", urlStr, nil)
-	if err != nil {
-		return nil, err
+// nextDelay 计算本次请求前的等待时间
+func (b *BaseModule) nextDelay() time.Duration {
+	if b.delayMax <= b.delayMin {
+		return b.delayMin
 	}
+	return b.delayMin + time.Duration(rand.Int63n(int64(b.delayMax-b.delayMin)+1))
+}
 
-	// 设置默认 User-Agent
+// defaultHeaders 补充默认请求头：User-Agent、Content-Type（非空时）和配置的附加请求头，
+// 调用方已设置的请求头优先
+func (b *BaseModule) defaultHeaders(headers map[string]string, contentType string) map[string]string {
 	if headers == nil {
 		headers = make(map[string]string)
 	}
-	if _, exists := headers["User-Agent"]; !exists {
-		headers["User-Agent"] = b.GetRandomUserAgent()
+	set := func(key, value string) {
+		for existing := range headers {
+			if strings.EqualFold(existing, key) {
+				return
+			}
+		}
+		headers[key] = value
 	}
 
+	set("User-Agent", b.GetRandomUserAgent())
+	if contentType != "" {
+		set("Content-Type", contentType)
+	}
+	for key, value := range b.config.ExtraHeaders {
+		set(key, value)
+	}
+	return headers
+}
+
+// HTTPGet 执行 HTTP GET 请求
+func (b *BaseModule) HTTPGet(urlStr string, headers map[string]string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(b.Context(), "GET", urlStr, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// 设置默认请求头
+	headers = b.defaultHeaders(headers, "")
+
 	// 设置请求头
 	for key, value := range headers {
 		req.Header.Set(key, value)
@@ -258,40 +395,18 @@ func (b *BaseModule) HTTPGet(urlStr string, headers map[string]string) (*http.Re
 	b.Sleep()
 
 	// 重试机制
-	var resp *http.Response
-	for i := 0; i < b.retryCount; i++ {
-		resp, err = b.httpClient.Do(req)
-		if err == nil && resp.StatusCode == 200 {
-			break
-		}
-		if resp != nil {
-			resp.Body.Close()
-		}
-		if i < b.retryCount-1 {
-			time.Sleep(time.Duration(i+1) * time.Second)
-		}
-	}
-
-	return resp, err
+	return b.doWithRetry(req)
 }
 
 // HTTPPost 执行 HTTP POST 请求
 func (b *BaseModule) HTTPPost(urlStr string, data url.Values, headers map[string]string) (*http.Response, error) {
-	req, err := http.NewRequest("POST", urlStr, strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(b.Context(), "POST", urlStr, strings.NewReader(data.Encode()))
 	if err != nil {
 		return nil, err
 	}
 
 	// 设置默认请求头
-	if headers == nil {
-		headers = make(map[string]string)
-	}
-	if _, exists := headers["User-Agent"]; !exists {
-		headers["User-Agent"] = b.GetRandomUserAgent()
-	}
-	if _, exists := headers["Content-Type"]; !exists {
-		headers["Content-Type"] = "application/x-www-form-urlencoded"
-	}
+	headers = b.defaultHeaders(headers, "application/x-www-form-urlencoded")
 
 	// 设置请求头
 	for key, value := range headers {
@@ -302,21 +417,7 @@ func (b *BaseModule) HTTPPost(urlStr string, data url.Values, headers map[string
 	b.Sleep()
 
 	// 重试机制
-	var resp *http.Response
-	for i := 0; i < b.retryCount; i++ {
-		resp, err = b.httpClient.Do(req)
-		if err == nil && resp.StatusCode == 200 {
-			break
-		}
-		if resp != nil {
-			resp.Body.Close()
-		}
-		if i < b.retryCount-1 {
-			time.Sleep(time.Duration(i+1) * time.Second)
-		}
-	}
-
-	return resp, err
+	return b.doWithRetry(req)
 }
 
 // HTTPPostJSON 执行 HTTP POST JSON 请求
@@ -326,21 +427,13 @@ func (b *BaseModule) HTTPPostJSON(urlStr string, data interface{}, headers map[s
 		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", urlStr, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(b.Context(), "POST", urlStr, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, err
 	}
 
 	// 设置默认请求头
-	if headers == nil {
-		headers = make(map[string]string)
-	}
-	if _, exists := headers["User-Agent"]; !exists {
-		headers["User-Agent"] = b.GetRandomUserAgent()
-	}
-	if _, exists := headers["Content-Type"]; !exists {
-		headers["Content-Type"] = "application/json"
-	}
+	headers = b.defaultHeaders(headers, "application/json")
 
 	// 设置请求头
 	for key, value := range headers {
@@ -351,38 +444,79 @@ func (b *BaseModule) HTTPPostJSON(urlStr string, data interface{}, headers map[s
 	b.Sleep()
 
 	// 重试机制
-	var resp *http.Response
-	for i := 0; i < b.retryCount; i++ {
-		resp, err = b.httpClient.Do(req)
-		if err == nil && resp.StatusCode == 200 {
-			break
-		}
-		if resp != nil {
-			resp.Body.Close()
-		}
-		if i < b.retryCount-1 {
-			time.Sleep(time.Duration(i+1) * time.Second)
-		}
-	}
-
-	return resp, err
+	return b.doWithRetry(req)
 }
 
-// ReadResponseBody 读取响应体
+// defaultMaxResponseBody 未配置 max_response_body 时的响应体上限
+const defaultMaxResponseBody = 10 << 20
+
+// ReadResponseBody 读取响应体，按 Content-Encoding 透明解压 gzip/deflate，
+// 解压后超过 max_response_body 时返回错误，避免超大响应耗尽内存
 func (b *BaseModule) ReadResponseBody(resp *http.Response) (string, error) {
 	if resp == nil {
 		return "", fmt.Errorf("response is nil")
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	reader, err := decodeBody(resp)
 	if err != nil {
 		return "", err
 	}
+	defer reader.Close()
+
+	limit := int64(defaultMaxResponseBody)
+	if b.config != nil && b.config.MaxResponseBody > 0 {
+		limit = b.config.MaxResponseBody
+	}
+
+	// 多读一个字节判断是否超出上限
+	body, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return "", err
+	}
+	if int64(len(body)) > limit {
+		return "", fmt.Errorf("response body exceeds limit of %d bytes", limit)
+	}
 
 	return string(body), nil
 }
 
+// decodeBody 根据 Content-Encoding 返回解压后的响应体读取器
+// 模块手动设置 Accept-Encoding 时 http.Transport 不会自动解压
+func decodeBody(resp *http.Response) (io.ReadCloser, error) {
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "gzip", "x-gzip":
+		reader, err := gzip.NewReader(resp.Body)
+		if err == io.EOF {
+			// 空响应体（如 204）
+			return io.NopCloser(resp.Body), nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode gzip body: %v", err)
+		}
+		return reader, nil
+	case "deflate":
+		// deflate 实际可能是 zlib 封装或原始 deflate 流，按 zlib 头区分
+		buffered := bufio.NewReader(resp.Body)
+		header, err := buffered.Peek(2)
+		if err == nil && isZlibHeader(header) {
+			reader, err := zlib.NewReader(buffered)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode deflate body: %v", err)
+			}
+			return reader, nil
+		}
+		return flate.NewReader(buffered), nil
+	default:
+		return io.NopCloser(resp.Body), nil
+	}
+}
+
+// isZlibHeader 判断是否为 zlib 头（CMF/FLG 校验，RFC 1950）
+func isZlibHeader(header []byte) bool {
+	return len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0
+}
+
 // 子域名处理相关方法
 
 // ExtractSubdomains 从文本中提取子域名
@@ -390,41 +524,28 @@ func (b *BaseModule) ExtractSubdomains(text, domain string) []string {
 	var subdomains []string
 
 	// 构建正则表达式模式
-	pattern := fmt.Sprintf(`([a-zA-Z0-9.-]+\.%s)`, regexp.QuoteMeta(domain))
+	pattern := fmt.Sprintf(`(?i)([a-zA-Z0-9.-]+\.%s)`, regexp.QuoteMeta(domain))
 	re := regexp.MustCompile(pattern)
 
-	matches := re.FindAllString(text, -1)
-	for _, match := range matches {
+	for _, loc := range re.FindAllStringIndex(text, -1) {
+		// 匹配后仍有主机名字符时属于其他域名（如 a.example.com.attacker.net）
+		if continuesHostname(text[loc[1]:]) {
+			continue
+		}
+
 		// 清理和验证子域名
-		subdomain := strings.TrimSpace(match)
+		subdomain := strings.TrimSpace(text[loc[0]:loc[1]])
 		if b.IsValidSubdomain(subdomain, domain) {
-			subdomains = append(subdomains, subdomain)
+			subdomains = append(subdomains, NormalizeHost(subdomain))
 		}
 	}
 
 	return subdomains
 }
 
-// IsValidSubdomain 验证子域名是否有效
+// IsValidSubdomain 验证子域名是否有效（属于目标域名范围且不是目标域名本身）
 func (b *BaseModule) IsValidSubdomain(subdomain, domain string) bool {
-	// 基本验证
-	if subdomain == domain {
-		return false
-	}
-
-	if !strings.HasSuffix(subdomain, "."+domain) {
-		return false
-	}
-
-	// 检查是否包含无效字符
-	invalidChars := []string{" ", "\t", "\n", "\r", "\\", "/", ":", "*", "?", "\"", "<", ">", "|"}
-	for _, char := range invalidChars {
-		if strings.Contains(subdomain, char) {
-			return false
-		}
-	}
-
-	return true
+	return NormalizeHost(subdomain) != NormalizeHost(domain) && InScope(subdomain, domain)
 }
 
 // Deduplicate 去重
@@ -0,0 +1,267 @@
+package core
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oneforall-go/pkg/logger"
+)
+
+// ResultDiff 与基线结果的差异报告
+type ResultDiff struct {
+	Baseline    string   `json:"baseline"`     // 基线文件
+	GeneratedAt string   `json:"generated_at"` // 生成时间
+	Added       []string `json:"added"`        // 新出现的子域名
+	Removed     []string `json:"removed"`      // 已消失的子域名
+}
+
+// LoadResults 从 CSV 或 JSON 文件加载结果，按扩展名和文件内容自动识别格式
+func LoadResults(path string) ([]SubdomainResult, error) {
+	return LoadResultsAs(path, "auto")
+}
+
+// LoadResultsAs 按指定格式（csv/json，auto 或空表示自动识别）加载结果文件
+func LoadResultsAs(path, format string) ([]SubdomainResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read result file: %v", err)
+	}
+
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" || format == "auto" {
+		format = detectResultFormat(path, data)
+	}
+
+	switch format {
+	case "json":
+		return parseResultsJSON(path, data)
+	case "csv":
+		return parseResultsCSV(data)
+	default:
+		return nil, fmt.Errorf("unsupported result file format: %s", path)
+	}
+}
+
+// detectResultFormat 识别结果文件格式：优先使用扩展名，扩展名未知时根据内容判断
+func detectResultFormat(path string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "json"
+	case ".csv":
+		return "csv"
+	}
+
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, utf8BOM))
+	if len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		return "json"
+	}
+	// CSV 结果的表头以 subdomain 列开头
+	if bytes.HasPrefix(trimmed, []byte("subdomain")) {
+		return "csv"
+	}
+	return ""
+}
+
+// utf8BOM 部分编辑器保存 CSV 时添加的 UTF-8 BOM
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// parseResultsJSON 解析 JSON 结果，支持结果数组和 JSON 信封两种格式
+func parseResultsJSON(path string, data []byte) ([]SubdomainResult, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var envelope JSONEnvelope
+		if err := json.Unmarshal(trimmed, &envelope); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON envelope: %v", err)
+		}
+		if envelope.Version > JSONEnvelopeVersion {
+			logger.Warnf("Result file %s uses envelope version %d (newer than %d), some fields may be ignored",
+				path, envelope.Version, JSONEnvelopeVersion)
+		}
+		return envelope.Results, nil
+	}
+
+	var results []SubdomainResult
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON results: %v", err)
+	}
+
+	return results, nil
+}
+
+// parseResultsCSV 解析 CSV 结果（表头与 exportCSV 一致）
+func parseResultsCSV(data []byte) ([]SubdomainResult, error) {
+	records, err := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, utf8BOM))).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CSV results: %v", err)
+	}
+	if len(records) == 0 {
+		return []SubdomainResult{}, nil
+	}
+
+	// 根据表头定位列
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.TrimSpace(name)] = i
+	}
+	if _, ok := columns["subdomain"]; !ok {
+		return nil, fmt.Errorf("CSV results missing subdomain column")
+	}
+
+	field := func(row []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+
+	results := make([]SubdomainResult, 0, len(records)-1)
+	for _, row := range records[1:] {
+		result := SubdomainResult{
+			Subdomain:  field(row, "subdomain"),
+			Title:      field(row, "title"),
+			Source:     field(row, "source"),
+			Time:       field(row, "time"),
+			Provider:   field(row, "provider"),
+			StatusText: field(row, "status_text"),
+			CertIssuer: field(row, "cert_issuer"),
+			CertExpiry: field(row, "cert_expiry"),
+			URL:        field(row, "url"),
+			Server:     field(row, "server"),
+			PoweredBy:  field(row, "powered_by"),
+			TLSVersion: field(row, "tls_version"),
+		}
+		if ips := field(row, "ip"); ips != "" {
+			result.IP = strings.Split(ips, ",")
+		}
+		if cname := field(row, "cname"); cname != "" {
+			result.CNAME = strings.Split(cname, ",")
+		}
+		if technologies := field(row, "technologies"); technologies != "" {
+			result.Technologies = strings.Split(technologies, ",")
+		}
+		result.Ports = parsePorts(field(row, "ports"))
+		result.Status, _ = strconv.Atoi(field(row, "status"))
+		result.Port, _ = strconv.Atoi(field(row, "port"))
+		result.StatusCode, _ = strconv.Atoi(field(row, "status_code"))
+		result.Confidence, _ = strconv.Atoi(field(row, "confidence"))
+		result.Alive, _ = strconv.ParseBool(field(row, "alive"))
+		result.DNSResolved, _ = strconv.ParseBool(field(row, "dns_resolved"))
+		result.PingAlive, _ = strconv.ParseBool(field(row, "ping_alive"))
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// ComputeDiff 按子域名比较基线与本次结果
+func ComputeDiff(baseline, current []SubdomainResult) *ResultDiff {
+	baselineSet := make(map[string]bool)
+	for _, result := range baseline {
+		baselineSet[result.Subdomain] = true
+	}
+	currentSet := make(map[string]bool)
+	for _, result := range current {
+		currentSet[result.Subdomain] = true
+	}
+
+	diff := &ResultDiff{
+		GeneratedAt: time.Now().Format("2006-01-02 15:04:05"),
+		Added:       make([]string, 0),
+		Removed:     make([]string, 0),
+	}
+	for subdomain := range currentSet {
+		if !baselineSet[subdomain] {
+			diff.Added = append(diff.Added, subdomain)
+		}
+	}
+	for subdomain := range baselineSet {
+		if !currentSet[subdomain] {
+			diff.Removed = append(diff.Removed, subdomain)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+
+	return diff
+}
+
+// AddedSinceBaseline 返回与基线相比新出现的子域名结果（按子域名排序），未设置基线时 ok 为 false
+func (o *OutputManager) AddedSinceBaseline() (added []SubdomainResult, ok bool) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.baseline == nil {
+		return nil, false
+	}
+
+	added = make([]SubdomainResult, 0)
+	for _, subdomain := range ComputeDiff(o.baseline, o.results).Added {
+		if i, exists := o.index[subdomain]; exists {
+			added = append(added, o.results[i])
+		}
+	}
+	return added, true
+}
+
+// BaselinePath 返回基线结果文件路径，未设置基线时为空
+func (o *OutputManager) BaselinePath() string {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.baselinePath
+}
+
+// exportDiff 导出与基线的差异报告
+func (o *OutputManager) exportDiff() error {
+	diff := ComputeDiff(o.baseline, o.results)
+	diff.Baseline = o.baselinePath
+
+	diffPath := strings.TrimSuffix(o.outputPath, filepath.Ext(o.outputPath)) + "_diff.json"
+	file, err := os.Create(diffPath)
+	if err != nil {
+		return fmt.Errorf("failed to create diff report: %v", err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(diff); err != nil {
+		return fmt.Errorf("failed to encode diff report: %v", err)
+	}
+
+	logger.Infof("Baseline diff: %d added, %d removed, report saved to %s", len(diff.Added), len(diff.Removed), diffPath)
+	return nil
+}
+
+// parsePorts 解析 formatPorts 生成的端口状态码，格式错误的项会被忽略
+func parsePorts(value string) map[int]int {
+	if value == "" {
+		return nil
+	}
+
+	ports := make(map[int]int)
+	for _, part := range strings.Split(value, ",") {
+		port, status, ok := strings.Cut(part, ":")
+		if !ok {
+			continue
+		}
+		p, err := strconv.Atoi(strings.TrimSpace(port))
+		if err != nil {
+			continue
+		}
+		code, err := strconv.Atoi(strings.TrimSpace(status))
+		if err != nil {
+			continue
+		}
+		ports[p] = code
+	}
+	if len(ports) == 0 {
+		return nil
+	}
+	return ports
+}
@@ -0,0 +1,1965 @@
+package core
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oneforall-go/internal/config"
+	"github.com/oneforall-go/internal/transport"
+	"github.com/oneforall-go/internal/validator"
+)
+
+func TestResultFilter(t *testing.T) {
+	filter, err := NewResultFilter(`\.example\.com$`, `^cdn\.`)
+	if err != nil {
+		t.Fatalf("NewResultFilter failed: %v", err)
+	}
+
+	results := []SubdomainResult{
+		{Subdomain: "www.example.com"},
+		{Subdomain: "cdn.example.com"},
+		{Subdomain: "www.other.com"},
+	}
+
+	filtered := filter.Apply(results)
+	if len(filtered) != 1 || filtered[0].Subdomain != "www.example.com" {
+		t.Errorf("Expected only www.example.com, got %v", filtered)
+	}
+
+	if _, err := NewResultFilter("[", ""); err == nil {
+		t.Error("Expected error for invalid include pattern")
+	}
+
+	var empty *ResultFilter
+	if got := empty.Apply(results); len(got) != len(results) {
+		t.Errorf("Expected nil filter to keep all results, got %d", len(got))
+	}
+}
+
+func TestComputeDiff(t *testing.T) {
+	baseline := []SubdomainResult{{Subdomain: "a.example.com"}, {Subdomain: "b.example.com"}}
+	current := []SubdomainResult{{Subdomain: "b.example.com"}, {Subdomain: "c.example.com"}}
+
+	diff := ComputeDiff(baseline, current)
+	if len(diff.Added) != 1 || diff.Added[0] != "c.example.com" {
+		t.Errorf("Expected added [c.example.com], got %v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0] != "a.example.com" {
+		t.Errorf("Expected removed [a.example.com], got %v", diff.Removed)
+	}
+}
+
+func TestExportElasticsearch(t *testing.T) {
+	saved := esRetryDelay
+	esRetryDelay = 10 * time.Millisecond
+	t.Cleanup(func() { esRetryDelay = saved })
+
+	requests := 0
+	var ids []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		scanner := bufio.NewScanner(r.Body)
+		for line := 0; scanner.Scan(); line++ {
+			if line%2 != 0 {
+				continue
+			}
+			var action map[string]map[string]string
+			if err := json.Unmarshal(scanner.Bytes(), &action); err != nil {
+				t.Errorf("invalid bulk action: %v", err)
+				continue
+			}
+			if action["index"]["_index"] != "recon" {
+				t.Errorf("Expected index recon, got %s", action["index"]["_index"])
+			}
+			ids = append(ids, action["index"]["_id"])
+		}
+		w.Write([]byte(`{"errors":false,"items":[]}`))
+	}))
+	defer server.Close()
+
+	output := NewOutputManager(&config.Config{ESURL: server.URL, ESIndex: "recon"})
+	output.SetFormat("elasticsearch")
+	output.SetOutputPath(filepath.Join(t.TempDir(), "out.elasticsearch"))
+	output.AddResults([]SubdomainResult{{Subdomain: "a.example.com"}, {Subdomain: "b.example.com"}, {Subdomain: "a.example.com"}})
+
+	if err := output.Export(); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected 2 requests (one 429 retry), got %d", requests)
+	}
+	if len(ids) != 2 || ids[0] != "a.example.com" || ids[1] != "b.example.com" {
+		t.Errorf("Expected document IDs [a.example.com b.example.com], got %v", ids)
+	}
+}
+
+func TestExportElasticsearchRetriesThrottledItems(t *testing.T) {
+	saved := esRetryDelay
+	esRetryDelay = 10 * time.Millisecond
+	t.Cleanup(func() { esRetryDelay = saved })
+
+	var batches [][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ids []string
+		scanner := bufio.NewScanner(r.Body)
+		for line := 0; scanner.Scan(); line++ {
+			if line%2 != 0 {
+				continue
+			}
+			var action map[string]map[string]string
+			if err := json.Unmarshal(scanner.Bytes(), &action); err != nil {
+				t.Errorf("invalid bulk action: %v", err)
+				continue
+			}
+			ids = append(ids, action["index"]["_id"])
+		}
+		batches = append(batches, ids)
+
+		// 第一次请求中第二个文档被限流，其余成功
+		if len(batches) == 1 {
+			w.Write([]byte(`{"errors":true,"items":[{"index":{"_id":"a.example.com","status":201}},{"index":{"_id":"b.example.com","status":429}}]}`))
+			return
+		}
+		w.Write([]byte(`{"errors":false,"items":[{"index":{"status":201}}]}`))
+	}))
+	defer server.Close()
+
+	output := NewOutputManager(&config.Config{ESURL: server.URL, ESIndex: "recon"})
+	output.SetFormat("elasticsearch")
+	output.SetOutputPath(filepath.Join(t.TempDir(), "out.elasticsearch"))
+	output.AddResults([]SubdomainResult{{Subdomain: "a.example.com"}, {Subdomain: "b.example.com"}})
+
+	if err := output.Export(); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if filepath.Ext(output.GetOutputPath()) == ".json" {
+		t.Fatal("Expected no JSON fallback when throttled documents succeed on retry")
+	}
+	if len(batches) != 2 || len(batches[1]) != 1 || batches[1][0] != "b.example.com" {
+		t.Errorf("Expected only the throttled document to be retried, got %v", batches)
+	}
+}
+
+func TestExportElasticsearchFallback(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	output := NewOutputManager(&config.Config{ESURL: server.URL, ESIndex: "recon"})
+	output.SetFormat("elasticsearch")
+	output.SetOutputPath(filepath.Join(t.TempDir(), "out.elasticsearch"))
+	output.AddResult(SubdomainResult{Subdomain: "a.example.com"})
+
+	if err := output.Export(); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if filepath.Ext(output.GetOutputPath()) != ".json" {
+		t.Fatalf("Expected JSON fallback path, got %s", output.GetOutputPath())
+	}
+
+	results, err := LoadResults(output.GetOutputPath())
+	if err != nil {
+		t.Fatalf("LoadResults failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Subdomain != "a.example.com" {
+		t.Errorf("Unexpected fallback results: %v", results)
+	}
+}
+
+// stubModule 测试用模块
+type stubModule struct {
+	*BaseModule
+}
+
+func (m *stubModule) Run(domain string) ([]string, error) {
+	return nil, nil
+}
+
+func TestFilterModules(t *testing.T) {
+	cfg := &config.Config{}
+	d := NewDispatcher(cfg)
+	for _, name := range []string{"CrtshQuery", "ShodanAPISearch", "RobtexQuery"} {
+		d.RegisterModule(&stubModule{NewBaseModule(name, ModuleTypeSearch, cfg)})
+	}
+
+	d.FilterModules([]string{"crtshquery", "ShodanAPISearch", "NoSuchModule"}, []string{"ShodanAPISearch"})
+
+	var remaining []string
+	for _, bucket := range d.moduleBuckets() {
+		for _, module := range *bucket {
+			remaining = append(remaining, module.Name())
+		}
+	}
+	if len(remaining) != 1 || remaining[0] != "CrtshQuery" {
+		t.Errorf("Expected [CrtshQuery], got %v", remaining)
+	}
+}
+
+// countingModule 记录 Run 调用次数的测试模块
+type countingModule struct {
+	*BaseModule
+	runs int32
+}
+
+func (m *countingModule) Run(domain string) ([]string, error) {
+	atomic.AddInt32(&m.runs, 1)
+	return []string{strings.ToLower(m.Name()) + "." + domain}, nil
+}
+
+func TestDisabledModules(t *testing.T) {
+	cfg := &config.Config{DisabledModules: []string{"robtexquery"}}
+	d := NewDispatcher(cfg)
+
+	robtex := &countingModule{BaseModule: NewBaseModule("RobtexQuery", ModuleTypeSearch, cfg)}
+	crtsh := &countingModule{BaseModule: NewBaseModule("CrtshQuery", ModuleTypeSearch, cfg)}
+	shodan := &countingModule{BaseModule: NewBaseModule("ShodanAPISearch", ModuleTypeSearch, cfg)}
+	for _, module := range []Module{robtex, crtsh, shodan} {
+		d.RegisterModule(module)
+	}
+
+	// 运行时禁用单个模块
+	d.SetModuleEnabled("ShodanAPISearch", false)
+
+	results, err := d.runModulesWithConcurrency([]Module{robtex, crtsh, shodan}, "example.com", 3, 5*time.Second, false)
+	if err != nil {
+		t.Fatalf("runModulesWithConcurrency failed: %v", err)
+	}
+
+	if n := atomic.LoadInt32(&robtex.runs); n != 0 {
+		t.Errorf("Expected disabled RobtexQuery to never run, ran %d times", n)
+	}
+	if n := atomic.LoadInt32(&shodan.runs); n != 0 {
+		t.Errorf("Expected disabled ShodanAPISearch to never run, ran %d times", n)
+	}
+	if n := atomic.LoadInt32(&crtsh.runs); n != 1 {
+		t.Errorf("Expected CrtshQuery to run once, ran %d times", n)
+	}
+	if len(results) != 1 || results[0] != "crtshquery.example.com" {
+		t.Errorf("Expected only CrtshQuery results, got %v", results)
+	}
+
+	// 重新启用后正常运行
+	d.SetModuleEnabled("shodanapisearch", true)
+	if !shodan.IsEnabled() {
+		t.Errorf("Expected ShodanAPISearch to be re-enabled")
+	}
+}
+
+func TestModuleTimingsRecorded(t *testing.T) {
+	cfg := &config.Config{}
+	d := NewDispatcher(cfg)
+
+	crtsh := &countingModule{BaseModule: NewBaseModule("CrtshQuery", ModuleTypeSearch, cfg)}
+	robtex := &countingModule{BaseModule: NewBaseModule("RobtexQuery", ModuleTypeSearch, cfg)}
+	shodan := &countingModule{BaseModule: NewBaseModule("ShodanAPISearch", ModuleTypeSearch, cfg)}
+	shodan.SetEnabled(false)
+
+	// 两个域名各运行一次，耗时和结果数累加
+	for _, domain := range []string{"example.com", "example.net"} {
+		if _, err := d.runModulesWithConcurrency([]Module{crtsh, robtex, shodan}, domain, 3, 5*time.Second, false); err != nil {
+			t.Fatalf("runModulesWithConcurrency failed: %v", err)
+		}
+	}
+
+	timings := d.ModuleTimings()
+	if len(timings) != 2 {
+		t.Fatalf("Expected timings for 2 executed modules, got %v", timings)
+	}
+	for _, timing := range timings {
+		if timing.Module != "CrtshQuery" && timing.Module != "RobtexQuery" {
+			t.Errorf("Unexpected timing for %s", timing.Module)
+		}
+		if timing.Runs != 2 || timing.Results != 2 || timing.Errors != 0 {
+			t.Errorf("Expected 2 runs with 2 results for %s, got %+v", timing.Module, timing)
+		}
+	}
+	if timings[0].Duration < timings[1].Duration {
+		t.Errorf("Expected timings sorted slowest first, got %v", timings)
+	}
+
+	// 共享收集器的调度器合并统计
+	worker := NewDispatcher(cfg)
+	worker.SetTimingCollector(d.TimingCollector())
+	if _, err := worker.runModulesWithConcurrency([]Module{crtsh}, "example.org", 1, 5*time.Second, false); err != nil {
+		t.Fatalf("runModulesWithConcurrency failed: %v", err)
+	}
+	for _, timing := range d.ModuleTimings() {
+		if timing.Module == "CrtshQuery" && timing.Runs != 3 {
+			t.Errorf("Expected shared collector to count 3 runs, got %d", timing.Runs)
+		}
+	}
+}
+
+func TestSleepJitterWithinRange(t *testing.T) {
+	cfg := &config.Config{RequestDelayMin: 5 * time.Millisecond, RequestDelayMax: 15 * time.Millisecond}
+	module := NewBaseModule("Test", ModuleTypeSearch, cfg)
+
+	// 每次取值都在配置范围内，且不是固定值
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 200; i++ {
+		delay := module.nextDelay()
+		if delay < cfg.RequestDelayMin || delay > cfg.RequestDelayMax {
+			t.Fatalf("Expected delay within [%v, %v], got %v", cfg.RequestDelayMin, cfg.RequestDelayMax, delay)
+		}
+		seen[delay] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("Expected delays to vary per call, got %v", seen)
+	}
+
+	for i := 0; i < 3; i++ {
+		start := time.Now()
+		module.Sleep()
+		if elapsed := time.Since(start); elapsed < cfg.RequestDelayMin || elapsed > cfg.RequestDelayMax+50*time.Millisecond {
+			t.Errorf("Expected Sleep to take between %v and %v, took %v", cfg.RequestDelayMin, cfg.RequestDelayMax, elapsed)
+		}
+	}
+
+	// 范围为 0 时不等待
+	module.SetDelayRange(0, 0)
+	start := time.Now()
+	module.Sleep()
+	if elapsed := time.Since(start); elapsed > 5*time.Millisecond {
+		t.Errorf("Expected zero delay to return immediately, took %v", elapsed)
+	}
+}
+
+func TestHTTPGetRetryBackoff(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	module := NewBaseModule("Test", ModuleTypeSearch, &config.Config{DNSResolveTimeout: 5})
+	module.SetDelay(0)
+	module.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: 20 * time.Millisecond, MaxDelay: time.Second})
+
+	start := time.Now()
+	resp, err := module.HTTPGet(server.URL, nil)
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatalf("HTTPGet failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || requests != 3 {
+		t.Errorf("Expected 200 after 3 requests, got %d after %d", resp.StatusCode, requests)
+	}
+	// 两次退避：20ms + 40ms，各自最多再加 50% 抖动
+	if elapsed < 60*time.Millisecond || elapsed > 500*time.Millisecond {
+		t.Errorf("Expected backoff between 60ms and 500ms, got %v", elapsed)
+	}
+}
+
+func TestHTTPGetRetryAfterClamped(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	module := NewBaseModule("Test", ModuleTypeSearch, &config.Config{DNSResolveTimeout: 5})
+	module.SetDelay(0)
+	module.SetRetryPolicy(RetryPolicy{MaxAttempts: 2, BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond})
+
+	start := time.Now()
+	resp, err := module.HTTPGet(server.URL, nil)
+	if err != nil {
+		t.Fatalf("HTTPGet failed: %v", err)
+	}
+	resp.Body.Close()
+
+	// Retry-After: 3600 被限制为 MaxDelay
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected Retry-After to be clamped to MaxDelay, waited %v", elapsed)
+	}
+	if resp.StatusCode != http.StatusOK || requests != 2 {
+		t.Errorf("Expected 200 after 2 requests, got %d after %d", resp.StatusCode, requests)
+	}
+}
+
+func TestHTTPGetNoRetryOnRedirect(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusMovedPermanently)
+	}))
+	defer server.Close()
+
+	module := NewBaseModule("Test", ModuleTypeSearch, &config.Config{DNSResolveTimeout: 5})
+	module.SetDelay(0)
+
+	resp, err := module.HTTPGet(server.URL, nil)
+	if err != nil {
+		t.Fatalf("HTTPGet failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMovedPermanently || requests != 1 {
+		t.Errorf("Expected a single 301 response, got %d after %d requests", resp.StatusCode, requests)
+	}
+}
+
+func TestCustomHeaders(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	module := NewBaseModule("Test", ModuleTypeSearch, &config.Config{
+		DNSResolveTimeout: 5,
+		UserAgent:         "recon-bot/1.0",
+		ExtraHeaders:      map[string]string{"proxy-authorization": "Basic dGVzdA==", "X-Api-Key": "default"},
+	})
+	module.SetDelay(0)
+
+	resp, err := module.HTTPGet(server.URL, map[string]string{"X-API-Key": "module"})
+	if err != nil {
+		t.Fatalf("HTTPGet failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if got.Get("User-Agent") != "recon-bot/1.0" {
+		t.Errorf("Expected custom User-Agent, got %q", got.Get("User-Agent"))
+	}
+	if got.Get("Proxy-Authorization") != "Basic dGVzdA==" {
+		t.Errorf("Expected extra header to be sent, got %q", got.Get("Proxy-Authorization"))
+	}
+	if values := got.Values("X-Api-Key"); len(values) != 1 || values[0] != "module" {
+		t.Errorf("Expected module header to take precedence, got %v", values)
+	}
+	if module.GetRandomUserAgent() != "recon-bot/1.0" {
+		t.Errorf("Expected GetRandomUserAgent to return the configured User-Agent")
+	}
+
+	resp, err = module.HTTPPostJSON(server.URL, map[string]string{"q": "x"}, nil)
+	if err != nil {
+		t.Fatalf("HTTPPostJSON failed: %v", err)
+	}
+	resp.Body.Close()
+	if got.Get("Content-Type") != "application/json" || got.Get("Proxy-Authorization") == "" {
+		t.Errorf("Expected default and extra headers on POST, got %v", got)
+	}
+}
+
+func TestReadResponseBodyGzip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte("api.example.com,www.example.com"))
+		gz.Close()
+	}))
+	defer server.Close()
+
+	module := NewBaseModule("Test", ModuleTypeSearch, &config.Config{DNSResolveTimeout: 5})
+	module.SetDelay(0)
+
+	// 手动设置 Accept-Encoding 时 http.Transport 不会自动解压
+	resp, err := module.HTTPGet(server.URL, map[string]string{"Accept-Encoding": "gzip, deflate"})
+	if err != nil {
+		t.Fatalf("HTTPGet failed: %v", err)
+	}
+	body, err := module.ReadResponseBody(resp)
+	if err != nil {
+		t.Fatalf("ReadResponseBody failed: %v", err)
+	}
+	if body != "api.example.com,www.example.com" {
+		t.Errorf("Expected decoded body, got %q", body)
+	}
+}
+
+func TestReadResponseBodyLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("a", 2048)))
+	}))
+	defer server.Close()
+
+	module := NewBaseModule("Test", ModuleTypeSearch, &config.Config{DNSResolveTimeout: 5, MaxResponseBody: 1024})
+	module.SetDelay(0)
+
+	resp, err := module.HTTPGet(server.URL, nil)
+	if err != nil {
+		t.Fatalf("HTTPGet failed: %v", err)
+	}
+	if _, err := module.ReadResponseBody(resp); err == nil || !strings.Contains(err.Error(), "exceeds limit") {
+		t.Errorf("Expected limit error for oversized body, got %v", err)
+	}
+
+	// 恰好等于上限时正常返回
+	module = NewBaseModule("Test", ModuleTypeSearch, &config.Config{DNSResolveTimeout: 5, MaxResponseBody: 2048})
+	module.SetDelay(0)
+	resp, err = module.HTTPGet(server.URL, nil)
+	if err != nil {
+		t.Fatalf("HTTPGet failed: %v", err)
+	}
+	if body, err := module.ReadResponseBody(resp); err != nil || len(body) != 2048 {
+		t.Errorf("Expected full 2048-byte body, got %d bytes, err %v", len(body), err)
+	}
+}
+
+func TestExportAliveOnly(t *testing.T) {
+	output := NewOutputManager(&config.Config{ExportAliveOnly: true})
+	output.SetFormat("json")
+	output.SetOutputPath(filepath.Join(t.TempDir(), "out.json"))
+	output.AddResults([]SubdomainResult{
+		{Subdomain: "alive.example.com", Alive: true},
+		{Subdomain: "dead.example.com", Alive: false},
+	})
+
+	if err := output.Export(); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	written, err := LoadResults(output.GetOutputPath())
+	if err != nil {
+		t.Fatalf("LoadResults failed: %v", err)
+	}
+	if len(written) != 1 || written[0].Subdomain != "alive.example.com" {
+		t.Errorf("Expected only alive.example.com in file, got %v", written)
+	}
+
+	stats := output.GetStats()
+	if stats["total"] != 2 || stats["dead"] != 1 {
+		t.Errorf("Expected stats to count 2 total and 1 dead, got %v", stats)
+	}
+}
+
+func TestExportDiscoveryOnly(t *testing.T) {
+	// 只导出存活结果的配置在未验证时不生效，否则会得到空文件
+	dir := t.TempDir()
+	output := NewOutputManager(&config.Config{ExportAliveOnly: true, MinConfidence: 50})
+	output.SetDiscoveryOnly(true)
+	output.SetFormat("csv")
+	output.SetOutputPath(filepath.Join(dir, "out.csv"))
+	output.AddResults([]SubdomainResult{
+		{Subdomain: "www.example.com", Source: "crtsh", Time: "2024-01-01 12:00:00"},
+		{Subdomain: "api.example.com", Source: "securitytrails", Time: "2024-01-01 12:00:01"},
+		{Subdomain: "www.example.com", Source: "fofa", Time: "2024-01-01 12:00:02"},
+		{Subdomain: "dev.example.com", Source: "brute", Time: "2024-01-01 12:00:03"},
+	})
+
+	if err := output.Export(); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	data, err := os.ReadFile(output.GetOutputPath())
+	if err != nil {
+		t.Fatalf("Failed to read CSV file: %v", err)
+	}
+	expected := "subdomain,source,time\n" +
+		"api.example.com,securitytrails,2024-01-01 12:00:01\n" +
+		"dev.example.com,brute,2024-01-01 12:00:03\n" +
+		"www.example.com,\"crtsh,fofa\",2024-01-01 12:00:00\n"
+	if string(data) != expected {
+		t.Errorf("Expected discovery-only CSV:\n%s\ngot:\n%s", expected, data)
+	}
+
+	stats := output.GetStats()
+	if stats["total"] != 3 || stats["unvalidated"] != 3 || stats["alive"] != 0 || stats["dead"] != 0 {
+		t.Errorf("Expected 3 unvalidated and no dead results, got %v", stats)
+	}
+	if reasons := stats["dead_reasons"].(map[string]int); len(reasons) != 0 {
+		t.Errorf("Expected no dead reasons, got %v", reasons)
+	}
+
+	// JSON 数组只包含子域名、来源和时间
+	output.SetFormat("json")
+	output.SetOutputPath(filepath.Join(dir, "out.json"))
+	if err := output.Export(); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	data, err = os.ReadFile(output.GetOutputPath())
+	if err != nil {
+		t.Fatalf("Failed to read JSON file: %v", err)
+	}
+	var discovered []map[string]interface{}
+	if err := json.Unmarshal(data, &discovered); err != nil {
+		t.Fatalf("Failed to parse JSON file: %v", err)
+	}
+	if len(discovered) != 3 {
+		t.Fatalf("Expected all 3 hosts, got %v", discovered)
+	}
+	for _, result := range discovered {
+		if _, ok := result["alive"]; ok || len(result) != 3 {
+			t.Errorf("Expected only subdomain, source and time, got %v", result)
+		}
+	}
+}
+
+func TestExportTXT(t *testing.T) {
+	results := []SubdomainResult{
+		{Subdomain: "www.example.com", Alive: true},
+		{Subdomain: "api.example.com", Alive: true},
+		{Subdomain: "dead.example.com", Alive: false},
+	}
+
+	for _, tc := range []struct {
+		withScheme bool
+		expected   string
+	}{
+		{false, "api.example.com\nwww.example.com\n"},
+		{true, "https://api.example.com\nhttps://www.example.com\n"},
+	} {
+		output := NewOutputManager(&config.Config{ExportAliveOnly: true, TxtWithScheme: tc.withScheme})
+		output.SetFormat("txt")
+		output.SetOutputPath(filepath.Join(t.TempDir(), "out.txt"))
+		output.AddResults(results)
+
+		if err := output.Export(); err != nil {
+			t.Fatalf("Export failed: %v", err)
+		}
+
+		// 每行一个存活主机，没有表头
+		data, err := os.ReadFile(output.GetOutputPath())
+		if err != nil {
+			t.Fatalf("Failed to read TXT file: %v", err)
+		}
+		if string(data) != tc.expected {
+			t.Errorf("withScheme=%v: expected %q, got %q", tc.withScheme, tc.expected, string(data))
+		}
+	}
+}
+
+func TestExportIPJSON(t *testing.T) {
+	output := NewOutputManager(&config.Config{})
+	output.SetFormat("ipjson")
+	output.SetOutputPath(filepath.Join(t.TempDir(), "out.ipjson"))
+	output.AddResults([]SubdomainResult{
+		{Subdomain: "b.ex.cn", IP: []string{"192.0.2.1"}},
+		{Subdomain: "a.ex.cn", IP: []string{"192.0.2.1", "192.0.2.2"}},
+		{Subdomain: "c.ex.cn", IP: []string{"192.0.2.2"}},
+		{Subdomain: "noip.ex.cn"},
+	})
+
+	if err := output.Export(); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	data, err := os.ReadFile(output.GetOutputPath())
+	if err != nil {
+		t.Fatalf("Failed to read IP JSON file: %v", err)
+	}
+	var groups map[string][]string
+	if err := json.Unmarshal(data, &groups); err != nil {
+		t.Fatalf("Failed to parse IP JSON: %v", err)
+	}
+
+	// 多 IP 的子域名出现在每个 IP 下，没有 IP 的子域名不出现
+	expected := map[string]string{
+		"192.0.2.1": "a.ex.cn,b.ex.cn",
+		"192.0.2.2": "a.ex.cn,c.ex.cn",
+	}
+	if len(groups) != len(expected) {
+		t.Fatalf("Expected %d IPs, got %v", len(expected), groups)
+	}
+	for ip, subdomains := range expected {
+		if got := strings.Join(groups[ip], ","); got != subdomains {
+			t.Errorf("Expected %s for %s, got %s", subdomains, ip, got)
+		}
+	}
+}
+
+func TestDeadReasons(t *testing.T) {
+	output := NewOutputManager(&config.Config{ExportAliveOnly: true, ExportDeadOnly: true})
+	output.SetFormat("csv")
+	output.SetOutputPath(filepath.Join(t.TempDir(), "out.csv"))
+	output.AddResults([]SubdomainResult{
+		{Subdomain: "alive.example.com", Alive: true, StatusText: "Alive"},
+		{Subdomain: "nx1.example.com", StatusCode: -1, StatusText: "DNS Resolution Failed"},
+		{Subdomain: "nx2.example.com", StatusCode: -1, StatusText: "DNS Resolution Failed"},
+		{Subdomain: "down.example.com", StatusText: "Ping Failed"},
+		{Subdomain: "raw.example.com"},
+	})
+
+	reasons, ok := output.GetStats()["dead_reasons"].(map[string]int)
+	if !ok {
+		t.Fatalf("Expected dead_reasons in stats")
+	}
+	expected := map[string]int{"DNS Resolution Failed": 2, "Ping Failed": 1, "Not Validated": 1}
+	if len(reasons) != len(expected) {
+		t.Errorf("Expected %v, got %v", expected, reasons)
+	}
+	for reason, count := range expected {
+		if reasons[reason] != count {
+			t.Errorf("Expected %d results for %q, got %d", count, reason, reasons[reason])
+		}
+	}
+
+	// 同时设置时 dead-only 优先
+	if err := output.Export(); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	written, err := LoadResults(output.GetOutputPath())
+	if err != nil {
+		t.Fatalf("LoadResults failed: %v", err)
+	}
+	if len(written) != 4 {
+		t.Fatalf("Expected 4 dead results in file, got %v", written)
+	}
+	for _, result := range written {
+		if result.Alive {
+			t.Errorf("Expected only dead results in file, got %s", result.Subdomain)
+		}
+		if result.Subdomain == "nx1.example.com" && result.StatusText != "DNS Resolution Failed" {
+			t.Errorf("Expected failure reason to be exported, got %q", result.StatusText)
+		}
+	}
+}
+
+func TestCertNamesInScope(t *testing.T) {
+	validationResults := []validator.ValidationResult{
+		{Subdomain: "www.example.com", CertNames: []string{"api.example.com", "mail.example.com", "cdn.other.net"}},
+		{Subdomain: "mail.example.com", CertNames: []string{"API.example.com.", "example.com.evil.net", "dev.example.com"}},
+	}
+
+	names := certNamesInScope("example.com", validationResults)
+	expected := []string{"api.example.com", "dev.example.com"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, names)
+	}
+}
+
+func TestExpandOutputTemplate(t *testing.T) {
+	now := time.Date(2024, 3, 5, 14, 7, 9, 0, time.UTC)
+	cases := map[string]string{
+		config.DefaultOutputTemplate:      "example.com_20240305_140709.csv",
+		"{domain}/{date}/results.{ext}":   "example.com/20240305/results.csv",
+		"{date}-{time}/{domain}.{format}": "20240305-140709/example.com.csv",
+		"static.csv":                      "static.csv",
+	}
+	for template, want := range cases {
+		if got := expandOutputTemplate(template, "example.com", "csv", now); got != want {
+			t.Errorf("expandOutputTemplate(%q) = %q, want %q", template, got, want)
+		}
+	}
+}
+
+func TestExportOutputTemplate(t *testing.T) {
+	dir := t.TempDir()
+	output := NewOutputManager(&config.Config{ResultSavePath: dir, OutputTemplate: "{domain}/{date}/results.{ext}"})
+	output.SetFormat("json")
+	output.AddResult(SubdomainResult{Subdomain: "www.example.com"})
+
+	if err := output.Export(); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	want := filepath.Join(dir, "example.com", time.Now().Format("20060102"), "results.json")
+	if output.GetOutputPath() != want {
+		t.Errorf("Expected output path %s, got %s", want, output.GetOutputPath())
+	}
+	if _, err := os.Stat(want); err != nil {
+		t.Errorf("Expected output file to be created: %v", err)
+	}
+}
+
+func TestPerDomainOutputIndex(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{ResultSaveFormat: "json", ResultSavePath: dir, OutputTemplate: "{domain}_{date}.{ext}", PerDomainOutput: true}
+	output := NewOutputManager(cfg)
+
+	exportDomain := func(domain string, results ...SubdomainResult) *OutputManager {
+		domainOutput := output.ForDomain(domain)
+		domainOutput.AddResults(results)
+		if err := domainOutput.Export(); err != nil {
+			t.Fatalf("Export for %s failed: %v", domain, err)
+		}
+		return domainOutput
+	}
+
+	// 每导出一个域名，索引立即更新
+	first := exportDomain("example.com", SubdomainResult{Subdomain: "www.example.com", Alive: true}, SubdomainResult{Subdomain: "dev.example.com"})
+	want := filepath.Join(dir, "example.com", "example.com_"+time.Now().Format("20060102")+".json")
+	if first.GetOutputPath() != want {
+		t.Errorf("Expected output path %s, got %s", want, first.GetOutputPath())
+	}
+	index, err := LoadIndex(IndexPath(dir))
+	if err != nil {
+		t.Fatalf("LoadIndex failed: %v", err)
+	}
+	if len(index.Domains) != 1 {
+		t.Fatalf("Expected 1 indexed domain, got %+v", index.Domains)
+	}
+	entry := index.Domains[0]
+	if entry.Domain != "example.com" || entry.Total != 2 || entry.Alive != 1 || entry.Format != "json" || entry.UpdatedAt == "" {
+		t.Errorf("Unexpected index entry %+v", entry)
+	}
+	if entry.Path != "example.com/example.com_"+time.Now().Format("20060102")+".json" {
+		t.Errorf("Expected path relative to the results directory, got %s", entry.Path)
+	}
+
+	exportDomain("example.net", SubdomainResult{Subdomain: "api.example.net"})
+	if index, err = LoadIndex(IndexPath(dir)); err != nil {
+		t.Fatalf("LoadIndex failed: %v", err)
+	}
+	if len(index.Domains) != 2 || index.Domains[0].Domain != "example.com" || index.Domains[1].Domain != "example.net" {
+		t.Fatalf("Expected example.com and example.net indexed, got %+v", index.Domains)
+	}
+
+	// 重新导出同一域名时替换原记录
+	exportDomain("example.com", SubdomainResult{Subdomain: "www.example.com"})
+	if index, err = LoadIndex(IndexPath(dir)); err != nil {
+		t.Fatalf("LoadIndex failed: %v", err)
+	}
+	if len(index.Domains) != 2 || index.Domains[0].Total != 1 {
+		t.Errorf("Expected the example.com entry to be replaced, got %+v", index.Domains)
+	}
+}
+
+func TestOutputManagerConcurrent(t *testing.T) {
+	output := NewOutputManager(&config.Config{ResultSaveFormat: "json", ResultSavePath: t.TempDir()})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(4)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				output.AddResult(SubdomainResult{Subdomain: fmt.Sprintf("a%d-%d.ex.cn", i, j), Alive: j%2 == 0})
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			output.AddResults([]SubdomainResult{{Subdomain: fmt.Sprintf("b%d.ex.cn", i)}})
+			output.AddValidationResults([]validator.ValidationResult{{Subdomain: fmt.Sprintf("v%d.ex.cn", i), Alive: true}})
+			output.AddRelatedDomains([]string{fmt.Sprintf("related%d.org", i)})
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				output.GetStats()
+				output.FilterAlive()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				output.GetResults()
+				output.GetRelatedDomains()
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := output.GetStats()
+	if stats["total"] != 8*52 {
+		t.Errorf("Expected %d results, got %v", 8*52, stats["total"])
+	}
+	if stats["related"] != 8 {
+		t.Errorf("Expected 8 related domains, got %v", stats["related"])
+	}
+	if err := output.Export(); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+}
+
+func TestAddResultDeduplicatesConcurrently(t *testing.T) {
+	output := NewOutputManager(&config.Config{})
+
+	// 多个模块并发报告同一子域名，只保留一条结果并合并来源和 IP
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				output.AddResult(SubdomainResult{
+					Subdomain: "www.ex.cn",
+					Source:    fmt.Sprintf("module%d", i%4),
+					IP:        []string{fmt.Sprintf("192.0.2.%d", j%3)},
+				})
+			}
+			output.AddValidationResults([]validator.ValidationResult{{Subdomain: "www.ex.cn", Source: "validator", Alive: true, StatusText: "Alive"}})
+		}(i)
+	}
+	wg.Wait()
+
+	results := output.GetResults()
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(results))
+	}
+	result := results[0]
+	if len(SplitSources(result.Source)) != 5 {
+		t.Errorf("Expected 5 merged sources, got %q", result.Source)
+	}
+	if len(result.IP) != 3 {
+		t.Errorf("Expected 3 merged IPs, got %v", result.IP)
+	}
+	if !result.Alive || result.StatusText != "Alive" {
+		t.Errorf("Expected merged validation status, got %+v", result)
+	}
+
+	// Reset 后可用于下一个域名
+	output.Reset()
+	output.AddResult(SubdomainResult{Subdomain: "www.ex.cn", Source: "search"})
+	if results := output.GetResults(); len(results) != 1 || results[0].Source != "search" || results[0].Alive {
+		t.Errorf("Expected a fresh result after Reset, got %+v", results)
+	}
+}
+
+func TestDeduplicateMerge(t *testing.T) {
+	output := NewOutputManager(&config.Config{})
+	output.AddResults([]SubdomainResult{
+		{Subdomain: "api.ex.cn", IP: []string{"192.0.2.1"}, Source: "search", DNSResolved: true},
+		{Subdomain: "www.ex.cn", Source: "search"},
+		{Subdomain: "api.ex.cn", IP: []string{"192.0.2.1", "192.0.2.2"}, Source: "certificate", Alive: true, StatusCode: 200, Title: "API"},
+		{Subdomain: "api.ex.cn", Source: "search"},
+	})
+
+	output.Deduplicate()
+
+	results := output.GetResults()
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results after merge, got %d: %v", len(results), results)
+	}
+
+	api := results[0]
+	if api.Subdomain != "api.ex.cn" {
+		t.Fatalf("Expected first result to be api.ex.cn, got %s", api.Subdomain)
+	}
+	if strings.Join(api.IP, ",") != "192.0.2.1,192.0.2.2" {
+		t.Errorf("Expected merged IPs, got %v", api.IP)
+	}
+	if api.Source != "search,certificate" {
+		t.Errorf("Expected combined sources, got %q", api.Source)
+	}
+	if !api.Alive || !api.DNSResolved || api.StatusCode != 200 || api.Title != "API" {
+		t.Errorf("Expected alive status to be kept, got %+v", api)
+	}
+}
+
+func TestDeduplicateWithBloomFilter(t *testing.T) {
+	// 阈值为 1 时走布隆过滤器预筛选，去重结果仍应精确
+	output := NewOutputManager(&config.Config{DedupBloomThreshold: 1, DedupFalsePositiveRate: 0.01})
+	for i := 0; i < 1000; i++ {
+		output.results = append(output.results, SubdomainResult{Subdomain: fmt.Sprintf("h%d.ex.cn", i%500), Source: fmt.Sprintf("m%d", i/500)})
+	}
+
+	output.Deduplicate()
+
+	results := output.GetResults()
+	if len(results) != 500 {
+		t.Fatalf("Expected 500 results after merge, got %d", len(results))
+	}
+	for _, result := range results {
+		if result.Source != "m0,m1" {
+			t.Fatalf("Expected merged sources for %s, got %q", result.Subdomain, result.Source)
+		}
+	}
+
+	// 索引在预筛选后重建，之后添加的重复结果仍会合并
+	output.AddResult(SubdomainResult{Subdomain: "h1.ex.cn", Source: "m2"})
+	if n := len(output.GetResults()); n != 500 {
+		t.Errorf("Expected 500 results after adding a duplicate, got %d", n)
+	}
+}
+
+func TestExportSortedOutput(t *testing.T) {
+	inputs := [][]string{
+		{"b.ex.cn", "a.ex.com", "x.a.ex.cn", "ex.cn", "a.ex.cn"},
+		{"a.ex.cn", "ex.cn", "b.ex.cn", "x.a.ex.cn", "a.ex.com"},
+	}
+	expected := []string{"ex.cn", "a.ex.cn", "x.a.ex.cn", "b.ex.cn", "a.ex.com"}
+
+	var contents []string
+	for i, input := range inputs {
+		output := NewOutputManager(&config.Config{})
+		output.SetFormat("json")
+		output.SetOutputPath(filepath.Join(t.TempDir(), "out.json"))
+		for _, subdomain := range input {
+			output.AddResult(SubdomainResult{Subdomain: subdomain, Source: "search", Time: "2024-01-01 00:00:00"})
+		}
+
+		if err := output.Export(); err != nil {
+			t.Fatalf("Export failed: %v", err)
+		}
+
+		var got []string
+		for _, result := range output.GetResults() {
+			got = append(got, result.Subdomain)
+		}
+		if strings.Join(got, ",") != strings.Join(expected, ",") {
+			t.Errorf("run %d: expected order %v, got %v", i, expected, got)
+		}
+
+		data, err := os.ReadFile(output.GetOutputPath())
+		if err != nil {
+			t.Fatalf("read output: %v", err)
+		}
+		contents = append(contents, string(data))
+	}
+
+	if contents[0] != contents[1] {
+		t.Errorf("Expected identical output for different input order")
+	}
+}
+
+func TestInScope(t *testing.T) {
+	cases := []struct {
+		host, domain string
+		want         bool
+	}{
+		{"www.example.com", "example.com", true},
+		{"WWW.Example.COM.", "example.com", true},
+		{"example.com", "example.com", true},
+		{"a.b.example.co.uk", "example.co.uk", true},
+		{"_dmarc.example.com", "example.com", true},
+		{"evilexample.com", "example.com", false},
+		{"notexample.com", "example.com", false},
+		{"example.com.attacker.net", "example.com", false},
+		{"www.example.com.attacker.net", "example.com", false},
+		{"other.co.uk", "co.uk", false},
+		{"a..example.com", "example.com", false},
+		{"*.example.com", "example.com", false},
+		{"www.example.com/path", "example.com", false},
+	}
+
+	for _, c := range cases {
+		if got := InScope(c.host, c.domain); got != c.want {
+			t.Errorf("InScope(%q, %q) = %t, want %t", c.host, c.domain, got, c.want)
+		}
+	}
+}
+
+func TestExtractSubdomainsBoundary(t *testing.T) {
+	module := NewBaseModule("Test", ModuleTypeSearch, &config.Config{})
+	text := `<a href="https://api.example.com/x">api</a> mail.example.com.attacker.net WWW.EXAMPLE.COM. evilexample.com`
+
+	subdomains := module.ExtractSubdomains(text, "example.com")
+	if len(subdomains) != 2 || subdomains[0] != "api.example.com" || subdomains[1] != "www.example.com" {
+		t.Errorf("Expected [api.example.com www.example.com], got %v", subdomains)
+	}
+}
+
+// relatedModule 运行时报告关联域名的测试模块
+type relatedModule struct {
+	*BaseModule
+	names []string
+}
+
+func (m *relatedModule) Run(domain string) ([]string, error) {
+	for _, name := range m.names {
+		m.AddRelatedDomain(name)
+	}
+	return []string{"www." + domain}, nil
+}
+
+func TestRelatedDomains(t *testing.T) {
+	cfg := &config.Config{}
+	d := NewDispatcher(cfg)
+	module := &relatedModule{
+		BaseModule: NewBaseModule("CertInfo", ModuleTypeCheck, cfg),
+		names:      []string{"*.example.com", "api.example.net", "www.example.net", "shop.example.co.uk", "mail.example.com"},
+	}
+
+	if _, err := d.runModule(module, "example.com"); err != nil {
+		t.Fatalf("runModule failed: %v", err)
+	}
+
+	related := d.RelatedDomains("example.com")
+	if len(related) != 2 || related[0] != "example.co.uk" || related[1] != "example.net" {
+		t.Errorf("Expected [example.co.uk example.net], got %v", related)
+	}
+	if len(module.TakeRelatedDomains()) != 0 {
+		t.Error("Expected module related domains to be drained after collection")
+	}
+
+	output := NewOutputManager(cfg)
+	output.SetFormat("json")
+	output.SetOutputPath(filepath.Join(t.TempDir(), "out.json"))
+	output.AddResult(SubdomainResult{Subdomain: "www.example.com"})
+	output.AddRelatedDomains(related)
+	if err := output.Export(); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	data, err := os.ReadFile(strings.TrimSuffix(output.GetOutputPath(), ".json") + "_related.txt")
+	if err != nil {
+		t.Fatalf("Expected related file: %v", err)
+	}
+	if string(data) != "example.co.uk\nexample.net\n" {
+		t.Errorf("Unexpected related file content: %q", data)
+	}
+}
+
+// taggedModule 为部分结果指定来源标记的测试模块
+type taggedModule struct {
+	*BaseModule
+}
+
+func (m *taggedModule) Run(domain string) ([]string, error) {
+	m.AddSubdomain("www." + domain)
+	m.AddTaggedSubdomain("legacy."+domain, "securitytrails_history")
+	return m.GetSubdomains(), nil
+}
+
+func TestSourceTags(t *testing.T) {
+	cfg := &config.Config{}
+	d := NewDispatcher(cfg)
+	module := &taggedModule{NewBaseModule("SecurityTrailsAPIQuery", ModuleTypeSearch, cfg)}
+
+	var mutex sync.Mutex
+	sources := make(map[string]string)
+	d.SetResultHandler(func(result SubdomainResult) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		sources[result.Subdomain] = result.Source
+	})
+
+	if _, err := d.runModulesWithConcurrency([]Module{module}, "example.com", 1, 5*time.Second, false); err != nil {
+		t.Fatalf("runModulesWithConcurrency failed: %v", err)
+	}
+
+	if sources["legacy.example.com"] != "securitytrails_history" {
+		t.Errorf("Expected tagged source for legacy.example.com, got %q", sources["legacy.example.com"])
+	}
+	if sources["www.example.com"] != string(ModuleTypeSearch) {
+		t.Errorf("Expected step source for www.example.com, got %q", sources["www.example.com"])
+	}
+	if got := d.sourceFor("other.com", "legacy.example.com", "search"); got != "search" {
+		t.Errorf("Expected tags to be scoped per domain, got %q", got)
+	}
+}
+
+// countConnections 统计服务器上新建的连接数
+func countConnections(server *httptest.Server) *int32 {
+	var conns int32
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	return &conns
+}
+
+// runModuleRequests 每个模块依次请求一次
+func runModuleRequests(tb testing.TB, modules []*BaseModule, url string) {
+	for _, module := range modules {
+		resp, err := module.HTTPGet(url, nil)
+		if err != nil {
+			tb.Fatalf("HTTPGet failed: %v", err)
+		}
+		module.ReadResponseBody(resp)
+	}
+}
+
+func TestSharedTransport(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	conns := countConnections(server)
+	// 自签名证书，使用不校验证书的检查模块
+	server.StartTLS()
+	defer server.Close()
+
+	cfg := &config.Config{DNSResolveTimeout: 5, MaxResponseBody: 1 << 20}
+	d := NewDispatcher(cfg)
+
+	var modules []*BaseModule
+	for i := 0; i < 10; i++ {
+		module := NewBaseModule(fmt.Sprintf("Module%d", i), ModuleTypeCheck, cfg)
+		module.SetDelay(0)
+		module.SetRetryPolicy(RetryPolicy{MaxAttempts: 1})
+		d.RegisterModule(&stubModule{module})
+		modules = append(modules, module)
+	}
+
+	runModuleRequests(t, modules, server.URL)
+	if n := atomic.LoadInt32(conns); n != 1 {
+		t.Errorf("Expected modules to share 1 connection, got %d", n)
+	}
+
+	// 设置代理的模块使用共享 Transport 的副本，不影响其他模块
+	if err := modules[0].SetProxy("http://127.0.0.1:1"); err != nil {
+		t.Fatalf("SetProxy failed: %v", err)
+	}
+	if modules[0].httpClient.Transport == d.transports.Insecure {
+		t.Errorf("Expected proxied module to use a cloned transport")
+	}
+	if d.transports.Insecure.Proxy != nil {
+		t.Errorf("Expected shared transport to stay without proxy")
+	}
+	if _, err := modules[0].HTTPGet(server.URL, nil); err == nil {
+		t.Errorf("Expected request through unreachable proxy to fail")
+	}
+	runModuleRequests(t, modules[1:], server.URL)
+	if n := atomic.LoadInt32(conns); n != 1 {
+		t.Errorf("Expected other modules to keep sharing 1 connection, got %d", n)
+	}
+}
+
+func TestModuleTLSVerification(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{DNSResolveTimeout: 5, MaxResponseBody: 1 << 20, InsecureModules: []string{"mirrorapi"}}
+	d := NewDispatcher(cfg)
+	newModule := func(name string, moduleType ModuleType) *BaseModule {
+		module := NewBaseModule(name, moduleType, cfg)
+		module.SetDelay(0)
+		module.SetRetryPolicy(RetryPolicy{MaxAttempts: 1})
+		d.RegisterModule(&stubModule{module})
+		return module
+	}
+
+	tests := []struct {
+		module   *BaseModule
+		insecure bool
+	}{
+		// API 模块默认校验证书，拒绝自签名证书
+		{newModule("SomeAPI", ModuleTypeSearch), false},
+		// 检查模块直接访问目标，容忍自签名证书
+		{newModule("SomeCheck", ModuleTypeCheck), true},
+		// insecure_modules 中列出的模块不校验证书
+		{newModule("MirrorAPI", ModuleTypeSearch), true},
+	}
+	for _, tt := range tests {
+		if tt.module.InsecureTLS() != tt.insecure {
+			t.Errorf("%s: expected InsecureTLS %v, got %v", tt.module.Name(), tt.insecure, tt.module.InsecureTLS())
+		}
+		resp, err := tt.module.HTTPGet(server.URL, nil)
+		if tt.insecure {
+			if err != nil {
+				t.Errorf("%s: expected request to self-signed server to succeed, got %v", tt.module.Name(), err)
+				continue
+			}
+			resp.Body.Close()
+		} else if err == nil {
+			resp.Body.Close()
+			t.Errorf("%s: expected request to self-signed server to fail certificate verification", tt.module.Name())
+		}
+	}
+}
+
+// BenchmarkModuleTransport 对比共享连接池与每个模块独立 Transport 的多模块请求
+func BenchmarkModuleTransport(b *testing.B) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	conns := countConnections(server)
+	server.StartTLS()
+	defer server.Close()
+
+	cfg := &config.Config{DNSResolveTimeout: 5, MaxResponseBody: 1 << 20}
+	newModules := func(shared bool) []*BaseModule {
+		pool := transport.NewPool(cfg)
+		var modules []*BaseModule
+		for i := 0; i < 60; i++ {
+			module := NewBaseModule(fmt.Sprintf("Module%d", i), ModuleTypeSearch, cfg)
+			module.SetDelay(0)
+			module.SetRetryPolicy(RetryPolicy{MaxAttempts: 1})
+			if !shared {
+				pool = transport.NewPool(cfg)
+			}
+			module.SetTransport(pool.Insecure)
+			modules = append(modules, module)
+		}
+		return modules
+	}
+
+	for _, shared := range []bool{false, true} {
+		name := "per-module"
+		if shared {
+			name = "shared"
+		}
+		b.Run(name, func(b *testing.B) {
+			atomic.StoreInt32(conns, 0)
+			for i := 0; i < b.N; i++ {
+				runModuleRequests(b, newModules(shared), server.URL)
+			}
+			b.ReportMetric(float64(atomic.LoadInt32(conns))/float64(b.N), "conns/op")
+		})
+	}
+}
+
+// roundTripResults 覆盖 CSV 各列（包括逗号拼接的 ip 和 cname）的测试结果，已按 SortResults 排序
+func roundTripResults() []SubdomainResult {
+	return []SubdomainResult{
+		{Subdomain: "api.example.com", IP: []string{"192.0.2.1", "192.0.2.2"}, Status: 1, Title: "API, v2", Port: 443, Alive: true,
+			Source: "crtsh,brute", Time: "2024-01-01 12:00:00", Provider: "Cloudflare", DNSResolved: true, PingAlive: true,
+			StatusCode: 403, StatusText: "Alive", CertIssuer: "R3", CertExpiry: "2030-01-02 03:04:05",
+			CNAME: []string{"api.cdn.net", "edge.cdn.net"}, URL: "https://api.example.com/login"},
+		{Subdomain: "old.example.com", Source: "securitytrails", Time: "2024-01-01 12:00:01", StatusCode: -1, StatusText: "DNS Resolution Failed"},
+	}
+}
+
+// normalizeLoaded 统一空切片，便于与原结果比较（JSON 的 null 和 CSV 的空列都加载为 nil）
+func normalizeLoaded(results []SubdomainResult) []SubdomainResult {
+	for i := range results {
+		if len(results[i].IP) == 0 {
+			results[i].IP = nil
+		}
+		if len(results[i].CNAME) == 0 {
+			results[i].CNAME = nil
+		}
+	}
+	return results
+}
+
+func TestLoadResultsRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		format   string
+		envelope bool
+	}{{"csv", false}, {"json", false}, {"json", true}} {
+		output := NewOutputManager(&config.Config{JSONEnvelope: tc.envelope})
+		output.SetFormat(tc.format)
+		output.SetOutputPath(filepath.Join(t.TempDir(), "results."+tc.format))
+		output.AddResults(roundTripResults())
+		if err := output.Export(); err != nil {
+			t.Fatalf("%s export failed: %v", tc.format, err)
+		}
+
+		loaded, err := LoadResults(output.GetOutputPath())
+		if err != nil {
+			t.Fatalf("%s LoadResults failed: %v", tc.format, err)
+		}
+		if expected := roundTripResults(); !reflect.DeepEqual(normalizeLoaded(loaded), expected) {
+			t.Errorf("%s (envelope=%t) round trip mismatch:\nexpected %+v\ngot      %+v", tc.format, tc.envelope, expected, loaded)
+		}
+	}
+}
+
+func TestLoadResultsSniffsContent(t *testing.T) {
+	dir := t.TempDir()
+	for _, format := range []string{"csv", "json"} {
+		output := NewOutputManager(&config.Config{})
+		output.SetFormat(format)
+		output.SetOutputPath(filepath.Join(dir, "results."+format))
+		output.AddResults(roundTripResults())
+		if err := output.Export(); err != nil {
+			t.Fatalf("%s export failed: %v", format, err)
+		}
+
+		// 去掉扩展名后按内容识别
+		renamed := filepath.Join(dir, format+"-results.txt")
+		if err := os.Rename(output.GetOutputPath(), renamed); err != nil {
+			t.Fatalf("rename: %v", err)
+		}
+		loaded, err := LoadResults(renamed)
+		if err != nil {
+			t.Fatalf("%s sniffing failed: %v", format, err)
+		}
+		if len(loaded) != 2 || strings.Join(loaded[0].IP, ",") != "192.0.2.1,192.0.2.2" {
+			t.Errorf("%s sniffing returned unexpected results: %+v", format, loaded)
+		}
+
+		// 显式指定格式时忽略扩展名
+		if _, err := LoadResultsAs(renamed, format); err != nil {
+			t.Errorf("LoadResultsAs(%s) failed: %v", format, err)
+		}
+	}
+
+	unknown := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(unknown, []byte("hello"), 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := LoadResults(unknown); err == nil {
+		t.Error("Expected an error for an unrecognised file")
+	}
+}
+
+func TestPreflightAbortsRun(t *testing.T) {
+	cfg := &config.Config{Preflight: true}
+	d := NewDispatcher(cfg)
+	module := &countingModule{BaseModule: NewBaseModule("CrtshQuery", ModuleTypeSearch, cfg)}
+	d.RegisterModule(module)
+
+	var checks int32
+	d.SetPreflight(func() error {
+		atomic.AddInt32(&checks, 1)
+		return fmt.Errorf("no internet connection")
+	})
+
+	if _, _, err := d.RunAllModules("example.com"); err == nil || !strings.Contains(err.Error(), "no internet connection") {
+		t.Fatalf("Expected RunAllModules to abort with the pre-flight error, got %v", err)
+	}
+	if _, err := d.RunLib("example.com", map[string]interface{}{"enable_validation": false}); err == nil {
+		t.Fatal("Expected RunLib to abort with the pre-flight error")
+	}
+	if n := atomic.LoadInt32(&module.runs); n != 0 {
+		t.Errorf("Expected no modules to run when offline, ran %d", n)
+	}
+	// 检查结果在调度器内缓存
+	if n := atomic.LoadInt32(&checks); n != 1 {
+		t.Errorf("Expected the checker to run once, ran %d times", n)
+	}
+
+	// --no-preflight 跳过检查
+	cfg.Preflight = false
+	if err := d.Preflight(); err != nil {
+		t.Errorf("Expected pre-flight to be skipped when disabled, got %v", err)
+	}
+}
+
+func TestSearchFilterUsesConfiguredSubnames(t *testing.T) {
+	s := NewSearch("TestSearch", &config.Config{CommonSubnames: "www, MAIL,api,www"})
+	subdomains := []string{"api.example.com", "dev.example.com", "www.example.com", "mail.example.com"}
+
+	// 按配置顺序两两生成，未配置的 dev 不参与过滤
+	statements := s.Filter("example.com", subdomains)
+	expected := []string{" -site:www.example.com -site:mail.example.com", " -site:api.example.com"}
+	if strings.Join(statements, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected statements %q, got %q", expected, statements)
+	}
+
+	// 未配置时读取数据目录下的列表文件
+	dataDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dataDir, commonSubnamesFile), []byte("# comment\ndev\n\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s = NewSearch("TestSearch", &config.Config{DataDir: dataDir})
+	if statements := s.Filter("example.com", subdomains); len(statements) != 1 || statements[0] != " -site:dev.example.com" {
+		t.Errorf("Expected statements from the subnames file, got %q", statements)
+	}
+}
+
+func TestSearchRoundsRecursive(t *testing.T) {
+	cfg := &config.Config{CommonSubnames: "www,mail", EnableRecursiveSearch: true, SearchRecursiveTimes: 1}
+	s := NewSearch("TestSearch", cfg)
+	s.SetDomain("example.com")
+
+	// 模拟搜索引擎：主域名返回常见子域名和 api，递归搜索 api 时返回下一层子域名
+	var queries []string
+	query := func(target, filter string) error {
+		queries = append(queries, target+filter)
+		switch target {
+		case "example.com":
+			if filter == "" {
+				s.AddSubdomain("www.example.com")
+				s.AddSubdomain("mail.example.com")
+				s.AddSubdomain("api.example.com")
+			}
+		case "api.example.com":
+			s.AddSubdomain("v1.api.example.com")
+		}
+		return nil
+	}
+
+	if err := s.SearchRounds("example.com", query); err != nil {
+		t.Fatalf("SearchRounds failed: %v", err)
+	}
+
+	// 首轮、排除语句、第 1 层子域名各搜索一次，search_recursive_times 为 1 时不再搜索第 2 层
+	expected := []string{
+		"example.com",
+		"example.com -site:www.example.com -site:mail.example.com",
+		"api.example.com",
+		"mail.example.com",
+		"www.example.com",
+	}
+	if strings.Join(queries, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected queries %q, got %q", expected, queries)
+	}
+	if !strings.Contains(strings.Join(s.GetSubdomains(), ","), "v1.api.example.com") {
+		t.Errorf("Expected the recursive round to add v1.api.example.com, got %v", s.GetSubdomains())
+	}
+
+	// 未开启递归搜索时只做首轮和排除语句搜索
+	cfg.EnableRecursiveSearch = false
+	s = NewSearch("TestSearch", cfg)
+	s.SetDomain("example.com")
+	queries = nil
+	if err := s.SearchRounds("example.com", query); err != nil {
+		t.Fatalf("SearchRounds failed: %v", err)
+	}
+	if len(queries) != 2 {
+		t.Errorf("Expected 2 queries without recursion, got %q", queries)
+	}
+}
+
+// seedRecorder 记录调度器传入种子的测试模块
+type seedRecorder struct {
+	*BaseModule
+	seed []string
+}
+
+func (m *seedRecorder) SetSeed(subdomains []string) {
+	m.seed = subdomains
+}
+
+func (m *seedRecorder) Run(domain string) ([]string, error) {
+	return nil, nil
+}
+
+func TestAltReceivesSearchSeed(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.MultiThreading.EnableFastSearch = true
+	cfg.MultiThreading.FastSearchConcurrency = 2
+	cfg.MultiThreading.FastSearchTimeout = 5
+	cfg.MultiThreading.EnableBruteForce = true
+	cfg.MultiThreading.BruteForceConcurrency = 1
+
+	d := NewDispatcher(cfg)
+	search := &countingModule{BaseModule: NewBaseModule("BingSearch", ModuleTypeSearch, cfg)}
+	alt := &seedRecorder{BaseModule: NewBaseModule("Alt", ModuleTypeBrute, cfg)}
+	d.RegisterModule(search)
+	d.RegisterModule(alt)
+
+	if _, _, err := d.RunAllModules("example.com"); err != nil {
+		t.Fatalf("RunAllModules failed: %v", err)
+	}
+
+	// Alt 在爆破步骤运行前应拿到搜索步骤的结果
+	if len(alt.seed) != 1 || alt.seed[0] != "bingsearch.example.com" {
+		t.Errorf("Expected Alt to be seeded with [bingsearch.example.com], got %v", alt.seed)
+	}
+}
+
+func TestExportPartialOnPanic(t *testing.T) {
+	cfg := &config.Config{JSONEnvelope: true}
+	output := NewOutputManager(cfg)
+	output.SetFormat("json")
+	output.SetOutputPath(filepath.Join(t.TempDir(), "out.json"))
+
+	// 模拟运行中途 panic：已收集的结果应导出到带 _partial 后缀的文件
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				if _, err := output.ExportPartial(); err != nil {
+					t.Errorf("ExportPartial failed: %v", err)
+				}
+			}
+		}()
+		output.AddResult(SubdomainResult{Subdomain: "www.example.com", Alive: true})
+		panic("module crashed")
+	}()
+
+	path := output.GetOutputPath()
+	if filepath.Base(path) != "out_partial.json" {
+		t.Fatalf("Expected partial file out_partial.json, got %s", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected partial file to be written: %v", err)
+	}
+	var envelope JSONEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		t.Fatalf("Failed to parse partial file: %v", err)
+	}
+	if !envelope.Partial || len(envelope.Results) != 1 || envelope.Results[0].Subdomain != "www.example.com" {
+		t.Errorf("Expected partial envelope with www.example.com, got %+v", envelope)
+	}
+
+	// 没有结果时不写文件
+	empty := NewOutputManager(cfg)
+	if path, err := empty.ExportPartial(); err != nil || path != "" {
+		t.Errorf("Expected no partial file for empty results, got %q (%v)", path, err)
+	}
+}
+
+// targetModule 直接请求目标的测试模块，模拟爬虫、爆破、文件检查等主动模块
+type targetModule struct {
+	*BaseModule
+	url string
+}
+
+func (m *targetModule) Run(domain string) ([]string, error) {
+	resp, err := http.Get(m.url)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return []string{strings.ToLower(m.Name()) + "." + domain}, nil
+}
+
+func TestPassiveModeSkipsTargetTraffic(t *testing.T) {
+	target := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	conns := countConnections(target)
+	target.Start()
+	defer target.Close()
+
+	cfg := &config.Config{Passive: true, EnableDomainValidation: true, ValidationConcurrency: 2}
+	threading := &cfg.MultiThreading
+	threading.EnableFastSearch, threading.FastSearchConcurrency, threading.FastSearchTimeout = true, 1, 5
+	threading.EnableCrawl, threading.CrawlConcurrency, threading.CrawlTimeout = true, 1, 5
+	threading.EnableDNSLookup, threading.DNSLookupConcurrency, threading.DNSLookupTimeout = true, 1, 5
+	threading.EnableBruteForce, threading.BruteForceConcurrency, threading.BruteForceTimeout = true, 1, 5
+	threading.EnableFileCheck, threading.FileCheckConcurrency, threading.FileCheckTimeout = true, 1, 5
+	threading.EnableEnrich, threading.EnrichConcurrency, threading.EnrichTimeout = true, 1, 5
+
+	d := NewDispatcher(cfg)
+	search := &countingModule{BaseModule: NewBaseModule("BingSearch", ModuleTypeSearch, cfg)}
+	d.RegisterModule(search)
+	// 与真实模块同名同类型：NSQuery、RobotsCheck 等按名称归入 Fast Search 步骤
+	active := map[string]ModuleType{
+		"ArchiveCrawl": ModuleTypeCrawl,
+		"NSQuery":      ModuleTypeSearch,
+		"Brute":        ModuleTypeBrute,
+		"RobotsCheck":  ModuleTypeCheck,
+		"enrich":       ModuleTypeEnrich,
+	}
+	for name, moduleType := range active {
+		d.RegisterModule(&targetModule{BaseModule: NewBaseModule(name, moduleType, cfg), url: target.URL})
+	}
+
+	results, validationResults, err := d.RunAllModules("example.com")
+	if err != nil {
+		t.Fatalf("RunAllModules failed: %v", err)
+	}
+	if len(validationResults) != 0 {
+		t.Errorf("Expected no validation in passive mode, got %v", validationResults)
+	}
+	if got := results[ModuleTypeSearch]; len(got) != 1 || got[0].Subdomain != "bingsearch.example.com" || got[0].Alive {
+		t.Errorf("Expected one unvalidated search result, got %v", got)
+	}
+
+	libResults, err := d.RunLib("example.com", map[string]interface{}{"enable_validation": true, "enable_brute_force": true})
+	if err != nil {
+		t.Fatalf("RunLib failed: %v", err)
+	}
+	if len(libResults) != 1 || libResults[0].Subdomain != "bingsearch.example.com" {
+		t.Errorf("Expected only the search result from RunLib, got %v", libResults)
+	}
+
+	if n := atomic.LoadInt32(&search.runs); n != 2 {
+		t.Errorf("Expected the passive search module to run twice, ran %d times", n)
+	}
+	if n := atomic.LoadInt32(conns); n != 0 {
+		t.Errorf("Expected no connections to the target in passive mode, got %d", n)
+	}
+
+	// 关闭被动模式后主动模块照常访问目标
+	cfg.Passive = false
+	cfg.EnableDomainValidation = false
+	if _, _, err := d.RunAllModules("example.com"); err != nil {
+		t.Fatalf("RunAllModules failed: %v", err)
+	}
+	if n := atomic.LoadInt32(conns); n == 0 {
+		t.Error("Expected active modules to contact the target outside passive mode")
+	}
+}
+
+func TestExportCSVColumns(t *testing.T) {
+	// 每个支持的列都有取值函数
+	if _, err := csvColumns(nil); err != nil {
+		t.Fatalf("Expected all default columns to be mapped, got %v", err)
+	}
+
+	dir := t.TempDir()
+	cfg := &config.Config{ResultSaveFormat: "csv", ResultSavePath: dir, CSVColumns: []string{"subdomain", " CNAME ", "status_code", "ip"}}
+	output := NewOutputManager(cfg)
+	output.SetDomain("example.com")
+	output.AddResults([]SubdomainResult{
+		{Subdomain: "www.example.com", IP: []string{"1.1.1.1", "2.2.2.2"}, CNAME: []string{"www.example.com.cdn.net"}, StatusCode: 200, Title: "Home"},
+	})
+	if err := output.Export(); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	data, err := os.ReadFile(output.GetOutputPath())
+	if err != nil {
+		t.Fatalf("Failed to read CSV: %v", err)
+	}
+	// 只输出选择的列，按配置的顺序
+	want := "subdomain,cname,status_code,ip\nwww.example.com,www.example.com.cdn.net,200,\"1.1.1.1,2.2.2.2\"\n"
+	if string(data) != want {
+		t.Errorf("Expected CSV %q, got %q", want, string(data))
+	}
+
+	// 只包含部分列的结果文件仍可作为基线加载
+	loaded, err := LoadResults(output.GetOutputPath())
+	if err != nil || len(loaded) != 1 || loaded[0].StatusCode != 200 || len(loaded[0].IP) != 2 || loaded[0].Title != "" {
+		t.Errorf("Expected the column subset to load back, got %+v (%v)", loaded, err)
+	}
+
+	// 未知列导出失败
+	cfg.CSVColumns = []string{"subdomain", "asn"}
+	if err := output.Export(); err == nil || !strings.Contains(err.Error(), "asn") {
+		t.Errorf("Expected an error for an unknown column, got %v", err)
+	}
+}
+
+func TestExportStatsFile(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{ResultSaveFormat: "csv", ResultSavePath: dir, StatsFile: true, ExportAliveOnly: true}
+	output := NewOutputManager(cfg)
+	output.SetDomain("example.com")
+	output.SetStartTime(time.Now().Add(-90 * time.Second))
+	output.AddResults([]SubdomainResult{
+		{Subdomain: "www.example.com", Alive: true, Source: "crtsh", Provider: "Cloudflare"},
+		{Subdomain: "api.example.com", Alive: true, Source: "brute"},
+		{Subdomain: "old.example.com", Source: "crtsh", StatusText: "DNS Resolution Failed"},
+	})
+	output.AddRelatedDomains([]string{"example.org"})
+	if err := output.Export(); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	statsPath := StatsPath(output.GetOutputPath())
+	if !strings.HasSuffix(statsPath, ".stats.json") || strings.Contains(statsPath, ".csv") {
+		t.Errorf("Expected the stats file to replace the result extension, got %s", statsPath)
+	}
+	data, err := os.ReadFile(statsPath)
+	if err != nil {
+		t.Fatalf("Failed to read stats file: %v", err)
+	}
+	var stats struct {
+		Domain     string                 `json:"domain"`
+		OutputPath string                 `json:"output_path"`
+		Duration   float64                `json:"duration"`
+		Stats      map[string]interface{} `json:"stats"`
+	}
+	if err := json.Unmarshal(data, &stats); err != nil {
+		t.Fatalf("Failed to parse stats file: %v", err)
+	}
+
+	if stats.Domain != "example.com" || stats.OutputPath != output.GetOutputPath() {
+		t.Errorf("Unexpected domain/output path: %s %s", stats.Domain, stats.OutputPath)
+	}
+	if stats.Duration < 90 || stats.Duration > 120 {
+		t.Errorf("Expected duration of about 90s, got %v", stats.Duration)
+	}
+
+	// 统计信息与 GetStats 一致（经过 JSON 编码后比较），包含未写入结果文件的未存活结果
+	encoded, _ := json.Marshal(output.GetStats())
+	var want map[string]interface{}
+	json.Unmarshal(encoded, &want)
+	if !reflect.DeepEqual(stats.Stats, want) {
+		t.Errorf("Expected stats %v, got %v", want, stats.Stats)
+	}
+	if stats.Stats["total"] != float64(3) || stats.Stats["dead"] != float64(1) || stats.Stats["related"] != float64(1) {
+		t.Errorf("Unexpected stats contents: %v", stats.Stats)
+	}
+
+	// 未开启时不写统计文件
+	cfg.StatsFile = false
+	disabled := NewOutputManager(cfg)
+	disabled.SetOutputPath(filepath.Join(dir, "disabled.csv"))
+	disabled.AddResult(SubdomainResult{Subdomain: "www.example.com", Alive: true})
+	if err := disabled.Export(); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "disabled.stats.json")); !os.IsNotExist(err) {
+		t.Errorf("Expected no stats file when disabled, got %v", err)
+	}
+}
+
+// domainStateModule 在 BaseModule 之外按域名保存状态的测试模块，覆盖 Reset 清空
+type domainStateModule struct {
+	*BaseModule
+	seen []string
+}
+
+func (m *domainStateModule) Run(domain string) ([]string, error) {
+	m.seen = append(m.seen, "api."+domain)
+	return append([]string(nil), m.seen...), nil
+}
+
+func (m *domainStateModule) Reset() {
+	m.BaseModule.Reset()
+	m.seen = nil
+}
+
+func TestDispatcherResetIsolatesDomains(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.MultiThreading.EnableFastSearch = true
+	cfg.MultiThreading.EnableFileCheck = true
+	d := NewDispatcher(cfg)
+	d.RegisterModule(&taggedModule{NewBaseModule("SecurityTrailsAPIQuery", ModuleTypeSearch, cfg)})
+	d.RegisterModule(&domainStateModule{BaseModule: NewBaseModule("BruteStub", ModuleTypeCheck, cfg)})
+
+	// 同一个调度器依次处理三个域名，每次运行前 Reset，不重新注册模块
+	for _, domain := range []string{"example.com", "example.net", "example.org"} {
+		d.Reset()
+		results, err := d.RunLib(domain, map[string]interface{}{"enable_validation": false, "concurrency": 1})
+		if err != nil {
+			t.Fatalf("RunLib(%s) failed: %v", domain, err)
+		}
+
+		got := make(map[string]bool)
+		for _, result := range results {
+			if !strings.HasSuffix(result.Subdomain, "."+domain) {
+				t.Errorf("Result %s leaked into the run for %s", result.Subdomain, domain)
+			}
+			got[result.Subdomain] = true
+		}
+		for _, want := range []string{"www." + domain, "legacy." + domain, "api." + domain} {
+			if !got[want] {
+				t.Errorf("Expected %s in results for %s, got %v", want, domain, results)
+			}
+		}
+		if len(got) != 3 {
+			t.Errorf("Expected 3 results for %s, got %d", domain, len(got))
+		}
+	}
+}
+
+type blockingModule struct {
+	*BaseModule
+	cancelled chan struct{}
+	started   chan struct{} // 不为 nil 时在开始运行时关闭
+}
+
+func (m *blockingModule) Run(domain string) ([]string, error) {
+	if m.started != nil {
+		close(m.started)
+	}
+	select {
+	case <-m.Context().Done():
+		close(m.cancelled)
+		return nil, m.Context().Err()
+	case <-time.After(5 * time.Second):
+		return []string{"www." + domain}, nil
+	}
+}
+
+func TestBudgetCancelsRunningModules(t *testing.T) {
+	cfg := &config.Config{}
+	d := NewDispatcher(cfg)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	d.SetContext(ctx)
+
+	module := &blockingModule{BaseModule: NewBaseModule("Brute", ModuleTypeBrute, cfg), cancelled: make(chan struct{})}
+	start := time.Now()
+	if _, err := d.runModulesWithConcurrency([]Module{module}, "example.com", 1, 0, true); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the step to return when the budget expired, took %v", elapsed)
+	}
+
+	select {
+	case <-module.cancelled:
+	case <-time.After(2 * time.Second):
+		t.Error("Expected the running module to see the cancelled context")
+	}
+}
+
+type fixedModule struct {
+	*BaseModule
+	results []string
+	after   <-chan struct{} // 不为 nil 时等待其关闭后再返回结果
+}
+
+func (m *fixedModule) Run(domain string) ([]string, error) {
+	if m.after != nil {
+		<-m.after
+	}
+	return m.results, nil
+}
+
+func TestModuleResultsFilteredByScope(t *testing.T) {
+	cfg := &config.Config{}
+	d := NewDispatcher(cfg)
+	module := &fixedModule{
+		BaseModule: NewBaseModule("CrtshQuery", ModuleTypeSearch, cfg),
+		results:    []string{"www.example.com", "evilexample.com.attacker.net", "API.Example.com.", "example.com.attacker.net", "notexample.com"},
+	}
+
+	results, err := d.runModulesWithConcurrency([]Module{module}, "example.com", 1, time.Minute, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(results)
+	if want := []string{"api.example.com", "www.example.com"}; !reflect.DeepEqual(results, want) {
+		t.Errorf("Expected %v, got %v", want, results)
+	}
+}
+
+func TestMaxResultsCountsUniqueSubdomains(t *testing.T) {
+	cfg := &config.Config{MaxResults: 5}
+	d := NewDispatcher(cfg)
+	d.resetResultCount([]string{"seed.example.com"})
+
+	// 与已收集结果重叠的子域名不占用名额
+	steps := []struct {
+		results []string
+		want    []string
+		reached bool
+	}{
+		{[]string{"a.example.com", "b.example.com", "seed.example.com"}, []string{"a.example.com", "b.example.com", "seed.example.com"}, false},
+		{[]string{"A.example.com", "b.example.com", "c.example.com"}, []string{"A.example.com", "b.example.com", "c.example.com"}, false},
+		{[]string{"b.example.com", "d.example.com", "e.example.com", "f.example.com"}, []string{"b.example.com", "d.example.com"}, true},
+		{[]string{"a.example.com", "g.example.com"}, []string{"a.example.com"}, true},
+	}
+	for i, step := range steps {
+		kept, reached := d.reserveResults(step.results)
+		if !reflect.DeepEqual(kept, step.want) || reached != step.reached {
+			t.Errorf("step %d: expected %v (reached %v), got %v (reached %v)", i, step.want, step.reached, kept, reached)
+		}
+	}
+}
+
+func TestMaxResultsCancelsRunningModules(t *testing.T) {
+	cfg := &config.Config{MaxResults: 3}
+	d := NewDispatcher(cfg)
+	d.resetResultCount(nil)
+
+	// 其他模块等待阻塞模块开始运行后再返回，确保达到上限时它仍在运行
+	blocking := &blockingModule{BaseModule: NewBaseModule("RobtexQuery", ModuleTypeSearch, cfg), cancelled: make(chan struct{}), started: make(chan struct{})}
+	first := &fixedModule{BaseModule: NewBaseModule("CrtshQuery", ModuleTypeSearch, cfg), results: []string{"a.example.com", "b.example.com", "a.example.com"}, after: blocking.started}
+	second := &fixedModule{BaseModule: NewBaseModule("RapidDNSQuery", ModuleTypeSearch, cfg), results: []string{"b.example.com", "c.example.com", "d.example.com"}, after: blocking.started}
+
+	results, err := d.runModulesWithConcurrency([]Module{blocking, first, second}, "example.com", 3, time.Minute, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unique := make(map[string]bool)
+	for _, result := range results {
+		unique[result] = true
+	}
+	if len(unique) != 3 {
+		t.Errorf("Expected exactly 3 unique subdomains under the cap, got %v", results)
+	}
+
+	select {
+	case <-blocking.cancelled:
+	case <-time.After(2 * time.Second):
+		t.Error("Expected the running module to be cancelled once the cap was reached")
+	}
+}
+
+func TestStatsCountMergedSources(t *testing.T) {
+	output := NewOutputManager(&config.Config{})
+	output.AddResults([]SubdomainResult{
+		{Subdomain: "www.example.com", Source: "crtsh"},
+		{Subdomain: "www.example.com", Source: "search"},
+		{Subdomain: "api.example.com", Source: "crtsh"},
+		{Subdomain: "dev.example.com", Source: "brute"},
+	})
+	output.Deduplicate()
+	if results := output.GetResults(); len(results) != 3 {
+		t.Fatalf("Expected 3 merged results, got %+v", results)
+	}
+
+	sources := output.GetStats()["sources"].(map[string]int)
+	if want := map[string]int{"crtsh": 2, "search": 1, "brute": 1}; !reflect.DeepEqual(sources, want) {
+		t.Errorf("Expected per-source counts %v, got %v", want, sources)
+	}
+}
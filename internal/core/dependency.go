@@ -0,0 +1,25 @@
+package core
+
+import "github.com/oneforall-go/pkg/logger"
+
+// SeedConsumer 依赖前面步骤结果的模块（如 Alt、Enrich），实现该接口即声明依赖：
+// 调度器运行模块所在步骤前，通过 SetSeed 传入目标域名此前已收集到的全部子域名
+type SeedConsumer interface {
+	SetSeed(subdomains []string)
+}
+
+// seedModules 将已收集的子域名（去重后）传给声明了依赖的模块，每个模块拿到独立的副本
+func seedModules(modules []Module, subdomains []string) {
+	var seed []string
+	for _, module := range modules {
+		consumer, ok := module.(SeedConsumer)
+		if !ok {
+			continue
+		}
+		if seed == nil {
+			seed = uniqueStrings(subdomains)
+		}
+		logger.Debugf("Seeding module %s with %d collected subdomains", module.Name(), len(seed))
+		consumer.SetSeed(append([]string(nil), seed...))
+	}
+}
@@ -0,0 +1,36 @@
+package core
+
+// discoveryCSVColumns 未验证结果默认导出的 CSV 列，不包含没有意义的存活状态和探测信息
+var discoveryCSVColumns = []string{"subdomain", "source", "time"}
+
+// DiscoveryResult 只做发现、不验证时导出的精简结果
+type DiscoveryResult struct {
+	Subdomain string `json:"subdomain"`
+	Source    string `json:"source"`
+	Time      string `json:"time"`
+}
+
+// toDiscoveryResults 转换为精简结果
+func toDiscoveryResults(results []SubdomainResult) []DiscoveryResult {
+	discovered := make([]DiscoveryResult, len(results))
+	for i, result := range results {
+		discovered[i] = DiscoveryResult{Subdomain: result.Subdomain, Source: result.Source, Time: result.Time}
+	}
+	return discovered
+}
+
+// SetDiscoveryOnly 设置结果是否未经验证（--no-validation 或被动模式）：导出去重后的全部候选及其来源，
+// CSV 默认只包含 subdomain、source、time 列，JSON 数组只包含这三个字段，统计中计为 unvalidated 而不是 dead，
+// 并忽略只导出存活/未存活结果和置信度过滤
+func (o *OutputManager) SetDiscoveryOnly(enabled bool) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.discoveryOnly = enabled
+}
+
+// DiscoveryOnly 结果是否未经验证
+func (o *OutputManager) DiscoveryOnly() bool {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.discoveryOnly
+}
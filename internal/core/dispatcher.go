@@ -1,11 +1,16 @@
 package core
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/oneforall-go/internal/config"
+	"github.com/oneforall-go/internal/metrics"
+	"github.com/oneforall-go/internal/transport"
 	"github.com/oneforall-go/internal/validator"
 	"github.com/oneforall-go/pkg/logger"
 )
@@ -17,6 +22,7 @@ type ExecutionStep struct {
 	Concurrency int
 	Timeout     time.Duration
 	Enabled     bool
+	Override    bool // 通过 SetStepConfig 单独设置，RunLib 使用该步骤自身的并发数和超时而不是统一的选项
 }
 
 // Dispatcher 统一调度器
@@ -41,6 +47,41 @@ type Dispatcher struct {
 	// 域名验证器
 	validator *validator.DomainValidator
 
+	// 共享 HTTP 连接池，注册时注入所有模块
+	transports *transport.Pool
+
+	// 结果回调，模块完成后逐条推送发现的子域名
+	resultHandler func(SubdomainResult)
+
+	// 验证回调，每完成一个域名的验证推送一次结果
+	validationHandler func(validator.ValidationResult)
+
+	// 预置种子结果（如 ASN/CIDR 反查得到的子域名），按域名存放，运行时并入结果
+	seeds map[string][]SubdomainResult
+
+	// 模块报告的关联域名，按目标域名存放
+	related map[string]map[string]bool
+
+	// 模块报告的子域名来源标记，按目标域名存放
+	sourceTags map[string]map[string]string
+
+	// 本轮已收集的去重结果（受 resultMutex 保护）及其数量（原子操作），用于 MaxResults 提前终止
+	collected   map[string]bool
+	resultMutex sync.Mutex
+	resultCount int64
+	limitLogged int32
+
+	// 运行上下文，取消或超出 MaxRuntime 预算时停止剩余工作
+	ctx context.Context
+
+	// 模块耗时统计
+	timings *TimingCollector
+
+	// 运行前的联网检查，结果在同一调度器内缓存
+	preflight     func() error
+	preflightDone bool
+	preflightErr  error
+
 	// 线程安全
 	mutex sync.RWMutex
 }
@@ -58,7 +99,12 @@ func NewDispatcher(cfg *config.Config) *Dispatcher {
 		enrichModules:    make([]Module, 0),
 		executionSteps:   make([]ExecutionStep, 0),
 		validator:        validator.NewDomainValidator(cfg),
+		transports:       transport.NewPool(cfg),
+		timings:          NewTimingCollector(),
+		ctx:              context.Background(),
 	}
+	d.validator.SetTransportPool(d.transports)
+	d.preflight = func() error { return CheckConnectivity(cfg) }
 
 	// 初始化执行步骤
 	d.initExecutionSteps()
@@ -68,7 +114,12 @@ func NewDispatcher(cfg *config.Config) *Dispatcher {
 
 // initExecutionSteps 初始化执行步骤
 func (d *Dispatcher) initExecutionSteps() {
-	d.executionSteps = []ExecutionStep{
+	d.executionSteps = d.defaultExecutionSteps()
+}
+
+// defaultExecutionSteps 按配置生成执行步骤
+func (d *Dispatcher) defaultExecutionSteps() []ExecutionStep {
+	return []ExecutionStep{
 		{
 			Name:        "Fast Search",
 			Modules:     []Module{},
@@ -149,6 +200,24 @@ func (d *Dispatcher) RegisterModule(module Module) {
 
 	logger.Debugf("Registering module: %s", module.Name())
 
+	// 配置中单独禁用的模块
+	for _, name := range d.config.DisabledModules {
+		if strings.EqualFold(strings.TrimSpace(name), module.Name()) {
+			module.SetEnabled(false)
+			logger.Infof("Module %s disabled by config", module.Name())
+			break
+		}
+	}
+
+	// 所有模块复用调度器的连接池，默认校验证书，只有声明跳过校验的模块使用不校验证书的 Transport
+	if setter, ok := module.(TransportSetter); ok {
+		shared := d.transports.Secure
+		if verifier, ok := module.(TLSVerifier); ok && verifier.InsecureTLS() {
+			shared = d.transports.Insecure
+		}
+		setter.SetTransport(shared)
+	}
+
 	moduleType := d.getModuleType(module)
 	logger.Debugf("Module %s classified as type: %s", module.Name(), moduleType)
 
@@ -191,6 +260,180 @@ func (d *Dispatcher) RegisterModule(module Module) {
 	logger.Infof("Successfully registered module: %s (Type: %s)", module.Name(), moduleType)
 }
 
+// FilterModules 按名称筛选已注册的模块：only 非空时只保留其中的模块，再移除 skip 中的模块
+// 名称与 Module.Name() 匹配（不区分大小写），未知名称会输出警告并列出所有可用名称
+func (d *Dispatcher) FilterModules(only, skip []string) {
+	if len(only) == 0 && len(skip) == 0 {
+		return
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	onlySet := toLowerSet(only)
+	skipSet := toLowerSet(skip)
+	known := make(map[string]bool)
+	var validNames []string
+	removed := 0
+
+	for _, bucket := range d.moduleBuckets() {
+		kept := make([]Module, 0, len(*bucket))
+		for _, module := range *bucket {
+			name := strings.ToLower(module.Name())
+			known[name] = true
+			validNames = append(validNames, module.Name())
+
+			if (len(onlySet) > 0 && !onlySet[name]) || skipSet[name] {
+				removed++
+				continue
+			}
+			kept = append(kept, module)
+		}
+		*bucket = kept
+	}
+
+	var unknown []string
+	for _, name := range append(append([]string{}, only...), skip...) {
+		if !known[strings.ToLower(strings.TrimSpace(name))] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(validNames)
+		logger.Warnf("Unknown module names: %s. Valid names: %s",
+			strings.Join(unknown, ", "), strings.Join(validNames, ", "))
+	}
+
+	logger.Infof("Module selection removed %d modules", removed)
+}
+
+// SetModuleEnabled 按名称（不区分大小写）启用或禁用单个已注册模块，不影响所在步骤的其他模块
+func (d *Dispatcher) SetModuleEnabled(name string, enabled bool) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	found := false
+	for _, bucket := range d.moduleBuckets() {
+		for _, module := range *bucket {
+			if strings.EqualFold(module.Name(), strings.TrimSpace(name)) {
+				module.SetEnabled(enabled)
+				found = true
+			}
+		}
+	}
+
+	if !found {
+		logger.Warnf("Cannot set enabled state of unknown module %s", name)
+		return
+	}
+	if enabled {
+		logger.Infof("Module %s enabled", name)
+	} else {
+		logger.Infof("Module %s disabled", name)
+	}
+}
+
+// moduleBuckets 返回所有模块分类切片的指针
+func (d *Dispatcher) moduleBuckets() []*[]Module {
+	return []*[]Module{
+		&d.searchModules,
+		&d.datasetModules,
+		&d.certificateModules,
+		&d.bruteModules,
+		&d.dnsLookupModules,
+		&d.resolveModules,
+		&d.checkModules,
+		&d.crawlModules,
+		&d.intelligenceModules,
+		&d.enrichModules,
+	}
+}
+
+// toLowerSet 将名称列表转换为小写集合
+func toLowerSet(names []string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+	return set
+}
+
+// SetResultHandler 设置结果回调，用于流式获取各模块发现的子域名（传 nil 取消）
+// 回调会在多个模块的 goroutine 中并发调用，需自行保证并发安全
+func (d *Dispatcher) SetResultHandler(handler func(SubdomainResult)) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.resultHandler = handler
+}
+
+// SetValidationHandler 设置验证回调，验证阶段每完成一个域名调用一次（传 nil 取消），用于增量保存验证结果，
+// 运行中断时已完成的验证不会丢失；同一次验证内串行调用
+func (d *Dispatcher) SetValidationHandler(handler func(validator.ValidationResult)) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.validationHandler = handler
+}
+
+// validateDomains 在运行上下文中验证域名，上下文取消时返回已完成的部分结果
+func (d *Dispatcher) validateDomains(domains []string, concurrency int) []validator.ValidationResult {
+	d.mutex.RLock()
+	handler := d.validationHandler
+	d.mutex.RUnlock()
+	return d.validator.ValidateDomainsContext(d.ctx, domains, concurrency, handler)
+}
+
+// emitResults 将模块结果推送给结果回调
+func (d *Dispatcher) emitResults(module Module, domain string, subdomains []string) {
+	d.mutex.RLock()
+	handler := d.resultHandler
+	d.mutex.RUnlock()
+	if handler == nil {
+		return
+	}
+
+	source := string(d.getModuleType(module))
+	for _, subdomain := range subdomains {
+		handler(SubdomainResult{
+			Subdomain: subdomain,
+			Source:    d.sourceFor(domain, subdomain, source),
+			Time:      time.Now().Format("2006-01-02 15:04:05"),
+		})
+	}
+}
+
+// AddSeeds 为域名添加种子结果，下次运行该域名时与模块结果一同验证和输出
+func (d *Dispatcher) AddSeeds(domain string, seeds []SubdomainResult) {
+	if len(seeds) == 0 {
+		return
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.seeds == nil {
+		d.seeds = make(map[string][]SubdomainResult)
+	}
+	d.seeds[domain] = append(d.seeds[domain], seeds...)
+}
+
+// takeSeeds 取出并清空域名的种子结果，同时推送给结果回调
+func (d *Dispatcher) takeSeeds(domain string) []SubdomainResult {
+	d.mutex.Lock()
+	seeds := d.seeds[domain]
+	delete(d.seeds, domain)
+	handler := d.resultHandler
+	d.mutex.Unlock()
+
+	if len(seeds) > 0 {
+		logger.Infof("Using %d seed subdomains for %s", len(seeds), domain)
+	}
+	if handler != nil {
+		for _, seed := range seeds {
+			handler(seed)
+		}
+	}
+	return seeds
+}
+
 // RunAllModules 运行所有模块（分步执行）
 func (d *Dispatcher) RunAllModules(domain string) (map[ModuleType][]SubdomainResult, []validator.ValidationResult, error) {
 	results := make(map[ModuleType][]SubdomainResult)
@@ -200,6 +443,18 @@ func (d *Dispatcher) RunAllModules(domain string) (map[ModuleType][]SubdomainRes
 	logger.Infof("=== Starting subdomain enumeration for domain: %s ===", domain)
 	logger.Debugf("Total execution steps: %d", len(d.executionSteps))
 
+	// 离线或 DNS 服务器不可用时直接终止，避免每个模块各自报错
+	if err := d.Preflight(); err != nil {
+		return nil, nil, err
+	}
+
+	for _, seed := range d.takeSeeds(domain) {
+		seedType := ModuleType(seed.Source)
+		results[seedType] = append(results[seedType], seed)
+		allSubdomains = append(allSubdomains, seed.Subdomain)
+	}
+	d.resetResultCount(allSubdomains)
+
 	// 执行所有步骤（包括爆破模块）
 	logger.Infof("=== Running all modules ===")
 	for i, step := range d.executionSteps {
@@ -209,6 +464,23 @@ func (d *Dispatcher) RunAllModules(domain string) (map[ModuleType][]SubdomainRes
 			continue
 		}
 
+		if d.skipForPassive(step.Name) {
+			logger.Infof("[passive] Skipping step %s", step.Name)
+			continue
+		}
+
+		// 运行预算耗尽后不再启动后续步骤
+		if d.budgetExpired() {
+			d.logBudgetExpired(domain, step.Name, i+1, len(d.executionSteps))
+			break
+		}
+
+		// 达到结果上限后不再启动后续步骤
+		if d.maxResultsReached() {
+			logger.Infof("Result cap reached, skipping step %s", step.Name)
+			continue
+		}
+
 		logger.Debugf("Processing step %d/%d: %s", i+1, len(d.executionSteps), step.Name)
 
 		if !step.Enabled {
@@ -228,9 +500,14 @@ func (d *Dispatcher) RunAllModules(domain string) (map[ModuleType][]SubdomainRes
 
 		logger.Debugf("Step %s has %d modules to execute", step.Name, len(stepModules))
 
+		// 依赖前面步骤结果的模块（Alt、Enrich 等）先拿到已收集的子域名
+		seedModules(stepModules, allSubdomains)
+
 		// 执行当前步骤并等待完全完成
 		logger.Infof("Starting step %s execution...", step.Name)
+		stepStart := time.Now()
 		stepResults, err := d.runModulesWithConcurrency(stepModules, domain, step.Concurrency, step.Timeout, d.getModuleTypeForStep(step.Name) == ModuleTypeBrute)
+		metrics.ObserveStep(step.Name, time.Since(stepStart))
 		if err != nil {
 			logger.Errorf("Step %s failed: %v", step.Name, err)
 			continue
@@ -242,7 +519,7 @@ func (d *Dispatcher) RunAllModules(domain string) (map[ModuleType][]SubdomainRes
 		for _, subdomain := range stepResults {
 			result := SubdomainResult{
 				Subdomain: subdomain,
-				Source:    string(stepType),
+				Source:    d.sourceFor(domain, subdomain, string(stepType)),
 				Time:      time.Now().Format("2006-01-02 15:04:05"),
 				Alive:     false, // 默认未检查存活状态
 			}
@@ -263,11 +540,31 @@ func (d *Dispatcher) RunAllModules(domain string) (map[ModuleType][]SubdomainRes
 
 	// 执行验证模块
 	logger.Infof("=== Running validation module ===")
-	if d.config.EnableDomainValidation && len(allSubdomains) > 0 {
+	if d.config.DryRun {
+		logger.Infof("[dry-run] Skipping validation of %d candidates", len(allSubdomains))
+	} else if d.config.Passive {
+		logger.Infof("[passive] Returning %d unvalidated candidates", len(allSubdomains))
+	} else if d.budgetExpired() {
+		logger.Warnf("Run budget expired, skipping validation of %d candidates", len(allSubdomains))
+	} else if d.config.EnableDomainValidation && len(allSubdomains) > 0 {
 		logger.Info("=== Starting domain validation and deduplication ===")
 
 		// 验证域名
-		validationResults = d.validator.ValidateDomains(allSubdomains, d.config.ValidationConcurrency)
+		validationStart := time.Now()
+		validationResults = d.validateDomains(allSubdomains, d.config.ValidationConcurrency)
+		interrupted := d.budgetExpired()
+
+		// 验证证书 SAN 中发现的新子域名
+		certResults := d.validateCertNames(domain, validationResults, d.config.ValidationConcurrency)
+		for _, result := range certResults {
+			results[ModuleType(CertSANSource)] = append(results[ModuleType(CertSANSource)], SubdomainResult{
+				Subdomain: result.Subdomain,
+				Source:    CertSANSource,
+				Time:      result.Time,
+			})
+		}
+		validationResults = append(validationResults, certResults...)
+		metrics.ObserveStep("Validation", time.Since(validationStart))
 
 		// 保留所有结果，包括验证不通过的域名
 		var allValidatedResults []validator.ValidationResult
@@ -288,20 +585,20 @@ func (d *Dispatcher) RunAllModules(domain string) (map[ModuleType][]SubdomainRes
 			var validatedResults []SubdomainResult
 			for _, result := range results[stepType] {
 				// 查找对应的验证结果
+				validated := false
 				for _, validationResult := range allValidatedResults {
 					if validationResult.Subdomain == result.Subdomain {
 						// 更新验证信息
-						result.IP = validationResult.IP
-						result.Alive = validationResult.Alive
-						result.DNSResolved = validationResult.DNSResolved
-						result.PingAlive = validationResult.PingAlive
-						result.StatusCode = validationResult.StatusCode
-						result.StatusText = validationResult.StatusText
-						result.Provider = validationResult.Provider
+						ApplyValidation(&result, validationResult)
 						validatedResults = append(validatedResults, result)
+						validated = true
 						break
 					}
 				}
+				// 验证被中断时保留尚未验证的候选
+				if !validated && interrupted {
+					validatedResults = append(validatedResults, result)
+				}
 			}
 			results[stepType] = validatedResults
 		}
@@ -321,6 +618,11 @@ func (d *Dispatcher) RunAllModules(domain string) (map[ModuleType][]SubdomainRes
 func (d *Dispatcher) RunLib(domain string, options map[string]interface{}) ([]SubdomainResult, error) {
 	logger.Infof("=== Starting library call for domain: %s ===", domain)
 
+	// 离线或 DNS 服务器不可用时直接终止，避免每个模块各自报错
+	if err := d.Preflight(); err != nil {
+		return nil, err
+	}
+
 	// 解析选项参数
 	enableValidation := true
 	if val, ok := options["enable_validation"].(bool); ok {
@@ -366,6 +668,12 @@ func (d *Dispatcher) RunLib(domain string, options map[string]interface{}) ([]Su
 	var allResults []SubdomainResult
 	var allSubdomains []string
 
+	for _, seed := range d.takeSeeds(domain) {
+		allResults = append(allResults, seed)
+		allSubdomains = append(allSubdomains, seed.Subdomain)
+	}
+	d.resetResultCount(allSubdomains)
+
 	// 执行所有收集模块
 	logger.Infof("=== Running collection modules ===")
 	for i, step := range d.executionSteps {
@@ -380,6 +688,23 @@ func (d *Dispatcher) RunLib(domain string, options map[string]interface{}) ([]Su
 			continue
 		}
 
+		if d.skipForPassive(step.Name) {
+			logger.Infof("[passive] Skipping step %s", step.Name)
+			continue
+		}
+
+		// 运行预算耗尽后不再启动后续步骤
+		if d.budgetExpired() {
+			d.logBudgetExpired(domain, step.Name, i+1, len(d.executionSteps))
+			break
+		}
+
+		// 达到结果上限后不再启动后续步骤
+		if d.maxResultsReached() {
+			logger.Infof("Result cap reached, skipping step %s", step.Name)
+			continue
+		}
+
 		logger.Debugf("Processing step %d/%d: %s", i+1, len(d.executionSteps), step.Name)
 
 		if !step.Enabled {
@@ -387,8 +712,17 @@ func (d *Dispatcher) RunLib(domain string, options map[string]interface{}) ([]Su
 			continue
 		}
 
+		// 单独设置过的步骤使用自身的并发数和超时（配置中未设置的项仍使用统一值）
+		stepConcurrency, stepTimeout := concurrency, timeout
+		if step.Override && step.Concurrency > 0 {
+			stepConcurrency = step.Concurrency
+		}
+		if step.Override && step.Timeout > 0 {
+			stepTimeout = step.Timeout
+		}
+
 		logger.Infof("Step %d/%d: %s (Concurrency: %d, Timeout: %v)",
-			i+1, len(d.executionSteps), step.Name, concurrency, timeout)
+			i+1, len(d.executionSteps), step.Name, stepConcurrency, stepTimeout)
 
 		// 获取当前步骤的模块
 		stepModules := d.getModulesForStep(step.Name, false)
@@ -399,8 +733,13 @@ func (d *Dispatcher) RunLib(domain string, options map[string]interface{}) ([]Su
 
 		logger.Debugf("Step %s has %d modules to execute", step.Name, len(stepModules))
 
+		// 依赖前面步骤结果的模块（Alt、Enrich 等）先拿到已收集的子域名
+		seedModules(stepModules, allSubdomains)
+
 		// 执行当前步骤
-		stepResults, err := d.runModulesWithConcurrency(stepModules, domain, concurrency, timeout, d.getModuleTypeForStep(step.Name) == ModuleTypeBrute)
+		stepStart := time.Now()
+		stepResults, err := d.runModulesWithConcurrency(stepModules, domain, stepConcurrency, stepTimeout, d.getModuleTypeForStep(step.Name) == ModuleTypeBrute)
+		metrics.ObserveStep(step.Name, time.Since(stepStart))
 		if err != nil {
 			logger.Errorf("Step %s failed: %v", step.Name, err)
 			continue
@@ -411,7 +750,7 @@ func (d *Dispatcher) RunLib(domain string, options map[string]interface{}) ([]Su
 		for _, subdomain := range stepResults {
 			result := SubdomainResult{
 				Subdomain: subdomain,
-				Source:    string(stepType),
+				Source:    d.sourceFor(domain, subdomain, string(stepType)),
 				Time:      time.Now().Format("2006-01-02 15:04:05"),
 				Alive:     false, // 默认未检查存活状态
 			}
@@ -427,24 +766,37 @@ func (d *Dispatcher) RunLib(domain string, options map[string]interface{}) ([]Su
 	logger.Infof("Total subdomains collected: %d", len(allSubdomains))
 
 	// 执行验证模块（如果启用）
-	if enableValidation && len(allSubdomains) > 0 {
+	if d.config.DryRun {
+		logger.Infof("[dry-run] Skipping validation of %d candidates", len(allSubdomains))
+	} else if d.config.Passive {
+		logger.Infof("[passive] Returning %d unvalidated candidates", len(allSubdomains))
+	} else if d.budgetExpired() {
+		logger.Warnf("Run budget expired, skipping validation of %d candidates", len(allSubdomains))
+	} else if enableValidation && len(allSubdomains) > 0 {
 		logger.Infof("=== Running validation module ===")
 		logger.Info("=== Starting domain validation and deduplication ===")
 
 		// 验证域名
-		validationResults := d.validator.ValidateDomains(allSubdomains, concurrency)
+		validationStart := time.Now()
+		validationResults := d.validateDomains(allSubdomains, concurrency)
+
+		// 验证证书 SAN 中发现的新子域名
+		certResults := d.validateCertNames(domain, validationResults, concurrency)
+		for _, result := range certResults {
+			allResults = append(allResults, SubdomainResult{
+				Subdomain: result.Subdomain,
+				Source:    CertSANSource,
+				Time:      result.Time,
+			})
+		}
+		validationResults = append(validationResults, certResults...)
+		metrics.ObserveStep("Validation", time.Since(validationStart))
 
 		// 更新结果中的验证信息
 		for i, result := range allResults {
 			for _, validationResult := range validationResults {
 				if validationResult.Subdomain == result.Subdomain {
-					result.IP = validationResult.IP
-					result.Alive = validationResult.Alive
-					result.DNSResolved = validationResult.DNSResolved
-					result.PingAlive = validationResult.PingAlive
-					result.StatusCode = validationResult.StatusCode
-					result.StatusText = validationResult.StatusText
-					result.Provider = validationResult.Provider
+					ApplyValidation(&result, validationResult)
 					allResults[i] = result
 					break
 				}
@@ -467,6 +819,44 @@ func (d *Dispatcher) RunLib(domain string, options map[string]interface{}) ([]Su
 	return allResults, nil
 }
 
+// CertSANSource 从验证阶段获取的 HTTPS 证书 SAN 中发现的子域名的来源
+const CertSANSource = "cert_san"
+
+// certNamesInScope 收集验证结果中证书 SAN 里属于目标范围、且尚未验证过的名称
+func certNamesInScope(domain string, validationResults []validator.ValidationResult) []string {
+	known := make(map[string]bool, len(validationResults))
+	for _, result := range validationResults {
+		known[NormalizeHost(result.Subdomain)] = true
+	}
+
+	var names []string
+	for _, result := range validationResults {
+		for _, name := range result.CertNames {
+			name = NormalizeHost(name)
+			if known[name] || !InScope(name, domain) {
+				continue
+			}
+			known[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// validateCertNames 对证书 SAN 中发现的新子域名再做一轮验证（不再递归收集第二轮的证书名称）
+func (d *Dispatcher) validateCertNames(domain string, validationResults []validator.ValidationResult, concurrency int) []validator.ValidationResult {
+	names := certNamesInScope(domain, validationResults)
+	if len(names) == 0 || d.budgetExpired() {
+		return nil
+	}
+	if names, _ = d.reserveResults(names); len(names) == 0 {
+		return nil
+	}
+
+	logger.Infof("Found %d new in-scope names in TLS certificates for %s, validating", len(names), domain)
+	return d.validateDomains(names, concurrency)
+}
+
 // getModulesForStep 根据步骤名称获取对应的模块
 func (d *Dispatcher) getModulesForStep(stepName string, excludeBrute bool) []Module {
 	logger.Debugf("Getting modules for step: %s (excludeBrute: %t)", stepName, excludeBrute)
@@ -587,8 +977,10 @@ func (d *Dispatcher) getCertificateModules() []Module {
 
 // getIntelligenceModules 获取情报模块
 func (d *Dispatcher) getIntelligenceModules() []Module {
+	// 只属于情报分类的模块（如 WhoisQuery）注册在 intelligenceModules 中
+	intelligenceModules := append([]Module(nil), d.intelligenceModules...)
+
 	// 从所有注册的模块中筛选情报模块
-	var intelligenceModules []Module
 
 	allModules := append(append(append(d.searchModules, d.bruteModules...), d.dnsLookupModules...), d.enrichModules...)
 
@@ -669,7 +1061,7 @@ func isCheckModule(name string) bool {
 
 // isIntelligenceModule 判断是否为情报模块
 func isIntelligenceModule(name string) bool {
-	intelligenceModules := []string{"AlienVaultQuery", "RiskIQAPIQuery", "ThreatBookAPIQuery", "ThreatMinerQuery", "VirusTotalQuery", "VirusTotalAPIQuery"}
+	intelligenceModules := []string{"AlienVaultQuery", "RiskIQAPIQuery", "ThreatBookAPIQuery", "ThreatMinerQuery", "VirusTotalQuery", "VirusTotalAPIQuery", "WhoisQuery"}
 	return containsInSlice(intelligenceModules, name)
 }
 
@@ -737,11 +1129,29 @@ func (d *Dispatcher) runModulesWithConcurrency(modules []Module, domain string,
 		}()
 	}
 
+	// 达到结果上限时关闭 stop，未启动的模块不再运行，也不再等待运行中的模块
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+
+	// 模块的运行上下文：达到结果上限、步骤超时或运行预算耗尽时取消，运行中的模块随之结束
+	runCtx, cancel := context.WithCancel(d.ctx)
+	defer cancel()
+
 	for _, module := range modules {
 		if !module.IsEnabled() {
 			logger.Debugf("Module %s is disabled, skipping", module.Name())
 			continue
 		}
+		if limited, ok := module.(QuotaLimited); ok {
+			if reason, exhausted := limited.QuotaExhausted(); exhausted {
+				logger.Infof("Module %s skipped, API quota exhausted (%s)", module.Name(), reason)
+				continue
+			}
+		}
+		if d.config.Passive && !IsPassiveModule(module) {
+			logger.Infof("[passive] Skipping module %s", module.Name())
+			continue
+		}
 
 		wg.Add(1)
 		go func(module Module) {
@@ -765,17 +1175,47 @@ func (d *Dispatcher) runModulesWithConcurrency(modules []Module, domain string,
 				case <-done:
 					logger.Warnf("Module %s skipped due to timeout", module.Name())
 					return
+				case <-stop:
+					logger.Debugf("Module %s skipped due to result cap", module.Name())
+					return
+				case <-d.ctx.Done():
+					logger.Debugf("Module %s skipped due to run budget", module.Name())
+					return
 				}
 			} else {
 				// 爆破模块不设置超时，直接获取信号量
-				semaphore <- struct{}{}
-				defer func() { <-semaphore }()
+				select {
+				case semaphore <- struct{}{}:
+					defer func() { <-semaphore }()
+				case <-stop:
+					logger.Debugf("Module %s skipped due to result cap", module.Name())
+					return
+				case <-d.ctx.Done():
+					logger.Debugf("Module %s skipped due to run budget", module.Name())
+					return
+				}
+			}
+
+			// 等待信号量期间其他模块可能已达到上限
+			if d.maxResultsReached() {
+				logger.Debugf("Module %s skipped due to result cap", module.Name())
+				return
+			}
+			if d.budgetExpired() {
+				logger.Debugf("Module %s skipped due to run budget", module.Name())
+				return
+			}
+
+			if setter, ok := module.(ContextSetter); ok {
+				setter.SetContext(runCtx)
 			}
 
 			logger.Debugf("Starting module: %s", module.Name())
 			startTime := time.Now()
 
-			results, err := module.Run(domain)
+			results, err := d.runModule(module, domain)
+			metrics.ObserveModuleRun(module.Name(), len(results), err)
+			d.TimingCollector().Record(module.Name(), time.Since(startTime), len(results), err)
 			if err != nil {
 				logger.Errorf("Module %s failed: %v", module.Name(), err)
 				mutex.Lock()
@@ -785,9 +1225,22 @@ func (d *Dispatcher) runModulesWithConcurrency(modules []Module, domain string,
 			}
 
 			elapsed := time.Since(startTime)
+			results = filterInScope(module.Name(), domain, results)
+
+			// 按结果上限合并，达到上限时通知其他模块停止
+			results, reached := d.reserveResults(results)
 			mutex.Lock()
 			allResults = append(allResults, results...)
 			mutex.Unlock()
+			if reached {
+				d.logLimitReached(domain)
+				stopOnce.Do(func() {
+					close(stop)
+					cancel()
+				})
+			}
+
+			d.emitResults(module, domain, results)
 
 			logger.Infof("Module %s completed in %v, found %d subdomains",
 				module.Name(), elapsed, len(results))
@@ -807,18 +1260,48 @@ func (d *Dispatcher) runModulesWithConcurrency(modules []Module, domain string,
 			logger.Debugf("All modules completed successfully")
 		case <-done:
 			logger.Warnf("Some modules timed out after %v", timeout)
+		case <-stop:
+			logger.Debugf("Result cap reached, not waiting for in-flight modules")
+		case <-d.ctx.Done():
+			logger.Warnf("Run budget expired, not waiting for in-flight modules")
 		}
 	} else {
 		// 爆破模块等待完成，不设置超时
-		<-completed
-		logger.Debugf("All brute force modules completed successfully")
+		select {
+		case <-completed:
+			logger.Debugf("All brute force modules completed successfully")
+		case <-stop:
+			logger.Debugf("Result cap reached, not waiting for in-flight brute force modules")
+		case <-d.ctx.Done():
+			logger.Warnf("Run budget expired, not waiting for in-flight brute force modules")
+		}
 	}
 
+	// 超时或达到上限时仍有模块在运行，复制结果避免并发修改
+	mutex.Lock()
+	defer mutex.Unlock()
 	if len(errors) > 0 {
 		logger.Warnf("Some modules failed: %v", errors)
 	}
 
-	return allResults, nil
+	return append([]string(nil), allResults...), nil
+}
+
+// runModule 运行单个模块，dry-run 模式下只记录将要运行的模块，支持 DryRunner 的模块返回候选列表
+func (d *Dispatcher) runModule(module Module, domain string) ([]string, error) {
+	if !d.config.DryRun {
+		defer d.collectRelated(module, domain)
+		defer d.collectSourceTags(module, domain)
+		return module.Run(domain)
+	}
+
+	if runner, ok := module.(DryRunner); ok {
+		logger.Infof("[dry-run] Generating candidates with module %s", module.Name())
+		return runner.DryRun(domain)
+	}
+
+	logger.Infof("[dry-run] Would run module %s (type: %s)", module.Name(), d.getModuleType(module))
+	return nil, nil
 }
 
 // runModules 运行指定类型的模块（兼容旧版本）
@@ -908,6 +1391,52 @@ func (d *Dispatcher) SetStepTimeout(stepIndex int, timeout time.Duration) {
 	}
 }
 
+// StepIndex 按名称（不区分大小写，如 "Brute Force"）查找执行步骤，不存在时返回 -1
+func (d *Dispatcher) StepIndex(name string) int {
+	for i, step := range d.executionSteps {
+		if strings.EqualFold(step.Name, strings.TrimSpace(name)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// SetStepConfig 单独设置步骤的并发数和超时（不大于 0 的值保持不变），库调用中该步骤不再使用统一的并发数和超时
+func (d *Dispatcher) SetStepConfig(name string, concurrency int, timeout time.Duration) error {
+	index := d.StepIndex(name)
+	if index < 0 {
+		names := make([]string, 0, len(d.executionSteps))
+		for _, step := range d.executionSteps {
+			names = append(names, step.Name)
+		}
+		return fmt.Errorf("unknown execution step %q (steps: %s)", name, strings.Join(names, ", "))
+	}
+
+	d.SetStepConcurrency(index, concurrency)
+	if timeout > 0 {
+		d.SetStepTimeout(index, timeout)
+	}
+	d.executionSteps[index].Override = true
+	return nil
+}
+
+// ClearStepConfig 撤销 SetStepConfig 的设置，步骤的并发数和超时恢复为配置值
+func (d *Dispatcher) ClearStepConfig() {
+	defaults := d.defaultExecutionSteps()
+	for i := range d.executionSteps {
+		if d.executionSteps[i].Override {
+			d.executionSteps[i].Concurrency = defaults[i].Concurrency
+			d.executionSteps[i].Timeout = defaults[i].Timeout
+			d.executionSteps[i].Override = false
+		}
+	}
+}
+
+// ExecutionSteps 返回执行步骤的副本
+func (d *Dispatcher) ExecutionSteps() []ExecutionStep {
+	return append([]ExecutionStep(nil), d.executionSteps...)
+}
+
 func extractHostsFromResults(results []SubdomainResult) []string {
 	var hosts []string
 	for _, r := range results {
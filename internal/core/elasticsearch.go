@@ -0,0 +1,184 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oneforall-go/pkg/logger"
+)
+
+const (
+	// esBulkBatchSize 每次 bulk 请求的文档数
+	esBulkBatchSize = 500
+	// esMaxRetries 遇到 429 时的最大重试次数
+	esMaxRetries = 3
+)
+
+// esRetryDelay 429 重试的初始等待时间（指数递增）
+var esRetryDelay = time.Second
+
+// esBulkResponse bulk 接口响应
+type esBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		ID     string `json:"_id"`
+		Status int    `json:"status"`
+	} `json:"items"`
+}
+
+// exportElasticsearch 通过 bulk 接口将结果写入 Elasticsearch，以子域名作为文档 ID
+// 写入失败时回退为本地 JSON 文件，避免数据丢失
+func (o *OutputManager) exportElasticsearch(results []SubdomainResult) error {
+	err := o.indexElasticsearch(results)
+	if err == nil {
+		logger.Infof("Indexed %d results into Elasticsearch index %s", len(results), o.config.ESIndex)
+		return nil
+	}
+
+	o.outputPath = strings.TrimSuffix(o.outputPath, filepath.Ext(o.outputPath)) + ".json"
+	logger.Warnf("Elasticsearch export failed (%v), falling back to local JSON: %s", err, o.outputPath)
+	return o.exportJSON(results)
+}
+
+// indexElasticsearch 分批写入结果
+func (o *OutputManager) indexElasticsearch(results []SubdomainResult) error {
+	if o.config.ESURL == "" {
+		return fmt.Errorf("ES_URL is not configured")
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	bulkURL := strings.TrimSuffix(o.config.ESURL, "/") + "/_bulk"
+
+	for start := 0; start < len(results); start += esBulkBatchSize {
+		end := start + esBulkBatchSize
+		if end > len(results) {
+			end = len(results)
+		}
+
+		if err := o.indexBatch(client, bulkURL, results[start:end]); err != nil {
+			return fmt.Errorf("bulk request for results %d-%d failed: %v", start, end, err)
+		}
+		logger.Debugf("Indexed results %d-%d into Elasticsearch", start, end)
+	}
+
+	return nil
+}
+
+// buildBulkBody 构建 bulk 请求体（NDJSON）
+func (o *OutputManager) buildBulkBody(results []SubdomainResult) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+
+	for _, result := range results {
+		action := map[string]map[string]string{
+			"index": {"_index": o.config.ESIndex, "_id": result.Subdomain},
+		}
+		if err := encoder.Encode(action); err != nil {
+			return nil, fmt.Errorf("failed to encode bulk action: %v", err)
+		}
+		if err := encoder.Encode(result); err != nil {
+			return nil, fmt.Errorf("failed to encode document: %v", err)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// indexBatch 写入一批结果，整个请求或单个文档返回 429 时按指数退避只重试被限流的文档
+func (o *OutputManager) indexBatch(client *http.Client, bulkURL string, results []SubdomainResult) error {
+	delay := esRetryDelay
+	pending := results
+
+	for attempt := 0; ; attempt++ {
+		body, err := o.buildBulkBody(pending)
+		if err != nil {
+			return err
+		}
+		throttled, retryAfter, err := sendBulk(client, bulkURL, body, len(pending))
+		if err != nil {
+			return err
+		}
+		if len(throttled) == 0 {
+			return nil
+		}
+		if attempt >= esMaxRetries {
+			return fmt.Errorf("%d documents still throttled after %d retries", len(throttled), esMaxRetries)
+		}
+
+		wait := delay
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+		logger.Debugf("Elasticsearch throttled %d documents, retrying in %v", len(throttled), wait)
+		time.Sleep(wait)
+		delay *= 2
+
+		retry := make([]SubdomainResult, 0, len(throttled))
+		for _, index := range throttled {
+			retry = append(retry, pending[index])
+		}
+		pending = retry
+	}
+}
+
+// sendBulk 发送一次 bulk 请求，返回被限流（429）需要重试的文档下标和 Retry-After 等待时间；
+// 整个请求被限流时返回全部下标，其他失败的文档返回错误
+func sendBulk(client *http.Client, bulkURL string, body []byte, count int) ([]int, time.Duration, error) {
+	resp, err := client.Post(bulkURL, "application/x-ndjson", bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read response: %v", err)
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		var retryAfter time.Duration
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+		all := make([]int, count)
+		for i := range all {
+			all[i] = i
+		}
+		return all, retryAfter, nil
+	}
+	if resp.StatusCode >= 300 {
+		return nil, 0, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var bulkResp esBulkResponse
+	if err := json.Unmarshal(respBody, &bulkResp); err != nil {
+		return nil, 0, fmt.Errorf("failed to parse response: %v", err)
+	}
+	if !bulkResp.Errors {
+		return nil, 0, nil
+	}
+
+	// items 与请求中的文档一一对应
+	var throttled []int
+	failed := 0
+	for index, item := range bulkResp.Items {
+		for _, result := range item {
+			switch {
+			case result.Status == http.StatusTooManyRequests:
+				throttled = append(throttled, index)
+			case result.Status >= 300:
+				failed++
+			}
+		}
+	}
+	if failed > 0 {
+		return nil, 0, fmt.Errorf("%d documents failed to index", failed)
+	}
+	return throttled, 0, nil
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/oneforall-go/pkg/logger"
+)
+
+// ResultFilter 子域名正则过滤器（先 include 后 exclude）
+type ResultFilter struct {
+	include *regexp.Regexp
+	exclude *regexp.Regexp
+}
+
+// NewResultFilter 创建结果过滤器，两个模式都为空时返回 nil
+func NewResultFilter(includePattern, excludePattern string) (*ResultFilter, error) {
+	if includePattern == "" && excludePattern == "" {
+		return nil, nil
+	}
+
+	filter := &ResultFilter{}
+	if includePattern != "" {
+		re, err := regexp.Compile(includePattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include pattern %q: %v", includePattern, err)
+		}
+		filter.include = re
+	}
+	if excludePattern != "" {
+		re, err := regexp.Compile(excludePattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern %q: %v", excludePattern, err)
+		}
+		filter.exclude = re
+	}
+
+	return filter, nil
+}
+
+// Apply 过滤结果并记录每个过滤器移除的数量
+func (f *ResultFilter) Apply(results []SubdomainResult) []SubdomainResult {
+	if f == nil {
+		return results
+	}
+
+	filtered := make([]SubdomainResult, 0, len(results))
+	includeRemoved, excludeRemoved := 0, 0
+	for _, result := range results {
+		if f.include != nil && !f.include.MatchString(result.Subdomain) {
+			includeRemoved++
+			continue
+		}
+		if f.exclude != nil && f.exclude.MatchString(result.Subdomain) {
+			excludeRemoved++
+			continue
+		}
+		filtered = append(filtered, result)
+	}
+
+	if f.include != nil {
+		logger.Infof("Include filter %q removed %d results", f.include.String(), includeRemoved)
+	}
+	if f.exclude != nil {
+		logger.Infof("Exclude filter %q removed %d results", f.exclude.String(), excludeRemoved)
+	}
+
+	return filtered
+}
@@ -0,0 +1,148 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/oneforall-go/pkg/logger"
+)
+
+// IndexFileName 按域名分目录输出时，结果保存路径下的索引文件名
+const IndexFileName = "index.json"
+
+// indexMutex 多个域名并发导出时串行更新索引文件
+var indexMutex sync.Mutex
+
+// Index 按域名分目录输出时的顶层索引，每个域名一条记录，按域名排序
+type Index struct {
+	UpdatedAt string       `json:"updated_at"`
+	Domains   []IndexEntry `json:"domains"`
+}
+
+// IndexEntry 单个域名最近一次导出的记录
+type IndexEntry struct {
+	Domain    string `json:"domain"`
+	Path      string `json:"path"` // 结果文件路径，相对于结果保存路径
+	Format    string `json:"format"`
+	Total     int    `json:"total"`
+	Alive     int    `json:"alive"`
+	UpdatedAt string `json:"updated_at"`
+	Partial   bool   `json:"partial,omitempty"` // 运行异常中断，结果不完整
+}
+
+// IndexPath 返回结果保存路径下的索引文件路径
+func IndexPath(resultSavePath string) string {
+	return filepath.Join(resultSavePath, IndexFileName)
+}
+
+// LoadIndex 读取索引文件，文件不存在时返回空索引
+func LoadIndex(path string) (*Index, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Index{Domains: []IndexEntry{}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index: %v", err)
+	}
+
+	var index Index
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse index %s: %v", path, err)
+	}
+	return &index, nil
+}
+
+// SetDomain 设置结果所属的目标域名，用于生成输出路径和索引记录
+func (o *OutputManager) SetDomain(domain string) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.domain = domain
+}
+
+// ForDomain 创建单个域名的输出管理器，沿用格式、过滤规则和该域名范围内的基线结果
+func (o *OutputManager) ForDomain(domain string) *OutputManager {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	output := NewOutputManager(o.config)
+	output.format = o.format
+	output.filter = o.filter
+	output.domain = domain
+	output.discoveryOnly = o.discoveryOnly
+	if o.baseline != nil {
+		output.baselinePath = o.baselinePath
+		output.baseline = make([]SubdomainResult, 0)
+		for _, result := range o.baseline {
+			if InScope(result.Subdomain, domain) {
+				output.baseline = append(output.baseline, result)
+			}
+		}
+	}
+	return output
+}
+
+// perDomain 是否按域名分目录输出，只对设置了域名的输出管理器（ForDomain/SetDomain）生效，
+// 汇总多个域名的输出管理器仍写入结果保存路径
+func (o *OutputManager) perDomain() bool {
+	return o.config.PerDomainOutput && o.domain != ""
+}
+
+// updateIndex 用本次导出替换索引中该域名的记录，调用方需持有锁
+func (o *OutputManager) updateIndex() error {
+	indexMutex.Lock()
+	defer indexMutex.Unlock()
+
+	indexPath := IndexPath(o.config.ResultSavePath)
+	index, err := LoadIndex(indexPath)
+	if err != nil {
+		return err
+	}
+
+	path, err := filepath.Rel(o.config.ResultSavePath, o.outputPath)
+	if err != nil {
+		path = o.outputPath
+	}
+	stats := o.stats()
+	now := time.Now().Format("2006-01-02 15:04:05")
+	entry := IndexEntry{
+		Domain:    o.domain,
+		Path:      filepath.ToSlash(path),
+		Format:    o.format,
+		Total:     stats["total"].(int),
+		Alive:     stats["alive"].(int),
+		UpdatedAt: now,
+		Partial:   o.partial,
+	}
+
+	domains := make([]IndexEntry, 0, len(index.Domains)+1)
+	for _, existing := range index.Domains {
+		if existing.Domain != entry.Domain {
+			domains = append(domains, existing)
+		}
+	}
+	domains = append(domains, entry)
+	sort.Slice(domains, func(i, j int) bool { return domains[i].Domain < domains[j].Domain })
+	index.Domains = domains
+	index.UpdatedAt = now
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode index: %v", err)
+	}
+	// 先写临时文件再重命名，避免读取方看到写了一半的索引
+	tmpPath := indexPath + ".tmp"
+	if err := os.WriteFile(tmpPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write index: %v", err)
+	}
+	if err := os.Rename(tmpPath, indexPath); err != nil {
+		return fmt.Errorf("failed to write index: %v", err)
+	}
+
+	logger.Infof("Updated index %s with %s (%d results)", indexPath, entry.Domain, entry.Total)
+	return nil
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/oneforall-go/pkg/logger"
+)
+
+// exportIPJSON 按 IP 反向分组导出 {"1.2.3.4": ["a.ex.cn", "b.ex.cn"]}，
+// 便于发现共享主机和值得用 Host 头探测的虚拟主机；没有 IP 的子域名不会出现在结果中
+func (o *OutputManager) exportIPJSON(results []SubdomainResult) error {
+	groups := groupByIP(results)
+
+	file, err := os.Create(o.outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create IP JSON file: %v", err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(groups); err != nil {
+		return fmt.Errorf("failed to encode IP JSON: %v", err)
+	}
+
+	logger.Infof("Exported %d IPs for %d results to IP JSON: %s", len(groups), len(results), o.outputPath)
+	return nil
+}
+
+// groupByIP 把结果按 IP 分组，每个 IP 下的子域名去重并排序
+func groupByIP(results []SubdomainResult) map[string][]string {
+	seen := make(map[string]map[string]bool)
+	for _, result := range results {
+		for _, ip := range result.IP {
+			if ip == "" {
+				continue
+			}
+			if seen[ip] == nil {
+				seen[ip] = make(map[string]bool)
+			}
+			seen[ip][result.Subdomain] = true
+		}
+	}
+
+	groups := make(map[string][]string, len(seen))
+	for ip, subdomains := range seen {
+		list := make([]string, 0, len(subdomains))
+		for subdomain := range subdomains {
+			list = append(list, subdomain)
+		}
+		sort.Strings(list)
+		groups[ip] = list
+	}
+	return groups
+}
@@ -0,0 +1,78 @@
+package core
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/oneforall-go/pkg/logger"
+)
+
+// resetResultCount 开始新一轮枚举时重置已收集的结果，initial 为预置的种子子域名
+func (d *Dispatcher) resetResultCount(initial []string) {
+	d.resultMutex.Lock()
+	defer d.resultMutex.Unlock()
+	d.collected = make(map[string]bool, len(initial))
+	for _, subdomain := range initial {
+		d.collected[NormalizeHost(subdomain)] = true
+	}
+	atomic.StoreInt64(&d.resultCount, int64(len(d.collected)))
+	atomic.StoreInt32(&d.limitLogged, 0)
+}
+
+// maxResultsReached 判断收集的结果数是否已达到 MaxResults 上限
+func (d *Dispatcher) maxResultsReached() bool {
+	return d.config.MaxResults > 0 && atomic.LoadInt64(&d.resultCount) >= int64(d.config.MaxResults)
+}
+
+// reserveResults 按上限为模块结果预留名额，返回可保留的结果和本次是否达到上限；
+// 按去重后的子域名计数，其他模块已收集的子域名不占用名额，照常保留以合并来源
+func (d *Dispatcher) reserveResults(results []string) ([]string, bool) {
+	d.resultMutex.Lock()
+	defer d.resultMutex.Unlock()
+	if d.collected == nil {
+		d.collected = make(map[string]bool)
+	}
+
+	limit := d.config.MaxResults
+	keep := make([]string, 0, len(results))
+	for _, result := range results {
+		key := NormalizeHost(result)
+		if !d.collected[key] {
+			if limit > 0 && len(d.collected) >= limit {
+				continue
+			}
+			d.collected[key] = true
+		}
+		keep = append(keep, result)
+	}
+	atomic.StoreInt64(&d.resultCount, int64(len(d.collected)))
+	return keep, limit > 0 && len(d.collected) >= limit
+}
+
+// logLimitReached 达到结果上限时只记录一次日志
+func (d *Dispatcher) logLimitReached(domain string) {
+	if atomic.CompareAndSwapInt32(&d.limitLogged, 0, 1) {
+		logger.Warnf("Result cap of %d reached for %s, stopping remaining modules and proceeding to validation/export",
+			d.config.MaxResults, domain)
+	}
+}
+
+// SetContext 设置运行上下文，需在运行前调用。上下文取消或超时（如 --max-runtime）后调度器停止启动新的模块，
+// 取消运行中的模块（包括爆破），并跳过验证直接返回已收集的结果
+func (d *Dispatcher) SetContext(ctx context.Context) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	d.ctx = ctx
+}
+
+// budgetExpired 判断运行上下文是否已取消
+func (d *Dispatcher) budgetExpired() bool {
+	return d.ctx.Err() != nil
+}
+
+// logBudgetExpired 记录运行预算耗尽时的进度
+func (d *Dispatcher) logBudgetExpired(domain, stepName string, step, total int) {
+	logger.Warnf("Run budget expired for %s before step %d/%d (%s), proceeding to export with %d collected subdomains",
+		domain, step, total, stepName, atomic.LoadInt64(&d.resultCount))
+}
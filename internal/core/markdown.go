@@ -0,0 +1,110 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/oneforall-go/pkg/logger"
+)
+
+// exportMarkdown 导出为 Markdown 报告（统计摘要 + 结果表格）
+func (o *OutputManager) exportMarkdown(results []SubdomainResult) error {
+	var b strings.Builder
+	stats := o.stats()
+
+	fmt.Fprintf(&b, "# Subdomain Report: %s\n\n", o.targetDomain())
+	fmt.Fprintf(&b, "Generated at %s\n\n", time.Now().Format("2006-01-02 15:04:05"))
+
+	// 统计摘要
+	b.WriteString("## Summary\n\n")
+	b.WriteString("| Metric | Value |\n")
+	b.WriteString("|--------|-------|\n")
+	fmt.Fprintf(&b, "| Total | %v |\n", stats["total"])
+	if o.discoveryOnly {
+		fmt.Fprintf(&b, "| Unvalidated | %v |\n", stats["unvalidated"])
+	} else {
+		fmt.Fprintf(&b, "| Alive | %v |\n", stats["alive"])
+		fmt.Fprintf(&b, "| Dead | %v |\n", stats["dead"])
+	}
+	if len(results) != len(o.results) {
+		fmt.Fprintf(&b, "| Exported | %d |\n", len(results))
+	}
+	if sources, ok := stats["sources"].(map[string]int); ok {
+		for _, source := range sortedKeys(sources) {
+			fmt.Fprintf(&b, "| Source: %s | %d |\n", escapeMarkdownCell(source), sources[source])
+		}
+	}
+	if providers, ok := stats["providers"].(map[string]int); ok {
+		for _, provider := range sortedKeys(providers) {
+			fmt.Fprintf(&b, "| Provider: %s | %d |\n", escapeMarkdownCell(provider), providers[provider])
+		}
+	}
+	if reasons, ok := stats["dead_reasons"].(map[string]int); ok {
+		for _, reason := range sortedKeys(reasons) {
+			fmt.Fprintf(&b, "| Dead reason: %s | %d |\n", escapeMarkdownCell(reason), reasons[reason])
+		}
+	}
+
+	// 结果表格，未验证的结果只有子域名和来源
+	b.WriteString("\n## Results\n\n")
+	if o.discoveryOnly {
+		b.WriteString("| Subdomain | Source |\n")
+		b.WriteString("|-----------|--------|\n")
+		for _, result := range results {
+			fmt.Fprintf(&b, "| %s | %s |\n", escapeMarkdownCell(result.Subdomain), escapeMarkdownCell(result.Source))
+		}
+	} else {
+		writeMarkdownResults(&b, results)
+	}
+
+	if err := os.WriteFile(o.outputPath, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write Markdown file: %v", err)
+	}
+
+	logger.Infof("Exported %d results to Markdown: %s", len(results), o.outputPath)
+	return nil
+}
+
+// writeMarkdownResults 写入带验证信息的结果表格
+func writeMarkdownResults(b *strings.Builder, results []SubdomainResult) {
+	b.WriteString("| Subdomain | IP | Status | Title | Source | Alive |\n")
+	b.WriteString("|-----------|----|--------|-------|--------|-------|\n")
+	for _, result := range results {
+		status := result.StatusCode
+		if status == 0 {
+			status = result.Status
+		}
+		statusText := ""
+		if status != 0 {
+			statusText = fmt.Sprintf("%d", status)
+		}
+
+		fmt.Fprintf(b, "| %s | %s | %s | %s | %s | %t |\n",
+			escapeMarkdownCell(result.Subdomain),
+			escapeMarkdownCell(strings.Join(result.IP, ", ")),
+			statusText,
+			escapeMarkdownCell(result.Title),
+			escapeMarkdownCell(result.Source),
+			result.Alive)
+	}
+}
+
+// escapeMarkdownCell 转义表格单元格中的竖线和换行，避免破坏表格结构
+func escapeMarkdownCell(value string) string {
+	value = strings.ReplaceAll(value, "|", "\\|")
+	value = strings.ReplaceAll(value, "\r", "")
+	return strings.ReplaceAll(value, "\n", " ")
+}
+
+// sortedKeys 返回排序后的键列表
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
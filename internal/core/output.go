@@ -6,97 +6,263 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/oneforall-go/internal/config"
+	"github.com/oneforall-go/internal/dedup"
 	"github.com/oneforall-go/internal/validator"
 	"github.com/oneforall-go/pkg/logger"
 )
 
 // SubdomainResult 子域名结果
 type SubdomainResult struct {
-	Subdomain   string   `json:"subdomain"`
-	IP          []string `json:"ip"`
-	Status      int      `json:"status"`
-	Title       string   `json:"title"`
-	Port        int      `json:"port"`
-	Alive       bool     `json:"alive"`
-	Source      string   `json:"source"`
-	Time        string   `json:"time"`
-	Provider    string   `json:"provider"`
-	DNSResolved bool     `json:"dns_resolved"`
-	PingAlive   bool     `json:"ping_alive"`
-	StatusCode  int      `json:"status_code"`
-	StatusText  string   `json:"status_text"`
-}
-
-// OutputManager 输出管理器
+	Subdomain   string      `json:"subdomain"`
+	IP          []string    `json:"ip"`
+	Status      int         `json:"status"`
+	Title       string      `json:"title"`
+	Port        int         `json:"port"`
+	Alive       bool        `json:"alive"`
+	Source      string      `json:"source"`
+	Time        string      `json:"time"`
+	Provider    string      `json:"provider"`
+	DNSResolved bool        `json:"dns_resolved"`
+	PingAlive   bool        `json:"ping_alive"`
+	PingMethod  string      `json:"ping_method"`
+	StatusCode  int         `json:"status_code"`
+	StatusText  string      `json:"status_text"`
+	CertIssuer  string      `json:"cert_issuer"`
+	CertExpiry  string      `json:"cert_expiry"`
+	TLSVersion  string      `json:"tls_version,omitempty"` // HTTPS 探测协商的 TLS 版本
+	CNAME       []string    `json:"cname"`
+	URL         string      `json:"url"`             // HTTP 探测跟随跳转后的最终 URL
+	Ports       map[int]int `json:"ports,omitempty"` // 各 TCP 验证端口的 HTTP 状态码
+	Confidence  int         `json:"confidence"`      // 0-100 的置信度，由通过的验证项计算
+
+	// Web 技术指纹（开启 fingerprint 时记录）
+	Server       string   `json:"server,omitempty"`
+	PoweredBy    string   `json:"powered_by,omitempty"`
+	Technologies []string `json:"technologies,omitempty"`
+}
+
+// OutputManager 输出管理器，结果的添加、读取和导出可以在多个 goroutine 中并发调用
 type OutputManager struct {
+	mutex      sync.Mutex
 	config     *config.Config
 	results    []SubdomainResult
+	index      map[string]int // 子域名在 results 中的位置，添加时据此合并重复的子域名
 	outputPath string
 	format     string
+	filter     *ResultFilter
+
+	// 基线结果，用于生成差异报告
+	baseline     []SubdomainResult
+	baselinePath string
+
+	// 关联域名（非目标子域名），单独导出到 *_related.txt
+	related map[string]bool
+
+	// 运行异常中断时的导出，文件名带 _partial 后缀
+	partial bool
+
+	// 结果所属的目标域名，为空时从结果中推断
+	domain string
+
+	// 运行开始时间，用于统计文件中的运行耗时
+	startTime time.Time
+
+	// 只做发现、不验证（--no-validation 或被动模式），导出精简结果，统计为未验证而不是未存活
+	discoveryOnly bool
 }
 
 // NewOutputManager 创建输出管理器
 func NewOutputManager(cfg *config.Config) *OutputManager {
 	return &OutputManager{
-		config:  cfg,
-		results: make([]SubdomainResult, 0),
-		format:  cfg.ResultSaveFormat,
+		config:    cfg,
+		results:   make([]SubdomainResult, 0),
+		index:     make(map[string]int),
+		format:    cfg.ResultSaveFormat,
+		startTime: time.Now(),
 	}
 }
 
-// AddResult 添加结果
+// AddResult 添加结果，已有的子域名不会重复添加，而是合并到已有结果中（与 Deduplicate 的合并规则相同）
 func (o *OutputManager) AddResult(result SubdomainResult) {
-	o.results = append(o.results, result)
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.add(result)
 }
 
 // AddResults 添加多个结果
 func (o *OutputManager) AddResults(results []SubdomainResult) {
-	o.results = append(o.results, results...)
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	for _, result := range results {
+		o.add(result)
+	}
+}
+
+// add 添加单条结果，重复的子域名合并到已有结果，调用方需持有锁
+func (o *OutputManager) add(result SubdomainResult) {
+	if o.index == nil {
+		o.reindex()
+	}
+	if i, ok := o.index[result.Subdomain]; ok {
+		mergeResult(&o.results[i], result)
+		return
+	}
+	o.index[result.Subdomain] = len(o.results)
+	o.results = append(o.results, result)
+}
+
+// reindex 重建子域名索引，结果被过滤或重新排序后调用，调用方需持有锁
+func (o *OutputManager) reindex() {
+	o.index = make(map[string]int, len(o.results))
+	for i, result := range o.results {
+		if _, ok := o.index[result.Subdomain]; !ok {
+			o.index[result.Subdomain] = i
+		}
+	}
+}
+
+// Reset 清空结果和关联域名，用于处理下一个域名时复用输出管理器；输出格式、路径、过滤条件和基线保持不变
+func (o *OutputManager) Reset() {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.results = make([]SubdomainResult, 0)
+	o.index = make(map[string]int)
+	o.related = nil
 }
 
 // AddValidationResults 添加验证结果
 func (o *OutputManager) AddValidationResults(results []validator.ValidationResult) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
 	for _, result := range results {
 		// 添加所有验证结果，包括验证不通过的域名
-		o.results = append(o.results, SubdomainResult{
-			Subdomain:   result.Subdomain,
-			IP:          result.IP,
-			Status:      result.Status,
-			Title:       result.Title,
-			Port:        result.Port,
-			Alive:       result.Alive,
-			Source:      result.Source,
-			Time:        result.Time,
-			Provider:    result.Provider,
-			DNSResolved: result.DNSResolved,
-			PingAlive:   result.PingAlive,
-			StatusCode:  result.StatusCode,
-			StatusText:  result.StatusText,
-		})
+		converted := SubdomainResult{
+			Subdomain: result.Subdomain,
+			Title:     result.Title,
+			Port:      result.Port,
+			Source:    result.Source,
+			Time:      result.Time,
+		}
+		ApplyValidation(&converted, result)
+		o.add(converted)
 	}
 }
 
+// ApplyValidation 用验证结果更新子域名结果的解析、存活、状态、服务商和证书信息
+func ApplyValidation(result *SubdomainResult, validation validator.ValidationResult) {
+	result.IP = validation.IP
+	result.Alive = validation.Alive
+	result.DNSResolved = validation.DNSResolved
+	result.PingAlive = validation.PingAlive
+	result.PingMethod = validation.PingMethod
+	result.Status = validation.Status
+	result.StatusCode = validation.StatusCode
+	result.StatusText = validation.StatusText
+	result.Title = validation.Title
+	result.URL = validation.FinalURL
+	result.Provider = validation.Provider
+	result.CertIssuer = validation.CertIssuer
+	result.CertExpiry = validation.CertExpiry
+	result.TLSVersion = validation.TLSVersion
+	result.CNAME = validation.CNAME
+	result.Ports = validation.Ports
+	result.Confidence = validation.Confidence
+	result.Server = validation.Server
+	result.PoweredBy = validation.PoweredBy
+	result.Technologies = validation.Technologies
+}
+
 // SetOutputPath 设置输出路径
 func (o *OutputManager) SetOutputPath(path string) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
 	o.outputPath = path
 }
 
 // SetFormat 设置输出格式
 func (o *OutputManager) SetFormat(format string) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
 	o.format = format
 }
 
-// GetResults 获取所有结果
+// SetFilter 设置导出前的正则过滤器
+func (o *OutputManager) SetFilter(filter *ResultFilter) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.filter = filter
+}
+
+// SetBaseline 设置基线结果，导出时额外生成差异报告
+func (o *OutputManager) SetBaseline(path string, baseline []SubdomainResult) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.baselinePath = path
+	o.baseline = baseline
+}
+
+// GetResults 获取所有结果的副本
 func (o *OutputManager) GetResults() []SubdomainResult {
-	return o.results
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return append([]SubdomainResult(nil), o.results...)
 }
 
 // FilterAlive 过滤存活结果
 func (o *OutputManager) FilterAlive() []SubdomainResult {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.filterAlive()
+}
+
+// FilterDead 过滤未存活结果
+func (o *OutputManager) FilterDead() []SubdomainResult {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.filterDead()
+}
+
+// filterDead 过滤未存活结果，调用方需持有锁
+func (o *OutputManager) filterDead() []SubdomainResult {
+	var deadResults []SubdomainResult
+	for _, result := range o.results {
+		if !result.Alive {
+			deadResults = append(deadResults, result)
+		}
+	}
+	return deadResults
+}
+
+// DeadReason 返回未存活结果的失败原因（验证时记录的 StatusText），未经验证的结果归为 "Not Validated"
+func DeadReason(result SubdomainResult) string {
+	if result.StatusText == "" {
+		return "Not Validated"
+	}
+	return result.StatusText
+}
+
+// FilterConfidence 过滤出置信度不低于 min 的结果，min 不大于 0 时返回全部结果
+func FilterConfidence(results []SubdomainResult, min int) []SubdomainResult {
+	if min <= 0 {
+		return results
+	}
+	filtered := make([]SubdomainResult, 0, len(results))
+	for _, result := range results {
+		if result.Confidence >= min {
+			filtered = append(filtered, result)
+		}
+	}
+	return filtered
+}
+
+// filterAlive 过滤存活结果，调用方需持有锁
+func (o *OutputManager) filterAlive() []SubdomainResult {
 	var aliveResults []SubdomainResult
 	for _, result := range o.results {
 		if result.Alive {
@@ -106,40 +272,240 @@ func (o *OutputManager) FilterAlive() []SubdomainResult {
 	return aliveResults
 }
 
-// Deduplicate 去重
+// Deduplicate 去重，同一子域名的多条结果合并为一条：合并 IP 和来源，保留存活状态更完整的验证信息
 func (o *OutputManager) Deduplicate() {
-	seen := make(map[string]bool)
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.deduplicate()
+}
+
+// deduplicate 去重，调用方需持有锁。结果数超过 dedup_bloom_threshold 时先用布隆过滤器找出可能重复的子域名，
+// 只为这些子域名建立索引；误判只会让不重复的子域名多走一次索引，去重结果仍然精确
+func (o *OutputManager) deduplicate() {
+	var maybeDuplicate map[string]bool
+	if threshold := o.config.DedupBloomThreshold; threshold > 0 && len(o.results) > threshold {
+		filter := dedup.NewBloomFilter(len(o.results), o.config.DedupFalsePositiveRate)
+		maybeDuplicate = make(map[string]bool)
+		for _, result := range o.results {
+			if !filter.Add(result.Subdomain) {
+				maybeDuplicate[result.Subdomain] = true
+			}
+		}
+	}
+
+	index := make(map[string]int)
 	var uniqueResults []SubdomainResult
 
 	for _, result := range o.results {
-		if !seen[result.Subdomain] {
-			seen[result.Subdomain] = true
+		if maybeDuplicate != nil && !maybeDuplicate[result.Subdomain] {
 			uniqueResults = append(uniqueResults, result)
+			continue
+		}
+		if i, ok := index[result.Subdomain]; ok {
+			mergeResult(&uniqueResults[i], result)
+			continue
 		}
+		index[result.Subdomain] = len(uniqueResults)
+		uniqueResults = append(uniqueResults, result)
 	}
 
 	o.results = uniqueResults
+	if maybeDuplicate != nil {
+		// 索引只包含部分子域名，下次添加结果时重建
+		o.index = nil
+		return
+	}
+	o.index = index
+}
+
+// SortResults 按反转的域名标签稳定排序（如 cn.ex.a 排在 cn.ex.b 之前），使同一父域名下的子域名相邻，
+// 标签相同时按原始子域名排序
+func SortResults(results []SubdomainResult) {
+	keys := make(map[string]string, len(results))
+	for _, result := range results {
+		if _, ok := keys[result.Subdomain]; !ok {
+			keys[result.Subdomain] = reversedDomainKey(result.Subdomain)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		ki, kj := keys[results[i].Subdomain], keys[results[j].Subdomain]
+		if ki != kj {
+			return ki < kj
+		}
+		return results[i].Subdomain < results[j].Subdomain
+	})
+}
+
+// reversedDomainKey 返回按标签反转的排序键，标签间以 \x00 分隔，保证父域名排在其子域名之前
+func reversedDomainKey(subdomain string) string {
+	labels := strings.Split(NormalizeHost(subdomain), ".")
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+	return strings.Join(labels, "\x00")
+}
+
+// mergeResult 将 src 合并到 dst：IP 与来源取并集，src 存活而 dst 未存活时采用 src 的状态信息，其余字段只补全空值
+func mergeResult(dst *SubdomainResult, src SubdomainResult) {
+	// 复制后再追加，避免修改原结果共享的底层数组
+	dst.IP = appendUnique(append([]string{}, dst.IP...), src.IP...)
+	dst.Source = strings.Join(appendUnique(SplitSources(dst.Source), SplitSources(src.Source)...), ",")
+	dst.DNSResolved = dst.DNSResolved || src.DNSResolved
+	if src.Confidence > dst.Confidence {
+		dst.Confidence = src.Confidence
+	}
+
+	if src.Alive && !dst.Alive {
+		dst.Alive = true
+		dst.Status = src.Status
+		dst.StatusCode = src.StatusCode
+		dst.StatusText = src.StatusText
+		dst.Port = src.Port
+		dst.PingAlive = src.PingAlive
+		dst.PingMethod = src.PingMethod
+		if src.Title != "" {
+			dst.Title = src.Title
+		}
+		if src.Provider != "" {
+			dst.Provider = src.Provider
+		}
+		if src.CertIssuer != "" {
+			dst.CertIssuer = src.CertIssuer
+			dst.CertExpiry = src.CertExpiry
+		}
+		if src.TLSVersion != "" {
+			dst.TLSVersion = src.TLSVersion
+		}
+		if len(src.CNAME) > 0 {
+			dst.CNAME = src.CNAME
+		}
+		if src.URL != "" {
+			dst.URL = src.URL
+		}
+		if len(src.Ports) > 0 {
+			dst.Ports = src.Ports
+		}
+		if src.Server != "" || len(src.Technologies) > 0 {
+			dst.Server = src.Server
+			dst.PoweredBy = src.PoweredBy
+			dst.Technologies = src.Technologies
+		}
+		return
+	}
+
+	if dst.Status == 0 {
+		dst.Status = src.Status
+	}
+	if dst.StatusCode == 0 {
+		dst.StatusCode = src.StatusCode
+		dst.StatusText = src.StatusText
+	}
+	if dst.Port == 0 {
+		dst.Port = src.Port
+	}
+	if dst.Title == "" {
+		dst.Title = src.Title
+	}
+	if dst.URL == "" {
+		dst.URL = src.URL
+	}
+	if dst.Provider == "" {
+		dst.Provider = src.Provider
+	}
+	if !dst.PingAlive && src.PingAlive {
+		dst.PingAlive = true
+		dst.PingMethod = src.PingMethod
+	}
+	if dst.Time == "" {
+		dst.Time = src.Time
+	}
+	if dst.CertIssuer == "" {
+		dst.CertIssuer = src.CertIssuer
+		dst.CertExpiry = src.CertExpiry
+	}
+	if dst.TLSVersion == "" {
+		dst.TLSVersion = src.TLSVersion
+	}
+	if len(dst.CNAME) == 0 {
+		dst.CNAME = src.CNAME
+	}
+	if dst.Server == "" && len(dst.Technologies) == 0 {
+		dst.Server = src.Server
+		dst.PoweredBy = src.PoweredBy
+		dst.Technologies = src.Technologies
+	}
+}
+
+// SplitSources 拆分逗号分隔的来源（合并后的结果可能有多个来源）
+func SplitSources(source string) []string {
+	var sources []string
+	for _, s := range strings.Split(source, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			sources = append(sources, s)
+		}
+	}
+	return sources
+}
+
+// appendUnique 追加不重复的非空元素
+func appendUnique(items []string, values ...string) []string {
+	for _, value := range values {
+		if value == "" || containsInSlice(items, value) {
+			continue
+		}
+		items = append(items, value)
+	}
+	return items
 }
 
 // Export 导出结果
 func (o *OutputManager) Export() error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
 	if len(o.results) == 0 {
 		logger.Warn("No results to export")
 		return nil
 	}
 
 	// 去重
-	o.Deduplicate()
+	o.deduplicate()
 
-	// 根据配置过滤存活结果
-	if o.config.ResultExportAlive {
-		o.results = o.FilterAlive()
+	// 正则过滤
+	o.results = o.filter.Apply(o.results)
+
+	// 排序，保证多次运行的输出顺序一致
+	SortResults(o.results)
+	o.reindex()
+
+	// 只写入存活结果，内存中保留全部结果用于统计；未验证的结果没有存活状态和置信度，全部导出
+	exported := o.results
+	if o.discoveryOnly {
+		if o.config.ExportDeadOnly || o.config.ExportAliveOnly || o.config.MinConfidence > 0 {
+			logger.Warnf("Results are not validated, ignoring alive/dead and confidence filters")
+		}
+		logger.Infof("Exporting %d unvalidated candidates", len(exported))
+	} else if o.config.ExportDeadOnly {
+		exported = o.filterDead()
+		logger.Infof("Exporting %d dead results (%d alive results excluded from file)", len(exported), len(o.results)-len(exported))
+	} else if o.config.ExportAliveOnly {
+		exported = o.filterAlive()
+		logger.Infof("Exporting %d alive results (%d dead results excluded from file)", len(exported), len(o.results)-len(exported))
+	}
+	if o.config.MinConfidence > 0 && !o.discoveryOnly {
+		before := len(exported)
+		exported = FilterConfidence(exported, o.config.MinConfidence)
+		logger.Infof("Exporting %d results with confidence >= %d (%d excluded from file)", len(exported), o.config.MinConfidence, before-len(exported))
 	}
 
 	// 生成输出路径
 	if o.outputPath == "" {
 		o.outputPath = o.generateOutputPath()
 	}
+	if o.partial {
+		o.outputPath = partialPath(o.outputPath)
+	}
 
 	// 确保输出目录存在
 	outputDir := filepath.Dir(o.outputPath)
@@ -148,18 +514,56 @@ func (o *OutputManager) Export() error {
 	}
 
 	// 根据格式导出
+	var err error
 	switch o.format {
 	case "csv":
-		return o.exportCSV()
+		err = o.exportCSV(exported)
 	case "json":
-		return o.exportJSON()
+		err = o.exportJSON(exported)
+	case "elasticsearch":
+		err = o.exportElasticsearch(exported)
+	case "md":
+		err = o.exportMarkdown(exported)
+	case "txt":
+		err = o.exportTXT(exported)
+	case "ipjson":
+		err = o.exportIPJSON(exported)
 	default:
-		return fmt.Errorf("unsupported format: %s", o.format)
+		err = fmt.Errorf("unsupported format: %s", o.format)
+	}
+	if err != nil {
+		return err
+	}
+
+	// 导出关联域名
+	if err := o.exportRelated(); err != nil {
+		return err
+	}
+
+	// 写入统计文件
+	if o.config.StatsFile {
+		if err := o.exportStats(); err != nil {
+			return err
+		}
+	}
+
+	// 更新顶层索引
+	if o.perDomain() {
+		if err := o.updateIndex(); err != nil {
+			return err
+		}
 	}
+
+	// 生成基线差异报告
+	if o.baseline != nil {
+		return o.exportDiff()
+	}
+
+	return nil
 }
 
 // exportCSV 导出为 CSV
-func (o *OutputManager) exportCSV() error {
+func (o *OutputManager) exportCSV(results []SubdomainResult) error {
 	file, err := os.Create(o.outputPath)
 	if err != nil {
 		return fmt.Errorf("failed to create CSV file: %v", err)
@@ -169,40 +573,109 @@ func (o *OutputManager) exportCSV() error {
 	writer := csv.NewWriter(file)
 	defer writer.Flush()
 
-	// 写入表头
-	headers := []string{"subdomain", "ip", "status", "title", "port", "alive", "source", "time", "provider", "dns_resolved", "ping_alive", "status_code", "status_text"}
+	// 按 csv_columns 选择列，未配置时输出全部默认列，未验证的结果只输出子域名、来源和时间
+	names := o.config.CSVColumns
+	if len(names) == 0 && o.discoveryOnly {
+		names = discoveryCSVColumns
+	}
+	columns, err := csvColumns(names)
+	if err != nil {
+		return err
+	}
+	headers := make([]string, len(columns))
+	for i, column := range columns {
+		headers[i] = column.name
+	}
 	if err := writer.Write(headers); err != nil {
 		return fmt.Errorf("failed to write CSV header: %v", err)
 	}
 
 	// 写入数据
-	for _, result := range o.results {
-		row := []string{
-			result.Subdomain,
-			strings.Join(result.IP, ","),
-			fmt.Sprintf("%d", result.Status),
-			result.Title,
-			fmt.Sprintf("%d", result.Port),
-			fmt.Sprintf("%t", result.Alive),
-			result.Source,
-			result.Time,
-			result.Provider,
-			fmt.Sprintf("%t", result.DNSResolved),
-			fmt.Sprintf("%t", result.PingAlive),
-			fmt.Sprintf("%d", result.StatusCode),
-			result.StatusText,
+	row := make([]string, len(columns))
+	for _, result := range results {
+		for i, column := range columns {
+			row[i] = column.value(result)
 		}
 		if err := writer.Write(row); err != nil {
 			return fmt.Errorf("failed to write CSV row: %v", err)
 		}
 	}
 
-	logger.Infof("Exported %d results to CSV: %s", len(o.results), o.outputPath)
+	logger.Infof("Exported %d results to CSV: %s", len(results), o.outputPath)
 	return nil
 }
 
+// csvColumn CSV 结果的一列
+type csvColumn struct {
+	name  string
+	value func(result SubdomainResult) string
+}
+
+// csvColumnValues CSV 列名（与 config.CSVColumns 一致）到取值函数的映射
+var csvColumnValues = map[string]func(result SubdomainResult) string{
+	"subdomain":    func(r SubdomainResult) string { return r.Subdomain },
+	"ip":           func(r SubdomainResult) string { return strings.Join(r.IP, ",") },
+	"status":       func(r SubdomainResult) string { return fmt.Sprintf("%d", r.Status) },
+	"title":        func(r SubdomainResult) string { return r.Title },
+	"port":         func(r SubdomainResult) string { return fmt.Sprintf("%d", r.Port) },
+	"alive":        func(r SubdomainResult) string { return fmt.Sprintf("%t", r.Alive) },
+	"source":       func(r SubdomainResult) string { return r.Source },
+	"time":         func(r SubdomainResult) string { return r.Time },
+	"provider":     func(r SubdomainResult) string { return r.Provider },
+	"dns_resolved": func(r SubdomainResult) string { return fmt.Sprintf("%t", r.DNSResolved) },
+	"ping_alive":   func(r SubdomainResult) string { return fmt.Sprintf("%t", r.PingAlive) },
+	"status_code":  func(r SubdomainResult) string { return fmt.Sprintf("%d", r.StatusCode) },
+	"status_text":  func(r SubdomainResult) string { return r.StatusText },
+	"cert_issuer":  func(r SubdomainResult) string { return r.CertIssuer },
+	"cert_expiry":  func(r SubdomainResult) string { return r.CertExpiry },
+	"cname":        func(r SubdomainResult) string { return strings.Join(r.CNAME, ",") },
+	"url":          func(r SubdomainResult) string { return r.URL },
+	"ports":        func(r SubdomainResult) string { return formatPorts(r.Ports) },
+	"confidence":   func(r SubdomainResult) string { return fmt.Sprintf("%d", r.Confidence) },
+	"server":       func(r SubdomainResult) string { return r.Server },
+	"powered_by":   func(r SubdomainResult) string { return r.PoweredBy },
+	"technologies": func(r SubdomainResult) string { return strings.Join(r.Technologies, ",") },
+	"tls_version":  func(r SubdomainResult) string { return r.TLSVersion },
+}
+
+// csvColumns 按列名（不区分大小写）返回要导出的列，names 为空时返回 config.CSVColumns 中的全部列
+func csvColumns(names []string) ([]csvColumn, error) {
+	if len(names) == 0 {
+		names = config.CSVColumns
+	}
+	if err := config.ValidateCSVColumns(names); err != nil {
+		return nil, err
+	}
+
+	columns := make([]csvColumn, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		value, ok := csvColumnValues[name]
+		if !ok {
+			return nil, fmt.Errorf("CSV column %q has no value mapping", name)
+		}
+		columns = append(columns, csvColumn{name: name, value: value})
+	}
+	return columns, nil
+}
+
+// formatPorts 将端口状态码格式化为 "8080:200,8443:401"，按端口排序
+func formatPorts(ports map[int]int) string {
+	keys := make([]int, 0, len(ports))
+	for port := range ports {
+		keys = append(keys, port)
+	}
+	sort.Ints(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, port := range keys {
+		parts = append(parts, fmt.Sprintf("%d:%d", port, ports[port]))
+	}
+	return strings.Join(parts, ",")
+}
+
 // exportJSON 导出为 JSON
-func (o *OutputManager) exportJSON() error {
+func (o *OutputManager) exportJSON(results []SubdomainResult) error {
 	file, err := os.Create(o.outputPath)
 	if err != nil {
 		return fmt.Errorf("failed to create JSON file: %v", err)
@@ -212,57 +685,134 @@ func (o *OutputManager) exportJSON() error {
 	encoder := json.NewEncoder(file)
 	encoder.SetIndent("", "  ")
 
-	if err := encoder.Encode(o.results); err != nil {
+	// 默认输出结果数组，开启 json_envelope 时包装为带版本和统计信息的信封；
+	// 未验证的结果数组只包含子域名、来源和时间，信封保留完整字段并标记 unvalidated
+	var payload interface{} = results
+	if o.discoveryOnly {
+		payload = toDiscoveryResults(results)
+	}
+	if o.config.JSONEnvelope {
+		payload = JSONEnvelope{
+			Version:     JSONEnvelopeVersion,
+			Domain:      o.targetDomain(),
+			GeneratedAt: time.Now().Format("2006-01-02 15:04:05"),
+			Stats:       o.stats(),
+			Results:     results,
+			Partial:     o.partial,
+			Unvalidated: o.discoveryOnly,
+		}
+	}
+
+	if err := encoder.Encode(payload); err != nil {
 		return fmt.Errorf("failed to encode JSON: %v", err)
 	}
 
-	logger.Infof("Exported %d results to JSON: %s", len(o.results), o.outputPath)
+	logger.Infof("Exported %d results to JSON: %s", len(results), o.outputPath)
 	return nil
 }
 
-// generateOutputPath 生成输出路径
+// JSONEnvelopeVersion JSON 信封格式版本，字段发生不兼容变更时递增
+const JSONEnvelopeVersion = 1
+
+// JSONEnvelope 带版本的 JSON 导出格式（json_envelope 开启时使用），stats 与 GetStats 一致并包含全部结果，
+// results 为写入文件的结果（受 export_alive_only/export_dead_only 影响）
+type JSONEnvelope struct {
+	Version     int                    `json:"version"`
+	Domain      string                 `json:"domain"`
+	GeneratedAt string                 `json:"generated_at"`
+	Stats       map[string]interface{} `json:"stats"`
+	Results     []SubdomainResult      `json:"results"`
+	Partial     bool                   `json:"partial,omitempty"`     // 运行异常中断，结果不完整
+	Unvalidated bool                   `json:"unvalidated,omitempty"` // 结果未经验证，alive 等验证字段没有意义
+}
+
+// generateOutputPath 按 output_template 生成输出路径，按域名分目录输出时放在 <domain>/ 目录下
 func (o *OutputManager) generateOutputPath() string {
-	timestamp := time.Now().Format("20060102_150405")
-	domain := "unknown"
+	template := o.config.OutputTemplate
+	if template == "" || config.ValidateOutputTemplate(template) != nil {
+		template = config.DefaultOutputTemplate
+	}
+	domain := o.targetDomain()
+	filename := expandOutputTemplate(template, domain, o.format, time.Now())
+	if o.perDomain() {
+		return filepath.Join(o.config.ResultSavePath, domain, filepath.FromSlash(filename))
+	}
+	return filepath.Join(o.config.ResultSavePath, filepath.FromSlash(filename))
+}
+
+// expandOutputTemplate 替换文件名模板中的 {domain}、{date}、{time}、{format}/{ext}
+func expandOutputTemplate(template, domain, format string, now time.Time) string {
+	return strings.NewReplacer(
+		"{domain}", domain,
+		"{date}", now.Format("20060102"),
+		"{time}", now.Format("150405"),
+		"{format}", format,
+		"{ext}", format,
+	).Replace(template)
+}
+
+// targetDomain 返回设置的目标域名，未设置时从第一个结果中提取主域名
+func (o *OutputManager) targetDomain() string {
+	if o.domain != "" {
+		return o.domain
+	}
 	if len(o.results) > 0 {
-		// 从第一个结果中提取域名
 		subdomain := o.results[0].Subdomain
 		if parts := strings.Split(subdomain, "."); len(parts) >= 2 {
-			domain = strings.Join(parts[len(parts)-2:], ".")
+			return strings.Join(parts[len(parts)-2:], ".")
 		}
 	}
-
-	filename := fmt.Sprintf("%s_%s.%s", domain, timestamp, o.format)
-	return filepath.Join(o.config.ResultSavePath, filename)
+	return "unknown"
 }
 
 // GetOutputPath 获取输出路径
 func (o *OutputManager) GetOutputPath() string {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
 	return o.outputPath
 }
 
 // GetStats 获取统计信息
 func (o *OutputManager) GetStats() map[string]interface{} {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.stats()
+}
+
+// stats 统计结果，调用方需持有锁
+func (o *OutputManager) stats() map[string]interface{} {
 	total := len(o.results)
 	alive := 0
 	sources := make(map[string]int)
 	providers := make(map[string]int)
+	deadReasons := make(map[string]int)
 
+	unvalidated := 0
 	for _, result := range o.results {
-		if result.Alive {
+		if o.discoveryOnly {
+			unvalidated++
+		} else if result.Alive {
 			alive++
+		} else {
+			deadReasons[DeadReason(result)]++
+		}
+		// 合并后的结果按每个来源分别计数
+		for _, source := range SplitSources(result.Source) {
+			sources[source]++
 		}
-		sources[result.Source]++
 		if result.Provider != "" {
 			providers[result.Provider]++
 		}
 	}
 
 	return map[string]interface{}{
-		"total":     total,
-		"alive":     alive,
-		"dead":      total - alive,
-		"sources":   sources,
-		"providers": providers,
+		"total":        total,
+		"alive":        alive,
+		"dead":         total - alive - unvalidated,
+		"unvalidated":  unvalidated,
+		"dead_reasons": deadReasons,
+		"sources":      sources,
+		"providers":    providers,
+		"related":      len(o.related),
 	}
 }
@@ -0,0 +1,41 @@
+package core
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/oneforall-go/pkg/logger"
+)
+
+// PartialSuffix 运行异常中断时导出的结果文件名后缀（加在扩展名之前），表示结果不完整
+const PartialSuffix = "_partial"
+
+// ExportPartial 运行异常中断（panic、收到终止信号）时导出已收集的结果，文件名加 _partial 后缀，
+// JSON 信封中 partial 为 true；没有结果时不写文件，返回空路径
+func (o *OutputManager) ExportPartial() (string, error) {
+	o.mutex.Lock()
+	if len(o.results) == 0 {
+		o.mutex.Unlock()
+		return "", nil
+	}
+	o.partial = true
+	o.mutex.Unlock()
+
+	if err := o.Export(); err != nil {
+		return "", err
+	}
+
+	path := o.GetOutputPath()
+	logger.Warnf("Run aborted, exported incomplete results to %s", path)
+	return path, nil
+}
+
+// partialPath 在文件扩展名之前加上 _partial 后缀，已带后缀的路径保持不变
+func partialPath(path string) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	if strings.HasSuffix(base, PartialSuffix) {
+		return path
+	}
+	return base + PartialSuffix + ext
+}
@@ -0,0 +1,44 @@
+package core
+
+// activeSteps 会向目标本身（Web 服务、权威 DNS 服务器）发送流量的步骤，被动模式下全部跳过：
+//   - Crawl：爬虫模块，统一按主动处理
+//   - DNS Lookup：NS/MX/SOA/SPF/TXT 记录查询，查询最终落到目标的权威 DNS
+//   - Brute Force：字典爆破和 Alt 变体的 DNS 查询
+//   - File Check：robots.txt、sitemap、CSP、证书、AXFR、NSEC 遍历等直接访问目标
+//   - Enrich：解析已发现子域名的 IP
+//   - Validation：DNS 解析、Ping、HTTP 和证书探测
+//
+// 其余步骤（Fast Search、Dataset、Certificate、Intelligence）只查询第三方搜索引擎、
+// 数据集、证书透明度日志和情报接口，不与目标直接通信
+var activeSteps = map[string]bool{
+	"Crawl":       true,
+	"DNS Lookup":  true,
+	"Brute Force": true,
+	"File Check":  true,
+	"Enrich":      true,
+	"Validation":  true,
+}
+
+// IsPassiveStep 判断步骤是否不向目标发送流量
+func IsPassiveStep(name string) bool {
+	return !activeSteps[name]
+}
+
+// IsPassiveModule 判断模块是否不向目标发送流量；检查、爬虫和 DNS 查询模块按名称归入
+// Fast Search 步骤运行，因此除步骤外还要按模块名称和类型判断
+func IsPassiveModule(module Module) bool {
+	name := module.Name()
+	if isCrawlModule(name) || isDNSLookupModule(name) || isCheckModule(name) || isBruteModule(name) || isEnrichModule(name) {
+		return false
+	}
+	switch module.Type() {
+	case ModuleTypeBrute, ModuleTypeDNSLookup, ModuleTypeResolve, ModuleTypeCheck, ModuleTypeCrawl:
+		return false
+	}
+	return true
+}
+
+// skipForPassive 被动模式下跳过会接触目标的步骤
+func (d *Dispatcher) skipForPassive(stepName string) bool {
+	return d.config.Passive && !IsPassiveStep(stepName)
+}
@@ -6,18 +6,23 @@ import (
 	"io"
 	"net"
 	"net/http"
-	"regexp"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/oneforall-go/internal/config"
+	httpclient "github.com/oneforall-go/internal/http"
 	"github.com/oneforall-go/pkg/logger"
 )
 
 // PostProcessHosts 按标题去重并对403做限流
 // 仅当总数大于 cfg.ResultCheckLimit 时执行
 func PostProcessHosts(hosts []string, cfg *config.Config) []SubdomainResult {
+	// dry-run 和被动模式不发送 HTTP 请求
+	if cfg.DryRun || cfg.Passive {
+		return nil
+	}
+
 	limit := cfg.ResultCheckLimit
 	if limit <= 0 {
 		limit = 30
@@ -48,8 +53,6 @@ func PostProcessHosts(hosts []string, cfg *config.Config) []SubdomainResult {
 		},
 	}
 
-	titleRe := regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`) // case-insensitive, dotall
-
 	type tmp struct {
 		host     string
 		status   int
@@ -73,11 +76,11 @@ func PostProcessHosts(hosts []string, cfg *config.Config) []SubdomainResult {
 		defer func() { <-sem }()
 
 		// 优先HTTPS（忽略证书）
-		status, title := fetchOnce(insecureClient, ua, "https", host, titleRe)
+		status, title := fetchOnce(insecureClient, ua, "https", host)
 		protocol := "https"
 		if status != 200 {
 			// 回退HTTP
-			status, title = fetchOnce(client, ua, "http", host, titleRe)
+			status, title = fetchOnce(client, ua, "http", host)
 			protocol = "http"
 		}
 
@@ -147,7 +150,7 @@ func PostProcessHosts(hosts []string, cfg *config.Config) []SubdomainResult {
 	return final
 }
 
-func fetchOnce(client *http.Client, ua, scheme, host string, titleRe *regexp.Regexp) (int, string) {
+func fetchOnce(client *http.Client, ua, scheme, host string) (int, string) {
 	url := fmt.Sprintf("%s://%s", scheme, host)
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -169,27 +172,12 @@ func fetchOnce(client *http.Client, ua, scheme, host string, titleRe *regexp.Reg
 	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
 	title := ""
 	if resp.StatusCode == 200 {
-		if m := titleRe.FindStringSubmatch(string(body)); len(m) >= 2 {
-			t := strings.TrimSpace(htmlUnescape(m[1]))
-			// 清理换行与多余空白
-			t = strings.Join(strings.Fields(t), " ")
-			title = t
-		}
+		// 按页面字符集转码后提取，GBK 等非 UTF-8 页面的标题不会乱码
+		title = httpclient.ExtractTitle(body, resp.Header.Get("Content-Type"))
 	}
 	return resp.StatusCode, title
 }
 
-func htmlUnescape(s string) string {
-	replacer := strings.NewReplacer(
-		"&amp;", "&",
-		"&lt;", "<",
-		"&gt;", ">",
-		"&quot;", "\"",
-		"&#39;", "'",
-	)
-	return replacer.Replace(s)
-}
-
 func uniqueStrings(in []string) []string {
 	seen := make(map[string]struct{})
 	var out []string
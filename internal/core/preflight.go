@@ -0,0 +1,71 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/oneforall-go/internal/config"
+	dnsutil "github.com/oneforall-go/internal/dns"
+	"github.com/oneforall-go/pkg/logger"
+	"github.com/oneforall-go/pkg/utils"
+)
+
+// preflightTimeout 预检查中单个 DNS 服务器的查询超时
+const preflightTimeout = 3 * time.Second
+
+// CheckConnectivity 默认的运行前检查：确认能连接互联网，配置了自定义 DNS 服务器时至少有一个能应答
+func CheckConnectivity(cfg *config.Config) error {
+	if !utils.CheckInternetConnection() {
+		return fmt.Errorf("no internet connection (cannot reach 8.8.8.8:53)")
+	}
+
+	if len(cfg.Resolvers) == 0 {
+		return nil
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(".", dns.TypeNS)
+	msg.RecursionDesired = true
+
+	var failures []string
+	for _, resolver := range cfg.Resolvers {
+		client, addr := dnsutil.NewExchangeClient(resolver, preflightTimeout, cfg.DoTInsecureSkipVerify)
+		if _, _, err := client.Exchange(msg, addr); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", resolver, err))
+			continue
+		}
+		return nil
+	}
+	return fmt.Errorf("none of the configured DNS resolvers responded (%s)", strings.Join(failures, "; "))
+}
+
+// SetPreflight 设置运行前检查（传 nil 关闭），默认使用 CheckConnectivity
+func (d *Dispatcher) SetPreflight(check func() error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.preflight = check
+	d.preflightDone = false
+	d.preflightErr = nil
+}
+
+// Preflight 执行运行前检查，同一调度器只检查一次；配置关闭 preflight、dry-run 模式或未设置检查函数时直接通过
+func (d *Dispatcher) Preflight() error {
+	if !d.config.Preflight || d.config.DryRun {
+		return nil
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.preflight == nil || d.preflightDone {
+		return d.preflightErr
+	}
+
+	logger.Info("Running pre-flight connectivity check...")
+	if err := d.preflight(); err != nil {
+		d.preflightErr = fmt.Errorf("pre-flight check failed: %v (use --no-preflight to skip)", err)
+	}
+	d.preflightDone = true
+	return d.preflightErr
+}
@@ -1,6 +1,9 @@
 package core
 
 import (
+	"encoding/json"
+	"fmt"
+
 	"github.com/oneforall-go/internal/config"
 )
 
@@ -15,3 +18,27 @@ func NewQuery(name string, cfg *config.Config) *Query {
 		BaseModule: NewBaseModule(name, ModuleTypeSearch, cfg),
 	}
 }
+
+// DecodeJSON 按结构解析 JSON 响应，成功时返回 true。接口字段变更或响应被截断导致解析失败时，
+// 改用正则从原始响应中提取范围内的子域名并直接添加到结果，返回 false；
+// 只有正则也没有提取到子域名时才返回错误
+func (q *Query) DecodeJSON(body, domain string, v interface{}) (bool, error) {
+	decodeErr := json.Unmarshal([]byte(body), v)
+	if decodeErr == nil {
+		return true, nil
+	}
+
+	salvaged := 0
+	for _, subdomain := range q.ExtractSubdomains(body, domain) {
+		if q.IsValidSubdomain(subdomain, domain) {
+			q.AddSubdomain(subdomain)
+			salvaged++
+		}
+	}
+	if salvaged == 0 {
+		return false, fmt.Errorf("failed to parse JSON response: %v", decodeErr)
+	}
+
+	q.LogInfo("Failed to parse JSON response (%v), salvaged %d subdomains from raw body", decodeErr, salvaged)
+	return false, nil
+}
@@ -0,0 +1,111 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/oneforall-go/pkg/logger"
+)
+
+// quotaBodyPeek 判断额度耗尽时读取的错误响应体长度
+const quotaBodyPeek = 4096
+
+// quotaExhaustedMarkers 429 响应体中表示额度（而不是每秒速率）耗尽的关键字，均为小写。
+// 如 Shodan 的 "Insufficient query credits"、SecurityTrails 的 "You've exceeded the usage limits for your current plan"
+var quotaExhaustedMarkers = []string{
+	"quota",
+	"credits",
+	"usage limit",
+	"monthly limit",
+	"plan limit",
+	"limit exceeded for your plan",
+}
+
+// quotaRemainingHeaders 部分 API 在响应头中返回剩余额度，按顺序取第一个存在的
+var quotaRemainingHeaders = []string{
+	"X-Quota-Remaining",
+	"X-RateLimit-Remaining-Month",
+	"X-API-Credits-Remaining",
+}
+
+// QuotaLimited 会因 API 额度耗尽停用的模块（嵌入 BaseModule 的模块都实现了该接口），
+// 额度耗尽后调度器在本次运行的剩余时间内跳过该模块
+type QuotaLimited interface {
+	QuotaExhausted() (reason string, exhausted bool)
+}
+
+// QuotaExhausted 返回 API 额度是否已耗尽及原因，耗尽状态在 Reset 后保留
+func (b *BaseModule) QuotaExhausted() (string, bool) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.quotaReason, b.quotaReason != ""
+}
+
+// QuotaRemaining 返回 API 最近一次在响应头中报告的剩余额度，未报告时返回空字符串
+func (b *BaseModule) QuotaRemaining() string {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.quotaRemaining
+}
+
+// checkQuota 检查响应是否表示额度耗尽：402 一律视为耗尽，429 只有响应体包含额度相关关键字时才视为耗尽
+// （其余 429 是每秒速率限制，由重试策略退避）。耗尽后记录原因并返回 true，同时记录响应头中的剩余额度
+func (b *BaseModule) checkQuota(resp *http.Response) bool {
+	for _, name := range quotaRemainingHeaders {
+		if value := resp.Header.Get(name); value != "" {
+			b.mutex.Lock()
+			b.quotaRemaining = value
+			b.mutex.Unlock()
+			b.LogDebug("API quota remaining: %s", value)
+			break
+		}
+	}
+
+	if resp.StatusCode != http.StatusPaymentRequired && resp.StatusCode != http.StatusTooManyRequests {
+		return false
+	}
+
+	// 读取部分响应体用于判断，再放回去供模块读取
+	peek, _ := io.ReadAll(io.LimitReader(resp.Body, quotaBodyPeek))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(peek), resp.Body), resp.Body}
+
+	body := strings.ToLower(string(peek))
+	if resp.StatusCode == http.StatusTooManyRequests && !containsAny(body, quotaExhaustedMarkers) {
+		return false
+	}
+
+	reason := fmt.Sprintf("status %d", resp.StatusCode)
+	if message := strings.TrimSpace(string(peek)); message != "" {
+		if len(message) > 200 {
+			message = message[:200]
+		}
+		reason = fmt.Sprintf("%s: %s", reason, message)
+	}
+
+	b.mutex.Lock()
+	first := b.quotaReason == ""
+	if first {
+		b.quotaReason = reason
+	}
+	b.mutex.Unlock()
+	if first {
+		logger.Warnf("[%s] API quota exhausted (%s), module disabled for the rest of this run", b.name, reason)
+	}
+	return true
+}
+
+// containsAny 判断 s 是否包含任意一个关键字
+func containsAny(s string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(s, keyword) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/oneforall-go/pkg/logger"
+	"golang.org/x/net/publicsuffix"
+)
+
+// RelatedReporter 可报告关联域名的模块（如证书、SPF 中出现的同组织其他主域名，而非目标的子域名）
+type RelatedReporter interface {
+	TakeRelatedDomains() []string
+}
+
+// RegisteredDomain 返回主机名的注册域名（eTLD+1），通配符前缀会被去除，无法识别时返回空
+func RegisteredDomain(host string) string {
+	host = strings.TrimPrefix(NormalizeHost(host), "*.")
+	if !isValidHostname(host) {
+		return ""
+	}
+	apex, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return ""
+	}
+	return apex
+}
+
+// AddRelatedDomain 记录关联域名，统一归一化为注册域名
+func (b *BaseModule) AddRelatedDomain(host string) {
+	apex := RegisteredDomain(host)
+	if apex == "" {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.related == nil {
+		b.related = make(map[string]bool)
+	}
+	b.related[apex] = true
+}
+
+// TakeRelatedDomains 取出并清空已记录的关联域名
+func (b *BaseModule) TakeRelatedDomains() []string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	related := make([]string, 0, len(b.related))
+	for apex := range b.related {
+		related = append(related, apex)
+	}
+	b.related = nil
+	return related
+}
+
+// collectRelated 收集模块报告的关联域名，排除与目标同属一个注册域名的结果
+func (d *Dispatcher) collectRelated(module Module, domain string) {
+	reporter, ok := module.(RelatedReporter)
+	if !ok {
+		return
+	}
+
+	related := reporter.TakeRelatedDomains()
+	if len(related) == 0 {
+		return
+	}
+
+	targetApex := RegisteredDomain(domain)
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.related == nil {
+		d.related = make(map[string]map[string]bool)
+	}
+	if d.related[domain] == nil {
+		d.related[domain] = make(map[string]bool)
+	}
+	for _, apex := range related {
+		if apex != targetApex && !InScope(apex, domain) {
+			d.related[domain][apex] = true
+		}
+	}
+}
+
+// RelatedDomains 返回域名运行期间模块报告的关联域名（已去重排序）
+func (d *Dispatcher) RelatedDomains(domain string) []string {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	related := make([]string, 0, len(d.related[domain]))
+	for apex := range d.related[domain] {
+		related = append(related, apex)
+	}
+	sort.Strings(related)
+	return related
+}
+
+// AddRelatedDomains 添加关联域名
+func (o *OutputManager) AddRelatedDomains(domains []string) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.related == nil {
+		o.related = make(map[string]bool)
+	}
+	for _, domain := range domains {
+		o.related[domain] = true
+	}
+}
+
+// GetRelatedDomains 获取去重排序后的关联域名
+func (o *OutputManager) GetRelatedDomains() []string {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.relatedDomains()
+}
+
+// relatedDomains 获取去重排序后的关联域名，调用方需持有锁
+func (o *OutputManager) relatedDomains() []string {
+	related := make([]string, 0, len(o.related))
+	for domain := range o.related {
+		related = append(related, domain)
+	}
+	sort.Strings(related)
+	return related
+}
+
+// exportRelated 将关联域名写入 *_related.txt，每行一个
+func (o *OutputManager) exportRelated() error {
+	related := o.relatedDomains()
+	if len(related) == 0 {
+		return nil
+	}
+
+	relatedPath := strings.TrimSuffix(o.outputPath, filepath.Ext(o.outputPath)) + "_related.txt"
+	if err := os.WriteFile(relatedPath, []byte(strings.Join(related, "\n")+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write related domains: %v", err)
+	}
+
+	logger.Infof("Exported %d related domains to %s", len(related), relatedPath)
+	return nil
+}
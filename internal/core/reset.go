@@ -0,0 +1,58 @@
+package core
+
+import (
+	"github.com/oneforall-go/pkg/logger"
+)
+
+// Resetter 保存了单次运行状态的模块，Dispatcher.Reset 时调用 Reset 清空，模块配置保持不变；
+// 嵌入 BaseModule 的模块都实现了该接口，自身另有按域名保存的状态（如 Brute 的爆破结果）时需覆盖 Reset
+type Resetter interface {
+	Reset()
+}
+
+// Reset 清空本轮收集的子域名、关联域名、来源标记和附加信息，请求头、代理、延迟等设置保持不变
+func (b *BaseModule) Reset() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.domain = ""
+	b.subdomains = make(map[string]bool)
+	b.related = nil
+	b.sourceTags = nil
+	b.infos = make(map[string]interface{})
+	b.results = make([]interface{}, 0)
+}
+
+// Reset 清空单次运行的状态（关联域名、来源标记、结果计数）并重置所有模块，
+// 已注册的模块、步骤配置、连接池、联网检查结果和模块耗时统计保持不变，
+// 使同一个调度器可以依次处理多个域名而不必重新注册模块。
+// 种子结果按域名存放、运行时取出，不受影响，可以在 Reset 前后添加
+func (d *Dispatcher) Reset() {
+	d.mutex.Lock()
+	d.related = nil
+	d.sourceTags = nil
+	var modules []Module
+	for _, bucket := range d.moduleBuckets() {
+		modules = append(modules, *bucket...)
+	}
+	d.mutex.Unlock()
+
+	d.resetResultCount(nil)
+
+	reset := 0
+	for _, module := range modules {
+		if resetter, ok := module.(Resetter); ok {
+			resetter.Reset()
+			reset++
+		}
+	}
+	logger.Debugf("Dispatcher reset, %d modules cleared", reset)
+}
+
+// ClearModules 移除所有已注册的模块，用于模块选择变化后重新注册；单次运行的状态不受影响，需要时另外调用 Reset
+func (d *Dispatcher) ClearModules() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	for _, bucket := range d.moduleBuckets() {
+		*bucket = make([]Module, 0)
+	}
+}
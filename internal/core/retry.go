@@ -0,0 +1,126 @@
+package core
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy HTTP 请求重试策略
+type RetryPolicy struct {
+	MaxAttempts int                       // 最大尝试次数（含首次请求）
+	BaseDelay   time.Duration             // 首次重试前的等待时间，之后每次翻倍
+	MaxDelay    time.Duration             // 单次等待上限
+	IsSuccess   func(statusCode int) bool // 判断响应是否无需重试，为 nil 时非 5xx 均视为成功
+}
+
+// DefaultRetryPolicy 默认重试策略：最多 3 次，1s 起指数退避
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Second,
+		MaxDelay:    30 * time.Second,
+	}
+}
+
+// SetRetryPolicy 设置 HTTP 重试策略
+func (b *BaseModule) SetRetryPolicy(policy RetryPolicy) {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 1
+	}
+	b.retry = policy
+}
+
+// GetRetryPolicy 获取 HTTP 重试策略
+func (b *BaseModule) GetRetryPolicy() RetryPolicy {
+	return b.retry
+}
+
+// isSuccess 判断响应状态码是否无需重试
+func (p RetryPolicy) isSuccess(statusCode int) bool {
+	if p.IsSuccess != nil {
+		return p.IsSuccess(statusCode)
+	}
+	return statusCode != http.StatusTooManyRequests && statusCode < 500
+}
+
+// backoff 计算第 attempt 次重试前的等待时间（指数退避加随机抖动）
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << uint(attempt)
+	if delay <= 0 || (p.MaxDelay > 0 && delay > p.MaxDelay) {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/2+1))
+}
+
+// doWithRetry 按重试策略发送请求，429 响应优先使用 Retry-After 指定的等待时间；
+// API 额度耗尽后不再发送请求，直接返回错误
+func (b *BaseModule) doWithRetry(req *http.Request) (*http.Response, error) {
+	if reason, exhausted := b.QuotaExhausted(); exhausted {
+		return nil, fmt.Errorf("API quota exhausted earlier in this run (%s)", reason)
+	}
+	policy := b.retry
+
+	var resp *http.Response
+	var err error
+	for attempt := 0; attempt < policy.MaxAttempts; attempt++ {
+		// 重试时重置请求体
+		if attempt > 0 && req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err = b.httpClient.Do(req)
+		if err == nil && (b.checkQuota(resp) || policy.isSuccess(resp.StatusCode)) {
+			return resp, nil
+		}
+		if attempt == policy.MaxAttempts-1 {
+			break
+		}
+
+		wait := policy.backoff(attempt)
+		if resp != nil {
+			if resp.StatusCode == http.StatusTooManyRequests {
+				// 服务端要求的等待时间同样受单次等待上限约束，避免一个响应头让模块长时间挂起
+				if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+					wait = retryAfter
+					if policy.MaxDelay > 0 && wait > policy.MaxDelay {
+						wait = policy.MaxDelay
+					}
+				}
+			}
+			resp.Body.Close()
+		}
+		b.LogDebug("Request to %s failed, retrying in %v (attempt %d/%d)", req.URL.Host, wait, attempt+1, policy.MaxAttempts)
+		select {
+		case <-time.After(wait):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+
+	return resp, err
+}
+
+// parseRetryAfter 解析 Retry-After 头（秒数或 HTTP 日期）
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"strings"
+
+	"github.com/oneforall-go/pkg/logger"
+	"golang.org/x/net/publicsuffix"
+)
+
+// NormalizeHost 规范化主机名：去除空白和末尾的点并转为小写
+func NormalizeHost(host string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(host), "."))
+}
+
+// InScope 判断主机名是否为目标域名本身或其子域名
+// 要求在标签边界上匹配（evilexample.com、example.com.attacker.net 不属于 example.com），
+// 且两者的注册域名（eTLD+1）一致；目标本身是公共后缀（如 co.uk）时不接受任何主机
+func InScope(host, domain string) bool {
+	host = NormalizeHost(host)
+	domain = NormalizeHost(domain)
+	if host == "" || domain == "" || !isValidHostname(host) {
+		return false
+	}
+
+	if host != domain && !strings.HasSuffix(host, "."+domain) {
+		return false
+	}
+
+	domainRoot, err := publicsuffix.EffectiveTLDPlusOne(domain)
+	if err != nil {
+		return false
+	}
+	hostRoot, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return false
+	}
+
+	return hostRoot == domainRoot
+}
+
+// continuesHostname 判断文本开头是否仍是主机名的一部分
+func continuesHostname(rest string) bool {
+	if rest == "" {
+		return false
+	}
+	if isHostnameChar(rest[0]) {
+		return true
+	}
+	return rest[0] == '.' && len(rest) > 1 && isHostnameChar(rest[1])
+}
+
+// isHostnameChar 判断是否为主机名字符
+func isHostnameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_'
+}
+
+// isValidHostname 检查主机名的标签是否合法（允许下划线，兼容 _dmarc 等记录名）
+func isValidHostname(host string) bool {
+	if len(host) > 253 {
+		return false
+	}
+
+	for _, label := range strings.Split(host, ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// filterInScope 只保留属于目标域名的模块结果并规范化，所有模块的输出都经过这里统一过滤
+func filterInScope(module, domain string, results []string) []string {
+	kept := results[:0:0]
+	dropped := 0
+	for _, result := range results {
+		if !InScope(result, domain) {
+			dropped++
+			continue
+		}
+		kept = append(kept, NormalizeHost(result))
+	}
+	if dropped > 0 {
+		logger.Debugf("Module %s: dropped %d out-of-scope results for %s", module, dropped, domain)
+	}
+	return kept
+}
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"sort"
 	"strings"
 
 	"github.com/oneforall-go/internal/config"
@@ -18,39 +19,89 @@ type Search struct {
 
 // NewSearch 创建搜索基础类
 func NewSearch(name string, cfg *config.Config) *Search {
-	return &Search{
-		BaseModule:      NewBaseModule(name, ModuleTypeSearch, cfg),
-		pageNum:         0,
-		perPageNum:      50,
-		recursiveSearch: true, // 默认启用递归搜索
-		recursiveTimes:  3,    // 默认递归3次
-		fullSearch:      true, // 默认启用全搜索
+	search := &Search{
+		BaseModule: NewBaseModule(name, ModuleTypeSearch, cfg),
+		pageNum:    0,
+		perPageNum: 50,
+		fullSearch: true, // 默认启用全搜索
+	}
+	// 递归搜索由 enable_recursive_search 开启，最多递归 search_recursive_times 层
+	if cfg != nil {
+		search.recursiveSearch = cfg.EnableRecursiveSearch
+		search.recursiveTimes = cfg.SearchRecursiveTimes
+	}
+	return search
+}
+
+// SearchRounds 执行完整的搜索流程：先搜索主域名，再用 Filter 生成的 -site: 语句排除结果较多的常见子域名继续搜索；
+// 开启递归搜索时，依次以已发现的第 1..recursiveTimes 层子域名为目标重复上述过程，每个子域名只搜索一次。
+// query 执行一次（分页）搜索，target 为搜索的域名，filter 为附加的排除语句。主域名的首次搜索失败时返回错误，之后的失败只记录日志
+func (s *Search) SearchRounds(domain string, query func(target, filter string) error) error {
+	if err := query(domain, ""); err != nil {
+		return err
+	}
+	s.queryFiltered(domain, query)
+
+	if !s.recursiveSearch {
+		return nil
+	}
+
+	searched := map[string]bool{domain: true}
+	for layer := 1; layer <= s.recursiveTimes; layer++ {
+		var targets []string
+		for _, subdomain := range s.RecursiveSubdomain(layer) {
+			if !searched[subdomain] {
+				searched[subdomain] = true
+				targets = append(targets, subdomain)
+			}
+		}
+		if len(targets) == 0 {
+			break
+		}
+
+		s.LogDebug("Recursive search round %d: %d subdomains", layer, len(targets))
+		for _, target := range targets {
+			if err := query(target, ""); err != nil {
+				s.LogError("Failed to search subdomain %s: %v", target, err)
+				continue
+			}
+			s.queryFiltered(target, query)
+		}
+	}
+	return nil
+}
+
+// queryFiltered 排除同一子域搜索结果过多的子域以发现新的子域
+func (s *Search) queryFiltered(target string, query func(target, filter string) error) {
+	for _, statement := range s.Filter(target, s.GetSubdomains()) {
+		if err := query(target, statement); err != nil {
+			s.LogError("Failed to search with filter %s: %v", statement, err)
+		}
 	}
 }
 
+// commonSubnamesFile 数据目录下的常见子域名列表文件，每行一个，common_subnames 未配置时使用
+const commonSubnamesFile = "common_subnames.txt"
+
 // Filter 生成搜索过滤语句
 // 使用搜索引擎支持的-site:语法过滤掉搜索页面较多的子域以发现新域
 func (s *Search) Filter(domain string, subdomains []string) []string {
 	statementsList := []string{}
 
-	// 获取常见子域名
-	commonSubnames := []string{"www", "mail", "ftp", "localhost", "webmail", "smtp", "pop", "ns1", "webdisk", "ns2", "cpanel", "whm", "autodiscover", "autoconfig", "m", "imap", "test", "ns", "blog", "pop3", "dev", "www2", "admin", "forum", "news", "vpn", "ns3", "mail2", "remote", "mysql", "api", "ns4", "server", "new", "beta", "shop", "ftp2", "media", "www1", "secure", "support", "static", "cdn", "mta", "ns5", "web", "mx", "email", "images", "img", "download", "dns1", "dns2", "portal", "ns6", "dns", "dns3", "dns4", "dns5", "dns6", "dns7", "dns8", "dns9", "dns10", "dns11", "dns12", "dns13", "dns14", "dns15", "dns16", "dns17", "dns18", "dns19", "dns20", "dns21", "dns22", "dns23", "dns24", "dns25", "dns26", "dns27", "dns28", "dns29", "dns30", "dns31", "dns32", "dns33", "dns34", "dns35", "dns36", "dns37", "dns38", "dns39", "dns40", "dns41", "dns42", "dns43", "dns44", "dns45", "dns46", "dns47", "dns48", "dns49", "dns50", "dns51", "dns52", "dns53", "dns54", "dns55", "dns56", "dns57", "dns58", "dns59", "dns60", "dns61", "dns62", "dns63", "dns64", "dns65", "dns66", "dns67", "dns68", "dns69", "dns70", "dns71", "dns72", "dns73", "dns74", "dns75", "dns76", "dns77", "dns78", "dns79", "dns80", "dns81", "dns82", "dns83", "dns84", "dns85", "dns86", "dns87", "dns88", "dns89", "dns90", "dns91", "dns92", "dns93", "dns94", "dns95", "dns96", "dns97", "dns98", "dns99", "dns100"}
-	subdomainsTemp := make(map[string]bool)
-
-	// 构建常见子域名集合
-	for _, subname := range commonSubnames {
-		fullSubdomain := subname + "." + domain
-		for _, subdomain := range subdomains {
-			if subdomain == fullSubdomain {
-				subdomainsTemp[fullSubdomain] = true
-			}
-		}
+	found := make(map[string]bool, len(subdomains))
+	for _, subdomain := range subdomains {
+		found[subdomain] = true
 	}
 
-	// 转换为切片
+	// 按常见子域名列表的顺序收集已发现的常见子域名
 	var tempList []string
-	for subdomain := range subdomainsTemp {
-		tempList = append(tempList, subdomain)
+	seen := make(map[string]bool)
+	for _, subname := range s.commonSubnames() {
+		fullSubdomain := subname + "." + domain
+		if found[fullSubdomain] && !seen[fullSubdomain] {
+			seen[fullSubdomain] = true
+			tempList = append(tempList, fullSubdomain)
+		}
 	}
 
 	// 生成过滤语句
@@ -71,6 +122,34 @@ func (s *Search) Filter(domain string, subdomains []string) []string {
 	return statementsList
 }
 
+// commonSubnames 获取常见子域名列表：优先使用 common_subnames 配置，未配置时读取数据目录下的 common_subnames.txt
+func (s *Search) commonSubnames() []string {
+	var subnames []string
+	if s.config != nil {
+		for _, subname := range strings.Split(s.config.CommonSubnames, ",") {
+			if subname = strings.ToLower(strings.TrimSpace(subname)); subname != "" {
+				subnames = append(subnames, subname)
+			}
+		}
+	}
+	if len(subnames) > 0 {
+		return subnames
+	}
+
+	data, err := s.config.ReadDataFile(commonSubnamesFile)
+	if err != nil {
+		s.LogDebug("Failed to load common subnames from %s: %v", commonSubnamesFile, err)
+		return nil
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.ToLower(strings.TrimSpace(line))
+		if line != "" && !strings.HasPrefix(line, "#") {
+			subnames = append(subnames, line)
+		}
+	}
+	return subnames
+}
+
 // MatchLocation 匹配跳转之后的url
 // 针对部分搜索引擎(如百度搜索)搜索展示url时有显示不全的情况
 // 此函数会向每条结果的链接发送head请求获取响应头的location值并做子域匹配
@@ -122,22 +201,18 @@ func (s *Search) CheckSubdomains(newSubdomains []string) bool {
 	return true
 }
 
-// RecursiveSubdomain 递归搜索下一层的子域
-func (s *Search) RecursiveSubdomain() []string {
+// RecursiveSubdomain 返回已发现的比主域名多 layer 层的子域名（按名称排序），作为递归搜索下一层的目标
+func (s *Search) RecursiveSubdomain(layer int) []string {
 	var recursiveSubdomains []string
-	subdomains := s.GetSubdomains()
-
-	// 从1开始是之前已经做过1层子域搜索了,当前实际递归层数是layer+1
-	for layerNum := 1; layerNum < s.recursiveTimes; layerNum++ {
-		for _, subdomain := range subdomains {
-			// 进行下一层子域搜索的限制条件
-			count := strings.Count(subdomain, ".") - strings.Count(s.domain, ".")
-			if count == layerNum {
-				recursiveSubdomains = append(recursiveSubdomains, subdomain)
-			}
+	for _, subdomain := range s.GetSubdomains() {
+		if !strings.HasSuffix(subdomain, "."+s.domain) {
+			continue
+		}
+		if strings.Count(subdomain, ".")-strings.Count(s.domain, ".") == layer {
+			recursiveSubdomains = append(recursiveSubdomains, subdomain)
 		}
 	}
-
+	sort.Strings(recursiveSubdomains)
 	return recursiveSubdomains
 }
 
@@ -0,0 +1,65 @@
+package core
+
+// SourceTagger 可为部分子域名指定来源标记的模块（如只在历史记录中出现的子域名），
+// 未标记的子域名使用步骤类型作为来源
+type SourceTagger interface {
+	TakeSourceTags() map[string]string
+}
+
+// AddTaggedSubdomain 添加子域名并指定来源标记
+func (b *BaseModule) AddTaggedSubdomain(subdomain, source string) {
+	host := NormalizeHost(subdomain)
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.subdomains[host] = true
+	if b.sourceTags == nil {
+		b.sourceTags = make(map[string]string)
+	}
+	b.sourceTags[host] = source
+}
+
+// TakeSourceTags 取出并清空已记录的来源标记
+func (b *BaseModule) TakeSourceTags() map[string]string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	tags := b.sourceTags
+	b.sourceTags = nil
+	return tags
+}
+
+// collectSourceTags 收集模块报告的来源标记
+func (d *Dispatcher) collectSourceTags(module Module, domain string) {
+	tagger, ok := module.(SourceTagger)
+	if !ok {
+		return
+	}
+
+	tags := tagger.TakeSourceTags()
+	if len(tags) == 0 {
+		return
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.sourceTags == nil {
+		d.sourceTags = make(map[string]map[string]string)
+	}
+	if d.sourceTags[domain] == nil {
+		d.sourceTags[domain] = make(map[string]string)
+	}
+	for subdomain, source := range tags {
+		d.sourceTags[domain][subdomain] = source
+	}
+}
+
+// sourceFor 返回子域名的来源，模块标记过的子域名使用标记，否则使用 fallback
+func (d *Dispatcher) sourceFor(domain, subdomain, fallback string) string {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	if source, ok := d.sourceTags[domain][subdomain]; ok {
+		return source
+	}
+	return fallback
+}
@@ -0,0 +1,87 @@
+package core
+
+import "sort"
+
+// APIKeyRequirer 需要配置 API 密钥才会发送请求的模块，未配置密钥时模块直接跳过
+type APIKeyRequirer interface {
+	RequiresAPIKey() bool
+}
+
+// SourceCategories 模块分类，按 sources 命令的显示顺序排列
+var SourceCategories = []string{
+	"search", "dataset", "certificate", "crawl", "dns", "check", "intelligence", "brute", "enrich",
+}
+
+// SourceInfo 已注册模块的名称、分类和是否需要 API 密钥
+type SourceInfo struct {
+	Name           string `json:"name"`
+	Category       string `json:"category"`
+	RequiresAPIKey bool   `json:"requires_api_key"`
+}
+
+// SourceCategory 返回模块的分类：先按模块名称归入数据集、证书等分类，其余按模块类型归类
+func SourceCategory(module Module) string {
+	name := module.Name()
+	switch {
+	case isDatasetModule(name):
+		return "dataset"
+	case isCertificateModule(name):
+		return "certificate"
+	case isCrawlModule(name):
+		return "crawl"
+	case isDNSLookupModule(name):
+		return "dns"
+	case isCheckModule(name):
+		return "check"
+	case isIntelligenceModule(name):
+		return "intelligence"
+	case isBruteModule(name):
+		return "brute"
+	case isEnrichModule(name):
+		return "enrich"
+	}
+
+	switch module.Type() {
+	case ModuleTypeBrute:
+		return "brute"
+	case ModuleTypeDNSLookup, ModuleTypeResolve:
+		return "dns"
+	case ModuleTypeCheck:
+		return "check"
+	case ModuleTypeCrawl:
+		return "crawl"
+	case ModuleTypeEnrich:
+		return "enrich"
+	}
+	return "search"
+}
+
+// Sources 返回所有已注册模块（包括已禁用的模块），按 SourceCategories 的顺序分组，组内按名称排序
+func (d *Dispatcher) Sources() []SourceInfo {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	order := make(map[string]int, len(SourceCategories))
+	for i, category := range SourceCategories {
+		order[category] = i
+	}
+
+	var sources []SourceInfo
+	for _, bucket := range d.moduleBuckets() {
+		for _, module := range *bucket {
+			info := SourceInfo{Name: module.Name(), Category: SourceCategory(module)}
+			if requirer, ok := module.(APIKeyRequirer); ok {
+				info.RequiresAPIKey = requirer.RequiresAPIKey()
+			}
+			sources = append(sources, info)
+		}
+	}
+
+	sort.SliceStable(sources, func(i, j int) bool {
+		if sources[i].Category != sources[j].Category {
+			return order[sources[i].Category] < order[sources[j].Category]
+		}
+		return sources[i].Name < sources[j].Name
+	})
+	return sources
+}
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/oneforall-go/pkg/logger"
+)
+
+// StatsFileSuffix 统计文件后缀，替换结果文件的扩展名（example.com.csv → example.com.stats.json）
+const StatsFileSuffix = ".stats.json"
+
+// StatsFile 统计文件内容（stats_file 开启时与结果文件一起写入），stats 与 GetStats 一致并包含全部结果
+type StatsFile struct {
+	Domain      string                 `json:"domain"`
+	OutputPath  string                 `json:"output_path"`
+	StartedAt   string                 `json:"started_at"`
+	GeneratedAt string                 `json:"generated_at"`
+	Duration    float64                `json:"duration"` // 从输出管理器创建（或 SetStartTime）到导出的耗时，单位秒
+	Stats       map[string]interface{} `json:"stats"`
+	Partial     bool                   `json:"partial,omitempty"` // 运行异常中断，结果不完整
+}
+
+// StatsPath 返回结果文件对应的统计文件路径
+func StatsPath(outputPath string) string {
+	return strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + StatsFileSuffix
+}
+
+// SetStartTime 设置运行开始时间，用于计算统计文件中的运行耗时，默认为创建输出管理器的时间
+func (o *OutputManager) SetStartTime(start time.Time) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.startTime = start
+}
+
+// exportStats 写入统计文件，调用方需持有锁
+func (o *OutputManager) exportStats() error {
+	now := time.Now()
+	stats := StatsFile{
+		Domain:      o.targetDomain(),
+		OutputPath:  o.outputPath,
+		StartedAt:   o.startTime.Format("2006-01-02 15:04:05"),
+		GeneratedAt: now.Format("2006-01-02 15:04:05"),
+		Duration:    now.Sub(o.startTime).Round(time.Millisecond).Seconds(),
+		Stats:       o.stats(),
+		Partial:     o.partial,
+	}
+
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode stats: %v", err)
+	}
+
+	statsPath := StatsPath(o.outputPath)
+	if err := os.WriteFile(statsPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write stats file: %v", err)
+	}
+
+	logger.Infof("Exported stats to %s", statsPath)
+	return nil
+}
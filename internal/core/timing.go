@@ -0,0 +1,109 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ModuleTiming 单个模块的累计耗时和结果数，多个域名的运行会累加
+type ModuleTiming struct {
+	Module   string        `json:"module"`
+	Runs     int           `json:"runs"`     // 运行次数
+	Errors   int           `json:"errors"`   // 失败次数
+	Duration time.Duration `json:"duration"` // 累计耗时
+	Results  int           `json:"results"`  // 累计发现的子域名数
+}
+
+// TimingCollector 模块耗时收集器，可在多个调度器间共享
+type TimingCollector struct {
+	mutex   sync.Mutex
+	timings map[string]*ModuleTiming
+}
+
+// NewTimingCollector 创建模块耗时收集器
+func NewTimingCollector() *TimingCollector {
+	return &TimingCollector{timings: make(map[string]*ModuleTiming)}
+}
+
+// Record 记录模块的一次运行
+func (c *TimingCollector) Record(module string, elapsed time.Duration, results int, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	timing, ok := c.timings[module]
+	if !ok {
+		timing = &ModuleTiming{Module: module}
+		c.timings[module] = timing
+	}
+	timing.Runs++
+	timing.Duration += elapsed
+	timing.Results += results
+	if err != nil {
+		timing.Errors++
+	}
+}
+
+// Timings 返回各模块的耗时，按累计耗时从长到短排序
+func (c *TimingCollector) Timings() []ModuleTiming {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	timings := make([]ModuleTiming, 0, len(c.timings))
+	for _, timing := range c.timings {
+		timings = append(timings, *timing)
+	}
+	sort.Slice(timings, func(i, j int) bool {
+		if timings[i].Duration != timings[j].Duration {
+			return timings[i].Duration > timings[j].Duration
+		}
+		return timings[i].Module < timings[j].Module
+	})
+	return timings
+}
+
+// Reset 清空已记录的耗时
+func (c *TimingCollector) Reset() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.timings = make(map[string]*ModuleTiming)
+}
+
+// FormatModuleTimings 把模块耗时格式化为表格，每行一个模块
+func FormatModuleTimings(timings []ModuleTiming) []string {
+	width := len("Module")
+	for _, timing := range timings {
+		if len(timing.Module) > width {
+			width = len(timing.Module)
+		}
+	}
+
+	lines := []string{fmt.Sprintf("%-*s %12s %8s %6s %6s", width, "Module", "Duration", "Results", "Runs", "Errors")}
+	lines = append(lines, strings.Repeat("-", width+37))
+	for _, timing := range timings {
+		lines = append(lines, fmt.Sprintf("%-*s %12s %8d %6d %6d", width, timing.Module,
+			timing.Duration.Round(time.Millisecond), timing.Results, timing.Runs, timing.Errors))
+	}
+	return lines
+}
+
+// SetTimingCollector 设置模块耗时收集器，多个调度器共享同一收集器时耗时合并统计
+func (d *Dispatcher) SetTimingCollector(collector *TimingCollector) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.timings = collector
+}
+
+// TimingCollector 返回调度器的模块耗时收集器
+func (d *Dispatcher) TimingCollector() *TimingCollector {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return d.timings
+}
+
+// ModuleTimings 返回已运行模块的耗时，按累计耗时从长到短排序
+func (d *Dispatcher) ModuleTimings() []ModuleTiming {
+	return d.TimingCollector().Timings()
+}
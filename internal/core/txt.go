@@ -0,0 +1,37 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"github.com/oneforall-go/pkg/logger"
+)
+
+// exportTXT 导出为纯文本主机列表，每行一个子域名，无表头，便于 nuclei/httpx 等工具直接读取
+// 开启 txt_with_scheme 时每行加 https:// 前缀
+func (o *OutputManager) exportTXT(results []SubdomainResult) error {
+	file, err := os.Create(o.outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create TXT file: %v", err)
+	}
+	defer file.Close()
+
+	prefix := ""
+	if o.config.TxtWithScheme {
+		prefix = "https://"
+	}
+
+	writer := bufio.NewWriter(file)
+	for _, result := range results {
+		if _, err := fmt.Fprintf(writer, "%s%s\n", prefix, result.Subdomain); err != nil {
+			return fmt.Errorf("failed to write TXT line: %v", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write TXT file: %v", err)
+	}
+
+	logger.Infof("Exported %d hosts to TXT: %s", len(results), o.outputPath)
+	return nil
+}
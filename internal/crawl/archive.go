@@ -2,6 +2,7 @@ package crawl
 
 import (
 	"fmt"
+	"net"
 	"regexp"
 	"strings"
 
@@ -122,9 +123,17 @@ func (a *Archive) extractSubdomainFromURL(urlStr, domain string) string {
 		urlStr = urlStr[:idx]
 	}
 
+	// 去除端口和用户信息
+	if idx := strings.LastIndex(urlStr, "@"); idx != -1 {
+		urlStr = urlStr[idx+1:]
+	}
+	if host, _, err := net.SplitHostPort(urlStr); err == nil {
+		urlStr = host
+	}
+
 	// 检查是否是目标域名的子域名
-	if strings.HasSuffix(urlStr, "."+domain) {
-		return urlStr
+	if core.InScope(urlStr, domain) {
+		return core.NormalizeHost(urlStr)
 	}
 
 	return ""
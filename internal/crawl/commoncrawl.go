@@ -3,7 +3,9 @@ package crawl
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -11,24 +13,46 @@ import (
 	"github.com/oneforall-go/internal/core"
 )
 
+// commonCrawlMaxPages 每个索引最多翻页数，CommonCrawl 对请求频率敏感
+const commonCrawlMaxPages = 10
+
 // CommonCrawl CommonCrawl 爬虫模块
 type CommonCrawl struct {
 	*core.Crawl
-	baseURL string
+	collinfoURL   string
+	fallbackIndex string
+	indexes       int
+}
+
+// CommonCrawlIndex collinfo.json 中的索引信息
+type CommonCrawlIndex struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	CDXAPI string `json:"cdx-api"`
 }
 
 // CommonCrawlResponse CommonCrawl API 响应结构
 type CommonCrawlResponse struct {
-	URL    string `json:"urlkey"`
-	Status string `json:"status"`
-	Text   string `json:"text"`
+	URL string `json:"url"`
+}
+
+// commonCrawlPages showNumPages 查询的响应
+type commonCrawlPages struct {
+	Pages int `json:"pages"`
 }
 
 // NewCommonCrawl 创建 CommonCrawl 爬虫模块
 func NewCommonCrawl(cfg *config.Config) *CommonCrawl {
+	indexes := cfg.CommonCrawlIndexes
+	if indexes <= 0 {
+		indexes = 3
+	}
+
 	return &CommonCrawl{
-		Crawl:   core.NewCrawl("CommonCrawl", cfg),
-		baseURL: "https://index.commoncrawl.org/CC-MAIN-2023-50-index",
+		Crawl:         core.NewCrawl("CommonCrawl", cfg),
+		collinfoURL:   "https://index.commoncrawl.org/collinfo.json",
+		fallbackIndex: "https://index.commoncrawl.org/CC-MAIN-2023-50-index",
+		indexes:       indexes,
 	}
 }
 
@@ -38,69 +62,174 @@ func (c *CommonCrawl) Run(domain string) ([]string, error) {
 	c.Begin()
 	defer c.Finish()
 
-	// 执行爬虫
-	if err := c.crawl(domain, 50); err != nil {
-		return nil, err
+	// 获取最近的索引，失败时使用内置索引
+	indexURLs, err := c.latestIndexes()
+	if err != nil {
+		c.LogInfo("Failed to fetch CommonCrawl index list (%v), using %s", err, c.fallbackIndex)
+		indexURLs = []string{c.fallbackIndex}
 	}
 
-	// 爬取已发现的子域以发现新的子域
-	for _, subdomain := range c.GetSubdomains() {
-		if subdomain != domain {
-			if err := c.crawl(subdomain, 10); err != nil {
-				c.LogError("Failed to crawl subdomain %s: %v", subdomain, err)
-			}
+	// 逐个索引查询 *.domain，单个索引失败不影响其他索引
+	var lastErr error
+	succeeded := 0
+	for _, indexURL := range indexURLs {
+		if err := c.crawl(indexURL, domain); err != nil {
+			c.LogError("Failed to query %s: %v", indexURL, err)
+			lastErr = err
+			continue
 		}
+		succeeded++
+	}
+	if succeeded == 0 && lastErr != nil {
+		return nil, lastErr
 	}
 
 	return c.GetSubdomains(), nil
 }
 
-// crawl 执行爬虫
-func (c *CommonCrawl) crawl(domain string, limit int) error {
-	// 设置请求头
+// latestIndexes 获取 collinfo 列表，返回最近 N 个索引的 CDX API 地址
+func (c *CommonCrawl) latestIndexes() ([]string, error) {
 	c.SetHeader("User-Agent", c.GetRandomUserAgent())
 
-	// 构建查询参数
+	resp, err := c.HTTPGet(c.collinfoURL, c.GetHeader())
+	if err != nil {
+		return nil, err
+	}
+	body, err := c.ReadResponseBody(resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("collinfo returned status %d", resp.StatusCode)
+	}
+
+	var indexes []CommonCrawlIndex
+	if err := json.Unmarshal([]byte(body), &indexes); err != nil {
+		return nil, fmt.Errorf("failed to parse collinfo: %v", err)
+	}
+
+	// 索引 ID 形如 CC-MAIN-2024-10，按 ID 倒序即为由新到旧
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i].ID > indexes[j].ID })
+
+	var urls []string
+	for _, index := range indexes {
+		if index.CDXAPI == "" {
+			continue
+		}
+		urls = append(urls, index.CDXAPI)
+		if len(urls) >= c.indexes {
+			break
+		}
+	}
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("collinfo contains no indexes")
+	}
+	return urls, nil
+}
+
+// crawl 查询单个索引，先获取总页数再逐页读取
+func (c *CommonCrawl) crawl(indexURL, domain string) error {
+	pages, err := c.pageCount(indexURL, domain)
+	if err != nil {
+		return err
+	}
+	if pages > commonCrawlMaxPages {
+		c.LogInfo("%s has %d pages for %s, only the first %d will be read", indexURL, pages, domain, commonCrawlMaxPages)
+		pages = commonCrawlMaxPages
+	}
+
+	for page := 0; page < pages; page++ {
+		params := c.queryParams(domain)
+		params.Set("page", strconv.Itoa(page))
+
+		body, found, err := c.fetch(fmt.Sprintf("%s?%s", indexURL, params.Encode()))
+		if err != nil {
+			return fmt.Errorf("failed to read page %d: %v", page, err)
+		}
+		if !found {
+			break
+		}
+		c.parseRecords(body, domain)
+	}
+
+	return nil
+}
+
+// pageCount 通过 showNumPages 查询索引的总页数
+func (c *CommonCrawl) pageCount(indexURL, domain string) (int, error) {
+	params := c.queryParams(domain)
+	params.Set("showNumPages", "true")
+
+	body, found, err := c.fetch(fmt.Sprintf("%s?%s", indexURL, params.Encode()))
+	if err != nil || !found {
+		return 0, err
+	}
+
+	var pages commonCrawlPages
+	if err := json.Unmarshal([]byte(strings.TrimSpace(body)), &pages); err != nil {
+		return 0, fmt.Errorf("failed to parse page count: %v", err)
+	}
+	return pages.Pages, nil
+}
+
+// queryParams 构建 *.domain 的查询参数
+func (c *CommonCrawl) queryParams(domain string) url.Values {
 	params := url.Values{}
-	params.Set("url", fmt.Sprintf("*.%s/*", domain))
+	params.Set("url", fmt.Sprintf("*.%s", domain))
 	params.Set("output", "json")
-	params.Set("fl", "urlkey,status,text")
-	params.Set("limit", strconv.Itoa(limit))
+	params.Set("fl", "url")
+	return params
+}
+
+// fetch 发送请求，索引中没有记录时（404）返回 found=false
+func (c *CommonCrawl) fetch(queryURL string) (string, bool, error) {
+	c.SetHeader("User-Agent", c.GetRandomUserAgent())
 
-	// 发送查询请求
-	queryURL := fmt.Sprintf("%s?%s", c.baseURL, params.Encode())
 	resp, err := c.HTTPGet(queryURL, c.GetHeader())
 	if err != nil {
-		return fmt.Errorf("failed to query CommonCrawl: %v", err)
+		return "", false, err
 	}
-
-	// 读取响应
 	body, err := c.ReadResponseBody(resp)
 	if err != nil {
-		return fmt.Errorf("failed to read response: %v", err)
+		return "", false, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return body, true, nil
+	case http.StatusNotFound:
+		return "", false, nil
+	default:
+		return "", false, fmt.Errorf("CommonCrawl returned status %d", resp.StatusCode)
 	}
+}
 
-	// 解析响应（每行一个 JSON 对象）
-	lines := strings.Split(body, "\n")
-	for _, line := range lines {
+// parseRecords 解析逐行 JSON 记录，从 URL 中提取主机名
+func (c *CommonCrawl) parseRecords(body, domain string) {
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
 		if line == "" {
 			continue
 		}
 
-		var response CommonCrawlResponse
-		if err := json.Unmarshal([]byte(line), &response); err != nil {
+		var record CommonCrawlResponse
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
 			continue
 		}
 
-		// 检查状态码
-		if response.Status != "301" && response.Status != "302" {
-			// 从文本中提取子域名
-			subdomains := c.ExtractSubdomains(response.Text, domain)
-			for _, subdomain := range subdomains {
-				c.AddSubdomain(subdomain)
-			}
+		parsed, err := url.Parse(record.URL)
+		if err != nil {
+			continue
 		}
-	}
+		host := core.NormalizeHost(parsed.Hostname())
+		if seen[host] {
+			continue
+		}
+		seen[host] = true
 
-	return nil
+		if c.IsValidSubdomain(host, domain) {
+			c.AddSubdomain(host)
+		}
+	}
 }
@@ -0,0 +1,130 @@
+package crawl
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/oneforall-go/internal/config"
+	"github.com/oneforall-go/internal/core"
+)
+
+// collinfoTemplate collinfo.json 响应（节选），{base} 替换为测试服务器地址
+const collinfoTemplate = `[
+  {"id": "CC-MAIN-2024-10", "name": "February/March 2024 Index", "timegate": "{base}/CC-MAIN-2024-10/", "cdx-api": "{base}/CC-MAIN-2024-10-index"},
+  {"id": "CC-MAIN-2024-18", "name": "April 2024 Index", "timegate": "{base}/CC-MAIN-2024-18/", "cdx-api": "{base}/CC-MAIN-2024-18-index"},
+  {"id": "CC-MAIN-2023-50", "name": "November/December 2023 Index", "timegate": "{base}/CC-MAIN-2023-50/", "cdx-api": "{base}/CC-MAIN-2023-50-index"}
+]`
+
+// indexPages CDX 索引分页响应（output=json&fl=url）
+var indexPages = map[string][]string{
+	"/CC-MAIN-2024-18-index": {
+		`{"url": "https://www.example.com/"}
+{"url": "https://www.example.com/about"}
+{"url": "http://api.example.com:8080/v1"}`,
+		`{"url": "https://blog.example.com/post"}
+{"url": "https://example.com.evil.net/"}
+not json`,
+	},
+}
+
+func TestCommonCrawlIndexesAndPagination(t *testing.T) {
+	var mutex sync.Mutex
+	var requested []string
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requested = append(requested, r.URL.Path)
+		mutex.Unlock()
+
+		if r.URL.Path == "/collinfo.json" {
+			w.Write([]byte(strings.ReplaceAll(collinfoTemplate, "{base}", server.URL)))
+			return
+		}
+
+		pages, ok := indexPages[r.URL.Path]
+		if !ok {
+			// 索引中没有记录时 CDX API 返回 404
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "No Captures found for: *.example.com"}`))
+			return
+		}
+		if r.URL.Query().Get("url") != "*.example.com" {
+			t.Errorf("Unexpected url parameter %q", r.URL.Query().Get("url"))
+		}
+		if r.URL.Query().Get("showNumPages") == "true" {
+			w.Write([]byte(`{"pages": 2, "pageSize": 5, "blocks": 7}` + "\n"))
+			return
+		}
+		switch r.URL.Query().Get("page") {
+		case "0":
+			w.Write([]byte(pages[0]))
+		case "1":
+			w.Write([]byte(pages[1]))
+		default:
+			t.Errorf("Unexpected page %q", r.URL.Query().Get("page"))
+		}
+	}))
+	defer server.Close()
+
+	c := NewCommonCrawl(&config.Config{CommonCrawlIndexes: 2})
+	c.collinfoURL = server.URL + "/collinfo.json"
+	c.SetDelay(0)
+	c.SetRetryPolicy(core.RetryPolicy{MaxAttempts: 1})
+
+	subdomains, err := c.Run("example.com")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	sort.Strings(subdomains)
+	expected := []string{"api.example.com", "blog.example.com", "www.example.com"}
+	if strings.Join(subdomains, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, subdomains)
+	}
+
+	// 只查询最新的两个索引
+	queried := make(map[string]bool)
+	for _, path := range requested {
+		queried[path] = true
+	}
+	if queried["/CC-MAIN-2023-50-index"] {
+		t.Errorf("Expected oldest index to be skipped, requests: %v", requested)
+	}
+	if !queried["/CC-MAIN-2024-18-index"] || !queried["/CC-MAIN-2024-10-index"] {
+		t.Errorf("Expected the two latest indexes to be queried, requests: %v", requested)
+	}
+}
+
+func TestCommonCrawlCollinfoFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/collinfo.json" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Query().Get("showNumPages") == "true" {
+			w.Write([]byte(`{"pages": 1}`))
+			return
+		}
+		w.Write([]byte(`{"url": "https://dev.example.com/"}`))
+	}))
+	defer server.Close()
+
+	c := NewCommonCrawl(&config.Config{})
+	c.collinfoURL = server.URL + "/collinfo.json"
+	c.fallbackIndex = server.URL + "/CC-MAIN-2023-50-index"
+	c.SetDelay(0)
+	c.SetRetryPolicy(core.RetryPolicy{MaxAttempts: 1})
+
+	subdomains, err := c.Run("example.com")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(subdomains) != 1 || subdomains[0] != "dev.example.com" {
+		t.Errorf("Expected [dev.example.com] from fallback index, got %v", subdomains)
+	}
+}
@@ -23,6 +23,11 @@ func NewBeVigil(cfg *config.Config) *BeVigil {
 	}
 }
 
+// RequiresAPIKey 未配置 bevigil_api 时不发送请求
+func (b *BeVigil) RequiresAPIKey() bool {
+	return true
+}
+
 // Run 执行查询
 func (b *BeVigil) Run(domain string) ([]string, error) {
 	b.SetDomain(domain)
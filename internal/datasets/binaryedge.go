@@ -23,6 +23,11 @@ func NewBinaryEdge(cfg *config.Config) *BinaryEdge {
 	}
 }
 
+// RequiresAPIKey 未配置 binaryedge_api 时不发送请求
+func (b *BinaryEdge) RequiresAPIKey() bool {
+	return true
+}
+
 // Run 执行查询
 func (b *BinaryEdge) Run(domain string) ([]string, error) {
 	b.SetDomain(domain)
@@ -24,6 +24,11 @@ func NewChinazAPI(cfg *config.Config) *ChinazAPI {
 	}
 }
 
+// RequiresAPIKey 未配置 chinaz_api 时不发送请求
+func (c *ChinazAPI) RequiresAPIKey() bool {
+	return true
+}
+
 // Run 执行查询
 func (c *ChinazAPI) Run(domain string) ([]string, error) {
 	c.SetDomain(domain)
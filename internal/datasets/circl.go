@@ -26,6 +26,11 @@ func NewCircl(cfg *config.Config) *Circl {
 	}
 }
 
+// RequiresAPIKey 未配置 circl_api_username、circl_api_password 时不发送请求
+func (c *Circl) RequiresAPIKey() bool {
+	return true
+}
+
 // Run 执行查询
 func (c *Circl) Run(domain string) ([]string, error) {
 	c.SetDomain(domain)
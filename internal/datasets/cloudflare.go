@@ -1,7 +1,6 @@
 package datasets
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/url"
 	"time"
@@ -53,6 +52,11 @@ func NewCloudflare(cfg *config.Config) *Cloudflare {
 	}
 }
 
+// RequiresAPIKey 未配置 cloudflare_api_token 时不发送请求
+func (c *Cloudflare) RequiresAPIKey() bool {
+	return true
+}
+
 // Run 执行查询
 func (c *Cloudflare) Run(domain string) ([]string, error) {
 	c.SetDomain(domain)
@@ -114,7 +118,7 @@ func (c *Cloudflare) getAccountID() (string, error) {
 	}
 
 	var response CloudflareResponse
-	if err := json.Unmarshal([]byte(body), &response); err != nil {
+	if _, err := c.DecodeJSON(body, c.GetDomain(), &response); err != nil {
 		return "", err
 	}
 
@@ -141,7 +145,7 @@ func (c *Cloudflare) getZoneID(domain, accountID string) (string, error) {
 	}
 
 	var response CloudflareZoneResponse
-	if err := json.Unmarshal([]byte(body), &response); err != nil {
+	if _, err := c.DecodeJSON(body, c.GetDomain(), &response); err != nil {
 		return "", err
 	}
 
@@ -177,7 +181,7 @@ func (c *Cloudflare) createZone(domain, accountID string) (string, error) {
 	}
 
 	var response CloudflareCreateZoneResponse
-	if err := json.Unmarshal([]byte(body), &response); err != nil {
+	if _, err := c.DecodeJSON(body, c.GetDomain(), &response); err != nil {
 		return "", err
 	}
 
@@ -214,7 +218,8 @@ func (c *Cloudflare) listDNS(zoneID string) error {
 
 		// 解析响应获取总页数
 		var response CloudflareResponse
-		if err := json.Unmarshal([]byte(body), &response); err != nil {
+		// 解析失败时已从原始响应中提取子域名，无法判断是否还有下一页
+		if decoded, err := c.DecodeJSON(body, c.GetDomain(), &response); err != nil || !decoded {
 			break
 		}
 
@@ -0,0 +1,170 @@
+package datasets
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/oneforall-go/internal/config"
+	"github.com/oneforall-go/internal/core"
+)
+
+// securityTrailsResponses SecurityTrails API 响应（节选），按路径和查询参数区分
+var securityTrailsResponses = map[string]string{
+	"/domain/example.com/subdomains": `{"endpoint": "/v1/domain/example.com/subdomains", "meta": {"limit_reached": false},
+		"subdomain_count": 2, "subdomains": ["www", "api"]}`,
+	"/domain/example.com/subdomains?children_only=false&include_inactive=true": `{"endpoint": "/v1/domain/example.com/subdomains",
+		"meta": {"limit_reached": false}, "subdomain_count": 4, "subdomains": ["www", "api", "legacy", "old-vpn"]}`,
+	"/history/example.com/dns/a?page=1": `{"endpoint": "/v1/history/example.com/dns/a", "pages": 2, "type": "a/ipv4",
+		"records": [{"first_seen": "2021-03-01", "last_seen": "2024-05-10", "organizations": ["Example Hosting"], "type": "a",
+		             "values": [{"ip": "192.0.2.10", "ip_count": 12}, {"ip": "192.0.2.11", "ip_count": 3}]}]}`,
+	"/history/example.com/dns/a?page=2": `{"endpoint": "/v1/history/example.com/dns/a", "pages": 2, "type": "a/ipv4",
+		"records": [{"first_seen": "2016-07-01", "last_seen": "2021-02-28", "organizations": ["Old Provider"], "type": "a",
+		             "values": [{"ip": "198.51.100.7", "ip_count": 1}, {"ip": "192.0.2.10", "ip_count": 12}]}]}`,
+	"/domain/example.com/associated?page=1": `{"endpoint": "/v1/domain/example.com/associated", "record_count": 2,
+		"meta": {"max_page": 100, "page": 1, "total_pages": 1},
+		"records": [{"hostname": "example.org", "computed": {"company_name": "Example Inc."}},
+		            {"hostname": "shop.example.com", "computed": {"company_name": "Example Inc."}}]}`,
+}
+
+// newSecurityTrailsServer 启动返回 securityTrailsResponses 的测试服务器，记录请求路径
+func newSecurityTrailsServer(t *testing.T, requested *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("APIKEY") != "test-key" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message": "Invalid authentication credentials"}`))
+			return
+		}
+
+		key := r.URL.RequestURI()
+		*requested = append(*requested, key)
+		body, ok := securityTrailsResponses[key]
+		if !ok {
+			t.Errorf("Unexpected request %s", key)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+}
+
+// newTestSecurityTrails 创建指向测试服务器的 SecurityTrails 模块
+func newTestSecurityTrails(serverURL string, deep bool) *SecurityTrails {
+	s := NewSecurityTrails(&config.Config{APIKeys: map[string]string{"securitytrails_api": "test-key"}, Deep: deep})
+	s.baseURL = serverURL + "/"
+	s.SetDelay(0)
+	s.SetRetryPolicy(core.RetryPolicy{MaxAttempts: 1})
+	return s
+}
+
+func TestSecurityTrailsDeep(t *testing.T) {
+	var requested []string
+	server := newSecurityTrailsServer(t, &requested)
+	defer server.Close()
+
+	s := newTestSecurityTrails(server.URL, true)
+	subdomains, err := s.Run("example.com")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	sort.Strings(subdomains)
+	expected := []string{"api.example.com", "legacy.example.com", "old-vpn.example.com", "www.example.com"}
+	if strings.Join(subdomains, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, subdomains)
+	}
+
+	// 只有历史中出现的子域名带有单独的来源标记
+	tags := s.TakeSourceTags()
+	if len(tags) != 2 || tags["legacy.example.com"] != SecurityTrailsHistorySource || tags["old-vpn.example.com"] != SecurityTrailsHistorySource {
+		t.Errorf("Expected legacy and old-vpn tagged as history, got %v", tags)
+	}
+
+	ips, _ := s.GetInfo("history_a").([]string)
+	if strings.Join(ips, ",") != "192.0.2.10,192.0.2.11,198.51.100.7" {
+		t.Errorf("Unexpected history IPs: %v", ips)
+	}
+
+	related := s.TakeRelatedDomains()
+	if len(related) != 1 || related[0] != "example.org" {
+		t.Errorf("Expected example.org as related domain, got %v", related)
+	}
+}
+
+func TestSecurityTrailsWithoutDeep(t *testing.T) {
+	var requested []string
+	server := newSecurityTrailsServer(t, &requested)
+	defer server.Close()
+
+	s := newTestSecurityTrails(server.URL, false)
+	subdomains, err := s.Run("example.com")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(subdomains) != 2 {
+		t.Errorf("Expected 2 current subdomains, got %v", subdomains)
+	}
+	if len(requested) != 1 {
+		t.Errorf("Expected only the subdomains endpoint to be queried, got %v", requested)
+	}
+	if tags := s.TakeSourceTags(); len(tags) != 0 {
+		t.Errorf("Expected no source tags, got %v", tags)
+	}
+}
+
+func TestDecodeJSONFallback(t *testing.T) {
+	// 响应被截断且字段名已变更，结构化解析失败，但原始内容里仍有子域名
+	body := `{"data": {"hosts": ["www.example.com", "mail.example.com", "cdn.example.com.attacker.net", "other.org"`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	i := NewIPv4Info(&config.Config{APIKeys: map[string]string{"ipv4info_api_key": "test-key"}})
+	i.baseURL = server.URL + "/"
+	i.SetDelay(0)
+	i.SetRetryPolicy(core.RetryPolicy{MaxAttempts: 1})
+
+	subdomains, err := i.Run("example.com")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	sort.Strings(subdomains)
+	if strings.Join(subdomains, ",") != "mail.example.com,www.example.com" {
+		t.Errorf("Expected in-scope subdomains salvaged from malformed JSON, got %v", subdomains)
+	}
+
+	// 正则也提取不到子域名时返回解析错误
+	q := core.NewQuery("TestQuery", &config.Config{})
+	var response IPv4InfoResponse
+	if decoded, err := q.DecodeJSON(`<html>rate limited</html>`, "example.com", &response); decoded || err == nil {
+		t.Errorf("Expected decode error without salvaged subdomains, got decoded=%v err=%v", decoded, err)
+	}
+	if decoded, err := q.DecodeJSON(`{"Subdomains": ["www.example.com"]}`, "example.com", &response); !decoded || err != nil {
+		t.Errorf("Expected structured decode to succeed, got decoded=%v err=%v", decoded, err)
+	}
+}
+
+func TestRobtexSalvagesMalformedLines(t *testing.T) {
+	// 被截断的一行记录仍含有子域名
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"rrname":"example.com","rrtype":"NS","rrdata":"ns1.example.net"}` + "\n" + `{"rrname":"api.example.com","rrty`))
+	}))
+	defer server.Close()
+
+	r := NewRobtex(&config.Config{})
+	r.baseURL = server.URL
+	r.SetDelay(0)
+	r.SetRetryPolicy(core.RetryPolicy{MaxAttempts: 1})
+
+	subdomains, err := r.Run("example.com")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if strings.Join(subdomains, ",") != "api.example.com" {
+		t.Errorf("Expected api.example.com salvaged from the malformed line, got %v", subdomains)
+	}
+}
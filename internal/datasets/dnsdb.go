@@ -23,6 +23,11 @@ func NewDNSDB(cfg *config.Config) *DNSDB {
 	}
 }
 
+// RequiresAPIKey 未配置 dnsdb_api_key 时不发送请求
+func (d *DNSDB) RequiresAPIKey() bool {
+	return true
+}
+
 // Run 执行查询
 func (d *DNSDB) Run(domain string) ([]string, error) {
 	d.SetDomain(domain)
@@ -23,6 +23,11 @@ func NewFullHunt(cfg *config.Config) *FullHunt {
 	}
 }
 
+// RequiresAPIKey 未配置 fullhunt_api_key 时不发送请求
+func (f *FullHunt) RequiresAPIKey() bool {
+	return true
+}
+
 // Run 执行查询
 func (f *FullHunt) Run(domain string) ([]string, error) {
 	f.SetDomain(domain)
@@ -1,7 +1,6 @@
 package datasets
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/url"
 	"strconv"
@@ -32,6 +31,11 @@ func NewIPv4Info(cfg *config.Config) *IPv4Info {
 	}
 }
 
+// RequiresAPIKey 未配置 ipv4info_api_key 时不发送请求
+func (i *IPv4Info) RequiresAPIKey() bool {
+	return true
+}
+
 // Run 执行查询
 func (i *IPv4Info) Run(domain string) ([]string, error) {
 	i.SetDomain(domain)
@@ -80,7 +84,8 @@ func (i *IPv4Info) query(domain string) error {
 
 		// 解析 JSON 响应
 		var response IPv4InfoResponse
-		if err := json.Unmarshal([]byte(body), &response); err != nil {
+		// 解析失败时已从原始响应中提取子域名，无法判断是否还有下一页
+		if decoded, err := i.DecodeJSON(body, domain, &response); err != nil || !decoded {
 			break
 		}
 
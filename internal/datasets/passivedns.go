@@ -28,6 +28,11 @@ func NewPassiveDNS(cfg *config.Config) *PassiveDNS {
 	}
 }
 
+// RequiresAPIKey 使用默认的 passivedns.cn 接口时需要配置 passivedns_api_token
+func (p *PassiveDNS) RequiresAPIKey() bool {
+	return p.baseURL == "http://api.passivedns.cn"
+}
+
 // Run 执行查询
 func (p *PassiveDNS) Run(domain string) ([]string, error) {
 	p.SetDomain(domain)
@@ -1,7 +1,6 @@
 package datasets
 
 import (
-	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -69,8 +68,9 @@ func (r *Robtex) query(domain string) error {
 			continue
 		}
 
+		// 解析失败的行已从原始内容中提取子域名
 		var record RobtexRecord
-		if err := json.Unmarshal([]byte(line), &record); err != nil {
+		if decoded, err := r.DecodeJSON(line, domain, &record); err != nil || !decoded {
 			continue
 		}
 
@@ -3,16 +3,25 @@ package datasets
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"sort"
 
 	"github.com/oneforall-go/internal/config"
 	"github.com/oneforall-go/internal/core"
 )
 
+// SecurityTrailsHistorySource 只在 SecurityTrails 历史记录中出现（当前已不活跃）的子域名的来源
+const SecurityTrailsHistorySource = "securitytrails_history"
+
+// securityTrailsMaxPages 历史记录和关联域名最多翻页数，每页消耗一次查询额度
+const securityTrailsMaxPages = 5
+
 // SecurityTrails SecurityTrails 数据集模块
 type SecurityTrails struct {
 	*core.Query
 	baseURL string
 	apiKey  string
+	deep    bool
 }
 
 // SecurityTrailsResponse SecurityTrails API 响应结构
@@ -20,15 +29,43 @@ type SecurityTrailsResponse struct {
 	Subdomains []string `json:"subdomains"`
 }
 
+// SecurityTrailsHistoryResponse DNS 历史记录（/history/{domain}/dns/a）响应结构
+type SecurityTrailsHistoryResponse struct {
+	Pages   int `json:"pages"`
+	Records []struct {
+		Values []struct {
+			IP string `json:"ip"`
+		} `json:"values"`
+		FirstSeen string `json:"first_seen"`
+		LastSeen  string `json:"last_seen"`
+	} `json:"records"`
+}
+
+// SecurityTrailsAssociatedResponse 关联域名（/domain/{domain}/associated）响应结构
+type SecurityTrailsAssociatedResponse struct {
+	Records []struct {
+		Hostname string `json:"hostname"`
+	} `json:"records"`
+	Meta struct {
+		TotalPages int `json:"total_pages"`
+	} `json:"meta"`
+}
+
 // NewSecurityTrails 创建 SecurityTrails 模块
 func NewSecurityTrails(cfg *config.Config) *SecurityTrails {
 	return &SecurityTrails{
 		Query:   core.NewQuery("SecurityTrailsAPIQuery", cfg),
-		baseURL: "https://api.securitytrails.com/v1/domain/",
+		baseURL: "https://api.securitytrails.com/v1/",
 		apiKey:  cfg.APIKeys["securitytrails_api"],
+		deep:    cfg.Deep,
 	}
 }
 
+// RequiresAPIKey 未配置 securitytrails_api 时不发送请求
+func (s *SecurityTrails) RequiresAPIKey() bool {
+	return true
+}
+
 // Run 执行查询
 func (s *SecurityTrails) Run(domain string) ([]string, error) {
 	s.SetDomain(domain)
@@ -40,49 +77,183 @@ func (s *SecurityTrails) Run(domain string) ([]string, error) {
 		return nil, fmt.Errorf("securitytrails_api key not configured")
 	}
 
+	// 设置请求头
+	s.SetHeader("APIKEY", s.apiKey)
+	s.SetHeader("Content-Type", "application/json")
+	s.SetHeader("User-Agent", s.GetRandomUserAgent())
+
 	// 执行查询
 	if err := s.query(domain); err != nil {
 		return nil, err
 	}
 
+	// 深度查询额外消耗 API 额度，失败不影响当前子域名结果
+	if s.deep {
+		if err := s.queryInactive(domain); err != nil {
+			s.LogError("Failed to query inactive subdomains: %v", err)
+		}
+		if err := s.queryHistory(domain); err != nil {
+			s.LogError("Failed to query DNS history: %v", err)
+		}
+		if err := s.queryAssociated(domain); err != nil {
+			s.LogError("Failed to query associated domains: %v", err)
+		}
+	}
+
 	return s.GetSubdomains(), nil
 }
 
-// query 执行查询
+// query 查询当前子域名
 func (s *SecurityTrails) query(domain string) error {
-	// 设置请求头
-	s.SetHeader("APIKEY", s.apiKey)
-	s.SetHeader("Content-Type", "application/json")
-	s.SetHeader("User-Agent", s.GetRandomUserAgent())
+	subdomains, err := s.subdomains(domain, false)
+	if err != nil {
+		return err
+	}
 
-	// 构建查询 URL
-	queryURL := fmt.Sprintf("%s%s/subdomains", s.baseURL, domain)
+	for _, subdomain := range subdomains {
+		if s.IsValidSubdomain(subdomain, domain) {
+			s.AddSubdomain(subdomain)
+		}
+	}
+	return nil
+}
 
-	// 发送 GET 请求
-	resp, err := s.HTTPGet(queryURL, s.GetHeader())
+// queryInactive 查询包含已不活跃子域名的完整列表，只在历史中出现的子域名单独标记来源
+func (s *SecurityTrails) queryInactive(domain string) error {
+	subdomains, err := s.subdomains(domain, true)
 	if err != nil {
-		return fmt.Errorf("failed to query SecurityTrails API: %v", err)
+		return err
 	}
 
-	// 读取响应
-	body, err := s.ReadResponseBody(resp)
-	if err != nil {
-		return fmt.Errorf("failed to read response: %v", err)
+	active := make(map[string]bool)
+	for _, subdomain := range s.GetSubdomains() {
+		active[subdomain] = true
 	}
 
-	// 解析 JSON 响应
+	historical := 0
+	for _, subdomain := range subdomains {
+		if active[core.NormalizeHost(subdomain)] || !s.IsValidSubdomain(subdomain, domain) {
+			continue
+		}
+		s.AddTaggedSubdomain(subdomain, SecurityTrailsHistorySource)
+		historical++
+	}
+	s.LogInfo("Found %d historical-only subdomains for %s", historical, domain)
+	return nil
+}
+
+// subdomains 请求子域名列表，返回完整的子域名
+func (s *SecurityTrails) subdomains(domain string, includeInactive bool) ([]string, error) {
+	queryURL := fmt.Sprintf("%sdomain/%s/subdomains", s.baseURL, domain)
+	if includeInactive {
+		queryURL += "?children_only=false&include_inactive=true"
+	}
+
+	body, err := s.get(queryURL)
+	if err != nil {
+		return nil, err
+	}
+	// 解析失败时已从原始响应中提取完整的子域名，不再按标签拼接
 	var response SecurityTrailsResponse
-	if err := json.Unmarshal([]byte(body), &response); err != nil {
-		return fmt.Errorf("failed to parse JSON response: %v", err)
+	if decoded, err := s.DecodeJSON(body, domain, &response); err != nil || !decoded {
+		return nil, err
 	}
 
-	// 添加子域名
+	subdomains := make([]string, 0, len(response.Subdomains))
 	for _, subdomain := range response.Subdomains {
-		fullSubdomain := fmt.Sprintf("%s.%s", subdomain, domain)
-		if s.IsValidSubdomain(fullSubdomain, domain) {
-			s.AddSubdomain(fullSubdomain)
+		subdomains = append(subdomains, fmt.Sprintf("%s.%s", subdomain, domain))
+	}
+	return subdomains, nil
+}
+
+// queryHistory 查询域名的 A 记录历史，记录曾经使用过的 IP（模块信息 history_a）
+func (s *SecurityTrails) queryHistory(domain string) error {
+	seen := make(map[string]bool)
+	for page := 1; page <= securityTrailsMaxPages; page++ {
+		var response SecurityTrailsHistoryResponse
+		queryURL := fmt.Sprintf("%shistory/%s/dns/a?page=%d", s.baseURL, domain, page)
+		if err := s.getJSON(queryURL, &response); err != nil {
+			if page > 1 {
+				break
+			}
+			return err
+		}
+
+		for _, record := range response.Records {
+			for _, value := range record.Values {
+				if value.IP != "" {
+					seen[value.IP] = true
+				}
+			}
+		}
+		if page >= response.Pages {
+			break
 		}
 	}
 
+	ips := make([]string, 0, len(seen))
+	for ip := range seen {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+	s.AddInfo("history_a", ips)
+	s.LogInfo("Found %d historical A record IPs for %s", len(ips), domain)
+	return nil
+}
+
+// queryAssociated 查询同一组织的关联域名，记为关联域名用于横向扩展
+func (s *SecurityTrails) queryAssociated(domain string) error {
+	for page := 1; page <= securityTrailsMaxPages; page++ {
+		var response SecurityTrailsAssociatedResponse
+		queryURL := fmt.Sprintf("%sdomain/%s/associated?page=%d", s.baseURL, domain, page)
+		if err := s.getJSON(queryURL, &response); err != nil {
+			if page > 1 {
+				break
+			}
+			return err
+		}
+
+		for _, record := range response.Records {
+			if record.Hostname != "" && !core.InScope(record.Hostname, domain) {
+				s.AddRelatedDomain(record.Hostname)
+			}
+		}
+		if page >= response.Meta.TotalPages {
+			break
+		}
+	}
 	return nil
 }
+
+// getJSON 发送 GET 请求并解析 JSON 响应
+func (s *SecurityTrails) getJSON(queryURL string, v interface{}) error {
+	body, err := s.get(queryURL)
+	if err != nil {
+		return err
+	}
+
+	// 解析 JSON 响应
+	if err := json.Unmarshal([]byte(body), v); err != nil {
+		return fmt.Errorf("failed to parse JSON response: %v", err)
+	}
+	return nil
+}
+
+// get 发送 GET 请求并返回响应内容，非 200 响应返回错误
+func (s *SecurityTrails) get(queryURL string) (string, error) {
+	// 发送 GET 请求
+	resp, err := s.HTTPGet(queryURL, s.GetHeader())
+	if err != nil {
+		return "", fmt.Errorf("failed to query SecurityTrails API: %v", err)
+	}
+
+	// 读取响应
+	body, err := s.ReadResponseBody(resp)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("SecurityTrails API returned status %d", resp.StatusCode)
+	}
+	return body, nil
+}
@@ -1,7 +1,6 @@
 package datasets
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/url"
 
@@ -32,6 +31,11 @@ func NewSpyse(cfg *config.Config) *Spyse {
 	}
 }
 
+// RequiresAPIKey 未配置 spyse_api_token 时不发送请求
+func (s *Spyse) RequiresAPIKey() bool {
+	return true
+}
+
 // Run 执行查询
 func (s *Spyse) Run(domain string) ([]string, error) {
 	s.SetDomain(domain)
@@ -82,7 +86,8 @@ func (s *Spyse) query(domain string) error {
 
 		// 解析 JSON 响应
 		var response SpyseResponse
-		if err := json.Unmarshal([]byte(body), &response); err != nil {
+		// 解析失败时已从原始响应中提取子域名，无法判断是否还有下一页
+		if decoded, err := s.DecodeJSON(body, domain, &response); err != nil || !decoded {
 			break
 		}
 
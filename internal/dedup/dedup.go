@@ -0,0 +1,112 @@
+package dedup
+
+import (
+	"hash/maphash"
+	"math"
+)
+
+// DefaultFalsePositiveRate 未配置时布隆过滤器的误判率
+const DefaultFalsePositiveRate = 0.0001
+
+// Set 字符串去重集合
+type Set interface {
+	// Add 添加元素，元素已存在时返回 false；布隆过滤器可能把新元素误判为已存在
+	Add(item string) bool
+}
+
+// NewSet 按预计元素数创建去重集合：超过 threshold 时使用误判率为 fpRate 的布隆过滤器，
+// 否则（或 threshold <= 0 时）使用精确的 map
+func NewSet(expected, threshold int, fpRate float64) Set {
+	if threshold > 0 && expected > threshold {
+		return NewBloomFilter(expected, fpRate)
+	}
+	return make(exactSet)
+}
+
+// exactSet 基于 map 的精确去重集合
+type exactSet map[string]struct{}
+
+// Add 添加元素，元素已存在时返回 false
+func (s exactSet) Add(item string) bool {
+	if _, ok := s[item]; ok {
+		return false
+	}
+	s[item] = struct{}{}
+	return true
+}
+
+// BloomFilter 布隆过滤器：判断为不存在的元素一定不存在（无漏判），
+// 判断为已存在的元素有约 fpRate 的概率其实没有添加过（误判）。
+// 每个元素只占约 -ln(fpRate)/ln(2)^2 位，不保存元素本身
+type BloomFilter struct {
+	bits  []uint64
+	m     uint64 // 位数
+	k     uint64 // 哈希函数个数
+	seed1 maphash.Seed
+	seed2 maphash.Seed
+}
+
+// NewBloomFilter 按预计元素数和误判率创建布隆过滤器，实际元素数超过预计值时误判率会上升
+func NewBloomFilter(expected int, fpRate float64) *BloomFilter {
+	if expected < 1 {
+		expected = 1
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		fpRate = DefaultFalsePositiveRate
+	}
+
+	n := float64(expected)
+	m := uint64(math.Ceil(-n * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := uint64(math.Round(float64(m) / n * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+
+	return &BloomFilter{
+		bits:  make([]uint64, (m+63)/64),
+		m:     m,
+		k:     k,
+		seed1: maphash.MakeSeed(),
+		seed2: maphash.MakeSeed(),
+	}
+}
+
+// Add 添加元素，所有对应位都已置位（元素已存在或误判）时返回 false
+func (f *BloomFilter) Add(item string) bool {
+	h1, h2 := f.hash(item)
+	added := false
+	for i := uint64(0); i < f.k; i++ {
+		pos := (h1 + i*h2) % f.m
+		word, mask := pos/64, uint64(1)<<(pos%64)
+		if f.bits[word]&mask == 0 {
+			f.bits[word] |= mask
+			added = true
+		}
+	}
+	return added
+}
+
+// Contains 判断元素是否可能已添加，返回 false 时一定未添加
+func (f *BloomFilter) Contains(item string) bool {
+	h1, h2 := f.hash(item)
+	for i := uint64(0); i < f.k; i++ {
+		pos := (h1 + i*h2) % f.m
+		if f.bits[pos/64]&(uint64(1)<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// SizeBytes 位数组占用的字节数
+func (f *BloomFilter) SizeBytes() int {
+	return len(f.bits) * 8
+}
+
+// hash 双重哈希：用两个独立种子的哈希值组合出 k 个位置，h2 取奇数保证步长不为 0
+func (f *BloomFilter) hash(item string) (uint64, uint64) {
+	return maphash.String(f.seed1, item), maphash.String(f.seed2, item) | 1
+}
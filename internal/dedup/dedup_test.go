@@ -0,0 +1,84 @@
+package dedup
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBloomFilterNoFalseNegatives(t *testing.T) {
+	const n = 100000
+	filter := NewBloomFilter(n, 0.001)
+	for i := 0; i < n; i++ {
+		filter.Add(fmt.Sprintf("word%d", i))
+	}
+
+	// 添加过的元素一定能查到
+	for i := 0; i < n; i++ {
+		if !filter.Contains(fmt.Sprintf("word%d", i)) {
+			t.Fatalf("Expected word%d to be present", i)
+		}
+	}
+
+	// 未添加的元素误判率应接近配置值
+	falsePositives := 0
+	for i := 0; i < n; i++ {
+		if filter.Contains(fmt.Sprintf("other%d", i)) {
+			falsePositives++
+		}
+	}
+	if rate := float64(falsePositives) / n; rate > 0.003 {
+		t.Errorf("Expected false positive rate near 0.001, got %.4f", rate)
+	}
+}
+
+func TestNewSet(t *testing.T) {
+	// 小集合使用精确的 map
+	small := NewSet(10, 100, 0.01)
+	if _, ok := small.(exactSet); !ok {
+		t.Errorf("Expected exact set below threshold, got %T", small)
+	}
+	if !small.Add("www") || small.Add("www") {
+		t.Error("Expected exact set to report duplicates")
+	}
+
+	// 超过阈值时使用布隆过滤器
+	if _, ok := NewSet(1000, 100, 0.01).(*BloomFilter); !ok {
+		t.Error("Expected bloom filter above threshold")
+	}
+	// 阈值为 0 时始终精确去重
+	if _, ok := NewSet(1000000, 0, 0.01).(exactSet); !ok {
+		t.Error("Expected exact set when threshold is disabled")
+	}
+}
+
+// benchmarkWords 基准测试使用的候选词
+var benchmarkWords = func() []string {
+	words := make([]string, 1000000)
+	for i := range words {
+		words[i] = fmt.Sprintf("candidate-%d", i)
+	}
+	return words
+}()
+
+// BenchmarkDedupExact 和 BenchmarkDedupBloom 对比 100 万候选去重的内存占用，
+// 使用 go test -bench Dedup -benchmem ./internal/dedup/ 查看 B/op
+func BenchmarkDedupExact(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		set := NewSet(len(benchmarkWords), 0, DefaultFalsePositiveRate)
+		for _, word := range benchmarkWords {
+			set.Add(word)
+		}
+	}
+}
+
+func BenchmarkDedupBloom(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		set := NewSet(len(benchmarkWords), 1, DefaultFalsePositiveRate)
+		for _, word := range benchmarkWords {
+			set.Add(word)
+		}
+		b.ReportMetric(float64(set.(*BloomFilter).SizeBytes())/float64(len(benchmarkWords)), "bytes/item")
+	}
+}
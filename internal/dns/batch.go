@@ -0,0 +1,210 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/oneforall-go/pkg/logger"
+)
+
+// batchAttempts 批量解析时每个主机最多尝试的 DNS 服务器数
+const batchAttempts = 3
+
+// batchDefaultTimeout 客户端未设置超时时批量解析单次查询的超时
+const batchDefaultTimeout = 2 * time.Second
+
+// ResolveBatch 使用工作池批量解析主机的地址记录（按 IP 版本查询 A 和/或 AAAA），返回主机到去重后 IP 的映射，
+// 无法解析的主机不在结果中。工作协程数为客户端的并发数，每个工作协程为各 DNS 服务器复用一条连接，
+// 同一主机的各类型查询连续发出后再统一读取应答；主机轮流分配到不同服务器，超时或出错时换下一个服务器重试
+func (c *Client) ResolveBatch(hosts []string) map[string][]string {
+	return c.ResolveBatchContext(context.Background(), hosts)
+}
+
+// ResolveBatchContext 同 ResolveBatch，ctx 取消后不再分发新的主机，等待进行中的查询结束后返回已解析的部分结果
+func (c *Client) ResolveBatchContext(ctx context.Context, hosts []string) map[string][]string {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	results := make(map[string][]string)
+	if len(hosts) == 0 || len(c.resolvers) == 0 {
+		return results
+	}
+
+	workers := int(c.concurrency)
+	if workers <= 0 {
+		workers = 1
+	}
+	if workers > len(hosts) {
+		workers = len(hosts)
+	}
+
+	type job struct {
+		host   string
+		offset int
+	}
+	jobs := make(chan job, workers)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker := &batchWorker{client: c, conns: make(map[string]*dns.Conn)}
+			defer worker.close()
+
+			for j := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				if ips := worker.resolve(j.host, j.offset); len(ips) > 0 {
+					mu.Lock()
+					results[j.host] = ips
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	seen := make(map[string]bool, len(hosts))
+	offset := 0
+dispatch:
+	for _, host := range hosts {
+		if host == "" || seen[host] {
+			continue
+		}
+		seen[host] = true
+		select {
+		case jobs <- job{host: host, offset: offset}:
+			offset++
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		logger.Debugf("Batch resolve interrupted (%v), resolved %d of %d hosts", ctx.Err(), len(results), len(hosts))
+		return results
+	}
+
+	logger.Debugf("Batch resolved %d of %d hosts with %d workers", len(results), len(seen), workers)
+	return results
+}
+
+// batchWorker 批量解析的工作协程，按 DNS 服务器缓存连接，只在所属协程中使用
+type batchWorker struct {
+	client *Client
+	conns  map[string]*dns.Conn
+}
+
+// resolve 从第 offset 个服务器开始依次尝试解析主机，服务器正常应答（包括 NXDOMAIN）后不再重试
+func (w *batchWorker) resolve(host string, offset int) []string {
+	resolvers := w.client.resolvers
+	attempts := batchAttempts
+	if attempts > len(resolvers) {
+		attempts = len(resolvers)
+	}
+
+	for i := 0; i < attempts; i++ {
+		server := resolvers[(offset+i)%len(resolvers)]
+		ips, err := w.exchange(host, server)
+		if err != nil {
+			logger.Debugf("Batch resolve of %s with %s failed: %v", host, server, err)
+			continue
+		}
+		return w.client.deduplicate(ips)
+	}
+	return nil
+}
+
+// exchange 在复用的连接上连续发出各记录类型的查询，再按消息 ID 读取全部应答；
+// 与本次查询无关的应答（之前超时查询的迟到应答）直接丢弃，连接出错时关闭以便下次重建
+func (w *batchWorker) exchange(host, server string) ([]string, error) {
+	conn, err := w.conn(server)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := w.timeout()
+	fqdn := dns.Fqdn(host)
+	pending := make(map[uint16]bool)
+	conn.SetWriteDeadline(time.Now().Add(timeout))
+	for _, qtype := range RecordTypes(w.client.ipVersion) {
+		msg := new(dns.Msg)
+		msg.SetQuestion(fqdn, qtype)
+		msg.RecursionDesired = true
+		AddClientSubnet(msg, w.client.subnet)
+		if err := conn.WriteMsg(msg); err != nil {
+			w.drop(server)
+			return nil, err
+		}
+		pending[msg.Id] = true
+	}
+
+	var ips []string
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	for len(pending) > 0 {
+		resp, err := conn.ReadMsg()
+		if err != nil {
+			w.drop(server)
+			return nil, err
+		}
+		if !pending[resp.Id] || len(resp.Question) != 1 || !strings.EqualFold(resp.Question[0].Name, fqdn) {
+			continue
+		}
+		delete(pending, resp.Id)
+
+		if resp.Rcode != dns.RcodeSuccess && resp.Rcode != dns.RcodeNameError {
+			return nil, fmt.Errorf("DNS query failed: %s", dns.RcodeToString[resp.Rcode])
+		}
+		for _, answer := range resp.Answer {
+			if ip := AddressFromRR(answer); ip != "" {
+				ips = append(ips, ip)
+			}
+		}
+	}
+	return ips, nil
+}
+
+// conn 返回到服务器的连接，不存在时新建
+func (w *batchWorker) conn(server string) (*dns.Conn, error) {
+	if conn, ok := w.conns[server]; ok {
+		return conn, nil
+	}
+	client, addr := NewExchangeClient(server, w.timeout(), w.client.insecureTLS)
+	conn, err := client.Dial(addr)
+	if err != nil {
+		return nil, err
+	}
+	w.conns[server] = conn
+	return conn, nil
+}
+
+// drop 关闭并移除到服务器的连接
+func (w *batchWorker) drop(server string) {
+	if conn, ok := w.conns[server]; ok {
+		conn.Close()
+		delete(w.conns, server)
+	}
+}
+
+// close 关闭全部连接
+func (w *batchWorker) close() {
+	for server := range w.conns {
+		w.drop(server)
+	}
+}
+
+// timeout 单次查询的超时
+func (w *batchWorker) timeout() time.Duration {
+	if w.client.timeout > 0 {
+		return w.client.timeout
+	}
+	return batchDefaultTimeout
+}
@@ -3,10 +3,12 @@ package dns
 import (
 	"context"
 	"fmt"
+	"net"
 	"strings"
 	"time"
 
 	"github.com/miekg/dns"
+	"github.com/oneforall-go/internal/config"
 	"github.com/oneforall-go/pkg/logger"
 	"golang.org/x/sync/semaphore"
 )
@@ -17,6 +19,9 @@ type Client struct {
 	concurrency int64
 	semaphore   *semaphore.Weighted
 	resolvers   []string
+	subnet      *net.IPNet // EDNS Client Subnet，为 nil 时不附加
+	insecureTLS bool       // DoT 服务器不校验证书
+	ipVersion   string     // 解析的 IP 版本，见 config.IPVersion
 }
 
 // NewClient 创建新的 DNS 客户端
@@ -26,6 +31,7 @@ func NewClient(timeout int, concurrency int) *Client {
 		concurrency: int64(concurrency),
 		semaphore:   semaphore.NewWeighted(int64(concurrency)),
 		resolvers:   getDefaultResolvers(),
+		ipVersion:   config.IPVersion4,
 	}
 }
 
@@ -55,32 +61,38 @@ func (c *Client) Resolve(domain string) ([]string, error) {
 	return ips, nil
 }
 
-// resolveWithServer 使用指定服务器解析
+// resolveWithServer 使用指定服务器解析，按 IP 版本查询 A 和/或 AAAA 记录
 func (c *Client) resolveWithServer(domain, server string) ([]string, error) {
 	// 创建 DNS 客户端
-	client := &dns.Client{
-		Timeout: c.timeout,
-	}
+	client, addr := NewExchangeClient(server, c.timeout, c.insecureTLS)
 
-	// 创建查询消息
-	msg := new(dns.Msg)
-	msg.SetQuestion(dns.Fqdn(domain), dns.TypeA)
-	msg.RecursionDesired = true
-
-	// 发送查询
-	resp, _, err := client.Exchange(msg, server)
-	if err != nil {
-		return nil, fmt.Errorf("DNS query failed: %v", err)
-	}
-
-	// 解析响应
 	ips := make([]string, 0)
-	for _, answer := range resp.Answer {
-		if a, ok := answer.(*dns.A); ok {
-			ips = append(ips, a.A.String())
+	var lastErr error
+	for _, qtype := range RecordTypes(c.ipVersion) {
+		// 创建查询消息
+		msg := new(dns.Msg)
+		msg.SetQuestion(dns.Fqdn(domain), qtype)
+		msg.RecursionDesired = true
+		AddClientSubnet(msg, c.subnet)
+
+		// 发送查询
+		resp, _, err := client.Exchange(msg, addr)
+		if err != nil {
+			lastErr = fmt.Errorf("DNS query failed: %v", err)
+			continue
+		}
+
+		// 解析响应
+		for _, answer := range resp.Answer {
+			if ip := AddressFromRR(answer); ip != "" {
+				ips = append(ips, ip)
+			}
 		}
 	}
 
+	if len(ips) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
 	return ips, nil
 }
 
@@ -113,17 +125,16 @@ func (c *Client) ResolveCNAME(domain string) ([]string, error) {
 // resolveCNAMEWithServer 使用指定服务器解析 CNAME
 func (c *Client) resolveCNAMEWithServer(domain, server string) ([]string, error) {
 	// 创建 DNS 客户端
-	client := &dns.Client{
-		Timeout: c.timeout,
-	}
+	client, addr := NewExchangeClient(server, c.timeout, c.insecureTLS)
 
 	// 创建查询消息
 	msg := new(dns.Msg)
 	msg.SetQuestion(dns.Fqdn(domain), dns.TypeCNAME)
 	msg.RecursionDesired = true
+	AddClientSubnet(msg, c.subnet)
 
 	// 发送查询
-	resp, _, err := client.Exchange(msg, server)
+	resp, _, err := client.Exchange(msg, addr)
 	if err != nil {
 		return nil, fmt.Errorf("DNS CNAME query failed: %v", err)
 	}
@@ -168,17 +179,16 @@ func (c *Client) ResolveMX(domain string) ([]string, error) {
 // resolveMXWithServer 使用指定服务器解析 MX
 func (c *Client) resolveMXWithServer(domain, server string) ([]string, error) {
 	// 创建 DNS 客户端
-	client := &dns.Client{
-		Timeout: c.timeout,
-	}
+	client, addr := NewExchangeClient(server, c.timeout, c.insecureTLS)
 
 	// 创建查询消息
 	msg := new(dns.Msg)
 	msg.SetQuestion(dns.Fqdn(domain), dns.TypeMX)
 	msg.RecursionDesired = true
+	AddClientSubnet(msg, c.subnet)
 
 	// 发送查询
-	resp, _, err := client.Exchange(msg, server)
+	resp, _, err := client.Exchange(msg, addr)
 	if err != nil {
 		return nil, fmt.Errorf("DNS MX query failed: %v", err)
 	}
@@ -223,17 +233,16 @@ func (c *Client) ResolveNS(domain string) ([]string, error) {
 // resolveNSWithServer 使用指定服务器解析 NS
 func (c *Client) resolveNSWithServer(domain, server string) ([]string, error) {
 	// 创建 DNS 客户端
-	client := &dns.Client{
-		Timeout: c.timeout,
-	}
+	client, addr := NewExchangeClient(server, c.timeout, c.insecureTLS)
 
 	// 创建查询消息
 	msg := new(dns.Msg)
 	msg.SetQuestion(dns.Fqdn(domain), dns.TypeNS)
 	msg.RecursionDesired = true
+	AddClientSubnet(msg, c.subnet)
 
 	// 发送查询
-	resp, _, err := client.Exchange(msg, server)
+	resp, _, err := client.Exchange(msg, addr)
 	if err != nil {
 		return nil, fmt.Errorf("DNS NS query failed: %v", err)
 	}
@@ -281,6 +290,21 @@ func (c *Client) SetResolvers(resolvers []string) {
 	c.resolvers = resolvers
 }
 
+// SetClientSubnet 设置 EDNS Client Subnet，查询时附加该子网以获取对应地区的解析结果（传 nil 取消）
+func (c *Client) SetClientSubnet(subnet *net.IPNet) {
+	c.subnet = subnet
+}
+
+// SetIPVersion 设置解析的 IP 版本（4、6、both），无效值按 4 处理
+func (c *Client) SetIPVersion(version string) {
+	c.ipVersion = version
+}
+
+// SetInsecureTLS 设置是否跳过 DoT 服务器的证书校验，用于使用自签名证书的内网解析器
+func (c *Client) SetInsecureTLS(insecure bool) {
+	c.insecureTLS = insecure
+}
+
 // GetResolvers 获取 DNS 服务器
 func (c *Client) GetResolvers() []string {
 	return c.resolvers
@@ -0,0 +1,59 @@
+package dns
+
+import (
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/oneforall-go/pkg/logger"
+)
+
+// MaxCNAMEHops CNAME 链最大跟踪层数，防止循环引用
+const MaxCNAMEHops = 8
+
+// QueryCNAME 依次向 resolvers 查询域名的 CNAME 记录，返回指向目标（小写，不含结尾的点），没有 CNAME 时返回空字符串
+func QueryCNAME(domain string, resolvers []string, timeout time.Duration, insecure bool) (string, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(domain), dns.TypeCNAME)
+	msg.RecursionDesired = true
+
+	resp, err := Exchange(msg, resolvers, timeout, insecure)
+	if err != nil {
+		return "", err
+	}
+
+	for _, answer := range resp.Answer {
+		if cname, ok := answer.(*dns.CNAME); ok && strings.EqualFold(cname.Hdr.Name, dns.Fqdn(domain)) {
+			return strings.ToLower(strings.TrimSuffix(cname.Target, ".")), nil
+		}
+	}
+	return "", nil
+}
+
+// ResolveCNAMEChain 逐层查询 CNAME，返回从域名出发的完整指向链（不含域名本身），
+// 遇到循环引用、查询失败或超过 MaxCNAMEHops 层时停止
+func ResolveCNAMEChain(domain string, resolvers []string, timeout time.Duration, insecure bool) []string {
+	var chain []string
+	seen := map[string]bool{strings.ToLower(domain): true}
+
+	current := domain
+	for hop := 0; hop < MaxCNAMEHops; hop++ {
+		target, err := QueryCNAME(current, resolvers, timeout, insecure)
+		if err != nil {
+			logger.Debugf("CNAME query %s failed: %v", current, err)
+			break
+		}
+		if target == "" {
+			break
+		}
+		if seen[target] {
+			logger.Debugf("CNAME loop detected for %s at %s", domain, target)
+			break
+		}
+		seen[target] = true
+		chain = append(chain, target)
+		current = target
+	}
+
+	return chain
+}
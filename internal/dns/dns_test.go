@@ -0,0 +1,625 @@
+package dns
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/oneforall-go/internal/config"
+)
+
+// startTestServer 启动本地 DNS 服务器，返回地址和关闭函数
+func startTestServer(t *testing.T, handler dns.HandlerFunc) (string, func()) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen udp: %v", err)
+	}
+
+	started := make(chan struct{})
+	server := &dns.Server{PacketConn: pc, Handler: handler, NotifyStartedFunc: func() { close(started) }}
+	go server.ActivateAndServe()
+	<-started
+
+	return pc.LocalAddr().String(), func() { server.Shutdown() }
+}
+
+// startSilentServer 启动一个不响应任何请求的 UDP 服务，用于模拟超时
+func startSilentServer(t *testing.T) (string, func()) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen udp: %v", err)
+	}
+	return pc.LocalAddr().String(), func() { pc.Close() }
+}
+
+func TestReflectClientFailover(t *testing.T) {
+	silent, closeSilent := startSilentServer(t)
+	defer closeSilent()
+
+	good, closeGood := startTestServer(t, func(w dns.ResponseWriter, req *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		if req.Question[0].Qtype == dns.TypeNS {
+			resp.Answer = append(resp.Answer, &dns.NS{
+				Hdr: dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: 60},
+				Ns:  "ns1.example.com.",
+			})
+		}
+		w.WriteMsg(resp)
+	})
+	defer closeGood()
+
+	client := NewReflectClient(1, 1)
+	client.timeout = 200 * time.Millisecond
+	client.SetResolvers([]string{silent, good})
+
+	records, err := client.queryNSRecords("example.com")
+	if err != nil {
+		t.Fatalf("queryNSRecords failed: %v", err)
+	}
+	if len(records) != 1 || records[0] != "ns1.example.com" {
+		t.Errorf("Expected [ns1.example.com], got %v", records)
+	}
+}
+
+func TestReflectClientAllResolversFail(t *testing.T) {
+	silent, closeSilent := startSilentServer(t)
+	defer closeSilent()
+
+	client := NewReflectClient(1, 1)
+	client.timeout = 100 * time.Millisecond
+	client.SetResolvers([]string{silent})
+
+	if _, err := client.queryNSRecords("example.com"); err == nil {
+		t.Error("Expected error when all resolvers fail")
+	}
+}
+
+func TestExchangeWithConfig(t *testing.T) {
+	silent, closeSilent := startSilentServer(t)
+	defer closeSilent()
+
+	refused, closeRefused := startTestServer(t, func(w dns.ResponseWriter, req *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetRcode(req, dns.RcodeRefused)
+		w.WriteMsg(resp)
+	})
+	defer closeRefused()
+
+	good, closeGood := startTestServer(t, func(w dns.ResponseWriter, req *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		resp.Answer = append(resp.Answer, &dns.MX{
+			Hdr:        dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeMX, Class: dns.ClassINET, Ttl: 60},
+			Preference: 10,
+			Mx:         "mail.example.com.",
+		})
+		w.WriteMsg(resp)
+	})
+	defer closeGood()
+
+	cfg := &config.Config{DNSResolveTimeout: 1, Resolvers: []string{silent, refused, good}}
+	msg := new(dns.Msg)
+	msg.SetQuestion("example.com.", dns.TypeMX)
+
+	resp, err := ExchangeWithConfig(msg, cfg)
+	if err != nil {
+		t.Fatalf("ExchangeWithConfig failed: %v", err)
+	}
+	if len(resp.Answer) != 1 {
+		t.Errorf("Expected answer from the configured resolver, got %v", resp.Answer)
+	}
+
+	cfg.Resolvers = []string{refused}
+	if _, err := ExchangeWithConfig(msg, cfg); err == nil {
+		t.Error("Expected error when every configured resolver refuses")
+	}
+}
+
+func TestQuerySRVRecords(t *testing.T) {
+	dataDir := t.TempDir()
+	prefixFile := filepath.Join(dataDir, srvPrefixFile)
+	if err := os.WriteFile(prefixFile, []byte("# comment\nsip\n_autodiscover\n_minecraft\n"), 0644); err != nil {
+		t.Fatalf("write prefix file: %v", err)
+	}
+
+	var mutex sync.Mutex
+	queried := make(map[string]bool)
+	addr, closeServer := startTestServer(t, func(w dns.ResponseWriter, req *dns.Msg) {
+		name := req.Question[0].Name
+		mutex.Lock()
+		queried[name] = true
+		mutex.Unlock()
+
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		srv := func(target string) {
+			resp.Answer = append(resp.Answer, &dns.SRV{
+				Hdr:    dns.RR_Header{Name: name, Rrtype: dns.TypeSRV, Class: dns.ClassINET, Ttl: 60},
+				Port:   5060,
+				Target: target,
+			})
+		}
+		switch name {
+		case "_sip._tcp.example.com.":
+			srv("sip.example.com.")
+		case "_minecraft._udp.example.com.":
+			srv("MC.Example.com.")
+		case "_autodiscover._tcp.example.com.":
+			srv("autodiscover.outlook.com.")
+		default:
+			resp.Rcode = dns.RcodeNameError
+		}
+		w.WriteMsg(resp)
+	})
+	defer closeServer()
+
+	client := NewReflectClient(1, 1)
+	client.SetResolvers([]string{addr})
+	client.SetConfig(&config.Config{DataDir: dataDir})
+
+	records, err := client.querySRVRecords("example.com")
+	if err != nil {
+		t.Fatalf("querySRVRecords failed: %v", err)
+	}
+	sort.Strings(records)
+	expected := []string{"mc.example.com", "sip.example.com"}
+	if strings.Join(records, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, records)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	for _, name := range []string{"_sip._tcp.example.com.", "_sip._udp.example.com.", "_autodiscover._udp.example.com."} {
+		if !queried[name] {
+			t.Errorf("Expected query for %s", name)
+		}
+	}
+}
+
+func TestParseSPF(t *testing.T) {
+	record := "v=spf1 ip4:192.0.2.0/24 a:web.example.com/24 mx:mail.example.com -include:_spf.example.com " +
+		"include:_spf.google.com exists:%{i}.spf.example.com ?ptr:ptr.example.com redirect=_spf2.example.com ~all"
+
+	hosts, follow := ParseSPF(record)
+
+	expectedHosts := []string{"web.example.com", "mail.example.com", "_spf.example.com", "_spf.google.com", "ptr.example.com", "_spf2.example.com"}
+	if len(hosts) != len(expectedHosts) {
+		t.Fatalf("Expected hosts %v, got %v", expectedHosts, hosts)
+	}
+	for i := range expectedHosts {
+		if hosts[i] != expectedHosts[i] {
+			t.Errorf("Expected host %s, got %s", expectedHosts[i], hosts[i])
+		}
+	}
+
+	expectedFollow := []string{"_spf.example.com", "_spf.google.com", "_spf2.example.com"}
+	if len(follow) != len(expectedFollow) {
+		t.Fatalf("Expected follow %v, got %v", expectedFollow, follow)
+	}
+	for i := range expectedFollow {
+		if follow[i] != expectedFollow[i] {
+			t.Errorf("Expected follow %s, got %s", expectedFollow[i], follow[i])
+		}
+	}
+}
+
+func TestParseDMARC(t *testing.T) {
+	domains := ParseDMARC("v=DMARC1; p=reject; rua=mailto:dmarc@reports.example.com,mailto:agg@vendor.net!10m; ruf=MAILTO:forensic@Example.com")
+
+	expected := []string{"reports.example.com", "vendor.net", "example.com"}
+	if len(domains) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, domains)
+	}
+	for i := range expected {
+		if domains[i] != expected[i] {
+			t.Errorf("Expected %s, got %s", expected[i], domains[i])
+		}
+	}
+}
+
+func TestAddClientSubnet(t *testing.T) {
+	subnet, err := ParseClientSubnet("203.0.113.77")
+	if err != nil {
+		t.Fatalf("ParseClientSubnet failed: %v", err)
+	}
+	if subnet.String() != "203.0.113.0/24" {
+		t.Errorf("Expected single IP to become 203.0.113.0/24, got %s", subnet)
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion("www.example.com.", dns.TypeA)
+	AddClientSubnet(msg, subnet)
+
+	opt := msg.IsEdns0()
+	if opt == nil {
+		t.Fatal("Expected OPT record to be attached")
+	}
+	if len(opt.Option) != 1 {
+		t.Fatalf("Expected 1 EDNS0 option, got %d", len(opt.Option))
+	}
+	ecs, ok := opt.Option[0].(*dns.EDNS0_SUBNET)
+	if !ok {
+		t.Fatalf("Expected EDNS0_SUBNET option, got %T", opt.Option[0])
+	}
+	if ecs.Family != 1 || ecs.SourceNetmask != 24 || ecs.SourceScope != 0 || ecs.Address.String() != "203.0.113.0" {
+		t.Errorf("Unexpected ECS option: family=%d netmask=%d scope=%d address=%s",
+			ecs.Family, ecs.SourceNetmask, ecs.SourceScope, ecs.Address)
+	}
+
+	// 序列化后仍可解析出 ECS 选项
+	packed, err := msg.Pack()
+	if err != nil {
+		t.Fatalf("Pack failed: %v", err)
+	}
+	unpacked := new(dns.Msg)
+	if err := unpacked.Unpack(packed); err != nil {
+		t.Fatalf("Unpack failed: %v", err)
+	}
+	if opt := unpacked.IsEdns0(); opt == nil || len(opt.Option) != 1 {
+		t.Errorf("Expected ECS option to survive packing, got %v", unpacked.Extra)
+	}
+
+	v6, err := ParseClientSubnet("2001:db8:1234::/48")
+	if err != nil {
+		t.Fatalf("ParseClientSubnet failed: %v", err)
+	}
+	msg6 := new(dns.Msg)
+	msg6.SetQuestion("www.example.com.", dns.TypeAAAA)
+	AddClientSubnet(msg6, v6)
+	if ecs := msg6.IsEdns0().Option[0].(*dns.EDNS0_SUBNET); ecs.Family != 2 || ecs.SourceNetmask != 48 {
+		t.Errorf("Unexpected IPv6 ECS option: family=%d netmask=%d", ecs.Family, ecs.SourceNetmask)
+	}
+
+	// 未配置时不附加 OPT
+	plain := new(dns.Msg)
+	plain.SetQuestion("www.example.com.", dns.TypeA)
+	AddClientSubnet(plain, nil)
+	if plain.IsEdns0() != nil {
+		t.Error("Expected no OPT record without a subnet")
+	}
+
+	if _, err := ParseClientSubnet("not-a-subnet"); err == nil {
+		t.Error("Expected error for invalid subnet")
+	}
+}
+
+// startDoTServer 启动本地 DNS over TLS 服务器，证书为 127.0.0.1 的自签名证书，返回地址和证书
+func startDoTServer(t *testing.T, handler dns.HandlerFunc) (string, *x509.Certificate, func()) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "dot.test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	})
+	if err != nil {
+		t.Fatalf("listen tls: %v", err)
+	}
+
+	started := make(chan struct{})
+	server := &dns.Server{Listener: listener, Net: "tcp-tls", Handler: handler, NotifyStartedFunc: func() { close(started) }}
+	go server.ActivateAndServe()
+	<-started
+
+	return listener.Addr().String(), cert, func() { server.Shutdown() }
+}
+
+func TestDoTResolve(t *testing.T) {
+	addr, cert, shutdown := startDoTServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		rr, _ := dns.NewRR(r.Question[0].Name + " 60 IN A 192.0.2.10")
+		m.Answer = append(m.Answer, rr)
+		w.WriteMsg(m)
+	})
+	defer shutdown()
+
+	server := DoTScheme + addr
+	msg := new(dns.Msg)
+	msg.SetQuestion("www.example.com.", dns.TypeA)
+
+	// 默认校验证书：自签名证书不受信任时查询失败
+	client, dialAddr := NewExchangeClient(server, 2*time.Second, false)
+	if client.Net != "tcp-tls" || dialAddr != addr {
+		t.Fatalf("Expected tcp-tls client for %s, got %q %s", addr, client.Net, dialAddr)
+	}
+	if _, _, err := client.Exchange(msg, dialAddr); err == nil {
+		t.Error("Expected certificate verification to fail for an untrusted certificate")
+	}
+
+	// 信任该证书后按服务器地址校验通过
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	client.TLSConfig.RootCAs = roots
+	resp, _, err := client.Exchange(msg, dialAddr)
+	if err != nil {
+		t.Fatalf("Expected DoT query to succeed with a trusted certificate: %v", err)
+	}
+	if len(resp.Answer) != 1 {
+		t.Errorf("Expected 1 answer, got %d", len(resp.Answer))
+	}
+
+	// 跳过证书校验（内网解析器）
+	c := NewClient(2, 1)
+	c.SetResolvers([]string{server})
+	c.SetInsecureTLS(true)
+	ips, err := c.Resolve("www.example.com")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if len(ips) != 1 || ips[0] != "192.0.2.10" {
+		t.Errorf("Expected [192.0.2.10] over DoT, got %v", ips)
+	}
+}
+
+func TestNewExchangeClientDefaultPort(t *testing.T) {
+	client, addr := NewExchangeClient("tls://dns.google", time.Second, false)
+	if addr != "dns.google:853" || client.TLSConfig.ServerName != "dns.google" {
+		t.Errorf("Expected dns.google:853 with SNI dns.google, got %s %q", addr, client.TLSConfig.ServerName)
+	}
+
+	client, addr = NewExchangeClient("10.0.0.1:5353", time.Second, false)
+	if addr != "10.0.0.1:5353" || client.Net != "" {
+		t.Errorf("Expected plain DNS client for 10.0.0.1:5353, got %q %s", client.Net, addr)
+	}
+}
+
+// dualStackHandler 对 A 查询返回 192.0.2.1，对 AAAA 查询返回 2001:db8::1，并记录收到的查询类型
+func dualStackHandler(mu *sync.Mutex, queried *[]string) dns.HandlerFunc {
+	return func(w dns.ResponseWriter, r *dns.Msg) {
+		question := r.Question[0]
+		mu.Lock()
+		*queried = append(*queried, dns.TypeToString[question.Qtype])
+		mu.Unlock()
+
+		m := new(dns.Msg)
+		m.SetReply(r)
+		switch question.Qtype {
+		case dns.TypeA:
+			rr, _ := dns.NewRR(question.Name + " 60 IN A 192.0.2.1")
+			m.Answer = append(m.Answer, rr)
+		case dns.TypeAAAA:
+			rr, _ := dns.NewRR(question.Name + " 60 IN AAAA 2001:db8::1")
+			m.Answer = append(m.Answer, rr)
+		}
+		w.WriteMsg(m)
+	}
+}
+
+func TestResolveIPVersion(t *testing.T) {
+	var mu sync.Mutex
+	var queried []string
+	addr, shutdown := startTestServer(t, dualStackHandler(&mu, &queried))
+	defer shutdown()
+
+	tests := []struct {
+		version string
+		queried string
+		ips     string
+	}{
+		{"", "A", "192.0.2.1"},
+		{"4", "A", "192.0.2.1"},
+		{"6", "AAAA", "2001:db8::1"},
+		{"both", "A,AAAA", "192.0.2.1,2001:db8::1"},
+	}
+	for _, tt := range tests {
+		mu.Lock()
+		queried = nil
+		mu.Unlock()
+
+		c := NewClient(2, 1)
+		c.SetResolvers([]string{addr})
+		c.SetIPVersion(tt.version)
+		ips, err := c.Resolve("www.example.com")
+		if err != nil {
+			t.Fatalf("Resolve with IP version %q failed: %v", tt.version, err)
+		}
+
+		sort.Strings(ips)
+		mu.Lock()
+		got := strings.Join(queried, ",")
+		mu.Unlock()
+		if got != tt.queried {
+			t.Errorf("IP version %q: expected %s queries, got %s", tt.version, tt.queried, got)
+		}
+		if strings.Join(ips, ",") != tt.ips {
+			t.Errorf("IP version %q: expected %s, got %v", tt.version, tt.ips, ips)
+		}
+	}
+}
+
+// batchHandler 对 hostN.example.com 返回 10.0.N/256.N%256，对 AAAA 查询返回 2001:db8::1，其他名称返回 NXDOMAIN
+func batchHandler(w dns.ResponseWriter, r *dns.Msg) {
+	question := r.Question[0]
+	m := new(dns.Msg)
+	m.SetReply(r)
+
+	var n int
+	if _, err := fmt.Sscanf(question.Name, "host%d.example.com.", &n); err != nil {
+		m.Rcode = dns.RcodeNameError
+		w.WriteMsg(m)
+		return
+	}
+	switch question.Qtype {
+	case dns.TypeA:
+		rr, _ := dns.NewRR(fmt.Sprintf("%s 60 IN A 10.0.%d.%d", question.Name, n/256, n%256))
+		m.Answer = append(m.Answer, rr)
+	case dns.TypeAAAA:
+		rr, _ := dns.NewRR(question.Name + " 60 IN AAAA 2001:db8::1")
+		m.Answer = append(m.Answer, rr)
+	}
+	w.WriteMsg(m)
+}
+
+func TestResolveBatch(t *testing.T) {
+	addr, shutdown := startTestServer(t, batchHandler)
+	defer shutdown()
+
+	hosts := []string{"missing.example.com"}
+	for i := 0; i < 300; i++ {
+		hosts = append(hosts, fmt.Sprintf("host%d.example.com", i))
+	}
+	// 重复的主机只解析一次
+	hosts = append(hosts, "host1.example.com", "host2.example.com")
+
+	c := NewClient(2, 8)
+	c.SetResolvers([]string{addr})
+	results := c.ResolveBatch(hosts)
+
+	if len(results) != 300 {
+		t.Fatalf("Expected 300 resolved hosts, got %d", len(results))
+	}
+	if _, ok := results["missing.example.com"]; ok {
+		t.Error("Expected NXDOMAIN host to be absent from the results")
+	}
+	// 每个主机拿到的是自己的应答
+	for i := 0; i < 300; i++ {
+		host := fmt.Sprintf("host%d.example.com", i)
+		want := fmt.Sprintf("10.0.%d.%d", i/256, i%256)
+		if ips := results[host]; len(ips) != 1 || ips[0] != want {
+			t.Errorf("Expected %s for %s, got %v", want, host, ips)
+		}
+	}
+
+	// 双栈时 A 和 AAAA 在同一连接上连续查询
+	c.SetIPVersion("both")
+	results = c.ResolveBatch([]string{"host7.example.com"})
+	ips := results["host7.example.com"]
+	sort.Strings(ips)
+	if strings.Join(ips, ",") != "10.0.0.7,2001:db8::1" {
+		t.Errorf("Expected A and AAAA answers, got %v", ips)
+	}
+
+	if results := c.ResolveBatch(nil); len(results) != 0 {
+		t.Errorf("Expected no results for an empty batch, got %v", results)
+	}
+}
+
+func TestResolveBatchFailover(t *testing.T) {
+	silent, closeSilent := startSilentServer(t)
+	defer closeSilent()
+
+	servfail, closeServfail := startTestServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeServerFailure)
+		w.WriteMsg(m)
+	})
+	defer closeServfail()
+
+	good, closeGood := startTestServer(t, batchHandler)
+	defer closeGood()
+
+	hosts := make([]string, 20)
+	for i := range hosts {
+		hosts[i] = fmt.Sprintf("host%d.example.com", i)
+	}
+
+	// 超时和 SERVFAIL 的服务器都换下一个服务器重试
+	c := NewClient(1, 4)
+	c.timeout = 200 * time.Millisecond
+	c.SetResolvers([]string{silent, servfail, good})
+	results := c.ResolveBatch(hosts)
+	if len(results) != len(hosts) {
+		t.Errorf("Expected all %d hosts resolved through the working server, got %d", len(hosts), len(results))
+	}
+
+	// 所有服务器都不可用时返回空结果
+	c.SetResolvers([]string{silent})
+	if results := c.ResolveBatch(hosts[:2]); len(results) != 0 {
+		t.Errorf("Expected no results when every resolver fails, got %v", results)
+	}
+}
+
+func TestResolveBatchContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 第一个主机的查询到达后取消，之后不再分发新的主机
+	var once sync.Once
+	server, closeServer := startTestServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		once.Do(cancel)
+		batchHandler(w, r)
+	})
+	defer closeServer()
+
+	hosts := make([]string, 50)
+	for i := range hosts {
+		hosts[i] = fmt.Sprintf("host%d.example.com", i)
+	}
+
+	c := NewClient(1, 1)
+	c.SetResolvers([]string{server})
+	c.SetIPVersion("4")
+	results := c.ResolveBatchContext(ctx, hosts)
+
+	if len(results["host0.example.com"]) == 0 {
+		t.Errorf("Expected the in-flight host to be kept, got %v", results)
+	}
+	if len(results) >= len(hosts) {
+		t.Errorf("Expected dispatching to stop after cancellation, got %d of %d hosts", len(results), len(hosts))
+	}
+}
+
+func BenchmarkResolveBatch(b *testing.B) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatalf("listen udp: %v", err)
+	}
+	started := make(chan struct{})
+	server := &dns.Server{PacketConn: pc, Handler: dns.HandlerFunc(batchHandler), NotifyStartedFunc: func() { close(started) }}
+	go server.ActivateAndServe()
+	<-started
+	defer server.Shutdown()
+
+	hosts := make([]string, 10000)
+	for i := range hosts {
+		hosts[i] = fmt.Sprintf("host%d.example.com", i)
+	}
+
+	c := NewClient(2, 100)
+	c.SetResolvers([]string{pc.LocalAddr().String()})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if results := c.ResolveBatch(hosts); len(results) != len(hosts) {
+			b.Fatalf("Expected %d resolved hosts, got %d", len(hosts), len(results))
+		}
+	}
+	b.ReportMetric(float64(len(hosts)*b.N)/b.Elapsed().Seconds(), "hosts/s")
+}
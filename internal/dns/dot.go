@@ -0,0 +1,94 @@
+package dns
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/oneforall-go/internal/config"
+	"github.com/oneforall-go/pkg/logger"
+)
+
+// DoTScheme DNS over TLS 服务器的地址前缀，如 tls://1.1.1.1:853 或 tls://dns.google
+const DoTScheme = "tls://"
+
+// DoTPort DNS over TLS 默认端口
+const DoTPort = "853"
+
+// IsDoT 判断服务器地址是否为 DNS over TLS
+func IsDoT(server string) bool {
+	return strings.HasPrefix(strings.ToLower(server), DoTScheme)
+}
+
+// NewExchangeClient 按服务器地址创建查询客户端，返回客户端和实际连接的 host:port
+// tls:// 前缀的服务器使用 DoT，证书按服务器主机名校验，insecure 为 true 时跳过校验（用于内网解析器）
+func NewExchangeClient(server string, timeout time.Duration, insecure bool) (*dns.Client, string) {
+	client := &dns.Client{Timeout: timeout}
+	if !IsDoT(server) {
+		return client, server
+	}
+
+	addr := server[len(DoTScheme):]
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = strings.Trim(addr, "[]")
+		addr = net.JoinHostPort(host, DoTPort)
+	}
+
+	client.Net = "tcp-tls"
+	client.TLSConfig = &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: insecure,
+	}
+	return client, addr
+}
+
+// Exchange 依次向 resolvers 发送查询，跳过出错或返回 SERVFAIL/REFUSED 的服务器，返回第一个可用响应
+func Exchange(msg *dns.Msg, resolvers []string, timeout time.Duration, insecure bool) (*dns.Msg, error) {
+	if len(resolvers) == 0 {
+		return nil, fmt.Errorf("no resolvers configured")
+	}
+
+	var lastErr error
+	for _, resolver := range resolvers {
+		client, addr := NewExchangeClient(resolver, timeout, insecure)
+		resp, _, err := client.Exchange(msg, addr)
+		if err != nil {
+			logger.Debugf("DNS query %s failed with %s: %v", msg.Question[0].Name, resolver, err)
+			lastErr = err
+			continue
+		}
+		if resp.Rcode == dns.RcodeServerFailure || resp.Rcode == dns.RcodeRefused {
+			lastErr = fmt.Errorf("resolver %s returned %s", resolver, dns.RcodeToString[resp.Rcode])
+			continue
+		}
+		return resp, nil
+	}
+
+	return nil, fmt.Errorf("all resolvers failed: %v", lastErr)
+}
+
+// ConfigResolvers 返回配置的 DNS 服务器，未配置时返回默认公共 DNS
+func ConfigResolvers(cfg *config.Config) []string {
+	if cfg == nil || len(cfg.Resolvers) == 0 {
+		return getDefaultResolvers()
+	}
+	return cfg.Resolvers
+}
+
+// ConfigTimeout 返回配置的 DNS 解析超时，未配置时为 10 秒
+func ConfigTimeout(cfg *config.Config) time.Duration {
+	if cfg == nil || cfg.DNSResolveTimeout <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(cfg.DNSResolveTimeout) * time.Second
+}
+
+// ExchangeWithConfig 使用配置中的 DNS 服务器、超时和 DoT 证书校验设置发送查询
+func ExchangeWithConfig(msg *dns.Msg, cfg *config.Config) (*dns.Msg, error) {
+	insecure := cfg != nil && cfg.DoTInsecureSkipVerify
+	return Exchange(msg, ConfigResolvers(cfg), ConfigTimeout(cfg), insecure)
+}
@@ -0,0 +1,64 @@
+package dns
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// ParseClientSubnet 解析 EDNS Client Subnet（如 203.0.113.0/24 或 2001:db8::/56），
+// 单个 IP 按 /24（IPv4）或 /56（IPv6）处理，空字符串返回 nil
+func ParseClientSubnet(value string) (*net.IPNet, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	if !strings.Contains(value, "/") {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid client subnet %q", value)
+		}
+		if ip.To4() != nil {
+			value += "/24"
+		} else {
+			value += "/56"
+		}
+	}
+
+	_, subnet, err := net.ParseCIDR(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid client subnet %q: %v", value, err)
+	}
+	return subnet, nil
+}
+
+// AddClientSubnet 为查询附加 EDNS0 Client Subnet 选项（RFC 7871），subnet 为 nil 时不做修改
+func AddClientSubnet(msg *dns.Msg, subnet *net.IPNet) {
+	if subnet == nil {
+		return
+	}
+
+	opt := msg.IsEdns0()
+	if opt == nil {
+		msg.SetEdns0(dns.DefaultMsgSize, false)
+		opt = msg.IsEdns0()
+	}
+
+	ones, _ := subnet.Mask.Size()
+	ecs := &dns.EDNS0_SUBNET{
+		Code:          dns.EDNS0SUBNET,
+		SourceNetmask: uint8(ones),
+		SourceScope:   0,
+	}
+	if ip4 := subnet.IP.To4(); ip4 != nil {
+		ecs.Family = 1
+		ecs.Address = ip4
+	} else {
+		ecs.Family = 2
+		ecs.Address = subnet.IP
+	}
+	opt.Option = append(opt.Option, ecs)
+}
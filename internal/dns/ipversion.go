@@ -0,0 +1,41 @@
+package dns
+
+import (
+	"github.com/miekg/dns"
+	"github.com/oneforall-go/internal/config"
+)
+
+// RecordTypes 返回 IP 版本对应的地址记录类型，无效的 IP 版本按 4 处理
+func RecordTypes(version string) []uint16 {
+	switch version, _ := config.ParseIPVersion(version); version {
+	case config.IPVersion6:
+		return []uint16{dns.TypeAAAA}
+	case config.IPVersionBoth:
+		return []uint16{dns.TypeA, dns.TypeAAAA}
+	default:
+		return []uint16{dns.TypeA}
+	}
+}
+
+// LookupNetwork 返回 IP 版本对应的 net.Resolver.LookupIP 网络类型（ip4、ip6、ip）
+func LookupNetwork(version string) string {
+	switch version, _ := config.ParseIPVersion(version); version {
+	case config.IPVersion6:
+		return "ip6"
+	case config.IPVersionBoth:
+		return "ip"
+	default:
+		return "ip4"
+	}
+}
+
+// AddressFromRR 提取 A/AAAA 记录中的 IP 地址，其他记录类型返回空字符串
+func AddressFromRR(rr dns.RR) string {
+	switch record := rr.(type) {
+	case *dns.A:
+		return record.A.String()
+	case *dns.AAAA:
+		return record.AAAA.String()
+	}
+	return ""
+}
@@ -0,0 +1,110 @@
+package dns
+
+import (
+	"strings"
+)
+
+// IsSPFRecord 判断 TXT 记录是否为 SPF 记录
+func IsSPFRecord(record string) bool {
+	record = strings.ToLower(strings.TrimSpace(record))
+	return record == "v=spf1" || strings.HasPrefix(record, "v=spf1 ")
+}
+
+// IsDMARCRecord 判断 TXT 记录是否为 DMARC 记录
+func IsDMARCRecord(record string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(record)), "v=dmarc1")
+}
+
+// ParseSPF 解析 SPF 记录，返回引用的全部主机名（include/a/mx/ptr/exists/redirect/exp）
+// 以及需要继续查询的 SPF 域名（include 与 redirect）
+// 含宏（%{...}）的域名无法静态展开，直接忽略
+func ParseSPF(record string) (hosts []string, follow []string) {
+	for _, term := range strings.Fields(record) {
+		term = strings.ToLower(term)
+		if term == "v=spf1" {
+			continue
+		}
+
+		// 修饰符：redirect=domain、exp=domain
+		if name, value, ok := strings.Cut(term, "="); ok {
+			host := spfDomain(value)
+			if host == "" {
+				continue
+			}
+			switch name {
+			case "redirect":
+				hosts = append(hosts, host)
+				follow = append(follow, host)
+			case "exp":
+				hosts = append(hosts, host)
+			}
+			continue
+		}
+
+		// 机制：[限定符]name[:domain][/cidr]
+		term = strings.TrimLeft(term, "+-~?")
+		name, value, ok := strings.Cut(term, ":")
+		if !ok {
+			continue
+		}
+		host := spfDomain(value)
+		if host == "" {
+			continue
+		}
+		switch name {
+		case "include":
+			hosts = append(hosts, host)
+			follow = append(follow, host)
+		case "a", "mx", "ptr", "exists":
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts, follow
+}
+
+// spfDomain 提取 SPF 机制中的域名，去除 CIDR 后缀，含宏或不含点时返回空
+func spfDomain(value string) string {
+	if strings.Contains(value, "%") {
+		return ""
+	}
+	if idx := strings.Index(value, "/"); idx != -1 {
+		value = value[:idx]
+	}
+	value = strings.TrimSuffix(value, ".")
+	if !strings.Contains(value, ".") {
+		return ""
+	}
+	return value
+}
+
+// ParseDMARC 解析 DMARC 记录中 rua/ruf 报告地址的域名
+// 格式：v=DMARC1; p=none; rua=mailto:dmarc@example.com,mailto:a@b.com!10m
+func ParseDMARC(record string) []string {
+	var domains []string
+	for _, tag := range strings.Split(record, ";") {
+		name, value, ok := strings.Cut(strings.TrimSpace(tag), "=")
+		if !ok {
+			continue
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "rua" && name != "ruf" {
+			continue
+		}
+
+		for _, uri := range strings.Split(value, ",") {
+			uri = strings.TrimSpace(uri)
+			if len(uri) < len("mailto:") || !strings.EqualFold(uri[:len("mailto:")], "mailto:") {
+				continue
+			}
+			address := uri[len("mailto:"):]
+			// 去除大小限制后缀，如 !10m
+			if idx := strings.Index(address, "!"); idx != -1 {
+				address = address[:idx]
+			}
+			if at := strings.LastIndex(address, "@"); at != -1 && at < len(address)-1 {
+				domains = append(domains, strings.ToLower(strings.TrimSuffix(address[at+1:], ".")))
+			}
+		}
+	}
+	return domains
+}
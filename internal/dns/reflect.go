@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/miekg/dns"
+	"github.com/oneforall-go/internal/config"
 	"github.com/oneforall-go/pkg/logger"
 	"golang.org/x/sync/semaphore"
 )
@@ -17,6 +19,8 @@ type ReflectClient struct {
 	concurrency int64
 	semaphore   *semaphore.Weighted
 	resolvers   []string
+	insecureTLS bool           // DoT 服务器不校验证书
+	config      *config.Config // 数据文件按其中的数据目录加载，为 nil 时使用默认数据目录
 }
 
 // NewReflectClient 创建新的 DNS 反射查询客户端
@@ -90,12 +94,11 @@ func (r *ReflectClient) QueryReflect(domain string) ([]string, error) {
 
 // queryNSRecords 查询 NS 记录
 func (r *ReflectClient) queryNSRecords(domain string) ([]string, error) {
-	client := &dns.Client{Timeout: r.timeout}
 	msg := new(dns.Msg)
 	msg.SetQuestion(dns.Fqdn(domain), dns.TypeNS)
 	msg.RecursionDesired = true
 
-	resp, _, err := client.Exchange(msg, r.resolvers[0])
+	resp, err := r.exchange(msg)
 	if err != nil {
 		return nil, err
 	}
@@ -113,12 +116,11 @@ func (r *ReflectClient) queryNSRecords(domain string) ([]string, error) {
 
 // queryMXRecords 查询 MX 记录
 func (r *ReflectClient) queryMXRecords(domain string) ([]string, error) {
-	client := &dns.Client{Timeout: r.timeout}
 	msg := new(dns.Msg)
 	msg.SetQuestion(dns.Fqdn(domain), dns.TypeMX)
 	msg.RecursionDesired = true
 
-	resp, _, err := client.Exchange(msg, r.resolvers[0])
+	resp, err := r.exchange(msg)
 	if err != nil {
 		return nil, err
 	}
@@ -136,12 +138,11 @@ func (r *ReflectClient) queryMXRecords(domain string) ([]string, error) {
 
 // queryTXTRecords 查询 TXT 记录
 func (r *ReflectClient) queryTXTRecords(domain string) ([]string, error) {
-	client := &dns.Client{Timeout: r.timeout}
 	msg := new(dns.Msg)
 	msg.SetQuestion(dns.Fqdn(domain), dns.TypeTXT)
 	msg.RecursionDesired = true
 
-	resp, _, err := client.Exchange(msg, r.resolvers[0])
+	resp, err := r.exchange(msg)
 	if err != nil {
 		return nil, err
 	}
@@ -151,6 +152,13 @@ func (r *ReflectClient) queryTXTRecords(domain string) ([]string, error) {
 		if txt, ok := answer.(*dns.TXT); ok {
 			// 从 TXT 记录中提取可能的子域名
 			for _, txtStr := range txt.Txt {
+				// SPF 记录解析 include/a/mx 等机制引用的主机名
+				if IsSPFRecord(txtStr) {
+					hosts, _ := ParseSPF(txtStr)
+					subdomains = append(subdomains, hosts...)
+					continue
+				}
+
 				// 简单的子域名提取逻辑
 				if strings.Contains(txtStr, ".") && !strings.Contains(txtStr, " ") {
 					subdomains = append(subdomains, txtStr)
@@ -164,12 +172,11 @@ func (r *ReflectClient) queryTXTRecords(domain string) ([]string, error) {
 
 // querySOARecords 查询 SOA 记录
 func (r *ReflectClient) querySOARecords(domain string) ([]string, error) {
-	client := &dns.Client{Timeout: r.timeout}
 	msg := new(dns.Msg)
 	msg.SetQuestion(dns.Fqdn(domain), dns.TypeSOA)
 	msg.RecursionDesired = true
 
-	resp, _, err := client.Exchange(msg, r.resolvers[0])
+	resp, err := r.exchange(msg)
 	if err != nil {
 		return nil, err
 	}
@@ -188,37 +195,117 @@ func (r *ReflectClient) querySOARecords(domain string) ([]string, error) {
 	return subdomains, nil
 }
 
-// querySRVRecords 查询 SRV 记录
-func (r *ReflectClient) querySRVRecords(domain string) ([]string, error) {
-	// 常见的 SRV 记录前缀
-	srvPrefixes := []string{"_ldap", "_kerberos", "_kpasswd", "_dns", "_ntp", "_sip", "_xmpp", "_imap", "_pop3", "_smtp"}
+// srvPrefixFile 数据目录下的 SRV 服务前缀列表文件，每行一个前缀
+const srvPrefixFile = "srv_prefixes.txt"
 
-	var allSubdomains []string
-	for _, prefix := range srvPrefixes {
-		srvDomain := prefix + "._tcp." + domain
-		client := &dns.Client{Timeout: r.timeout}
-		msg := new(dns.Msg)
-		msg.SetQuestion(dns.Fqdn(srvDomain), dns.TypeSRV)
-		msg.RecursionDesired = true
-
-		resp, _, err := client.Exchange(msg, r.resolvers[0])
-		if err != nil {
+// defaultSRVPrefixes 前缀文件不可用时使用的内置 SRV 服务前缀
+var defaultSRVPrefixes = []string{
+	"_ldap", "_kerberos", "_kpasswd", "_dns", "_ntp", "_sip", "_sips", "_xmpp", "_imap", "_pop3", "_smtp",
+	"_autodiscover", "_caldav", "_carddav", "_minecraft", "_matrix",
+}
+
+// srvProtocols SRV 查询的协议标签
+var srvProtocols = []string{"_tcp", "_udp"}
+
+// loadSRVPrefixes 从数据目录的前缀文件加载 SRV 服务前缀，读取失败或为空时使用内置列表
+func loadSRVPrefixes(cfg *config.Config) []string {
+	data, err := cfg.ReadDataFile(srvPrefixFile)
+	if err != nil {
+		logger.Debugf("Failed to load SRV prefixes from %s, using built-in list: %v", srvPrefixFile, err)
+		return defaultSRVPrefixes
+	}
+
+	var prefixes []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
+		if !strings.HasPrefix(line, "_") {
+			line = "_" + line
+		}
+		prefixes = append(prefixes, strings.ToLower(line))
+	}
 
-		for _, answer := range resp.Answer {
-			if srv, ok := answer.(*dns.SRV); ok {
-				target := strings.TrimSuffix(srv.Target, ".")
-				if target != domain {
-					allSubdomains = append(allSubdomains, target)
+	if len(prefixes) == 0 {
+		return defaultSRVPrefixes
+	}
+	return prefixes
+}
+
+// querySRVRecords 按前缀列表查询 _tcp 和 _udp 的 SRV 记录，返回范围内的目标主机
+func (r *ReflectClient) querySRVRecords(domain string) ([]string, error) {
+	prefixes := loadSRVPrefixes(r.config)
+	suffix := "." + strings.ToLower(domain)
+
+	var allSubdomains []string
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	limit := make(chan struct{}, 10)
+
+	for _, prefix := range prefixes {
+		for _, proto := range srvProtocols {
+			wg.Add(1)
+			go func(srvDomain string) {
+				defer wg.Done()
+				limit <- struct{}{}
+				defer func() { <-limit }()
+
+				msg := new(dns.Msg)
+				msg.SetQuestion(dns.Fqdn(srvDomain), dns.TypeSRV)
+				msg.RecursionDesired = true
+
+				resp, err := r.exchange(msg)
+				if err != nil {
+					return
 				}
-			}
+
+				for _, answer := range resp.Answer {
+					srv, ok := answer.(*dns.SRV)
+					if !ok {
+						continue
+					}
+					// 目标为 "." 表示服务不可用，范围外的目标（如托管服务商）不作为子域名
+					target := strings.ToLower(strings.TrimSuffix(srv.Target, "."))
+					if strings.HasSuffix(target, suffix) {
+						mutex.Lock()
+						allSubdomains = append(allSubdomains, target)
+						mutex.Unlock()
+					}
+				}
+			}(prefix + "." + proto + "." + domain)
 		}
 	}
+	wg.Wait()
 
 	return allSubdomains, nil
 }
 
+// exchange 依次尝试各个 DNS 服务器，返回第一个成功的响应
+func (r *ReflectClient) exchange(msg *dns.Msg) (*dns.Msg, error) {
+	return Exchange(msg, r.resolvers, r.timeout, r.insecureTLS)
+}
+
+// SetResolvers 设置 DNS 服务器
+func (r *ReflectClient) SetResolvers(resolvers []string) {
+	r.resolvers = resolvers
+}
+
+// SetInsecureTLS 设置是否跳过 DoT 服务器的证书校验
+func (r *ReflectClient) SetInsecureTLS(insecure bool) {
+	r.insecureTLS = insecure
+}
+
+// SetConfig 设置配置，SRV 前缀文件从配置的数据目录加载
+func (r *ReflectClient) SetConfig(cfg *config.Config) {
+	r.config = cfg
+}
+
+// GetResolvers 获取 DNS 服务器
+func (r *ReflectClient) GetResolvers() []string {
+	return r.resolvers
+}
+
 // deduplicate 去重
 func (r *ReflectClient) deduplicate(items []string) []string {
 	seen := make(map[string]bool)
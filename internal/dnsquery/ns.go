@@ -7,6 +7,7 @@ import (
 	"github.com/miekg/dns"
 	"github.com/oneforall-go/internal/config"
 	"github.com/oneforall-go/internal/core"
+	dnsutil "github.com/oneforall-go/internal/dns"
 )
 
 // NS NS 查询模块
@@ -43,7 +44,7 @@ func (n *NS) query(domain string) error {
 	msg.RecursionDesired = true
 
 	// 发送查询
-	resp, err := dns.Exchange(msg, "8.8.8.8:53")
+	resp, err := dnsutil.ExchangeWithConfig(msg, n.GetConfig())
 	if err != nil {
 		return fmt.Errorf("failed to query NS records: %v", err)
 	}
@@ -7,6 +7,7 @@ import (
 	"github.com/miekg/dns"
 	"github.com/oneforall-go/internal/config"
 	"github.com/oneforall-go/internal/core"
+	dnsutil "github.com/oneforall-go/internal/dns"
 )
 
 // SOA SOA 查询模块
@@ -43,7 +44,7 @@ func (s *SOA) query(domain string) error {
 	msg.RecursionDesired = true
 
 	// 发送查询
-	resp, err := dns.Exchange(msg, "8.8.8.8:53")
+	resp, err := dnsutil.ExchangeWithConfig(msg, s.GetConfig())
 	if err != nil {
 		return fmt.Errorf("failed to query SOA records: %v", err)
 	}
@@ -2,14 +2,20 @@ package dnsquery
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/miekg/dns"
 	"github.com/oneforall-go/internal/config"
 	"github.com/oneforall-go/internal/core"
+	dnsutil "github.com/oneforall-go/internal/dns"
+	"github.com/oneforall-go/pkg/logger"
 )
 
-// SPF SPF 查询模块
+// maxSPFDepth include/redirect 最大跟随深度（RFC 7208 限制单次校验最多 10 次 DNS 查询）
+const maxSPFDepth = 10
+
+// SPF SPF/DMARC 查询模块
 type SPF struct {
 	*core.Query
 }
@@ -37,44 +43,118 @@ func (s *SPF) Run(domain string) ([]string, error) {
 
 // query 执行查询
 func (s *SPF) query(domain string) error {
-	// 创建 DNS 查询消息
-	msg := new(dns.Msg)
-	msg.SetQuestion(dns.Fqdn(domain), dns.TypeTXT)
-	msg.RecursionDesired = true
+	related := make(map[string]bool)
 
-	// 发送查询
-	resp, err := dns.Exchange(msg, "8.8.8.8:53")
+	// 查询 SPF 记录并跟随 include/redirect
+	hosts, err := s.resolveSPF(domain)
 	if err != nil {
 		return fmt.Errorf("failed to query SPF records: %v", err)
 	}
 
-	// 处理响应
-	for _, answer := range resp.Answer {
-		if txt, ok := answer.(*dns.TXT); ok {
-			for _, record := range txt.Txt {
-				// 检查是否是 SPF 记录
-				if strings.HasPrefix(record, "v=spf1") {
-					// 从 SPF 记录中提取子域名
-					s.extractSubdomainsFromSPF(record, domain)
-				}
-			}
+	// 查询 DMARC 记录中的报告地址域名
+	dmarcDomains, err := s.queryDMARC(domain)
+	if err != nil {
+		logger.Debugf("Failed to query DMARC record for %s: %v", domain, err)
+	}
+	hosts = append(hosts, dmarcDomains...)
+
+	// 范围内的作为子域名，范围外的作为关联资产单独记录
+	for _, host := range hosts {
+		if s.IsValidSubdomain(host, domain) {
+			s.AddSubdomain(host)
+		} else if !core.InScope(host, domain) {
+			related[host] = true
+			s.AddRelatedDomain(host)
 		}
 	}
 
+	if len(related) > 0 {
+		names := make([]string, 0, len(related))
+		for name := range related {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		logger.Infof("Related assets referenced by SPF/DMARC of %s: %v", domain, names)
+	}
+
 	return nil
 }
 
-// extractSubdomainsFromSPF 从 SPF 记录中提取子域名
-func (s *SPF) extractSubdomainsFromSPF(spfRecord, domain string) {
-	// SPF 记录格式：v=spf1 include:_spf.google.com ~all
-	// 查找 include: 后面的域名
-	parts := strings.Fields(spfRecord)
-	for _, part := range parts {
-		if strings.HasPrefix(part, "include:") {
-			host := strings.TrimPrefix(part, "include:")
-			if s.IsValidSubdomain(host, domain) {
-				s.AddSubdomain(host)
+// resolveSPF 查询域名的 SPF 记录，按广度优先跟随 include/redirect，返回所有引用的主机名
+func (s *SPF) resolveSPF(domain string) ([]string, error) {
+	var hosts []string
+	visited := map[string]bool{domain: true}
+	queue := []string{domain}
+
+	for depth := 0; len(queue) > 0 && depth <= maxSPFDepth; depth++ {
+		var next []string
+		for _, name := range queue {
+			records, err := s.queryTXT(name)
+			if err != nil {
+				// 只有根域名查询失败时返回错误，被引用的域名失败时跳过
+				if name == domain {
+					return nil, err
+				}
+				logger.Debugf("Failed to query SPF record of %s: %v", name, err)
+				continue
+			}
+
+			for _, record := range records {
+				if !dnsutil.IsSPFRecord(record) {
+					continue
+				}
+				found, follow := dnsutil.ParseSPF(record)
+				hosts = append(hosts, found...)
+				for _, target := range follow {
+					if !visited[target] {
+						visited[target] = true
+						next = append(next, target)
+					}
+				}
 			}
 		}
+		queue = next
+	}
+
+	if len(queue) > 0 {
+		logger.Debugf("SPF include chain of %s exceeds depth %d, %d domains not followed", domain, maxSPFDepth, len(queue))
+	}
+	return hosts, nil
+}
+
+// queryDMARC 查询 _dmarc 记录，返回 rua/ruf 报告地址的域名
+func (s *SPF) queryDMARC(domain string) ([]string, error) {
+	records, err := s.queryTXT("_dmarc." + domain)
+	if err != nil {
+		return nil, err
+	}
+
+	var domains []string
+	for _, record := range records {
+		if dnsutil.IsDMARCRecord(record) {
+			domains = append(domains, dnsutil.ParseDMARC(record)...)
+		}
+	}
+	return domains, nil
+}
+
+// queryTXT 查询 TXT 记录，多段字符串拼接为一条记录
+func (s *SPF) queryTXT(name string) ([]string, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), dns.TypeTXT)
+	msg.RecursionDesired = true
+
+	resp, err := dnsutil.ExchangeWithConfig(msg, s.GetConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	var records []string
+	for _, answer := range resp.Answer {
+		if txt, ok := answer.(*dns.TXT); ok {
+			// 长 SPF 记录会被拆分为多个字符串，需拼接后解析
+			records = append(records, strings.Join(txt.Txt, ""))
+		}
 	}
+	return records, nil
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/miekg/dns"
 	"github.com/oneforall-go/internal/config"
 	"github.com/oneforall-go/internal/core"
+	dnsutil "github.com/oneforall-go/internal/dns"
 )
 
 // TXT TXT 查询模块
@@ -43,7 +44,7 @@ func (t *TXT) query(domain string) error {
 	msg.RecursionDesired = true
 
 	// 发送查询
-	resp, err := dns.Exchange(msg, "8.8.8.8:53")
+	resp, err := dnsutil.ExchangeWithConfig(msg, t.GetConfig())
 	if err != nil {
 		return fmt.Errorf("failed to query TXT records: %v", err)
 	}
@@ -2,9 +2,8 @@ package enrich
 
 import (
 	"encoding/json"
-	//"fmt"
+	"fmt"
 	"net"
-	"os"
 	"strings"
 	"sync"
 	"time"
@@ -12,6 +11,7 @@ import (
 	"github.com/miekg/dns"
 	"github.com/oneforall-go/internal/config"
 	"github.com/oneforall-go/internal/core"
+	dnsutil "github.com/oneforall-go/internal/dns"
 	"github.com/oneforall-go/pkg/logger"
 )
 
@@ -19,6 +19,7 @@ import (
 type EnrichResult struct {
 	IP           string   `json:"ip"`
 	IsCDN        bool     `json:"is_cdn"`
+	CDNSource    string   `json:"cdn_source"` // 识别CDN的依据：ip 或 cname
 	ReverseNames []string `json:"reverse_names"`
 	Provider     string   `json:"provider"`
 }
@@ -27,9 +28,14 @@ type EnrichResult struct {
 type Enrich struct {
 	*core.BaseModule
 	cdnIPs      map[string]bool
+	cdnCNAMEs   []string
 	nameservers []string
 	concurrent  int
 	timeout     time.Duration
+	seed        []string // 前面步骤已收集的子域名，由调度器通过 SetSeed 传入
+
+	// 反向 IP 查询接口，查询同 IP 上的其他主机名
+	reverseIPURL string
 }
 
 // NewEnrich 创建反查模块
@@ -39,35 +45,58 @@ func NewEnrich(cfg *config.Config) *Enrich {
 		cdnIPs:     make(map[string]bool),
 		concurrent: cfg.MultiThreading.EnrichConcurrency,
 		timeout:    time.Duration(cfg.MultiThreading.EnrichTimeout) * time.Second,
+
+		reverseIPURL: defaultReverseIPURL,
 	}
 
 	// 加载CDN IP列表
 	enrich.loadCDNIPs()
 
+	// 加载CDN CNAME列表
+	enrich.loadCDNCNAMEs()
+
 	// 加载DNS服务器列表
 	enrich.loadNameservers()
 
 	return enrich
 }
 
+// SetSeed 设置需要一并反查 IP 的已知子域名（实现 core.SeedConsumer）
+func (e *Enrich) SetSeed(subdomains []string) {
+	e.seed = subdomains
+}
+
+// Reset 清空上一个域名的种子（实现 core.Resetter），CDN 和 DNS 服务器列表保持不变
+func (e *Enrich) Reset() {
+	e.BaseModule.Reset()
+	e.seed = nil
+}
+
 // Run 运行反查模块
 func (e *Enrich) Run(domain string) ([]string, error) {
 	logger.Infof("Starting domain enrichment for: %s", domain)
 
 	// 获取域名的IP列表
 	ips, err := e.getDomainIPs(domain)
-	if err != nil {
+	if err != nil && len(e.seed) == 0 {
 		logger.Errorf("Failed to get IPs for domain %s: %v", domain, err)
 		return []string{}, err
 	}
 
-	if len(ips) == 0 {
+	// 前面步骤发现的子域名的 IP 也一并反查
+	seedIPs := e.seedIPs(domain, ips)
+
+	if len(ips) == 0 && len(seedIPs) == 0 {
 		logger.Warnf("No IPs found for domain: %s", domain)
 		return []string{}, nil
 	}
 
+	// 通过CNAME判断域名是否接入CDN
+	cnameCDN := e.isCDNByCNAME(domain)
+
 	// 并发处理IP反查
-	results := e.enrichIPs(ips)
+	results := e.enrichIPs(ips, cnameCDN)
+	results = append(results, e.enrichIPs(seedIPs, false)...)
 
 	// 转换为子域名列表
 	var subdomains []string
@@ -80,6 +109,15 @@ func (e *Enrich) Run(domain string) ([]string, error) {
 	logger.Infof("Enrichment completed for %s, found %d non-CDN IPs with %d reverse names",
 		domain, len(results), len(subdomains))
 
+	// 同一 IP 上的其他子域名（反向 IP 数据集）
+	known := append(append([]string(nil), subdomains...), e.seed...)
+	related := e.relatedHosts(domain, results, known)
+	subdomains = append(subdomains, related...)
+
+	// 同一 /24 网段中其他 IP 的 PTR 记录
+	known = append(known, related...)
+	subdomains = append(subdomains, e.sweepNetworks(domain, results, known)...)
+
 	return subdomains, nil
 }
 
@@ -92,7 +130,7 @@ func (e *Enrich) loadCDNIPs() {
 		}
 	}()
 
-	data, err := os.ReadFile("data/cdn_ip_cidr.json")
+	data, err := e.GetConfig().ReadDataFile("cdn_ip_cidr.json")
 	if err != nil {
 		logger.Errorf("Failed to load CDN IP file: %v", err)
 		return
@@ -119,6 +157,30 @@ func (e *Enrich) loadCDNIPs() {
 	logger.Infof("Loaded %d CDN IP ranges", len(e.cdnIPs))
 }
 
+// loadCDNCNAMEs 加载CDN CNAME列表
+func (e *Enrich) loadCDNCNAMEs() {
+	data, err := e.GetConfig().ReadDataFile("cdn_cname.json")
+	if err != nil {
+		logger.Errorf("Failed to load CDN CNAME file: %v", err)
+		return
+	}
+
+	var cnames []string
+	if err := json.Unmarshal(data, &cnames); err != nil {
+		logger.Errorf("Failed to parse CDN CNAME file: %v", err)
+		return
+	}
+
+	for _, cname := range cnames {
+		cname = strings.ToLower(strings.Trim(strings.TrimSpace(cname), "."))
+		if cname != "" {
+			e.cdnCNAMEs = append(e.cdnCNAMEs, cname)
+		}
+	}
+
+	logger.Infof("Loaded %d CDN CNAME patterns", len(e.cdnCNAMEs))
+}
+
 // loadNameservers 加载DNS服务器列表
 func (e *Enrich) loadNameservers() {
 	// 添加异常处理
@@ -128,8 +190,8 @@ func (e *Enrich) loadNameservers() {
 		}
 	}()
 
-	// 读取nameservers.txt
-	data, err := os.ReadFile("data/nameservers.txt")
+	// 读取数据目录下的nameservers.txt
+	data, err := e.GetConfig().ReadDataFile("nameservers.txt")
 	if err != nil {
 		logger.Errorf("Failed to load nameservers.txt: %v", err)
 		return
@@ -144,7 +206,7 @@ func (e *Enrich) loadNameservers() {
 	}
 
 	// 读取nameservers_cn.txt
-	data, err = os.ReadFile("data/nameservers_cn.txt")
+	data, err = e.GetConfig().ReadDataFile("nameservers_cn.txt")
 	if err == nil {
 		lines = strings.Split(string(data), "\n")
 		for _, line := range lines {
@@ -167,9 +229,30 @@ func (e *Enrich) getDomainIPs(domain string) ([]string, error) {
 		}
 	}()
 
-	ips, err := net.LookupHost(domain)
-	if err != nil {
-		return nil, err
+	// 使用配置的 DNS 服务器按 IP 版本查询地址记录
+	var ips []string
+	var lastErr error
+	for _, qtype := range dnsutil.RecordTypes(e.GetConfig().IPVersion) {
+		msg := new(dns.Msg)
+		msg.SetQuestion(dns.Fqdn(domain), qtype)
+		msg.RecursionDesired = true
+
+		resp, err := dnsutil.ExchangeWithConfig(msg, e.GetConfig())
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		for _, answer := range resp.Answer {
+			if ip := dnsutil.AddressFromRR(answer); ip != "" {
+				ips = append(ips, ip)
+			}
+		}
+	}
+	if len(ips) == 0 {
+		if lastErr != nil {
+			return nil, lastErr
+		}
+		return nil, fmt.Errorf("no address records for %s", domain)
 	}
 
 	// 过滤私有IP
@@ -183,14 +266,73 @@ func (e *Enrich) getDomainIPs(domain string) ([]string, error) {
 	return publicIPs, nil
 }
 
+// seedIPs 并发解析种子子域名，返回不在 known 中的公网 IP；CNAME 指向 CDN 的子域名跳过
+func (e *Enrich) seedIPs(domain string, known []string) []string {
+	seen := make(map[string]bool)
+	for _, ip := range known {
+		seen[ip] = true
+	}
+
+	var ips []string
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	semaphore := make(chan struct{}, e.concurrency())
+
+	for _, host := range e.seed {
+		if host == domain || !core.InScope(host, domain) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			if e.isCDNByCNAME(host) {
+				logger.Debugf("Skipping seed %s behind CDN", host)
+				return
+			}
+			hostIPs, err := e.getDomainIPs(host)
+			if err != nil {
+				logger.Debugf("Failed to resolve seed %s: %v", host, err)
+				return
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			for _, ip := range hostIPs {
+				if !seen[ip] {
+					seen[ip] = true
+					ips = append(ips, ip)
+				}
+			}
+		}(host)
+	}
+
+	wg.Wait()
+	if len(ips) > 0 {
+		logger.Infof("Resolved %d additional IPs from %d seed subdomains of %s", len(ips), len(e.seed), domain)
+	}
+	return ips
+}
+
+// concurrency 返回反查并发数，未配置时为 1
+func (e *Enrich) concurrency() int {
+	if e.concurrent <= 0 {
+		return 1
+	}
+	return e.concurrent
+}
+
 // enrichIPs 并发处理IP反查
-func (e *Enrich) enrichIPs(ips []string) []EnrichResult {
+func (e *Enrich) enrichIPs(ips []string, cnameCDN bool) []EnrichResult {
 	var results []EnrichResult
 	var wg sync.WaitGroup
 	var mutex sync.Mutex
 
 	// 创建信号量控制并发数
-	semaphore := make(chan struct{}, e.concurrent)
+	semaphore := make(chan struct{}, e.concurrency())
 
 	for _, ip := range ips {
 		wg.Add(1)
@@ -208,7 +350,7 @@ func (e *Enrich) enrichIPs(ips []string) []EnrichResult {
 			semaphore <- struct{}{}
 			defer func() { <-semaphore }()
 
-			result := e.enrichSingleIP(ip)
+			result := e.enrichSingleIP(ip, cnameCDN)
 
 			mutex.Lock()
 			results = append(results, result)
@@ -221,7 +363,7 @@ func (e *Enrich) enrichIPs(ips []string) []EnrichResult {
 }
 
 // enrichSingleIP 处理单个IP的反查
-func (e *Enrich) enrichSingleIP(ip string) EnrichResult {
+func (e *Enrich) enrichSingleIP(ip string, cnameCDN bool) EnrichResult {
 	// 添加异常处理
 	defer func() {
 		if r := recover(); r != nil {
@@ -239,10 +381,19 @@ func (e *Enrich) enrichSingleIP(ip string) EnrichResult {
 	// 检查是否为CDN IP
 	if e.isCDNIP(ip) {
 		result.IsCDN = true
+		result.CDNSource = "ip"
 		logger.Debugf("IP %s is identified as CDN", ip)
 		return result
 	}
 
+	// 域名CNAME指向CDN时，其IP同样视为CDN节点
+	if cnameCDN {
+		result.IsCDN = true
+		result.CDNSource = "cname"
+		logger.Debugf("IP %s is identified as CDN by CNAME", ip)
+		return result
+	}
+
 	// 执行反向DNS查询
 	reverseNames := e.reverseDNSLookup(ip)
 	result.ReverseNames = reverseNames
@@ -286,6 +437,36 @@ func (e *Enrich) isCDNIP(ip string) bool {
 	return false
 }
 
+// isCDNByCNAME 检查主机的CNAME链中是否有指向CDN的名称，链通过配置的 DNS 服务器逐层解析
+func (e *Enrich) isCDNByCNAME(host string) bool {
+	if len(e.cdnCNAMEs) == 0 {
+		return false
+	}
+
+	if e.matchCDNCNAME(host) {
+		return true
+	}
+
+	cfg := e.GetConfig()
+	for _, cname := range dnsutil.ResolveCNAMEChain(host, dnsutil.ConfigResolvers(cfg), dnsutil.ConfigTimeout(cfg), cfg.DoTInsecureSkipVerify) {
+		if e.matchCDNCNAME(cname) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchCDNCNAME 检查名称是否匹配CDN CNAME列表
+func (e *Enrich) matchCDNCNAME(name string) bool {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	for _, pattern := range e.cdnCNAMEs {
+		if name == pattern || strings.HasSuffix(name, "."+pattern) {
+			return true
+		}
+	}
+	return false
+}
+
 // reverseDNSLookup 反向DNS查询
 func (e *Enrich) reverseDNSLookup(ip string) []string {
 	// 添加异常处理
@@ -297,39 +478,39 @@ func (e *Enrich) reverseDNSLookup(ip string) []string {
 
 	var reverseNames []string
 
-	// 遍历多个DNS服务器
-	for _, nameserver := range e.nameservers {
-		names, err := e.queryReverseDNS(ip, nameserver)
-		if err != nil {
-			continue
-		}
+	// 构建反向查询域名（IPv4 或 IPv6）
+	reverseName := e.reverseIP(ip)
+	if reverseName == "" {
+		logger.Debugf("Skipping reverse lookup for invalid IP: %s", ip)
+		return reverseNames
+	}
 
-		reverseNames = append(reverseNames, names...)
+	// 使用第一个可用的 DNS 服务器，出错或 SERVFAIL/REFUSED 时换下一个
+	names, err := e.queryReverseDNS(reverseName, e.ptrServers())
+	if err != nil {
+		logger.Debugf("Reverse lookup for %s failed: %v", ip, err)
+		return reverseNames
 	}
+	reverseNames = append(reverseNames, names...)
 
 	// 去重
 	return e.deduplicateStrings(reverseNames)
 }
 
-// queryReverseDNS 查询反向DNS
-func (e *Enrich) queryReverseDNS(ip string, nameserver string) ([]string, error) {
+// queryReverseDNS 依次向 servers（host:port 或 tls:// 地址）查询反向DNS，返回第一个可用响应中的主机名
+func (e *Enrich) queryReverseDNS(reverseName string, servers []string) ([]string, error) {
 	// 添加异常处理
 	defer func() {
 		if r := recover(); r != nil {
-			logger.Errorf("Panic in queryReverseDNS for %s: %v", ip, r)
+			logger.Errorf("Panic in queryReverseDNS for %s: %v", reverseName, r)
 		}
 	}()
 
-	client := new(dns.Client)
-	client.Timeout = e.timeout
-
-	// 构建反向查询域名
-	reverseIP := e.reverseIP(ip)
 	msg := new(dns.Msg)
-	msg.SetQuestion(reverseIP, dns.TypePTR)
+	msg.SetQuestion(reverseName, dns.TypePTR)
 	msg.RecursionDesired = true
 
-	resp, _, err := client.Exchange(msg, nameserver+":53")
+	resp, err := dnsutil.Exchange(msg, servers, e.timeout, e.GetConfig().DoTInsecureSkipVerify)
 	if err != nil {
 		return nil, err
 	}
@@ -345,19 +526,26 @@ func (e *Enrich) queryReverseDNS(ip string, nameserver string) ([]string, error)
 	return names, nil
 }
 
-// reverseIP 反转IP地址用于PTR查询
+// reverseIP 反转IP地址用于PTR查询，IPv4 使用 in-addr.arpa，IPv6 使用 ip6.arpa
 func (e *Enrich) reverseIP(ip string) string {
-	parts := strings.Split(ip, ".")
-	if len(parts) != 4 {
+	parsedIP := net.ParseIP(ip)
+	if parsedIP == nil {
 		return ""
 	}
 
-	// 反转IP段
-	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
-		parts[i], parts[j] = parts[j], parts[i]
+	if ipv4 := parsedIP.To4(); ipv4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.in-addr.arpa.", ipv4[3], ipv4[2], ipv4[1], ipv4[0])
+	}
+
+	// IPv6 按半字节（nibble）逆序展开
+	ipv6 := parsedIP.To16()
+	var builder strings.Builder
+	for i := len(ipv6) - 1; i >= 0; i-- {
+		builder.WriteString(fmt.Sprintf("%x.%x.", ipv6[i]&0x0f, ipv6[i]>>4))
 	}
+	builder.WriteString("ip6.arpa.")
 
-	return strings.Join(parts, ".") + ".in-addr.arpa."
+	return builder.String()
 }
 
 // isPrivateIP 检查是否为私有IP
@@ -374,6 +562,9 @@ func (e *Enrich) isPrivateIP(ip string) bool {
 		"192.168.0.0/16",
 		"127.0.0.0/8",
 		"169.254.0.0/16",
+		"::1/128",
+		"fc00::/7",
+		"fe80::/10",
 	}
 
 	for _, cidr := range privateRanges {
@@ -0,0 +1,356 @@
+package enrich
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/oneforall-go/internal/config"
+	"github.com/oneforall-go/internal/core"
+)
+
+func TestReverseIP(t *testing.T) {
+	e := &Enrich{}
+
+	tests := map[string]string{
+		"192.0.2.1":   "1.2.0.192.in-addr.arpa.",
+		"2001:db8::1": "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.",
+		"not-an-ip":   "",
+	}
+
+	for ip, expected := range tests {
+		if got := e.reverseIP(ip); got != expected {
+			t.Errorf("reverseIP(%s): expected %s, got %s", ip, expected, got)
+		}
+	}
+}
+
+func TestMatchCDNCNAME(t *testing.T) {
+	e := &Enrich{cdnCNAMEs: []string{"cloudfront.net", "akamaiedge.net"}}
+
+	tests := map[string]bool{
+		"d111111abcdef8.cloudfront.net.": true,
+		"e1234.a.akamaiedge.net":         true,
+		"cloudfront.net":                 true,
+		"notcloudfront.net":              false,
+		"www.example.com":                false,
+	}
+
+	for name, expected := range tests {
+		if got := e.matchCDNCNAME(name); got != expected {
+			t.Errorf("matchCDNCNAME(%s): expected %v, got %v", name, expected, got)
+		}
+	}
+}
+
+func TestExpandTarget(t *testing.T) {
+	dataDir := t.TempDir()
+	cfg := &config.Config{DataDir: dataDir}
+	ips, source, err := ExpandTarget(cfg, "192.0.2.0/30", 100)
+	if err != nil {
+		t.Fatalf("ExpandTarget failed: %v", err)
+	}
+	if source != SourceCIDR || len(ips) != 4 || ips[0] != "192.0.2.0" || ips[3] != "192.0.2.3" {
+		t.Errorf("Unexpected expansion: %s %v", source, ips)
+	}
+
+	ips, _, err = ExpandTarget(cfg, "10.0.0.0/8", 3)
+	if err != nil || len(ips) != 3 || ips[2] != "10.0.0.2" {
+		t.Errorf("Expected expansion capped at 3, got %v (%v)", ips, err)
+	}
+
+	// ASN 前缀表从配置的数据目录加载
+	table := filepath.Join(dataDir, asnTableFile)
+	if err := os.WriteFile(table, []byte(`{"AS64500": ["198.51.100.0/31", "203.0.113.8/32"]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ips, source, err = ExpandTarget(cfg, "as64500", 100)
+	if err != nil {
+		t.Fatalf("ExpandTarget ASN failed: %v", err)
+	}
+	if source != SourceASN || len(ips) != 3 || ips[2] != "203.0.113.8" {
+		t.Errorf("Unexpected ASN expansion: %s %v", source, ips)
+	}
+
+	if _, _, err := ExpandTarget(cfg, "AS1", 100); err == nil {
+		t.Error("Expected error for ASN missing from table")
+	}
+	if IsNetworkTarget("example.com") || !IsNetworkTarget("AS13335") || !IsNetworkTarget("2001:db8::/126") {
+		t.Error("IsNetworkTarget returned unexpected result")
+	}
+}
+
+func TestExpandNetworkTarget(t *testing.T) {
+	ptrs := map[string]string{
+		"1.2.0.192.in-addr.arpa.": "www.example.com.",
+		"2.2.0.192.in-addr.arpa.": "mail.example.com.",
+		"3.2.0.192.in-addr.arpa.": "host.example.org.",
+	}
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen udp: %v", err)
+	}
+	started := make(chan struct{})
+	var queries int32
+	server := &dns.Server{PacketConn: pc, NotifyStartedFunc: func() { close(started) }, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		atomic.AddInt32(&queries, 1)
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		if target, ok := ptrs[req.Question[0].Name]; ok {
+			resp.Answer = append(resp.Answer, &dns.PTR{
+				Hdr: dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypePTR, Class: dns.ClassINET, Ttl: 60},
+				Ptr: target,
+			})
+		}
+		w.WriteMsg(resp)
+	})}
+	go server.ActivateAndServe()
+	<-started
+	defer server.Shutdown()
+
+	cfg := &config.Config{MaxCIDRHosts: 256, Resolvers: []string{pc.LocalAddr().String()}}
+	cfg.MultiThreading.EnrichConcurrency = 4
+	cfg.MultiThreading.EnrichTimeout = 2
+
+	// dry-run 不发送 PTR 查询
+	cfg.DryRun = true
+	if seeds, err := ExpandNetworkTarget(cfg, "192.0.2.0/30"); err != nil || len(seeds) != 0 {
+		t.Fatalf("Expected no seeds in dry-run, got %v (%v)", seeds, err)
+	}
+	if n := atomic.LoadInt32(&queries); n != 0 {
+		t.Fatalf("Expected no PTR queries in dry-run, got %d", n)
+	}
+	cfg.DryRun = false
+
+	seeds, err := ExpandNetworkTarget(cfg, "192.0.2.0/30")
+	if err != nil {
+		t.Fatalf("ExpandNetworkTarget failed: %v", err)
+	}
+	if len(seeds) != 2 || len(seeds["example.com"]) != 2 || len(seeds["example.org"]) != 1 {
+		t.Fatalf("Unexpected seeds: %v", seeds)
+	}
+	for _, seed := range seeds["example.com"] {
+		if seed.Source != SourceCIDR {
+			t.Errorf("Expected source cidr, got %s", seed.Source)
+		}
+	}
+}
+
+func TestRelatedHostsFromReverseIP(t *testing.T) {
+	var queries int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&queries, 1)
+		switch r.URL.Query().Get("q") {
+		case "192.0.2.10":
+			// 两个范围内的新主机、一个已知主机和一个范围外主机
+			w.Write([]byte("shop.example.com\nwww.example.com\nvpn.example.com\nother.example.org\n"))
+		case "192.0.2.11":
+			w.Write([]byte("shop.example.com\n"))
+		default:
+			w.Write([]byte("API count exceeded - Increase Quota with Membership"))
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{ReverseIPLimit: 2}
+	cfg.MultiThreading.EnrichConcurrency = 2
+	e := NewEnrich(cfg)
+	e.reverseIPURL = server.URL
+
+	results := []EnrichResult{
+		{IP: "192.0.2.10"},
+		{IP: "192.0.2.10"},               // 重复 IP 只查询一次
+		{IP: "203.0.113.5", IsCDN: true}, // CDN IP 不查询
+		{IP: "192.0.2.11"},
+		{IP: "192.0.2.12"}, // 超出上限
+	}
+	hosts := e.relatedHosts("example.com", results, []string{"www.example.com"})
+	sort.Strings(hosts)
+
+	if strings.Join(hosts, ",") != "shop.example.com,vpn.example.com" {
+		t.Errorf("Expected [shop.example.com vpn.example.com], got %v", hosts)
+	}
+	if n := atomic.LoadInt32(&queries); n != 2 {
+		t.Errorf("Expected 2 reverse IP queries, got %d", n)
+	}
+	if tags := e.TakeSourceTags(); tags["vpn.example.com"] != ReverseIPSource {
+		t.Errorf("Expected reverse IP source tag, got %v", tags)
+	}
+
+	// 上限为 0 时不查询
+	cfg.ReverseIPLimit = 0
+	if hosts := e.relatedHosts("example.com", results, nil); len(hosts) != 0 {
+		t.Errorf("Expected no lookups when disabled, got %v", hosts)
+	}
+}
+
+func TestSweepNetworks(t *testing.T) {
+	ptrs := map[string]string{
+		"1.2.0.192.in-addr.arpa.":    "www.example.com.", // 已知主机
+		"7.2.0.192.in-addr.arpa.":    "db.example.com.",
+		"200.2.0.192.in-addr.arpa.":  "Intranet.Example.com.",
+		"9.2.0.192.in-addr.arpa.":    "host.example.org.", // 范围外
+		"5.100.51.198.in-addr.arpa.": "mail.example.com.", // 超出网段上限
+	}
+
+	var queries int32
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen udp: %v", err)
+	}
+	started := make(chan struct{})
+	server := &dns.Server{PacketConn: pc, NotifyStartedFunc: func() { close(started) }, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		atomic.AddInt32(&queries, 1)
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		if target, ok := ptrs[req.Question[0].Name]; ok {
+			resp.Answer = append(resp.Answer, &dns.PTR{
+				Hdr: dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypePTR, Class: dns.ClassINET, Ttl: 60},
+				Ptr: target,
+			})
+		}
+		w.WriteMsg(resp)
+	})}
+	go server.ActivateAndServe()
+	<-started
+	defer server.Shutdown()
+
+	cfg := &config.Config{PTRSweepLimit: 1, PTRSweepRate: 10000, Resolvers: []string{pc.LocalAddr().String()}}
+	cfg.MultiThreading.EnrichConcurrency = 16
+	cfg.MultiThreading.EnrichTimeout = 2
+	e := NewEnrich(cfg)
+
+	results := []EnrichResult{
+		{IP: "192.0.2.1"},
+		{IP: "192.0.2.50"},               // 同一网段只扫描一次
+		{IP: "203.0.113.5", IsCDN: true}, // CDN 网段不扫描
+		{IP: "10.0.0.1"},                 // 私有网段不扫描
+		{IP: "2001:db8::1"},              // 只扫描 IPv4
+		{IP: "198.51.100.5"},             // 超出上限
+	}
+	hosts := e.sweepNetworks("example.com", results, []string{"www.example.com"})
+	sort.Strings(hosts)
+
+	if strings.Join(hosts, ",") != "db.example.com,intranet.example.com" {
+		t.Errorf("Expected [db.example.com intranet.example.com], got %v", hosts)
+	}
+	if n := atomic.LoadInt32(&queries); n != 256 {
+		t.Errorf("Expected 256 PTR queries for one /24, got %d", n)
+	}
+	if tags := e.TakeSourceTags(); tags["db.example.com"] != PTRSweepSource {
+		t.Errorf("Expected PTR sweep source tag, got %v", tags)
+	}
+
+	// 未开启时不扫描
+	cfg.PTRSweepLimit = 0
+	if hosts := e.sweepNetworks("example.com", results, nil); len(hosts) != 0 {
+		t.Errorf("Expected no sweep when disabled, got %v", hosts)
+	}
+}
+
+func TestEnrichUsesConfiguredResolvers(t *testing.T) {
+	// 第一个服务器返回 REFUSED，第二个服务器提供 A、CNAME 和 PTR 记录
+	startServer := func(handler dns.HandlerFunc) string {
+		pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("listen udp: %v", err)
+		}
+		started := make(chan struct{})
+		server := &dns.Server{PacketConn: pc, NotifyStartedFunc: func() { close(started) }, Handler: handler}
+		go server.ActivateAndServe()
+		<-started
+		t.Cleanup(func() { server.Shutdown() })
+		return pc.LocalAddr().String()
+	}
+	refused := startServer(func(w dns.ResponseWriter, req *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetRcode(req, dns.RcodeRefused)
+		w.WriteMsg(resp)
+	})
+	good := startServer(func(w dns.ResponseWriter, req *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		q := req.Question[0]
+		var rr string
+		switch {
+		case q.Name == "www.example.com." && q.Qtype == dns.TypeA:
+			rr = "www.example.com. 60 IN A 198.51.100.7"
+		case q.Name == "static.example.com." && q.Qtype == dns.TypeCNAME:
+			rr = "static.example.com. 60 IN CNAME example.cdn-provider.net."
+		case q.Name == "7.100.51.198.in-addr.arpa." && q.Qtype == dns.TypePTR:
+			rr = "7.100.51.198.in-addr.arpa. 60 IN PTR host7.example.com."
+		}
+		if rr != "" {
+			answer, _ := dns.NewRR(rr)
+			resp.Answer = append(resp.Answer, answer)
+		}
+		w.WriteMsg(resp)
+	})
+
+	cfg := &config.Config{IPVersion: "4", DNSResolveTimeout: 2, Resolvers: []string{refused, good}}
+	cfg.MultiThreading.EnrichTimeout = 2
+	e := &Enrich{BaseModule: core.NewBaseModule("enrich", "", cfg), cdnCNAMEs: []string{"cdn-provider.net"}, timeout: 2 * time.Second}
+
+	if ips, err := e.getDomainIPs("www.example.com"); err != nil || len(ips) != 1 || ips[0] != "198.51.100.7" {
+		t.Errorf("Expected the address from the configured resolver, got %v (%v)", ips, err)
+	}
+	if !e.isCDNByCNAME("static.example.com") {
+		t.Error("Expected CDN detection through the configured resolver")
+	}
+	if names := e.reverseDNSLookup("198.51.100.7"); len(names) != 1 || names[0] != "host7.example.com" {
+		t.Errorf("Expected the PTR name from the configured resolver, got %v", names)
+	}
+}
+
+func TestSeedIPsSkipsHostsBehindCDNChain(t *testing.T) {
+	// static.example.com 经过两层 CNAME 指向 CDN，app.example.com 直接解析
+	records := map[string]string{
+		"static.example.com. CNAME": "static.example.com. 60 IN CNAME edge.example.net.",
+		"edge.example.net. CNAME":   "edge.example.net. 60 IN CNAME x.cdn-provider.net.",
+		"static.example.com. A":     "static.example.com. 60 IN A 198.51.100.20",
+		"app.example.com. A":        "app.example.com. 60 IN A 198.51.100.21",
+	}
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen udp: %v", err)
+	}
+	started := make(chan struct{})
+	server := &dns.Server{PacketConn: pc, NotifyStartedFunc: func() { close(started) }, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		q := req.Question[0]
+		if rr, ok := records[q.Name+" "+dns.TypeToString[q.Qtype]]; ok {
+			answer, _ := dns.NewRR(rr)
+			resp.Answer = append(resp.Answer, answer)
+		}
+		w.WriteMsg(resp)
+	})}
+	go server.ActivateAndServe()
+	<-started
+	defer server.Shutdown()
+
+	cfg := &config.Config{IPVersion: "4", DNSResolveTimeout: 2, Resolvers: []string{pc.LocalAddr().String()}}
+	e := &Enrich{BaseModule: core.NewBaseModule("enrich", "", cfg), cdnCNAMEs: []string{"cdn-provider.net"}, concurrent: 2}
+
+	if !e.isCDNByCNAME("static.example.com") {
+		t.Error("Expected a CDN match further down the CNAME chain")
+	}
+	if e.isCDNByCNAME("app.example.com") {
+		t.Error("Expected no CDN match for a host without CNAME")
+	}
+
+	e.SetSeed([]string{"static.example.com", "app.example.com"})
+	if ips := e.seedIPs("example.com", nil); len(ips) != 1 || ips[0] != "198.51.100.21" {
+		t.Errorf("Expected only the IP of the host outside the CDN, got %v", ips)
+	}
+}
@@ -0,0 +1,267 @@
+package enrich
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oneforall-go/internal/config"
+	"github.com/oneforall-go/internal/core"
+	"github.com/oneforall-go/pkg/logger"
+	"golang.org/x/net/publicsuffix"
+)
+
+// 网络目标结果来源
+const (
+	SourceASN  = "asn"
+	SourceCIDR = "cidr"
+)
+
+// largeRangeWarning 超过该 IP 数时提示范围过大
+const largeRangeWarning = 4096
+
+// asnTableFile 数据目录下的离线 ASN 前缀表，格式为 {"AS13335": ["1.1.1.0/24", ...]}
+const asnTableFile = "asn_prefixes.json"
+
+var asnPattern = regexp.MustCompile(`(?i)^AS(\d+)$`)
+
+// IsNetworkTarget 判断目标是否为 ASN（如 AS13335）或 CIDR（如 192.0.2.0/24）
+func IsNetworkTarget(target string) bool {
+	target = strings.TrimSpace(target)
+	if asnPattern.MatchString(target) {
+		return true
+	}
+	_, _, err := net.ParseCIDR(target)
+	return err == nil
+}
+
+// ExpandTarget 将 ASN/CIDR 目标展开为 IP 列表，最多返回 maxHosts 个，同时返回结果来源；ASN 前缀表从 cfg 的数据目录加载
+func ExpandTarget(cfg *config.Config, target string, maxHosts int) ([]string, string, error) {
+	target = strings.TrimSpace(target)
+
+	if m := asnPattern.FindStringSubmatch(target); m != nil {
+		asn := "AS" + m[1]
+		prefixes, err := lookupASNPrefixes(cfg, asn)
+		if err != nil {
+			return nil, SourceASN, err
+		}
+		logger.Infof("%s has %d prefixes in offline table", asn, len(prefixes))
+		ips, err := expandPrefixes(prefixes, maxHosts)
+		return ips, SourceASN, err
+	}
+
+	if _, _, err := net.ParseCIDR(target); err == nil {
+		ips, err := expandPrefixes([]string{target}, maxHosts)
+		return ips, SourceCIDR, err
+	}
+
+	return nil, "", fmt.Errorf("not an ASN or CIDR target: %s", target)
+}
+
+// lookupASNPrefixes 从离线表中查询 ASN 的前缀
+func lookupASNPrefixes(cfg *config.Config, asn string) ([]string, error) {
+	data, err := cfg.ReadDataFile(asnTableFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ASN table: %v", err)
+	}
+
+	var table map[string][]string
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("failed to parse ASN table: %v", err)
+	}
+
+	for key, prefixes := range table {
+		if strings.EqualFold(key, asn) {
+			return prefixes, nil
+		}
+	}
+	return nil, fmt.Errorf("%s not found in ASN table %s", asn, asnTableFile)
+}
+
+// expandPrefixes 展开 CIDR 前缀为 IP 列表，超过 maxHosts 时截断
+func expandPrefixes(prefixes []string, maxHosts int) ([]string, error) {
+	var networks []*net.IPNet
+	var total uint64
+	for _, prefix := range prefixes {
+		_, ipNet, err := net.ParseCIDR(strings.TrimSpace(prefix))
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %s: %v", prefix, err)
+		}
+		networks = append(networks, ipNet)
+
+		ones, bits := ipNet.Mask.Size()
+		if hostBits := bits - ones; hostBits >= 32 {
+			total += 1 << 32
+		} else {
+			total += 1 << uint(hostBits)
+		}
+	}
+
+	if total > largeRangeWarning {
+		logger.Warnf("Target range contains %d addresses, reverse DNS may take a long time", total)
+	}
+	if total > uint64(maxHosts) {
+		logger.Warnf("Target range exceeds max_cidr_hosts, only the first %d addresses will be used", maxHosts)
+	}
+
+	var ips []string
+	for _, ipNet := range networks {
+		for ip := cloneIP(ipNet.IP); ipNet.Contains(ip); incrementIP(ip) {
+			if len(ips) >= maxHosts {
+				return ips, nil
+			}
+			ips = append(ips, ip.String())
+			if isLastIP(ip) {
+				break
+			}
+		}
+	}
+	return ips, nil
+}
+
+// cloneIP 复制 IP
+func cloneIP(ip net.IP) net.IP {
+	clone := make(net.IP, len(ip))
+	copy(clone, ip)
+	return clone
+}
+
+// incrementIP 将 IP 加一
+func incrementIP(ip net.IP) {
+	for i := len(ip) - 1; i >= 0; i-- {
+		ip[i]++
+		if ip[i] != 0 {
+			return
+		}
+	}
+}
+
+// isLastIP 判断是否为地址空间的最后一个 IP，避免加一后回绕
+func isLastIP(ip net.IP) bool {
+	for _, b := range ip {
+		if b != 0xff {
+			return false
+		}
+	}
+	return true
+}
+
+// ReverseLookup 并发对 IP 列表执行 PTR 查询，返回去重后的主机名
+// 每个 IP 使用第一个成功响应的 DNS 服务器，不会逐个查询全部服务器
+func (e *Enrich) ReverseLookup(ips []string) []string {
+	return e.reverseLookup(ips, 0)
+}
+
+// reverseLookup 同 ReverseLookup，rate 大于 0 时每秒最多发起 rate 个 IP 的查询
+func (e *Enrich) reverseLookup(ips []string, rate int) []string {
+	servers := e.ptrServers()
+
+	var throttle <-chan time.Time
+	if rate > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(rate))
+		defer ticker.Stop()
+		throttle = ticker.C
+	}
+
+	var names []string
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+
+	concurrent := e.concurrent
+	if concurrent <= 0 {
+		concurrent = 20
+	}
+	semaphore := make(chan struct{}, concurrent)
+
+	for _, ip := range ips {
+		reverseName := e.reverseIP(ip)
+		if reverseName == "" {
+			continue
+		}
+		if throttle != nil {
+			<-throttle
+		}
+
+		wg.Add(1)
+		go func(reverseName string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			found, err := e.queryReverseDNS(reverseName, servers)
+			if err != nil || len(found) == 0 {
+				return
+			}
+			mutex.Lock()
+			names = append(names, found...)
+			mutex.Unlock()
+		}(reverseName)
+	}
+
+	wg.Wait()
+	return e.deduplicateStrings(names)
+}
+
+// ptrServers PTR 查询使用的 DNS 服务器（host:port），优先使用自定义服务器
+func (e *Enrich) ptrServers() []string {
+	if cfg := e.GetConfig(); cfg != nil && len(cfg.Resolvers) > 0 {
+		return cfg.Resolvers
+	}
+
+	var servers []string
+	for _, nameserver := range e.nameservers {
+		servers = append(servers, net.JoinHostPort(nameserver, "53"))
+	}
+	if len(servers) == 0 {
+		servers = []string{"8.8.8.8:53", "1.1.1.1:53"}
+	}
+	return servers
+}
+
+// ExpandNetworkTarget 展开 ASN/CIDR 目标并反查 PTR，按注册域名分组返回种子结果，种子结果的来源为 asn 或 cidr；
+// 调用方只把 NetworkTargetDomains 中列出的注册域名作为新目标继续枚举，dry-run 时不反查、返回空结果
+func ExpandNetworkTarget(cfg *config.Config, target string) (map[string][]core.SubdomainResult, error) {
+	// PTR 反查直接查询目标网段的权威 DNS，被动模式下不允许
+	if cfg.Passive {
+		return nil, fmt.Errorf("ASN/CIDR target %s requires reverse DNS lookups and is not supported in passive mode", target)
+	}
+	ips, source, err := ExpandTarget(cfg, target, cfg.MaxCIDRHosts)
+	if err != nil {
+		return nil, err
+	}
+	// dry-run 不发送任何请求，只报告将要反查的地址数
+	if cfg.DryRun {
+		logger.Infof("[dry-run] Would run reverse DNS for %d addresses of %s", len(ips), target)
+		return map[string][]core.SubdomainResult{}, nil
+	}
+	logger.Infof("Expanded %s to %d addresses, running reverse DNS", target, len(ips))
+
+	names := NewEnrich(cfg).ReverseLookup(ips)
+
+	seeds := make(map[string][]core.SubdomainResult)
+	now := time.Now().Format("2006-01-02 15:04:05")
+	for _, name := range names {
+		host := core.NormalizeHost(name)
+		domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+		if err != nil {
+			continue
+		}
+		if _, ok := seeds[domain]; !ok {
+			seeds[domain] = nil
+		}
+		if host != domain {
+			seeds[domain] = append(seeds[domain], core.SubdomainResult{
+				Subdomain: host,
+				Source:    source,
+				Time:      now,
+			})
+		}
+	}
+
+	logger.Infof("Reverse DNS for %s found %d hostnames across %d domains", target, len(names), len(seeds))
+	return seeds, nil
+}
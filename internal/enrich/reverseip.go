@@ -0,0 +1,112 @@
+package enrich
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/oneforall-go/internal/core"
+	"github.com/oneforall-go/pkg/logger"
+)
+
+// ReverseIPSource 通过反向 IP 数据集发现的子域名的来源标记
+const ReverseIPSource = "hackertarget_reverseip"
+
+// defaultReverseIPURL HackerTarget 反向 IP 查询接口，每行返回一个解析到该 IP 的主机名
+const defaultReverseIPURL = "https://api.hackertarget.com/reverseiplookup/"
+
+// relatedHosts 对反查结果中的非 CDN IP 查询反向 IP 数据集，返回同 IP 上范围内的新子域名。
+// IP 去重后最多查询 reverse_ip_limit 个，known 中已有的主机名不再返回
+func (e *Enrich) relatedHosts(domain string, results []EnrichResult, known []string) []string {
+	limit := e.GetConfig().ReverseIPLimit
+	if limit <= 0 {
+		return nil
+	}
+
+	seenIPs := make(map[string]bool)
+	var ips []string
+	for _, result := range results {
+		if result.IsCDN || seenIPs[result.IP] || e.isPrivateIP(result.IP) {
+			continue
+		}
+		seenIPs[result.IP] = true
+		ips = append(ips, result.IP)
+	}
+	if len(ips) > limit {
+		logger.Infof("Reverse IP lookup limited to %d of %d non-CDN IPs for %s", limit, len(ips), domain)
+		ips = ips[:limit]
+	}
+
+	seenHosts := make(map[string]bool)
+	for _, host := range known {
+		seenHosts[core.NormalizeHost(host)] = true
+	}
+
+	var hosts []string
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	semaphore := make(chan struct{}, e.concurrency())
+	for _, ip := range ips {
+		wg.Add(1)
+		go func(ip string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			names, err := e.queryReverseIPLookup(ip)
+			if err != nil {
+				logger.Debugf("Reverse IP lookup for %s failed: %v", ip, err)
+				return
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			for _, name := range names {
+				host := core.NormalizeHost(name)
+				if seenHosts[host] || !e.IsValidSubdomain(host, domain) {
+					continue
+				}
+				seenHosts[host] = true
+				hosts = append(hosts, host)
+				e.AddTaggedSubdomain(host, ReverseIPSource)
+			}
+		}(ip)
+	}
+	wg.Wait()
+
+	if len(hosts) > 0 {
+		logger.Infof("Reverse IP lookup found %d new subdomains of %s on %d IPs", len(hosts), domain, len(ips))
+	}
+	return hosts
+}
+
+// queryReverseIPLookup 查询解析到该 IP 的主机名
+func (e *Enrich) queryReverseIPLookup(ip string) ([]string, error) {
+	params := url.Values{}
+	params.Set("q", ip)
+
+	resp, err := e.HTTPGet(fmt.Sprintf("%s?%s", e.reverseIPURL, params.Encode()), e.GetHeader())
+	if err != nil {
+		return nil, err
+	}
+	body, err := e.ReadResponseBody(resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("returned status %d", resp.StatusCode)
+	}
+
+	// 次数超限和查询失败时返回一行说明文字（如 "API count exceeded"、"No DNS A records found"）
+	var names []string
+	for _, line := range strings.Split(body, "\n") {
+		name, _, _ := strings.Cut(strings.TrimSpace(line), ",")
+		if name == "" || strings.Contains(name, " ") {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
@@ -0,0 +1,81 @@
+package enrich
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/oneforall-go/internal/core"
+	"github.com/oneforall-go/pkg/logger"
+)
+
+// PTRSweepSource 通过 /24 网段 PTR 扫描发现的子域名的来源标记
+const PTRSweepSource = "ptr_sweep"
+
+// sweepNetworks 对反查结果中非 CDN 公网 IPv4 地址所在的 /24 网段逐个查询 256 个地址的 PTR 记录，
+// 返回范围内的新子域名。网段去重后最多扫描 ptr_sweep_limit 个，每秒最多查询 ptr_sweep_rate 个地址，known 中已有的主机名不再返回
+func (e *Enrich) sweepNetworks(domain string, results []EnrichResult, known []string) []string {
+	cfg := e.GetConfig()
+	if cfg.PTRSweepLimit <= 0 {
+		return nil
+	}
+
+	networks := sweepPrefixes(results, e.isPrivateIP)
+	if len(networks) == 0 {
+		return nil
+	}
+	if len(networks) > cfg.PTRSweepLimit {
+		logger.Infof("PTR sweep limited to %d of %d /24 networks for %s", cfg.PTRSweepLimit, len(networks), domain)
+		networks = networks[:cfg.PTRSweepLimit]
+	}
+
+	var ips []string
+	for _, network := range networks {
+		for i := 0; i < 256; i++ {
+			ips = append(ips, fmt.Sprintf("%d.%d.%d.%d", network[0], network[1], network[2], i))
+		}
+	}
+	logger.Infof("Sweeping PTR records of %d addresses in %d /24 networks for %s", len(ips), len(networks), domain)
+
+	seenHosts := make(map[string]bool)
+	for _, host := range known {
+		seenHosts[core.NormalizeHost(host)] = true
+	}
+
+	var hosts []string
+	for _, name := range e.reverseLookup(ips, cfg.PTRSweepRate) {
+		host := core.NormalizeHost(name)
+		if seenHosts[host] || !e.IsValidSubdomain(host, domain) {
+			continue
+		}
+		seenHosts[host] = true
+		hosts = append(hosts, host)
+		e.AddTaggedSubdomain(host, PTRSweepSource)
+	}
+
+	if len(hosts) > 0 {
+		logger.Infof("PTR sweep found %d new subdomains of %s in %d /24 networks", len(hosts), domain, len(networks))
+	}
+	return hosts
+}
+
+// sweepPrefixes 按出现顺序返回非 CDN 公网 IPv4 地址所在的 /24 网段（前三个字节），已去重
+func sweepPrefixes(results []EnrichResult, isPrivate func(ip string) bool) []net.IP {
+	seen := make(map[string]bool)
+	var networks []net.IP
+	for _, result := range results {
+		if result.IsCDN || isPrivate(result.IP) {
+			continue
+		}
+		ip := net.ParseIP(result.IP).To4()
+		if ip == nil {
+			continue
+		}
+		network := ip.Mask(net.CIDRMask(24, 32))
+		if seen[network.String()] {
+			continue
+		}
+		seen[network.String()] = true
+		networks = append(networks, network)
+	}
+	return networks
+}
@@ -0,0 +1,76 @@
+package http
+
+import (
+	"html"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// titlePattern 匹配 HTML 标题
+var titlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// metaCharsetPattern 匹配 <meta charset="..."> 和 <meta http-equiv="Content-Type" content="text/html; charset=...">
+var metaCharsetPattern = regexp.MustCompile(`(?is)<meta[^>]+charset\s*=\s*["']?\s*([a-z0-9_:.\-]+)`)
+
+// DecodeHTML 将 HTML 响应体转码为 UTF-8：字符集依次取自 Content-Type 和页面中的 <meta charset>，
+// 声明为 UTF-8 但内容不是合法 UTF-8 时（服务器默认头常见）忽略该声明；
+// 没有可用的声明且内容不是合法的 UTF-8 时按 GB18030（兼容 GBK/GB2312）处理，其余情况原样返回
+func DecodeHTML(body []byte, contentType string) []byte {
+	valid := utf8.Valid(body)
+	names := []string{charsetFromContentType(contentType)}
+	if match := metaCharsetPattern.FindSubmatch(body); len(match) == 2 {
+		names = append(names, string(match[1]))
+	}
+
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		enc, err := htmlindex.Get(name)
+		if err != nil {
+			continue
+		}
+		if canonical, _ := htmlindex.Name(enc); canonical == "utf-8" {
+			if valid {
+				return body
+			}
+			continue
+		}
+		if decoded, err := enc.NewDecoder().Bytes(body); err == nil {
+			return decoded
+		}
+	}
+
+	if !valid {
+		if decoded, err := simplifiedchinese.GB18030.NewDecoder().Bytes(body); err == nil {
+			return decoded
+		}
+	}
+	return body
+}
+
+// ExtractTitle 按页面字符集转码后提取 HTML 标题，合并空白并还原实体
+func ExtractTitle(body []byte, contentType string) string {
+	match := titlePattern.FindSubmatch(DecodeHTML(body, contentType))
+	if len(match) < 2 {
+		return ""
+	}
+	return strings.Join(strings.Fields(html.UnescapeString(string(match[1]))), " ")
+}
+
+// charsetFromContentType 返回 Content-Type 中声明的字符集，没有声明时返回空
+func charsetFromContentType(contentType string) string {
+	if contentType == "" {
+		return ""
+	}
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(params["charset"])
+}
@@ -1,9 +1,11 @@
 package http
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/valyala/fasthttp"
@@ -79,7 +81,7 @@ func (c *Client) requestHTTP(domain, ip, scheme string) (int, string, error) {
 	}
 
 	// 提取标题
-	title := c.extractTitle(resp.Body())
+	title := c.extractTitle(resp.Body(), string(resp.Header.ContentType()))
 
 	return status, title, nil
 }
@@ -88,12 +90,15 @@ func (c *Client) requestHTTP(domain, ip, scheme string) (int, string, error) {
 func (c *Client) RequestWithPort(domain, ip string, port int) (int, string, error) {
 	// 尝试 HTTP
 	status, title, err := c.requestHTTPWithPort(domain, ip, port, "http")
-	if err == nil && status > 0 {
+	if err == nil && status > 0 && status != http.StatusBadRequest {
 		return status, title, nil
 	}
 
-	// 尝试 HTTPS
-	status, title, err = c.requestHTTPWithPort(domain, ip, port, "https")
+	// 尝试 HTTPS，HTTPS 服务对明文请求通常返回 400，此时以 HTTPS 的响应为准
+	httpsStatus, httpsTitle, httpsErr := c.requestHTTPWithPort(domain, ip, port, "https")
+	if httpsErr == nil && httpsStatus > 0 {
+		return httpsStatus, httpsTitle, nil
+	}
 	if err == nil && status > 0 {
 		return status, title, nil
 	}
@@ -130,14 +135,14 @@ func (c *Client) requestHTTPWithPort(domain, ip string, port int, scheme string)
 	}
 
 	// 提取标题
-	title := c.extractTitle(resp.Body())
+	title := c.extractTitle(resp.Body(), string(resp.Header.ContentType()))
 
 	return status, title, nil
 }
 
-// extractTitle 从 HTML 中提取标题
-func (c *Client) extractTitle(body []byte) string {
-	bodyStr := string(body)
+// extractTitle 按页面字符集转码后从 HTML 中提取标题
+func (c *Client) extractTitle(body []byte, contentType string) string {
+	bodyStr := string(DecodeHTML(body, contentType))
 
 	// 查找 <title> 标签
 	titleStart := strings.Index(strings.ToLower(bodyStr), "<title>")
@@ -164,28 +169,42 @@ func (c *Client) extractTitle(body []byte) string {
 		title = strings.ReplaceAll(title, "  ", " ")
 	}
 
-	// 限制标题长度
-	if len(title) > 100 {
-		title = title[:100] + "..."
+	// 限制标题长度，按字符截断，避免截断多字节的中文字符
+	if runes := []rune(title); len(runes) > 100 {
+		title = string(runes[:100]) + "..."
 	}
 
 	return title
 }
 
-// RequestMultiplePorts 发送多端口请求
+// RequestMultiplePorts 并发请求多个端口，返回有 HTTP 响应的端口及其状态码
 func (c *Client) RequestMultiplePorts(domain, ip string, ports []int) map[int]int {
 	results := make(map[int]int)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
 
 	for _, port := range ports {
-		status, _, err := c.RequestWithPort(domain, ip, port)
-		if err == nil && status > 0 {
-			results[port] = status
-		}
+		wg.Add(1)
+		go func(port int) {
+			defer wg.Done()
+			status, _, err := c.RequestWithPort(domain, ip, port)
+			if err == nil && status > 0 {
+				mu.Lock()
+				results[port] = status
+				mu.Unlock()
+			}
+		}(port)
 	}
+	wg.Wait()
 
 	return results
 }
 
+// SetInsecureSkipVerify 设置 HTTPS 请求是否跳过证书校验，探测非标准端口的自签名服务时使用
+func (c *Client) SetInsecureSkipVerify(insecure bool) {
+	c.httpClient.TLSConfig = &tls.Config{InsecureSkipVerify: insecure}
+}
+
 // SetTimeout 设置超时时间
 func (c *Client) SetTimeout(timeout time.Duration) {
 	c.timeout = timeout
@@ -33,6 +33,11 @@ func NewRiskIQ(cfg *config.Config) *RiskIQ {
 	}
 }
 
+// RequiresAPIKey 未配置 riskiq_api_username、riskiq_api_key 时不发送请求
+func (r *RiskIQ) RequiresAPIKey() bool {
+	return true
+}
+
 // Run 执行查询
 func (r *RiskIQ) Run(domain string) ([]string, error) {
 	r.SetDomain(domain)
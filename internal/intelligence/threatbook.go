@@ -24,6 +24,11 @@ func NewThreatBook(cfg *config.Config) *ThreatBook {
 	}
 }
 
+// RequiresAPIKey 未配置 threatbook_api_key 时不发送请求
+func (t *ThreatBook) RequiresAPIKey() bool {
+	return true
+}
+
 // Run 执行查询
 func (t *ThreatBook) Run(domain string) ([]string, error) {
 	t.SetDomain(domain)
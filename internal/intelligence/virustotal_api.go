@@ -32,6 +32,11 @@ func NewVirusTotalAPI(cfg *config.Config) *VirusTotalAPI {
 	}
 }
 
+// RequiresAPIKey 未配置 virustotal_api_key 时不发送请求
+func (v *VirusTotalAPI) RequiresAPIKey() bool {
+	return true
+}
+
 // Run 执行查询
 func (v *VirusTotalAPI) Run(domain string) ([]string, error) {
 	v.SetDomain(domain)
@@ -0,0 +1,347 @@
+package intelligence
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oneforall-go/internal/config"
+	"github.com/oneforall-go/internal/core"
+)
+
+// whoisTimeout 传统 WHOIS 查询超时
+const whoisTimeout = 10 * time.Second
+
+// privacyMarkers 隐私保护的注册人信息，不能用于反查
+var privacyMarkers = []string{"redacted", "privacy", "proxy", "protected", "withheld", "not disclosed", "data protected"}
+
+// reverseWhoisCache 按组织缓存反查结果，多个目标属于同一组织时只查询一次
+var (
+	reverseWhoisCache = make(map[string][]string)
+	reverseWhoisMutex sync.Mutex
+)
+
+// Whois WHOIS/RDAP 组织关联模块
+type Whois struct {
+	*core.Query
+	rdapURL    string
+	whoisURL   string
+	reverseURL string
+	key        string
+}
+
+// WhoisRegistrant 注册人信息
+type WhoisRegistrant struct {
+	Organization string
+	Email        string
+}
+
+// rdapEntity RDAP 实体（注册人、注册商等）
+type rdapEntity struct {
+	Roles      []string      `json:"roles"`
+	VCardArray []interface{} `json:"vcardArray"`
+	Entities   []rdapEntity  `json:"entities"`
+}
+
+// rdapDomain RDAP 域名查询响应
+type rdapDomain struct {
+	Entities    []rdapEntity `json:"entities"`
+	Nameservers []struct {
+		LdhName string `json:"ldhName"`
+	} `json:"nameservers"`
+}
+
+// reverseWhoisResponse WhoisXML 反查响应
+type reverseWhoisResponse struct {
+	DomainsCount int      `json:"domainsCount"`
+	DomainsList  []string `json:"domainsList"`
+}
+
+// NewWhois 创建 WHOIS/RDAP 组织关联模块
+func NewWhois(cfg *config.Config) *Whois {
+	return &Whois{
+		Query:      core.NewQuery("WhoisQuery", cfg),
+		rdapURL:    "https://rdap.org/domain/",
+		whoisURL:   "whois.iana.org:43",
+		reverseURL: "https://reverse-whois.whoisxmlapi.com/api/v2",
+		key:        cfg.APIKeys["whoisxml_api_key"],
+	}
+}
+
+// Run 执行查询
+func (w *Whois) Run(domain string) ([]string, error) {
+	w.SetDomain(domain)
+	w.Begin()
+	defer w.Finish()
+
+	// 执行查询
+	if err := w.query(domain); err != nil {
+		return nil, err
+	}
+
+	return w.GetSubdomains(), nil
+}
+
+// query 执行查询，优先使用 RDAP，失败时回退到传统 WHOIS
+func (w *Whois) query(domain string) error {
+	registrant, err := w.queryRDAP(domain)
+	if err != nil {
+		w.LogDebug("RDAP lookup failed (%v), falling back to WHOIS", err)
+		registrant, err = w.queryWhois(domain)
+		if err != nil {
+			return fmt.Errorf("failed to query WHOIS: %v", err)
+		}
+	}
+
+	if registrant.Organization == "" && registrant.Email == "" {
+		w.LogInfo("No registrant information found for %s", domain)
+		return nil
+	}
+	w.LogInfo("Registrant of %s: organization=%q email=%q", domain, registrant.Organization, registrant.Email)
+	w.AddInfo("registrant_organization", registrant.Organization)
+	w.AddInfo("registrant_email", registrant.Email)
+
+	// 反查同一组织注册的其他域名
+	if registrant.Organization == "" || isPrivacyProtected(registrant.Organization) {
+		w.LogDebug("Registrant organization is empty or privacy protected, skipping reverse WHOIS")
+		return nil
+	}
+	if !w.HaveAPI("whoisxml_api_key") {
+		return nil
+	}
+
+	domains, err := w.reverseWhois(registrant.Organization)
+	if err != nil {
+		w.LogError("Reverse WHOIS for %q failed: %v", registrant.Organization, err)
+		return nil
+	}
+	for _, related := range domains {
+		w.AddRelatedDomain(related)
+	}
+	w.LogInfo("Reverse WHOIS found %d domains registered by %q", len(domains), registrant.Organization)
+
+	return nil
+}
+
+// queryRDAP 通过 RDAP 查询注册人信息，同时收集范围内的 NS 主机名
+func (w *Whois) queryRDAP(domain string) (WhoisRegistrant, error) {
+	w.SetHeader("User-Agent", w.GetRandomUserAgent())
+	w.SetHeader("Accept", "application/rdap+json")
+
+	resp, err := w.HTTPGet(w.rdapURL+domain, w.GetHeader())
+	if err != nil {
+		return WhoisRegistrant{}, err
+	}
+	body, err := w.ReadResponseBody(resp)
+	if err != nil {
+		return WhoisRegistrant{}, fmt.Errorf("failed to read response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return WhoisRegistrant{}, fmt.Errorf("RDAP returned status %d", resp.StatusCode)
+	}
+
+	var data rdapDomain
+	if err := json.Unmarshal([]byte(body), &data); err != nil {
+		return WhoisRegistrant{}, fmt.Errorf("failed to parse RDAP response: %v", err)
+	}
+
+	for _, ns := range data.Nameservers {
+		if name := core.NormalizeHost(ns.LdhName); w.IsValidSubdomain(name, domain) {
+			w.AddSubdomain(name)
+		}
+	}
+
+	return findRegistrant(data.Entities), nil
+}
+
+// findRegistrant 在实体树中查找 registrant 角色的组织和邮箱
+func findRegistrant(entities []rdapEntity) WhoisRegistrant {
+	for _, entity := range entities {
+		for _, role := range entity.Roles {
+			if strings.EqualFold(role, "registrant") {
+				return parseVCard(entity.VCardArray)
+			}
+		}
+		if registrant := findRegistrant(entity.Entities); registrant != (WhoisRegistrant{}) {
+			return registrant
+		}
+	}
+	return WhoisRegistrant{}
+}
+
+// parseVCard 解析 jCard 格式的 vcardArray：["vcard", [[name, params, type, value], ...]]
+// 组织名优先取 org，没有时取 fn
+func parseVCard(vcard []interface{}) WhoisRegistrant {
+	var registrant WhoisRegistrant
+	if len(vcard) < 2 {
+		return registrant
+	}
+	properties, ok := vcard[1].([]interface{})
+	if !ok {
+		return registrant
+	}
+
+	var fn string
+	for _, item := range properties {
+		property, ok := item.([]interface{})
+		if !ok || len(property) < 4 {
+			continue
+		}
+		name, _ := property[0].(string)
+		value := vcardText(property[3])
+		switch strings.ToLower(name) {
+		case "org":
+			registrant.Organization = value
+		case "fn":
+			fn = value
+		case "email":
+			registrant.Email = value
+		}
+	}
+	if registrant.Organization == "" {
+		registrant.Organization = fn
+	}
+	return registrant
+}
+
+// vcardText 提取 vCard 属性值，org 等结构化属性的值可能是字符串数组
+func vcardText(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case []interface{}:
+		var parts []string
+		for _, part := range v {
+			if s, ok := part.(string); ok && strings.TrimSpace(s) != "" {
+				parts = append(parts, strings.TrimSpace(s))
+			}
+		}
+		return strings.Join(parts, " ")
+	}
+	return ""
+}
+
+// queryWhois 通过传统 WHOIS 查询注册人信息，先询问 IANA 获取注册局的 WHOIS 服务器
+func (w *Whois) queryWhois(domain string) (WhoisRegistrant, error) {
+	text, err := whoisLookup(w.whoisURL, domain)
+	if err != nil {
+		return WhoisRegistrant{}, err
+	}
+
+	// IANA 返回注册局服务器，thin 注册局（如 .com）还需再跟随一次注册商服务器
+	if server := whoisField(text, "refer", "whois"); server != "" {
+		w.Sleep()
+		referred, err := whoisLookup(net.JoinHostPort(server, "43"), domain)
+		if err != nil {
+			return WhoisRegistrant{}, err
+		}
+		text = referred
+
+		if registrar := whoisField(text, "registrar whois server"); registrar != "" && !strings.EqualFold(registrar, server) {
+			w.Sleep()
+			if detail, err := whoisLookup(net.JoinHostPort(registrar, "43"), domain); err == nil {
+				text = detail
+			} else {
+				w.LogDebug("Registrar WHOIS %s failed: %v", registrar, err)
+			}
+		}
+	}
+
+	return WhoisRegistrant{
+		Organization: whoisField(text, "registrant organization", "registrant organisation", "registrant"),
+		Email:        whoisField(text, "registrant email"),
+	}, nil
+}
+
+// whoisLookup 向 WHOIS 服务器（host:port）发送查询并读取完整响应
+func whoisLookup(server, domain string) (string, error) {
+	conn, err := net.DialTimeout("tcp", server, whoisTimeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(whoisTimeout))
+	if _, err := conn.Write([]byte(domain + "\r\n")); err != nil {
+		return "", err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(conn, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// whoisField 按顺序查找 "key: value" 形式的字段，返回第一个非空值
+func whoisField(text string, keys ...string) string {
+	lines := strings.Split(text, "\n")
+	for _, key := range keys {
+		for _, line := range lines {
+			name, value, ok := strings.Cut(line, ":")
+			if !ok || !strings.EqualFold(strings.TrimSpace(name), key) {
+				continue
+			}
+			if value = strings.TrimSpace(value); value != "" {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// isPrivacyProtected 判断注册人信息是否被隐私保护
+func isPrivacyProtected(value string) bool {
+	value = strings.ToLower(value)
+	for _, marker := range privacyMarkers {
+		if strings.Contains(value, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// reverseWhois 查询同一组织注册的域名，结果按组织缓存
+func (w *Whois) reverseWhois(organization string) ([]string, error) {
+	cacheKey := strings.ToLower(organization)
+
+	reverseWhoisMutex.Lock()
+	defer reverseWhoisMutex.Unlock()
+	if domains, ok := reverseWhoisCache[cacheKey]; ok {
+		w.LogDebug("Using cached reverse WHOIS result for %q", organization)
+		return domains, nil
+	}
+
+	payload := map[string]interface{}{
+		"apiKey":     w.key,
+		"searchType": "current",
+		"mode":       "purchase",
+		"basicSearchTerms": map[string][]string{
+			"include": {organization},
+		},
+	}
+	resp, err := w.HTTPPostJSON(w.reverseURL, payload, nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := w.ReadResponseBody(resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("reverse WHOIS returned status %d", resp.StatusCode)
+	}
+
+	var result reverseWhoisResponse
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse reverse WHOIS response: %v", err)
+	}
+
+	reverseWhoisCache[cacheKey] = result.DomainsList
+	return result.DomainsList, nil
+}
@@ -0,0 +1,270 @@
+package intelligence
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/oneforall-go/internal/config"
+	"github.com/oneforall-go/internal/core"
+)
+
+// rdapResponse RDAP 域名查询响应（节选），registrant 嵌套在注册商实体下
+const rdapResponse = `{
+  "objectClassName": "domain",
+  "ldhName": "example.com",
+  "nameservers": [{"ldhName": "NS1.EXAMPLE.COM"}, {"ldhName": "ns.other-dns.net"}],
+  "entities": [{
+    "roles": ["registrar"],
+    "vcardArray": ["vcard", [["version", {}, "text", "4.0"], ["fn", {}, "text", "Example Registrar"]]],
+    "entities": [{
+      "roles": ["registrant"],
+      "vcardArray": ["vcard", [
+        ["version", {}, "text", "4.0"],
+        ["fn", {}, "text", "Jane Doe"],
+        ["org", {}, "text", ["Example Inc.", " "]],
+        ["email", {}, "text", "hostmaster@example.com"]
+      ]]
+    }]
+  }]
+}`
+
+// parseVCardJSON 把 JSON 形式的 vcardArray 解码为 parseVCard 的参数
+func parseVCardJSON(t *testing.T, raw string) []interface{} {
+	var vcard []interface{}
+	if err := json.Unmarshal([]byte(raw), &vcard); err != nil {
+		t.Fatalf("invalid vcard %s: %v", raw, err)
+	}
+	return vcard
+}
+
+func TestParseVCard(t *testing.T) {
+	tests := []struct {
+		name  string
+		vcard string
+		want  WhoisRegistrant
+	}{
+		{"org and email", `["vcard", [["org", {}, "text", "Example Inc."], ["email", {}, "text", " admin@example.com "]]]`,
+			WhoisRegistrant{Organization: "Example Inc.", Email: "admin@example.com"}},
+		{"structured org", `["vcard", [["org", {}, "text", ["Example Inc.", "IT Department", ""]]]]`,
+			WhoisRegistrant{Organization: "Example Inc. IT Department"}},
+		{"fn fallback", `["vcard", [["fn", {}, "text", "Example Holdings"]]]`,
+			WhoisRegistrant{Organization: "Example Holdings"}},
+		{"org preferred over fn", `["vcard", [["fn", {}, "text", "Jane Doe"], ["ORG", {}, "text", "Example Inc."]]]`,
+			WhoisRegistrant{Organization: "Example Inc."}},
+		{"short property skipped", `["vcard", [["org", {}, "text"], ["email", {}, "text", "a@example.com"]]]`,
+			WhoisRegistrant{Email: "a@example.com"}},
+		{"missing properties", `["vcard"]`, WhoisRegistrant{}},
+		{"malformed properties", `["vcard", "org"]`, WhoisRegistrant{}},
+	}
+
+	for _, tt := range tests {
+		if got := parseVCard(parseVCardJSON(t, tt.vcard)); got != tt.want {
+			t.Errorf("%s: parseVCard = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestVCardText(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{"string", "  Example Inc. ", "Example Inc."},
+		{"array", []interface{}{"Example Inc.", " ", "Sales", 42}, "Example Inc. Sales"},
+		{"empty array", []interface{}{}, ""},
+		{"number", 42.0, ""},
+		{"nil", nil, ""},
+	}
+
+	for _, tt := range tests {
+		if got := vcardText(tt.value); got != tt.want {
+			t.Errorf("%s: vcardText = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFindRegistrant(t *testing.T) {
+	var data rdapDomain
+	if err := json.Unmarshal([]byte(rdapResponse), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	// registrant 嵌套在注册商实体下，注册商自身的 fn 不能当作注册人
+	want := WhoisRegistrant{Organization: "Example Inc.", Email: "hostmaster@example.com"}
+	if got := findRegistrant(data.Entities); got != want {
+		t.Errorf("findRegistrant = %+v, want %+v", got, want)
+	}
+
+	// 没有 registrant 角色时返回空
+	registrarOnly := []rdapEntity{{Roles: []string{"registrar"}, VCardArray: parseVCardJSON(t, `["vcard", [["fn", {}, "text", "Registrar"]]]`)}}
+	if got := findRegistrant(registrarOnly); got != (WhoisRegistrant{}) {
+		t.Errorf("Expected no registrant, got %+v", got)
+	}
+}
+
+func TestWhoisField(t *testing.T) {
+	iana := "% IANA WHOIS server\n\nrefer:        whois.verisign-grs.com\n\ndomain:       COM\nwhois:        whois.verisign-grs.com\n"
+	registry := "   Domain Name: EXAMPLE.COM\r\n   Registrar WHOIS Server: whois.registrar.example\r\n   Registrar URL: http://www.registrar.example\r\n"
+	registrar := "Registrant Organization: \nRegistrant: Example Inc.\nRegistrant Email: hostmaster@example.com\n"
+
+	tests := []struct {
+		name string
+		text string
+		keys []string
+		want string
+	}{
+		{"refer", iana, []string{"refer", "whois"}, "whois.verisign-grs.com"},
+		{"whois fallback", "whois: whois.nic.example\n", []string{"refer", "whois"}, "whois.nic.example"},
+		{"registrar whois server", registry, []string{"registrar whois server"}, "whois.registrar.example"},
+		{"value with colon", registry, []string{"registrar url"}, "http://www.registrar.example"},
+		{"empty value skipped", registrar, []string{"registrant organization", "registrant"}, "Example Inc."},
+		{"case insensitive", registrar, []string{"REGISTRANT EMAIL"}, "hostmaster@example.com"},
+		{"missing", iana, []string{"registrant"}, ""},
+	}
+
+	for _, tt := range tests {
+		if got := whoisField(tt.text, tt.keys...); got != tt.want {
+			t.Errorf("%s: whoisField = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIsPrivacyProtected(t *testing.T) {
+	tests := map[string]bool{
+		"REDACTED FOR PRIVACY":            true,
+		"Domains By Proxy, LLC":           true,
+		"Data Protected":                  true,
+		"Contact Privacy Inc. Customer 1": true,
+		"Withheld for Privacy ehf":        true,
+		"Example Inc.":                    false,
+	}
+
+	for value, want := range tests {
+		if got := isPrivacyProtected(value); got != want {
+			t.Errorf("isPrivacyProtected(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+// newWhoisServer 启动 RDAP 和反查 WHOIS 测试服务器：/rdap/<domain> 按 rdap 中的响应返回，
+// /reverse 返回 Example Inc. 注册的域名；reverseQueries 记录反查次数
+func newWhoisServer(t *testing.T, rdap map[string]string, reverseQueries *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if domain := strings.TrimPrefix(r.URL.Path, "/rdap/"); domain != r.URL.Path {
+			body, ok := rdap[domain]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(body))
+			return
+		}
+		if r.URL.Path != "/reverse" {
+			t.Errorf("Unexpected request %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		atomic.AddInt32(reverseQueries, 1)
+		data, _ := io.ReadAll(r.Body)
+		var payload struct {
+			APIKey           string              `json:"apiKey"`
+			BasicSearchTerms map[string][]string `json:"basicSearchTerms"`
+		}
+		if err := json.Unmarshal(data, &payload); err != nil || payload.APIKey != "test-key" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if include := payload.BasicSearchTerms["include"]; len(include) != 1 || include[0] != "Example Inc." {
+			t.Errorf("Unexpected reverse WHOIS terms %v", payload.BasicSearchTerms)
+		}
+		w.Write([]byte(`{"domainsCount": 3, "domainsList": ["example.com", "example.org", "shop.example-store.net"]}`))
+	}))
+}
+
+// newTestWhois 创建指向测试服务器的 WHOIS 模块
+func newTestWhois(serverURL string) *Whois {
+	w := NewWhois(&config.Config{APIKeys: map[string]string{"whoisxml_api_key": "test-key"}})
+	w.rdapURL = serverURL + "/rdap/"
+	w.reverseURL = serverURL + "/reverse"
+	w.SetDelay(0)
+	w.SetRetryPolicy(core.RetryPolicy{MaxAttempts: 1})
+	return w
+}
+
+// resetReverseWhoisCache 清空全局的反查缓存，测试结束后再次清空
+func resetReverseWhoisCache(t *testing.T) {
+	reset := func() {
+		reverseWhoisMutex.Lock()
+		reverseWhoisCache = make(map[string][]string)
+		reverseWhoisMutex.Unlock()
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func TestWhoisReportsRelatedDomains(t *testing.T) {
+	resetReverseWhoisCache(t)
+	var reverseQueries int32
+	server := newWhoisServer(t, map[string]string{
+		"example.com": rdapResponse,
+		"example.net": strings.Replace(rdapResponse, "NS1.EXAMPLE.COM", "ns1.example.net", 1),
+	}, &reverseQueries)
+	defer server.Close()
+
+	w := newTestWhois(server.URL)
+	subdomains, err := w.Run("example.com")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	// 范围内的 NS 主机名作为子域名，范围外的忽略
+	if len(subdomains) != 1 || subdomains[0] != "ns1.example.com" {
+		t.Errorf("Expected ns1.example.com, got %v", subdomains)
+	}
+	if org := w.GetInfo("registrant_organization"); org != "Example Inc." {
+		t.Errorf("Expected registrant organization, got %v", org)
+	}
+
+	related := w.TakeRelatedDomains()
+	sort.Strings(related)
+	expected := []string{"example-store.net", "example.com", "example.org"}
+	if strings.Join(related, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected related domains %v, got %v", expected, related)
+	}
+
+	// 同一组织的另一个目标使用缓存，不再反查
+	if _, err := newTestWhois(server.URL).Run("example.net"); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if n := atomic.LoadInt32(&reverseQueries); n != 1 {
+		t.Errorf("Expected one reverse WHOIS query per organization, got %d", n)
+	}
+}
+
+func TestWhoisSkipsPrivacyProtectedRegistrant(t *testing.T) {
+	resetReverseWhoisCache(t)
+	var reverseQueries int32
+	server := newWhoisServer(t, map[string]string{
+		"example.com": strings.Replace(rdapResponse, `["Example Inc.", " "]`, `"REDACTED FOR PRIVACY"`, 1),
+	}, &reverseQueries)
+	defer server.Close()
+
+	w := newTestWhois(server.URL)
+	if _, err := w.Run("example.com"); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if n := atomic.LoadInt32(&reverseQueries); n != 0 {
+		t.Errorf("Expected no reverse WHOIS for a privacy protected registrant, got %d queries", n)
+	}
+	if related := w.TakeRelatedDomains(); len(related) != 0 {
+		t.Errorf("Expected no related domains, got %v", related)
+	}
+}
@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/oneforall-go/pkg/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "oneforall"
+
+var (
+	registry = prometheus.NewRegistry()
+
+	// modulesRun 模块运行次数（按模块和状态）
+	modulesRun = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "modules_run_total",
+		Help:      "Number of module runs by module and status.",
+	}, []string{"module", "status"})
+
+	// subdomainsFound 各来源发现的子域名数量
+	subdomainsFound = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "subdomains_found_total",
+		Help:      "Number of subdomains found by source module.",
+	}, []string{"source"})
+
+	// validationResults 验证结果（存活/失效）
+	validationResults = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "validation_results_total",
+		Help:      "Number of validated subdomains by result.",
+	}, []string{"result"})
+
+	// stepDuration 各执行步骤耗时
+	stepDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "step_duration_seconds",
+		Help:      "Duration of execution steps in seconds.",
+		Buckets:   []float64{1, 5, 10, 30, 60, 120, 300, 600, 1800},
+	}, []string{"step"})
+)
+
+func init() {
+	registry.MustRegister(modulesRun, subdomainsFound, validationResults, stepDuration)
+}
+
+// ObserveModuleRun 记录模块运行结果
+func ObserveModuleRun(module string, found int, err error) {
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+	modulesRun.WithLabelValues(module, status).Inc()
+	if found > 0 {
+		subdomainsFound.WithLabelValues(module).Add(float64(found))
+	}
+}
+
+// ObserveValidation 记录验证结果
+func ObserveValidation(alive bool) {
+	result := "dead"
+	if alive {
+		result = "alive"
+	}
+	validationResults.WithLabelValues(result).Inc()
+}
+
+// ObserveStep 记录步骤耗时
+func ObserveStep(step string, elapsed time.Duration) {
+	stepDuration.WithLabelValues(step).Observe(elapsed.Seconds())
+}
+
+// Handler 返回指标 HTTP 处理器
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// Serve 在后台启动指标服务，addr 为空时不启动
+func Serve(addr string) {
+	if addr == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+
+	go func() {
+		logger.Infof("Metrics server listening on %s/metrics", addr)
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			logger.Errorf("Metrics server stopped: %v", err)
+		}
+	}()
+}
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/oneforall-go/internal/config"
+	"github.com/oneforall-go/internal/core"
 	"github.com/oneforall-go/pkg/logger"
 )
 
@@ -37,8 +38,9 @@ func NewBaseModule(name string, moduleType ModuleType, cfg *config.Config) *Base
 		httpClient: &http.Client{
 			Timeout: time.Duration(cfg.DNSResolveTimeout) * time.Second,
 			Transport: &http.Transport{
+				// 只有直接访问目标的检查模块和 insecure_modules 中列出的模块不校验证书
 				TLSClientConfig: &tls.Config{
-					InsecureSkipVerify: true,
+					InsecureSkipVerify: moduleType == ModuleTypeCheck || cfg.InsecureModule(name),
 				},
 			},
 		},
@@ -198,26 +200,9 @@ func (b *BaseModule) ExtractSubdomains(text, domain string) []string {
 	return subdomains
 }
 
-// IsValidSubdomain 验证子域名是否有效
+// IsValidSubdomain 验证子域名是否有效（与 core 使用相同的范围检查）
 func (b *BaseModule) IsValidSubdomain(subdomain, domain string) bool {
-	// 基本验证
-	if subdomain == domain {
-		return false
-	}
-
-	if !strings.HasSuffix(subdomain, "."+domain) {
-		return false
-	}
-
-	// 检查是否包含无效字符
-	invalidChars := []string{" ", "\t", "\n", "\r", "\\", "/", ":", "*", "?", "\"", "<", ">", "|"}
-	for _, char := range invalidChars {
-		if strings.Contains(subdomain, char) {
-			return false
-		}
-	}
-
-	return true
+	return core.NormalizeHost(subdomain) != core.NormalizeHost(domain) && core.InScope(subdomain, domain)
 }
 
 // Deduplicate 去重
@@ -250,6 +235,11 @@ func (b *BaseModule) ReadResponseBody(resp *http.Response) (string, error) {
 	return string(body), nil
 }
 
+// GetConfig 获取配置
+func (b *BaseModule) GetConfig() *config.Config {
+	return b.config
+}
+
 // GetAPIKey 获取 API 密钥
 func (b *BaseModule) GetAPIKey(keyName string) string {
 	return b.config.APIKeys[keyName]
@@ -6,6 +6,7 @@ import (
 
 	"github.com/miekg/dns"
 	"github.com/oneforall-go/internal/config"
+	dnsutil "github.com/oneforall-go/internal/dns"
 	"github.com/oneforall-go/internal/modules"
 )
 
@@ -52,12 +53,11 @@ func (a *AXFR) Run(domain string) ([]string, error) {
 
 // getNameservers 获取域名服务器
 func (a *AXFR) getNameservers(domain string) ([]string, error) {
-	client := &dns.Client{Timeout: 10 * 1000000000} // 10 seconds
 	msg := new(dns.Msg)
 	msg.SetQuestion(dns.Fqdn(domain), dns.TypeNS)
 	msg.RecursionDesired = true
 
-	resp, _, err := client.Exchange(msg, "8.8.8.8:53")
+	resp, err := dnsutil.ExchangeWithConfig(msg, a.GetConfig())
 	if err != nil {
 		return nil, err
 	}
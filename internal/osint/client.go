@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/oneforall-go/internal/config"
+	"github.com/oneforall-go/internal/core"
+	"github.com/oneforall-go/internal/search"
 	"github.com/oneforall-go/pkg/logger"
 )
 
@@ -224,39 +226,9 @@ func (o *OSINTClient) queryRiskIQ(domain string) ([]string, error) {
 	return subdomains, nil
 }
 
-// queryShodan 从 Shodan 查询
+// queryShodan 从 Shodan 查询，复用 Shodan 模块（需要配置 shodan_api_key），合并 DNS 域名接口和主机搜索的结果
 func (o *OSINTClient) queryShodan(domain string) ([]string, error) {
-	apiKey := o.apiKeys["shodan_api"]
-	if apiKey == "" {
-		return nil, fmt.Errorf("Shodan API key not configured")
-	}
-
-	url := fmt.Sprintf("https://api.shodan.io/dns/domain/%s?key=%s", domain, apiKey)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := o.client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("Shodan API returned status: %d", resp.StatusCode)
-	}
-
-	var result struct {
-		Subdomains []string `json:"subdomains"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
-	}
-
-	return result.Subdomains, nil
+	return search.NewShodan(config.GetConfig()).Run(domain)
 }
 
 // queryFofa 从 Fofa 查询
@@ -297,7 +269,7 @@ func (o *OSINTClient) queryFofa(domain string) ([]string, error) {
 	for _, row := range result.Results {
 		if len(row) > 0 {
 			host := row[0]
-			if strings.Contains(host, domain) {
+			if core.InScope(host, domain) {
 				subdomains = append(subdomains, host)
 			}
 		}
@@ -350,7 +322,7 @@ func (o *OSINTClient) queryQuake(domain string) ([]string, error) {
 		return nil, fmt.Errorf("Quake API key not configured")
 	}
 
-	url := fmt.Sprintf("https://quake.360.net/api/v3/search/quake", domain)
+	url := "https://quake.360.net/api/v3/search/quake"
 
 	req, err := http.NewRequest("POST", url, strings.NewReader(fmt.Sprintf(`{
 		"query": "domain:\"%s\"",
@@ -386,7 +358,7 @@ func (o *OSINTClient) queryQuake(domain string) ([]string, error) {
 
 	var subdomains []string
 	for _, item := range result.Data {
-		if strings.Contains(item.Domain, domain) {
+		if core.InScope(item.Domain, domain) {
 			subdomains = append(subdomains, item.Domain)
 		}
 	}
@@ -432,7 +404,7 @@ func (o *OSINTClient) queryZoomEye(domain string) ([]string, error) {
 
 	var subdomains []string
 	for _, item := range result.Results {
-		if strings.Contains(item.Domain, domain) {
+		if core.InScope(item.Domain, domain) {
 			subdomains = append(subdomains, item.Domain)
 		}
 	}
@@ -35,27 +35,11 @@ func (a *Ask) Run(domain string) ([]string, error) {
 	a.Begin()
 	defer a.Finish()
 
-	// 执行搜索
-	if err := a.search(domain, ""); err != nil {
+	// 执行搜索，包括过滤常见子域名和递归搜索下一层子域名
+	if err := a.SearchRounds(domain, a.search); err != nil {
 		return nil, err
 	}
 
-	// 排除同一子域搜索结果过多的子域以发现新的子域
-	for _, statement := range a.Filter(domain, a.GetSubdomains()) {
-		if err := a.search(domain, statement); err != nil {
-			a.LogError("Failed to search with filter %s: %v", statement, err)
-		}
-	}
-
-	// 递归搜索下一层的子域
-	if a.IsRecursiveSearch() {
-		for _, subdomain := range a.RecursiveSubdomain() {
-			if err := a.search(subdomain, ""); err != nil {
-				a.LogError("Failed to search subdomain %s: %v", subdomain, err)
-			}
-		}
-	}
-
 	return a.GetSubdomains(), nil
 }
 
@@ -31,27 +31,11 @@ func (b *Baidu) Run(domain string) ([]string, error) {
 	b.Begin()
 	defer b.Finish()
 
-	// 执行搜索
-	if err := b.search(domain, ""); err != nil {
+	// 执行搜索，包括过滤常见子域名和递归搜索下一层子域名
+	if err := b.SearchRounds(domain, b.search); err != nil {
 		return nil, err
 	}
 
-	// 排除同一子域搜索结果过多的子域以发现新的子域
-	for _, statement := range b.Filter(domain, b.GetSubdomains()) {
-		if err := b.search(domain, statement); err != nil {
-			b.LogError("Failed to search with filter %s: %v", statement, err)
-		}
-	}
-
-	// 递归搜索下一层的子域
-	if b.IsRecursiveSearch() {
-		for _, subdomain := range b.RecursiveSubdomain() {
-			if err := b.search(subdomain, ""); err != nil {
-				b.LogError("Failed to search subdomain %s: %v", subdomain, err)
-			}
-		}
-	}
-
 	return b.GetSubdomains(), nil
 }
 
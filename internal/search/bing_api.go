@@ -41,6 +41,11 @@ func NewBingAPI(cfg *config.Config) *BingAPI {
 	}
 }
 
+// RequiresAPIKey 未配置 bing_api_id、bing_api_key 时不发送请求
+func (b *BingAPI) RequiresAPIKey() bool {
+	return true
+}
+
 // Run 执行搜索
 func (b *BingAPI) Run(domain string) ([]string, error) {
 	b.SetDomain(domain)
@@ -52,27 +57,11 @@ func (b *BingAPI) Run(domain string) ([]string, error) {
 		return nil, fmt.Errorf("bing API keys not configured")
 	}
 
-	// 执行搜索
-	if err := b.search(domain, ""); err != nil {
+	// 执行搜索，包括过滤常见子域名和递归搜索下一层子域名
+	if err := b.SearchRounds(domain, b.search); err != nil {
 		return nil, err
 	}
 
-	// 排除同一子域搜索结果过多的子域以发现新的子域
-	for _, statement := range b.Filter(domain, b.GetSubdomains()) {
-		if err := b.search(domain, statement); err != nil {
-			b.LogError("Failed to search with filter %s: %v", statement, err)
-		}
-	}
-
-	// 递归搜索下一层的子域
-	if b.IsRecursiveSearch() {
-		for _, subdomain := range b.RecursiveSubdomain() {
-			if err := b.search(subdomain, ""); err != nil {
-				b.LogError("Failed to search subdomain %s: %v", subdomain, err)
-			}
-		}
-	}
-
 	return b.GetSubdomains(), nil
 }
 
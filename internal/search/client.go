@@ -258,50 +258,9 @@ func (s *SearchClient) queryBaidu(domain string) ([]string, error) {
 	return s.parseBaiduResults(resp.Body), nil
 }
 
-// queryGitHub 从 GitHub 查询
+// queryGitHub 从 GitHub 查询，复用 GitHub 模块（需要配置 github_api_token）
 func (s *SearchClient) queryGitHub(domain string) ([]string, error) {
-	apiKey := s.apiKeys["github_api"]
-
-	searchQuery := fmt.Sprintf("%s", domain)
-	searchURL := fmt.Sprintf("https://api.github.com/search/code?q=%s", url.QueryEscape(searchQuery))
-
-	req, err := http.NewRequest("GET", searchURL, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	if apiKey != "" {
-		req.Header.Set("Authorization", "token "+apiKey)
-	}
-
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("GitHub API returned status: %d", resp.StatusCode)
-	}
-
-	var result struct {
-		Items []struct {
-			HTMLURL string `json:"html_url"`
-			Path    string `json:"path"`
-		} `json:"items"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
-	}
-
-	var subdomains []string
-	for _, item := range result.Items {
-		// 从 GitHub 代码中提取可能的子域名
-		subdomains = append(subdomains, s.extractSubdomainsFromText(item.Path)...)
-	}
-
-	return subdomains, nil
+	return NewGitHub(config.GetConfig()).Run(domain)
 }
 
 // queryYahoo 从 Yahoo 查询
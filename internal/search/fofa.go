@@ -37,6 +37,11 @@ func NewFofa(cfg *config.Config) *Fofa {
 	}
 }
 
+// RequiresAPIKey 未配置 fofa_api_email、fofa_api_key 时不发送请求
+func (f *Fofa) RequiresAPIKey() bool {
+	return true
+}
+
 // Run 执行搜索
 func (f *Fofa) Run(domain string) ([]string, error) {
 	f.SetDomain(domain)
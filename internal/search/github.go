@@ -3,28 +3,50 @@ package search
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/oneforall-go/internal/config"
 	"github.com/oneforall-go/internal/core"
 )
 
+const (
+	// githubPerPage 每页结果数（GitHub 上限为 100）
+	githubPerPage = 100
+	// githubMaxPages 最大翻页数，GitHub 代码搜索最多返回 1000 条结果
+	githubMaxPages = 10
+	// githubMaxContentFiles 最多拉取内容的文件数，每个文件消耗一次 API 额度
+	githubMaxContentFiles = 300
+	// githubMaxRateLimitRetries 触发速率限制后的最大重试次数
+	githubMaxRateLimitRetries = 3
+	// githubMaxRateLimitWait 等待速率限制重置的上限，超过时放弃本次查询
+	githubMaxRateLimitWait = 2 * time.Minute
+)
+
 // GitHub GitHub API 搜索模块
 type GitHub struct {
 	*core.Search
 	searchURL string
 	apiToken  string
 	delay     time.Duration
+	sleep     func(time.Duration)
 }
 
 // GitHubResponse GitHub API 响应结构
 type GitHubResponse struct {
-	TotalCount int `json:"total_count"`
-	Items      []struct {
-		Path string `json:"path"`
-		URL  string `json:"url"`
-	} `json:"items"`
+	TotalCount int              `json:"total_count"`
+	Items      []GitHubCodeItem `json:"items"`
+}
+
+// GitHubCodeItem 代码搜索结果中的单个文件
+type GitHubCodeItem struct {
+	Path        string `json:"path"`
+	URL         string `json:"url"` // contents API 地址，以 raw 格式请求得到文件内容
+	TextMatches []struct {
+		Fragment string `json:"fragment"`
+	} `json:"text_matches"`
 }
 
 // NewGitHub 创建 GitHub API 搜索模块
@@ -34,9 +56,15 @@ func NewGitHub(cfg *config.Config) *GitHub {
 		searchURL: "https://api.github.com/search/code",
 		apiToken:  cfg.APIKeys["github_api_token"],
 		delay:     5 * time.Second,
+		sleep:     time.Sleep,
 	}
 }
 
+// RequiresAPIKey 未配置 github_api_token 时不发送请求
+func (g *GitHub) RequiresAPIKey() bool {
+	return true
+}
+
 // Run 执行搜索
 func (g *GitHub) Run(domain string) ([]string, error) {
 	g.SetDomain(domain)
@@ -56,79 +84,143 @@ func (g *GitHub) Run(domain string) ([]string, error) {
 	return g.GetSubdomains(), nil
 }
 
-// search 执行搜索
+// search 分页搜索代码，从文件路径、匹配片段和完整文件内容中提取子域名
 func (g *GitHub) search(domain string) error {
-	page := 1
-
 	// 设置请求头
-	g.SetHeader("Accept", "application/vnd.github.v3.text-match+json")
 	g.SetHeader("Authorization", "token "+g.apiToken)
 	g.SetHeader("User-Agent", g.GetRandomUserAgent())
 
-	for {
-		// 延迟
-		time.Sleep(g.delay)
+	fetched := make(map[string]bool)
+	for page := 1; page <= githubMaxPages; page++ {
+		// 代码搜索的速率限制很严格，翻页之间保持间隔
+		if page > 1 {
+			g.sleep(g.delay)
+		}
 
 		// 构建查询参数
 		params := url.Values{}
 		params.Set("q", domain)
-		params.Set("per_page", "100")
-		params.Set("page", fmt.Sprintf("%d", page))
+		params.Set("per_page", strconv.Itoa(githubPerPage))
+		params.Set("page", strconv.Itoa(page))
 		params.Set("sort", "indexed")
-		params.Set("access_token", g.apiToken)
 
-		// 发送搜索请求
-		searchURL := fmt.Sprintf("%s?%s", g.searchURL, params.Encode())
-		resp, err := g.HTTPGet(searchURL, g.GetHeader())
+		// 发送搜索请求，第一页失败时返回错误，后续页失败时保留已有结果
+		body, err := g.get(fmt.Sprintf("%s?%s", g.searchURL, params.Encode()), "application/vnd.github.v3.text-match+json")
 		if err != nil {
-			g.LogError("Failed to query GitHub API: %v", err)
-			break
-		}
-
-		// 检查响应状态
-		if resp == nil || resp.StatusCode != 200 {
-			g.LogError("GitHub API query failed with status: %d", resp.StatusCode)
-			break
-		}
-
-		// 读取响应
-		body, err := g.ReadResponseBody(resp)
-		if err != nil {
-			g.LogError("Failed to read response: %v", err)
+			if page == 1 {
+				return err
+			}
+			g.LogError("Failed to query page %d: %v", page, err)
 			break
 		}
 
 		// 解析 JSON 响应
 		var response GitHubResponse
 		if err := json.Unmarshal([]byte(body), &response); err != nil {
+			if page == 1 {
+				return fmt.Errorf("failed to parse JSON response: %v", err)
+			}
 			g.LogError("Failed to parse JSON response: %v", err)
 			break
 		}
 
-		// 提取子域名
-		subdomains := g.ExtractSubdomains(body, domain)
-		if len(subdomains) == 0 {
+		for _, item := range response.Items {
+			g.addSubdomains(item.Path, domain)
+			for _, match := range item.TextMatches {
+				g.addSubdomains(match.Fragment, domain)
+			}
+
+			// 匹配片段只包含命中的几行，拉取完整文件内容
+			if item.URL == "" || fetched[item.URL] || len(fetched) >= githubMaxContentFiles {
+				continue
+			}
+			fetched[item.URL] = true
+			content, err := g.get(item.URL, "application/vnd.github.raw")
+			if err != nil {
+				g.LogDebug("Failed to fetch content of %s: %v", item.Path, err)
+				continue
+			}
+			g.addSubdomains(content, domain)
+		}
+
+		// 检查是否还有更多结果
+		if len(response.Items) == 0 || page*githubPerPage >= response.TotalCount {
 			break
 		}
+	}
+
+	g.LogInfo("Scanned %d files for %s", len(fetched), domain)
+	return nil
+}
 
-		// 添加子域名
-		for _, subdomain := range subdomains {
-			g.AddSubdomain(subdomain)
+// addSubdomains 提取文本中属于目标的子域名
+func (g *GitHub) addSubdomains(text, domain string) {
+	for _, subdomain := range g.ExtractSubdomains(text, domain) {
+		g.AddSubdomain(subdomain)
+	}
+}
+
+// get 发送 GET 请求并返回响应体，触发速率限制时等待到 X-RateLimit-Reset 后重试；
+// 额度用尽但请求成功时，同样等待重置后再返回，避免下一个请求被拒绝
+func (g *GitHub) get(queryURL, accept string) (string, error) {
+	headers := g.GetHeader()
+	headers["Accept"] = accept
+
+	for attempt := 0; ; attempt++ {
+		resp, err := g.HTTPGet(queryURL, headers)
+		if err != nil {
+			return "", fmt.Errorf("failed to query GitHub API: %v", err)
 		}
 
-		page++
+		body, err := g.ReadResponseBody(resp)
+		if err != nil {
+			return "", fmt.Errorf("failed to read response: %v", err)
+		}
 
-		// 检查是否还有更多结果
-		totalCount := response.TotalCount
-		if page*100 > totalCount {
-			break
+		wait, limited := githubRateLimitWait(resp.Header, time.Now())
+		if resp.StatusCode == http.StatusOK {
+			if limited && wait <= githubMaxRateLimitWait {
+				g.LogInfo("GitHub API quota exhausted, waiting %v for reset", wait.Round(time.Second))
+				g.sleep(wait)
+			}
+			return body, nil
 		}
 
-		// 限制最大查询页数
-		if page*100 > 1000 {
-			break
+		rateLimited := limited && (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests)
+		if !rateLimited {
+			return "", fmt.Errorf("GitHub API returned status %d", resp.StatusCode)
 		}
+		if attempt >= githubMaxRateLimitRetries {
+			return "", fmt.Errorf("GitHub API rate limit still exceeded after %d retries", attempt)
+		}
+		if wait > githubMaxRateLimitWait {
+			return "", fmt.Errorf("GitHub API rate limit exceeded, resets in %v", wait.Round(time.Second))
+		}
+
+		g.LogInfo("GitHub API rate limit exceeded, waiting %v for reset", wait.Round(time.Second))
+		g.sleep(wait)
 	}
+}
 
-	return nil
+// githubRateLimitWait 根据响应头计算需要等待的时间：优先使用 Retry-After（次级速率限制），
+// 否则在 X-RateLimit-Remaining 为 0 时等待到 X-RateLimit-Reset；未触发限制时 limited 为 false
+func githubRateLimitWait(header http.Header, now time.Time) (wait time.Duration, limited bool) {
+	if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if header.Get("X-RateLimit-Remaining") != "0" {
+		return 0, false
+	}
+	reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	// 多等一秒，避免本地时钟略快于 GitHub
+	wait = time.Unix(reset, 0).Sub(now) + time.Second
+	if wait < time.Second {
+		wait = time.Second
+	}
+	return wait, true
 }
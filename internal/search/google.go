@@ -33,27 +33,11 @@ func (g *Google) Run(domain string) ([]string, error) {
 	g.Begin()
 	defer g.Finish()
 
-	// 执行搜索
-	if err := g.search(domain, ""); err != nil {
+	// 执行搜索，包括过滤常见子域名和递归搜索下一层子域名
+	if err := g.SearchRounds(domain, g.search); err != nil {
 		return nil, err
 	}
 
-	// 排除同一子域搜索结果过多的子域以发现新的子域
-	for _, statement := range g.Filter(domain, g.GetSubdomains()) {
-		if err := g.search(domain, statement); err != nil {
-			g.LogError("Failed to search with filter %s: %v", statement, err)
-		}
-	}
-
-	// 递归搜索下一层的子域
-	if g.IsRecursiveSearch() {
-		for _, subdomain := range g.RecursiveSubdomain() {
-			if err := g.search(subdomain, ""); err != nil {
-				g.LogError("Failed to search subdomain %s: %v", subdomain, err)
-			}
-		}
-	}
-
 	return g.GetSubdomains(), nil
 }
 
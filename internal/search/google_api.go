@@ -39,6 +39,11 @@ func NewGoogleAPI(cfg *config.Config) *GoogleAPI {
 	}
 }
 
+// RequiresAPIKey 未配置 google_api_id、google_api_key 时不发送请求
+func (g *GoogleAPI) RequiresAPIKey() bool {
+	return true
+}
+
 // Run 执行搜索
 func (g *GoogleAPI) Run(domain string) ([]string, error) {
 	g.SetDomain(domain)
@@ -50,27 +55,11 @@ func (g *GoogleAPI) Run(domain string) ([]string, error) {
 		return nil, fmt.Errorf("google API keys not configured")
 	}
 
-	// 执行搜索
-	if err := g.search(domain, ""); err != nil {
+	// 执行搜索，包括过滤常见子域名和递归搜索下一层子域名
+	if err := g.SearchRounds(domain, g.search); err != nil {
 		return nil, err
 	}
 
-	// 排除同一子域搜索结果过多的子域以发现新的子域
-	for _, statement := range g.Filter(domain, g.GetSubdomains()) {
-		if err := g.search(domain, statement); err != nil {
-			g.LogError("Failed to search with filter %s: %v", statement, err)
-		}
-	}
-
-	// 递归搜索下一层的子域
-	if g.IsRecursiveSearch() {
-		for _, subdomain := range g.RecursiveSubdomain() {
-			if err := g.search(subdomain, ""); err != nil {
-				g.LogError("Failed to search subdomain %s: %v", subdomain, err)
-			}
-		}
-	}
-
 	return g.GetSubdomains(), nil
 }
 
@@ -36,6 +36,11 @@ func NewHunter(cfg *config.Config) *Hunter {
 	}
 }
 
+// RequiresAPIKey 未配置 hunter_api_key 时不发送请求
+func (h *Hunter) RequiresAPIKey() bool {
+	return true
+}
+
 // Run 执行搜索
 func (h *Hunter) Run(domain string) ([]string, error) {
 	h.SetDomain(domain)
@@ -43,6 +43,11 @@ func NewQuake(cfg *config.Config) *Quake {
 	}
 }
 
+// RequiresAPIKey 未配置 quake_api_key 时不发送请求
+func (q *Quake) RequiresAPIKey() bool {
+	return true
+}
+
 // Run 执行搜索
 func (q *Quake) Run(domain string) ([]string, error) {
 	q.SetDomain(domain)
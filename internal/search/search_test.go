@@ -0,0 +1,334 @@
+package search
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oneforall-go/internal/config"
+	"github.com/oneforall-go/internal/core"
+)
+
+// GitHub 代码搜索接口的响应（取自真实响应，删减了无关字段），{base} 替换为测试服务器地址
+const githubSearchPage1 = `{
+  "total_count": 150,
+  "incomplete_results": false,
+  "items": [
+    {
+      "name": "settings.py",
+      "path": "deploy/staging.example.com/settings.py",
+      "sha": "5b1b4c2e9d8f0a7c3e6b1d2f4a5c6e7f8a9b0c1d",
+      "url": "{base}/repositories/1296269/contents/deploy/staging.example.com/settings.py?ref=5b1b4c2",
+      "html_url": "https://github.com/octocat/Hello-World/blob/5b1b4c2/deploy/staging.example.com/settings.py",
+      "score": 1.0,
+      "text_matches": [
+        {
+          "object_type": "FileContent",
+          "property": "content",
+          "fragment": "API_HOST = \"https://api.example.com/v1\"\n",
+          "matches": [{"text": "example.com", "indices": [22, 33]}]
+        }
+      ]
+    }
+  ]
+}`
+
+const githubSearchPage2 = `{
+  "total_count": 150,
+  "incomplete_results": false,
+  "items": [
+    {
+      "name": "README.md",
+      "path": "README.md",
+      "url": "{base}/repositories/1296269/contents/README.md?ref=7fd1a60",
+      "text_matches": []
+    }
+  ]
+}`
+
+// settingsContent 文件的完整内容，只有拉取原始内容才能发现 db.internal.example.com
+const settingsContent = `API_HOST = "https://api.example.com/v1"
+# 内网地址
+DB_HOST = "db.internal.example.com"
+CDN = "static.example.com.evil.net"
+`
+
+func TestGitHubSearchScansFileContents(t *testing.T) {
+	var searches, contents int32
+	var authorized int32 = 1
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token test-token" {
+			atomic.StoreInt32(&authorized, 0)
+		}
+		switch {
+		case r.URL.Path == "/search/code":
+			atomic.AddInt32(&searches, 1)
+			body := githubSearchPage1
+			if r.URL.Query().Get("page") == "2" {
+				body = githubSearchPage2
+			}
+			w.Write([]byte(strings.ReplaceAll(body, "{base}", server.URL)))
+		case strings.HasSuffix(r.URL.Path, "/settings.py"):
+			atomic.AddInt32(&contents, 1)
+			if r.Header.Get("Accept") != "application/vnd.github.raw" {
+				t.Errorf("Expected raw Accept header for contents, got %q", r.Header.Get("Accept"))
+			}
+			w.Write([]byte(settingsContent))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	g := newTestGitHub(server.URL)
+	subdomains, err := g.Run("example.com")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	sort.Strings(subdomains)
+	expected := []string{"api.example.com", "db.internal.example.com", "staging.example.com"}
+	if strings.Join(subdomains, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, subdomains)
+	}
+	// total_count 为 150，每页 100 条，应请求两页
+	if n := atomic.LoadInt32(&searches); n != 2 {
+		t.Errorf("Expected 2 search requests, got %d", n)
+	}
+	if n := atomic.LoadInt32(&contents); n != 1 {
+		t.Errorf("Expected 1 content request, got %d", n)
+	}
+	if atomic.LoadInt32(&authorized) != 1 {
+		t.Error("Expected every request to carry the token")
+	}
+}
+
+func TestGitHubSearchWaitsForRateLimitReset(t *testing.T) {
+	var searches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search/code" {
+			http.NotFound(w, r)
+			return
+		}
+		// 第一次请求返回 GitHub 的速率限制响应
+		if atomic.AddInt32(&searches, 1) == 1 {
+			w.Header().Set("X-RateLimit-Limit", "30")
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(20*time.Second).Unix(), 10))
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"API rate limit exceeded for user ID 1.","documentation_url":"https://docs.github.com/rest/overview/resources-in-the-rest-api#rate-limiting"}`))
+			return
+		}
+		w.Write([]byte(`{"total_count":1,"items":[{"path":"hosts/vpn.example.com/client.ovpn"}]}`))
+	}))
+	defer server.Close()
+
+	g := newTestGitHub(server.URL)
+	var waits []time.Duration
+	g.sleep = func(d time.Duration) { waits = append(waits, d) }
+
+	subdomains, err := g.Run("example.com")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(subdomains) != 1 || subdomains[0] != "vpn.example.com" {
+		t.Errorf("Expected [vpn.example.com] after retry, got %v", subdomains)
+	}
+	if len(waits) != 1 || waits[0] < 15*time.Second || waits[0] > 25*time.Second {
+		t.Errorf("Expected one wait of about 20s until reset, got %v", waits)
+	}
+
+	// 重置时间过远时放弃，而不是长时间阻塞
+	header := http.Header{}
+	header.Set("X-RateLimit-Remaining", "0")
+	header.Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+	if wait, limited := githubRateLimitWait(header, time.Now()); !limited || wait <= githubMaxRateLimitWait {
+		t.Errorf("Expected a long wait beyond the cap, got %v (limited=%v)", wait, limited)
+	}
+}
+
+// newTestGitHub 创建指向测试服务器、不等待的 GitHub 模块
+func newTestGitHub(baseURL string) *GitHub {
+	cfg := &config.Config{APIKeys: map[string]string{"github_api_token": "test-token"}}
+	g := NewGitHub(cfg)
+	g.searchURL = fmt.Sprintf("%s/search/code", baseURL)
+	g.delay = 0
+	g.sleep = func(time.Duration) {}
+	g.SetDelay(0)
+	return g
+}
+
+// Shodan DNS 域名接口的响应（取自真实响应，删减了部分记录）
+const shodanDNSPage1 = `{
+  "domain": "example.com",
+  "tags": ["ipv6"],
+  "data": [
+    {"subdomain": "", "type": "MX", "value": "mx1.mail.example.com", "last_seen": "2024-05-01T10:21:33.000000"},
+    {"subdomain": "www", "type": "A", "value": "93.184.216.34", "last_seen": "2024-05-02T08:01:12.000000"},
+    {"subdomain": "shop", "type": "CNAME", "value": "shops.myshopify.com", "last_seen": "2024-04-28T17:45:09.000000"}
+  ],
+  "subdomains": ["www", "shop"],
+  "more": true
+}`
+
+const shodanDNSPage2 = `{
+  "domain": "example.com",
+  "data": [
+    {"subdomain": "vpn", "type": "A", "value": "198.51.100.7", "last_seen": "2024-03-11T02:13:40.000000"}
+  ],
+  "subdomains": ["vpn"],
+  "more": false
+}`
+
+// Shodan 主机搜索 hostname:example.com 的响应（删减了 banner 字段）
+const shodanHostSearch = `{
+  "matches": [
+    {
+      "ip_str": "93.184.216.34",
+      "port": 443,
+      "hostnames": ["www.example.com", "origin.example.com", "example.org"],
+      "domains": ["example.com", "example.org"],
+      "http": {"host": "staging.example.com", "status": 200, "title": "Staging"}
+    },
+    {
+      "ip_str": "198.51.100.7",
+      "port": 22,
+      "hostnames": ["vpn.example.com"],
+      "domains": ["example.com"]
+    }
+  ],
+  "total": 2
+}`
+
+func TestShodanMergesDNSAndHostSearch(t *testing.T) {
+	var searches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("key") != "test-key" {
+			t.Errorf("Expected API key on %s", r.URL.Path)
+		}
+		switch r.URL.Path {
+		case "/dns/domain/example.com":
+			if r.URL.Query().Get("page") == "2" {
+				w.Write([]byte(shodanDNSPage2))
+				return
+			}
+			w.Write([]byte(shodanDNSPage1))
+		case "/shodan/host/search":
+			if r.URL.Query().Get("query") != "hostname:example.com" {
+				t.Errorf("Unexpected host search query %q", r.URL.Query().Get("query"))
+			}
+			// 第一次请求超出速率限制，退避后重试
+			if atomic.AddInt32(&searches, 1) == 1 {
+				w.WriteHeader(http.StatusTooManyRequests)
+				w.Write([]byte(`{"error": "Rate limit reached (1/s)"}`))
+				return
+			}
+			w.Write([]byte(shodanHostSearch))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	s := newTestShodan(server.URL)
+	subdomains, err := s.Run("example.com")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	sort.Strings(subdomains)
+	expected := []string{"mx1.mail.example.com", "origin.example.com", "shop.example.com", "staging.example.com", "vpn.example.com", "www.example.com"}
+	if strings.Join(subdomains, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, subdomains)
+	}
+	if n := atomic.LoadInt32(&searches); n != 2 {
+		t.Errorf("Expected host search to be retried once after 429, got %d requests", n)
+	}
+	// 每秒速率限制不是额度耗尽
+	if reason, exhausted := s.QuotaExhausted(); exhausted {
+		t.Errorf("Expected rate limiting not to exhaust the quota, got %q", reason)
+	}
+}
+
+func TestShodanQuotaExhaustedDisablesModule(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusPaymentRequired)
+		w.Write([]byte(`{"error": "Insufficient query credits, please upgrade your API plan or wait for the monthly limit to reset"}`))
+	}))
+	defer server.Close()
+
+	s := newTestShodan(server.URL)
+	if _, err := s.Run("example.com"); err == nil {
+		t.Fatal("Expected Run to fail when the quota is exhausted")
+	}
+	// 402 不重试，DNS 接口耗尽额度后主机搜索不再发送请求
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("Expected a single request before the module is disabled, got %d", n)
+	}
+	reason, exhausted := s.QuotaExhausted()
+	if !exhausted || !strings.Contains(reason, "Insufficient query credits") {
+		t.Errorf("Expected the quota to be exhausted with the API message, got %v %q", exhausted, reason)
+	}
+
+	// 同一次运行中的下一个域名不再请求 Shodan
+	s.Reset()
+	if _, err := s.Run("example.org"); err == nil {
+		t.Error("Expected Run to fail after the quota is exhausted")
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("Expected no further Shodan requests, got %d in total", n)
+	}
+
+	// 调度器跳过额度耗尽的模块
+	cfg := &config.Config{}
+	cfg.MultiThreading.EnableFastSearch = true
+	d := core.NewDispatcher(cfg)
+	d.RegisterModule(s)
+	if _, err := d.RunLib("example.net", nil); err != nil {
+		t.Fatalf("RunLib failed: %v", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("Expected the dispatcher to skip Shodan, got %d requests in total", n)
+	}
+}
+
+func TestShodanKeepsDNSResultsWhenHostSearchDenied(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/shodan/host/search" {
+			// 免费 API 密钥不能使用带过滤条件的主机搜索
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error": "Access denied (403 Forbidden)"}`))
+			return
+		}
+		w.Write([]byte(shodanDNSPage2))
+	}))
+	defer server.Close()
+
+	subdomains, err := newTestShodan(server.URL).Run("example.com")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(subdomains) != 1 || subdomains[0] != "vpn.example.com" {
+		t.Errorf("Expected [vpn.example.com], got %v", subdomains)
+	}
+}
+
+// newTestShodan 创建指向测试服务器、不等待的 Shodan 模块
+func newTestShodan(baseURL string) *Shodan {
+	cfg := &config.Config{APIKeys: map[string]string{"shodan_api_key": "test-key"}}
+	s := NewShodan(cfg)
+	s.dnsURL = baseURL + "/dns/domain/"
+	s.searchURL = baseURL + "/shodan/host/search"
+	s.SetDelay(0)
+	s.SetRetryPolicy(core.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond})
+	return s
+}
@@ -3,30 +3,73 @@ package search
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/oneforall-go/internal/config"
 	"github.com/oneforall-go/internal/core"
 )
 
-// Shodan Shodan API 搜索模块
+const (
+	// shodanMaxDNSPages DNS 域名接口的最大翻页数
+	shodanMaxDNSPages = 10
+	// shodanMaxSearchPages 主机搜索的最大翻页数，翻页（page > 1）每页消耗一次查询额度
+	shodanMaxSearchPages = 5
+	// shodanSearchPageSize 主机搜索每页固定返回的结果数
+	shodanSearchPageSize = 100
+)
+
+// Shodan Shodan API 搜索模块，合并 DNS 域名接口和主机搜索（hostname:domain）的结果
 type Shodan struct {
 	*core.Search
+	dnsURL    string
 	searchURL string
 	apiKey    string
 }
 
-// ShodanResponse Shodan API 响应结构
+// ShodanResponse Shodan DNS 域名接口响应结构
 type ShodanResponse struct {
 	Subdomains []string `json:"subdomains"`
+	Data       []struct {
+		Subdomain string `json:"subdomain"`
+		Type      string `json:"type"`
+		Value     string `json:"value"`
+	} `json:"data"`
+	More bool `json:"more"`
+}
+
+// ShodanHostSearchResponse Shodan 主机搜索接口响应结构
+type ShodanHostSearchResponse struct {
+	Total   int `json:"total"`
+	Matches []struct {
+		IP        string   `json:"ip_str"`
+		Hostnames []string `json:"hostnames"`
+		HTTP      *struct {
+			Host string `json:"host"`
+		} `json:"http"`
+	} `json:"matches"`
 }
 
 // NewShodan 创建 Shodan API 搜索模块
 func NewShodan(cfg *config.Config) *Shodan {
-	return &Shodan{
+	s := &Shodan{
 		Search:    core.NewSearch("ShodanAPISearch", cfg),
-		searchURL: "https://api.shodan.io/dns/domain/",
+		dnsURL:    "https://api.shodan.io/dns/domain/",
+		searchURL: "https://api.shodan.io/shodan/host/search",
 		apiKey:    cfg.APIKeys["shodan_api_key"],
 	}
+	// Shodan 按套餐限制每秒一次请求（HTTPGet 的请求间隔已满足），
+	// 超出限制时返回 429 且不带 Retry-After，按指数退避多重试几次
+	s.SetRetryPolicy(core.RetryPolicy{MaxAttempts: 5, BaseDelay: 2 * time.Second, MaxDelay: 30 * time.Second})
+	return s
+}
+
+// RequiresAPIKey 未配置 shodan_api_key 时不发送请求
+func (s *Shodan) RequiresAPIKey() bool {
+	return true
 }
 
 // Run 执行搜索
@@ -40,47 +83,131 @@ func (s *Shodan) Run(domain string) ([]string, error) {
 		return nil, fmt.Errorf("shodan_api_key not configured")
 	}
 
-	// 执行搜索
-	if err := s.search(domain); err != nil {
-		return nil, err
+	// 设置请求头
+	s.SetHeader("User-Agent", s.GetRandomUserAgent())
+
+	// 两个接口互为补充，只有都失败时才返回错误
+	dnsErr := s.searchDNS(domain)
+	if dnsErr != nil {
+		s.LogError("DNS domain query failed: %v", dnsErr)
+	}
+	hostErr := s.searchHosts(domain)
+	if hostErr != nil {
+		// 主机搜索需要付费套餐，免费 API 密钥会返回 403
+		s.LogError("Host search failed: %v", hostErr)
+	}
+	if dnsErr != nil && hostErr != nil {
+		return nil, fmt.Errorf("shodan DNS query failed: %v; host search failed: %v", dnsErr, hostErr)
 	}
 
 	return s.GetSubdomains(), nil
 }
 
-// search 执行搜索
-func (s *Shodan) search(domain string) error {
-	// 设置请求头
-	s.SetHeader("User-Agent", s.GetRandomUserAgent())
+// searchDNS 分页查询 DNS 域名接口，合并子域名列表和记录值中的子域名
+func (s *Shodan) searchDNS(domain string) error {
+	for page := 1; page <= shodanMaxDNSPages; page++ {
+		params := url.Values{}
+		params.Set("key", s.apiKey)
+		params.Set("page", strconv.Itoa(page))
+		body, err := s.get(fmt.Sprintf("%s%s?%s", s.dnsURL, domain, params.Encode()))
+		if err != nil {
+			if page == 1 {
+				return err
+			}
+			s.LogError("Failed to query DNS page %d: %v", page, err)
+			return nil
+		}
 
-	// 构建查询 URL
-	queryURL := fmt.Sprintf("%s%s?key=%s", s.searchURL, domain, s.apiKey)
+		var response ShodanResponse
+		if err := json.Unmarshal([]byte(body), &response); err != nil {
+			return fmt.Errorf("failed to parse JSON response: %v", err)
+		}
 
-	// 发送 GET 请求
+		for _, subdomain := range response.Subdomains {
+			s.addHost(fmt.Sprintf("%s.%s", subdomain, domain), domain)
+		}
+		// CNAME/MX/NS 等记录的值也可能是目标的子域名
+		for _, record := range response.Data {
+			s.addHost(record.Value, domain)
+		}
+
+		if !response.More {
+			return nil
+		}
+	}
+	return nil
+}
+
+// searchHosts 分页执行主机搜索 hostname:domain，合并匹配主机上的所有主机名
+func (s *Shodan) searchHosts(domain string) error {
+	hosts := 0
+	for page := 1; page <= shodanMaxSearchPages; page++ {
+		params := url.Values{}
+		params.Set("key", s.apiKey)
+		params.Set("query", "hostname:"+domain)
+		params.Set("page", strconv.Itoa(page))
+		body, err := s.get(fmt.Sprintf("%s?%s", s.searchURL, params.Encode()))
+		if err != nil {
+			if page == 1 {
+				return err
+			}
+			s.LogError("Failed to query host search page %d: %v", page, err)
+			break
+		}
+
+		var response ShodanHostSearchResponse
+		if err := json.Unmarshal([]byte(body), &response); err != nil {
+			return fmt.Errorf("failed to parse JSON response: %v", err)
+		}
+
+		for _, match := range response.Matches {
+			hosts++
+			for _, hostname := range match.Hostnames {
+				s.addHost(hostname, domain)
+			}
+			if match.HTTP != nil {
+				s.addHost(match.HTTP.Host, domain)
+			}
+		}
+
+		if len(response.Matches) == 0 || page*shodanSearchPageSize >= response.Total {
+			break
+		}
+	}
+
+	s.LogInfo("Host search matched %d hosts for %s", hosts, domain)
+	return nil
+}
+
+// addHost 添加属于目标的主机名
+func (s *Shodan) addHost(host, domain string) {
+	host = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(host), "."))
+	if host != "" && s.IsValidSubdomain(host, domain) {
+		s.AddSubdomain(host)
+	}
+}
+
+// get 发送 GET 请求并返回响应体，429 由重试策略退避重试
+func (s *Shodan) get(queryURL string) (string, error) {
 	resp, err := s.HTTPGet(queryURL, s.GetHeader())
 	if err != nil {
-		return fmt.Errorf("failed to query Shodan API: %v", err)
+		return "", fmt.Errorf("failed to query Shodan API: %v", err)
 	}
 
-	// 读取响应
 	body, err := s.ReadResponseBody(resp)
 	if err != nil {
-		return fmt.Errorf("failed to read response: %v", err)
+		return "", fmt.Errorf("failed to read response: %v", err)
 	}
 
-	// 解析 JSON 响应
-	var response ShodanResponse
-	if err := json.Unmarshal([]byte(body), &response); err != nil {
-		return fmt.Errorf("failed to parse JSON response: %v", err)
-	}
-
-	// 处理子域名
-	for _, subdomain := range response.Subdomains {
-		fullSubdomain := fmt.Sprintf("%s.%s", subdomain, domain)
-		if s.IsValidSubdomain(fullSubdomain, domain) {
-			s.AddSubdomain(fullSubdomain)
+	if resp.StatusCode != http.StatusOK {
+		// 错误响应形如 {"error": "Access denied"}
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal([]byte(body), &apiErr) == nil && apiErr.Error != "" {
+			return "", fmt.Errorf("Shodan API returned status %d: %s", resp.StatusCode, apiErr.Error)
 		}
+		return "", fmt.Errorf("Shodan API returned status %d", resp.StatusCode)
 	}
-
-	return nil
+	return body, nil
 }
@@ -33,27 +33,11 @@ func (s *Sogou) Run(domain string) ([]string, error) {
 	s.Begin()
 	defer s.Finish()
 
-	// 执行搜索
-	if err := s.search(domain, ""); err != nil {
+	// 执行搜索，包括过滤常见子域名和递归搜索下一层子域名
+	if err := s.SearchRounds(domain, s.search); err != nil {
 		return nil, err
 	}
 
-	// 排除同一子域搜索结果过多的子域以发现新的子域
-	for _, statement := range s.Filter(domain, s.GetSubdomains()) {
-		if err := s.search(domain, statement); err != nil {
-			s.LogError("Failed to search with filter %s: %v", statement, err)
-		}
-	}
-
-	// 递归搜索下一层的子域
-	if s.IsRecursiveSearch() {
-		for _, subdomain := range s.RecursiveSubdomain() {
-			if err := s.search(subdomain, ""); err != nil {
-				s.LogError("Failed to search subdomain %s: %v", subdomain, err)
-			}
-		}
-	}
-
 	return s.GetSubdomains(), nil
 }
 
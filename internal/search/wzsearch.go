@@ -31,27 +31,11 @@ func (w *WZSearch) Run(domain string) ([]string, error) {
 	w.Begin()
 	defer w.Finish()
 
-	// 执行搜索
-	if err := w.search(domain, ""); err != nil {
+	// 执行搜索，包括过滤常见子域名和递归搜索下一层子域名
+	if err := w.SearchRounds(domain, w.search); err != nil {
 		return nil, err
 	}
 
-	// 排除同一子域搜索结果过多的子域以发现新的子域
-	for _, statement := range w.Filter(domain, w.GetSubdomains()) {
-		if err := w.search(domain, statement); err != nil {
-			w.LogError("Failed to search with filter %s: %v", statement, err)
-		}
-	}
-
-	// 递归搜索下一层的子域
-	if w.IsRecursiveSearch() {
-		for _, subdomain := range w.RecursiveSubdomain() {
-			if err := w.search(subdomain, ""); err != nil {
-				w.LogError("Failed to search subdomain %s: %v", subdomain, err)
-			}
-		}
-	}
-
 	return w.GetSubdomains(), nil
 }
 
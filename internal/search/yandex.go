@@ -36,27 +36,11 @@ func (y *Yandex) Run(domain string) ([]string, error) {
 	y.Begin()
 	defer y.Finish()
 
-	// 执行搜索
-	if err := y.search(domain, ""); err != nil {
+	// 执行搜索，包括过滤常见子域名和递归搜索下一层子域名
+	if err := y.SearchRounds(domain, y.search); err != nil {
 		return nil, err
 	}
 
-	// 排除同一子域搜索结果过多的子域以发现新的子域
-	for _, statement := range y.Filter(domain, y.GetSubdomains()) {
-		if err := y.search(domain, statement); err != nil {
-			y.LogError("Failed to search with filter %s: %v", statement, err)
-		}
-	}
-
-	// 递归搜索下一层的子域
-	if y.IsRecursiveSearch() {
-		for _, subdomain := range y.RecursiveSubdomain() {
-			if err := y.search(subdomain, ""); err != nil {
-				y.LogError("Failed to search subdomain %s: %v", subdomain, err)
-			}
-		}
-	}
-
 	return y.GetSubdomains(), nil
 }
 
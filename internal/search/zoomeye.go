@@ -35,6 +35,11 @@ func NewZoomEye(cfg *config.Config) *ZoomEye {
 	}
 }
 
+// RequiresAPIKey 未配置 zoomeye_api_key 时不发送请求
+func (z *ZoomEye) RequiresAPIKey() bool {
+	return true
+}
+
 // Run 执行搜索
 func (z *ZoomEye) Run(domain string) ([]string, error) {
 	z.SetDomain(domain)
@@ -0,0 +1,246 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oneforall-go/internal/config"
+	"github.com/oneforall-go/pkg/api"
+	"github.com/oneforall-go/pkg/logger"
+)
+
+// shutdownTimeout 优雅关闭等待时间
+const shutdownTimeout = 30 * time.Second
+
+// defaultProgressInterval SSE 进度事件的发送间隔
+const defaultProgressInterval = 5 * time.Second
+
+// Enumerator 子域名枚举接口，ctx 取消（如客户端断开）后应尽快停止
+type Enumerator interface {
+	RunSubdomainEnumerationStreamContext(ctx context.Context, options api.Options, onResult func(api.SubdomainResult)) (*api.Result, error)
+}
+
+// Server HTTP 服务模式
+type Server struct {
+	addr             string
+	newEnumerator    func() Enumerator
+	progressInterval time.Duration
+}
+
+// Progress SSE 进度事件内容
+type Progress struct {
+	Target         string  `json:"target"`
+	Found          int     `json:"found"`           // 已推送的子域名数
+	ElapsedSeconds float64 `json:"elapsed_seconds"` // 已运行时间（秒）
+}
+
+// NewServer 创建 HTTP 服务，每个请求使用独立的 API 实例（各自持有配置副本），日志由服务统一管理
+func NewServer(addr string) *Server {
+	// 提前加载全局配置，避免并发请求同时触发懒加载
+	config.GetConfig()
+	return &Server{
+		addr: addr,
+		newEnumerator: func() Enumerator {
+			enumerator := api.NewOneForAllAPI()
+			enumerator.KeepLogger()
+			return enumerator
+		},
+		progressInterval: defaultProgressInterval,
+	}
+}
+
+// Handler 返回服务路由
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/enumerate", s.handleEnumerate)
+	mux.HandleFunc("/stream", s.handleStream)
+	return mux
+}
+
+// ListenAndServe 启动服务，ctx 取消后优雅关闭并等待进行中的请求完成
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	httpServer := &http.Server{
+		Addr:              s.addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		logger.Infof("Server listening on %s", s.addr)
+		errCh <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("server stopped: %v", err)
+	case <-ctx.Done():
+	}
+
+	logger.Info("Shutting down server...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shutdown server: %v", err)
+	}
+	if err := <-errCh; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("server stopped: %v", err)
+	}
+
+	logger.Info("Server stopped")
+	return nil
+}
+
+// handleEnumerate 处理 POST /enumerate，请求体为 api.Options，返回 api.Result
+// 请求头 Accept: text/event-stream 时以 SSE 实时推送发现的子域名
+func (s *Server) handleEnumerate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	// 未指定的字段使用默认配置
+	options := api.GetDefaultOptions()
+	if err := json.NewDecoder(r.Body).Decode(&options); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if options.Target == "" {
+		writeError(w, http.StatusBadRequest, "target domain is required")
+		return
+	}
+	// 远程调用方不能指定服务器上的本地目录
+	if options.DataDir != "" {
+		writeError(w, http.StatusBadRequest, "data_dir is not allowed in server mode")
+		return
+	}
+
+	logger.Infof("Received enumeration request for %s from %s", options.Target, r.RemoteAddr)
+
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		s.streamEnumerate(w, r, options)
+		return
+	}
+
+	result, err := s.newEnumerator().RunSubdomainEnumerationStreamContext(r.Context(), options, nil)
+	if err != nil {
+		logger.Errorf("Enumeration for %s failed: %v", options.Target, err)
+		writeJSON(w, http.StatusInternalServerError, result)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleStream 处理 GET /stream?domain=...，使用默认选项枚举并以 SSE 实时推送结果，便于网页直接用 EventSource 订阅
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	domain := strings.TrimSpace(r.URL.Query().Get("domain"))
+	if domain == "" {
+		writeError(w, http.StatusBadRequest, "domain query parameter is required")
+		return
+	}
+
+	options := api.GetDefaultOptions()
+	options.Target = domain
+
+	logger.Infof("Received stream request for %s from %s", domain, r.RemoteAddr)
+	s.streamEnumerate(w, r, options)
+}
+
+// streamEnumerate 以 SSE 推送结果：每个子域名一个 result 事件，定期发送 progress 事件，结束时发送 done 事件
+// 客户端断开后请求上下文被取消，枚举随之停止
+func (s *Server) streamEnumerate(w http.ResponseWriter, r *http.Request, options api.Options) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	var mutex sync.Mutex
+	found := 0
+	send := func(event string, data interface{}) {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			logger.Errorf("Failed to marshal %s event: %v", event, err)
+			return
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		if event == "result" {
+			found++
+		}
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+		flusher.Flush()
+	}
+
+	ctx := r.Context()
+	start := time.Now()
+	stopProgress := make(chan struct{})
+	progressDone := make(chan struct{})
+	go func() {
+		defer close(progressDone)
+		ticker := time.NewTicker(s.progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				mutex.Lock()
+				progress := Progress{Target: options.Target, Found: found, ElapsedSeconds: time.Since(start).Seconds()}
+				mutex.Unlock()
+				send("progress", progress)
+			case <-stopProgress:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	result, err := s.newEnumerator().RunSubdomainEnumerationStreamContext(ctx, options, func(subdomain api.SubdomainResult) {
+		send("result", subdomain)
+	})
+	close(stopProgress)
+	<-progressDone
+
+	if ctx.Err() != nil {
+		logger.Infof("Client disconnected, stream for %s cancelled", options.Target)
+		return
+	}
+	if err != nil {
+		logger.Errorf("Enumeration for %s failed: %v", options.Target, err)
+	}
+	send("done", result)
+}
+
+// writeJSON 写入 JSON 响应
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		logger.Errorf("Failed to write response: %v", err)
+	}
+}
+
+// writeError 写入错误响应
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
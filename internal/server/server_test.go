@@ -0,0 +1,266 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oneforall-go/pkg/api"
+)
+
+// fakeEnumerator 固定返回结果的枚举器
+type fakeEnumerator struct {
+	options api.Options
+}
+
+func (f *fakeEnumerator) RunSubdomainEnumerationStreamContext(ctx context.Context, options api.Options, onResult func(api.SubdomainResult)) (*api.Result, error) {
+	f.options = options
+	results := []api.SubdomainResult{
+		{Subdomain: "www." + options.Target, Source: "search"},
+		{Subdomain: "api." + options.Target, Source: "search"},
+	}
+	if onResult != nil {
+		for _, result := range results {
+			onResult(result)
+		}
+	}
+	return &api.Result{Domain: options.Target, TotalSubdomains: len(results), Results: results}, nil
+}
+
+func newTestServer(fake *fakeEnumerator) *httptest.Server {
+	s := NewServer("")
+	s.newEnumerator = func() Enumerator { return fake }
+	return httptest.NewServer(s.Handler())
+}
+
+func TestEnumerate(t *testing.T) {
+	fake := &fakeEnumerator{}
+	ts := newTestServer(fake)
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/enumerate", "application/json", strings.NewReader(`{"target":"example.com","concurrency":3}`))
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	var result api.Result
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if result.Domain != "example.com" || result.TotalSubdomains != 2 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if fake.options.Concurrency != 3 {
+		t.Errorf("Expected concurrency 3, got %d", fake.options.Concurrency)
+	}
+	if !fake.options.EnableSearchModules {
+		t.Error("Expected unspecified options to keep defaults")
+	}
+}
+
+func TestEnumerateRejectsBadRequests(t *testing.T) {
+	ts := newTestServer(&fakeEnumerator{})
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/enumerate")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Post(ts.URL+"/enumerate", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for missing target, got %d", resp.StatusCode)
+	}
+}
+
+func TestEnumerateStream(t *testing.T) {
+	ts := newTestServer(&fakeEnumerator{})
+	defer ts.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/enumerate", strings.NewReader(`{"target":"example.com"}`))
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected text/event-stream, got %s", ct)
+	}
+
+	var events []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, "event: ") {
+			events = append(events, strings.TrimPrefix(line, "event: "))
+		}
+	}
+
+	expected := []string{"result", "result", "done"}
+	if strings.Join(events, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected events %v, got %v", expected, events)
+	}
+}
+
+// slowEnumerator 持续推送结果直到上下文取消，用于验证客户端断开后的取消
+type slowEnumerator struct {
+	target    chan string
+	cancelled chan struct{}
+}
+
+func (e *slowEnumerator) RunSubdomainEnumerationStreamContext(ctx context.Context, options api.Options, onResult func(api.SubdomainResult)) (*api.Result, error) {
+	e.target <- options.Target
+	for i := 0; ; i++ {
+		select {
+		case <-ctx.Done():
+			close(e.cancelled)
+			return &api.Result{Domain: options.Target}, ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+			onResult(api.SubdomainResult{Subdomain: fmt.Sprintf("host%d.%s", i, options.Target), Source: "search"})
+		}
+	}
+}
+
+func TestStreamCancelsOnDisconnect(t *testing.T) {
+	slow := &slowEnumerator{target: make(chan string, 1), cancelled: make(chan struct{})}
+	s := NewServer("")
+	s.newEnumerator = func() Enumerator { return slow }
+	s.progressInterval = 20 * time.Millisecond
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/stream?domain=example.com")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected text/event-stream, got %s", ct)
+	}
+	if target := <-slow.target; target != "example.com" {
+		t.Errorf("Expected target example.com, got %s", target)
+	}
+
+	// 读到若干 result 事件和至少一个 progress 事件后断开
+	results, progress := 0, 0
+	scanner := bufio.NewScanner(resp.Body)
+	var event string
+	for scanner.Scan() && (results < 3 || progress < 1) {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: ") && event == "result":
+			var result api.SubdomainResult
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &result); err != nil {
+				t.Fatalf("Invalid result event: %v", err)
+			}
+			if !strings.HasSuffix(result.Subdomain, ".example.com") {
+				t.Errorf("Unexpected subdomain %s", result.Subdomain)
+			}
+			results++
+		case strings.HasPrefix(line, "data: ") && event == "progress":
+			var p Progress
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &p); err != nil {
+				t.Fatalf("Invalid progress event: %v", err)
+			}
+			if p.Target != "example.com" {
+				t.Errorf("Expected progress for example.com, got %+v", p)
+			}
+			progress++
+		}
+	}
+	resp.Body.Close()
+
+	select {
+	case <-slow.cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected enumeration to be cancelled after client disconnected")
+	}
+}
+
+func TestStreamRequiresDomain(t *testing.T) {
+	ts := newTestServer(&fakeEnumerator{})
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/stream")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for missing domain, got %d", resp.StatusCode)
+	}
+}
+
+func TestEnumerateRejectsDataDir(t *testing.T) {
+	ts := newTestServer(&fakeEnumerator{})
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/enumerate", "application/json", strings.NewReader(`{"target":"example.com","data_dir":"/etc"}`))
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for data_dir, got %d", resp.StatusCode)
+	}
+}
+
+func TestEnumerateConcurrentRequests(t *testing.T) {
+	// 使用真实的 API 实例，关闭所有模块和验证，只验证并发请求互不影响（配合 -race 运行）
+	ts := httptest.NewServer(NewServer("").Handler())
+	defer ts.Close()
+
+	requests := map[string]string{
+		"a.example.com": "4",
+		"b.example.com": "6",
+	}
+
+	var wg sync.WaitGroup
+	for target, version := range requests {
+		wg.Add(1)
+		go func(target, version string) {
+			defer wg.Done()
+			body := fmt.Sprintf(`{"target":%q,"ip_version":%q,"passive":true,"enable_validation":false,"enable_brute_force":false,`+
+				`"enable_search_modules":false,"enable_dataset_modules":false,"enable_certificate_modules":false,"enable_crawl_modules":false,`+
+				`"enable_check_modules":false,"enable_intelligence_modules":false,"enable_enrich_modules":false}`, target, version)
+			resp, err := http.Post(ts.URL+"/enumerate", "application/json", strings.NewReader(body))
+			if err != nil {
+				t.Errorf("POST failed: %v", err)
+				return
+			}
+			defer resp.Body.Close()
+
+			var result api.Result
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				t.Errorf("decode: %v", err)
+				return
+			}
+			if resp.StatusCode != http.StatusOK || result.Domain != target {
+				t.Errorf("Expected 200 for %s, got %d: %+v", target, resp.StatusCode, result)
+			}
+		}(target, version)
+	}
+	wg.Wait()
+}
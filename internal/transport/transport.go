@@ -0,0 +1,76 @@
+package transport
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/oneforall-go/internal/config"
+)
+
+// defaultMaxIdleConnsPerHost 未配置时每个主机保留的空闲连接数
+const defaultMaxIdleConnsPerHost = 16
+
+// Pool 共享的 HTTP 连接池，所有模块和验证器复用同一组 Transport，避免每个模块单独建连
+type Pool struct {
+	// Insecure 不校验证书，检查模块、insecure_modules 中的模块和 HTTPS 存活探测使用
+	Insecure *http.Transport
+	// Secure 校验证书，其他模块请求使用
+	Secure *http.Transport
+}
+
+var (
+	defaultPool *Pool
+	defaultOnce sync.Once
+)
+
+// NewPool 按配置创建连接池
+func NewPool(cfg *config.Config) *Pool {
+	return &Pool{
+		Insecure: newTransport(cfg, true),
+		Secure:   newTransport(cfg, false),
+	}
+}
+
+// Default 返回进程级默认连接池，未经调度器注入的模块（如单独使用或测试）使用它
+func Default() *Pool {
+	defaultOnce.Do(func() {
+		defaultPool = NewPool(&config.Config{})
+	})
+	return defaultPool
+}
+
+// newTransport 创建调优后的 Transport
+func newTransport(cfg *config.Config, insecure bool) *http.Transport {
+	perHost := cfg.HTTPMaxIdleConnsPerHost
+	if perHost <= 0 {
+		perHost = defaultMaxIdleConnsPerHost
+	}
+
+	t := &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          perHost * 16,
+		MaxIdleConnsPerHost:   perHost,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	if insecure {
+		t.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return t
+}
+
+// WithProxy 复制 Transport 并设置代理，代理连接单独成池，不影响共享连接池
+func WithProxy(base *http.Transport, proxy *url.URL) *http.Transport {
+	t := base.Clone()
+	t.Proxy = http.ProxyURL(proxy)
+	return t
+}
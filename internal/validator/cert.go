@@ -0,0 +1,87 @@
+package validator
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/oneforall-go/pkg/logger"
+)
+
+// defaultCertTimeout 未配置 validation_timeout 时获取证书的超时
+const defaultCertTimeout = 10 * time.Second
+
+// inspectCertificate 通过 HTTPS 获取服务器证书，记录签发者、过期时间和证书中的 DNS 名称
+func (v *DomainValidator) inspectCertificate(host string, result *ValidationResult) {
+	cert, err := v.fetchCertificate(host)
+	if err != nil {
+		logger.Debugf("Failed to get certificate for %s: %v", host, err)
+		return
+	}
+
+	applyCertificate(result, cert.cert)
+	if result.TLSVersion == "" {
+		result.TLSVersion = cert.version
+	}
+	logger.Debugf("Certificate for %s issued by %q, expires %s, SANs: %v",
+		host, result.CertIssuer, result.CertExpiry, result.CertNames)
+}
+
+// peerCertificate 服务器证书和协商的 TLS 版本
+type peerCertificate struct {
+	cert    *x509.Certificate
+	version string
+}
+
+// fetchCertificate 请求 https://host 并从连接状态中取出服务器证书（不校验证书），不跟随跳转，
+// 证书始终属于 host 本身；HTTP 探测已拿到证书时不会调用
+func (v *DomainValidator) fetchCertificate(host string) (*peerCertificate, error) {
+	timeout := time.Duration(v.config.ValidationTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultCertTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("https://%s", host), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "OneForAll-Go/1.0")
+
+	client := *v.httpsClient
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	cert := firstHopCertificate(resp)
+	if cert == nil {
+		return nil, fmt.Errorf("no peer certificate")
+	}
+	return cert, nil
+}
+
+// applyCertificate 将证书信息写入验证结果，通配符名称（*.example.com）记录为其父域名
+func applyCertificate(result *ValidationResult, cert *x509.Certificate) {
+	result.CertIssuer = cert.Issuer.CommonName
+	if result.CertIssuer == "" {
+		result.CertIssuer = cert.Issuer.String()
+	}
+	result.CertExpiry = cert.NotAfter.UTC().Format("2006-01-02 15:04:05")
+
+	seen := map[string]bool{strings.ToLower(result.Subdomain): true}
+	for _, name := range cert.DNSNames {
+		name = strings.TrimPrefix(strings.ToLower(strings.TrimSuffix(strings.TrimSpace(name), ".")), "*.")
+		if name == "" || strings.Contains(name, "*") || seen[name] {
+			continue
+		}
+		seen[name] = true
+		result.CertNames = append(result.CertNames, name)
+	}
+}
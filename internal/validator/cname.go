@@ -0,0 +1,15 @@
+package validator
+
+import (
+	"time"
+
+	dnsutil "github.com/oneforall-go/internal/dns"
+)
+
+// cnameTimeout 单次 CNAME 查询超时
+const cnameTimeout = 3 * time.Second
+
+// resolveCNAMEChain 查询域名的完整 CNAME 指向链（不含域名本身）
+func (v *DomainValidator) resolveCNAMEChain(domain string) []string {
+	return dnsutil.ResolveCNAMEChain(domain, v.nameservers, cnameTimeout, v.config.DoTInsecureSkipVerify)
+}
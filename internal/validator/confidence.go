@@ -0,0 +1,58 @@
+package validator
+
+import "strings"
+
+// 置信度各项信号的权重，总和为 100。后面的信号都以 DNS 解析成功为前提，
+// 未解析的域名（包括被动模式下未验证的候选）置信度为 0
+const (
+	// ConfidenceDNS DNS 解析到 IP：域名确实存在，但可能是泛解析或已下线的主机
+	ConfidenceDNS = 30
+	// ConfidenceReachable ICMP/TCP 探测存活：解析到的 IP 上有在线主机
+	ConfidenceReachable = 20
+	// ConfidenceHTTP 有任何 HTTP 响应（包括 4xx/5xx）：主机上运行着 Web 服务
+	ConfidenceHTTP = 15
+	// ConfidenceHTTPSuccess HTTP 2xx/3xx 响应：Web 服务正常对外提供内容
+	ConfidenceHTTPSuccess = 15
+	// ConfidenceCertName HTTPS 证书的 SAN 包含该域名：服务确实为该域名配置，而不是共享 IP 上的默认站点
+	ConfidenceCertName = 20
+)
+
+// Confidence 根据通过的验证项计算 0-100 的置信度，表示该子域名真实存在且在线的可信程度
+func Confidence(result ValidationResult) int {
+	if !result.DNSResolved {
+		return 0
+	}
+
+	score := ConfidenceDNS
+	if result.PingAlive {
+		score += ConfidenceReachable
+	}
+	// 关闭 HTTP 请求时端口可达的状态码固定为 200 且没有最终 URL，不计为 HTTP 响应
+	if result.StatusCode > 0 && result.FinalURL != "" {
+		score += ConfidenceHTTP
+		if result.StatusCode < 400 {
+			score += ConfidenceHTTPSuccess
+		}
+	}
+	if certCovers(result.CertNames, result.Subdomain) {
+		score += ConfidenceCertName
+	}
+	return score
+}
+
+// certCovers 判断证书名称是否覆盖域名，支持单层通配符（*.example.com 覆盖 www.example.com）
+func certCovers(names []string, host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSuffix(name, "."))
+		if name == host {
+			return true
+		}
+		if suffix, ok := strings.CutPrefix(name, "*."); ok {
+			if label, rest, found := strings.Cut(host, "."); found && label != "" && rest == suffix {
+				return true
+			}
+		}
+	}
+	return false
+}
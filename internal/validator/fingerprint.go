@@ -0,0 +1,110 @@
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/oneforall-go/pkg/logger"
+)
+
+// fingerprintFile 数据目录下的 Web 技术指纹文件
+const fingerprintFile = "web_fingerprints.json"
+
+// WebFingerprint Web 技术指纹，任意一条响应头或响应体规则匹配即认为使用了该技术，均不区分大小写
+type WebFingerprint struct {
+	Name string `json:"name"`
+	// 响应头名称到应包含的值，值为空表示只要求存在该响应头
+	Headers map[string]string `json:"headers,omitempty"`
+	// 响应体（前 256KB）应包含的关键字
+	Body []string `json:"body,omitempty"`
+}
+
+// LoadFingerprints 加载 Web 技术指纹文件
+func LoadFingerprints(path string) ([]WebFingerprint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fingerprint file: %v", err)
+	}
+	return parseFingerprints(data, path)
+}
+
+// parseFingerprints 解析指纹文件内容，name 用于错误信息
+func parseFingerprints(data []byte, name string) ([]WebFingerprint, error) {
+	var fingerprints []WebFingerprint
+	if err := json.Unmarshal(data, &fingerprints); err != nil {
+		return nil, fmt.Errorf("failed to parse fingerprint file %s: %v", name, err)
+	}
+	return fingerprints, nil
+}
+
+// MatchFingerprints 返回响应匹配的技术名称，按名称排序
+func MatchFingerprints(fingerprints []WebFingerprint, header http.Header, body []byte) []string {
+	lowerBody := strings.ToLower(string(body))
+
+	var matched []string
+	for _, fingerprint := range fingerprints {
+		if fingerprint.matches(header, lowerBody) {
+			matched = append(matched, fingerprint.Name)
+		}
+	}
+	sort.Strings(matched)
+	return matched
+}
+
+// matches 判断响应是否匹配指纹，body 已转为小写
+func (f WebFingerprint) matches(header http.Header, body string) bool {
+	for name, want := range f.Headers {
+		values := header.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+		if want == "" {
+			return true
+		}
+		for _, value := range values {
+			if strings.Contains(strings.ToLower(value), strings.ToLower(want)) {
+				return true
+			}
+		}
+	}
+
+	for _, keyword := range f.Body {
+		if keyword != "" && strings.Contains(body, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+	return false
+}
+
+// webFingerprints 首次使用时从数据目录加载指纹文件，加载失败时只记录 Server 和 X-Powered-By
+func (v *DomainValidator) webFingerprints() []WebFingerprint {
+	v.fingerprintOnce.Do(func() {
+		data, err := v.config.ReadDataFile(fingerprintFile)
+		if err != nil {
+			logger.Warnf("Web fingerprinting without signatures: %v", err)
+			return
+		}
+		fingerprints, err := parseFingerprints(data, fingerprintFile)
+		if err != nil {
+			logger.Warnf("Web fingerprinting without signatures: %v", err)
+			return
+		}
+		v.fingerprints = fingerprints
+		logger.Debugf("Loaded %d web fingerprints from %s", len(fingerprints), fingerprintFile)
+	})
+	return v.fingerprints
+}
+
+// fingerprint 记录 Server、X-Powered-By 响应头和匹配的 Web 技术
+func (v *DomainValidator) fingerprint(page *httpPage, result *ValidationResult) {
+	result.Server = page.header.Get("Server")
+	result.PoweredBy = page.header.Get("X-Powered-By")
+	result.Technologies = MatchFingerprints(v.webFingerprints(), page.header, page.body)
+	if len(result.Technologies) > 0 {
+		logger.Debugf("Web fingerprint for %s: %v", page.finalURL, result.Technologies)
+	}
+}
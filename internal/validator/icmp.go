@@ -0,0 +1,165 @@
+package validator
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/oneforall-go/pkg/logger"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// ICMP 协议号
+const (
+	protocolICMP     = 1
+	protocolIPv6ICMP = 58
+)
+
+var (
+	icmpWarnOnce  sync.Once
+	icmpSeq       uint32
+	rawICMPDenied atomic.Bool // 原始套接字无权限，直接使用非特权套接字
+	icmpDisabled  atomic.Bool // ICMP 完全不可用，直接使用 TCP 验证
+)
+
+// errICMPNotPermitted 无权限创建 ICMP 套接字
+var errICMPNotPermitted = errors.New("icmp socket not permitted")
+
+// validateICMP 发送 ICMP Echo 请求验证 IP 是否存活
+func (v *DomainValidator) validateICMP(ip string, timeout time.Duration) (bool, error) {
+	parsedIP := net.ParseIP(ip)
+	if parsedIP == nil {
+		return false, fmt.Errorf("invalid IP: %s", ip)
+	}
+
+	isIPv4 := parsedIP.To4() != nil
+
+	conn, privileged, err := listenICMP(isIPv4)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	var echoType icmp.Type = ipv4.ICMPTypeEcho
+	var replyType icmp.Type = ipv4.ICMPTypeEchoReply
+	protocol := protocolICMP
+	if !isIPv4 {
+		echoType = ipv6.ICMPTypeEchoRequest
+		replyType = ipv6.ICMPTypeEchoReply
+		protocol = protocolIPv6ICMP
+	}
+
+	seq := int(atomic.AddUint32(&icmpSeq, 1) & 0xffff)
+	id := os.Getpid() & 0xffff
+	msg := icmp.Message{
+		Type: echoType,
+		Code: 0,
+		Body: &icmp.Echo{ID: id, Seq: seq, Data: []byte("oneforall-go")},
+	}
+	data, err := msg.Marshal(nil)
+	if err != nil {
+		return false, err
+	}
+
+	// 非特权模式（UDP ICMP 套接字）下目标地址需使用 UDPAddr
+	var dst net.Addr = &net.IPAddr{IP: parsedIP}
+	if !privileged {
+		dst = &net.UDPAddr{IP: parsedIP}
+	}
+
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return false, err
+	}
+	if _, err := conn.WriteTo(data, dst); err != nil {
+		return false, err
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			return false, err
+		}
+
+		reply, err := icmp.ParseMessage(protocol, buf[:n])
+		if err != nil || reply.Type != replyType {
+			continue
+		}
+
+		echo, ok := reply.Body.(*icmp.Echo)
+		if !ok || echo.Seq != seq {
+			continue
+		}
+
+		// 非特权模式下内核会改写 ID，只校验来源地址和序号
+		if privileged && echo.ID != id {
+			continue
+		}
+		if peerIP := addrIP(peer); peerIP != nil && !peerIP.Equal(parsedIP) {
+			continue
+		}
+
+		return true, nil
+	}
+}
+
+// listenICMP 创建 ICMP 套接字，优先使用原始套接字，无权限时回退到非特权 UDP ICMP 套接字
+func listenICMP(isIPv4 bool) (*icmp.PacketConn, bool, error) {
+	network, udpNetwork, address := "ip4:icmp", "udp4", "0.0.0.0"
+	if !isIPv4 {
+		network, udpNetwork, address = "ip6:ipv6-icmp", "udp6", "::"
+	}
+
+	if !rawICMPDenied.Load() {
+		conn, err := icmp.ListenPacket(network, address)
+		if err == nil {
+			return conn, true, nil
+		}
+		if !isPermissionError(err) {
+			return nil, false, err
+		}
+	}
+
+	conn, err := icmp.ListenPacket(udpNetwork, address)
+	if err == nil {
+		// 记录原始套接字不可用，后续直接使用非特权套接字
+		rawICMPDenied.Store(true)
+		return conn, false, nil
+	}
+	if isPermissionError(err) {
+		return nil, false, errICMPNotPermitted
+	}
+
+	return nil, false, err
+}
+
+// isPermissionError 判断是否为权限错误
+func isPermissionError(err error) bool {
+	return errors.Is(err, os.ErrPermission) || errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.EACCES)
+}
+
+// addrIP 提取地址中的 IP
+func addrIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.IPAddr:
+		return a.IP
+	case *net.UDPAddr:
+		return a.IP
+	}
+	return nil
+}
+
+// disableICMP 禁用 ICMP 验证并输出一次性警告
+func disableICMP(err error) {
+	icmpDisabled.Store(true)
+	icmpWarnOnce.Do(func() {
+		logger.Warnf("ICMP ping unavailable (%v), falling back to TCP validation. Run with root/CAP_NET_RAW or allow net.ipv4.ping_group_range to enable ICMP", err)
+	})
+}
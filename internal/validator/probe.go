@@ -0,0 +1,128 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/oneforall-go/internal/config"
+	httpclient "github.com/oneforall-go/internal/http"
+	"github.com/oneforall-go/pkg/logger"
+)
+
+// defaultProbeTimeout 未配置 validation_timeout 时 HTTP 探测的超时
+const defaultProbeTimeout = 10 * time.Second
+
+// maxProbeBodySize 提取标题时最多读取的响应体大小
+const maxProbeBodySize = 256 * 1024
+
+// httpPage HTTP 探测得到的页面
+type httpPage struct {
+	statusCode int
+	finalURL   string
+	title      string
+	header     http.Header
+	body       []byte           // 响应体，最多 maxProbeBodySize 字节
+	tlsVersion string           // 协商的 TLS 版本，HTTP 响应为空
+	cert       *peerCertificate // 第一跳（请求的主机本身，而不是跳转目标）的服务器证书，HTTP 请求为空
+}
+
+// probeHTTP 依次请求 https:// 和 http://，记录第一个得到响应的真实状态码、跳转后的最终 URL 和页面标题，
+// 开启 fingerprint 时同时记录 Web 技术指纹。端口可达但没有任何 HTTP 响应时返回 false，结果保持不变
+func (v *DomainValidator) probeHTTP(host string, result *ValidationResult) bool {
+	for _, scheme := range []string{"https", "http"} {
+		page, err := v.fetchPage(scheme, host)
+		if err != nil {
+			logger.Debugf("HTTP probe %s://%s failed: %v", scheme, host, err)
+			continue
+		}
+
+		result.StatusCode = page.statusCode
+		result.FinalURL = page.finalURL
+		result.Title = page.title
+		if page.tlsVersion != "" {
+			result.TLSVersion = page.tlsVersion
+		}
+		// 复用探测连接上的证书，不再单独请求
+		if page.cert != nil && v.config.HarvestCertSANs {
+			applyCertificate(result, page.cert.cert)
+		}
+		logger.Debugf("HTTP probe %s://%s: status %d, final URL %s, title %q", scheme, host, page.statusCode, page.finalURL, page.title)
+		if v.config.Fingerprint {
+			v.fingerprint(page, result)
+		}
+		return true
+	}
+	return false
+}
+
+// fetchPage 请求页面（跟随跳转），返回状态码、最终 URL、标题、响应头和响应体
+func (v *DomainValidator) fetchPage(scheme, host string) (*httpPage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout(v.config))
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s://%s", scheme, host), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "OneForAll-Go/1.0")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,*/*;q=0.8")
+
+	// HTTPS 不校验证书，跳转到的 HTTP 地址同样可以使用该客户端
+	resp, err := v.httpsClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxProbeBodySize))
+	if err != nil {
+		logger.Debugf("Failed to read response body from %s://%s: %v", scheme, host, err)
+	}
+
+	return &httpPage{
+		statusCode: resp.StatusCode,
+		finalURL:   resp.Request.URL.String(),
+		title:      httpclient.ExtractTitle(body, resp.Header.Get("Content-Type")),
+		header:     resp.Header,
+		body:       body,
+		tlsVersion: tlsVersionName(resp.TLS),
+		cert:       firstHopCertificate(resp),
+	}, nil
+}
+
+// firstHopCertificate 沿跳转链回到第一个响应，返回其服务器证书；跳转目标的证书可能属于其他主机，不能使用
+func firstHopCertificate(resp *http.Response) *peerCertificate {
+	first := resp
+	for first.Request != nil && first.Request.Response != nil {
+		first = first.Request.Response
+	}
+	if first.TLS == nil || len(first.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+	return &peerCertificate{cert: first.TLS.PeerCertificates[0], version: tlsVersionName(first.TLS)}
+}
+
+// probePorts 并发请求配置的 TCP 验证端口（先 HTTP 后 HTTPS），记录每个有响应端口的状态码
+func (v *DomainValidator) probePorts(host, ip string, result *ValidationResult) {
+	if len(v.config.TCPValidationPorts) == 0 {
+		return
+	}
+
+	ports := v.portClient.RequestMultiplePorts(host, ip, v.config.TCPValidationPorts)
+	if len(ports) > 0 {
+		result.Ports = ports
+		logger.Debugf("Port probe for %s: %v", host, ports)
+	}
+}
+
+// probeTimeout HTTP 探测超时，未配置 validation_timeout 时使用默认值
+func probeTimeout(cfg *config.Config) time.Duration {
+	timeout := time.Duration(cfg.ValidationTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultProbeTimeout
+	}
+	return timeout
+}
@@ -0,0 +1,75 @@
+package validator
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+
+	"github.com/oneforall-go/internal/config"
+	"github.com/oneforall-go/pkg/logger"
+)
+
+// probeTransport 基于共享连接池的不校验证书 Transport 创建 HTTPS 探测使用的 Transport：
+// 按 tls_min_version 设置最低版本，按 tls_send_sni 决定是否发送 SNI，握手失败时按 tls_fallback 降到 TLS 1.0 重试。
+// 使用代理时由代理连接目标，只应用最低版本
+func (v *DomainValidator) probeTransport(base *http.Transport) *http.Transport {
+	minVersion, err := config.ParseTLSVersion(v.config.TLSMinVersion)
+	if err != nil {
+		logger.Warnf("Ignoring %v, using TLS 1.2", err)
+		minVersion = tls.VersionTLS12
+	}
+
+	t := base.Clone()
+	t.TLSClientConfig = &tls.Config{InsecureSkipVerify: true, MinVersion: minVersion}
+
+	dial := t.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	t.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, handshakeFailed, err := v.dialTLS(ctx, dial, network, addr, minVersion)
+		if err == nil || !handshakeFailed || !v.config.TLSFallback || minVersion <= tls.VersionTLS10 || ctx.Err() != nil {
+			return conn, err
+		}
+
+		logger.Debugf("TLS handshake with %s failed (%v), retrying with TLS 1.0", addr, err)
+		conn, _, err = v.dialTLS(ctx, dial, network, addr, tls.VersionTLS10)
+		return conn, err
+	}
+	return t
+}
+
+// dialTLS 建立 TCP 连接并完成 TLS 握手，握手失败时 handshakeFailed 为 true（区别于 TCP 连接失败）
+func (v *DomainValidator) dialTLS(ctx context.Context, dial func(context.Context, string, string) (net.Conn, error),
+	network, addr string, minVersion uint16) (net.Conn, bool, error) {
+	raw, err := dial(ctx, network, addr)
+	if err != nil {
+		return nil, false, err
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: true,
+		MinVersion:         minVersion,
+		NextProtos:         []string{"http/1.1"},
+	}
+	// IP 地址不能作为 SNI
+	if host, _, err := net.SplitHostPort(addr); err == nil && v.config.TLSSendSNI && net.ParseIP(host) == nil {
+		tlsConfig.ServerName = host
+	}
+
+	conn := tls.Client(raw, tlsConfig)
+	if err := conn.HandshakeContext(ctx); err != nil {
+		raw.Close()
+		return nil, true, err
+	}
+	return conn, false, nil
+}
+
+// tlsVersionName 返回协商的 TLS 版本名称（如 "TLS 1.3"），非 HTTPS 响应返回空字符串
+func tlsVersionName(state *tls.ConnectionState) string {
+	if state == nil {
+		return ""
+	}
+	return tls.VersionName(state.Version)
+}
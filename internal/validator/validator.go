@@ -1,7 +1,8 @@
 package validator
 
 import (
-	"crypto/tls"
+	"context"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -10,93 +11,145 @@ import (
 	"time"
 
 	"github.com/oneforall-go/internal/config"
+	dnsutil "github.com/oneforall-go/internal/dns"
+	httpclient "github.com/oneforall-go/internal/http"
+	"github.com/oneforall-go/internal/metrics"
+	"github.com/oneforall-go/internal/transport"
 	"github.com/oneforall-go/pkg/logger"
 )
 
+// PassiveStatusText 被动模式下未验证结果的状态文本
+const PassiveStatusText = "Not Validated (Passive)"
+
+// InterruptedStatusText 验证在地址解析阶段被取消、只完成 DNS 解析的结果的状态文本
+const InterruptedStatusText = "Not Probed (Interrupted)"
+
 // DomainValidator 域名验证器
 type DomainValidator struct {
 	config      *config.Config
 	client      *http.Client
 	httpsClient *http.Client
+	portClient  *httpclient.Client // 多端口探测使用的 fasthttp 客户端
+	nameservers []string           // CNAME 查询和地址解析使用的 DNS 服务器
+
+	// Web 技术指纹，开启 fingerprint 时首次 HTTP 探测前加载
+	fingerprints    []WebFingerprint
+	fingerprintOnce sync.Once
+
+	// 两个验证阶段的实现，测试时可替换：resolveIPs 以 concurrency 个工作协程批量解析全部域名，返回域名到 IP 的映射，ctx 取消时返回已解析的部分
+	resolveIPs func(ctx context.Context, domains []string, concurrency int) map[string][]string
+	probeHost  func(domain string, ips []string, result *ValidationResult)
 }
 
 // ValidationResult 验证结果
 type ValidationResult struct {
-	Subdomain   string   `json:"subdomain"`
-	IP          []string `json:"ip"`
-	Status      int      `json:"status"`
-	Title       string   `json:"title"`
-	Port        int      `json:"port"`
-	Alive       bool     `json:"alive"`
-	Source      string   `json:"source"`
-	Time        string   `json:"time"`
-	Provider    string   `json:"provider"`
-	DNSResolved bool     `json:"dns_resolved"`
-	PingAlive   bool     `json:"ping_alive"`
-	StatusCode  int      `json:"status_code"` // 新增状态码字段
-	StatusText  string   `json:"status_text"` // 新增状态文本字段
+	Subdomain   string      `json:"subdomain"`
+	IP          []string    `json:"ip"`
+	Status      int         `json:"status"`
+	Title       string      `json:"title"`
+	Port        int         `json:"port"`
+	Alive       bool        `json:"alive"`
+	Source      string      `json:"source"`
+	Time        string      `json:"time"`
+	Provider    string      `json:"provider"`
+	DNSResolved bool        `json:"dns_resolved"`
+	PingAlive   bool        `json:"ping_alive"`
+	PingMethod  string      `json:"ping_method"`     // 存活探测方式：icmp 或 tcp
+	StatusCode  int         `json:"status_code"`     // 新增状态码字段
+	StatusText  string      `json:"status_text"`     // 新增状态文本字段
+	CertIssuer  string      `json:"cert_issuer"`     // HTTPS 证书签发者
+	CertExpiry  string      `json:"cert_expiry"`     // HTTPS 证书过期时间（UTC）
+	CertNames   []string    `json:"cert_names"`      // HTTPS 证书中的 DNS 名称（SAN），未做范围过滤
+	TLSVersion  string      `json:"tls_version"`     // HTTPS 探测协商的 TLS 版本（如 "TLS 1.3"）
+	CNAME       []string    `json:"cname"`           // CNAME 指向链，按解析顺序排列
+	FinalURL    string      `json:"final_url"`       // HTTP 探测跟随跳转后的最终 URL
+	Ports       map[int]int `json:"ports,omitempty"` // 各 TCP 验证端口的 HTTP 状态码，只包含有响应的端口
+	Confidence  int         `json:"confidence"`      // 0-100 的置信度，由通过的验证项计算，见 Confidence
+
+	// Web 技术指纹（开启 fingerprint 时记录）
+	Server       string   `json:"server,omitempty"`       // Server 响应头
+	PoweredBy    string   `json:"powered_by,omitempty"`   // X-Powered-By 响应头
+	Technologies []string `json:"technologies,omitempty"` // 匹配 data/web_fingerprints.json 的技术，按名称排序
 }
 
 // NewDomainValidator 创建域名验证器
 func NewDomainValidator(cfg *config.Config) *DomainValidator {
-	// 创建HTTP客户端
+	// 创建HTTP客户端和HTTPS客户端（忽略证书验证），默认使用进程级共享连接池
 	client := &http.Client{
 		Timeout: 60 * time.Second, // 设置60秒超时
-		Transport: &http.Transport{
-			DialContext: (&net.Dialer{
-				Timeout:   60 * time.Second,
-				KeepAlive: 30 * time.Second,
-			}).DialContext,
-			MaxIdleConns:        100,
-			IdleConnTimeout:     90 * time.Second,
-			TLSHandshakeTimeout: 10 * time.Second,
-		},
-	}
-
-	// 创建HTTPS客户端（忽略证书验证）
+	}
 	httpsClient := &http.Client{
 		Timeout: 60 * time.Second, // 设置60秒超时
-		Transport: &http.Transport{
-			DialContext: (&net.Dialer{
-				Timeout:   60 * time.Second,
-				KeepAlive: 30 * time.Second,
-			}).DialContext,
-			MaxIdleConns:        100,
-			IdleConnTimeout:     90 * time.Second,
-			TLSHandshakeTimeout: 10 * time.Second,
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true, // 忽略证书验证
-			},
-		},
-	}
-
-	return &DomainValidator{
+	}
+
+	// CNAME 查询优先使用用户指定的 DNS 服务器，未指定时使用默认公共 DNS
+	nameservers := dnsutil.ConfigResolvers(cfg)
+
+	// 多端口探测不校验证书，8443 等端口上的管理后台通常使用自签名证书
+	portClient := httpclient.NewClient()
+	portClient.SetTimeout(probeTimeout(cfg))
+	portClient.SetInsecureSkipVerify(true)
+
+	v := &DomainValidator{
 		config:      cfg,
 		client:      client,
 		httpsClient: httpsClient,
+		portClient:  portClient,
+		nameservers: nameservers,
 	}
+	v.resolveIPs = v.resolveDomains
+	v.probeHost = v.probeResolved
+	v.SetTransportPool(transport.Default())
+	return v
+}
+
+// SetTransportPool 使用共享连接池，HTTPS 客户端在不校验证书的 Transport 上应用 TLS 版本和 SNI 设置
+func (v *DomainValidator) SetTransportPool(pool *transport.Pool) {
+	v.client.Transport = pool.Secure
+	v.httpsClient.Transport = v.probeTransport(pool.Insecure)
 }
 
 // ValidateDomains 验证域名列表
 func (v *DomainValidator) ValidateDomains(domains []string, concurrency int) []ValidationResult {
+	return v.ValidateDomainsContext(context.Background(), domains, concurrency, nil)
+}
+
+// ValidateDomainsContext 验证域名列表，ctx 取消后不再开始新的验证，立即返回已完成的部分结果，
+// 正在进行的探测在后台结束后丢弃；onResult 不为 nil 时每完成一个域名串行调用一次，便于调用方增量保存
+func (v *DomainValidator) ValidateDomainsContext(ctx context.Context, domains []string, concurrency int, onResult func(ValidationResult)) []ValidationResult {
 	if len(domains) == 0 {
 		return []ValidationResult{}
 	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
 
-	logger.Infof("Starting comprehensive domain validation for %d domains with concurrency %d", len(domains), concurrency)
+	// DNS 阶段和探测阶段分别限制并发，未配置时都使用 concurrency
+	limits := newPhaseLimits(v.config.ValidationDNSConcurrency, v.config.ValidationHTTPConcurrency, concurrency)
+	limits.ctx = ctx
+	logger.Infof("Starting comprehensive domain validation for %d domains with concurrency %d (DNS %d, HTTP %d)",
+		len(domains), concurrency, cap(limits.dns), cap(limits.http))
 
 	// 去重
 	uniqueDomains := v.deduplicateDomains(domains)
 	logger.Infof("After deduplication: %d unique domains", len(uniqueDomains))
 
-	// 并发验证
+	// 地址记录按 DNS 阶段并发数批量解析，被动模式不发送任何 DNS 请求
+	addresses := map[string][]string{}
+	if !v.config.Passive {
+		addresses = v.resolveIPs(ctx, uniqueDomains, cap(limits.dns))
+		logger.Infof("Resolved %d of %d domains", len(addresses), len(uniqueDomains))
+	}
+	if ctx.Err() != nil {
+		return v.interruptedResults(uniqueDomains, addresses, onResult)
+	}
+
+	// 并发验证，interrupted 置位后完成的验证不再计入结果
 	var results []ValidationResult
 	var wg sync.WaitGroup
 	var mutex sync.Mutex
 	var errors []error
-
-	// 创建信号量控制并发数
-	semaphore := make(chan struct{}, concurrency)
+	var interrupted bool
 
 	for _, domain := range uniqueDomains {
 		wg.Add(1)
@@ -113,15 +166,21 @@ func (v *DomainValidator) ValidateDomains(domains []string, concurrency int) []V
 				}
 			}()
 
-			// 获取信号量
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
-
-			result := v.validateSingleDomain(domain)
+			result, ok := v.validateSingleDomain(domain, addresses[domain], limits)
+			if !ok {
+				return
+			}
+			result.Confidence = Confidence(result)
+			metrics.ObserveValidation(result.Alive)
 
 			// 添加所有验证结果，不管是否存活
 			mutex.Lock()
-			results = append(results, result)
+			if !interrupted {
+				results = append(results, result)
+				if onResult != nil {
+					onResult(result)
+				}
+			}
 			mutex.Unlock()
 
 			if result.Alive {
@@ -134,7 +193,22 @@ func (v *DomainValidator) ValidateDomains(domains []string, concurrency int) []V
 		}(domain)
 	}
 
-	wg.Wait()
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		mutex.Lock()
+		interrupted = true
+		partial := append([]ValidationResult(nil), results...)
+		mutex.Unlock()
+		logger.Warnf("Domain validation interrupted (%v), returning %d of %d validated domains", ctx.Err(), len(partial), len(uniqueDomains))
+		return partial
+	}
 
 	if len(errors) > 0 {
 		logger.Warnf("Some validation errors occurred: %v", errors)
@@ -144,8 +218,71 @@ func (v *DomainValidator) ValidateDomains(domains []string, concurrency int) []V
 	return results
 }
 
-// validateSingleDomain 验证单个域名
-func (v *DomainValidator) validateSingleDomain(domain string) ValidationResult {
+// interruptedResults 地址解析阶段被取消时，为已解析的域名生成只包含 DNS 结果的验证结果，不再进行存活探测
+func (v *DomainValidator) interruptedResults(domains []string, addresses map[string][]string, onResult func(ValidationResult)) []ValidationResult {
+	results := []ValidationResult{}
+	for _, domain := range domains {
+		ips, ok := addresses[domain]
+		if !ok {
+			continue
+		}
+		result := ValidationResult{
+			Subdomain:   domain,
+			IP:          ips,
+			Source:      "validator",
+			Time:        time.Now().Format("2006-01-02 15:04:05"),
+			DNSResolved: true,
+			StatusText:  InterruptedStatusText,
+		}
+		result.Confidence = Confidence(result)
+		results = append(results, result)
+		if onResult != nil {
+			onResult(result)
+		}
+	}
+	logger.Warnf("Domain validation interrupted during address resolution, returning %d of %d resolved domains without probing", len(results), len(domains))
+	return results
+}
+
+// phaseLimits 验证两个阶段的并发限制：DNS 阶段（CNAME 和 A 记录解析）可以承受远高于
+// 探测阶段（Ping、HTTP、多端口、证书）的并发，各自使用独立的信号量，互不阻塞
+type phaseLimits struct {
+	dns  chan struct{}
+	http chan struct{}
+	ctx  context.Context // 验证取消后不再发放名额
+}
+
+// acquire 获取 sem 的一个并发名额，验证已取消时返回 false
+func (l *phaseLimits) acquire(sem chan struct{}) bool {
+	select {
+	case sem <- struct{}{}:
+		return true
+	case <-l.ctx.Done():
+		return false
+	}
+}
+
+// newPhaseLimits 创建阶段并发限制，dns 或 http 不大于 0 时使用 fallback
+func newPhaseLimits(dns, http, fallback int) *phaseLimits {
+	if fallback <= 0 {
+		fallback = 1
+	}
+	if dns <= 0 {
+		dns = fallback
+	}
+	if http <= 0 {
+		http = fallback
+	}
+	return &phaseLimits{
+		dns:  make(chan struct{}, dns),
+		http: make(chan struct{}, http),
+		ctx:  context.Background(),
+	}
+}
+
+// validateSingleDomain 验证单个域名，ips 为批量解析得到的地址；CNAME 查询和后续探测分别占用对应阶段的并发名额，
+// 等待名额期间验证被取消时返回 false，结果不完整
+func (v *DomainValidator) validateSingleDomain(domain string, ips []string, limits *phaseLimits) (ValidationResult, bool) {
 	// 添加异常处理
 	defer func() {
 		if r := recover(); r != nil {
@@ -169,30 +306,29 @@ func (v *DomainValidator) validateSingleDomain(domain string) ValidationResult {
 		StatusText:  "",
 	}
 
+	// 被动模式不向目标发送任何 DNS 或 HTTP 请求，直接返回未验证的候选
+	if v.config.Passive {
+		result.StatusText = PassiveStatusText
+		return result, true
+	}
+
 	// 1. DNS 解析验证
-	ips := v.resolveDomain(domain)
+	if !v.resolvePhase(domain, &result, limits) {
+		return result, false
+	}
 	if len(ips) > 0 {
 		result.IP = ips
 		result.DNSResolved = true
 		logger.Debugf("DNS resolution successful for %s: %v", domain, ips)
 
-		// 2. Ping 验证（TCP连接测试）
-		result.PingAlive = v.validatePing(ips[0])
-		if result.PingAlive {
-			result.Alive = true
-			result.StatusCode = 200
-			result.StatusText = "Alive"
-			logger.Debugf("Ping successful for %s", domain)
-
-			// 3. IP供应商查询
-			if len(ips) > 0 {
-				result.Provider = v.getIPProvider(ips[0])
-			}
-		} else {
-			result.StatusCode = 0
-			result.StatusText = "Ping Failed"
-			logger.Debugf("Ping failed for %s", domain)
+		// 2. 存活探测
+		if !limits.acquire(limits.http) {
+			return result, false
 		}
+		func() {
+			defer func() { <-limits.http }()
+			v.probeHost(domain, ips, &result)
+		}()
 	} else {
 		result.StatusCode = -1
 		result.StatusText = "DNS Resolution Failed"
@@ -202,31 +338,94 @@ func (v *DomainValidator) validateSingleDomain(domain string) ValidationResult {
 	logger.Debugf("Validation result for %s: Alive=%v, DNS=%v, Ping=%v, Status=%d, Text=%s",
 		domain, result.Alive, result.DNSResolved, result.PingAlive, result.StatusCode, result.StatusText)
 
-	return result
+	return result, true
 }
 
-// resolveDomain DNS 解析域名
-func (v *DomainValidator) resolveDomain(domain string) []string {
-	var ips []string
+// resolvePhase DNS 阶段：记录 CNAME 链，地址记录已在验证开始时批量解析；等待名额期间验证被取消时返回 false
+func (v *DomainValidator) resolvePhase(domain string, result *ValidationResult, limits *phaseLimits) bool {
+	if !v.config.ResolveCNAME {
+		return true
+	}
 
-	// 尝试 A 记录解析
-	addresses, err := net.LookupHost(domain)
-	if err != nil {
-		logger.Debugf("Failed to resolve %s: %v", domain, err)
-		return ips
+	if !limits.acquire(limits.dns) {
+		return false
+	}
+	defer func() { <-limits.dns }()
+
+	// 记录 CNAME 链，无法解析的域名也记录，悬空的 CNAME 是子域名接管的典型特征
+	result.CNAME = v.resolveCNAMEChain(domain)
+	if len(result.CNAME) > 0 {
+		logger.Debugf("CNAME chain for %s: %v", domain, result.CNAME)
+	}
+	return true
+}
+
+// probeResolved 探测阶段：对已解析的域名做 Ping、HTTP 请求、多端口探测、IP 供应商查询和证书获取
+func (v *DomainValidator) probeResolved(domain string, ips []string, result *ValidationResult) {
+	// Ping 验证（ICMP 或 TCP连接测试）
+	result.PingAlive, result.PingMethod = v.validatePing(ips[0])
+	if !result.PingAlive {
+		result.StatusCode = 0
+		result.StatusText = "Ping Failed"
+		logger.Debugf("Ping failed for %s", domain)
+		return
+	}
+
+	result.Alive = true
+	result.StatusCode = 200
+	result.StatusText = "Alive"
+	logger.Debugf("Ping successful for %s", domain)
+
+	// 开启 HTTP 请求时记录真实状态码，区分端口可达和真正的 HTTP 响应
+	if v.config.EnableHTTPRequest && !v.probeHTTP(domain, result) {
+		result.StatusCode = 0
+		result.StatusText = "No HTTP Response"
+	}
+
+	// 并发探测 TCP 验证端口，发现 8080/8443 等非标准端口上的服务
+	if v.config.EnableHTTPRequest && v.config.EnableTCPValidation {
+		v.probePorts(domain, ips[0], result)
+	}
+
+	// IP供应商查询
+	result.Provider = v.getIPProvider(ips[0])
+
+	// 获取 HTTPS 证书，证书中的其他名称可能是新的子域名；HTTPS 探测已在同一连接上拿到证书时不再请求
+	if v.config.HarvestCertSANs && result.CertExpiry == "" {
+		v.inspectCertificate(domain, result)
+	}
+}
+
+// resolveDomains 使用 DNS 服务器列表批量解析域名，按 IP 版本查询 A 和/或 AAAA 记录，ctx 取消后返回已解析的部分
+func (v *DomainValidator) resolveDomains(ctx context.Context, domains []string, concurrency int) map[string][]string {
+	client := dnsutil.NewClient(v.config.DNSResolveTimeout, concurrency)
+	client.SetResolvers(v.nameservers)
+	client.SetIPVersion(v.config.IPVersion)
+	client.SetInsecureTLS(v.config.DoTInsecureSkipVerify)
+	if subnet, err := dnsutil.ParseClientSubnet(v.config.EDNSClientSubnet); err == nil && subnet != nil {
+		client.SetClientSubnet(subnet)
+	}
+
+	addresses := client.ResolveBatchContext(ctx, domains)
+	if !v.config.ExcludePrivateIP {
+		return addresses
 	}
 
-	// 过滤有效的 IP 地址
-	for _, addr := range addresses {
-		if ip := net.ParseIP(addr); ip != nil {
-			// 排除私有 IP 地址（可选）
-			if !v.config.ExcludePrivateIP || !isPrivateIP(ip) {
-				ips = append(ips, addr)
+	// 排除私有 IP 地址
+	for domain, resolved := range addresses {
+		var ips []string
+		for _, address := range resolved {
+			if ip := net.ParseIP(address); ip != nil && !isPrivateIP(ip) {
+				ips = append(ips, address)
 			}
 		}
+		if len(ips) > 0 {
+			addresses[domain] = ips
+		} else {
+			delete(addresses, domain)
+		}
 	}
-
-	return ips
+	return addresses
 }
 
 // validateHTTPRequest 验证HTTP请求
@@ -488,8 +687,8 @@ func (v *DomainValidator) GetValidationStats(results []ValidationResult) map[str
 	return stats
 }
 
-// validatePing 验证IP是否可以ping通
-func (v *DomainValidator) validatePing(ip string) bool {
+// validatePing 验证IP是否可以ping通，返回是否存活及成功的探测方式
+func (v *DomainValidator) validatePing(ip string) (bool, string) {
 	// 添加异常处理
 	defer func() {
 		if r := recover(); r != nil {
@@ -497,11 +696,30 @@ func (v *DomainValidator) validatePing(ip string) bool {
 		}
 	}()
 
-	// 使用TCP连接验证IP是否可达
-	conn, err := net.DialTimeout("tcp", ip+":80", 5*time.Second)
+	// 优先使用ICMP，失败时回退到TCP
+	if v.config.ValidationUseICMP && !icmpDisabled.Load() {
+		alive, err := v.validateICMP(ip, 3*time.Second)
+		if errors.Is(err, errICMPNotPermitted) {
+			disableICMP(err)
+		} else if alive {
+			logger.Debugf("ICMP ping successful for %s", ip)
+			return true, "icmp"
+		}
+	}
+
+	if v.validateTCP(ip) {
+		return true, "tcp"
+	}
+
+	return false, ""
+}
+
+// validateTCP 使用TCP连接验证IP是否可达
+func (v *DomainValidator) validateTCP(ip string) bool {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(ip, "80"), 5*time.Second)
 	if err != nil {
 		// 尝试443端口
-		conn, err = net.DialTimeout("tcp", ip+":443", 5*time.Second)
+		conn, err = net.DialTimeout("tcp", net.JoinHostPort(ip, "443"), 5*time.Second)
 		if err != nil {
 			logger.Debugf("Ping failed for %s: %v", ip, err)
 			return false
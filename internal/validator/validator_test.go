@@ -0,0 +1,693 @@
+package validator
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/oneforall-go/internal/config"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// newMultiSANCert 生成带多个 SAN 的自签名证书
+func newMultiSANCert(t *testing.T, names []string, notAfter time.Time) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: names[0]},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		DNSNames:     names,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestInspectCertificateSANs(t *testing.T) {
+	notAfter := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	cert := newMultiSANCert(t, []string{"www.example.com", "*.api.example.com", "mail.example.com", "WWW.example.com", "cdn.other.net"}, notAfter)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	server.StartTLS()
+	defer server.Close()
+
+	v := NewDomainValidator(&config.Config{ValidationTimeout: 5})
+	result := ValidationResult{Subdomain: "www.example.com"}
+	v.inspectCertificate(strings.TrimPrefix(server.URL, "https://"), &result)
+
+	if result.CertIssuer != "www.example.com" {
+		// 自签名证书的签发者即其主体
+		t.Errorf("Expected issuer www.example.com, got %q", result.CertIssuer)
+	}
+	if result.CertExpiry != "2030-01-02 03:04:05" {
+		t.Errorf("Expected expiry 2030-01-02 03:04:05, got %q", result.CertExpiry)
+	}
+
+	// 去掉通配符前缀和自身，去重但不做范围过滤
+	expected := []string{"api.example.com", "mail.example.com", "cdn.other.net"}
+	if strings.Join(result.CertNames, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected cert names %v, got %v", expected, result.CertNames)
+	}
+}
+
+func TestCertificateFromFirstHop(t *testing.T) {
+	notAfter := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	target := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<title>other</title>"))
+	}))
+	target.TLS = &tls.Config{Certificates: []tls.Certificate{newMultiSANCert(t, []string{"b.example.com", "b-alt.example.com"}, notAfter)}}
+	target.StartTLS()
+	defer target.Close()
+
+	var requests int32
+	origin := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.Redirect(w, r, target.URL, http.StatusFound)
+	}))
+	origin.TLS = &tls.Config{Certificates: []tls.Certificate{newMultiSANCert(t, []string{"a.example.com", "a-alt.example.com"}, notAfter)}}
+	origin.StartTLS()
+	defer origin.Close()
+	host := strings.TrimPrefix(origin.URL, "https://")
+
+	// HTTP 探测跟随跳转，但证书取自第一跳
+	v := NewDomainValidator(&config.Config{ValidationTimeout: 5, HarvestCertSANs: true})
+	result := ValidationResult{Subdomain: "a.example.com"}
+	if !v.probeHTTP(host, &result) {
+		t.Fatal("Expected an HTTP response")
+	}
+	if result.FinalURL != target.URL+"/" && result.FinalURL != target.URL {
+		t.Errorf("Expected the redirect to be followed, got %q", result.FinalURL)
+	}
+	if result.CertIssuer != "a.example.com" || strings.Join(result.CertNames, ",") != "a-alt.example.com" {
+		t.Errorf("Expected the origin certificate, got issuer %q names %v", result.CertIssuer, result.CertNames)
+	}
+
+	// 单独获取证书时不跟随跳转
+	result = ValidationResult{Subdomain: "a.example.com"}
+	v.inspectCertificate(host, &result)
+	if result.CertIssuer != "a.example.com" || strings.Join(result.CertNames, ",") != "a-alt.example.com" {
+		t.Errorf("Expected the origin certificate, got issuer %q names %v", result.CertIssuer, result.CertNames)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("Expected one request per probe to the origin, got %d", n)
+	}
+}
+
+func TestInspectCertificateUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// 纯 HTTP 服务无法完成 TLS 握手，结果保持不变
+	v := NewDomainValidator(&config.Config{ValidationTimeout: 5})
+	result := ValidationResult{Subdomain: "www.example.com"}
+	v.inspectCertificate(strings.TrimPrefix(server.URL, "http://"), &result)
+
+	if result.CertIssuer != "" || len(result.CertNames) != 0 {
+		t.Errorf("Expected no certificate info, got %+v", result)
+	}
+}
+
+// startCNAMEServer 启动本地 DNS 服务器，按 records 应答 CNAME 查询，未知名称返回 NXDOMAIN
+func startCNAMEServer(t *testing.T, records map[string]string) (string, func()) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen udp: %v", err)
+	}
+
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		msg := new(dns.Msg)
+		msg.SetReply(r)
+		name := r.Question[0].Name
+		if target, ok := records[name]; ok {
+			msg.Answer = append(msg.Answer, &dns.CNAME{
+				Hdr:    dns.RR_Header{Name: name, Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: 300},
+				Target: target,
+			})
+		} else {
+			msg.Rcode = dns.RcodeNameError
+		}
+		w.WriteMsg(msg)
+	})
+
+	started := make(chan struct{})
+	server := &dns.Server{PacketConn: pc, Handler: handler, NotifyStartedFunc: func() { close(started) }}
+	go server.ActivateAndServe()
+	<-started
+
+	return pc.LocalAddr().String(), func() { server.Shutdown() }
+}
+
+func TestResolveCNAMEChain(t *testing.T) {
+	addr, shutdown := startCNAMEServer(t, map[string]string{
+		"www.example.com.":          "example.cdn-provider.net.",
+		"example.cdn-provider.net.": "edge-1.cdn-provider.net.",
+		"loop-a.example.com.":       "loop-b.example.com.",
+		"loop-b.example.com.":       "loop-a.example.com.",
+	})
+	defer shutdown()
+
+	v := NewDomainValidator(&config.Config{Resolvers: []string{addr}})
+
+	chain := v.resolveCNAMEChain("www.example.com")
+	expected := []string{"example.cdn-provider.net", "edge-1.cdn-provider.net"}
+	if strings.Join(chain, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected CNAME chain %v, got %v", expected, chain)
+	}
+
+	if chain := v.resolveCNAMEChain("api.example.com"); len(chain) != 0 {
+		t.Errorf("Expected no CNAME for api.example.com, got %v", chain)
+	}
+
+	// 循环引用在回到起点时停止
+	if chain := v.resolveCNAMEChain("loop-a.example.com"); strings.Join(chain, ",") != "loop-b.example.com" {
+		t.Errorf("Expected loop to stop after one hop, got %v", chain)
+	}
+}
+
+func TestProbeHTTPRecordsRealStatus(t *testing.T) {
+	// 端口可达但返回 403，记录的状态码应为 403 而不是 200
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("<html><head><title>\n  Access &amp; Denied </title></head></html>"))
+	}))
+	defer server.Close()
+
+	v := NewDomainValidator(&config.Config{ValidationTimeout: 5})
+	host := strings.TrimPrefix(server.URL, "http://")
+	result := ValidationResult{Subdomain: "www.example.com", StatusCode: 200}
+	if !v.probeHTTP(host, &result) {
+		t.Fatal("Expected HTTP probe to succeed")
+	}
+
+	if result.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status code 403, got %d", result.StatusCode)
+	}
+	if result.Title != "Access & Denied" {
+		t.Errorf("Expected title %q, got %q", "Access & Denied", result.Title)
+	}
+	if result.FinalURL != server.URL+"/" && result.FinalURL != server.URL {
+		t.Errorf("Expected final URL %s, got %s", server.URL, result.FinalURL)
+	}
+}
+
+func TestProbeHTTPDecodesCharset(t *testing.T) {
+	gbk := func(s string) []byte {
+		encoded, err := simplifiedchinese.GBK.NewEncoder().Bytes([]byte(s))
+		if err != nil {
+			t.Fatalf("encode GBK: %v", err)
+		}
+		return encoded
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+	}{
+		{"content type", "text/html; charset=GBK", gbk("<html><head><title>百度一下，你就知道</title></head></html>")},
+		{"meta charset", "text/html", gbk(`<html><head><meta charset="gb2312"><title>百度一下，你就知道</title></head></html>`)},
+		{"meta http-equiv", "", gbk(`<meta http-equiv="Content-Type" content="text/html; charset=gbk"><title>百度一下，你就知道</title>`)},
+		// 没有声明字符集且不是合法 UTF-8 时按 GB18030 处理
+		{"undeclared", "text/html", gbk("<title>百度一下，你就知道</title>")},
+		{"utf-8", "text/html; charset=utf-8", []byte("<title>百度一下，你就知道</title>")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				w.Write(tt.body)
+			}))
+			defer server.Close()
+
+			v := NewDomainValidator(&config.Config{ValidationTimeout: 5})
+			result := ValidationResult{Subdomain: "www.example.com"}
+			if !v.probeHTTP(strings.TrimPrefix(server.URL, "http://"), &result) {
+				t.Fatal("Expected HTTP probe to succeed")
+			}
+			if result.Title != "百度一下，你就知道" {
+				t.Errorf("Expected decoded title, got %q", result.Title)
+			}
+		})
+	}
+}
+
+func TestProbePortsRecordsEachPort(t *testing.T) {
+	// 两个端口分别运行 HTTP 和 HTTPS 服务，第三个端口没有服务
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<title>App</title>"))
+	}))
+	defer plain.Close()
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer secure.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	closedPort := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	plainPort := plain.Listener.Addr().(*net.TCPAddr).Port
+	securePort := secure.Listener.Addr().(*net.TCPAddr).Port
+
+	v := NewDomainValidator(&config.Config{ValidationTimeout: 5, TCPValidationPorts: []int{plainPort, securePort, closedPort}})
+	result := ValidationResult{Subdomain: "127.0.0.1"}
+	v.probePorts("127.0.0.1", "127.0.0.1", &result)
+
+	if result.Ports[plainPort] != http.StatusOK {
+		t.Errorf("Expected status 200 on port %d, got %v", plainPort, result.Ports)
+	}
+	if result.Ports[securePort] != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 on TLS port %d, got %v", securePort, result.Ports)
+	}
+	if _, ok := result.Ports[closedPort]; ok || len(result.Ports) != 2 {
+		t.Errorf("Expected only the two listening ports, got %v", result.Ports)
+	}
+}
+
+func TestProbeHTTPFollowsRedirect(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/login" {
+			http.Redirect(w, r, "/login", http.StatusFound)
+			return
+		}
+		w.Write([]byte("<title>Login</title>"))
+	}))
+	defer server.Close()
+
+	v := NewDomainValidator(&config.Config{ValidationTimeout: 5})
+	result := ValidationResult{Subdomain: "www.example.com"}
+	if !v.probeHTTP(strings.TrimPrefix(server.URL, "https://"), &result) {
+		t.Fatal("Expected HTTP probe to succeed")
+	}
+
+	if result.StatusCode != http.StatusOK || result.FinalURL != server.URL+"/login" || result.Title != "Login" {
+		t.Errorf("Expected 200 %s/login with title Login, got %d %s %q", server.URL, result.StatusCode, result.FinalURL, result.Title)
+	}
+}
+
+// concurrencyGauge 记录同时进行的调用数及其峰值
+type concurrencyGauge struct {
+	current, peak int32
+}
+
+func (g *concurrencyGauge) enter() {
+	n := atomic.AddInt32(&g.current, 1)
+	for {
+		peak := atomic.LoadInt32(&g.peak)
+		if n <= peak || atomic.CompareAndSwapInt32(&g.peak, peak, n) {
+			return
+		}
+	}
+}
+
+func (g *concurrencyGauge) leave() {
+	atomic.AddInt32(&g.current, -1)
+}
+
+func TestValidatePhasesUseIndependentLimits(t *testing.T) {
+	v := NewDomainValidator(&config.Config{ValidationDNSConcurrency: 8, ValidationHTTPConcurrency: 2})
+
+	var httpGauge concurrencyGauge
+	var batches, dnsWorkers, batchSize int32
+	v.resolveIPs = func(ctx context.Context, domains []string, concurrency int) map[string][]string {
+		atomic.AddInt32(&batches, 1)
+		atomic.StoreInt32(&dnsWorkers, int32(concurrency))
+		atomic.StoreInt32(&batchSize, int32(len(domains)))
+		addresses := make(map[string][]string)
+		for _, domain := range domains {
+			addresses[domain] = []string{"192.0.2.1"}
+		}
+		return addresses
+	}
+	v.probeHost = func(domain string, ips []string, result *ValidationResult) {
+		httpGauge.enter()
+		defer httpGauge.leave()
+		time.Sleep(20 * time.Millisecond)
+		result.Alive = true
+	}
+
+	domains := make([]string, 40)
+	for i := range domains {
+		domains[i] = fmt.Sprintf("host%d.example.com", i)
+	}
+	// concurrency 只在未配置阶段并发数时生效
+	results := v.ValidateDomains(domains, 1)
+
+	if len(results) != len(domains) {
+		t.Fatalf("Expected %d results, got %d", len(domains), len(results))
+	}
+	// 地址记录一次批量解析，工作协程数为 DNS 阶段并发数，与 HTTP 阶段的限制无关
+	if atomic.LoadInt32(&batches) != 1 || atomic.LoadInt32(&batchSize) != int32(len(domains)) {
+		t.Errorf("Expected one batch of %d domains, got %d batches of %d", len(domains), batches, batchSize)
+	}
+	if workers := atomic.LoadInt32(&dnsWorkers); workers != 8 {
+		t.Errorf("Expected the batch resolver to use 8 workers, got %d", workers)
+	}
+	if peak := atomic.LoadInt32(&httpGauge.peak); peak > 2 {
+		t.Errorf("Expected at most 2 concurrent HTTP probes, peak was %d", peak)
+	}
+}
+
+func TestValidateDomainsCancel(t *testing.T) {
+	v := NewDomainValidator(&config.Config{ValidationHTTPConcurrency: 2})
+	v.resolveIPs = func(ctx context.Context, domains []string, concurrency int) map[string][]string {
+		addresses := make(map[string][]string)
+		for _, domain := range domains {
+			addresses[domain] = []string{"192.0.2.1"}
+		}
+		return addresses
+	}
+
+	// 前 5 个探测立即完成，之后的探测一直阻塞到测试结束
+	release := make(chan struct{})
+	defer close(release)
+	var probes int32
+	v.probeHost = func(domain string, ips []string, result *ValidationResult) {
+		if atomic.AddInt32(&probes, 1) > 5 {
+			<-release
+		}
+		result.Alive = true
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var emitted []string
+	onResult := func(result ValidationResult) {
+		emitted = append(emitted, result.Subdomain)
+		if len(emitted) == 5 {
+			cancel()
+		}
+	}
+
+	domains := make([]string, 50)
+	for i := range domains {
+		domains[i] = fmt.Sprintf("host%d.example.com", i)
+	}
+
+	done := make(chan []ValidationResult, 1)
+	go func() { done <- v.ValidateDomainsContext(ctx, domains, 2, onResult) }()
+
+	var results []ValidationResult
+	select {
+	case results = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected validation to return after cancellation")
+	}
+
+	if len(results) != 5 {
+		t.Fatalf("Expected the 5 completed validations, got %d", len(results))
+	}
+	if len(emitted) != len(results) {
+		t.Errorf("Expected every returned result to be emitted, got %d emitted for %d results", len(emitted), len(results))
+	}
+	for _, result := range results {
+		if !result.Alive || result.Confidence == 0 {
+			t.Errorf("Expected a completed validation, got %+v", result)
+		}
+	}
+}
+
+func TestValidateDomainsCancelDuringResolution(t *testing.T) {
+	v := NewDomainValidator(&config.Config{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 解析到一半时取消，只返回已解析的部分
+	v.resolveIPs = func(ctx context.Context, domains []string, concurrency int) map[string][]string {
+		cancel()
+		return map[string][]string{domains[0]: {"192.0.2.1"}}
+	}
+	v.probeHost = func(domain string, ips []string, result *ValidationResult) {
+		t.Errorf("Expected no probe of %s after cancellation", domain)
+	}
+
+	var emitted int
+	results := v.ValidateDomainsContext(ctx, []string{"a.example.com", "b.example.com"}, 2, func(ValidationResult) { emitted++ })
+
+	if len(results) != 1 || emitted != 1 {
+		t.Fatalf("Expected the resolved domain to be kept, got %d results and %d emitted", len(results), emitted)
+	}
+	result := results[0]
+	if result.Subdomain != "a.example.com" || !result.DNSResolved || result.Alive || result.StatusText != InterruptedStatusText || result.Confidence == 0 {
+		t.Errorf("Expected a DNS-only result, got %+v", result)
+	}
+}
+
+func TestValidatePassiveSendsNoTraffic(t *testing.T) {
+	// 记录发往 DNS 服务器的查询，被动模式下不应收到任何数据包
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var queries int32
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			if _, _, err := conn.ReadFrom(buf); err != nil {
+				return
+			}
+			atomic.AddInt32(&queries, 1)
+		}
+	}()
+
+	cfg := &config.Config{Passive: true, ResolveCNAME: true, Resolvers: []string{conn.LocalAddr().String()}}
+	v := NewDomainValidator(cfg)
+	v.resolveIPs = func(ctx context.Context, domains []string, concurrency int) map[string][]string {
+		t.Errorf("Expected no address lookup for %v in passive mode", domains)
+		return nil
+	}
+	v.probeHost = func(domain string, ips []string, result *ValidationResult) {
+		t.Errorf("Expected no probe of %s in passive mode", domain)
+	}
+
+	results := v.ValidateDomains([]string{"www.example.com", "api.example.com"}, 2)
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+	for _, result := range results {
+		if result.Alive || result.DNSResolved || result.StatusText != PassiveStatusText {
+			t.Errorf("Expected an unvalidated candidate, got %+v", result)
+		}
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&queries); n != 0 {
+		t.Errorf("Expected no DNS queries in passive mode, got %d", n)
+	}
+}
+
+func TestConfidence(t *testing.T) {
+	tests := []struct {
+		name   string
+		result ValidationResult
+		want   int
+	}{
+		{"unresolved", ValidationResult{Subdomain: "a.example.com", StatusCode: -1}, 0},
+		{"passive candidate", ValidationResult{Subdomain: "a.example.com", StatusText: PassiveStatusText}, 0},
+		{"dns only", ValidationResult{Subdomain: "a.example.com", DNSResolved: true}, 30},
+		{"tcp alive without http probe", ValidationResult{Subdomain: "a.example.com", DNSResolved: true, PingAlive: true, StatusCode: 200}, 50},
+		{"tcp alive, no http response", ValidationResult{Subdomain: "a.example.com", DNSResolved: true, PingAlive: true}, 50},
+		{"http 403", ValidationResult{Subdomain: "a.example.com", DNSResolved: true, PingAlive: true, StatusCode: 403, FinalURL: "https://a.example.com/"}, 65},
+		{"http 200", ValidationResult{Subdomain: "a.example.com", DNSResolved: true, PingAlive: true, StatusCode: 200, FinalURL: "https://a.example.com/"}, 80},
+		{"http 200 with own cert", ValidationResult{Subdomain: "a.example.com", DNSResolved: true, PingAlive: true, StatusCode: 200,
+			FinalURL: "https://a.example.com/", CertNames: []string{"A.example.com"}}, 100},
+		{"wildcard cert", ValidationResult{Subdomain: "a.example.com", DNSResolved: true, PingAlive: true, StatusCode: 302,
+			FinalURL: "https://a.example.com/login", CertNames: []string{"*.example.com"}}, 100},
+		{"cert for another host", ValidationResult{Subdomain: "a.b.example.com", DNSResolved: true, PingAlive: true, StatusCode: 200,
+			FinalURL: "https://a.b.example.com/", CertNames: []string{"*.example.com", "www.example.com"}}, 80},
+	}
+
+	for _, tt := range tests {
+		if got := Confidence(tt.result); got != tt.want {
+			t.Errorf("%s: Confidence = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestResolveDomainsIPVersion(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen udp: %v", err)
+	}
+
+	var mu sync.Mutex
+	var queried []string
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		question := r.Question[0]
+		mu.Lock()
+		queried = append(queried, dns.TypeToString[question.Qtype])
+		mu.Unlock()
+
+		m := new(dns.Msg)
+		m.SetReply(r)
+		switch question.Qtype {
+		case dns.TypeA:
+			rr, _ := dns.NewRR(question.Name + " 60 IN A 192.0.2.1")
+			m.Answer = append(m.Answer, rr)
+		case dns.TypeAAAA:
+			rr, _ := dns.NewRR(question.Name + " 60 IN AAAA 2001:db8::1")
+			m.Answer = append(m.Answer, rr)
+		}
+		w.WriteMsg(m)
+	})
+
+	started := make(chan struct{})
+	server := &dns.Server{PacketConn: pc, Handler: handler, NotifyStartedFunc: func() { close(started) }}
+	go server.ActivateAndServe()
+	<-started
+	defer server.Shutdown()
+
+	tests := []struct {
+		version string
+		queried string
+		ips     string
+	}{
+		{"4", "A", "192.0.2.1"},
+		{"6", "AAAA", "2001:db8::1"},
+		{"both", "A,AAAA", "192.0.2.1,2001:db8::1"},
+	}
+	for _, tt := range tests {
+		mu.Lock()
+		queried = nil
+		mu.Unlock()
+
+		// 所有查询都发往本地 DNS 服务器
+		v := NewDomainValidator(&config.Config{IPVersion: tt.version, Resolvers: []string{pc.LocalAddr().String()}})
+		ips := v.resolveDomains(context.Background(), []string{"www.example.com"}, 1)["www.example.com"]
+
+		sort.Strings(ips)
+		mu.Lock()
+		got := append([]string(nil), queried...)
+		mu.Unlock()
+		sort.Strings(got)
+		if strings.Join(got, ",") != tt.queried {
+			t.Errorf("IP version %q: expected %s queries, got %v", tt.version, tt.queried, got)
+		}
+		if strings.Join(ips, ",") != tt.ips {
+			t.Errorf("IP version %q: expected %s, got %v", tt.version, tt.ips, ips)
+		}
+	}
+}
+
+func TestProbeHTTPFingerprint(t *testing.T) {
+	// 测试目录下没有数据目录，使用内置的指纹文件
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "nginx/1.25.3")
+		w.Header().Set("X-Powered-By", "PHP/8.2.1")
+		w.Header().Set("X-Jenkins", "2.440")
+		w.Write([]byte(`<html><head><title>Blog</title><link rel="stylesheet" href="/wp-content/themes/x/style.css"></head></html>`))
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	v := NewDomainValidator(&config.Config{ValidationTimeout: 5, Fingerprint: true})
+	result := ValidationResult{Subdomain: "blog.example.com"}
+	if !v.probeHTTP(host, &result) {
+		t.Fatal("Expected HTTP probe to succeed")
+	}
+	if result.Server != "nginx/1.25.3" || result.PoweredBy != "PHP/8.2.1" {
+		t.Errorf("Expected server and powered-by headers, got %q and %q", result.Server, result.PoweredBy)
+	}
+	want := []string{"Jenkins", "Nginx", "PHP", "WordPress"}
+	if strings.Join(result.Technologies, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected technologies %v, got %v", want, result.Technologies)
+	}
+
+	// 未开启时不记录指纹
+	v = NewDomainValidator(&config.Config{ValidationTimeout: 5})
+	result = ValidationResult{Subdomain: "blog.example.com"}
+	if !v.probeHTTP(host, &result) {
+		t.Fatal("Expected HTTP probe to succeed")
+	}
+	if result.Server != "" || len(result.Technologies) != 0 {
+		t.Errorf("Expected no fingerprint when disabled, got %q %v", result.Server, result.Technologies)
+	}
+}
+
+func TestHTTPSProbeTLSSettings(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<title>ok</title>"))
+	})
+
+	// 只支持 TLS 1.0/1.1 的老旧主机
+	legacy := httptest.NewUnstartedServer(handler)
+	legacy.TLS = &tls.Config{MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS11}
+	legacy.StartTLS()
+	defer legacy.Close()
+	legacyHost := strings.TrimPrefix(legacy.URL, "https://")
+
+	v := NewDomainValidator(&config.Config{ValidationTimeout: 5, TLSMinVersion: "1.2", TLSFallback: true})
+	page, err := v.fetchPage("https", legacyHost)
+	if err != nil {
+		t.Fatalf("Expected fallback to TLS 1.1 to succeed, got %v", err)
+	}
+	if page.tlsVersion != "TLS 1.1" {
+		t.Errorf("Expected negotiated TLS 1.1, got %q", page.tlsVersion)
+	}
+
+	v = NewDomainValidator(&config.Config{ValidationTimeout: 5, TLSMinVersion: "1.2"})
+	if _, err := v.fetchPage("https", legacyHost); err == nil {
+		t.Error("Expected handshake failure without fallback")
+	}
+
+	// 没有 SNI 时拒绝握手的主机
+	strict := httptest.NewUnstartedServer(handler)
+	strict.TLS = &tls.Config{GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		if hello.ServerName != "localhost" {
+			return nil, fmt.Errorf("unknown server name %q", hello.ServerName)
+		}
+		return nil, nil
+	}}
+	strict.StartTLS()
+	defer strict.Close()
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(strict.URL, "https://"))
+	strictHost := net.JoinHostPort("localhost", port)
+
+	v = NewDomainValidator(&config.Config{ValidationTimeout: 5, TLSSendSNI: true})
+	result := ValidationResult{Subdomain: "localhost"}
+	if !v.probeHTTP(strictHost, &result) {
+		t.Fatal("Expected HTTPS probe with SNI to succeed")
+	}
+	if !strings.HasPrefix(result.FinalURL, "https://") || result.TLSVersion != "TLS 1.3" {
+		t.Errorf("Expected an HTTPS response over TLS 1.3, got %s over %q", result.FinalURL, result.TLSVersion)
+	}
+
+	v = NewDomainValidator(&config.Config{ValidationTimeout: 5, TLSSendSNI: false, TLSFallback: true})
+	if _, err := v.fetchPage("https", strictHost); err == nil {
+		t.Error("Expected handshake failure without SNI")
+	}
+}
@@ -0,0 +1,78 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/oneforall-go/internal/core"
+	"github.com/oneforall-go/pkg/logger"
+)
+
+// 增量通知的事件类型
+const (
+	// EventDelta 与基线相比有新出现的子域名
+	EventDelta = "delta"
+	// EventHeartbeat 没有新出现的子域名，只在开启 webhook_heartbeat 时发送
+	EventHeartbeat = "heartbeat"
+)
+
+// Delta 增量通知内容（webhook_delta_only 开启且指定了基线时代替 Summary 发送），只包含与基线相比新出现的子域名
+type Delta struct {
+	Event      string      `json:"event"`       // delta 或 heartbeat
+	Domain     string      `json:"domain"`      // 目标域名，多个域名以逗号分隔
+	Baseline   string      `json:"baseline"`    // 基线结果文件
+	Time       string      `json:"time"`        // 通知生成时间
+	Total      int         `json:"total"`       // 本次结果总数
+	AddedCount int         `json:"added_count"` // 新出现的子域名数
+	Added      []DeltaHost `json:"added"`       // 新出现的子域名，按子域名排序，heartbeat 时为空数组
+}
+
+// DeltaHost 新出现的子域名及其解析和探测结果
+type DeltaHost struct {
+	Subdomain  string   `json:"subdomain"`
+	IP         []string `json:"ip,omitempty"`
+	CNAME      []string `json:"cname,omitempty"`
+	Alive      bool     `json:"alive"`
+	StatusCode int      `json:"status_code,omitempty"`
+	Title      string   `json:"title,omitempty"`
+	URL        string   `json:"url,omitempty"`
+	Source     string   `json:"source,omitempty"`
+}
+
+// NewDelta 根据新出现的结果创建增量通知，没有新增时事件类型为 heartbeat
+func NewDelta(domain, baseline string, total int, added []core.SubdomainResult) Delta {
+	delta := Delta{
+		Event:      EventDelta,
+		Domain:     domain,
+		Baseline:   baseline,
+		Time:       time.Now().Format("2006-01-02 15:04:05"),
+		Total:      total,
+		AddedCount: len(added),
+		Added:      make([]DeltaHost, 0, len(added)),
+	}
+	if len(added) == 0 {
+		delta.Event = EventHeartbeat
+	}
+
+	for _, result := range added {
+		delta.Added = append(delta.Added, DeltaHost{
+			Subdomain:  result.Subdomain,
+			IP:         result.IP,
+			CNAME:      result.CNAME,
+			Alive:      result.Alive,
+			StatusCode: result.StatusCode,
+			Title:      result.Title,
+			URL:        result.URL,
+			Source:     result.Source,
+		})
+	}
+	return delta
+}
+
+// SendDelta 发送增量通知，没有新出现的子域名且未开启 webhook_heartbeat 时不发送
+func (n *Notifier) SendDelta(delta Delta) error {
+	if delta.AddedCount == 0 && !n.heartbeat {
+		logger.Infof("No new subdomains since %s, webhook notification skipped", delta.Baseline)
+		return nil
+	}
+	return n.Send(delta)
+}
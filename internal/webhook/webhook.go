@@ -0,0 +1,105 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/oneforall-go/internal/config"
+	"github.com/oneforall-go/pkg/logger"
+)
+
+// SignatureHeader HMAC 签名请求头
+const SignatureHeader = "X-OneForAll-Signature"
+
+// Summary 扫描完成通知内容
+type Summary struct {
+	Domain     string                 `json:"domain"`
+	OutputPath string                 `json:"output_path"`
+	Stats      map[string]interface{} `json:"stats"`
+	Time       string                 `json:"time"`
+}
+
+// Notifier Webhook 通知器
+type Notifier struct {
+	url        string
+	secret     string
+	client     *http.Client
+	retryDelay time.Duration
+	heartbeat  bool // 增量通知没有新增时也发送
+}
+
+// NewNotifier 创建 Webhook 通知器，未配置 URL 时返回 nil
+func NewNotifier(cfg *config.Config) *Notifier {
+	if cfg.WebhookURL == "" {
+		return nil
+	}
+
+	return &Notifier{
+		url:        cfg.WebhookURL,
+		secret:     cfg.WebhookSecret,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		retryDelay: time.Second,
+		heartbeat:  cfg.WebhookHeartbeat,
+	}
+}
+
+// Send 发送 JSON 通知，失败时重试一次
+func (n *Notifier) Send(payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %v", err)
+	}
+
+	err = n.post(body)
+	if err != nil {
+		logger.Debugf("Webhook request failed, retrying: %v", err)
+		time.Sleep(n.retryDelay)
+		err = n.post(body)
+	}
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %v", err)
+	}
+
+	logger.Infof("Webhook notification sent to %s", n.url)
+	return nil
+}
+
+// post 发送一次请求
+func (n *Notifier) post(body []byte) error {
+	req, err := http.NewRequest("POST", n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "OneForAll-Go/1.0")
+	if n.secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(n.secret, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Sign 计算 HMAC-SHA256 签名（十六进制）
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,143 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/oneforall-go/internal/config"
+	"github.com/oneforall-go/internal/core"
+)
+
+func TestNotifierSendWithSignatureAndRetry(t *testing.T) {
+	var attempts int32
+	var received Summary
+	var signature string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 第一次请求失败，验证重试
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+		if signature != "sha256="+Sign("secret", body) {
+			t.Errorf("Unexpected signature: %s", signature)
+		}
+		json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	notifier := NewNotifier(&config.Config{WebhookURL: server.URL, WebhookSecret: "secret"})
+	notifier.retryDelay = 0
+
+	err := notifier.Send(Summary{
+		Domain:     "example.com",
+		OutputPath: "results/example.com.csv",
+		Stats:      map[string]interface{}{"total": 3},
+	})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	if atomic.LoadInt32(&attempts) != 2 {
+		t.Errorf("Expected 2 attempts, got %d", attempts)
+	}
+	if received.Domain != "example.com" || received.Stats["total"] != float64(3) {
+		t.Errorf("Unexpected payload: %+v", received)
+	}
+}
+
+func TestNotifierSendFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	notifier := NewNotifier(&config.Config{WebhookURL: server.URL})
+	notifier.retryDelay = 0
+
+	if err := notifier.Send(Summary{Domain: "example.com"}); err == nil {
+		t.Error("Expected error when webhook keeps failing")
+	}
+}
+
+func TestNewNotifierDisabled(t *testing.T) {
+	if NewNotifier(&config.Config{}) != nil {
+		t.Error("Expected nil notifier without webhook URL")
+	}
+}
+
+func TestNotifierSendDeltaOnlyNewHosts(t *testing.T) {
+	var requests int32
+	var received Delta
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		body, _ := io.ReadAll(r.Body)
+		received = Delta{}
+		json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{WebhookURL: server.URL, WebhookDeltaOnly: true}
+	output := core.NewOutputManager(cfg)
+	output.SetBaseline("results/baseline.csv", []core.SubdomainResult{
+		{Subdomain: "www.example.com"},
+		{Subdomain: "old.example.com"},
+	})
+	output.AddResults([]core.SubdomainResult{
+		{Subdomain: "www.example.com", Alive: true},
+		{Subdomain: "vpn.example.com", IP: []string{"192.0.2.7"}, Alive: true, StatusCode: 200, Source: "crtsh"},
+		{Subdomain: "api.example.com", Source: "brute"},
+	})
+
+	added, ok := output.AddedSinceBaseline()
+	if !ok {
+		t.Fatal("Expected a baseline to be set")
+	}
+	notifier := NewNotifier(cfg)
+	notifier.retryDelay = 0
+	if err := notifier.SendDelta(NewDelta("example.com", output.BaselinePath(), 3, added)); err != nil {
+		t.Fatalf("SendDelta failed: %v", err)
+	}
+
+	// 只发送新出现的子域名，已在基线中的 www 和已消失的 old 都不发送
+	if received.Event != EventDelta || received.AddedCount != 2 || len(received.Added) != 2 {
+		t.Fatalf("Expected a delta with 2 new hosts, got %+v", received)
+	}
+	if received.Added[0].Subdomain != "api.example.com" || received.Added[1].Subdomain != "vpn.example.com" {
+		t.Errorf("Expected api and vpn in order, got %+v", received.Added)
+	}
+	if host := received.Added[1]; host.StatusCode != 200 || !host.Alive || host.Source != "crtsh" || len(host.IP) != 1 {
+		t.Errorf("Expected probe details for vpn.example.com, got %+v", host)
+	}
+	if received.Baseline != "results/baseline.csv" || received.Total != 3 {
+		t.Errorf("Unexpected baseline/total: %+v", received)
+	}
+
+	// 没有新增时默认不发送
+	noChange := NewDelta("example.com", "results/baseline.csv", 2, nil)
+	if err := notifier.SendDelta(noChange); err != nil {
+		t.Fatalf("SendDelta failed: %v", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("Expected no request without changes, got %d requests", n)
+	}
+
+	// 开启心跳后发送 heartbeat，added 为空数组
+	cfg.WebhookHeartbeat = true
+	notifier = NewNotifier(cfg)
+	if err := notifier.SendDelta(noChange); err != nil {
+		t.Fatalf("SendDelta failed: %v", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 || received.Event != EventHeartbeat || received.Added == nil || len(received.Added) != 0 {
+		t.Errorf("Expected a heartbeat with an empty added list, got %d requests, %+v", n, received)
+	}
+}
@@ -1,7 +1,13 @@
 package api
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/oneforall-go/internal/alt"
@@ -19,24 +25,66 @@ import (
 	"github.com/oneforall-go/pkg/logger"
 )
 
-// SubdomainResult 子域名结果结构
+// SubdomainResult 子域名结果结构，包含结果文件中的全部字段，可直接解析 Envelope.Results
 type SubdomainResult struct {
-	Subdomain   string   `json:"subdomain"`
-	Source      string   `json:"source"`
-	Time        string   `json:"time"`
-	Alive       bool     `json:"alive"`
-	IP          []string `json:"ip,omitempty"`
-	DNSResolved bool     `json:"dns_resolved"`
-	PingAlive   bool     `json:"ping_alive"`
-	StatusCode  int      `json:"status_code"`
-	StatusText  string   `json:"status_text"`
-	Provider    string   `json:"provider,omitempty"`
+	Subdomain   string      `json:"subdomain"`
+	Source      string      `json:"source"`
+	Time        string      `json:"time"`
+	Alive       bool        `json:"alive"`
+	IP          []string    `json:"ip,omitempty"`
+	DNSResolved bool        `json:"dns_resolved"`
+	PingAlive   bool        `json:"ping_alive"`
+	PingMethod  string      `json:"ping_method,omitempty"` // 存活探测方式
+	Status      int         `json:"status,omitempty"`
+	StatusCode  int         `json:"status_code"`
+	StatusText  string      `json:"status_text"`
+	Port        int         `json:"port,omitempty"` // 探测成功的端口
+	Title       string      `json:"title,omitempty"`
+	URL         string      `json:"url,omitempty"`   // HTTP 探测跟随跳转后的最终 URL
+	Ports       map[int]int `json:"ports,omitempty"` // 各 TCP 验证端口的 HTTP 状态码
+	Provider    string      `json:"provider,omitempty"`
+	CNAME       []string    `json:"cname,omitempty"`
+	CertIssuer  string      `json:"cert_issuer,omitempty"`
+	CertExpiry  string      `json:"cert_expiry,omitempty"`
+	TLSVersion  string      `json:"tls_version,omitempty"` // HTTPS 探测协商的 TLS 版本
+	Confidence  int         `json:"confidence"`            // 0-100 的置信度，由通过的验证项计算
+
+	// Web 技术指纹（Options.Fingerprint 开启时记录）
+	Server       string   `json:"server,omitempty"`
+	PoweredBy    string   `json:"powered_by,omitempty"`
+	Technologies []string `json:"technologies,omitempty"`
+}
+
+// EnvelopeVersion JSON 结果信封的当前版本
+const EnvelopeVersion = core.JSONEnvelopeVersion
+
+// Envelope JSON 结果文件的信封格式（--json-envelope / JSON_ENVELOPE），可直接用 json.Unmarshal 解析结果文件
+type Envelope struct {
+	Version     int               `json:"version"`               // 信封格式版本
+	Domain      string            `json:"domain"`                // 目标域名
+	GeneratedAt string            `json:"generated_at"`          // 生成时间
+	Stats       EnvelopeStats     `json:"stats"`                 // 统计信息（包含未写入文件的结果）
+	Results     []SubdomainResult `json:"results"`               // 写入文件的结果
+	Partial     bool              `json:"partial,omitempty"`     // 运行异常中断，结果不完整
+	Unvalidated bool              `json:"unvalidated,omitempty"` // 结果未经验证（--no-validation 或被动模式），alive 等验证字段没有意义
+}
+
+// EnvelopeStats JSON 结果信封中的统计信息
+type EnvelopeStats struct {
+	Total       int            `json:"total"`
+	Alive       int            `json:"alive"`
+	Dead        int            `json:"dead"`
+	Unvalidated int            `json:"unvalidated"`  // 未验证的域名数，不计入 alive 和 dead
+	DeadReasons map[string]int `json:"dead_reasons"` // 按失败原因统计的未存活域名数
+	Sources     map[string]int `json:"sources"`
+	Providers   map[string]int `json:"providers"`
+	Related     int            `json:"related"` // 关联域名数
 }
 
 // Options 配置选项
 type Options struct {
 	// 基本配置
-	Target string `json:"target"` // 目标域名（必需）
+	Target string `json:"target"` // 目标域名（必需），也可以是 ASN（如 AS13335）或 CIDR（如 192.0.2.0/24）
 
 	// 功能开关
 	EnableValidation bool `json:"enable_validation"`  // 是否启用域名验证
@@ -46,6 +94,10 @@ type Options struct {
 	Concurrency int           `json:"concurrency"` // 并发数
 	Timeout     time.Duration `json:"timeout"`     // 超时时间
 
+	// 按步骤名称（不区分大小写，如 "Fast Search"、"Brute Force"、"Enrich"）单独设置并发数和超时，
+	// 未列出的步骤使用 Concurrency 和 Timeout；步骤名称不存在时返回错误
+	StepConfig map[string]StepConfig `json:"step_config,omitempty"`
+
 	// 模块开关
 	EnableSearchModules       bool `json:"enable_search_modules"`       // 搜索模块
 	EnableDatasetModules      bool `json:"enable_dataset_modules"`      // 数据集模块
@@ -55,41 +107,109 @@ type Options struct {
 	EnableIntelligenceModules bool `json:"enable_intelligence_modules"` // 智能模块
 	EnableEnrichModules       bool `json:"enable_enrich_modules"`       // 丰富模块
 
+	// 模块筛选（与模块 Name() 匹配，不区分大小写）
+	OnlyModules []string `json:"only_modules"` // 只运行这些模块
+	SkipModules []string `json:"skip_modules"` // 跳过这些模块
+
 	// 爆破模块配置
 	BruteDictionaryURL string `json:"brute_dictionary_url"` // 爆破字典URL
 	BruteDNSServerURL  string `json:"brute_dns_server_url"` // 爆破DNS服务器URL
 
+	// 结果过滤（先 include 后 exclude）
+	IncludePattern string `json:"include_pattern"` // 只保留匹配该正则的子域名
+	ExcludePattern string `json:"exclude_pattern"` // 排除匹配该正则的子域名
+	MinConfidence  int    `json:"min_confidence"`  // 只保留置信度（0-100）不低于该值的结果，0 表示不过滤
+
+	// 预览模式：只生成候选列表，不发送网络请求
+	DryRun bool `json:"dry_run"`
+
+	// 被动模式：只运行不接触目标的模块（搜索、数据集、证书透明度、情报），返回未验证的候选
+	Passive bool `json:"passive"`
+
+	// HTTP 探测时记录 Server、X-Powered-By 响应头并识别 Web 技术（结果的 Server、PoweredBy、Technologies 字段）
+	Fingerprint bool `json:"fingerprint"`
+
+	// 爆破、解析和验证查询的 IP 版本：4（A 记录）、6（AAAA 记录）或 both，为空时使用配置
+	IPVersion string `json:"ip_version"`
+
+	// 数据文件目录（字典、DNS 服务器列表、CDN 列表等），为空时使用配置；缺少的文件使用内置默认数据
+	DataDir string `json:"data_dir"`
+
+	// 结果后处理：在所有步骤（包括验证和过滤）完成后、统计之前，在调用 RunSubdomainEnumeration 的协程中调用一次，
+	// 可以给结果打标记、过滤或补充信息，返回值作为最终结果，Result 中的总数、存活数和来源统计按返回值计算；为 nil 时不处理
+	PostProcess func([]SubdomainResult) []SubdomainResult `json:"-"`
+
 	// 日志配置
 	Debug   bool `json:"debug"`   // 调试模式
 	Verbose bool `json:"verbose"` // 详细日志
 }
 
+// StepConfig 单个执行步骤的并发数和超时，不大于 0 的项使用统一的 Concurrency/Timeout
+type StepConfig struct {
+	Concurrency int           `json:"concurrency"`
+	Timeout     time.Duration `json:"timeout"`
+}
+
 // Result 执行结果
 type Result struct {
-	Domain          string            `json:"domain"`           // 目标域名
-	TotalSubdomains int               `json:"total_subdomains"` // 总子域名数
-	AliveSubdomains int               `json:"alive_subdomains"` // 存活子域名数
-	AlivePercentage float64           `json:"alive_percentage"` // 存活百分比
-	Results         []SubdomainResult `json:"results"`          // 详细结果
-	ExecutionTime   time.Duration     `json:"execution_time"`   // 执行时间
-	Error           string            `json:"error,omitempty"`  // 错误信息
+	Domain            string            `json:"domain"`             // 目标域名
+	TotalSubdomains   int               `json:"total_subdomains"`   // 总子域名数
+	AliveSubdomains   int               `json:"alive_subdomains"`   // 存活子域名数
+	AlivePercentage   float64           `json:"alive_percentage"`   // 存活百分比
+	Results           []SubdomainResult `json:"results"`            // 详细结果
+	RelatedDomains    []string          `json:"related_domains"`    // 关联域名（非目标子域名，如证书/SPF 中的其他主域名）
+	SourceBreakdown   map[string]int    `json:"source_breakdown"`   // 按来源统计的结果数，有多个来源的结果在每个来源下各计一次
+	ProviderBreakdown map[string]int    `json:"provider_breakdown"` // 按IP提供商统计的结果数
+	ExecutionTime     time.Duration     `json:"execution_time"`     // 执行时间
+	ModuleTimings     []ModuleTiming    `json:"module_timings"`     // 各模块耗时，按耗时从长到短排序
+	Error             string            `json:"error,omitempty"`    // 错误信息
+}
+
+// ModuleTiming 单个模块的累计耗时和结果数
+type ModuleTiming struct {
+	Module   string        `json:"module"`   // 模块名称
+	Runs     int           `json:"runs"`     // 运行次数
+	Errors   int           `json:"errors"`   // 失败次数
+	Duration time.Duration `json:"duration"` // 累计耗时
+	Results  int           `json:"results"`  // 累计发现的子域名数
 }
 
 // OneForAllAPI OneForAll API接口
 type OneForAllAPI struct {
 	config     *config.Config
 	dispatcher *core.Dispatcher
+
+	// 配置校验错误，运行枚举时返回
+	configErr error
+
+	// 已注册模块对应的模块选项，再次调用且选项相同时复用已注册的模块
+	moduleKey string
+
+	// 运行时不按 Debug/Verbose 重新初始化全局日志（服务模式由服务统一管理日志）
+	keepLogger bool
 }
 
-// NewOneForAllAPI 创建新的API实例
+// NewOneForAllAPI 创建新的API实例，使用全局配置的副本，按调用选项修改配置不影响其他实例
 func NewOneForAllAPI() *OneForAllAPI {
-	cfg := config.GetConfig()
+	cfg := config.GetConfig().Clone()
 	return &OneForAllAPI{
 		config:     cfg,
 		dispatcher: core.NewDispatcher(cfg),
+		configErr:  cfg.Validate(),
 	}
 }
 
+// KeepLogger 运行时不再按 Options.Debug/Verbose 重新初始化全局日志，
+// 多个实例并发运行（如服务模式）时使用，避免互相替换日志实例和关闭日志文件
+func (api *OneForAllAPI) KeepLogger() {
+	api.keepLogger = true
+}
+
+// ConfigError 返回配置校验错误，配置有效时返回 nil
+func (api *OneForAllAPI) ConfigError() error {
+	return api.configErr
+}
+
 // RunSubdomainEnumeration 运行子域名枚举（仅返回数据结构，不保存到本地）
 func (api *OneForAllAPI) RunSubdomainEnumeration(options Options) (*Result, error) {
 	startTime := time.Now()
@@ -103,6 +223,43 @@ func (api *OneForAllAPI) RunSubdomainEnumeration(options Options) (*Result, erro
 		}, fmt.Errorf("target domain is required")
 	}
 
+	// 配置无效时直接返回，避免运行中途才暴露问题
+	if api.configErr != nil {
+		return &Result{
+			Domain:        options.Target,
+			ExecutionTime: time.Since(startTime),
+			Error:         api.configErr.Error(),
+		}, api.configErr
+	}
+
+	// 编译过滤规则
+	filter, err := core.NewResultFilter(options.IncludePattern, options.ExcludePattern)
+	if err != nil {
+		return &Result{
+			Domain:        options.Target,
+			ExecutionTime: time.Since(startTime),
+			Error:         err.Error(),
+		}, err
+	}
+
+	// IP 版本
+	if options.IPVersion != "" {
+		version, err := config.ParseIPVersion(options.IPVersion)
+		if err != nil {
+			return &Result{
+				Domain:        options.Target,
+				ExecutionTime: time.Since(startTime),
+				Error:         err.Error(),
+			}, err
+		}
+		api.config.IPVersion = version
+	}
+
+	// 数据文件目录
+	if options.DataDir != "" {
+		api.config.DataDir = options.DataDir
+	}
+
 	// 设置默认值
 	if options.Concurrency <= 0 {
 		options.Concurrency = 10
@@ -112,16 +269,37 @@ func (api *OneForAllAPI) RunSubdomainEnumeration(options Options) (*Result, erro
 	}
 
 	// 配置日志
-	if options.Debug {
-		logger.Init("debug", "")
-	} else if options.Verbose {
-		logger.Init("info", "")
-	} else {
-		logger.Init("warn", "")
+	if !api.keepLogger {
+		if options.Debug {
+			logger.Init("debug", "")
+		} else if options.Verbose {
+			logger.Init("info", "")
+		} else {
+			logger.Init("warn", "")
+		}
 	}
 
-	// 注册模块
-	api.registerModules(options)
+	// 预览模式
+	api.config.DryRun = options.DryRun
+
+	// 被动模式
+	api.config.Passive = options.Passive
+
+	// Web 技术指纹
+	api.config.Fingerprint = options.Fingerprint
+
+	// 按步骤设置并发数和超时，先撤销上次调用的设置
+	if err := api.applyStepConfig(options.StepConfig); err != nil {
+		return &Result{
+			Domain:        options.Target,
+			ExecutionTime: time.Since(startTime),
+			Error:         err.Error(),
+		}, err
+	}
+
+	// 注册模块，耗时只统计本次调用
+	api.prepareModules(options)
+	api.dispatcher.TimingCollector().Reset()
 
 	// 准备库调用选项
 	libOptions := map[string]interface{}{
@@ -133,43 +311,89 @@ func (api *OneForAllAPI) RunSubdomainEnumeration(options Options) (*Result, erro
 		"brute_dns_server_url": options.BruteDNSServerURL,
 	}
 
+	// ASN/CIDR 目标展开为反查得到的域名，反查到的主机名作为种子结果一同验证；
+	// 只枚举 NetworkTargetDomains 中列出的注册域名，其他注册域名下的主机名直接作为结果返回
+	domains := []string{options.Target}
+	var results []core.SubdomainResult
+	if enrich.IsNetworkTarget(options.Target) {
+		seeds, err := enrich.ExpandNetworkTarget(api.config, options.Target)
+		if err != nil {
+			return &Result{
+				Domain:        options.Target,
+				ExecutionTime: time.Since(startTime),
+				Error:         err.Error(),
+			}, err
+		}
+
+		domains = domains[:0]
+		for domain, domainSeeds := range seeds {
+			if !api.config.NetworkTargetDomain(domain) {
+				results = append(results, domainSeeds...)
+				continue
+			}
+			domains = append(domains, domain)
+			api.dispatcher.AddSeeds(domain, domainSeeds)
+		}
+		sort.Strings(domains)
+	}
+
 	// 执行子域名枚举
-	logger.Infof("Starting subdomain enumeration for domain: %s", options.Target)
-	results, err := api.dispatcher.RunLib(options.Target, libOptions)
-	if err != nil {
-		return &Result{
-			Domain:        options.Target,
-			ExecutionTime: time.Since(startTime),
-			Error:         err.Error(),
-		}, err
+	relatedSet := make(map[string]bool)
+	for _, domain := range domains {
+		logger.Infof("Starting subdomain enumeration for domain: %s", domain)
+		api.dispatcher.Reset()
+		domainResults, err := api.dispatcher.RunLib(domain, libOptions)
+		if err != nil {
+			return &Result{
+				Domain:        options.Target,
+				ExecutionTime: time.Since(startTime),
+				Error:         err.Error(),
+			}, err
+		}
+		results = append(results, domainResults...)
+		for _, related := range api.dispatcher.RelatedDomains(domain) {
+			relatedSet[related] = true
+		}
 	}
 
+	relatedDomains := make([]string, 0, len(relatedSet))
+	for related := range relatedSet {
+		relatedDomains = append(relatedDomains, related)
+	}
+	sort.Strings(relatedDomains)
+
+	// 正则过滤和置信度过滤
+	results = core.FilterConfidence(filter.Apply(results), options.MinConfidence)
+
 	// 转换结果格式
 	var apiResults []SubdomainResult
 	if results != nil {
 		apiResults = make([]SubdomainResult, len(results))
 		for i, result := range results {
-			apiResults[i] = SubdomainResult{
-				Subdomain:   result.Subdomain,
-				Source:      result.Source,
-				Time:        result.Time,
-				Alive:       result.Alive,
-				IP:          result.IP,
-				DNSResolved: result.DNSResolved,
-				PingAlive:   result.PingAlive,
-				StatusCode:  result.StatusCode,
-				StatusText:  result.StatusText,
-				Provider:    result.Provider,
-			}
+			apiResults[i] = convertResult(result)
 		}
 	}
 
+	// 调用方的结果后处理
+	if options.PostProcess != nil {
+		apiResults = options.PostProcess(apiResults)
+		logger.Debugf("Post-processing returned %d results", len(apiResults))
+	}
+
 	// 计算统计信息
 	aliveCount := 0
+	sourceBreakdown := make(map[string]int)
+	providerBreakdown := make(map[string]int)
 	for _, result := range apiResults {
 		if result.Alive {
 			aliveCount++
 		}
+		for _, source := range core.SplitSources(result.Source) {
+			sourceBreakdown[source]++
+		}
+		if result.Provider != "" {
+			providerBreakdown[result.Provider]++
+		}
 	}
 
 	alivePercentage := 0.0
@@ -183,15 +407,138 @@ func (api *OneForAllAPI) RunSubdomainEnumeration(options Options) (*Result, erro
 		options.Target, len(apiResults), aliveCount, alivePercentage, executionTime)
 
 	return &Result{
-		Domain:          options.Target,
-		TotalSubdomains: len(apiResults),
-		AliveSubdomains: aliveCount,
-		AlivePercentage: alivePercentage,
-		Results:         apiResults,
-		ExecutionTime:   executionTime,
+		Domain:            options.Target,
+		TotalSubdomains:   len(apiResults),
+		AliveSubdomains:   aliveCount,
+		AlivePercentage:   alivePercentage,
+		Results:           apiResults,
+		RelatedDomains:    relatedDomains,
+		SourceBreakdown:   sourceBreakdown,
+		ProviderBreakdown: providerBreakdown,
+		ExecutionTime:     executionTime,
+		ModuleTimings:     api.moduleTimings(),
 	}, nil
 }
 
+// moduleTimings 转换调度器记录的模块耗时
+func (api *OneForAllAPI) moduleTimings() []ModuleTiming {
+	timings := api.dispatcher.ModuleTimings()
+	converted := make([]ModuleTiming, len(timings))
+	for i, timing := range timings {
+		converted[i] = ModuleTiming(timing)
+	}
+	return converted
+}
+
+// RunSubdomainEnumerationStream 运行子域名枚举，并在各模块发现子域名时通过 onResult 实时推送
+// 推送的结果尚未验证，最终结果（含验证信息）仍通过返回值获取；onResult 会被并发调用
+// 多个模块发现的同一子域名只推送一次，其来源等信息合并在最终结果中
+func (api *OneForAllAPI) RunSubdomainEnumerationStream(options Options, onResult func(SubdomainResult)) (*Result, error) {
+	if onResult != nil {
+		var mutex sync.Mutex
+		seen := make(map[string]bool)
+		api.dispatcher.SetResultHandler(func(result core.SubdomainResult) {
+			mutex.Lock()
+			duplicate := seen[result.Subdomain]
+			seen[result.Subdomain] = true
+			mutex.Unlock()
+			if !duplicate {
+				onResult(convertResult(result))
+			}
+		})
+		defer api.dispatcher.SetResultHandler(nil)
+	}
+
+	return api.RunSubdomainEnumeration(options)
+}
+
+// RunSubdomainEnumerationStreamContext 与 RunSubdomainEnumerationStream 相同，ctx 取消后不再运行剩余模块，尽快返回已有结果
+func (api *OneForAllAPI) RunSubdomainEnumerationStreamContext(ctx context.Context, options Options, onResult func(SubdomainResult)) (*Result, error) {
+	api.dispatcher.SetContext(ctx)
+	defer api.dispatcher.SetContext(nil)
+	return api.RunSubdomainEnumerationStream(options, onResult)
+}
+
+// convertResult 将内部结果转换为 API 结果
+func convertResult(result core.SubdomainResult) SubdomainResult {
+	return SubdomainResult{
+		Subdomain:   result.Subdomain,
+		Source:      result.Source,
+		Time:        result.Time,
+		Alive:       result.Alive,
+		IP:          result.IP,
+		DNSResolved: result.DNSResolved,
+		PingAlive:   result.PingAlive,
+		PingMethod:  result.PingMethod,
+		Status:      result.Status,
+		StatusCode:  result.StatusCode,
+		StatusText:  result.StatusText,
+		Port:        result.Port,
+		Title:       result.Title,
+		URL:         result.URL,
+		Ports:       result.Ports,
+		Provider:    result.Provider,
+		CNAME:       result.CNAME,
+		CertIssuer:  result.CertIssuer,
+		CertExpiry:  result.CertExpiry,
+		TLSVersion:  result.TLSVersion,
+		Confidence:  result.Confidence,
+
+		Server:       result.Server,
+		PoweredBy:    result.PoweredBy,
+		Technologies: result.Technologies,
+	}
+}
+
+// applyStepConfig 把 Options.StepConfig 应用到调度器的执行步骤，上次调用的设置先恢复为配置值
+func (api *OneForAllAPI) applyStepConfig(steps map[string]StepConfig) error {
+	api.dispatcher.ClearStepConfig()
+	for name, step := range steps {
+		if err := api.dispatcher.SetStepConfig(name, step.Concurrency, step.Timeout); err != nil {
+			api.dispatcher.ClearStepConfig()
+			return err
+		}
+		logger.Debugf("Step %s: concurrency=%d, timeout=%v", name, step.Concurrency, step.Timeout)
+	}
+	return nil
+}
+
+// prepareModules 按模块选项注册模块；同一实例再次调用且模块选项和配置不变时复用已注册的模块实例，
+// 各域名运行前由 Dispatcher.Reset 清空上次的状态；模块选项或配置变化时移除已注册的模块后重新注册
+func (api *OneForAllAPI) prepareModules(options Options) {
+	key := fmt.Sprintf("%t|%t|%t|%t|%t|%t|%t|%t|%q|%q|%s",
+		options.EnableSearchModules, options.EnableDatasetModules, options.EnableCertificateModules,
+		options.EnableCrawlModules, options.EnableCheckModules, options.EnableIntelligenceModules,
+		options.EnableBruteForce, options.EnableEnrichModules, options.OnlyModules, options.SkipModules,
+		moduleConfigKey(api.config))
+	if api.moduleKey == key {
+		logger.Debugf("Reusing registered modules")
+		return
+	}
+	if api.moduleKey != "" {
+		api.dispatcher.ClearModules()
+	}
+	api.registerModules(options)
+	api.moduleKey = key
+}
+
+// moduleConfigKey 模块构造时读取的配置（IP 版本、数据目录、爆破并发数、DNS 服务器、CDN 数据等）的摘要，
+// 任一配置变化都重新创建模块；运行时才读取的 DryRun、Passive 和 Fingerprint 不计入
+func moduleConfigKey(cfg *config.Config) string {
+	clone := cfg.Clone()
+	clone.DryRun = false
+	clone.Passive = false
+	clone.Fingerprint = false
+
+	data, err := json.Marshal(clone)
+	if err != nil {
+		// 无法计算摘要时不复用模块
+		return time.Now().String()
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
 // registerModules 注册模块
 func (api *OneForAllAPI) registerModules(options Options) {
 	// 注册搜索模块
@@ -233,6 +580,9 @@ func (api *OneForAllAPI) registerModules(options Options) {
 	if options.EnableEnrichModules {
 		api.registerEnrichModules()
 	}
+
+	// 按名称筛选模块
+	api.dispatcher.FilterModules(options.OnlyModules, options.SkipModules)
 }
 
 func (o *OneForAllAPI) registerSearchModules() {
@@ -332,6 +682,7 @@ func (o *OneForAllAPI) registerIntelligenceModules() {
 	o.dispatcher.RegisterModule(intelligence.NewThreatMiner(o.config))
 	o.dispatcher.RegisterModule(intelligence.NewVirusTotal(o.config))
 	o.dispatcher.RegisterModule(intelligence.NewVirusTotalAPI(o.config))
+	o.dispatcher.RegisterModule(intelligence.NewWhois(o.config))
 }
 
 // registerBruteModules 注册爆破模块
@@ -2,8 +2,15 @@ package api
 
 import (
 	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/oneforall-go/internal/config"
+	"github.com/oneforall-go/internal/core"
 )
 
 func TestGetDefaultOptions(t *testing.T) {
@@ -45,6 +52,11 @@ func TestNewOneForAllAPI(t *testing.T) {
 	if api.dispatcher == nil {
 		t.Error("Expected dispatcher to be initialized")
 	}
+
+	// 每个实例持有配置副本，修改不影响全局配置
+	if api.config == config.GetConfig() {
+		t.Error("Expected API instance to own a copy of the config")
+	}
 }
 
 func TestRunSubdomainEnumeration_EmptyTarget(t *testing.T) {
@@ -68,6 +80,23 @@ func TestRunSubdomainEnumeration_EmptyTarget(t *testing.T) {
 	}
 }
 
+func TestRunSubdomainEnumeration_InvalidPattern(t *testing.T) {
+	api := NewOneForAllAPI()
+	options := GetDefaultOptions()
+	options.Target = "example.com"
+	options.ExcludePattern = "(" // 非法正则
+
+	result, err := api.RunSubdomainEnumeration(options)
+
+	if err == nil {
+		t.Error("Expected error for invalid exclude pattern")
+	}
+
+	if result == nil || result.Error == "" {
+		t.Error("Expected error message in result")
+	}
+}
+
 func TestSubdomainResult_JSON(t *testing.T) {
 	result := SubdomainResult{
 		Subdomain:   "test.example.com",
@@ -104,6 +133,30 @@ func TestSubdomainResult_JSON(t *testing.T) {
 	}
 }
 
+func TestConvertResultFingerprint(t *testing.T) {
+	result := convertResult(core.SubdomainResult{
+		Subdomain:    "blog.example.com",
+		Alive:        true,
+		Server:       "nginx/1.25.3",
+		PoweredBy:    "PHP/8.2.1",
+		Technologies: []string{"PHP", "WordPress"},
+	})
+
+	if result.Server != "nginx/1.25.3" || result.PoweredBy != "PHP/8.2.1" || !reflect.DeepEqual(result.Technologies, []string{"PHP", "WordPress"}) {
+		t.Errorf("Expected fingerprint fields to be kept, got %+v", result)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("Failed to marshal JSON: %v", err)
+	}
+	for _, key := range []string{`"server":"nginx/1.25.3"`, `"powered_by":"PHP/8.2.1"`, `"technologies":["PHP","WordPress"]`} {
+		if !strings.Contains(string(data), key) {
+			t.Errorf("Expected %s in %s", key, data)
+		}
+	}
+}
+
 func TestResult_JSON(t *testing.T) {
 	result := Result{
 		Domain:          "example.com",
@@ -150,3 +203,243 @@ func TestResult_JSON(t *testing.T) {
 		t.Errorf("Expected total subdomains %d, got %d", result.TotalSubdomains, decodedResult.TotalSubdomains)
 	}
 }
+
+func TestEnvelope_RoundTrip(t *testing.T) {
+	cfg := &config.Config{JSONEnvelope: true, ExportAliveOnly: true}
+	output := core.NewOutputManager(cfg)
+	output.SetFormat("json")
+	path := filepath.Join(t.TempDir(), "example.com.json")
+	output.SetOutputPath(path)
+	output.AddResults([]core.SubdomainResult{
+		{Subdomain: "www.example.com", Source: "crtsh", Time: "2025-08-03 19:51:48", Alive: true, IP: []string{"93.184.216.34"},
+			Status: 200, Title: "Example", Port: 443, Provider: "Cloudflare", DNSResolved: true, PingAlive: true, PingMethod: "tcp",
+			StatusCode: 200, StatusText: "Alive", CertIssuer: "Example CA", CertExpiry: "2026-01-01", TLSVersion: "TLS 1.3",
+			CNAME: []string{"www.example.com.cdn.cloudflare.net"}, URL: "https://www.example.com/", Ports: map[int]int{443: 200},
+			Confidence: 100, Server: "cloudflare", PoweredBy: "PHP/8.2.1", Technologies: []string{"PHP"}},
+		{Subdomain: "old.example.com", Source: "securitytrails", Alive: false, StatusText: "DNS Resolution Failed"},
+	})
+	if err := output.Export(); err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read export: %v", err)
+	}
+
+	var envelope Envelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		t.Fatalf("Failed to unmarshal envelope: %v", err)
+	}
+
+	if envelope.Version != EnvelopeVersion {
+		t.Errorf("Expected version %d, got %d", EnvelopeVersion, envelope.Version)
+	}
+	if envelope.Domain != "example.com" {
+		t.Errorf("Expected domain example.com, got %s", envelope.Domain)
+	}
+	if envelope.GeneratedAt == "" {
+		t.Error("Expected generated_at to be set")
+	}
+	// 统计包含全部结果，results 只包含写入文件的存活结果
+	if envelope.Stats.Total != 2 || envelope.Stats.Alive != 1 || envelope.Stats.Dead != 1 {
+		t.Errorf("Expected stats total=2 alive=1 dead=1, got %+v", envelope.Stats)
+	}
+	if envelope.Stats.Sources["crtsh"] != 1 || envelope.Stats.Providers["Cloudflare"] != 1 {
+		t.Errorf("Unexpected stats breakdown: %+v", envelope.Stats)
+	}
+	if len(envelope.Results) != 1 || envelope.Results[0].Subdomain != "www.example.com" {
+		t.Fatalf("Expected only www.example.com in results, got %+v", envelope.Results)
+	}
+
+	// api 结果保留文件中 core 结果的全部字段
+	var written core.JSONEnvelope
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatalf("Failed to unmarshal core envelope: %v", err)
+	}
+	encodedResult, err := json.Marshal(envelope.Results[0])
+	if err != nil {
+		t.Fatalf("Failed to marshal result: %v", err)
+	}
+	var roundTripped core.SubdomainResult
+	if err := json.Unmarshal(encodedResult, &roundTripped); err != nil {
+		t.Fatalf("Failed to unmarshal result: %v", err)
+	}
+	if !reflect.DeepEqual(roundTripped, written.Results[0]) {
+		t.Errorf("api result lost fields of the written result:\n%+v\n%+v", roundTripped, written.Results[0])
+	}
+	if converted := convertResult(written.Results[0]); !reflect.DeepEqual(converted, envelope.Results[0]) {
+		t.Errorf("convertResult differs from the decoded envelope result:\n%+v\n%+v", converted, envelope.Results[0])
+	}
+
+	// 再次序列化后应得到相同的信封
+	encoded, err := json.Marshal(envelope)
+	if err != nil {
+		t.Fatalf("Failed to marshal envelope: %v", err)
+	}
+	var decoded Envelope
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal re-encoded envelope: %v", err)
+	}
+	if !reflect.DeepEqual(envelope, decoded) {
+		t.Errorf("Envelope changed after round trip:\n%+v\n%+v", envelope, decoded)
+	}
+}
+
+func TestStepConfigReachesExecutionSteps(t *testing.T) {
+	api := NewOneForAllAPI()
+	defaults := api.dispatcher.ExecutionSteps()
+
+	err := api.applyStepConfig(map[string]StepConfig{
+		"brute force": {Concurrency: 3, Timeout: 5 * time.Minute},
+		"Enrich":      {Timeout: 90 * time.Second},
+	})
+	if err != nil {
+		t.Fatalf("applyStepConfig failed: %v", err)
+	}
+
+	for i, step := range api.dispatcher.ExecutionSteps() {
+		switch step.Name {
+		case "Brute Force":
+			if !step.Override || step.Concurrency != 3 || step.Timeout != 5*time.Minute {
+				t.Errorf("Expected Brute Force override 3/5m, got %+v", step)
+			}
+		case "Enrich":
+			// 只设置了超时，并发数保持配置值
+			if !step.Override || step.Concurrency != defaults[i].Concurrency || step.Timeout != 90*time.Second {
+				t.Errorf("Expected Enrich timeout override 90s, got %+v", step)
+			}
+		default:
+			if step.Override || step.Concurrency != defaults[i].Concurrency || step.Timeout != defaults[i].Timeout {
+				t.Errorf("Expected step %s to keep its config, got %+v", step.Name, step)
+			}
+		}
+	}
+
+	// 下一次调用不带 StepConfig 时恢复配置值
+	if err := api.applyStepConfig(nil); err != nil {
+		t.Fatalf("applyStepConfig failed: %v", err)
+	}
+	if steps := api.dispatcher.ExecutionSteps(); !reflect.DeepEqual(steps, defaults) {
+		t.Errorf("Expected steps to be restored, got %+v", steps)
+	}
+
+	// 未知的步骤名称在运行前报错
+	options := GetDefaultOptions()
+	options.Target = "example.com"
+	options.StepConfig = map[string]StepConfig{"Slow Search": {Concurrency: 1}}
+	result, err := api.RunSubdomainEnumeration(options)
+	if err == nil || result == nil || result.Error == "" {
+		t.Errorf("Expected an error for an unknown step, got %v", err)
+	}
+}
+
+func TestPostProcessFiltersResults(t *testing.T) {
+	api := NewOneForAllAPI()
+	preflight := api.config.Preflight
+	api.config.Preflight = false
+	defer func() { api.config.Preflight = preflight }()
+
+	// 不注册模块、不验证，种子结果原样返回
+	options := Options{Target: "example.com"}
+	api.dispatcher.AddSeeds("example.com", []core.SubdomainResult{
+		{Subdomain: "www.example.com", Source: "cidr", Alive: true, Provider: "Cloudflare"},
+		{Subdomain: "old.example.com", Source: "cidr"},
+		{Subdomain: "dev.example.com", Source: "asn"},
+	})
+
+	calls := 0
+	options.PostProcess = func(results []SubdomainResult) []SubdomainResult {
+		calls++
+		if len(results) != 3 {
+			t.Errorf("Expected post-processor to receive 3 results, got %d", len(results))
+		}
+		var alive []SubdomainResult
+		for _, result := range results {
+			if result.Alive {
+				alive = append(alive, result)
+			}
+		}
+		return alive
+	}
+
+	result, err := api.RunSubdomainEnumeration(options)
+	if err != nil {
+		t.Fatalf("RunSubdomainEnumeration failed: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected post-processor to be called once, got %d", calls)
+	}
+	if result.TotalSubdomains != 1 || result.AliveSubdomains != 1 || result.AlivePercentage != 100 {
+		t.Errorf("Expected totals for 1 alive result, got %d/%d (%.1f%%)",
+			result.TotalSubdomains, result.AliveSubdomains, result.AlivePercentage)
+	}
+	if len(result.Results) != 1 || result.Results[0].Subdomain != "www.example.com" {
+		t.Errorf("Expected only www.example.com, got %+v", result.Results)
+	}
+	if !reflect.DeepEqual(result.SourceBreakdown, map[string]int{"cidr": 1}) {
+		t.Errorf("Expected source breakdown of the kept result, got %v", result.SourceBreakdown)
+	}
+	if !reflect.DeepEqual(result.ProviderBreakdown, map[string]int{"Cloudflare": 1}) {
+		t.Errorf("Expected provider breakdown of the kept result, got %v", result.ProviderBreakdown)
+	}
+}
+
+func TestPrepareModulesTracksConfig(t *testing.T) {
+	api := NewOneForAllAPI()
+	options := Options{EnableBruteForce: true}
+	bruteModule := func() core.Module {
+		modules := api.dispatcher.GetModules(core.ModuleTypeBrute)
+		if len(modules) == 0 {
+			t.Fatal("Expected the brute module to be registered")
+		}
+		return modules[0]
+	}
+
+	api.prepareModules(options)
+	first := bruteModule()
+
+	// 只在运行时读取的配置变化时复用模块
+	api.config.DryRun = !api.config.DryRun
+	api.prepareModules(options)
+	if bruteModule() != first {
+		t.Error("Expected modules to be reused when only DryRun changes")
+	}
+
+	// 模块构造时读取的配置变化时重新创建
+	api.config.IPVersion = "6"
+	api.prepareModules(options)
+	second := bruteModule()
+	if second == first {
+		t.Error("Expected modules to be recreated after IPVersion changes")
+	}
+
+	api.config.DataDir = t.TempDir()
+	api.prepareModules(options)
+	if bruteModule() == second {
+		t.Error("Expected modules to be recreated after DataDir changes")
+	}
+}
+
+func TestSourceBreakdownSplitsMergedSources(t *testing.T) {
+	api := NewOneForAllAPI()
+	preflight := api.config.Preflight
+	api.config.Preflight = false
+	defer func() { api.config.Preflight = preflight }()
+
+	options := Options{Target: "example.com"}
+	options.PostProcess = func([]SubdomainResult) []SubdomainResult {
+		return []SubdomainResult{
+			{Subdomain: "www.example.com", Source: "crtsh,search"},
+			{Subdomain: "api.example.com", Source: "crtsh"},
+		}
+	}
+
+	result, err := api.RunSubdomainEnumeration(options)
+	if err != nil {
+		t.Fatalf("RunSubdomainEnumeration failed: %v", err)
+	}
+	if want := map[string]int{"crtsh": 2, "search": 1}; !reflect.DeepEqual(result.SourceBreakdown, want) {
+		t.Errorf("Expected source breakdown %v, got %v", want, result.SourceBreakdown)
+	}
+}
@@ -6,16 +6,28 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 
 	"github.com/sirupsen/logrus"
 )
 
 var (
 	logger *logrus.Logger
+
+	// 当前打开的日志文件，重复初始化时先关闭
+	logFileHandle *os.File
+	initMutex     sync.Mutex
+
+	// 通过 SetFormat 设置的日志格式，重复初始化时保留
+	logFormat string
 )
 
-// Init 初始化日志
+// Init 初始化日志（可重复调用，会关闭之前打开的日志文件）
 func Init(level string, logFile string) error {
+	initMutex.Lock()
+	defer initMutex.Unlock()
+
 	logger = logrus.New()
 
 	// 设置日志级别
@@ -34,12 +46,18 @@ func Init(level string, logFile string) error {
 		logger.SetLevel(logrus.InfoLevel)
 	}
 
-	// 设置日志格式
-	logger.SetFormatter(&logrus.TextFormatter{
-		FullTimestamp:   true,
-		TimestampFormat: "2006-01-02 15:04:05",
-		ForceColors:     true,
-	})
+	// 设置日志格式，优先使用 SetFormat 设置的格式（LOG_FORMAT=json 时输出结构化日志）
+	format := logFormat
+	if format == "" {
+		format = os.Getenv("LOG_FORMAT")
+	}
+	logger.SetFormatter(newFormatter(format))
+
+	// 关闭之前打开的日志文件
+	if logFileHandle != nil {
+		logFileHandle.Close()
+		logFileHandle = nil
+	}
 
 	// 设置输出
 	if logFile != "" {
@@ -55,6 +73,8 @@ func Init(level string, logFile string) error {
 			return fmt.Errorf("failed to open log file: %v", err)
 		}
 
+		logFileHandle = file
+
 		// 同时输出到文件和控制台
 		mw := io.MultiWriter(os.Stdout, file)
 		logger.SetOutput(mw)
@@ -65,6 +85,50 @@ func Init(level string, logFile string) error {
 	return nil
 }
 
+// SetFormat 设置日志格式（text/json），格式为空时保持当前格式，之后的 Init 沿用该格式
+func SetFormat(format string) {
+	if format == "" {
+		return
+	}
+
+	initMutex.Lock()
+	defer initMutex.Unlock()
+
+	logFormat = format
+	if logger != nil {
+		logger.SetFormatter(newFormatter(format))
+	}
+}
+
+// newFormatter 根据格式创建日志格式化器
+func newFormatter(format string) logrus.Formatter {
+	if strings.EqualFold(format, "json") {
+		return &logrus.JSONFormatter{
+			TimestampFormat: "2006-01-02 15:04:05",
+		}
+	}
+
+	return &logrus.TextFormatter{
+		FullTimestamp:   true,
+		TimestampFormat: "2006-01-02 15:04:05",
+		ForceColors:     useColors(),
+		DisableColors:   !useColors(),
+	}
+}
+
+// useColors 检查是否输出彩色日志（非终端或设置 NO_COLOR 时禁用）
+func useColors() bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("LOG_NO_COLOR") == "true" {
+		return false
+	}
+
+	info, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
 // Debug 输出调试日志（受调试总开关控制）
 func Debug(args ...interface{}) {
 	if shouldLogDebug() {
@@ -0,0 +1,65 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// countOpenFiles 统计当前进程打开的文件描述符数量
+func countOpenFiles(t *testing.T) int {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("/proc/self/fd not available")
+	}
+	return len(entries)
+}
+
+func TestInitDoesNotLeakFileHandles(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "test.log")
+
+	if err := Init("info", logFile); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	before := countOpenFiles(t)
+
+	for i := 0; i < 100; i++ {
+		if err := Init("info", logFile); err != nil {
+			t.Fatalf("Init failed on iteration %d: %v", i, err)
+		}
+	}
+
+	after := countOpenFiles(t)
+	if after > before {
+		t.Errorf("Expected no leaked file descriptors, before=%d after=%d", before, after)
+	}
+
+	// 切换回控制台输出时应关闭日志文件
+	if err := Init("info", ""); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if logFileHandle != nil {
+		t.Error("Expected log file to be closed")
+	}
+}
+
+func TestInitKeepsConfiguredFormat(t *testing.T) {
+	t.Setenv("LOG_FORMAT", "")
+	defer func(saved string) { logFormat = saved }(logFormat)
+
+	if err := Init("info", ""); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	SetFormat("json")
+	SetFormat("")
+
+	// 重新初始化后仍使用之前设置的格式
+	if err := Init("debug", ""); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if _, ok := logger.Formatter.(*logrus.JSONFormatter); !ok {
+		t.Errorf("Expected JSON formatter after re-init, got %T", logger.Formatter)
+	}
+}